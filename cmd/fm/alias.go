@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/logandonley/font-manager/internal/config"
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Manage your own names for font specs",
+	Long: `Name font specs so a team can standardize on what a font is for rather
+than which font it is. Aliases are saved in the config file and resolve
+anywhere a font spec is accepted, including font lists in config files, and
+keep any version or options given with them:
+
+  fm alias add code-font "FiraCode@nerdfonts"
+  fm install code-font@>=3.0`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listAliases()
+	},
+}
+
+var aliasAddCmd = &cobra.Command{
+	Use:   "add <name> <spec>",
+	Short: "Add or change an alias",
+	Example: `  fm alias add code-font "FiraCode@nerdfonts"
+  fm alias add ui-font Inter@google`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, spec := args[0], args[1]
+		if _, err := fm.ParseUserAlias(name, spec); err != nil {
+			return err
+		}
+
+		changed := false
+		err := updateConfig(cmd, func(c *config.Config) error {
+			_, changed = c.Aliases[name]
+			if c.Aliases == nil {
+				c.Aliases = make(map[string]string)
+			}
+			c.Aliases[name] = spec
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if changed {
+			fmt.Printf("Changed alias %s to %s\n", name, spec)
+		} else {
+			fmt.Printf("Added alias %s for %s\n", name, spec)
+		}
+		return nil
+	},
+}
+
+var aliasRemoveCmd = &cobra.Command{
+	Use:     "remove <name>...",
+	Aliases: []string{"rm"},
+	Short:   "Remove aliases",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := updateConfig(cmd, func(c *config.Config) error {
+			for _, name := range args {
+				if _, ok := c.Aliases[name]; !ok {
+					return fmt.Errorf("no alias named %q", name)
+				}
+				delete(c.Aliases, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, name := range args {
+			fmt.Printf("Removed alias %s\n", name)
+		}
+		return nil
+	},
+}
+
+var aliasListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List your aliases",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listAliases()
+	},
+}
+
+// listAliases prints the configured aliases by name
+func listAliases() error {
+	if jsonOutput {
+		aliases := cfg.Aliases
+		if aliases == nil {
+			aliases = map[string]string{}
+		}
+		return writeJSON(aliases)
+	}
+	if len(cfg.Aliases) == 0 {
+		fmt.Fprintln(textOut(), "No aliases defined")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSPEC")
+	names := make([]string, 0, len(cfg.Aliases))
+	for name := range cfg.Aliases {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%s\n", name, cfg.Aliases[name])
+	}
+	return w.Flush()
+}
+
+// updateConfig applies change to the config file in use and to the loaded
+// configuration
+func updateConfig(cmd *cobra.Command, change func(*config.Config) error) error {
+	path, err := configFile(cmd)
+	if err != nil {
+		return err
+	}
+	if err := config.Update(path, change); err != nil {
+		return err
+	}
+	return change(cfg)
+}
+
+func init() {
+	aliasCmd.AddCommand(aliasAddCmd)
+	aliasCmd.AddCommand(aliasRemoveCmd)
+	aliasCmd.AddCommand(aliasListCmd)
+	rootCmd.AddCommand(aliasCmd)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the font installation audit log",
+	Long: `fm records every install and uninstall (who, when, what, where from and the
+archive checksum) in an append-only audit log under ~/.local/state/fm.`,
+}
+
+var auditExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export audit log entries as CSV or JSON",
+	Long: `Export audit log entries as CSV or JSON for compliance reporting.
+
+Examples:
+  # Export the last 30 days as CSV
+  fm audit export --since 30d --format csv
+
+  # Export everything since a date as JSON into a file
+  fm audit export --since 2024-01-01 --format json -o fonts-audit.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+
+		var since time.Time
+		if sinceFlag != "" {
+			var err error
+			if since, err = parseSince(sinceFlag); err != nil {
+				return err
+			}
+		}
+
+		entries, err := auditLog.Entries(since)
+		if err != nil {
+			return fmt.Errorf("reading audit log: %w", err)
+		}
+
+		var w io.Writer = os.Stdout
+		if output != "" {
+			file, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("creating output file: %w", err)
+			}
+			defer file.Close()
+			w = file
+		}
+
+		switch format {
+		case "json":
+			return writeAuditJSON(w, entries)
+		case "csv":
+			return writeAuditCSV(w, entries)
+		default:
+			return fmt.Errorf("unsupported format %q (expected csv or json)", format)
+		}
+	},
+}
+
+func writeAuditJSON(w io.Writer, entries []fm.AuditEntry) error {
+	if entries == nil {
+		entries = []fm.AuditEntry{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
+
+func writeAuditCSV(w io.Writer, entries []fm.AuditEntry) error {
+	writer := csv.NewWriter(w)
+	header := []string{"time", "user", "sudo_user", "host", "action", "font", "source", "version", "url", "path", "sha256", "result", "error"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		record := []string{
+			e.Time.Format(time.RFC3339), e.User, e.SudoUser, e.Host, e.Action, e.Font,
+			e.Source, e.Version, e.URL, e.Path, e.SHA256, e.Result, e.Error,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// parseSince accepts a relative age such as "30d", "2w" or "12h", or an
+// absolute date in YYYY-MM-DD or RFC 3339 form
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+
+	// time.ParseDuration has no day or week units
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(value, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid time %q", value)
+			}
+			return time.Now().Add(-time.Duration(count) * unit), nil
+		}
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (use e.g. 30d, 12h or 2024-01-31)", value)
+	}
+	return time.Now().Add(-d), nil
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditExportCmd)
+
+	auditExportCmd.Flags().String("since", "", "Only export entries newer than this age (e.g. 30d) or date")
+	auditExportCmd.Flags().String("format", "csv", "Output format: csv or json")
+	auditExportCmd.Flags().StringP("output", "o", "", "Write to a file instead of stdout")
+}
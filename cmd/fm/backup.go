@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup [archive]",
+	Short: "Back up installed fonts and restore them without downloading",
+	Long: `Package the fonts fm installed, together with their recorded sources,
+versions and checksums, into a single archive, and restore it on another
+machine or after reinstalling the OS without downloading anything.
+
+"fm backup <archive>" is short for "fm backup create <archive>", and
+"fm restore <archive>" for "fm backup restore <archive>".`,
+	Example: `  fm backup fonts.tar.zst
+  fm restore fonts.tar.zst`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return cmd.Help()
+		}
+		return createBackup(cmd, args[0])
+	},
+}
+
+var backupCreateCmd = &cobra.Command{
+	Use:     "create <archive>",
+	Short:   "Write installed fonts to a .tar.zst, .tar.gz, .tar or .zip archive",
+	Example: `  fm backup create fonts.tar.zst`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return createBackup(cmd, args[0])
+	},
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:     "restore <archive>",
+	Short:   "Reinstall the fonts of a backup, verifying their checksums",
+	Example: `  fm backup restore fonts.tar.zst`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return restoreBackup(cmd, args[0])
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <archive>",
+	Short: "Reinstall the fonts of a backup made with fm backup",
+	Long: `Reinstall the fonts of a backup made with "fm backup", checking every file
+against its recorded SHA-256. Fonts that are already installed are skipped,
+and nothing is downloaded.`,
+	Example: `  fm restore fonts.tar.zst`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return restoreBackup(cmd, args[0])
+	},
+}
+
+// createBackup writes the installed fonts to the archive at path
+func createBackup(cmd *cobra.Command, path string) error {
+	manifest, err := manager.CreateBackup(cmd.Context(), path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backed up %d fonts to %s\n", len(manifest.Fonts), path)
+	return nil
+}
+
+// restoreBackup reinstalls the fonts of the archive at path
+func restoreBackup(cmd *cobra.Command, path string) error {
+	result, err := manager.RestoreBackup(cmd.Context(), path)
+	if result != nil {
+		for _, name := range result.Restored {
+			fmt.Printf("Restored %s\n", name)
+		}
+		for _, name := range result.Skipped {
+			fmt.Printf("Skipped %s (already installed)\n", name)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("restoring backup: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	backupCmd.AddCommand(backupCreateCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+}
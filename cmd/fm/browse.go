@@ -0,0 +1,22 @@
+package main
+
+import "github.com/spf13/cobra"
+
+var browseCmd = &cobra.Command{
+	Use:   "browse",
+	Short: "Search, inspect, install and uninstall fonts in an interactive browser",
+	Long: `Open a terminal UI listing the installed fonts. Type / to search every
+source, tab to switch between the installed fonts and search results, space to
+select several fonts, and i or u to install or uninstall the selection. Enter
+shows the details of the font under the cursor.
+
+Running fm without a command in a terminal opens the browser too.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runBrowse(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(browseCmd)
+}
@@ -0,0 +1,18 @@
+//go:build plan9
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+// browseSupported tells whether the browser runs on this platform; the
+// terminal libraries it's built on don't support this one
+const browseSupported = false
+
+// runBrowse is not supported on this platform
+func runBrowse(ctx context.Context) error {
+	return fmt.Errorf("fm browse is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,418 @@
+//go:build !plan9
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/logandonley/font-manager/pkg/fm"
+)
+
+// browseSupported tells whether the browser runs on this platform
+const browseSupported = true
+
+// runBrowse runs the browser until the user quits
+func runBrowse(ctx context.Context) error {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return errors.New("fm browse needs a terminal")
+	}
+	_, err := tea.NewProgram(newBrowseModel(ctx, manager), tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	return err
+}
+
+// browseView is the list the browser shows
+type browseView int
+
+const (
+	viewInstalled browseView = iota
+	viewResults
+)
+
+// Messages delivered by the browser's background commands
+type (
+	installedMsg struct {
+		fonts []fm.Font
+		err   error
+	}
+	searchMsg struct {
+		query string
+		fonts []fm.Font
+		err   error
+	}
+	actionMsg struct {
+		summary string
+		err     error
+	}
+)
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	cursorStyle   = lipgloss.NewStyle().Reverse(true)
+	dimStyle      = lipgloss.NewStyle().Faint(true)
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	detailsBorder = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+)
+
+// browseModel is the state of the browser
+type browseModel struct {
+	ctx     context.Context
+	manager *fm.DefaultManager
+
+	input     textinput.Model
+	view      browseView
+	installed []fm.Font
+	results   []fm.Font
+	query     string
+	cursor    int
+	selected  map[string]bool // By fontKey
+	details   bool
+
+	busy   string // What is running, shown until it finishes
+	status string
+	err    error
+	height int
+}
+
+func newBrowseModel(ctx context.Context, manager *fm.DefaultManager) *browseModel {
+	input := textinput.New()
+	input.Prompt = "/"
+	input.Placeholder = "search all sources"
+	return &browseModel{
+		ctx:      ctx,
+		manager:  manager,
+		input:    input,
+		selected: make(map[string]bool),
+		busy:     "Loading installed fonts…",
+		height:   24,
+	}
+}
+
+// fontKey identifies a font across the installed fonts and search results
+func fontKey(font fm.Font) string {
+	return font.Source + "\x00" + strings.ToLower(font.Name)
+}
+
+func (m *browseModel) Init() tea.Cmd {
+	return m.loadInstalled()
+}
+
+func (m *browseModel) loadInstalled() tea.Cmd {
+	return func() tea.Msg {
+		fonts, err := m.manager.List(m.ctx)
+		return installedMsg{fonts: fonts, err: err}
+	}
+}
+
+func (m *browseModel) search(query string) tea.Cmd {
+	return func() tea.Msg {
+		results, err := m.manager.Search(m.ctx, query)
+		fonts := make([]fm.Font, len(results))
+		for i, result := range results {
+			fonts[i] = result.Font
+		}
+		return searchMsg{query: query, fonts: fonts, err: err}
+	}
+}
+
+// install installs fonts one after another, carrying on past failures
+func (m *browseModel) install(fonts []fm.Font) tea.Cmd {
+	return func() tea.Msg {
+		var errs []error
+		for _, font := range fonts {
+			spec := font.Name
+			if font.Source != "" {
+				spec += "@" + font.Source
+			}
+			if err := m.manager.Install(m.ctx, spec); err != nil {
+				if errors.Is(err, fm.ErrEULANotAccepted) {
+					err = fmt.Errorf("%w; run fm install %q --accept-eula", err, spec)
+				}
+				errs = append(errs, fmt.Errorf("%s: %w", font.Name, err))
+			}
+		}
+		return actionMsg{summary: fmt.Sprintf("Installed %d of %d fonts", len(fonts)-len(errs), len(fonts)), err: errors.Join(errs...)}
+	}
+}
+
+// uninstall uninstalls fonts one after another, carrying on past failures
+func (m *browseModel) uninstall(fonts []fm.Font) tea.Cmd {
+	return func() tea.Msg {
+		var errs []error
+		for _, font := range fonts {
+			if err := m.manager.Uninstall(m.ctx, font.Name); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", font.Name, err))
+			}
+		}
+		return actionMsg{summary: fmt.Sprintf("Uninstalled %d of %d fonts", len(fonts)-len(errs), len(fonts)), err: errors.Join(errs...)}
+	}
+}
+
+// fonts returns the list shown in the current view
+func (m *browseModel) fonts() []fm.Font {
+	if m.view == viewResults {
+		return m.results
+	}
+	return m.installed
+}
+
+// targets returns the selected fonts of the current view, or the font under
+// the cursor when none are selected
+func (m *browseModel) targets() []fm.Font {
+	var fonts []fm.Font
+	for _, font := range m.fonts() {
+		if m.selected[fontKey(font)] {
+			fonts = append(fonts, font)
+		}
+	}
+	if len(fonts) == 0 && m.cursor < len(m.fonts()) {
+		fonts = append(fonts, m.fonts()[m.cursor])
+	}
+	return fonts
+}
+
+// isInstalled reports whether a search result is installed
+func (m *browseModel) isInstalled(font fm.Font) bool {
+	for _, installed := range m.installed {
+		if strings.EqualFold(installed.Name, font.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *browseModel) setView(view browseView) {
+	m.view = view
+	m.cursor = 0
+	m.details = false
+}
+
+func (m *browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		return m, nil
+
+	case installedMsg:
+		m.busy = ""
+		m.err = msg.err
+		m.installed = msg.fonts
+		sort.Slice(m.installed, func(i, j int) bool {
+			return strings.ToLower(m.installed[i].Name) < strings.ToLower(m.installed[j].Name)
+		})
+		m.cursor = min(m.cursor, max(len(m.fonts())-1, 0))
+		return m, nil
+
+	case searchMsg:
+		m.busy = ""
+		// Sources that failed are reported alongside what the others found
+		m.err = msg.err
+		m.query = msg.query
+		m.results = msg.fonts
+		m.status = fmt.Sprintf("%d results for %q", len(msg.fonts), msg.query)
+		m.setView(viewResults)
+		return m, nil
+
+	case actionMsg:
+		m.busy = ""
+		m.status = msg.summary
+		m.err = msg.err
+		m.selected = make(map[string]bool)
+		return m, m.loadInstalled()
+
+	case tea.KeyMsg:
+		if m.input.Focused() {
+			return m.updateInput(msg)
+		}
+		return m.updateList(msg)
+	}
+	return m, nil
+}
+
+func (m *browseModel) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		m.input.Blur()
+		query := strings.TrimSpace(m.input.Value())
+		if query == "" {
+			return m, nil
+		}
+		m.busy = fmt.Sprintf("Searching for %q…", query)
+		m.err = nil
+		return m, m.search(query)
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m *browseModel) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	fonts := m.fonts()
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "/":
+		return m, m.input.Focus()
+	case "tab":
+		if m.view == viewInstalled {
+			m.setView(viewResults)
+		} else {
+			m.setView(viewInstalled)
+		}
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(fonts)-1 {
+			m.cursor++
+		}
+	case " ":
+		if m.cursor < len(fonts) {
+			key := fontKey(fonts[m.cursor])
+			m.selected[key] = !m.selected[key]
+			if m.cursor < len(fonts)-1 {
+				m.cursor++
+			}
+		}
+	case "enter":
+		m.details = !m.details
+	case "r":
+		m.busy = "Loading installed fonts…"
+		return m, m.loadInstalled()
+	case "i":
+		if m.busy != "" || m.view != viewResults {
+			break
+		}
+		if targets := m.targets(); len(targets) > 0 {
+			m.busy = fmt.Sprintf("Installing %d fonts…", len(targets))
+			m.err = nil
+			return m, m.install(targets)
+		}
+	case "u":
+		if m.busy != "" || m.view != viewInstalled {
+			break
+		}
+		if targets := m.targets(); len(targets) > 0 {
+			m.busy = fmt.Sprintf("Uninstalling %d fonts…", len(targets))
+			m.err = nil
+			return m, m.uninstall(targets)
+		}
+	}
+	return m, nil
+}
+
+func (m *browseModel) View() string {
+	var b strings.Builder
+
+	installed, results := "Installed", "Results"
+	if m.view == viewInstalled {
+		installed = titleStyle.Render("[" + installed + "]")
+	} else {
+		results = titleStyle.Render("[" + results + "]")
+	}
+	fmt.Fprintf(&b, "%s  %s\n", installed, results)
+	b.WriteString(m.input.View() + "\n\n")
+
+	// Reserve lines for the header, status and help
+	rows := max(m.height-7, 3)
+	if m.details {
+		rows = max(rows-10, 3)
+	}
+
+	fonts := m.fonts()
+	if len(fonts) == 0 {
+		switch {
+		case m.view == viewResults && m.query == "":
+			b.WriteString(dimStyle.Render("Type / to search") + "\n")
+		case m.busy == "":
+			b.WriteString(dimStyle.Render("No fonts") + "\n")
+		}
+	}
+	start := max(0, min(m.cursor-rows/2, len(fonts)-rows))
+	for i := start; i < len(fonts) && i < start+rows; i++ {
+		b.WriteString(m.row(fonts[i], i == m.cursor) + "\n")
+	}
+
+	if m.details && m.cursor < len(fonts) {
+		b.WriteString(detailsBorder.Render(fontDetails(fonts[m.cursor])) + "\n")
+	}
+
+	b.WriteString("\n")
+	switch {
+	case m.busy != "":
+		b.WriteString(m.busy + "\n")
+	case m.status != "":
+		b.WriteString(m.status + "\n")
+	}
+	if m.err != nil {
+		b.WriteString(errorStyle.Render(m.err.Error()) + "\n")
+	}
+
+	help := "/ search • tab switch • space select • enter details • r reload • q quit"
+	if m.view == viewResults {
+		help = "i install • " + help
+	} else {
+		help = "u uninstall • " + help
+	}
+	b.WriteString(dimStyle.Render(help))
+	return b.String()
+}
+
+// row renders a font in the list
+func (m *browseModel) row(font fm.Font, current bool) string {
+	mark := "[ ]"
+	if m.selected[fontKey(font)] {
+		mark = "[x]"
+	}
+
+	line := fmt.Sprintf("%s %s", mark, font.Name)
+	if font.Source != "" {
+		line += dimStyle.Render("@" + font.Source)
+	}
+	if version := font.Meta["version"]; version != "" {
+		line += " " + version
+	}
+	if m.view == viewResults && m.isInstalled(font) {
+		line += dimStyle.Render(" (installed)")
+	}
+
+	if current {
+		return cursorStyle.Render(line)
+	}
+	return line
+}
+
+// fontDetails describes font for the details pane
+func fontDetails(font fm.Font) string {
+	lines := []string{titleStyle.Render(font.Name)}
+	if font.Source != "" {
+		lines = append(lines, "source: "+font.Source)
+	}
+	if font.URL != "" {
+		lines = append(lines, "url: "+font.URL)
+	}
+
+	keys := make([]string, 0, len(font.Meta))
+	for key := range font.Meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("%s: %s", key, font.Meta[key]))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle -f <file> [-o <archive>]",
+	Short: "Download the fonts of a config into one archive for offline installs",
+	Long: `Resolve every font of a config file and download it into a single
+portable archive, without installing anything. "fm install --from-bundle"
+installs the fonts from the archive on machines without internet access,
+checking every download against the checksum recorded when it was bundled.
+
+The format follows the extension of the archive: .tar.zst, .tar.gz, .tar or
+.zip.`,
+	Example: `  fm bundle -f fonts.txt -o fonts-bundle.tar.zst
+  fm install --from-bundle fonts-bundle.tar.zst`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configFiles, _ := cmd.Flags().GetStringArray("file")
+		fonts, err := readConfigs(configFiles)
+		if err != nil {
+			return err
+		}
+
+		var opts []fm.InstallOption
+		if accept, _ := cmd.Flags().GetBool("accept-eula"); accept {
+			opts = append(opts, fm.WithAcceptEULA())
+		}
+		output, _ := cmd.Flags().GetString("output")
+		fmt.Fprintf(textOut(), "Downloading fonts from %s...\n", configNames(configFiles))
+		manifest, err := manager.CreateBundle(cmd.Context(), fonts, output, opts...)
+		if err != nil {
+			return err
+		}
+
+		var size int64
+		for _, font := range manifest.Fonts {
+			size += font.Size
+		}
+		fmt.Fprintf(textOut(), "Bundled %d fonts (%s) into %s\n", len(manifest.Fonts), fm.FormatSize(size), output)
+		return nil
+	},
+}
+
+func init() {
+	bundleCmd.Flags().StringArrayP("file", "f", nil, "Config file listing the fonts to bundle, or - for stdin; repeat to merge files")
+	bundleCmd.Flags().StringP("output", "o", "fonts-bundle.tar.zst", "Archive to write the bundle to")
+	bundleCmd.Flags().Bool("accept-eula", false, "Accept the license agreement of sources that require one (e.g. mscorefonts)")
+	bundleCmd.MarkFlagRequired("file")
+	rootCmd.AddCommand(bundleCmd)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and clean the download and catalog cache",
+	Long: `fm caches source catalogs and search results for a few hours, and keeps the
+archives of versioned releases, such as a Nerd Fonts tag, so reinstalling the
+same release doesn't download it again. Unversioned downloads are never
+cached. Disable caching with "cache: {enabled: false}" in the config file.`,
+}
+
+var cacheInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show the size and number of cached entries",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := cacheForCommand()
+		if err != nil {
+			return err
+		}
+		stats, err := c.Stats()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Cache directory: %s\n\n", stats.Dir)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KIND\tENTRIES\tSIZE")
+		fmt.Fprintf(w, "downloads\t%d\t%s\n", stats.Downloads.Entries, fm.FormatSize(stats.Downloads.Size))
+		fmt.Fprintf(w, "catalogs\t%d\t%s\n", stats.Index.Entries, fm.FormatSize(stats.Index.Size))
+		fmt.Fprintf(w, "total\t%d\t%s\n", stats.Downloads.Entries+stats.Index.Entries, fm.FormatSize(stats.Downloads.Size+stats.Index.Size))
+		return w.Flush()
+	},
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove cached entries, all of them or those unused for a while",
+	Example: `  # Empty the cache
+  fm cache clean
+
+  # Remove entries that haven't been used for 30 days
+  fm cache clean --older-than 30d`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var before time.Time
+		if olderThan, _ := cmd.Flags().GetString("older-than"); olderThan != "" {
+			var err error
+			if before, err = parseSince(olderThan); err != nil {
+				return err
+			}
+		}
+
+		c, err := cacheForCommand()
+		if err != nil {
+			return err
+		}
+		removed, err := c.Clean(before)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d cached entries, freeing %s\n",
+			removed.Downloads.Entries+removed.Index.Entries, fm.FormatSize(removed.Downloads.Size+removed.Index.Size))
+		return nil
+	},
+}
+
+// cacheForCommand returns the cache, even when caching is disabled in the
+// config so entries left from before can still be inspected and removed
+func cacheForCommand() (*fm.Cache, error) {
+	if cache != nil {
+		return cache, nil
+	}
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return fm.NewCache(dir, fm.DefaultCacheTTL), nil
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheInfoCmd)
+	cacheCmd.AddCommand(cacheCleanCmd)
+
+	cacheCleanCmd.Flags().String("older-than", "", "Only remove entries last used longer ago than this age (e.g. 30d) or before this date")
+}
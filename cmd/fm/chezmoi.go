@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+var chezmoiCmd = &cobra.Command{
+	Use:   "chezmoi",
+	Short: "Install fonts as part of applying dotfiles with chezmoi",
+	Long: `Make fonts part of your dotfiles. fm chezmoi script writes a script for
+chezmoi's source directory that installs the fonts of a config file whenever
+chezmoi applies it.
+
+When chezmoi runs fm, fm never prompts, as if --no-input were given.`,
+}
+
+var chezmoiScriptCmd = &cobra.Command{
+	Use:   "script -f <file>",
+	Short: "Write a chezmoi run_once script that installs the fonts of a config file",
+	Long: `Write a shell script that installs the fonts of a config file, for chezmoi
+to run once on every machine. The config file, and its lockfile if there is
+one, are embedded in the script, so chezmoi runs it again whenever they change;
+regenerate the script after changing the fonts.
+
+With a lockfile the script installs exactly the locked downloads, like
+fm install -f --locked; otherwise it runs fm sync.`,
+	Example: `  # Install the fonts of fonts.txt on every chezmoi apply that changes them
+  fm chezmoi script -f fonts.txt -o "$(chezmoi source-path)/run_once_install-fonts.sh"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, _ := cmd.Flags().GetString("file")
+		if path == "" {
+			return fmt.Errorf("a config file is required (-f)")
+		}
+		prune, _ := cmd.Flags().GetBool("prune")
+
+		// Refuse to embed a config that fm can't install from
+		if _, err := readConfigs([]string{path}); err != nil {
+			return err
+		}
+		script, err := chezmoiScript(path, prune)
+		if err != nil {
+			return err
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			_, err := os.Stdout.Write(script)
+			return err
+		}
+		if err := os.WriteFile(output, script, 0755); err != nil {
+			return fmt.Errorf("writing script: %w", err)
+		}
+		fmt.Printf("Wrote %s\n", output)
+		return nil
+	},
+}
+
+func init() {
+	chezmoiScriptCmd.Flags().StringP("file", "f", "", "Config file listing the fonts to install")
+	chezmoiScriptCmd.Flags().StringP("output", "o", "", "Write the script to this file instead of stdout")
+	chezmoiScriptCmd.Flags().Bool("prune", false, "Without a lockfile, also remove fonts fm installed that aren't listed")
+	chezmoiCmd.AddCommand(chezmoiScriptCmd)
+	rootCmd.AddCommand(chezmoiCmd)
+}
+
+// runByChezmoi reports whether fm was started by chezmoi, which sets CHEZMOI
+// for the scripts and commands it runs
+func runByChezmoi() bool {
+	return os.Getenv("CHEZMOI") == "1"
+}
+
+// chezmoiHeredoc ends the files embedded in the script
+const chezmoiHeredoc = "FM_EOF"
+
+var chezmoiTemplate = template.Must(template.New("chezmoi").Parse(`#!/bin/sh
+# Installs the fonts of {{.Name}}. Generated by fm chezmoi script; regenerate
+# it after changing the fonts. chezmoi runs it again whenever it changes.
+set -eu
+
+if ! command -v fm >/dev/null 2>&1; then
+	echo "fm is not installed, so the fonts of {{.Name}} can't be installed" >&2
+	exit 1
+fi
+
+dir=$(mktemp -d)
+trap 'rm -rf "$dir"' EXIT
+
+cat >"$dir/{{.Name}}" <<'{{.Heredoc}}'
+{{.Config}}{{.Heredoc}}
+{{- if .Lock}}
+
+cat >"$dir/{{.LockName}}" <<'{{.Heredoc}}'
+{{.Lock}}{{.Heredoc}}
+
+fm install -f "$dir/{{.Name}}" --locked --yes --no-input
+{{- else}}
+
+fm sync -f "$dir/{{.Name}}"{{if .Prune}} --prune{{end}} --yes --no-input
+{{- end}}
+`))
+
+// chezmoiScript generates the script installing the fonts of the config at
+// path, embedding the config and its lockfile
+func chezmoiScript(path string, prune bool) ([]byte, error) {
+	config, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	lock, err := os.ReadFile(lockfilePath(path))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading lockfile: %w", err)
+	}
+
+	data := struct {
+		Name, LockName, Config, Lock, Heredoc string
+		Prune                                 bool
+	}{
+		Name:     filepath.Base(path),
+		LockName: filepath.Base(lockfilePath(path)),
+		Config:   withTrailingNewline(string(config)),
+		Heredoc:  chezmoiHeredoc,
+		Prune:    prune,
+	}
+	if len(lock) > 0 {
+		data.Lock = withTrailingNewline(string(lock))
+	}
+	for _, content := range []string{data.Config, data.Lock} {
+		for _, line := range strings.Split(content, "\n") {
+			if line == chezmoiHeredoc {
+				return nil, fmt.Errorf("%s contains a line %q, which ends the embedded files", path, chezmoiHeredoc)
+			}
+		}
+	}
+	if strings.ContainsAny(data.Name, `"$`+"`\\") {
+		return nil, fmt.Errorf("config file name %q can't be used in a shell script", data.Name)
+	}
+
+	var buf bytes.Buffer
+	if err := chezmoiTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// withTrailingNewline ends s with a newline unless it is empty
+func withTrailingNewline(s string) string {
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	return s
+}
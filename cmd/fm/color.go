@@ -0,0 +1,33 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// noColor turns off colored output, with --no-color
+var noColor bool
+
+// color is an ANSI foreground color for results in command output
+type color string
+
+const (
+	successColor color = "32" // Green
+	skipColor    color = "33" // Yellow
+	failColor    color = "31" // Red
+)
+
+// colored reports whether output written to w is colored: only on a
+// terminal, and neither with --no-color nor with NO_COLOR set
+func colored(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && !noColor && os.Getenv("NO_COLOR") == "" && isTerminal(f)
+}
+
+// paint renders s in c when output written to w is colored
+func paint(w io.Writer, c color, s string) string {
+	if !colored(w) {
+		return s
+	}
+	return "\x1b[" + string(c) + "m" + s + "\x1b[0m"
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+// completeInstallable completes font names from the alias index and the
+// cached source catalogs, without network requests
+func completeInstallable(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if manager == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names := manager.CompleteInstallable(toComplete)
+	return slices.DeleteFunc(names, func(name string) bool {
+		return slices.Contains(args, name)
+	}), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeInstalled completes the names of installed fonts, once per
+// argument for commands taking a single font
+func completeInstalled(single bool) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if manager == nil || single && len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		mgr, err := managerForUser(cmd)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		names, err := mgr.CompleteInstalled(cmd.Context(), toComplete)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return slices.DeleteFunc(names, func(name string) bool {
+			return slices.Contains(args, name)
+		}), cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon -f <file>",
+	Short: "Keep the installed fonts in sync with a config file",
+	Long: `Run in the background and keep the installed fonts in sync with one or more
+config files, as "fm sync" would. The fonts are synced on start, then again
+whenever a config file changes, e.g. when a dotfiles tool drops in a new
+version, and whenever fonts are removed or changed by something other than
+the daemon. Changes are watched for and picked up once the files have been
+left alone for --interval. Where they can't be watched, they're checked every
+--interval instead.
+
+A config that fails to parse is reported and nothing is synced until it's
+fixed, so a typo never prunes fonts. Syncs that fail, e.g. while offline, are
+retried every --retry interval. The daemon never prompts; pass --prune to
+remove fonts that aren't listed.
+
+The daemon serves its status on a socket in fm's state directory; read it
+with "fm daemon status". Run the daemon as a systemd user service or a
+launchd agent.`,
+	Example: `  fm daemon -f ~/.config/fm/fonts.txt --prune
+  fm daemon status`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		files, _ := cmd.Flags().GetStringArray("file")
+		if len(files) == 0 {
+			return &usageError{fmt.Errorf("at least one config file is required (-f)")}
+		}
+		for i, file := range files {
+			if file == stdinConfig {
+				return &usageError{fmt.Errorf("the daemon can't watch stdin; pass a config file")}
+			}
+			abs, err := filepath.Abs(file)
+			if err != nil {
+				return err
+			}
+			files[i] = abs
+		}
+
+		opts := fm.DaemonOptions{}
+		opts.Interval, _ = cmd.Flags().GetDuration("interval")
+		opts.RetryInterval, _ = cmd.Flags().GetDuration("retry")
+		opts.Sync.Prune, _ = cmd.Flags().GetBool("prune")
+		if accept, _ := cmd.Flags().GetBool("accept-eula"); accept {
+			opts.Install = append(opts.Install, fm.WithAcceptEULA())
+		}
+		if low, _ := cmd.Flags().GetBool("low-priority"); low || cfg.Background.LowPriority {
+			lowerPriority()
+			opts.Install = append(opts.Install, fm.WithParallelism(cfg.Background.Parallelism))
+		}
+		daemon := manager.NewDaemon(files, opts)
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		socket, err := daemonSocket(cmd)
+		if err != nil {
+			return err
+		}
+		listener, err := listenDaemonSocket(socket)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(socket)
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(daemon.Status())
+		})}
+		go srv.Serve(listener)
+		defer srv.Close()
+
+		fmt.Printf("Syncing fonts with %s as they change (Ctrl-C to stop)\n", configNames(files))
+		return daemon.Run(ctx, func(result fm.DaemonSync) {
+			fmt.Fprintf(textOut(), "Syncing at %s (%s)\n", result.Time.Format(time.TimeOnly), syncTrigger(result.Trigger))
+			printSyncChanges(result.Changes, false)
+			if result.Error != "" && len(result.Failed) == 0 {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", paint(os.Stderr, failColor, "Sync failed"), result.Error)
+			}
+		})
+	},
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show what a running daemon last synced",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		socket, err := daemonSocket(cmd)
+		if err != nil {
+			return err
+		}
+		client := &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		}
+		resp, err := client.Get("http://fm-daemon/status")
+		if err != nil {
+			return fmt.Errorf("no daemon is running on %s", socket)
+		}
+		defer resp.Body.Close()
+		var status fm.DaemonStatus
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			return fmt.Errorf("reading daemon status: %w", err)
+		}
+
+		if jsonOutput {
+			return writeJSON(status)
+		}
+		fmt.Printf("Syncing fonts with %s since %s\n", strings.Join(status.Files, ", "), status.Started.Format(time.DateTime))
+		last := status.LastSync
+		if last == nil {
+			fmt.Println("Not synced yet")
+			return nil
+		}
+		fmt.Printf("Synced %d times, last at %s (%s)\n", status.Syncs, last.Time.Format(time.DateTime), syncTrigger(last.Trigger))
+		if len(last.Changed) > 0 {
+			fmt.Printf("  Changed: %s\n", strings.Join(last.Changed, ", "))
+		}
+		for _, failure := range last.Failed {
+			fmt.Printf("  %s %s\n", paint(os.Stdout, failColor, "Failed:"), failure)
+		}
+		if last.Error != "" && len(last.Failed) == 0 {
+			fmt.Printf("  %s %s\n", paint(os.Stdout, failColor, "Error:"), last.Error)
+		}
+		return nil
+	},
+}
+
+// syncTrigger describes why the daemon synced
+func syncTrigger(trigger string) string {
+	switch trigger {
+	case fm.TriggerStart:
+		return "daemon started"
+	case fm.TriggerConfig:
+		return "config changed"
+	case fm.TriggerFonts:
+		return "fonts changed"
+	case fm.TriggerRetry:
+		return "retrying a failed sync"
+	}
+	return trigger
+}
+
+// daemonSocket returns the status socket given with --socket, or the default
+func daemonSocket(cmd *cobra.Command) (string, error) {
+	if socket, _ := cmd.Flags().GetString("socket"); socket != "" {
+		return socket, nil
+	}
+	return fm.DefaultDaemonSocket()
+}
+
+// listenDaemonSocket listens on socket, taking it over from a daemon that
+// exited without removing it but refusing while one is still running
+func listenDaemonSocket(socket string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(socket), 0700); err != nil {
+		return nil, err
+	}
+	if conn, err := net.Dial("unix", socket); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a daemon is already running on %s", socket)
+	}
+	if err := os.Remove(socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return net.Listen("unix", socket)
+}
+
+func init() {
+	daemonCmd.Flags().StringArrayP("file", "f", nil, "Config file to sync with; repeat to merge files, later ones overriding earlier duplicates")
+	daemonCmd.Flags().Bool("prune", false, "Remove fonts fm installed that aren't listed")
+	daemonCmd.Flags().Duration("interval", fm.DefaultWatchInterval, "How long changes must settle before a sync, and how often to check for them when they can't be watched")
+	daemonCmd.Flags().Duration("retry", fm.DefaultDaemonRetryInterval, "How soon to retry a sync that failed")
+	daemonCmd.Flags().Bool("accept-eula", false, "Accept the license agreement of sources that require one (e.g. mscorefonts)")
+	daemonCmd.Flags().Bool("low-priority", false, "Run at reduced CPU and I/O priority with the parallelism from the background config")
+	daemonCmd.PersistentFlags().String("socket", "", "Status socket (defaults to daemon.sock in fm's state directory)")
+	daemonCmd.AddCommand(daemonStatusCmd)
+	rootCmd.AddCommand(daemonCmd)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+)
+
+// debugf prints a --debug message on stderr
+func debugf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "debug: "+format+"\n", args...)
+}
+
+// debugProgress reports the phases of installs for --debug. Downloads are
+// reported as they start rather than for every chunk.
+func debugProgress(p fm.Progress) {
+	switch p.Phase {
+	case fm.PhaseDownload:
+		switch {
+		case p.Bytes > 0:
+		case p.Total > 0:
+			debugf("%s: downloading %s", p.Spec, fm.FormatSize(p.Total))
+		default:
+			debugf("%s: downloading", p.Spec)
+		}
+	case fm.PhaseDone:
+		if p.Err != nil {
+			debugf("%s: done: %v", p.Spec, p.Err)
+			return
+		}
+		debugf("%s: done", p.Spec)
+	case fm.PhaseCache:
+		debugf("refreshing the font cache")
+	default:
+		debugf("%s: %s", p.Spec, p.Phase)
+	}
+}
+
+// newDebugClient returns an HTTP client built from settings that reports
+// every request it makes, with the status and time taken, for --debug
+func newDebugClient(settings fm.HTTPSettings) (*http.Client, error) {
+	client, err := fm.NewHTTPClient(settings)
+	if err != nil {
+		return nil, err
+	}
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	client.Transport = debugTransport{next: transport}
+	return client, nil
+}
+
+// debugTransport logs the requests passing through it
+type debugTransport struct {
+	next http.RoundTripper
+}
+
+func (t debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		debugf("%s %s: %v (%s)", req.Method, req.URL.Redacted(), err, elapsed)
+		return nil, err
+	}
+	debugf("%s %s: %s (%s)", req.Method, req.URL.Redacted(), resp.Status, elapsed)
+	return resp, nil
+}
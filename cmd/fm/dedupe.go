@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Find and consolidate duplicate copies of fonts",
+	Long: `Find fonts installed more than once and consolidate them:
+
+  - Fonts fm installed whose every face is also part of another installed
+    font, such as FiraCode installed from two sources. The duplicate font is
+    uninstalled; pinned fonts are always kept.
+  - Font faces present in more than one font directory, such as a copy in
+    the legacy ~/.fonts alongside one fm installed in ~/.local/share/fonts.
+    The extra copies are removed, or replaced with symlinks with --link, and
+    the kept copy is moved into the user font directory.
+
+Copies are duplicates when they are byte-identical or carry the same family,
+style and version in their name table. The duplicates are listed and, once
+confirmed, consolidated. --dry-run only lists them.`,
+	Example: `  # Show duplicates and consolidate them after confirming
+  fm dedupe
+
+  # Only report duplicates
+  fm dedupe --dry-run
+
+  # Keep the old paths working through symlinks, without asking
+  fm dedupe --link --yes`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		link, _ := cmd.Flags().GetBool("link")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		fonts, err := manager.FindDuplicateFonts(cmd.Context())
+		if err != nil {
+			return err
+		}
+		faces, err := manager.FindDuplicates(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if len(fonts) == 0 && len(faces) == 0 {
+			fmt.Println("No duplicate fonts found")
+			return nil
+		}
+
+		if len(fonts) > 0 {
+			printDuplicateFonts(fonts)
+		}
+		copies := 0
+		if len(faces) > 0 {
+			if len(fonts) > 0 {
+				fmt.Println()
+			}
+			copies = printDuplicateFaces(faces)
+		}
+		if dryRun {
+			return nil
+		}
+
+		var actions []string
+		if len(fonts) > 0 {
+			actions = append(actions, fmt.Sprintf("uninstall %d duplicate fonts", len(fonts)))
+		}
+		if copies > 0 {
+			action := "remove"
+			if link {
+				action = "replace with symlinks"
+			}
+			actions = append(actions, fmt.Sprintf("%s %d duplicate files", action, copies))
+		}
+		question := strings.Join(actions, " and ") + "?"
+		if shouldConfirm(cmd, true) && !confirm(strings.ToUpper(question[:1])+question[1:]) {
+			return nil
+		}
+
+		var errs []error
+		if len(fonts) > 0 {
+			if err := manager.RemoveDuplicateFonts(cmd.Context(), fonts); err != nil {
+				errs = append(errs, err)
+			} else {
+				fmt.Printf("Uninstalled %d duplicate fonts\n", len(fonts))
+			}
+
+			// The copies to keep may have belonged to a font just uninstalled
+			if copies > 0 {
+				if faces, err = manager.FindDuplicates(cmd.Context()); err != nil {
+					return err
+				}
+			}
+		}
+		if copies > 0 {
+			if err := manager.ConsolidateDuplicates(cmd.Context(), faces, link); err != nil {
+				errs = append(errs, err)
+			} else {
+				fmt.Printf("Consolidated %d duplicate files\n", copies)
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return fmt.Errorf("consolidating duplicates: %w", err)
+		}
+		return nil
+	},
+}
+
+// printDuplicateFonts prints a table of installed fonts duplicating others
+func printDuplicateFonts(duplicates []fm.DuplicateFont) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FONT\tSOURCE\tMATCH\tKEEP")
+	for _, d := range duplicates {
+		match := "identical"
+		if !d.Identical {
+			match = "same version"
+		}
+		keep := d.Keep.Name
+		if d.Keep.Source != "" {
+			keep += " (" + d.Keep.Source + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", d.Duplicate.Name, orDash(d.Duplicate.Source), match, keep)
+	}
+	w.Flush()
+}
+
+// printDuplicateFaces prints a table of font files duplicated across
+// directories and returns how many copies it lists
+func printDuplicateFaces(duplicates []fm.DuplicateFace) int {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FONT\tMATCH\tKEEP\tDUPLICATE")
+	copies := 0
+	for _, d := range duplicates {
+		name := d.Keep.Family
+		if d.Keep.Style != "" {
+			name += " " + d.Keep.Style
+		}
+		if name == "" {
+			name = "-"
+		}
+		match := "identical"
+		if !d.Identical {
+			match = "same version"
+		}
+		for _, c := range d.Copies {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, match, d.Keep.Path, c.Path)
+			copies++
+		}
+	}
+	w.Flush()
+	return copies
+}
+
+func init() {
+	dedupeCmd.Flags().Bool("across-dirs", false, "Look for copies across the user and legacy font directories")
+	dedupeCmd.Flags().MarkDeprecated("across-dirs", "fm dedupe always looks across font directories")
+	dedupeCmd.Flags().Bool("link", false, "Replace duplicate files with symlinks instead of removing them")
+	dedupeCmd.Flags().Bool("dry-run", false, "Only list duplicates, without changing anything")
+	dedupeCmd.Flags().Bool("confirm", false, "Ask for confirmation, whatever the confirm policy")
+	rootCmd.AddCommand(dedupeCmd)
+}
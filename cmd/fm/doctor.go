@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose interrupted installs and problems reaching font sources",
+	Long: `Check for installs that were cut short and that every font source is
+reachable. With --network, also walk the network path to each source endpoint:
+name resolution, a connection to every resolved IPv4 and IPv6 address, the
+proxy in use and the TLS handshake.
+
+Installs are cut short when fm is quit with a second Ctrl-C or killed, leaving
+a partially installed font, or the previous copy of a font being reinstalled
+with --force moved aside. --fix removes partial installs and puts those copies
+back.
+
+Examples:
+  # Check every source
+  fm doctor
+
+  # Clean up after a forced quit
+  fm doctor --fix
+
+  # Find out why a source can't be reached from this network
+  fm doctor --network`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fix, _ := cmd.Flags().GetBool("fix")
+		interruptedErr := checkInterrupted(fix)
+		results, checkErr := checkSources(cmd)
+		checkErr = errors.Join(interruptedErr, checkErr)
+
+		if network, _ := cmd.Flags().GetBool("network"); !network {
+			return checkErr
+		}
+
+		fmt.Println("\nChecking network paths...")
+		failed := 0
+		for _, r := range results {
+			if r.Endpoint == "" {
+				continue
+			}
+			d := fm.DiagnoseEndpoint(cmd.Context(), r.Endpoint, httpSettings())
+			if !d.OK() {
+				failed++
+			}
+			printDiagnosis(r.Source, d)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d source endpoints are unreachable", failed)
+		}
+		return checkErr
+	},
+}
+
+// checkInterrupted reports installs that were cut short, cleaning up after
+// them when fix is set
+func checkInterrupted(fix bool) error {
+	if fix {
+		cleaned, err := manager.CleanInterrupted()
+		for _, install := range cleaned {
+			switch {
+			case install.Restored:
+				fmt.Printf("Restored %s after an interrupted reinstall\n", install.Dir)
+			case install.Partial:
+				fmt.Printf("Removed partially installed %s\n", install.Dir)
+			}
+			if install.SetAside != "" && !install.Restored {
+				fmt.Printf("Removed %s, left behind by a completed reinstall\n", install.SetAside)
+			}
+		}
+		return err
+	}
+
+	interrupted, err := manager.Interrupted()
+	if err != nil || len(interrupted) == 0 {
+		return err
+	}
+	fmt.Println("Interrupted installs:")
+	for _, install := range interrupted {
+		switch {
+		case install.Partial && install.SetAside != "":
+			fmt.Printf("  %s: partially reinstalled, previous copy in %s\n", install.Dir, install.SetAside)
+		case install.Partial:
+			fmt.Printf("  %s: partially installed\n", install.Dir)
+		default:
+			fmt.Printf("  %s: copy left in %s by a reinstall\n", install.Dir, install.SetAside)
+		}
+	}
+	fmt.Println("Run 'fm doctor --fix' to clean them up")
+	fmt.Println()
+	return fmt.Errorf("%d installs were interrupted", len(interrupted))
+}
+
+// printDiagnosis prints each step of an endpoint diagnosis
+func printDiagnosis(source string, d fm.EndpointDiagnosis) {
+	status := "OK"
+	if !d.OK() {
+		status = "FAIL"
+	}
+	fmt.Printf("\n%s %s (%s)\n", status, source, d.Endpoint)
+
+	if d.Proxy != "" {
+		fmt.Printf("  proxy:   %s\n", d.Proxy)
+	} else {
+		fmt.Println("  proxy:   none")
+	}
+
+	if d.ResolveErr != nil {
+		fmt.Printf("  resolve: %v\n", d.ResolveErr)
+		return
+	}
+
+	var addrs []string
+	for _, c := range d.Connects {
+		addrs = append(addrs, c.Addr)
+	}
+	fmt.Printf("  resolve: %s\n", strings.Join(addrs, ", "))
+
+	for _, c := range d.Connects {
+		if c.Err != nil {
+			fmt.Printf("  connect: %s: %v\n", c.Addr, c.Err)
+		} else {
+			fmt.Printf("  connect: %s: ok\n", c.Addr)
+		}
+	}
+
+	switch {
+	case d.TLSErr != nil:
+		fmt.Printf("  tls:     %v\n", d.TLSErr)
+	case d.TLS != nil:
+		fmt.Printf("  tls:     %s %s, certificate %q issued by %q, expires %s\n",
+			d.TLS.Version, d.TLS.CipherSuite, d.TLS.Subject, d.TLS.Issuer, d.TLS.NotAfter.Format(time.DateOnly))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().Bool("fix", false, "Remove partially installed fonts and restore fonts moved aside by interrupted reinstalls")
+	doctorCmd.Flags().Bool("network", false, "Diagnose DNS, IPv4/IPv6 connectivity, proxy and TLS for each source endpoint")
+}
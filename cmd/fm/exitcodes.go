@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+// Exit statuses, so scripts can tell why fm failed without parsing its output
+const (
+	exitError    = 1 // Any failure not covered below
+	exitUsage    = 2 // Unknown commands, flags or arguments
+	exitPartial  = 3 // Some of the fonts acted on failed and others didn't
+	exitNotFound = 4 // The font or source asked for doesn't exist or isn't installed
+	exitNetwork  = 5 // A download or source couldn't be reached
+
+	// exitChanged is the status fm install --detailed-exit-code exits with
+	// when it installed or would install fonts, leaving 0 for runs that
+	// changed nothing
+	exitChanged = 10
+
+	// exitInterrupted is the status after Ctrl-C, as for a shell
+	exitInterrupted = 130
+)
+
+// usageError is a command line fm can't make sense of
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+func (e *usageError) Unwrap() error { return e.err }
+
+// fontsFailedError reports that some of the fonts a command acted on failed,
+// with the error of each of them
+type fontsFailedError struct {
+	action  string
+	errs    []error
+	partial bool // Whether other fonts succeeded
+}
+
+func (e *fontsFailedError) Error() string {
+	return fmt.Sprintf("some fonts failed to %s", e.action)
+}
+
+// fontsFailed returns a fontsFailedError for the results of acting on fonts
+// one by one, or nil when none of them failed. Fonts skipped because they're
+// already installed didn't fail.
+func fontsFailed(action string, results []error) error {
+	var errs []error
+	for _, err := range results {
+		if err != nil && !errors.Is(err, fm.ErrAlreadyInstalled) {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &fontsFailedError{action: action, errs: errs, partial: len(errs) < len(results)}
+}
+
+// exitStatus returns the status fm exits with after a command failed with
+// err. Fonts that all failed the same way exit as a single one would.
+func exitStatus(err error) int {
+	var usage *usageError
+	var failed *fontsFailedError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.As(err, &usage), isCobraUsageError(err):
+		return exitUsage
+	case errors.As(err, &failed):
+		if failed.partial {
+			return exitPartial
+		}
+		status := errorClass(failed.errs[0])
+		for _, err := range failed.errs[1:] {
+			if errorClass(err) != status {
+				return exitPartial
+			}
+		}
+		return status
+	}
+	return errorClass(err)
+}
+
+// errorClass tells network failures and missing fonts from other errors.
+// Network failures come first, since a source that can't be reached also
+// means the font wasn't found.
+func errorClass(err error) int {
+	var networkErr *fm.NetworkError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &networkErr), errors.As(err, &netErr), errors.Is(err, fm.ErrOffline):
+		return exitNetwork
+	case errors.Is(err, fm.ErrNotFound), errors.Is(err, fm.ErrNotInstalled):
+		return exitNotFound
+	}
+	return exitError
+}
+
+// markUsageErrors makes the flag and argument errors of cmd and its
+// subcommands usage errors
+func markUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return &usageError{err}
+	})
+	markArgErrors(cmd)
+}
+
+func markArgErrors(cmd *cobra.Command) {
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			if err := validate(cmd, args); err != nil {
+				return &usageError{err}
+			}
+			return nil
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markArgErrors(sub)
+	}
+}
+
+// isCobraUsageError recognizes the usage errors cobra returns without a hook
+// to mark them, for unknown commands and conflicting flags
+func isCobraUsageError(err error) bool {
+	msg := err.Error()
+	return strings.HasPrefix(msg, "unknown command ") ||
+		strings.HasPrefix(msg, "if any flags in the group ") ||
+		strings.HasPrefix(msg, "required flag(s) ")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the installed fonts to a config file for fm install -f",
+	Long: `Write every font fm installed, with the source it came from, as a config
+file that "fm install -f" reinstalls. Keep it in your dotfiles to set up the
+same fonts on a new machine.`,
+	Example: `  # Print the config
+  fm export
+
+  # Save it with minimum versions and the exact download URLs
+  fm export -o fonts.txt --versions --urls`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		versions, _ := cmd.Flags().GetBool("versions")
+		urls, _ := cmd.Flags().GetBool("urls")
+
+		fonts, err := manager.Export(cmd.Context(), fm.ExportOptions{Versions: versions, URLs: urls})
+		if fonts == nil && err != nil {
+			return err
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: some fonts can't be exported: %v\n", err)
+		}
+
+		toFile := output != "" && output != "-"
+		var w io.Writer = os.Stdout
+		if toFile {
+			f, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("creating %s: %w", output, err)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		comment := fmt.Sprintf("Exported by fm on %s; install with: fm install -f <file>", time.Now().Format(time.DateOnly))
+		if err := fm.WriteConfig(w, fonts, comment); err != nil {
+			return fmt.Errorf("writing config: %w", err)
+		}
+		if toFile {
+			fmt.Printf("Exported %d fonts to %s\n", len(fonts), output)
+		}
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringP("output", "o", "", "File to write instead of standard output")
+	exportCmd.Flags().Bool("versions", false, "Require at least the installed version of each font")
+	exportCmd.Flags().Bool("urls", false, "Pin fonts to the URLs they were downloaded from")
+	rootCmd.AddCommand(exportCmd)
+}
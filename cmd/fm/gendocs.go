@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+var genDocsCmd = &cobra.Command{
+	Use:   "gen-docs <dir>",
+	Short: "Generate man pages or markdown for every command",
+	Long: `Write a page for fm and each of its commands into dir, as man pages
+(fm.1, fm-install.1, ...) or markdown (fm.md, fm_install.md, ...), for
+packagers to ship with fm. Pages are dated SOURCE_DATE_EPOCH when it's set,
+for reproducible builds.`,
+	Example: `  fm gen-docs --format man ./man
+  fm gen-docs --format markdown ./docs`,
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	// Generating docs needs no config or font directories, e.g. in a
+	// package build
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := args[0]
+		format, _ := cmd.Flags().GetString("format")
+		if format != "man" && format != "markdown" {
+			return &usageError{fmt.Errorf("unknown format %q: use man or markdown", format)}
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+
+		root := cmd.Root()
+		if format == "markdown" {
+			return doc.GenMarkdownTree(root, dir)
+		}
+		return doc.GenManTree(root, &doc.GenManHeader{
+			Section: "1",
+			Source:  "fm " + version,
+			Manual:  "fm Manual",
+		}, dir)
+	},
+}
+
+func init() {
+	genDocsCmd.Flags().String("format", "man", "Page format: man or markdown")
+	rootCmd.AddCommand(genDocsCmd)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [font]",
+	Short: "Show what fm changed on this machine",
+	Long: `Show the installs, upgrades, uninstalls, rollbacks and restores fm made,
+oldest first, with the source and version of each font and whether it
+succeeded. The history is read from the audit log; see "fm audit export" for
+the full records.`,
+	Example: `  fm history --since 7d
+  fm history FiraCode
+  fm history -n 20`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeInstalled(true),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		var since time.Time
+		if sinceFlag != "" {
+			var err error
+			if since, err = parseSince(sinceFlag); err != nil {
+				return err
+			}
+		}
+
+		entries, err := auditLog.Entries(since)
+		if err != nil {
+			return fmt.Errorf("reading history: %w", err)
+		}
+		if len(args) > 0 {
+			var matching []fm.AuditEntry
+			for _, entry := range entries {
+				if strings.EqualFold(entry.Font, args[0]) {
+					matching = append(matching, entry)
+				}
+			}
+			entries = matching
+		}
+		if limit > 0 && len(entries) > limit {
+			entries = entries[len(entries)-limit:]
+		}
+
+		if jsonOutput {
+			return writeAuditJSON(os.Stdout, entries)
+		}
+		if len(entries) == 0 {
+			fmt.Fprintln(textOut(), "No changes recorded")
+			return nil
+		}
+		printHistory(entries)
+		return nil
+	},
+}
+
+// printHistory prints a table of audit log entries
+func printHistory(entries []fm.AuditEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tACTION\tFONT\tSOURCE\tVERSION\tRESULT")
+	for _, e := range entries {
+		result := paint(os.Stdout, successColor, "ok")
+		if e.Result != "success" {
+			result = paint(os.Stdout, failColor, "failed: "+e.Error)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Time.Local().Format("2006-01-02 15:04"), e.Action, e.Font, orDash(e.Source), orDash(e.Version), result)
+	}
+	w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().String("since", "", "Only show changes newer than this age (e.g. 7d) or date")
+	historyCmd.Flags().IntP("limit", "n", 0, "Only show the most recent changes")
+}
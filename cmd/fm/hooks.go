@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/logandonley/font-manager/internal/config"
+	"github.com/logandonley/font-manager/pkg/fm"
+)
+
+// configHooks returns a hook running the shell commands of the hooks config
+// with the font described in the environment:
+//
+//	FM_HOOK          e.g. post_install
+//	FM_FONT          the font's name
+//	FM_HOOK_FONT_DIR its directory, unless it's about to be installed
+//	FM_FONT_SOURCE   the source it comes from
+//	FM_FONT_VERSION  its version, when known
+func configHooks(hooks config.HooksConfig) fm.Hook {
+	return func(ctx context.Context, event fm.HookEvent) error {
+		when := "pre"
+		if event.After {
+			when = "post"
+		}
+		for _, command := range hooks.Commands(string(event.Action), event.After) {
+			cmd := shellCommand(ctx, command)
+			cmd.Env = append(os.Environ(),
+				"FM_HOOK="+when+"_"+string(event.Action),
+				"FM_FONT="+event.Font,
+				"FM_HOOK_FONT_DIR="+event.Dir,
+				"FM_FONT_SOURCE="+event.Source,
+				"FM_FONT_VERSION="+event.Version,
+			)
+			// Keep stdout for fm's own output, e.g. with --json
+			cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("%s: %w", command, err)
+			}
+		}
+		return nil
+	}
+}
+
+// shellCommand runs command with the shell of the platform
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
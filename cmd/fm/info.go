@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/logandonley/font-manager/pkg/fontmeta"
+	"github.com/spf13/cobra"
+)
+
+var infoCmd = &cobra.Command{
+	Use:   "info <font>",
+	Short: "Show what fm recorded about an installed font",
+	Long: `Print the source, version, install time, location and note of an installed
+font, and the families, styles and variation axes its files name. Notes are
+recorded with fm install --note, or from the comment of a font's line in a
+config file.`,
+	Example:           `  fm info JetBrainsMono`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInstalled(true),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loc, err := manager.Which(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		font := loc.Font
+		faces := readFaces(loc.Files)
+
+		if jsonOutput {
+			info := newFontJSON(font)
+			info.Path, info.Scope = loc.Dir, loc.Scope
+			if info.Files, err = manager.Files(cmd.Context(), font, false); err != nil {
+				return fmt.Errorf("listing files of %s: %w", font.Name, err)
+			}
+			info.Faces = faces
+			return writeJSON(info)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		field := func(name, value string) {
+			if value != "" {
+				fmt.Fprintf(w, "%s:\t%s\n", name, value)
+			}
+		}
+		field("Name", font.Name)
+		field("Source", font.Source)
+		field("Version", font.Meta["version"])
+		field("URL", font.Meta["url"])
+		field("Installed", font.Meta["installed_at"])
+		field("Location", fmt.Sprintf("%s (%s)", loc.Dir, loc.Scope))
+		field("Files", fmt.Sprint(len(loc.Files)))
+		field("Family", strings.Join(uniqueFaceNames(faces, func(f *fontmeta.Font) string { return f.Family }), ", "))
+		field("Styles", strings.Join(uniqueFaceNames(faces, func(f *fontmeta.Font) string { return f.Style }), ", "))
+		field("Axes", formatFaceAxes(faces))
+		if fm.IsPinned(font) {
+			field("Pinned", "yes")
+		}
+		field("Note", fm.Note(font))
+		return w.Flush()
+	},
+}
+
+// readFaces reads the faces of font files, leaving out files that can't be
+// parsed
+func readFaces(paths []string) []faceJSON {
+	var faces []faceJSON
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		fonts, err := fontmeta.ParseCollection(data)
+		if err != nil {
+			continue
+		}
+		for _, font := range fonts {
+			faces = append(faces, faceJSON{File: filepath.Base(path), Font: font})
+		}
+	}
+	return faces
+}
+
+// uniqueFaceNames returns the distinct non-empty names of faces, in order
+func uniqueFaceNames(faces []faceJSON, name func(*fontmeta.Font) string) []string {
+	var names []string
+	for _, face := range faces {
+		if n := name(face.Font); n != "" && !slices.Contains(names, n) {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// formatFaceAxes describes the variation axes of faces, with each axis's
+// widest range and its default, e.g. "wght 100..900 (400)"
+func formatFaceAxes(faces []faceJSON) string {
+	var axes []fontmeta.Axis
+	for _, face := range faces {
+		for _, axis := range face.Axes {
+			i := slices.IndexFunc(axes, func(a fontmeta.Axis) bool { return a.Tag == axis.Tag })
+			if i < 0 {
+				axes = append(axes, axis)
+				continue
+			}
+			axes[i].Min = min(axes[i].Min, axis.Min)
+			axes[i].Max = max(axes[i].Max, axis.Max)
+		}
+	}
+
+	parts := make([]string, len(axes))
+	for i, axis := range axes {
+		parts[i] = fmt.Sprintf("%s %g..%g (%g)", axis.Tag, axis.Min, axis.Max, axis.Default)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// forceQuitGrace is how soon after the first Ctrl-C a second one quits
+// immediately instead of waiting for fm to stop cleanly
+const forceQuitGrace = 5 * time.Second
+
+// interrupted is closed on the first Ctrl-C
+var interrupted = make(chan struct{})
+
+// handleInterrupts cancels the context commands run with on the first Ctrl-C,
+// so installs in progress stop and roll back, and quits right away on a
+// second one within forceQuitGrace, which may leave a font half installed
+func handleInterrupts(cancel context.CancelFunc) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		var first time.Time
+		for range signals {
+			if !first.IsZero() && time.Since(first) < forceQuitGrace {
+				fmt.Fprintln(os.Stderr, "\nQuitting without cleaning up; fonts being installed may be left partially installed.")
+				fmt.Fprintln(os.Stderr, "Run 'fm doctor --fix' to remove them and restore fonts that were being reinstalled.")
+				os.Exit(exitInterrupted)
+			}
+			if first.IsZero() {
+				close(interrupted)
+			}
+			first = time.Now()
+			fmt.Fprintln(os.Stderr, "\nStopping; press Ctrl-C again to quit immediately")
+			cancel()
+		}
+	}()
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+// unknownLicense stands for licenses fm couldn't recognize
+const unknownLicense = "unknown"
+
+var licenseCmd = &cobra.Command{
+	Use:   "license [font]",
+	Short: "Show the licenses of installed fonts",
+	Long: `Print the license files installed with a font, such as LICENSE or OFL.txt,
+and the license fm recognized them as. With --all, list the license of every
+font fm installed, or with --summary how many fonts each license covers, for
+compliance reviews.`,
+	Example: `  fm license FiraCode
+  fm license --all
+  fm license --all --summary`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeInstalled(true),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		summary, _ := cmd.Flags().GetBool("summary")
+		switch {
+		case all && len(args) > 0:
+			return fmt.Errorf("--all can't be combined with a font name")
+		case summary && !all:
+			return fmt.Errorf("--summary requires --all")
+		case !all && len(args) == 0:
+			return fmt.Errorf("name a font, or use --all")
+		}
+
+		if !all {
+			license, err := manager.License(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return writeJSON(newLicenseJSON(*license))
+			}
+			return printLicense(license)
+		}
+
+		licenses, err := manager.Licenses(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if summary {
+			return printLicenseSummary(licenses)
+		}
+		if jsonOutput {
+			entries := make([]licenseJSON, len(licenses))
+			for i, license := range licenses {
+				entries[i] = newLicenseJSON(license)
+			}
+			return writeJSON(entries)
+		}
+		if len(licenses) == 0 {
+			fmt.Fprintln(textOut(), "No fonts installed by fm")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "FONT\tSOURCE\tLICENSE\tFILES")
+		for _, license := range licenses {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", license.Font.Name, orDash(license.Font.Source),
+				licenseID(license), orDash(strings.Join(license.Files, ", ")))
+		}
+		return w.Flush()
+	},
+}
+
+type licenseJSON struct {
+	Name    string   `json:"name"`
+	Source  string   `json:"source,omitempty"`
+	License string   `json:"license,omitempty"`
+	Files   []string `json:"files"`
+}
+
+func newLicenseJSON(license fm.FontLicense) licenseJSON {
+	files := license.Files
+	if files == nil {
+		files = []string{}
+	}
+	return licenseJSON{Name: license.Font.Name, Source: license.Font.Source, License: license.ID, Files: files}
+}
+
+// licenseID returns the SPDX identifier of a license, or unknownLicense
+func licenseID(license fm.FontLicense) string {
+	if license.ID == "" {
+		return unknownLicense
+	}
+	return license.ID
+}
+
+// printLicense prints the license of a font followed by its license files
+func printLicense(license *fm.FontLicense) error {
+	fmt.Printf("%s: %s\n", license.Font.Name, licenseID(*license))
+	if len(license.Files) == 0 {
+		fmt.Fprintf(textOut(), "No license files were installed with %s\n", license.Font.Name)
+		return nil
+	}
+	for _, path := range license.Files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading license: %w", err)
+		}
+		fmt.Printf("\n==> %s <==\n", path)
+		fmt.Print(string(data))
+		if !strings.HasSuffix(string(data), "\n") {
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+// printLicenseSummary prints how many fonts each license covers, most
+// common first
+func printLicenseSummary(licenses []fm.FontLicense) error {
+	fonts := make(map[string][]string)
+	for _, license := range licenses {
+		id := licenseID(license)
+		fonts[id] = append(fonts[id], license.Font.Name)
+	}
+	ids := make([]string, 0, len(fonts))
+	for id := range fonts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if len(fonts[ids[i]]) != len(fonts[ids[j]]) {
+			return len(fonts[ids[i]]) > len(fonts[ids[j]])
+		}
+		return ids[i] < ids[j]
+	})
+
+	if jsonOutput {
+		type summaryJSON struct {
+			License string   `json:"license"`
+			Count   int      `json:"count"`
+			Fonts   []string `json:"fonts"`
+		}
+		summary := make([]summaryJSON, len(ids))
+		for i, id := range ids {
+			summary[i] = summaryJSON{License: id, Count: len(fonts[id]), Fonts: fonts[id]}
+		}
+		return writeJSON(summary)
+	}
+	if len(ids) == 0 {
+		fmt.Fprintln(textOut(), "No fonts installed by fm")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LICENSE\tFONTS\tNAMES")
+	for _, id := range ids {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", id, len(fonts[id]), strings.Join(fonts[id], ", "))
+	}
+	return w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(licenseCmd)
+
+	licenseCmd.Flags().Bool("all", false, "List the license of every font fm installed")
+	licenseCmd.Flags().Bool("summary", false, "With --all, count the fonts under each license")
+}
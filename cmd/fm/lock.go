@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+// lockfilePath returns the lockfile kept next to a config file, e.g.
+// fonts.lock for fonts.txt
+func lockfilePath(config string) string {
+	return strings.TrimSuffix(config, filepath.Ext(config)) + ".lock"
+}
+
+// writeLockfile locks the fonts just installed from a config
+func writeLockfile(cmd *cobra.Command, manager *fm.DefaultManager, path string, fonts []fm.Font) error {
+	lock, err := manager.Lock(cmd.Context(), fonts)
+	if err != nil {
+		return fmt.Errorf("locking fonts: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating lockfile: %w", err)
+	}
+	defer f.Close()
+	if err := fm.WriteLockfile(f, lock); err != nil {
+		return err
+	}
+	fmt.Fprintf(textOut(), "Wrote %s\n", path)
+	return nil
+}
+
+// installLocked installs the fonts of a config as recorded in its lockfile
+func installLocked(cmd *cobra.Command, manager *fm.DefaultManager, path string, fonts []fm.Font, opts []fm.InstallOption) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening lockfile: %w", err)
+	}
+	defer f.Close()
+
+	lock, err := fm.ReadLockfile(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	fonts, err = manager.ExpandAliases(fonts)
+	if err != nil {
+		return err
+	}
+	if err := lock.Check(fonts); err != nil {
+		return fmt.Errorf("%s: %w; run fm install -f without --locked to update it", path, err)
+	}
+
+	fmt.Printf("Installing fonts locked in %s...\n", path)
+	if err := manager.InstallLocked(cmd.Context(), lock, opts...); err != nil {
+		printLimitHint(err)
+		return fmt.Errorf("installing locked fonts: %w", err)
+	}
+	fmt.Println("Successfully installed locked fonts")
+	return nil
+}
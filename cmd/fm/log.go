@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// newLogger returns the logger fm passes to the manager and its sources,
+// printing on stderr: warnings always, details of downloads with --debug
+func newLogger() *slog.Logger {
+	level := slog.LevelInfo
+	switch {
+	case debug:
+		level = slog.LevelDebug
+	case quiet:
+		level = slog.LevelWarn
+	}
+	return slog.New(&logHandler{w: os.Stderr, level: level, mu: new(sync.Mutex)})
+}
+
+// logHandler prints log records the way fm prints its own messages, e.g.
+// "Warning: failed to update font cache: <error>" or
+// "debug: Inter: extracted file=Inter.ttf size=1.2 MiB"
+type logHandler struct {
+	w     io.Writer
+	level slog.Level
+	attrs []slog.Attr
+	mu    *sync.Mutex
+}
+
+func (h *logHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *logHandler) Handle(_ context.Context, r slog.Record) error {
+	var font, details, cause string
+	add := func(a slog.Attr) bool {
+		switch a.Key {
+		case "font":
+			font = a.Value.String() + ": "
+		case "err":
+			cause = ": " + a.Value.String()
+		default:
+			value := a.Value.String()
+			if strings.ContainsAny(value, " \"=") || value == "" {
+				value = fmt.Sprintf("%q", value)
+			}
+			details += " " + a.Key + "=" + value
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		add(a)
+	}
+	r.Attrs(add)
+
+	var prefix string
+	switch {
+	case r.Level >= slog.LevelError:
+		prefix = "Error: "
+	case r.Level >= slog.LevelWarn:
+		prefix = "Warning: "
+	case r.Level < slog.LevelInfo:
+		prefix = "debug: "
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := fmt.Fprintf(h.w, "%s%s%s%s%s\n", prefix, font, r.Message, details, cause)
+	return err
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	copied := *h
+	copied.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &copied
+}
+
+// WithGroup is a no-op; fm's messages are flat
+func (h *logHandler) WithGroup(string) slog.Handler {
+	return h
+}
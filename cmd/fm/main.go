@@ -1,38 +1,395 @@
 package main
 
 import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
 
+	"github.com/logandonley/font-manager/internal/config"
+	"github.com/logandonley/font-manager/internal/platform"
 	"github.com/logandonley/font-manager/pkg/fm"
 	"github.com/spf13/cobra"
 )
 
-var manager *fm.DefaultManager
+var (
+	manager  *fm.DefaultManager
+	auditLog *fm.AuditLog
+	cache    *fm.Cache
+	cfg      *config.Config
+
+	// verbosity counts the -v flags
+	verbosity int
+
+	// quiet prints only failures, with --quiet
+	quiet bool
+
+	// debug shows requests, resolved downloads and extracted files, with
+	// --debug
+	debug bool
+
+	// noInput keeps fm from prompting, with --no-input or when run by a
+	// dotfile manager such as chezmoi
+	noInput bool
+
+	// assumeYes answers every confirmation with yes, with --yes
+	assumeYes bool
+
+	// stdinUsed is set once stdin has been read as a font list, leaving
+	// nothing to answer questions with
+	stdinUsed bool
+
+	// exitCode is the status fm exits with once a command succeeds
+	exitCode int
+
+	// version is the release fm was built from, set by goreleaser through
+	// -ldflags "-X main.version=..."
+	version = "dev"
+)
+
+// manyFonts is how many fonts an uninstall removes before it counts as
+// destructive even when they were named one by one
+const manyFonts = 10
+
+// largeDownload is the size of download fm asks about before going ahead
+const largeDownload = 200 << 20
 
 func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	handleInterrupts(cancel)
+
+	markUsageErrors(rootCmd)
+	if cmd, err := rootCmd.ExecuteContextC(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		switch status := exitStatus(err); status {
+		case exitUsage:
+			fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", cmd.CommandPath())
+			os.Exit(status)
+		case exitInterrupted:
+			os.Exit(status)
+		default:
+			printNetworkDetails(err)
+			os.Exit(status)
+		}
+	}
+	os.Exit(exitCode)
+}
+
+// setup initializes the shared state used by every command once the global
+// flags have been parsed
+func setup(cmd *cobra.Command, args []string) error {
+	noInput, _ = cmd.Flags().GetBool("no-input")
+	noInput = noInput || runByChezmoi()
+
+	if quiet && (verbosity > 0 || debug) {
+		return fmt.Errorf("--quiet can't be combined with --verbose or --debug")
+	}
+	// Debugging shows network error details as well
+	if debug && verbosity < 2 {
+		verbosity = 2
+	}
+
+	configPath, err := configFile(cmd)
+	if err != nil {
+		return err
+	}
+	if cfg, err = config.Load(configPath); err != nil {
+		return err
+	}
+	if err := firstRun(cmd, configPath); err != nil {
+		return err
+	}
+
+	auditPath, err := fm.DefaultAuditLogPath()
+	if err != nil {
+		return fmt.Errorf("locating audit log: %w", err)
+	}
+	auditLog = fm.NewAuditLog(auditPath)
+
+	cacheDir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	cache = nil
+	if cfg.Cache.Enabled == nil || *cfg.Cache.Enabled {
+		cache = fm.NewCache(cacheDir, fm.DefaultCacheTTL)
+		refresh, _ := cmd.Flags().GetBool("refresh")
+		cache.SetRefresh(refresh)
+	}
+
+	manager, err = newManager()
+	if err != nil {
+		return fmt.Errorf("initializing font manager: %w", err)
+	}
+	return nil
+}
+
+// configFile returns the config file given with --config, or the default
+func configFile(cmd *cobra.Command) (string, error) {
+	if path, _ := cmd.Flags().GetString("config"); path != "" {
+		return path, nil
+	}
+	path, err := config.DefaultPath()
+	if err != nil {
+		return "", fmt.Errorf("locating config: %w", err)
+	}
+	return path, nil
+}
+
+// cacheDir returns the cache directory set in the config, or the default.
+// FM_CACHE_DIR takes precedence over both.
+func cacheDir() (string, error) {
+	if cfg.Cache.Dir != "" && os.Getenv(fm.CacheDirEnv) == "" {
+		return cfg.Cache.Dir, nil
+	}
+	dir, err := fm.DefaultCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("locating cache: %w", err)
+	}
+	return dir, nil
+}
+
+// httpSettings returns the default HTTP settings with the proxy from the
+// config
+func httpSettings() fm.HTTPSettings {
+	settings := fm.DefaultHTTPSettings
+	settings.Proxy = cfg.Proxy
+	return settings
+}
+
+// sourceOptions returns opts with a client of its own for a source whose
+// config overrides the HTTP settings, and opts unchanged otherwise
+func sourceOptions(opts []fm.SourceOption, override *config.HTTPConfig) ([]fm.SourceOption, error) {
+	if override == nil {
+		return opts, nil
+	}
+	settings := httpSettings()
+	if override.Timeout > 0 {
+		settings.Timeout = override.Timeout
+	}
+	if override.ConnectTimeout > 0 {
+		settings.ConnectTimeout = override.ConnectTimeout
+	}
+	if override.Retries != nil {
+		settings.Retries = *override.Retries
+	}
+	if override.Proxy != "" {
+		settings.Proxy = override.Proxy
+	}
+	settings.CACertFile = override.CACertFile
+	settings.InsecureSkipVerify = override.InsecureSkipVerify
+
+	var client *http.Client
 	var err error
-	manager, err = fm.NewManager()
+	if debug {
+		client, err = newDebugClient(settings)
+	} else {
+		client, err = fm.NewHTTPClient(settings)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error initializing font manager: %v\n", err)
-		os.Exit(1)
+		return nil, err
+	}
+	return append(slices.Clip(opts), fm.WithClient(client)), nil
+}
+
+// newManager creates a font manager with the default sources registered
+func newManager(opts ...fm.ManagerOption) (*fm.DefaultManager, error) {
+	logger := newLogger()
+	sourceOpts := []fm.SourceOption{fm.WithCache(cache), fm.WithSourceLogger(logger)}
+	defaults := []fm.ManagerOption{
+		fm.WithAuditLog(auditLog),
+		fm.WithDownloadCache(cache),
+		fm.WithLogger(logger),
+	}
+	switch {
+	case debug:
+		client, err := newDebugClient(httpSettings())
+		if err != nil {
+			return nil, err
+		}
+		sourceOpts = append(sourceOpts, fm.WithClient(client))
+		defaults = append(defaults, fm.WithHTTPClient(client), fm.WithProgress(debugProgress))
+	case cfg.Proxy != "":
+		client, err := fm.NewHTTPClient(httpSettings())
+		if err != nil {
+			return nil, err
+		}
+		sourceOpts = append(sourceOpts, fm.WithClient(client))
+		defaults = append(defaults, fm.WithHTTPClient(client))
+	}
+	defaults = append(defaults, fm.WithAliases(fm.NewAliasIndex(fm.DefaultAliasIndexURL, sourceOpts...)))
+	if len(cfg.Aliases) > 0 {
+		defaults = append(defaults, fm.WithUserAliases(cfg.Aliases))
+	}
+	for _, host := range cfg.Hosts {
+		header := make(http.Header)
+		for name, value := range host.Headers {
+			header.Set(name, value)
+		}
+		defaults = append(defaults, fm.WithHostCredentials(host.Host, fm.HostCredentials{
+			Username: host.Username,
+			Password: host.Password,
+			Headers:  header,
+		}))
+	}
+	if len(cfg.CacheCommand) > 0 || cfg.ResetFontServer != nil {
+		defaults = append(defaults, fm.WithCacheSettings(fm.CacheSettings{
+			Command:             cfg.CacheCommand,
+			SkipFontServerReset: cfg.ResetFontServer != nil && !*cfg.ResetFontServer,
+		}))
+	}
+	if len(cfg.InstancerCommand) > 0 {
+		defaults = append(defaults, fm.WithInstancer(cfg.InstancerCommand...))
+	}
+	defaults = append(defaults, fm.WithHook(configHooks(cfg.Hooks)))
+	if len(cfg.AllowedLicenses) > 0 {
+		defaults = append(defaults, fm.WithAllowedLicenses(cfg.AllowedLicenses...))
+	}
+	if len(cfg.SourcePriority) > 0 {
+		defaults = append(defaults, fm.WithSourcePriority(cfg.SourcePriority...))
+	}
+	for source, variants := range cfg.DefaultVariants {
+		defaults = append(defaults, fm.WithDefaultVariants(source, variants...))
+	}
+	if cfg.Format != "" {
+		defaults = append(defaults, fm.WithDefaultFormat(cfg.Format))
+	}
+	if cfg.Parallelism > 0 {
+		defaults = append(defaults, fm.WithDefaultParallelism(cfg.Parallelism))
+	}
+	if cfg.Scope == config.ScopeSystem {
+		defaults = append(defaults, fm.WithSystemScope())
+	}
+	// FM_FONT_DIR has the platform use its directory already
+	if cfg.FontDir != "" && os.Getenv(fm.FontDirEnv) == "" {
+		defaults = append(defaults, fm.WithFontDir(cfg.FontDir))
+	}
+	defaults = append(defaults, fm.WithScanOptions(fm.ScanOptions{
+		MaxDepth:      cfg.Scan.MaxDepth,
+		Exclude:       cfg.Scan.Exclude,
+		ScanLargeDirs: cfg.Scan.SkipLargeDirs != nil && !*cfg.Scan.SkipLargeDirs,
+	}))
+	if cfg.Cache.Rehash {
+		defaults = append(defaults, fm.WithRehash())
+	}
+	if lowMemoryMode() {
+		defaults = append(defaults, fm.WithLowMemory())
+	}
+	if dir, err := fm.DefaultRollbackDir(); err == nil {
+		defaults = append(defaults, fm.WithRollbackDir(dir))
+	}
+	if cfg.Layout == config.LayoutStore {
+		store, err := defaultStore()
+		if err != nil {
+			return nil, err
+		}
+		defaults = append(defaults, fm.WithStore(store))
+	}
+	mgr, err := fm.NewManager(append(defaults, opts...)...)
+	if err != nil {
+		return nil, err
 	}
 
 	// Register default sources
-	if err := manager.RegisterSource(fm.NewNerdFontsSource()); err != nil {
-		fmt.Fprintf(os.Stderr, "Error registering NerdFonts source: %v\n", err)
-		os.Exit(1)
+	builtins := []struct {
+		name, label string
+		source      func(opts ...fm.SourceOption) fm.Source
+	}{
+		{"cjk", "CJK source", func(opts ...fm.SourceOption) fm.Source { return fm.NewCJKSource(opts...) }},
+		{"nerdfonts", "NerdFonts source", func(opts ...fm.SourceOption) fm.Source { return fm.NewNerdFontsSource(opts...) }},
+		{"fontsource", "FontSource API", func(opts ...fm.SourceOption) fm.Source { return fm.NewFontSourceAPI(opts...) }},
+		{"mscorefonts", "Microsoft core fonts source", func(opts ...fm.SourceOption) fm.Source { return fm.NewMSCoreFontsSource(opts...) }},
+		{"ipfs", "IPFS source", func(opts ...fm.SourceOption) fm.Source { return fm.NewIPFSSource(cfg.IPFS.Gateway, opts...) }},
 	}
-	if err := manager.RegisterSource(fm.NewFontSourceAPI()); err != nil {
-		fmt.Fprintf(os.Stderr, "Error registering FontSource API: %v\n", err)
-		os.Exit(1)
+	for _, builtin := range builtins {
+		opts, err := sourceOptions(sourceOpts, cfg.SourceHTTP[builtin.name])
+		if err != nil {
+			return nil, fmt.Errorf("source %q: %w", builtin.name, err)
+		}
+		if err := mgr.RegisterSource(builtin.source(opts...)); err != nil {
+			return nil, fmt.Errorf("registering %s: %w", builtin.label, err)
+		}
 	}
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	// Register custom sources defined in the config file
+	for _, sc := range cfg.Sources {
+		opts, err := sourceOptions(sourceOpts, sc.HTTP)
+		if err != nil {
+			return nil, fmt.Errorf("source %q: %w", sc.Name, err)
+		}
+		var source fm.Source
+		switch sc.Type {
+		case config.SourceTypeDirectory:
+			source, err = fm.NewDirectorySource(sc.Name, sc.URL, opts...)
+		default:
+			source, err = fm.NewTemplateSource(sc.Name, sc.URL, sc.Index, opts...)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("source %q: %w", sc.Name, err)
+		}
+		if err := mgr.RegisterSource(source); err != nil {
+			return nil, fmt.Errorf("registering source %q: %w", sc.Name, err)
+		}
+	}
+
+	return mgr, nil
+}
+
+// managerForUser returns the font manager for the user named by the --user
+// flag, or the default manager when the flag is unset
+func managerForUser(cmd *cobra.Command) (*fm.DefaultManager, error) {
+	username, _ := cmd.Flags().GetString("user")
+	system, _ := cmd.Flags().GetBool("system")
+	switch {
+	case username != "" && system:
+		return nil, fmt.Errorf("--user and --system can't be combined")
+	case system:
+		return newManager(fm.WithSystemScope())
+	case username == "":
+		return manager, nil
+	}
+
+	p, err := fm.NewPlatformForUser(username)
+	if err != nil {
+		return nil, err
 	}
+	return newManager(fm.WithPlatform(p))
+}
+
+// elevate reruns the command line as root through sudo unless fm already
+// runs as root, reporting whether it did; the elevated run has done the work
+// then. A failed elevated run exits with its status, having printed its own
+// errors.
+func elevate(cmd *cobra.Command, reason string) (bool, error) {
+	if platform.IsRoot() {
+		return false, nil
+	}
+	fmt.Fprintf(os.Stderr, "%s requires root privileges\n", reason)
+
+	// Root would otherwise read its own config
+	args := os.Args[1:]
+	if path, err := configFile(cmd); err == nil && !cmd.Flags().Changed("config") && config.Exists(path) {
+		args = append([]string{"--config", path}, args...)
+	}
+
+	err := platform.RunElevated(args, noInput)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	return err == nil, err
 }
 
 var rootCmd = &cobra.Command{
@@ -41,6 +398,8 @@ var rootCmd = &cobra.Command{
 	Long: `A font manager that supports multiple sources including:
 - Nerd Fonts
 - FontSource
+- Noto CJK and Source Han (region-specific subsets)
+- Microsoft Core Fonts for the Web
 - Direct URLs
 
 Examples:
@@ -55,13 +414,26 @@ Examples:
 
   # Install multiple fonts from a config file
   fm install -f fonts.txt`,
+	PersistentPreRunE: setup,
+	// main prints errors, and the usage hint only for usage errors
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	// Without a command, open the browser in a terminal and show the help
+	// otherwise
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if browseSupported && !noInput && isTerminal(os.Stdin) && isTerminal(os.Stdout) {
+			return runBrowse(cmd.Context())
+		}
+		return cmd.Help()
+	},
 }
 
 var installCmd = &cobra.Command{
 	Use:   "install [font names...] | -f <file>",
 	Short: "Install one or more fonts",
 	Long: `Install one or more fonts from any supported source.
-You can specify multiple fonts and mix sources:
+You can specify multiple fonts and mix sources. "-f -" reads the font list
+from stdin, as does piping it in without any font names.
 
 Examples:
   # Install a single font
@@ -76,127 +448,1178 @@ Examples:
   # Install from URLs and sources together
   fm install "FiraCode@nerdfonts" https://example.com/font.zip
 
+  # Install the Traditional Chinese subset of a CJK family
+  fm install "Noto Sans CJK" --region TC
+
+  # Install only a few styles of a family
+  fm install "FiraCode@nerdfonts" --variants Regular,Bold,Italic
+
+  # Install Microsoft's core fonts, accepting their license agreement
+  fm install "Arial@mscorefonts" "Verdana@mscorefonts" --accept-eula
+
+  # Replace a corrupted or partially installed font
+  fm install "FiraCode" --force
+
+  # Install the variable build of a font limited to weights 400 to 700
+  fm install Inter --axes wght=400..700
+
+  # Check that the downloaded files load and have the Powerline glyphs
+  fm install "FiraCode@nerdfonts" --verify-render
+
+  # Remember why a font was installed; fm info shows the note
+  fm install "Montserrat" --note "for client X branding project"
+
+  # Install a font whose license isn't in allowed_licenses
+  fm install "Arial@mscorefonts" --accept-eula --override-license
+
+  # Install for every user into the system font directory, using sudo
+  fm install --system "Inter"
+
+  # Install into another user's font directory (requires root)
+  sudo fm install --user alice "FiraCode"
+
+  # Install from a URL that requires a token
+  fm install https://fonts.example.com/acme.zip --header "Authorization: Bearer $TOKEN"
+
   # Install multiple fonts from a config file
-  fm install -f fonts.txt`,
+  fm install -f fonts.txt
+
+  # Install the fonts another tool lists, one spec per line
+  grep font dotfiles.txt | fm install -f -
+
+  # Layer config files; later files override fonts listed in earlier ones
+  fm install -f base.txt -f work.txt
+
+  # Show where every font would come from without installing anything
+  fm install -f base.txt -f work.txt --dry-run
+
+  # Check whether anything would change, for configuration management
+  fm install --json -f fonts.txt --check --detailed-exit-code
+
+  # Reproduce the exact downloads recorded in fonts.lock
+  fm install -f fonts.txt --locked
+
+  # Install a bundle made with fm bundle, without network access
+  fm install --from-bundle fonts-bundle.tar.zst`,
 	Args: func(cmd *cobra.Command, args []string) error {
-		fileFlag, _ := cmd.Flags().GetString("file")
-		if fileFlag != "" {
+		files, _ := cmd.Flags().GetStringArray("file")
+		if bundle, _ := cmd.Flags().GetString("from-bundle"); bundle != "" {
+			if len(args) > 0 || len(files) > 0 {
+				return fmt.Errorf("--from-bundle installs the fonts of the bundle; no font names or -f can be given")
+			}
+			return nil
+		}
+		if len(files) > 0 {
 			if len(args) > 0 {
 				return fmt.Errorf("when using -f flag, no additional arguments should be provided")
 			}
 			return nil
 		}
-		if len(args) < 1 {
+		if locked, _ := cmd.Flags().GetBool("locked"); locked {
+			return fmt.Errorf("--locked requires -f")
+		}
+		if len(args) < 1 && isTerminal(os.Stdin) {
 			return fmt.Errorf("requires at least 1 font name when not using -f flag")
 		}
 		return nil
 	},
+	ValidArgsFunction: completeInstallable,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		configFile, _ := cmd.Flags().GetString("file")
-		if configFile != "" {
-			file, err := os.Open(configFile)
+		var opts []fm.InstallOption
+		if region, _ := cmd.Flags().GetString("region"); region != "" {
+			if !slices.Contains(fm.CJKRegions, strings.ToUpper(region)) {
+				return fmt.Errorf("invalid region %q (expected one of %s)", region, strings.Join(fm.CJKRegions, ", "))
+			}
+			opts = append(opts, fm.WithRegion(region))
+		}
+
+		limits, err := limitsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, fm.WithLimits(limits))
+
+		depth, _ := cmd.Flags().GetInt("archive-depth")
+		opts = append(opts, fm.WithArchiveDepth(depth))
+
+		if subsets, _ := cmd.Flags().GetStringSlice("subset"); len(subsets) > 0 {
+			opts = append(opts, fm.WithSubsets(subsets...))
+		}
+		if variants, _ := cmd.Flags().GetStringSlice("variants"); len(variants) > 0 {
+			opts = append(opts, fm.WithVariants(variants...))
+		}
+		if format, _ := cmd.Flags().GetString("format"); format != "" {
+			if !slices.Contains(fm.FontFormats, strings.ToLower(format)) {
+				return fmt.Errorf("invalid format %q (expected one of %s)", format, strings.Join(fm.FontFormats, ", "))
+			}
+			opts = append(opts, fm.WithFormat(format))
+		}
+		if ranges, _ := cmd.Flags().GetString("unicode-range"); ranges != "" {
+			opts = append(opts, fm.WithUnicodeRanges(ranges))
+		}
+		if value, _ := cmd.Flags().GetString("axes"); value != "" {
+			axes, err := fm.ParseAxes(value)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, fm.WithAxes(axes...))
+		}
+		if accept, _ := cmd.Flags().GetBool("accept-eula"); accept {
+			opts = append(opts, fm.WithAcceptEULA())
+		}
+		if override, _ := cmd.Flags().GetBool("override-license"); override {
+			opts = append(opts, fm.WithLicenseOverride())
+		}
+		if verify, _ := cmd.Flags().GetBool("verify-render"); verify {
+			opts = append(opts, fm.WithVerifyRender())
+		}
+		if note, _ := cmd.Flags().GetString("note"); note != "" {
+			opts = append(opts, fm.WithNote(note))
+		}
+		if headers, _ := cmd.Flags().GetStringArray("header"); len(headers) > 0 {
+			header, err := parseHeaders(headers)
 			if err != nil {
-				return fmt.Errorf("opening config file: %w", err)
+				return err
 			}
-			defer file.Close()
+			opts = append(opts, fm.WithHeaders(header))
+		}
+		if force, _ := cmd.Flags().GetBool("force"); force {
+			opts = append(opts, fm.WithForce())
+		}
+		if jobs, ok := jobsFlag(cmd); ok {
+			opts = append(opts, fm.WithParallelism(jobs))
+		}
+		if low, _ := cmd.Flags().GetBool("low-priority"); low {
+			lowerPriority()
+		}
 
-			fmt.Printf("Installing fonts from %s...\n", configFile)
-			if err := manager.InstallFromConfig(cmd.Context(), file); err != nil {
-				return fmt.Errorf("installing fonts from config: %w", err)
+		// --check is the dry run whose outcome is read by tools such as
+		// Ansible or Salt
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		check, _ := cmd.Flags().GetBool("check")
+		dryRun = dryRun || check
+
+		if system, _ := cmd.Flags().GetBool("system"); system {
+			if !dryRun {
+				if elevated, err := elevate(cmd, "Installing fonts for every user"); elevated || err != nil {
+					return err
+				}
 			}
-			fmt.Println("Successfully installed fonts from config file")
+		}
+
+		manager, err := managerForUser(cmd)
+		if err != nil {
+			return err
+		}
+
+		// Bundles hold every download, so nothing is fetched to confirm
+		if bundle, _ := cmd.Flags().GetString("from-bundle"); bundle != "" {
+			if dryRun {
+				return fmt.Errorf("--dry-run and --check can't be combined with --from-bundle")
+			}
+			if locked, _ := cmd.Flags().GetBool("locked"); locked {
+				return fmt.Errorf("--locked can't be combined with --from-bundle; bundles are checked against their own checksums")
+			}
+			fmt.Fprintf(textOut(), "Installing fonts from %s...\n", bundle)
+			changed, err := installBundle(cmd, manager, bundle, opts)
+			if err != nil {
+				return err
+			}
+			reportChanged(cmd, changed)
+			return nil
+		}
+
+		if !dryRun && shouldConfirm(cmd, true) {
+			opts = append(opts, fm.WithConfirmDownload(largeDownload, confirmDownload()))
+		}
+
+		// Font names piped in are read like a config file
+		configFiles, _ := cmd.Flags().GetStringArray("file")
+		piped := len(configFiles) == 0 && len(args) == 0
+		if piped {
+			configFiles = []string{stdinConfig}
+		}
+		if len(configFiles) > 0 {
+			fonts, parseErr := readConfigs(configFiles)
+			if fonts == nil && parseErr != nil {
+				return parseErr
+			}
+			if piped && len(fonts) == 0 {
+				return fmt.Errorf("requires at least 1 font name when not using -f flag")
+			}
+
+			if dryRun {
+				changed, err := planInstall(cmd, manager, fonts, opts)
+				if err := errors.Join(parseErr, err); err != nil {
+					return err
+				}
+				reportChanged(cmd, changed)
+				return nil
+			}
+			if !confirmSystemInstall(cmd, len(fonts)) {
+				return nil
+			}
+
+			// Fonts from stdin have no config file to keep a lockfile next to
+			lockPath := ""
+			if configFiles[0] != stdinConfig {
+				lockPath = lockfilePath(configFiles[0])
+			}
+			if locked, _ := cmd.Flags().GetBool("locked"); locked {
+				if lockPath == "" {
+					return fmt.Errorf("--locked requires a config file rather than stdin")
+				}
+				if parseErr != nil {
+					return parseErr
+				}
+				return installLocked(cmd, manager, lockPath, fonts, opts)
+			}
+
+			fmt.Fprintf(textOut(), "Installing fonts from %s...\n", configNames(configFiles))
+			changed, err := installConfig(cmd, manager, fonts, opts)
+			if err := errors.Join(parseErr, err); err != nil {
+				return err
+			}
+			if lockPath != "" {
+				if err := writeLockfile(cmd, manager, lockPath, fonts); err != nil {
+					return err
+				}
+			}
+			reportChanged(cmd, changed)
+			return nil
+		}
+
+		if dryRun {
+			var fonts []fm.Font
+			for _, name := range args {
+				font, err := fm.ParseFontSpec(name)
+				if err != nil {
+					return err
+				}
+				if font != nil {
+					fonts = append(fonts, *font)
+				}
+			}
+			changed, err := planInstall(cmd, manager, fonts, opts)
+			if err != nil {
+				return err
+			}
+			reportChanged(cmd, changed)
+			return nil
+		}
+
+		if !confirmSystemInstall(cmd, len(args)) {
+			return nil
+		}
+
+		// Several fonts are installed at once, refreshing the cache once
+		if len(args) > 1 {
+			var fonts []fm.Font
+			for _, name := range args {
+				font, err := fm.ParseFontSpec(name)
+				if err != nil {
+					return err
+				}
+				if font != nil {
+					fonts = append(fonts, *font)
+				}
+			}
+			fmt.Fprintf(textOut(), "Installing %d fonts...\n", len(fonts))
+			changed, err := installConfig(cmd, manager, fonts, opts)
+			if err != nil {
+				return err
+			}
+			reportChanged(cmd, changed)
 			return nil
 		}
 
 		// Track installation results
-		var failed []string
-		var skipped []string
-		successful := 0
+		var results []installResult
+		var errs []error
+		var summary installSummaryJSON
 
-		// Install each font specified
+		// Install each font specified, until interrupted
+		out := textOut()
 		for _, name := range args {
-			fmt.Printf("Installing %s...\n", name)
-			if err := manager.Install(cmd.Context(), name); err != nil {
-				if strings.Contains(err.Error(), "already installed") {
-					fmt.Printf("Skipped %s (already installed)\n", name)
-					skipped = append(skipped, name)
+			if cmd.Context().Err() != nil {
+				break
+			}
+			fmt.Fprintf(out, "Installing %s...\n", name)
+			err := manager.Install(cmd.Context(), name, opts...)
+			summary.add(cmd, manager, name, installNameOf(name), err)
+			results = append(results, installResult{name: name, err: err})
+			errs = append(errs, err)
+			if err != nil {
+				if errors.Is(err, fm.ErrAlreadyInstalled) {
+					fmt.Fprintf(out, "%s %s (already installed)\n", paint(out, skipColor, "Skipped"), name)
 					continue
 				}
-				fmt.Fprintf(os.Stderr, "Error installing %s: %v\n", name, err)
-				failed = append(failed, name)
+				fmt.Fprintf(os.Stderr, "%s %s: %v\n", paint(os.Stderr, failColor, "Error installing"), name, err)
+				printLimitHint(err)
+				printNetworkDetails(err)
 				continue
 			}
-			fmt.Printf("Successfully installed %s\n", name)
-			successful++
+			fmt.Fprintf(out, "%s %s\n", paint(out, successColor, "Successfully installed"), name)
 		}
 
-		// Print summary
-		fmt.Printf("\nInstallation Summary:\n")
-		fmt.Printf("Successfully installed: %d\n", successful)
-		if len(skipped) > 0 {
-			fmt.Printf("Skipped (already installed): %d\n", len(skipped))
-			for _, name := range skipped {
-				fmt.Printf("  - %s\n", name)
+		if jsonOutput {
+			if err := summary.write(); err != nil {
+				return err
 			}
-		}
-		if len(failed) > 0 {
-			fmt.Printf("Failed to install: %d\n", len(failed))
-			fmt.Println("Failed fonts:")
-			for _, name := range failed {
-				fmt.Printf("  - %s\n", name)
+			if err := cmd.Context().Err(); err != nil {
+				return fmt.Errorf("installation interrupted: %w", err)
+			}
+			if err := fontsFailed("install", errs); err != nil {
+				return err
 			}
-			return fmt.Errorf("some fonts failed to install")
+			reportChanged(cmd, summary.Changed)
+			return nil
 		}
 
+		if err := printInstallSummary(out, results); err != nil {
+			return err
+		}
+		if err := cmd.Context().Err(); err != nil {
+			return fmt.Errorf("installation interrupted: %w", err)
+		}
+		if err := fontsFailed("install", errs); err != nil {
+			return err
+		}
+		reportChanged(cmd, slices.ContainsFunc(results, func(result installResult) bool {
+			return result.err == nil
+		}))
 		return nil
 	},
 }
 
+// jobsFlag returns how many fonts to install at once given with --jobs, or
+// its deprecated spelling --parallel, reporting whether either was given.
+// Otherwise the manager installs up to the config's parallelism at once.
+func jobsFlag(cmd *cobra.Command) (int, bool) {
+	for _, name := range []string{"jobs", "parallel"} {
+		if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+			jobs, _ := cmd.Flags().GetInt(name)
+			return jobs, true
+		}
+	}
+	return 0, false
+}
+
+// confirmSystemInstall asks before installing fonts for every user, with
+// --system or the system scope in the config, reporting whether to go ahead
+func confirmSystemInstall(cmd *cobra.Command, count int) bool {
+	system, _ := cmd.Flags().GetBool("system")
+	if !system && cfg.Scope != config.ScopeSystem || !shouldConfirm(cmd, true) {
+		return true
+	}
+	return confirm(fmt.Sprintf("Install %d fonts for every user into the system font directory?", count))
+}
+
+// confirmDownload returns the question asked before large downloads. Parallel
+// installs ask one at a time.
+func confirmDownload() func(fm.Font, int64) bool {
+	var mu sync.Mutex
+	return func(font fm.Font, size int64) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return confirm(fmt.Sprintf("Download %s of %s?", fm.FormatSize(size), font.Name))
+	}
+}
+
+// reportChanged makes fm exit with exitChanged when fonts were installed,
+// or would be with --check, if --detailed-exit-code asks for it
+func reportChanged(cmd *cobra.Command, changed bool) {
+	if detailed, _ := cmd.Flags().GetBool("detailed-exit-code"); detailed && changed {
+		exitCode = exitChanged
+	}
+}
+
 var uninstallCmd = &cobra.Command{
-	Use:   "uninstall [font name]",
-	Short: "Uninstall a font",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		name := args[0]
-		fmt.Printf("Uninstalling %s...\n", name)
-		if err := manager.Uninstall(cmd.Context(), name); err != nil {
-			return fmt.Errorf("uninstalling %s: %w", name, err)
+	Use:   "uninstall [font names or patterns...] | -f <file> | --source <source>",
+	Short: "Uninstall one or more fonts",
+	Long: `Uninstall one or more fonts, by name or every font listed in config files.
+The font cache is updated once after all of them are removed.
+
+Names with *, ? or [ are glob patterns matched against the installed fonts,
+ignoring case, and --source limits the matches to fonts from one source, or
+selects all of them without a pattern. The matching fonts are listed and
+removed after confirming.
+
+Fonts installed with --system are removed from the system font directory,
+rerunning fm with sudo when it isn't root.
+
+Examples:
+  # Uninstall a single font
+  fm uninstall "FiraCode"
+
+  # Uninstall multiple fonts
+  fm uninstall "FiraCode" "RobotoMono" "JetBrainsMono"
+
+  # Uninstall the fonts listed in a config file
+  fm uninstall -f fonts.txt
+
+  # Uninstall every installed Fira font
+  fm uninstall 'Fira*'
+
+  # Uninstall every font installed from Nerd Fonts, without asking
+  fm uninstall --source nerdfonts --yes
+
+  # Show what would be removed without removing anything
+  fm uninstall "FiraCode" --dry-run`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		files, _ := cmd.Flags().GetStringArray("file")
+		if len(files) > 0 {
+			if len(args) > 0 {
+				return fmt.Errorf("when using -f flag, no additional arguments should be provided")
+			}
+			return nil
+		}
+		if source, _ := cmd.Flags().GetString("source"); len(args) < 1 && source == "" {
+			return fmt.Errorf("requires at least 1 font name when not using -f or --source")
 		}
-		fmt.Printf("Successfully uninstalled %s\n", name)
 		return nil
 	},
+	ValidArgsFunction: completeInstalled(false),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manager, err := managerForUser(cmd)
+		if err != nil {
+			return err
+		}
+
+		var fonts []fm.Font
+		var parseErr error
+		if configFiles, _ := cmd.Flags().GetStringArray("file"); len(configFiles) > 0 {
+			fonts, parseErr = readConfigs(configFiles)
+			if fonts == nil && parseErr != nil {
+				return parseErr
+			}
+		}
+		named, matched, err := matchUninstall(cmd, manager, args)
+		if err != nil {
+			return err
+		}
+		fonts = append(fonts, named...)
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if !dryRun && installedSystemWide(cmd, manager, fonts) {
+			if elevated, err := elevate(cmd, "Uninstalling fonts installed for every user"); elevated || err != nil {
+				return err
+			}
+		}
+		// Fonts matched by a pattern or --source weren't named one by one
+		if !dryRun && shouldConfirm(cmd, matched || len(fonts) >= manyFonts) {
+			fmt.Println("The following fonts will be uninstalled:")
+			for _, font := range fonts {
+				if font.Source != "" {
+					fmt.Printf("  %s (%s)\n", font.Name, font.Source)
+				} else {
+					fmt.Printf("  %s\n", font.Name)
+				}
+			}
+			if !confirm(fmt.Sprintf("Uninstall %d fonts?", len(fonts))) {
+				return nil
+			}
+		}
+
+		if dryRun {
+			for i, font := range fonts {
+				if i > 0 {
+					fmt.Println()
+				}
+				impact, err := manager.UninstallImpact(cmd.Context(), uninstallName(font))
+				if err != nil {
+					return err
+				}
+				printUninstallImpact(impact)
+			}
+			return parseErr
+		}
+
+		var failed []string
+		successful := 0
+		results := manager.UninstallFonts(cmd.Context(), fonts)
+		for i, err := range results {
+			name := uninstallName(fonts[i])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s %s: %v\n", paint(os.Stderr, failColor, "Error uninstalling"), name, err)
+				failed = append(failed, name)
+				continue
+			}
+			out := textOut()
+			fmt.Fprintf(out, "%s %s\n", paint(out, successColor, "Successfully uninstalled"), name)
+			successful++
+		}
+
+		// Print summary
+		out := textOut()
+		fmt.Fprintf(out, "\nUninstallation Summary:\n")
+		fmt.Fprintf(out, "Successfully uninstalled: %d\n", successful)
+		if len(failed) > 0 {
+			fmt.Fprintf(out, "Failed to uninstall: %d\n", len(failed))
+			fmt.Fprintln(out, "Failed fonts:")
+			for _, name := range failed {
+				fmt.Fprintf(out, "  - %s\n", name)
+			}
+			return errors.Join(parseErr, fontsFailed("uninstall", results))
+		}
+		return parseErr
+	},
+}
+
+// matchUninstall returns the fonts args name, expanding patterns and
+// --source into the installed fonts they match, and whether any were
+// matched that way. Plain names are returned as given unless --source is
+// set, when they have to match a font from that source too.
+func matchUninstall(cmd *cobra.Command, manager *fm.DefaultManager, args []string) ([]fm.Font, bool, error) {
+	source, _ := cmd.Flags().GetString("source")
+	if source != "" && len(args) == 0 {
+		args = []string{""}
+	}
+
+	var fonts []fm.Font
+	matched := false
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		if !fm.IsPattern(arg) && source == "" {
+			fonts = append(fonts, fm.Font{Name: arg})
+			continue
+		}
+
+		matches, err := manager.MatchInstalled(cmd.Context(), arg, source)
+		if err != nil {
+			return nil, false, err
+		}
+		switch {
+		case len(matches) > 0:
+			matched = true
+		case arg == "":
+			return nil, false, fmt.Errorf("no installed fonts from %s", source)
+		case source != "":
+			return nil, false, fmt.Errorf("no installed fonts from %s match %q", source, arg)
+		default:
+			return nil, false, fmt.Errorf("no installed fonts match %q", arg)
+		}
+		for _, font := range matches {
+			if !seen[font.Name] {
+				seen[font.Name] = true
+				fonts = append(fonts, font)
+			}
+		}
+	}
+	return fonts, matched, nil
+}
+
+// installedSystemWide reports whether fm installed any of fonts into the
+// system font directory
+func installedSystemWide(cmd *cobra.Command, manager *fm.DefaultManager, fonts []fm.Font) bool {
+	for _, font := range fonts {
+		// Fonts that can't be found are reported by the uninstall itself
+		loc, err := manager.Which(cmd.Context(), uninstallName(font))
+		if err == nil && loc.Scope == fm.ScopeSystem && loc.Font.Meta["scope"] == fm.ScopeSystem {
+			return true
+		}
+	}
+	return false
+}
+
+// uninstallName is the name a font from the command line or a config file is
+// installed under
+func uninstallName(font fm.Font) string {
+	if name := font.Options["install_as"]; name != "" {
+		return name
+	}
+	return font.Name
+}
+
+// planInstall prints where every font would be installed from, without
+// installing anything
+func planInstall(cmd *cobra.Command, manager *fm.DefaultManager, fonts []fm.Font, opts []fm.InstallOption) (bool, error) {
+	plans, err := manager.PlanInstall(cmd.Context(), fonts, opts...)
+	if err != nil {
+		return false, err
+	}
+
+	changed := slices.ContainsFunc(plans, func(plan fm.PlannedInstall) bool {
+		return plan.Err == nil && !plan.Installed
+	})
+	failed := 0
+	for _, plan := range plans {
+		if plan.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		err = fmt.Errorf("%d of %d fonts would fail to install", failed, len(plans))
+	}
+
+	if jsonOutput {
+		return changed, errors.Join(writePlanJSON(plans, changed), err)
+	}
+
+	for _, plan := range plans {
+		spec := fm.FormatFontSpec(plan.Font)
+		switch {
+		case plan.Err != nil:
+			fmt.Printf("! %s: %v\n", spec, plan.Err)
+		case plan.Installed:
+			fmt.Printf("= %s (already installed)\n", spec)
+		case plan.Reinstall != "":
+			fmt.Printf("~ %s%s (reinstalling: %s)\n", spec, planSummary(plan), plan.Reinstall)
+		default:
+			fmt.Printf("+ %s%s\n", spec, planSummary(plan))
+		}
+		if plan.Err == nil && !plan.Installed && plan.URL != "" {
+			fmt.Printf("    %s\n", plan.URL)
+		}
+	}
+
+	fmt.Println("Dry run: nothing was installed")
+	return changed, err
+}
+
+// planSummary describes the source and version a plan resolved
+func planSummary(plan fm.PlannedInstall) string {
+	summary := " from " + plan.Source
+	if plan.Version != "" {
+		summary += " " + plan.Version
+	}
+	return summary
+}
+
+// printUninstallImpact prints what uninstalling a font would remove and
+// what depends on it
+func printUninstallImpact(impact *fm.UninstallImpact) {
+	fmt.Printf("Would remove %s (%s):\n", impact.Font.Name, impact.Dir)
+	for _, file := range impact.Files {
+		fmt.Printf("  %s\n", file)
+	}
+
+	if len(impact.Families) > 0 {
+		fmt.Printf("\nFamilies: %s\n", strings.Join(impact.Families, ", "))
+	}
+
+	if len(impact.Used) == 0 {
+		fmt.Println("\nNo application configs reference this font")
+	} else {
+		fmt.Println("\nReferenced by application configs:")
+		for _, ref := range impact.Used {
+			fmt.Printf("  %s:%d: %s\n", ref.Path, ref.Line, ref.Text)
+		}
+	}
+
+	if len(impact.Shared) > 0 {
+		fmt.Println("\nShared with other installed fonts, which keep their copies:")
+		for _, file := range impact.Shared {
+			fmt.Printf("  %s: %s\n", file.Name, strings.Join(file.Fonts, ", "))
+		}
+	}
+
+	fmt.Println("\nDry run: nothing was removed")
 }
 
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List installed fonts",
+	Long: `List the installed fonts, those fm installed and the ones already on the
+system.
+
+--filter keeps the fonts whose name contains a substring, ignoring case,
+--installed-after the fonts fm installed after a date or within an age, and
+--source the fonts installed from a source. --source system lists the fonts
+fm didn't install.
+--sort orders them by name, by install date with the newest first, or by size
+with the largest first.`,
+	Example: `  # Every font with its version, install date and disk usage
+  fm list --long
+
+  # The ten largest fonts
+  fm list --long --sort size | head -n 11
+
+  # Fonts installed in the last week, newest first
+  fm list --installed-after 7d --sort date
+
+  # Every Fira font
+  fm list --filter fira
+
+  # Fonts that came with the system or were copied in by hand
+  fm list --source system`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		fonts, err := manager.List(cmd.Context())
 		if err != nil {
 			return fmt.Errorf("listing fonts: %w", err)
 		}
+		if fonts, err = selectListed(cmd, fonts); err != nil {
+			return err
+		}
+
+		showFiles, _ := cmd.Flags().GetBool("files")
+		rehash, _ := cmd.Flags().GetBool("rehash")
+
+		if jsonOutput {
+			list := make([]fontJSON, len(fonts))
+			for i, font := range fonts {
+				list[i] = newFontJSON(font)
+				if showFiles || rehash {
+					if list[i].Files, err = manager.Files(cmd.Context(), font, rehash); err != nil {
+						return fmt.Errorf("listing files of %s: %w", font.Name, err)
+					}
+				}
+			}
+			return writeJSON(list)
+		}
 
 		if len(fonts) == 0 {
+			if cmd.Flags().Changed("filter") || cmd.Flags().Changed("installed-after") || cmd.Flags().Changed("source") {
+				fmt.Println("No installed fonts match")
+				return nil
+			}
 			fmt.Println("No fonts installed")
 			return nil
 		}
 
+		if long, _ := cmd.Flags().GetBool("long"); long {
+			return printListTable(fonts)
+		}
+
 		fmt.Println("Installed fonts:")
 		for _, font := range fonts {
+			line := "  - " + font.Name
 			if font.Source != "" {
-				fmt.Printf("  - %s (from %s)\n", font.Name, font.Source)
-			} else {
-				fmt.Printf("  - %s\n", font.Name)
+				line += fmt.Sprintf(" (from %s)", font.Source)
+			}
+			if fm.IsPinned(font) {
+				line += " [pinned]"
+			}
+			if note := fm.Note(font); note != "" {
+				line += "  # " + note
+			}
+			fmt.Println(line)
+
+			if showFiles || rehash {
+				if err := printFiles(cmd, font, rehash); err != nil {
+					return err
+				}
 			}
 		}
 		return nil
 	},
 }
 
+// selectListed filters and sorts the fonts fm list shows as its flags ask
+func selectListed(cmd *cobra.Command, fonts []fm.Font) ([]fm.Font, error) {
+	if filter, _ := cmd.Flags().GetString("filter"); filter != "" {
+		filter = strings.ToLower(filter)
+		fonts = slices.DeleteFunc(fonts, func(font fm.Font) bool {
+			return !strings.Contains(strings.ToLower(font.Name), filter)
+		})
+	}
+
+	if source, _ := cmd.Flags().GetString("source"); source != "" {
+		fonts = slices.DeleteFunc(fonts, func(font fm.Font) bool {
+			return !strings.EqualFold(listedSource(font), source)
+		})
+	}
+
+	if after, _ := cmd.Flags().GetString("installed-after"); after != "" {
+		since, err := parseSince(after)
+		if err != nil {
+			return nil, fmt.Errorf("parsing --installed-after: %w", err)
+		}
+		// Fonts fm didn't install have no install date
+		fonts = slices.DeleteFunc(fonts, func(font fm.Font) bool {
+			installed, ok := installedAt(font)
+			return !ok || !installed.After(since)
+		})
+	}
+
+	sortBy, _ := cmd.Flags().GetString("sort")
+	switch sortBy {
+	case "", "name":
+		slices.SortStableFunc(fonts, func(a, b fm.Font) int {
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+	case "date":
+		// Newest first, with fonts fm didn't install last
+		slices.SortStableFunc(fonts, func(a, b fm.Font) int {
+			ta, okA := installedAt(a)
+			tb, okB := installedAt(b)
+			if okA != okB {
+				if okA {
+					return -1
+				}
+				return 1
+			}
+			return tb.Compare(ta)
+		})
+	case "size":
+		sizes := make(map[string]int64, len(fonts))
+		for _, font := range fonts {
+			_, sizes[font.Name] = fontUsage(font)
+		}
+		slices.SortStableFunc(fonts, func(a, b fm.Font) int {
+			return cmp.Compare(sizes[b.Name], sizes[a.Name])
+		})
+	default:
+		return nil, fmt.Errorf("unknown sort order %q (expected name, date or size)", sortBy)
+	}
+	return fonts, nil
+}
+
+// systemSource is the source fm list --source gives fonts fm didn't install
+const systemSource = "system"
+
+// listedSource returns the source font is listed under by fm list --source
+func listedSource(font fm.Font) string {
+	if font.Meta["installed_at"] == "" {
+		return systemSource
+	}
+	return font.Source
+}
+
+// installedAt returns when fm installed font
+func installedAt(font fm.Font) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, font.Meta["installed_at"])
+	return t, err == nil
+}
+
+// fontUsage counts the font files in the directory fm installed font into
+// and totals the size of everything in it. A font file already on the
+// system is counted on its own.
+func fontUsage(font fm.Font) (files int, size int64) {
+	if font.Meta["installed_at"] == "" {
+		if info, err := os.Stat(font.Meta["path"]); err == nil {
+			return 1, info.Size()
+		}
+		return 0, 0
+	}
+	filepath.WalkDir(font.Meta["directory"], func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+		if ext := strings.ToLower(filepath.Ext(path)); ext == ".ttf" || ext == ".otf" {
+			files++
+		}
+		return nil
+	})
+	return files, size
+}
+
+// printListTable prints the fonts with their source, version, install date,
+// number of font files and disk usage, for fm list --long
+func printListTable(fonts []fm.Font) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSOURCE\tVERSION\tINSTALLED\tFILES\tSIZE")
+	for _, font := range fonts {
+		name := font.Name
+		if fm.IsPinned(font) {
+			name += " [pinned]"
+		}
+		installed := "-"
+		if t, ok := installedAt(font); ok {
+			installed = t.Local().Format(time.DateOnly)
+		}
+		files, size := fontUsage(font)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", name, listedSource(font),
+			orDash(font.Meta["version"]), installed, files, fm.FormatSize(size))
+	}
+	return w.Flush()
+}
+
+// printFiles prints the long-format file listing for an installed font
+func printFiles(cmd *cobra.Command, font fm.Font, rehash bool) error {
+	files, err := manager.Files(cmd.Context(), font, rehash)
+	if err != nil {
+		return fmt.Errorf("listing files of %s: %w", font.Name, err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, file := range files {
+		fmt.Fprintf(w, "      %s\t%s\t%s\t%s\n", file.Name, fm.FormatSize(file.Size), file.Format, file.SHA256)
+	}
+	return w.Flush()
+}
+
 func init() {
+	rootCmd.Version = version
+	rootCmd.PersistentFlags().String("config", "", "Path to the config file (defaults to $FM_CONFIG, then the user config directory)")
+	rootCmd.PersistentFlags().Bool("refresh", false, "Ignore cached source indexes and fetch fresh data")
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "Increase output detail; -vv shows connection and TLS details for network errors")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only report failures")
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Show requests and their status, resolved downloads and extracted files (implies -vv)")
+	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Print list, search, info and install results as JSON")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Don't color output (also with NO_COLOR set)")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Answer yes to every confirmation, e.g. before removing fonts, large downloads and system-wide installs")
+	rootCmd.PersistentFlags().Bool("no-input", false, "Never prompt; skip the first-run setup and use the defaults (implied when run by chezmoi)")
+
 	rootCmd.AddCommand(installCmd)
 	rootCmd.AddCommand(uninstallCmd)
 	rootCmd.AddCommand(listCmd)
 
-	installCmd.Flags().StringP("file", "f", "", "Install fonts from a config file")
+	listCmd.Flags().BoolP("long", "l", false, "Show a table with each font's source, version, install date, file count and disk usage")
+	listCmd.Flags().Bool("files", false, "Show every installed file with its size, format and SHA-256")
+	listCmd.Flags().Bool("rehash", false, "Recompute file hashes instead of using the recorded ones (implies --files)")
+	listCmd.Flags().String("sort", "name", "Order fonts by name, date (newest install first) or size (largest first)")
+	listCmd.Flags().String("filter", "", "Only list fonts whose name contains this, ignoring case")
+	listCmd.Flags().String("source", "", "Only list fonts installed from this source, or \"system\" for the fonts fm didn't install")
+	listCmd.Flags().String("installed-after", "", "Only list fonts fm installed after this date (e.g. 2024-01-31) or within this age (e.g. 30d)")
+	listCmd.MarkFlagsMutuallyExclusive("long", "files")
+	listCmd.MarkFlagsMutuallyExclusive("long", "rehash")
+
+	uninstallCmd.Flags().String("user", "", "Uninstall from another user's font directory (requires root)")
+	uninstallCmd.Flags().StringArrayP("file", "f", nil, "Uninstall the fonts listed in a config file, or - for stdin; repeatable")
+	uninstallCmd.Flags().String("source", "", "Only uninstall fonts installed from this source; all of them without names")
+	uninstallCmd.Flags().Bool("confirm", false, "Ask for confirmation even for fonts named one by one")
+	uninstallCmd.Flags().Bool("dry-run", false, "Show the files that would be removed, configs using the font and shared files, without removing anything")
+
+	installCmd.Flags().StringArrayP("file", "f", nil, "Install fonts from a config file, or - for stdin; repeat to merge files, later ones overriding earlier duplicates")
+	installCmd.Flags().Bool("dry-run", false, "Resolve the source, version and download URL of every font and print them without installing anything")
+	installCmd.Flags().Bool("check", false, "Report what would change without installing anything, like --dry-run; for Ansible, Salt and similar tools")
+	installCmd.Flags().Bool("detailed-exit-code", false, fmt.Sprintf("Exit with status %d instead of 0 when fonts were installed, or would be with --check", exitChanged))
+	installCmd.Flags().Bool("force", false, "Reinstall fonts that are already installed, replacing their files; the old copy is kept if the reinstall fails")
+	installCmd.Flags().String("from-bundle", "", "Install the fonts of a bundle made with fm bundle, without network access")
+	installCmd.Flags().Bool("locked", false, "With -f, install the exact downloads recorded in the lockfile next to the first config, failing on any checksum mismatch")
+	installCmd.Flags().String("region", "", "Regional subset for CJK families (SC, TC, JP, KR); defaults to your locale")
+	installCmd.Flags().String("max-size", fm.FormatSize(fm.DefaultLimits.MaxDownloadSize), "Maximum download size of a font archive (0 disables the limit)")
+	installCmd.Flags().String("max-extracted-size", fm.FormatSize(fm.DefaultLimits.MaxUncompressedSize), "Maximum uncompressed size of a font archive (0 disables the limit)")
+	installCmd.Flags().String("user", "", "Install into another user's font directory (requires root)")
+	installCmd.Flags().Bool("system", false, "Install for every user into the system font directory, rerunning with sudo if needed")
+	installCmd.Flags().Int("max-files", fm.DefaultLimits.MaxFiles, "Maximum number of files in a font archive (0 disables the limit)")
+	installCmd.Flags().StringSlice("subset", nil, "Only download these script subsets of web fonts (e.g. latin,latin-ext)")
+	installCmd.Flags().String("format", "", "Install only TTF or OTF files of faces the archive has in both (ttf, otf); defaults to format in the config")
+	installCmd.Flags().StringSlice("variants", nil, "Only extract these styles from the archive (e.g. Regular,Bold,Italic); Nerd Fonts also take Mono or Propo")
+	installCmd.Flags().String("unicode-range", "", "Only download web font subsets covering these characters (e.g. U+0000-00FF)")
+	installCmd.Flags().String("axes", "", "Install the variable build limited to these axis ranges (e.g. wght=400..700,wdth=100); needs fontTools")
+	installCmd.Flags().StringArrayP("header", "H", nil, "Add a header to direct URL downloads, as \"Name: value\" (repeatable)")
+	installCmd.Flags().Bool("accept-eula", false, "Accept the license agreement of sources that require one (e.g. mscorefonts)")
+	installCmd.Flags().Bool("override-license", false, "Install even if the font's license isn't in allowed_licenses; recorded in the audit log")
+	installCmd.Flags().IntP("jobs", "j", 0, fmt.Sprintf("How many fonts to download and extract at once (defaults to parallelism in the config, or %d)", fm.DefaultParallelism))
+	installCmd.Flags().Int("parallel", 0, "How many fonts to download and extract at once")
+	installCmd.Flags().MarkDeprecated("parallel", "use --jobs instead")
+	installCmd.Flags().Bool("low-priority", false, "Run at reduced CPU and I/O priority, like nice and ionice")
+	installCmd.Flags().String("note", "", "Record a note with the install, e.g. why the font is needed; shown by fm info and kept by fm export")
+	installCmd.Flags().Bool("verify-render", false, "Render a sample with every installed file and fail if one can't be loaded or lacks expected glyphs")
+	installCmd.Flags().Int("archive-depth", fm.DefaultArchiveDepth, "How many levels of archives nested inside a download to unpack")
+}
+
+// stdinConfig is the config file name that reads the font list from stdin,
+// one spec per line
+const stdinConfig = "-"
+
+// readConfigs parses and merges font config files, fonts in later files
+// overriding the same fonts in earlier ones. Invalid lines are reported in the
+// error alongside the fonts that did parse.
+func readConfigs(paths []string) ([]fm.Font, error) {
+	var configs [][]fm.Font
+	var errs []error
+	readStdin := false
+	for _, path := range paths {
+		var fonts []fm.Font
+		var err error
+		if path == stdinConfig {
+			if readStdin {
+				return nil, fmt.Errorf("stdin can only be read once")
+			}
+			readStdin = true
+			stdinUsed = true
+			fonts, err = fm.ParseConfig(os.Stdin)
+		} else {
+			fonts, err = fm.ParseConfigFile(path)
+		}
+		if fonts == nil && err != nil {
+			return nil, err
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", configNames([]string{path}), err))
+		}
+		configs = append(configs, fonts)
+	}
+	return fm.MergeConfigs(configs...), errors.Join(errs...)
+}
+
+// configNames lists config files for messages, calling stdin by its name
+func configNames(paths []string) string {
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		if path == stdinConfig {
+			path = "stdin"
+		}
+		names[i] = path
+	}
+	return strings.Join(names, ", ")
+}
+
+// limitsFromFlags builds the archive limits from the install flags
+func limitsFromFlags(cmd *cobra.Command) (fm.Limits, error) {
+	limits := fm.DefaultLimits
+
+	maxSize, _ := cmd.Flags().GetString("max-size")
+	size, err := fm.ParseSize(maxSize)
+	if err != nil {
+		return limits, fmt.Errorf("parsing --max-size: %w", err)
+	}
+	limits.MaxDownloadSize = size
+
+	maxExtracted, _ := cmd.Flags().GetString("max-extracted-size")
+	if size, err = fm.ParseSize(maxExtracted); err != nil {
+		return limits, fmt.Errorf("parsing --max-extracted-size: %w", err)
+	}
+	limits.MaxUncompressedSize = size
+
+	limits.MaxFiles, _ = cmd.Flags().GetInt("max-files")
+	return limits, nil
+}
+
+// installResult is the outcome of installing one font named on the command
+// line
+type installResult struct {
+	name string
+	err  error
+}
+
+// printInstallSummary prints a table with the outcome of each install,
+// followed by the totals
+func printInstallSummary(out io.Writer, results []installResult) error {
+	installed, skipped, failed := 0, 0, 0
+	fmt.Fprintf(out, "\nInstallation Summary:\n")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, result := range results {
+		// The colored outcome comes last, since escape codes would throw
+		// off the alignment of the columns after it
+		var outcome string
+		switch {
+		case result.err == nil:
+			outcome = paint(out, successColor, "installed")
+			installed++
+		case errors.Is(result.err, fm.ErrAlreadyInstalled):
+			outcome = paint(out, skipColor, "skipped") + " (already installed)"
+			skipped++
+		default:
+			outcome = paint(out, failColor, "failed") + ": " + result.err.Error()
+			failed++
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", result.name, outcome)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%d installed, %d skipped, %d failed\n", installed, skipped, failed)
+	if skipped > 0 {
+		fmt.Fprintln(out, "Use --force to reinstall skipped fonts")
+	}
+	return nil
+}
+
+// printLimitHint tells the user how to override a limit that blocked an install
+func printLimitHint(err error) {
+	if errors.Is(err, fm.ErrLimitExceeded) {
+		fmt.Fprintln(os.Stderr, "  Raise the limit with --max-size, --max-extracted-size or --max-files if the archive is expected to be this large")
+	}
+}
+
+// parseHeaders parses "Name: value" header flags
+func parseHeaders(headers []string) (http.Header, error) {
+	header := make(http.Header)
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q (expected \"Name: value\")", h)
+		}
+		header.Add(name, strings.TrimSpace(value))
+	}
+	return header, nil
+}
+
+// printNetworkDetails shows every connection attempt and the TLS handshake
+// behind a network failure at -vv
+func printNetworkDetails(err error) {
+	var netErr *fm.NetworkError
+	if verbosity < 2 || !errors.As(err, &netErr) {
+		return
+	}
+	for _, line := range netErr.Details() {
+		fmt.Fprintf(os.Stderr, "  %s\n", line)
+	}
+}
+
+// lowMemoryThreshold is the available memory below which low-memory mode
+// turns on unless the config decides
+const lowMemoryThreshold = 1 << 30
+
+// lowMemoryMode reports whether to extract and hash with as little memory as
+// possible, as configured or when little memory is available
+func lowMemoryMode() bool {
+	if cfg.LowMemory != nil {
+		return *cfg.LowMemory
+	}
+	available, err := platform.AvailableMemory()
+	if err != nil || available >= lowMemoryThreshold {
+		return false
+	}
+	if verbosity > 0 {
+		fmt.Fprintf(os.Stderr, "Only %s of memory available; using low-memory mode\n", fm.FormatSize(available))
+	}
+	return true
+}
+
+// lowerPriority runs the rest of the command at reduced CPU and I/O
+// priority, carrying on with a warning where the platform can't
+func lowerPriority() {
+	if err := platform.LowerPriority(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// shouldConfirm reports whether a command asks before removing fonts or
+// other large changes. --yes never asks and --confirm always does; otherwise
+// the confirm policy in the config decides, asking before destructive
+// operations by default.
+func shouldConfirm(cmd *cobra.Command, destructive bool) bool {
+	if assumeYes {
+		return false
+	}
+	if always, _ := cmd.Flags().GetBool("confirm"); always {
+		return true
+	}
+	switch cfg.Confirm {
+	case config.ConfirmAlways:
+		return true
+	case config.ConfirmNever:
+		return false
+	default:
+		return destructive
+	}
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no. Without
+// input the answer is no.
+func confirm(question string) bool {
+	if noInput || stdinUsed {
+		fmt.Printf("%s Not asking without input; pass --yes to go ahead.\n", question)
+		return false
+	}
+	fmt.Printf("%s [y/N] ", question)
+
+	// Ctrl-C at the prompt answers no
+	answers := make(chan string, 1)
+	go func() {
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		answers <- answer
+	}()
+	var answer string
+	select {
+	case answer = <-answers:
+	case <-interrupted:
+		fmt.Println()
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/logandonley/font-manager/internal/platform"
+	"github.com/spf13/cobra"
+)
+
+var openCmd = &cobra.Command{
+	Use:   "open <font>",
+	Short: "Open a font's directory in the file manager",
+	Long: `Open the directory of an installed font in the file manager, with xdg-open
+on Linux and the BSDs and open on macOS. With --file, open the font's primary
+file, its regular style, in the system font viewer instead.`,
+	Example: `  fm open JetBrainsMono
+  fm open JetBrainsMono --file`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInstalled(true),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var path string
+		if file, _ := cmd.Flags().GetBool("file"); file {
+			var err error
+			if path, err = manager.PrimaryFile(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+		} else {
+			loc, err := manager.Which(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			path = loc.Dir
+		}
+
+		if verbosity > 0 {
+			fmt.Fprintf(textOut(), "Opening %s\n", path)
+		}
+		return platform.Open(path)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(openCmd)
+	openCmd.Flags().Bool("file", false, "Open the primary font file in the font viewer instead of the directory")
+}
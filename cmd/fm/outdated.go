@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated [font...]",
+	Short: "List installed fonts that have a newer upstream release",
+	Long: `Compare the version each font was installed at with the latest release of
+its source without changing anything. Run "fm upgrade" to reinstall the
+outdated fonts.
+
+Without arguments every installed font with a recorded version is checked;
+--all also lists the fonts that are up to date.
+
+Patched fonts, such as Nerd Fonts builds, are also compared with the unmodified
+font they were built from. When upstream has a newer release than the one
+bundled, a note explains that the patched build lags behind it.
+
+Examples:
+  # Show fonts with a newer release
+  fm outdated
+
+  # Check specific fonts, whether or not they are outdated
+  fm outdated FiraCode Inter`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		results, err := manager.Outdated(cmd.Context(), args)
+		if err != nil && results == nil {
+			return err
+		}
+
+		all, _ := cmd.Flags().GetBool("all")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSOURCE\tINSTALLED\tLATEST\tSTATUS")
+		shown := 0
+		for _, result := range results {
+			status := "up to date"
+			switch {
+			case result.Err != nil:
+				status = "unknown"
+			case result.Outdated && result.Pinned:
+				status = "outdated (pinned)"
+			case result.Outdated:
+				status = "outdated"
+			}
+			// Named fonts are always shown
+			if status == "up to date" && !all && len(args) == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", result.Name, result.Source,
+				orDash(result.Installed), orDash(result.Latest), status)
+			shown++
+		}
+
+		if shown == 0 {
+			fmt.Println("All fonts are up to date")
+		} else if flushErr := w.Flush(); flushErr != nil {
+			return flushErr
+		}
+		printUpstreamNotes(results)
+
+		if err != nil {
+			return fmt.Errorf("some fonts could not be checked: %w", err)
+		}
+		return nil
+	},
+}
+
+// printUpstreamNotes explains which patched fonts lag behind a newer
+// upstream release
+func printUpstreamNotes(results []fm.FontUpgrade) {
+	first := true
+	for _, result := range results {
+		upstream := result.Upstream
+		if upstream == nil || !upstream.Newer {
+			continue
+		}
+		if first {
+			fmt.Println()
+			first = false
+		}
+		fmt.Printf("Note: %s is built from %s (%s), whose latest release %s is newer than the %q it bundles.\n",
+			result.Name, upstream.Name, upstream.Source, upstream.Latest, upstream.Bundled)
+		fmt.Printf("  %s rebuilds fonts from upstream releases, so %s may only pick it up in a later release.\n",
+			result.Source, result.Name)
+	}
+}
+
+func init() {
+	outdatedCmd.Flags().Bool("all", false, "Also list fonts that are up to date")
+	rootCmd.AddCommand(outdatedCmd)
+}
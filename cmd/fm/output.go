@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/logandonley/font-manager/pkg/fontmeta"
+	"github.com/spf13/cobra"
+)
+
+// jsonOutput makes list, search, info and install print JSON on stdout
+// instead of text, with --json. Progress and warnings go to stderr.
+var jsonOutput bool
+
+// fontJSON is an installed font in JSON output
+type fontJSON struct {
+	Name        string             `json:"name"`
+	Source      string             `json:"source,omitempty"`
+	Version     string             `json:"version,omitempty"`
+	URL         string             `json:"url,omitempty"`
+	Path        string             `json:"path,omitempty"`
+	Scope       string             `json:"scope,omitempty"`
+	InstalledAt string             `json:"installed_at,omitempty"`
+	Pinned      bool               `json:"pinned,omitempty"`
+	Note        string             `json:"note,omitempty"`
+	Files       []fm.InstalledFile `json:"files,omitempty"`
+	Faces       []faceJSON         `json:"faces,omitempty"`
+}
+
+// faceJSON is a face read from an installed font file in JSON output
+type faceJSON struct {
+	File string `json:"file"`
+	*fontmeta.Font
+}
+
+func newFontJSON(font fm.Font) fontJSON {
+	return fontJSON{
+		Name:        font.Name,
+		Source:      font.Source,
+		Version:     font.Meta["version"],
+		URL:         font.Meta["url"],
+		Path:        font.Meta["directory"],
+		Scope:       font.Meta["scope"],
+		InstalledAt: font.Meta["installed_at"],
+		Pinned:      fm.IsPinned(font),
+		Note:        fm.Note(font),
+	}
+}
+
+// searchResultJSON is a search result in JSON output
+type searchResultJSON struct {
+	Name         string            `json:"name"`
+	Source       string            `json:"source"`
+	Match        fm.MatchKind      `json:"match"`
+	Score        float64           `json:"score"`
+	Availability string            `json:"availability"`
+	Meta         map[string]string `json:"meta,omitempty"`
+}
+
+// installSummaryJSON is the outcome of fm install in JSON output
+type installSummaryJSON struct {
+	Changed   bool                 `json:"changed"` // Whether any font was installed
+	Installed []fontJSON           `json:"installed"`
+	Skipped   []string             `json:"skipped"`
+	Failed    []installFailureJSON `json:"failed"`
+}
+
+type installFailureJSON struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// add records the outcome of installing the spec given as name, which is
+// installed under installName; installed fonts are described with what fm
+// recorded about them
+func (s *installSummaryJSON) add(cmd *cobra.Command, manager *fm.DefaultManager, name, installName string, err error) {
+	switch {
+	case errors.Is(err, fm.ErrAlreadyInstalled):
+		s.Skipped = append(s.Skipped, name)
+	case err != nil:
+		s.Failed = append(s.Failed, installFailureJSON{Name: name, Error: err.Error()})
+	default:
+		font := fontJSON{Name: installName}
+		if loc, err := manager.Which(cmd.Context(), installName); err == nil {
+			font = newFontJSON(loc.Font)
+			font.Path, font.Scope = loc.Dir, loc.Scope
+		}
+		s.Installed = append(s.Installed, font)
+		s.Changed = true
+	}
+}
+
+// write prints the summary, with empty lists rather than null
+func (s *installSummaryJSON) write() error {
+	if s.Installed == nil {
+		s.Installed = []fontJSON{}
+	}
+	if s.Skipped == nil {
+		s.Skipped = []string{}
+	}
+	if s.Failed == nil {
+		s.Failed = []installFailureJSON{}
+	}
+	return writeJSON(s)
+}
+
+// installConfig installs fonts parsed from config files and prints the
+// outcome of each, as a summary table or JSON. It reports whether any font
+// was installed.
+func installConfig(cmd *cobra.Command, manager *fm.DefaultManager, fonts []fm.Font, opts []fm.InstallOption) (bool, error) {
+	results, err := manager.InstallEach(cmd.Context(), fonts, opts...)
+	if results == nil && err != nil {
+		return false, err
+	}
+	changed, reportErr := reportInstalls(cmd, manager, fonts, results)
+	return changed, errors.Join(err, reportErr)
+}
+
+// installBundle installs the fonts of a bundle made with fm bundle and
+// prints the outcome of each like installConfig
+func installBundle(cmd *cobra.Command, manager *fm.DefaultManager, path string, opts []fm.InstallOption) (bool, error) {
+	manifest, results, err := manager.InstallBundle(cmd.Context(), path, opts...)
+	if results == nil {
+		return false, err
+	}
+	fonts := make([]fm.Font, len(manifest.Fonts))
+	for i, bundled := range manifest.Fonts {
+		// InstallBundle already rejected specs that don't parse
+		if font, _ := fm.ParseFontSpec(bundled.Spec); font != nil {
+			fonts[i] = *font
+		}
+	}
+	changed, reportErr := reportInstalls(cmd, manager, fonts, results)
+	return changed, errors.Join(err, reportErr)
+}
+
+// reportInstalls prints the outcome of installing each of fonts, as a
+// summary table or JSON, and reports whether any font was installed
+func reportInstalls(cmd *cobra.Command, manager *fm.DefaultManager, fonts []fm.Font, results []error) (bool, error) {
+	var summary installSummaryJSON
+	var printed []installResult
+	for i, result := range results {
+		summary.add(cmd, manager, fonts[i].Name, uninstallName(fonts[i]), result)
+		printed = append(printed, installResult{name: fm.FormatFontSpec(fonts[i]), err: result})
+	}
+	var err error
+	if jsonOutput {
+		err = summary.write()
+	} else {
+		err = printInstallSummary(textOut(), printed)
+		printLimitHint(errors.Join(results...))
+	}
+	return summary.Changed, errors.Join(err, fontsFailed("install", results))
+}
+
+// planJSON is what fm install --dry-run or --check would do, in JSON output
+type planJSON struct {
+	Changed bool          `json:"changed"` // Whether any font would be installed
+	Fonts   []plannedJSON `json:"fonts"`
+}
+
+type plannedJSON struct {
+	Name    string `json:"name"`
+	Spec    string `json:"spec"`
+	Status  string `json:"status"` // install, reinstall, unchanged or failed
+	Source  string `json:"source,omitempty"`
+	Version string `json:"version,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Reason  string `json:"reason,omitempty"` // Why a font would be reinstalled
+	Error   string `json:"error,omitempty"`
+}
+
+// writePlanJSON prints the plans of an install
+func writePlanJSON(plans []fm.PlannedInstall, changed bool) error {
+	out := planJSON{Changed: changed, Fonts: []plannedJSON{}}
+	for _, plan := range plans {
+		font := plannedJSON{
+			Name:    plan.Font.Name,
+			Spec:    fm.FormatFontSpec(plan.Font),
+			Source:  plan.Source,
+			Version: plan.Version,
+			URL:     plan.URL,
+			Reason:  plan.Reinstall,
+		}
+		switch {
+		case plan.Err != nil:
+			font.Status, font.Error = "failed", plan.Err.Error()
+		case plan.Installed:
+			font.Status = "unchanged"
+		case plan.Reinstall != "":
+			font.Status = "reinstall"
+		default:
+			font.Status = "install"
+		}
+		out.Fonts = append(out.Fonts, font)
+	}
+	return writeJSON(out)
+}
+
+// installNameOf is the name the font given as an install argument is
+// installed under
+func installNameOf(arg string) string {
+	if font, err := fm.ParseFontSpec(arg); err == nil && font != nil {
+		return uninstallName(*font)
+	}
+	return arg
+}
+
+// textOut is where commands print progress: stdout, or stderr with --json
+// so that stdout holds nothing but the JSON document. With --quiet progress
+// isn't printed at all.
+func textOut() io.Writer {
+	if quiet {
+		return io.Discard
+	}
+	if jsonOutput {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// writeJSON prints v as indented JSON on stdout
+func writeJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var pinCmd = &cobra.Command{
+	Use:   "pin <font>...",
+	Short: "Keep installed fonts from being upgraded or pruned",
+	Long: `Pin installed fonts at their current version. "fm upgrade" and "fm sync"
+leave pinned fonts alone, even when a newer release is out or the font is no
+longer listed with --prune. "fm list" marks them as pinned.`,
+	Example: `  fm pin JetBrainsMono
+  fm unpin JetBrainsMono`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setPinned(cmd, args, true)
+	},
+}
+
+var unpinCmd = &cobra.Command{
+	Use:   "unpin <font>...",
+	Short: "Let pinned fonts be upgraded and pruned again",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setPinned(cmd, args, false)
+	},
+}
+
+func setPinned(cmd *cobra.Command, names []string, pinned bool) error {
+	for _, name := range names {
+		if err := manager.Pin(cmd.Context(), name, pinned); err != nil {
+			return err
+		}
+		if pinned {
+			fmt.Printf("Pinned %s\n", name)
+		} else {
+			fmt.Printf("Unpinned %s\n", name)
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(pinCmd)
+	rootCmd.AddCommand(unpinCmd)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+// defaultPreviewText shows the glyphs that tell monospace fonts apart
+const defaultPreviewText = "Hello 0O 1lI {}[] => !="
+
+var previewCmd = &cobra.Command{
+	Use:   "preview <font> [sample text]",
+	Short: "Render a text sample with an installed font in the terminal",
+	Long: `Render a sample string with an installed font's file as Unicode braille or
+block art, making it easier to pick between similar fonts without leaving the
+terminal. The regular style is used unless --file names another file of the
+font (see "fm list --files").
+
+Examples:
+  # Compare two monospace fonts
+  fm preview FiraCode
+  fm preview JetBrainsMono
+
+  # Render your own sample, larger and with half blocks
+  fm preview Inter "Sphinx of black quartz" --size 32 --style block`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		text := defaultPreviewText
+		if len(args) == 2 {
+			text = args[1]
+		}
+
+		var opts fm.PreviewOptions
+		opts.Size, _ = cmd.Flags().GetFloat64("size")
+		opts.Style, _ = cmd.Flags().GetString("style")
+		opts.Width, _ = cmd.Flags().GetInt("width")
+		opts.File, _ = cmd.Flags().GetString("file")
+
+		lines, err := manager.Preview(cmd.Context(), args[0], text, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Println(strings.Join(lines, "\n"))
+		return nil
+	},
+}
+
+func init() {
+	previewCmd.Flags().Float64("size", fm.DefaultPreviewSize, "Font size in pixels")
+	previewCmd.Flags().String("style", fm.PreviewBraille, "Rendering style: braille (finer) or block")
+	previewCmd.Flags().Int("width", 0, "Maximum width in columns (0 for no limit)")
+	previewCmd.Flags().String("file", "", "Font file to render, e.g. FiraCode-Bold.ttf")
+	rootCmd.AddCommand(previewCmd)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Uninstall every font fm installed",
+	Long: `Uninstall every font fm installed in your font directory, pinned ones
+included, for a clean slate. Fonts fm installed are told apart by the
+metadata it keeps with them, so system fonts and fonts you copied in by hand
+stay. The fonts are listed and removed after confirming.`,
+	Example: `  fm purge
+  fm purge --yes`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fonts, err := manager.ManagedFonts(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if len(fonts) == 0 {
+			fmt.Fprintln(textOut(), "No fonts installed by fm")
+			return nil
+		}
+
+		if shouldConfirm(cmd, true) {
+			fmt.Println("The following fonts will be uninstalled:")
+			for _, font := range fonts {
+				if fm.IsPinned(font) {
+					fmt.Printf("  %s (pinned)\n", font.Name)
+				} else {
+					fmt.Printf("  %s\n", font.Name)
+				}
+			}
+			if !confirm(fmt.Sprintf("Uninstall %d fonts?", len(fonts))) {
+				return nil
+			}
+		}
+
+		failed := 0
+		out := textOut()
+		results := manager.UninstallFonts(cmd.Context(), fonts)
+		for i, err := range results {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s %s: %v\n", paint(os.Stderr, failColor, "Error uninstalling"), fonts[i].Name, err)
+				failed++
+				continue
+			}
+			fmt.Fprintf(out, "%s %s\n", paint(out, successColor, "Successfully uninstalled"), fonts[i].Name)
+		}
+		fmt.Fprintf(out, "Uninstalled %d of %d fonts\n", len(fonts)-failed, len(fonts))
+		return fontsFailed("uninstall", results)
+	},
+}
+
+func init() {
+	purgeCmd.Flags().Bool("confirm", false, "Ask for confirmation, whatever the confirm policy")
+	rootCmd.AddCommand(purgeCmd)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <font>",
+	Short: "Switch a font back to the install its last upgrade replaced",
+	Long: `Switch a font back to the install "fm upgrade" replaced, for when a new
+release breaks something. Running it again returns to the upgrade. With the
+store layout, the font switches to its previous generation instead.`,
+	Example:           `  fm rollback FiraCode`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInstalled(true),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		font, err := manager.Rollback(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		if version := font.Meta["version"]; version != "" {
+			fmt.Printf("Rolled %s back to %s\n", font.Name, version)
+		} else {
+			fmt.Printf("Rolled %s back to its previous install\n", font.Name)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search every source for installable fonts",
+	Long: `Search every registered source for fonts matching a query and show where
+each can be installed from, best matches first.
+
+MATCH is "exact" for the queried name, ignoring case, accents and spacing,
+"prefix" for names starting with the query and "fuzzy" for anything else the
+source returned; -v adds the score results are ranked by.
+
+AVAILABILITY is "installed" for fonts already present, "available" when the
+source confirmed the font exists and "unverified" for sources that can't be
+searched (such as Nerd Fonts), where the font is only found when installing.
+
+Examples:
+  # Find every source offering Fira Code
+  fm search "Fira Code"
+
+  # Then install from the one you want
+  fm install "Fira Code@fontsource"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		results, err := manager.Search(cmd.Context(), args[0])
+		if err != nil {
+			// Results from the sources that answered are still useful
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if jsonOutput {
+			list := make([]searchResultJSON, len(results))
+			for i, result := range results {
+				list[i] = searchResultJSON{
+					Name:         result.Font.Name,
+					Source:       result.Source,
+					Match:        result.Match,
+					Score:        result.Score,
+					Availability: availability(cmd, result.Font),
+					Meta:         result.Font.Meta,
+				}
+			}
+			return writeJSON(list)
+		}
+		if len(results) == 0 {
+			fmt.Printf("No fonts found matching %q\n", args[0])
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSOURCE\tMATCH\tAVAILABILITY")
+		for _, result := range results {
+			match := string(result.Match)
+			if verbosity > 0 {
+				match += fmt.Sprintf(" (%.2f)", result.Score)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Font.Name, result.Source, match, availability(cmd, result.Font))
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+}
+
+// availability tells whether a font found by a search is installed, available
+// or unverified
+func availability(cmd *cobra.Command, font fm.Font) string {
+	if installed, err := manager.IsInstalled(cmd.Context(), font.Name); err == nil && installed {
+		return "installed"
+	}
+	if !font.Verified() {
+		return "unverified"
+	}
+	return "available"
+}
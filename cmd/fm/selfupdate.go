@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/logandonley/font-manager/internal/selfupdate"
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Update fm to the latest release",
+	Long: `Check the project's GitHub releases for a newer fm, download the binary
+for this platform, verify it against the release checksums and replace the
+running executable with it. Rerun with sudo when fm is installed somewhere
+only root can write, such as /usr/local/bin.`,
+	Example: `  fm self-update
+  fm self-update --check`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := fm.NewHTTPClient(httpSettings())
+		if err != nil {
+			return err
+		}
+		release, err := selfupdate.Latest(cmd.Context(), client, selfupdate.DefaultAPIURL)
+		if err != nil {
+			return err
+		}
+
+		force, _ := cmd.Flags().GetBool("force")
+		newer, err := newerRelease(release.Tag)
+		if err != nil && !force {
+			return fmt.Errorf("%w; pass --force to install %s anyway", err, release.Tag)
+		}
+		if !newer && !force {
+			fmt.Printf("fm %s is the latest release\n", version)
+			return nil
+		}
+		if check, _ := cmd.Flags().GetBool("check"); check {
+			fmt.Printf("fm %s is available (running %s): %s\n", release.Tag, version, release.URL)
+			reportChanged(cmd, true)
+			return nil
+		}
+
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("locating the fm executable: %w", err)
+		}
+		if exe, err = filepath.EvalSymlinks(exe); err != nil {
+			return fmt.Errorf("locating the fm executable: %w", err)
+		}
+		fmt.Fprintf(textOut(), "Downloading fm %s...\n", release.Tag)
+		if err := release.Install(cmd.Context(), client, exe); err != nil {
+			return err
+		}
+		out := textOut()
+		fmt.Fprintf(out, "%s fm %s to %s\n", paint(out, successColor, "Updated"), version, release.Tag)
+		return nil
+	},
+}
+
+// newerRelease reports whether the release tagged tag is newer than the
+// running fm. Development builds have no version to compare.
+func newerRelease(tag string) (bool, error) {
+	latest, err := fm.ParseVersion(tag)
+	if err != nil {
+		return false, err
+	}
+	running, err := fm.ParseVersion(version)
+	if err != nil {
+		return false, fmt.Errorf("fm %s is a development build", version)
+	}
+	return latest.Compare(running) > 0, nil
+}
+
+func init() {
+	selfUpdateCmd.Flags().Bool("check", false, "Only report whether a newer release is available")
+	selfUpdateCmd.Flags().Bool("force", false, "Install the latest release even if it isn't newer, e.g. over a development build")
+	selfUpdateCmd.Flags().Bool("detailed-exit-code", false, "With --check, exit with status 10 when a newer release is available")
+	rootCmd.AddCommand(selfUpdateCmd)
+}
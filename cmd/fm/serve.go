@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/logandonley/font-manager/internal/server"
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+// serveTokenEnv holds the token fm serve requires when --token isn't given,
+// keeping it out of process listings
+const serveTokenEnv = "FM_SERVE_TOKEN"
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the font manager over an HTTP API",
+	Long: `Serve a JSON API over HTTP for GUI front ends and fleet management tools to
+list, search, install and uninstall fonts with the same engine as the
+command line:
+
+  GET  /v1/status           fm's version and the number of installed fonts
+  GET  /v1/fonts            Installed fonts, as fm list --json
+  GET  /v1/search?q=QUERY   Fonts found in the sources
+  POST /v1/install          Install {"fonts": ["Inter", ...], "force": false}
+  POST /v1/uninstall        Uninstall {"fonts": ["Inter", ...]}
+
+Installs and uninstalls answer with the fonts done, skipped and failed. Send
+"Accept: application/x-ndjson" to stream an event per font as it's acted on
+instead, ending with a "done" event that carries the same summary.
+
+Clients must send a bearer token with every request. Set it with --token or
+` + serveTokenEnv + `; otherwise fm generates one and writes it to serve.token
+in its state directory, readable only by you. Listening beyond localhost
+needs a token set explicitly. Request bodies must be sent as
+application/json, and requests from web pages, which carry an Origin header,
+are refused.`,
+	Example: `  fm serve
+  FM_SERVE_TOKEN=secret fm serve --addr :7070
+  curl -H "Authorization: Bearer $(cat ~/.local/state/fm/serve.token)" \
+    -H 'Content-Type: application/json' \
+    -d '{"fonts": ["Inter"]}' http://127.0.0.1:7070/v1/install`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+		token, _ := cmd.Flags().GetString("token")
+		var err error
+		if token == "" {
+			token = os.Getenv(serveTokenEnv)
+		}
+		local := isLoopback(addr)
+		if token == "" && !local {
+			return &usageError{fmt.Errorf("serving on %s needs a token: pass --token or set %s", addr, serveTokenEnv)}
+		}
+		if token == "" {
+			var path string
+			token, path, err = generateServeToken()
+			if err != nil {
+				return fmt.Errorf("generating a token: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "Clients must send the bearer token in %s\n", path)
+		}
+		opts := []server.Option{server.WithVersion(version), server.WithToken(token)}
+		if local {
+			opts = append(opts, server.WithLocalOnly())
+		}
+
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		// Requests run with the command's context, so Ctrl-C stops installs
+		// in progress and rolls them back before fm exits
+		ctx := cmd.Context()
+		srv := &http.Server{
+			Handler:           server.New(manager, opts...),
+			ReadHeaderTimeout: 10 * time.Second,
+			BaseContext:       func(net.Listener) context.Context { return ctx },
+		}
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			<-ctx.Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), forceQuitGrace)
+			defer cancel()
+			srv.Shutdown(shutdown)
+		}()
+
+		fmt.Fprintf(os.Stderr, "Serving the fm API on http://%s (Ctrl-C to stop)\n", listener.Addr())
+		if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		<-stopped
+		return nil
+	},
+}
+
+// generateServeToken writes a random token to the default token path, readable
+// only by the user, and returns it with the path
+func generateServeToken() (token, path string, err error) {
+	path, err = fm.DefaultServeTokenPath()
+	if err != nil {
+		return "", "", err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", "", err
+	}
+	// Remove any earlier token first so the new file is created with the
+	// restrictive mode rather than keeping an old file's
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", "", err
+	}
+	return token, path, nil
+}
+
+// isLoopback reports whether addr only accepts connections from this machine
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func init() {
+	serveCmd.Flags().String("addr", "127.0.0.1:7070", "Address to listen on")
+	serveCmd.Flags().String("token", "", "Bearer token clients must send (defaults to $"+serveTokenEnv+")")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var sourcesCmd = &cobra.Command{
+	Use:   "sources",
+	Short: "Inspect the registered font sources",
+	Long: `List the registered font sources in the order they are searched, with
+their type, authentication and how fresh their cached catalog is.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		listSources()
+		return nil
+	},
+}
+
+var sourcesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the registered font sources",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		listSources()
+		return nil
+	},
+}
+
+// listSources prints a table of the registered sources
+func listSources() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PRIORITY\tNAME\tTYPE\tAUTH\tCACHE")
+	for _, s := range manager.Sources() {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", s.Priority, s.Name, s.Type, s.Auth, cacheStatus(s))
+	}
+	w.Flush()
+}
+
+// cacheStatus describes how fresh a source's cached catalog is
+func cacheStatus(s fm.SourceInfo) string {
+	switch {
+	case s.Index == "":
+		return "-"
+	case !s.Cached:
+		return "not cached"
+	}
+
+	age := time.Since(s.Updated).Round(time.Minute)
+	state := "fresh"
+	if !s.Fresh {
+		state = "stale"
+	}
+	if age < time.Minute {
+		return state + ", updated just now"
+	}
+	return fmt.Sprintf("%s, updated %s ago", state, strings.TrimSuffix(age.String(), "0s"))
+}
+
+var sourcesDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that every font source is reachable",
+	Long: `Exercise each registered source's API and report reachability, latency,
+authentication and rate limit status. Useful when a font refuses to install.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, err := checkSources(cmd)
+		return err
+	},
+}
+
+// checkSources probes every source and prints a table of the results
+func checkSources(cmd *cobra.Command) ([]fm.SourceHealth, error) {
+	fmt.Println("Checking sources...")
+	results := manager.CheckSources(cmd.Context())
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tSTATUS\tLATENCY\tAUTH\tRATE LIMIT\tDETAILS")
+
+	unhealthy := 0
+	for _, r := range results {
+		status := "OK"
+		if !r.Healthy {
+			status = "FAIL"
+			unhealthy++
+		}
+
+		latency := "-"
+		if r.Latency > 0 {
+			latency = r.Latency.Round(time.Millisecond).String()
+		}
+
+		rateLimit := "-"
+		if r.RateLimit != nil {
+			rateLimit = fmt.Sprintf("%d/%d left, resets %s",
+				r.RateLimit.Remaining, r.RateLimit.Limit, r.RateLimit.Reset.Format(time.Kitchen))
+		}
+
+		auth := r.Auth
+		if auth == "" {
+			auth = "-"
+		}
+
+		details := r.Endpoint
+		if r.Err != nil {
+			details = r.Err.Error()
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Source, status, latency, auth, rateLimit, details)
+	}
+	w.Flush()
+
+	if unhealthy > 0 {
+		return results, fmt.Errorf("%d of %d sources are unhealthy", unhealthy, len(results))
+	}
+	return results, nil
+}
+
+func init() {
+	rootCmd.AddCommand(sourcesCmd)
+	sourcesCmd.AddCommand(sourcesListCmd)
+	sourcesCmd.AddCommand(sourcesDoctorCmd)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var storeCmd = &cobra.Command{
+	Use:   "store",
+	Short: "Manage the experimental content-addressed font store",
+	Long: `The store layout keeps every font file once in a content-addressed store
+and turns each font directory into a symlink to an immutable generation,
+allowing instant rollback and atomic upgrades. Enable it for new installs
+with "layout: store" in the config file.`,
+}
+
+var storeMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move installed fonts into the store, or back out with --classic",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mgr, err := storeManager()
+		if err != nil {
+			return err
+		}
+
+		classic, _ := cmd.Flags().GetBool("classic")
+		migrate, layout := mgr.MigrateToStore, "store"
+		if classic {
+			migrate, layout = mgr.MigrateToClassic, "classic"
+		}
+
+		migrated, err := migrate(cmd.Context())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Migrated %d fonts to the %s layout\n", migrated, layout)
+		return nil
+	},
+}
+
+var storeRollbackCmd = &cobra.Command{
+	Use:   "rollback <font>",
+	Short: "Switch a font back to its previous generation",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mgr, err := storeManager()
+		if err != nil {
+			return err
+		}
+
+		generation, err := mgr.RollbackFont(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled %s back to generation %d\n", args[0], generation)
+		return nil
+	},
+}
+
+// defaultStore opens the store in its default location
+func defaultStore() (*fm.Store, error) {
+	dir, err := fm.DefaultStoreDir()
+	if err != nil {
+		return nil, fmt.Errorf("locating store: %w", err)
+	}
+	return fm.NewStore(dir), nil
+}
+
+// storeManager returns a font manager using the store regardless of the
+// configured layout, so fonts can be migrated in either direction
+func storeManager() (*fm.DefaultManager, error) {
+	store, err := defaultStore()
+	if err != nil {
+		return nil, err
+	}
+	return newManager(fm.WithStore(store))
+}
+
+func init() {
+	rootCmd.AddCommand(storeCmd)
+	storeCmd.AddCommand(storeMigrateCmd)
+	storeCmd.AddCommand(storeRollbackCmd)
+
+	storeMigrateCmd.Flags().Bool("classic", false, "Convert store-managed fonts back into plain directories")
+}
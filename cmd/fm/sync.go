@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync -f <file>",
+	Short: "Make the installed fonts match a config file",
+	Long: `Reconcile the installed fonts with one or more config files, in the format
+of "fm install -f" or as YAML:
+
+  fonts:
+    - JetBrainsMono@nerdfonts >=v3.0.0
+    - name: Inter
+      source: fontsource
+
+Missing fonts are installed, and fonts older than their ">=" constraint or
+installed from a different source are reinstalled. With --prune, fonts fm
+installed that aren't listed are removed after confirming; fonts installed by
+other means are never touched, and neither are fonts pinned with "fm pin". The
+changes are printed as a diff.`,
+	Example: `  # Preview the changes
+  fm sync -f fonts.yaml --prune --dry-run
+
+  # Apply them at low priority, e.g. from a login script
+  fm sync -f fonts.yaml --prune --background`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		files, _ := cmd.Flags().GetStringArray("file")
+		if len(files) == 0 {
+			return fmt.Errorf("at least one config file is required (-f)")
+		}
+		prune, _ := cmd.Flags().GetBool("prune")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		// A line that fails to parse must not get its font pruned
+		fonts, err := readConfigs(files)
+		if err != nil {
+			return err
+		}
+
+		var opts []fm.InstallOption
+		if accept, _ := cmd.Flags().GetBool("accept-eula"); accept {
+			opts = append(opts, fm.WithAcceptEULA())
+		}
+		if background, _ := cmd.Flags().GetBool("background"); background {
+			lowerPriority()
+			opts = append(opts, fm.WithParallelism(cfg.Background.Parallelism))
+		}
+		if jobs, ok := jobsFlag(cmd); ok {
+			opts = append(opts, fm.WithParallelism(jobs))
+		}
+
+		syncOpts := fm.SyncOptions{Prune: prune, DryRun: dryRun}
+		if shouldConfirm(cmd, true) {
+			syncOpts.ConfirmRemove = confirmPrune
+			opts = append(opts, fm.WithConfirmDownload(largeDownload, confirmDownload()))
+		}
+		changes, err := manager.Sync(cmd.Context(), fonts, syncOpts, opts...)
+		printSyncChanges(changes, dryRun)
+		if err != nil {
+			return fmt.Errorf("syncing fonts: %w", err)
+		}
+		return nil
+	},
+}
+
+// printSyncChanges prints the changes of a sync as a diff. With --quiet only
+// the changes that failed are printed, unless it was a dry run.
+func printSyncChanges(changes []fm.SyncChange, dryRun bool) {
+	out := textOut()
+	if dryRun {
+		out = os.Stdout
+	}
+	unchanged := 0
+	for _, change := range changes {
+		var line string
+		switch change.Action {
+		case fm.SyncInstall:
+			line = "+ " + fm.FormatFontSpec(change.Font)
+		case fm.SyncUpdate:
+			line = fmt.Sprintf("~ %s (%s)", fm.FormatFontSpec(change.Font), change.Reason)
+		case fm.SyncRemove:
+			line = "- " + change.Font.Name
+		default:
+			unchanged++
+			// Pinned fonts are always shown, since they may be held back
+			if change.Reason != "" {
+				fmt.Fprintf(out, "  %s (%s)\n", fm.FormatFontSpec(change.Font), change.Reason)
+			} else if verbosity > 0 {
+				fmt.Fprintf(out, "  %s\n", fm.FormatFontSpec(change.Font))
+			}
+			continue
+		}
+		if change.Plan != nil && change.Err == nil {
+			line += planSummary(*change.Plan)
+		}
+		if change.Err != nil {
+			fmt.Printf("%s: %s: %v\n", line, paint(os.Stdout, failColor, "failed"), change.Err)
+			continue
+		}
+		fmt.Fprintln(out, line)
+		if change.Plan != nil && change.Plan.URL != "" {
+			fmt.Fprintf(out, "    %s\n", change.Plan.URL)
+		}
+	}
+
+	if dryRun {
+		fmt.Fprintf(out, "Dry run: nothing was changed, %d fonts already up to date\n", unchanged)
+		return
+	}
+	fmt.Fprintf(out, "%d fonts already up to date\n", unchanged)
+}
+
+// confirmPrune asks before a sync removes fonts that aren't listed; declined
+// removals are skipped while the rest of the sync goes ahead
+func confirmPrune(fonts []fm.Font) bool {
+	fmt.Println("The following fonts aren't listed and will be uninstalled:")
+	for _, font := range fonts {
+		fmt.Printf("  %s\n", font.Name)
+	}
+	return confirm(fmt.Sprintf("Uninstall %d fonts?", len(fonts)))
+}
+
+func init() {
+	syncCmd.Flags().StringArrayP("file", "f", nil, "Config file to sync with, or - for stdin; repeat to merge files, later ones overriding earlier duplicates")
+	syncCmd.Flags().Bool("prune", false, "Remove fonts fm installed that aren't listed")
+	syncCmd.Flags().Bool("confirm", false, "Ask for confirmation before pruning, whatever the confirm policy")
+	syncCmd.Flags().Bool("dry-run", false, "Print the changes and where installs would be downloaded from, without making them")
+	syncCmd.Flags().Bool("accept-eula", false, "Accept the license agreement of sources that require one (e.g. mscorefonts)")
+	syncCmd.Flags().Bool("background", false, "Run at low priority with the parallelism from the background config")
+	syncCmd.Flags().IntP("jobs", "j", 0, fmt.Sprintf("How many fonts to download and extract at once (defaults to parallelism in the config, or %d)", fm.DefaultParallelism))
+	rootCmd.AddCommand(syncCmd)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade [font...]",
+	Short: "Reinstall fonts that have a newer upstream release",
+	Long: `Compare the version each font was installed at with the latest release of
+its source (Nerd Fonts tags, fontsource versions, CJK releases) and reinstall
+only the fonts that are out of date. Fonts pinned with "fm pin" are never
+reinstalled.
+
+Without arguments every installed font with a recorded version is checked.
+
+Examples:
+  # Upgrade everything that is out of date
+  fm upgrade
+
+  # Upgrade specific fonts
+  fm upgrade FiraCode JetBrainsMono`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts []fm.InstallOption
+		if accept, _ := cmd.Flags().GetBool("accept-eula"); accept {
+			opts = append(opts, fm.WithAcceptEULA())
+		}
+		if override, _ := cmd.Flags().GetBool("override-license"); override {
+			opts = append(opts, fm.WithLicenseOverride())
+		}
+
+		results, err := manager.Upgrade(cmd.Context(), args, opts...)
+		if len(results) == 0 && err == nil {
+			fmt.Println("No installed fonts have a recorded version to check")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSOURCE\tINSTALLED\tLATEST\tSTATUS")
+		for _, result := range results {
+			status := "up to date"
+			switch {
+			case result.Err != nil:
+				status = "failed"
+			case result.Pinned:
+				status = "pinned"
+			case result.Upgraded:
+				status = "upgraded"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", result.Name, result.Source,
+				orDash(result.Installed), orDash(result.Latest), status)
+		}
+		if flushErr := w.Flush(); flushErr != nil {
+			return flushErr
+		}
+
+		if err != nil {
+			return fmt.Errorf("some fonts could not be upgraded: %w", err)
+		}
+		return nil
+	},
+}
+
+// orDash stands in for unknown values in tables
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	upgradeCmd.Flags().Bool("accept-eula", false, "Accept the license agreement of sources that require one (e.g. mscorefonts)")
+	upgradeCmd.Flags().Bool("override-license", false, "Upgrade even if a font's license isn't in allowed_licenses; recorded in the audit log")
+	rootCmd.AddCommand(upgradeCmd)
+}
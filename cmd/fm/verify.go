@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [font...]",
+	Short: "Check installed font files against their recorded checksums",
+	Long: `Re-hash the files of installed fonts and compare them with the SHA-256
+checksums recorded when they were installed, reporting files that are missing,
+were modified, or were added to the font's directory since.
+
+Without arguments every font fm installed is checked. The command fails if
+any font doesn't match, so it can run in scripts and CI.
+
+Files whose size and modification time haven't changed since they were hashed
+are trusted without reading them again; --rehash hashes every file.`,
+	Example: `  fm verify
+  fm verify JetBrainsMono Inter
+  fm verify --rehash`,
+	ValidArgsFunction: completeInstalled(false),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rehash, _ := cmd.Flags().GetBool("rehash")
+		results, err := manager.Verify(cmd.Context(), args, rehash)
+		if err != nil && results == nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Println("No fonts installed by fm")
+			return nil
+		}
+
+		failed := 0
+		for _, result := range results {
+			printVerification(result)
+			if !result.OK() {
+				failed++
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("some fonts could not be checked: %w", err)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d fonts failed verification", failed, len(results))
+		}
+		return nil
+	},
+}
+
+// printVerification prints the outcome for one font and its changed files
+func printVerification(result fm.FontVerification) {
+	switch {
+	case result.Err != nil:
+		fmt.Printf("%s: could not be checked: %v\n", result.Font.Name, result.Err)
+	case result.Unrecorded:
+		fmt.Printf("%s: no checksums were recorded\n", result.Font.Name)
+	case result.OK():
+		fmt.Printf("%s: ok\n", result.Font.Name)
+	default:
+		fmt.Printf("%s:\n", result.Font.Name)
+		for _, file := range result.Missing {
+			fmt.Printf("  missing   %s\n", file)
+		}
+		for _, file := range result.Modified {
+			fmt.Printf("  modified  %s\n", file)
+		}
+		for _, file := range result.Extra {
+			fmt.Printf("  extra     %s\n", file)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().Bool("rehash", false, "Hash every file, even those unchanged in size and modification time since they were recorded")
+}
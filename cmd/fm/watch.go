@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Refresh the font cache when fonts change outside fm",
+	Long: `Watch the user and system font directories and refresh the font cache when
+font files are added, removed or replaced by something other than fm, such as
+copying fonts by hand or syncing them with Syncthing. Changes are picked up
+once the directories settle, so a large sync triggers a single refresh.
+
+fm refreshes the cache after its own installs, so this is only needed when
+fonts are managed by other means too. Run it in the background, e.g. as a
+systemd user service or a launchd agent.
+
+Examples:
+  # Watch with the default interval
+  fm watch
+
+  # Check every 30 seconds, refreshing the cache at low priority
+  fm watch --interval 30s --low-priority`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		interval, _ := cmd.Flags().GetDuration("interval")
+		if low, _ := cmd.Flags().GetBool("low-priority"); low || cfg.Background.LowPriority {
+			lowerPriority()
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		fmt.Printf("Watching font directories every %s (Ctrl-C to stop)\n", interval)
+		return manager.WatchFontDirs(ctx, interval, func(change fm.FontDirChange, err error) {
+			for _, path := range change.Added {
+				fmt.Printf("+ %s\n", path)
+			}
+			for _, path := range change.Removed {
+				fmt.Printf("- %s\n", path)
+			}
+			for _, path := range change.Modified {
+				fmt.Printf("~ %s\n", path)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to update font cache: %v\n", err)
+				return
+			}
+			fmt.Printf("Refreshed font cache at %s\n", time.Now().Format(time.TimeOnly))
+		})
+	},
+}
+
+func init() {
+	watchCmd.Flags().Duration("interval", fm.DefaultWatchInterval, "How often to scan the font directories")
+	watchCmd.Flags().Bool("low-priority", false, "Run at reduced CPU and I/O priority (background.low_priority in the config)")
+	rootCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var whichCmd = &cobra.Command{
+	Use:   "which <font>",
+	Short: "Show the directory and files of an installed font",
+	Long: `Print where an installed font lives: whether it is in the user or system
+font directory, the directory holding it and the path of every font file, e.g.
+to point a terminal emulator or editor at a specific file. --paths prints only
+the file paths, one per line, for use in scripts.`,
+	Example: `  fm which JetBrainsMono
+  fm which JetBrainsMono --paths | grep Mono-Regular`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInstalled(true),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loc, err := manager.Which(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		if paths, _ := cmd.Flags().GetBool("paths"); paths {
+			for _, file := range loc.Files {
+				fmt.Println(file)
+			}
+			return nil
+		}
+
+		fmt.Printf("%s (%s", loc.Font.Name, loc.Scope)
+		if loc.Font.Source != "" {
+			fmt.Printf(", from %s", loc.Font.Source)
+		}
+		fmt.Printf(")\n  %s\n\nFiles:\n", loc.Dir)
+		for _, file := range loc.Files {
+			fmt.Printf("  %s\n", file)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whichCmd)
+	whichCmd.Flags().Bool("paths", false, "Print only the file paths, one per line")
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/logandonley/font-manager/internal/config"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var setupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Choose preferred sources, variants, install scope and caching",
+	Long: `Walk through fm's main settings and write them to the config file:
+
+- which sources are searched first
+- whether Nerd Fonts installs only the Mono or Propo variants
+- whether fonts are installed for you or for every user
+- whether downloads and source catalogs are cached
+
+fm offers this the first time it runs without a config file. Other settings in
+an existing config file are kept.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configFile(cmd)
+		if err != nil {
+			return err
+		}
+		return runWizard(path)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(setupCmd)
+}
+
+// firstRun offers the setup wizard when fm runs without a config file.
+// Without a terminal, or with --no-input or under chezmoi, the defaults are
+// used and nothing is written.
+func firstRun(cmd *cobra.Command, path string) error {
+	if config.Exists(path) || skipsFirstRun(cmd) {
+		return nil
+	}
+	if noInput || !isTerminal(os.Stdin) {
+		return nil
+	}
+
+	fmt.Printf("No config file found at %s.\n", path)
+	w := newWizard(os.Stdin, os.Stdout)
+	if w.choose("Set up fm now?", []string{"yes", "no"}, "yes") == "no" {
+		// Write the defaults so the question isn't asked again
+		if err := config.Save(path, cfg); err != nil {
+			return err
+		}
+		fmt.Println("Using the defaults; run fm setup to change them.")
+		fmt.Println()
+		return nil
+	}
+	if err := w.run(path); err != nil {
+		return err
+	}
+	fmt.Println()
+	return nil
+}
+
+// skipsFirstRun reports whether cmd runs without offering the wizard, such
+// as help, shell completion and fm setup itself
+func skipsFirstRun(cmd *cobra.Command) bool {
+	switch cmd.Name() {
+	case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	return cmd == setupCmd
+}
+
+// runWizard runs the setup wizard on the terminal
+func runWizard(path string) error {
+	return newWizard(os.Stdin, os.Stdout).run(path)
+}
+
+// wizard asks the setup questions
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newWizard(in io.Reader, out io.Writer) *wizard {
+	return &wizard{in: bufio.NewReader(in), out: out}
+}
+
+// run asks every question, then saves the answers on top of the loaded
+// config to path and makes them the config of this run
+func (w *wizard) run(path string) error {
+	mgr, err := newManager()
+	if err != nil {
+		return fmt.Errorf("initializing font manager: %w", err)
+	}
+	var sources []string
+	for _, info := range mgr.Sources() {
+		sources = append(sources, info.Name)
+	}
+
+	updated := *cfg
+	updated.SourcePriority = w.sourcePriority(sources)
+
+	variants := make(map[string][]string)
+	for source, v := range cfg.DefaultVariants {
+		variants[source] = v
+	}
+	delete(variants, "nerdfonts")
+	switch w.choose("Nerd Fonts variants to install by default", []string{"all", "mono", "propo"}, "all") {
+	case "mono":
+		variants["nerdfonts"] = []string{"Mono"}
+	case "propo":
+		variants["nerdfonts"] = []string{"Propo"}
+	}
+	updated.DefaultVariants = nil
+	if len(variants) > 0 {
+		updated.DefaultVariants = variants
+	}
+
+	scope := config.ScopeUser
+	if cfg.Scope != "" {
+		scope = cfg.Scope
+	}
+	updated.Scope = w.choose("Install fonts for this user or for every user (requires root)", []string{config.ScopeUser, config.ScopeSystem}, scope)
+
+	cached := "yes"
+	if cfg.Cache.Enabled != nil && !*cfg.Cache.Enabled {
+		cached = "no"
+	}
+	enabled := w.choose("Cache downloads and source catalogs?", []string{"yes", "no"}, cached) == "yes"
+	updated.Cache.Enabled = &enabled
+
+	if err := config.Save(path, &updated); err != nil {
+		return err
+	}
+	cfg = &updated
+	fmt.Fprintf(w.out, "Wrote %s\n", path)
+	return nil
+}
+
+// sourcePriority asks for the sources to search first, returning nil to
+// keep the registration order
+func (w *wizard) sourcePriority(sources []string) []string {
+	fmt.Fprintf(w.out, "Sources are searched in this order: %s\n", strings.Join(sources, ", "))
+	for {
+		answer := w.ask("Sources to search first, comma separated (empty keeps the order):", "")
+		if answer == "" {
+			return nil
+		}
+
+		var priority []string
+		var unknown []string
+		for _, name := range strings.Split(answer, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			switch {
+			case name == "" || slices.Contains(priority, name):
+			case slices.Contains(sources, name):
+				priority = append(priority, name)
+			default:
+				unknown = append(unknown, name)
+			}
+		}
+		if len(unknown) == 0 {
+			return priority
+		}
+		fmt.Fprintf(w.out, "Unknown sources: %s\n", strings.Join(unknown, ", "))
+	}
+}
+
+// ask prints question and returns the trimmed answer, or def when the
+// answer is empty or the input has ended
+func (w *wizard) ask(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s] ", question, def)
+	} else {
+		fmt.Fprintf(w.out, "%s ", question)
+	}
+	answer, _ := w.in.ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def
+	}
+	return answer
+}
+
+// choose asks question until the answer is one of choices
+func (w *wizard) choose(question string, choices []string, def string) string {
+	for {
+		answer := strings.ToLower(w.ask(fmt.Sprintf("%s (%s)", question, strings.Join(choices, "/")), def))
+		for _, choice := range choices {
+			if answer == choice || len(answer) == 1 && strings.HasPrefix(choice, answer) {
+				return choice
+			}
+		}
+		fmt.Fprintf(w.out, "Please answer %s\n", strings.Join(choices, " or "))
+	}
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe
+// or file
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
@@ -0,0 +1,500 @@
+// Package config loads fm's user configuration file
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the contents of fm's configuration file
+type Config struct {
+	Sources []SourceConfig `yaml:"sources,omitempty"`
+	IPFS    IPFSConfig     `yaml:"ipfs,omitempty"`
+	Hosts   []HostConfig   `yaml:"hosts,omitempty"`
+
+	// Layout selects how installed fonts are stored: LayoutClassic (the
+	// default) or the experimental LayoutStore
+	Layout string `yaml:"layout,omitempty"`
+
+	// CacheCommand replaces the platform's font cache refresh, e.g.
+	// ["fc-cache", "-f", "-v"]
+	CacheCommand []string `yaml:"cache_command,omitempty"`
+
+	// InstancerCommand replaces the fontTools instancer that limits the axes
+	// of variable fonts, e.g. ["python3", "-m", "fontTools.varLib.instancer"]
+	InstancerCommand []string `yaml:"instancer_command,omitempty"`
+
+	// ResetFontServer controls whether macOS refreshes reset the font server
+	// with atsutil; unset means true
+	ResetFontServer *bool `yaml:"reset_font_server,omitempty"`
+
+	// AllowedLicenses lists the SPDX identifiers fonts may be installed under,
+	// e.g. ["OFL-1.1", "Apache-2.0"]; empty allows any license
+	AllowedLicenses []string `yaml:"allowed_licenses,omitempty"`
+
+	// Background tunes work fm does unattended, such as fm watch
+	Background BackgroundConfig `yaml:"background,omitempty"`
+
+	// Aliases map names of the user's choosing to the font specs they stand
+	// for wherever a spec is accepted, e.g. code-font: FiraCode@nerdfonts
+	Aliases map[string]string `yaml:"aliases,omitempty"`
+
+	// SourcePriority lists sources to search before the others, in order
+	SourcePriority []string `yaml:"source_priority,omitempty"`
+
+	// DefaultVariants maps a source name to the variants installed from it
+	// when an install doesn't choose any, e.g. nerdfonts: [Mono]
+	DefaultVariants map[string][]string `yaml:"default_variants,omitempty"`
+
+	// Scope selects where fonts are installed: ScopeUser (the default) or
+	// ScopeSystem
+	Scope string `yaml:"scope,omitempty"`
+
+	// FontDir replaces the platform's user font directory, e.g. to keep
+	// fonts on another disk; may reference environment variables like $HOME
+	FontDir string `yaml:"font_dir,omitempty"`
+
+	// Proxy is the URL of the proxy every request goes through; unset uses
+	// HTTP_PROXY and HTTPS_PROXY from the environment
+	Proxy string `yaml:"proxy,omitempty"`
+
+	// SourceHTTP tunes how the built-in sources talk to their servers, by
+	// source name, e.g. nerdfonts: {timeout: 10m, retries: 5}. Custom
+	// sources take the same settings in their own http block.
+	SourceHTTP map[string]*HTTPConfig `yaml:"source_http,omitempty"`
+
+	// Parallelism is how many fonts are downloaded and extracted at once
+	// when installing several; zero means fm's default of 4
+	Parallelism int `yaml:"parallelism,omitempty"`
+
+	// Format is the font format installed, ttf or otf, when an archive has
+	// the same faces in both; unset installs both
+	Format string `yaml:"format,omitempty"`
+
+	// Cache controls the download and catalog cache
+	Cache CacheConfig `yaml:"cache,omitempty"`
+
+	// Scan limits how the system font directory is walked
+	Scan ScanConfig `yaml:"scan,omitempty"`
+
+	// LowMemory extracts and hashes downloads one at a time with small
+	// buffers, for devices like the Raspberry Pi; unset enables it when
+	// little memory is available
+	LowMemory *bool `yaml:"low_memory,omitempty"`
+
+	// Confirm selects when commands that remove fonts ask first: one of the
+	// Confirm constants; unset means ConfirmDestructive
+	Confirm string `yaml:"confirm,omitempty"`
+
+	// Hooks run shell commands before and after fonts change
+	Hooks HooksConfig `yaml:"hooks,omitempty"`
+}
+
+// HooksConfig lists shell commands run before and after each font is
+// installed, uninstalled or upgraded, e.g. to regenerate a terminal's config.
+// A command that fails before a change stops it.
+type HooksConfig struct {
+	PreInstall    []string `yaml:"pre_install,omitempty"`
+	PostInstall   []string `yaml:"post_install,omitempty"`
+	PreUninstall  []string `yaml:"pre_uninstall,omitempty"`
+	PostUninstall []string `yaml:"post_uninstall,omitempty"`
+	PreUpgrade    []string `yaml:"pre_upgrade,omitempty"`
+	PostUpgrade   []string `yaml:"post_upgrade,omitempty"`
+}
+
+// HookActions are the changes hooks can run around
+var HookActions = []string{"install", "uninstall", "upgrade"}
+
+// Commands returns the commands run before, or after, the action, one of
+// HookActions
+func (h HooksConfig) Commands(action string, after bool) []string {
+	switch {
+	case action == "install" && !after:
+		return h.PreInstall
+	case action == "install":
+		return h.PostInstall
+	case action == "uninstall" && !after:
+		return h.PreUninstall
+	case action == "uninstall":
+		return h.PostUninstall
+	case action == "upgrade" && !after:
+		return h.PreUpgrade
+	case action == "upgrade":
+		return h.PostUpgrade
+	}
+	return nil
+}
+
+// Confirmation policies
+const (
+	ConfirmAlways      = "always"      // Before every uninstall, prune and dedupe
+	ConfirmDestructive = "destructive" // Before removing fonts that weren't named one by one
+	ConfirmNever       = "never"       // Never ask, e.g. for automation
+)
+
+// ScanConfig limits how the system font directory is walked when listing
+// fonts
+type ScanConfig struct {
+	// MaxDepth is how many directory levels below the font directory are
+	// walked; zero means no limit
+	MaxDepth int `yaml:"max_depth,omitempty"`
+
+	// Exclude lists globs of paths to skip, relative to the font directory,
+	// e.g. ["truetype/dejavu", "noto*"]
+	Exclude []string `yaml:"exclude,omitempty"`
+
+	// SkipLargeDirs skips known huge subtrees such as Noto and Source Han
+	// for commands that only need the fonts fm installed; unset means true
+	SkipLargeDirs *bool `yaml:"skip_large_dirs,omitempty"`
+}
+
+// CacheConfig controls the download and catalog cache
+type CacheConfig struct {
+	// Enabled caches downloads, source catalogs and search results; unset
+	// means true
+	Enabled *bool `yaml:"enabled,omitempty"`
+
+	// Dir replaces the per-user cache directory; may reference environment
+	// variables like $HOME
+	Dir string `yaml:"dir,omitempty"`
+
+	// Rehash hashes cached downloads and installed files every time they are
+	// reused or verified, instead of trusting the digests recorded with them
+	// while their size and modification time are unchanged
+	Rehash bool `yaml:"rehash,omitempty"`
+}
+
+// Install scopes
+const (
+	ScopeUser   = "user"   // The user's own font directory
+	ScopeSystem = "system" // The system font directory, shared by every user
+)
+
+// BackgroundConfig keeps unattended work from slowing down the machine
+type BackgroundConfig struct {
+	// LowPriority runs at reduced CPU and I/O priority, like nice and ionice
+	LowPriority bool `yaml:"low_priority,omitempty"`
+
+	// Parallelism caps how many fonts are downloaded and extracted at once;
+	// zero means one at a time
+	Parallelism int `yaml:"parallelism,omitempty"`
+}
+
+// Installed font layouts
+const (
+	LayoutClassic = "classic" // A plain directory per font
+	LayoutStore   = "store"   // Symlinks into a content-addressed store
+)
+
+// IPFSConfig configures how IPFS content is fetched
+type IPFSConfig struct {
+	Gateway string `yaml:"gateway,omitempty"` // HTTP gateway, e.g. https://ipfs.io
+}
+
+// HostConfig holds the credentials used for direct URL downloads from a
+// host. Values may reference environment variables as $VAR or ${VAR} so
+// secrets can stay out of the file.
+type HostConfig struct {
+	Host     string            `yaml:"host,omitempty"`     // Host name, optionally with a port
+	Username string            `yaml:"username,omitempty"` // Basic auth user
+	Password string            `yaml:"password,omitempty"` // Basic auth password
+	Headers  map[string]string `yaml:"headers,omitempty"`  // Extra request headers, e.g. Authorization
+}
+
+// Custom source types
+const (
+	SourceTypeTemplate  = "template"  // Downloads from a URL template
+	SourceTypeDirectory = "directory" // Matches archives in a directory listing
+)
+
+// SourceConfig defines a custom font source
+type SourceConfig struct {
+	Name  string `yaml:"name,omitempty"`  // Identifier used with name@source
+	Type  string `yaml:"type,omitempty"`  // One of the SourceType constants; defaults to template
+	URL   string `yaml:"url,omitempty"`   // URL template containing {name}, or the directory URL
+	Index string `yaml:"index,omitempty"` // Optional JSON index used for search by template sources
+
+	// HTTP tunes how the source talks to its server; unset settings keep
+	// fm's defaults
+	HTTP *HTTPConfig `yaml:"http,omitempty"`
+}
+
+// HTTPConfig overrides the HTTP settings of one source. Unset fields keep
+// fm's defaults.
+type HTTPConfig struct {
+	Timeout            time.Duration `yaml:"timeout,omitempty"`              // Limit for a whole request including the body, e.g. 10m
+	ConnectTimeout     time.Duration `yaml:"connect_timeout,omitempty"`      // Limit for dialing and the TLS handshake, e.g. 5s
+	Retries            *int          `yaml:"retries,omitempty"`              // Additional attempts after network errors and 429/5xx responses
+	Proxy              string        `yaml:"proxy,omitempty"`                // Proxy URL, replacing the global proxy for this source
+	CACertFile         string        `yaml:"ca_cert_file,omitempty"`         // PEM bundle trusted in addition to the system roots
+	InsecureSkipVerify bool          `yaml:"insecure_skip_verify,omitempty"` // Disable TLS certificate verification
+}
+
+// validate checks that the settings are usable
+func (h *HTTPConfig) validate() error {
+	if h.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if h.ConnectTimeout < 0 {
+		return fmt.Errorf("connect_timeout must not be negative")
+	}
+	if h.Retries != nil && *h.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+	if h.Proxy != "" {
+		if u, err := url.Parse(h.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("proxy must be a URL such as http://proxy.example.com:3128, got %q", h.Proxy)
+		}
+	}
+	return nil
+}
+
+// PathEnv names the environment variable that replaces the default
+// configuration file location
+const PathEnv = "FM_CONFIG"
+
+// DefaultPath returns the per-user configuration file location, or the one
+// PathEnv names
+func DefaultPath() (string, error) {
+	if path := os.Getenv(PathEnv); path != "" {
+		return path, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("getting user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "fm", "config.yaml"), nil
+}
+
+// Exists reports whether a config file has been written at path
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Save writes cfg to path, creating its directory
+func Save(path string, cfg *Config) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return fmt.Errorf("encoding config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	return nil
+}
+
+// Update applies change to the configuration at path and saves it. Unlike
+// Load, environment variables in credentials are left unexpanded, so they
+// aren't written back as their values.
+func Update(path string, change func(*Config) error) error {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	if err := change(&cfg); err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	return Save(path, &cfg)
+}
+
+// Load reads the configuration at path. A missing file is not an error and
+// yields an empty configuration.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	cfg.FontDir = os.ExpandEnv(cfg.FontDir)
+	cfg.Cache.Dir = os.ExpandEnv(cfg.Cache.Dir)
+	for _, settings := range cfg.SourceHTTP {
+		if settings != nil {
+			settings.CACertFile = os.ExpandEnv(settings.CACertFile)
+		}
+	}
+	for _, source := range cfg.Sources {
+		if source.HTTP != nil {
+			source.HTTP.CACertFile = os.ExpandEnv(source.HTTP.CACertFile)
+		}
+	}
+	for i := range cfg.Hosts {
+		host := &cfg.Hosts[i]
+		host.Username = os.ExpandEnv(host.Username)
+		host.Password = os.ExpandEnv(host.Password)
+		for name, value := range host.Headers {
+			host.Headers[name] = os.ExpandEnv(value)
+		}
+	}
+	return &cfg, nil
+}
+
+// Validate checks that every source and host is fully specified and uniquely
+// named
+func (c *Config) Validate() error {
+	switch c.Layout {
+	case "", LayoutClassic, LayoutStore:
+	default:
+		return fmt.Errorf("unknown layout %q", c.Layout)
+	}
+
+	if len(c.CacheCommand) > 0 && c.CacheCommand[0] == "" {
+		return fmt.Errorf("cache_command: command name is empty")
+	}
+	if len(c.InstancerCommand) > 0 && c.InstancerCommand[0] == "" {
+		return fmt.Errorf("instancer_command: command name is empty")
+	}
+
+	for i, license := range c.AllowedLicenses {
+		if license == "" {
+			return fmt.Errorf("allowed_licenses: entry %d is empty", i+1)
+		}
+	}
+
+	if c.Parallelism < 0 {
+		return fmt.Errorf("parallelism must not be negative")
+	}
+
+	switch strings.ToLower(c.Format) {
+	case "", "ttf", "otf":
+	default:
+		return fmt.Errorf("unknown format %q (expected ttf or otf)", c.Format)
+	}
+	if c.Background.Parallelism < 0 {
+		return fmt.Errorf("background.parallelism must not be negative")
+	}
+
+	if c.Proxy != "" {
+		if u, err := url.Parse(c.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("proxy must be a URL such as http://proxy.example.com:3128, got %q", c.Proxy)
+		}
+	}
+
+	if c.Scan.MaxDepth < 0 {
+		return fmt.Errorf("scan.max_depth must not be negative")
+	}
+	for i, glob := range c.Scan.Exclude {
+		if _, err := filepath.Match(glob, ""); glob == "" || err != nil {
+			return fmt.Errorf("scan.exclude: entry %d is not a valid glob: %q", i+1, glob)
+		}
+	}
+
+	switch c.Scope {
+	case "", ScopeUser, ScopeSystem:
+	default:
+		return fmt.Errorf("unknown scope %q", c.Scope)
+	}
+
+	switch c.Confirm {
+	case "", ConfirmAlways, ConfirmDestructive, ConfirmNever:
+	default:
+		return fmt.Errorf("unknown confirm policy %q (expected always, destructive or never)", c.Confirm)
+	}
+
+	for _, action := range HookActions {
+		for _, when := range []string{"pre", "post"} {
+			for i, command := range c.Hooks.Commands(action, when == "post") {
+				if strings.TrimSpace(command) == "" {
+					return fmt.Errorf("hooks.%s_%s: entry %d is empty", when, action, i+1)
+				}
+			}
+		}
+	}
+
+	for name, spec := range c.Aliases {
+		if strings.TrimSpace(name) == "" || strings.TrimSpace(spec) == "" {
+			return fmt.Errorf("aliases: %q needs both a name and a font spec", name)
+		}
+	}
+
+	for i, name := range c.SourcePriority {
+		if name == "" {
+			return fmt.Errorf("source_priority: entry %d is empty", i+1)
+		}
+	}
+
+	for source, variants := range c.DefaultVariants {
+		if len(variants) == 0 {
+			return fmt.Errorf("default_variants: %s lists no variants", source)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, source := range c.Sources {
+		if source.Name == "" {
+			return fmt.Errorf("source %d: name is required", i+1)
+		}
+		if source.URL == "" {
+			return fmt.Errorf("source %q: url is required", source.Name)
+		}
+		switch source.Type {
+		case "", SourceTypeTemplate, SourceTypeDirectory:
+		default:
+			return fmt.Errorf("source %q: unknown type %q", source.Name, source.Type)
+		}
+		if seen[source.Name] {
+			return fmt.Errorf("source %q is defined more than once", source.Name)
+		}
+		seen[source.Name] = true
+		if source.HTTP != nil {
+			if err := source.HTTP.validate(); err != nil {
+				return fmt.Errorf("source %q: http: %w", source.Name, err)
+			}
+		}
+	}
+
+	for name, settings := range c.SourceHTTP {
+		if seen[name] {
+			return fmt.Errorf("source_http: %s is a custom source; set http on its definition instead", name)
+		}
+		if settings == nil {
+			continue
+		}
+		if err := settings.validate(); err != nil {
+			return fmt.Errorf("source_http: %s: %w", name, err)
+		}
+	}
+
+	hosts := make(map[string]bool)
+	for i, host := range c.Hosts {
+		if host.Host == "" {
+			return fmt.Errorf("host %d: host is required", i+1)
+		}
+		if hosts[host.Host] {
+			return fmt.Errorf("host %q is defined more than once", host.Host)
+		}
+		hosts[host.Host] = true
+	}
+	return nil
+}
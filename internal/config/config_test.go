@@ -0,0 +1,406 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/logandonley/font-manager/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config", func() {
+	var (
+		tempDir string
+		path    string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "config-test-*")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(tempDir, "config.yaml")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	It("should take the default path from the environment", func() {
+		os.Setenv(config.PathEnv, path)
+		defer os.Unsetenv(config.PathEnv)
+
+		Expect(config.DefaultPath()).To(Equal(path))
+	})
+
+	It("should treat a missing file as an empty config", func() {
+		cfg, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Sources).To(BeEmpty())
+	})
+
+	It("should load URL template sources", func() {
+		Expect(os.WriteFile(path, []byte(`
+sources:
+  - name: internal
+    url: https://fonts.example.com/{name}.zip
+    index: https://fonts.example.com/index.json
+`), 0644)).To(Succeed())
+
+		cfg, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Sources).To(Equal([]config.SourceConfig{{
+			Name:  "internal",
+			URL:   "https://fonts.example.com/{name}.zip",
+			Index: "https://fonts.example.com/index.json",
+		}}))
+	})
+
+	It("should reject sources without a URL", func() {
+		Expect(os.WriteFile(path, []byte("sources:\n  - name: internal\n"), 0644)).To(Succeed())
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("url is required")))
+	})
+
+	It("should reject unknown source types", func() {
+		Expect(os.WriteFile(path, []byte(`
+sources:
+  - name: internal
+    type: ftp
+    url: ftp://fonts.example.com/
+`), 0644)).To(Succeed())
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("unknown type")))
+	})
+
+	It("should load per-source HTTP settings, expanding environment variables in paths", func() {
+		os.Setenv("CERT_DIR", "/etc/certs")
+		defer os.Unsetenv("CERT_DIR")
+
+		Expect(os.WriteFile(path, []byte(`
+source_http:
+  nerdfonts:
+    timeout: 10m
+    retries: 0
+sources:
+  - name: internal
+    url: https://fonts.example.com/{name}.zip
+    http:
+      connect_timeout: 5s
+      proxy: http://proxy.example.com:3128
+      ca_cert_file: $CERT_DIR/internal.pem
+      insecure_skip_verify: true
+`), 0644)).To(Succeed())
+
+		cfg, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		retries := 0
+		Expect(cfg.SourceHTTP).To(Equal(map[string]*config.HTTPConfig{
+			"nerdfonts": {Timeout: 10 * time.Minute, Retries: &retries},
+		}))
+		Expect(cfg.Sources[0].HTTP).To(Equal(&config.HTTPConfig{
+			ConnectTimeout:     5 * time.Second,
+			Proxy:              "http://proxy.example.com:3128",
+			CACertFile:         "/etc/certs/internal.pem",
+			InsecureSkipVerify: true,
+		}))
+	})
+
+	It("should reject invalid per-source HTTP settings", func() {
+		Expect(os.WriteFile(path, []byte("source_http:\n  nerdfonts:\n    retries: -1\n"), 0644)).To(Succeed())
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("source_http: nerdfonts: retries must not be negative")))
+
+		Expect(os.WriteFile(path, []byte(`
+sources:
+  - name: internal
+    url: https://fonts.example.com/{name}.zip
+    http:
+      proxy: proxy.example.com
+`), 0644)).To(Succeed())
+		_, err = config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring(`source "internal": http: proxy must be a URL`)))
+
+		Expect(os.WriteFile(path, []byte("source_http:\n  fontsource:\n    timeout: soon\n"), 0644)).To(Succeed())
+		_, err = config.Load(path)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject source_http entries for custom sources", func() {
+		Expect(os.WriteFile(path, []byte(`
+source_http:
+  internal:
+    timeout: 1m
+sources:
+  - name: internal
+    url: https://fonts.example.com/{name}.zip
+`), 0644)).To(Succeed())
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("set http on its definition instead")))
+	})
+
+	It("should load host credentials, expanding environment variables", func() {
+		os.Setenv("FM_TEST_TOKEN", "s3cret")
+		defer os.Unsetenv("FM_TEST_TOKEN")
+
+		Expect(os.WriteFile(path, []byte(`
+hosts:
+  - host: fonts.example.com
+    username: ci
+    password: ${FM_TEST_TOKEN}
+    headers:
+      X-Api-Key: key-$FM_TEST_TOKEN
+`), 0644)).To(Succeed())
+
+		cfg, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Hosts).To(Equal([]config.HostConfig{{
+			Host:     "fonts.example.com",
+			Username: "ci",
+			Password: "s3cret",
+			Headers:  map[string]string{"X-Api-Key": "key-s3cret"},
+		}}))
+	})
+
+	It("should reject hosts without a name", func() {
+		Expect(os.WriteFile(path, []byte("hosts:\n  - username: ci\n"), 0644)).To(Succeed())
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("host is required")))
+	})
+
+	It("should load the font cache settings", func() {
+		Expect(os.WriteFile(path, []byte(`
+cache_command: ["fc-cache", "-f", "-v"]
+reset_font_server: false
+`), 0644)).To(Succeed())
+
+		cfg, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.CacheCommand).To(Equal([]string{"fc-cache", "-f", "-v"}))
+		Expect(cfg.ResetFontServer).To(HaveValue(BeFalse()))
+	})
+
+	It("should reject an empty cache command name", func() {
+		Expect(os.WriteFile(path, []byte(`cache_command: ["", "-f"]`), 0644)).To(Succeed())
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("cache_command")))
+	})
+
+	It("should reject an empty instancer command name", func() {
+		Expect(os.WriteFile(path, []byte(`instancer_command: ["", "varLib.instancer"]`), 0644)).To(Succeed())
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("instancer_command")))
+	})
+
+	It("should load the allowed license list", func() {
+		Expect(os.WriteFile(path, []byte(`allowed_licenses: [OFL-1.1, Apache-2.0]`), 0644)).To(Succeed())
+
+		cfg, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.AllowedLicenses).To(Equal([]string{"OFL-1.1", "Apache-2.0"}))
+	})
+
+	It("should reject an empty allowed license", func() {
+		Expect(os.WriteFile(path, []byte(`allowed_licenses: [OFL-1.1, ""]`), 0644)).To(Succeed())
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("allowed_licenses")))
+	})
+
+	It("should load the background settings", func() {
+		Expect(os.WriteFile(path, []byte("background:\n  low_priority: true\n  parallelism: 2\n"), 0644)).To(Succeed())
+
+		cfg, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Background).To(Equal(config.BackgroundConfig{LowPriority: true, Parallelism: 2}))
+	})
+
+	It("should load the low-memory setting, leaving it unset by default", func() {
+		Expect(os.WriteFile(path, []byte("low_memory: true\n"), 0644)).To(Succeed())
+
+		cfg, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.LowMemory).To(HaveValue(BeTrue()))
+
+		Expect(os.WriteFile(path, []byte("scope: user\n"), 0644)).To(Succeed())
+		cfg, err = config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.LowMemory).To(BeNil())
+	})
+
+	It("should reject negative background parallelism", func() {
+		Expect(os.WriteFile(path, []byte("background:\n  parallelism: -1\n"), 0644)).To(Succeed())
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("background.parallelism")))
+	})
+
+	It("should load the directories, proxy and parallelism, expanding environment variables in paths", func() {
+		os.Setenv("FONT_DISK", "/mnt/fonts")
+		defer os.Unsetenv("FONT_DISK")
+
+		Expect(os.WriteFile(path, []byte(`
+font_dir: $FONT_DISK/user
+cache:
+  dir: ${FONT_DISK}/cache
+proxy: http://proxy.example.com:3128
+parallelism: 4
+`), 0644)).To(Succeed())
+
+		cfg, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.FontDir).To(Equal("/mnt/fonts/user"))
+		Expect(cfg.Cache.Dir).To(Equal("/mnt/fonts/cache"))
+		Expect(cfg.Proxy).To(Equal("http://proxy.example.com:3128"))
+		Expect(cfg.Parallelism).To(Equal(4))
+	})
+
+	It("should reject formats other than ttf and otf", func() {
+		Expect(os.WriteFile(path, []byte("format: otf\n"), 0644)).To(Succeed())
+		cfg, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Format).To(Equal("otf"))
+
+		Expect(os.WriteFile(path, []byte("format: woff2\n"), 0644)).To(Succeed())
+		_, err = config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring(`unknown format "woff2"`)))
+	})
+
+	It("should reject proxies that aren't URLs", func() {
+		Expect(os.WriteFile(path, []byte("proxy: proxy.example.com\n"), 0644)).To(Succeed())
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("proxy must be a URL")))
+	})
+
+	It("should save settings that load back unchanged", func() {
+		enabled := false
+		saved := &config.Config{
+			SourcePriority:  []string{"nerdfonts", "fontsource"},
+			DefaultVariants: map[string][]string{"nerdfonts": {"Mono"}},
+			Scope:           config.ScopeSystem,
+			Cache:           config.CacheConfig{Enabled: &enabled},
+		}
+		path = filepath.Join(tempDir, "fm", "config.yaml")
+		Expect(config.Exists(path)).To(BeFalse())
+		Expect(config.Save(path, saved)).To(Succeed())
+		Expect(config.Exists(path)).To(BeTrue())
+
+		cfg, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg).To(Equal(saved))
+	})
+
+	It("should load user aliases", func() {
+		Expect(os.WriteFile(path, []byte("aliases:\n  code-font: FiraCode@nerdfonts\n"), 0644)).To(Succeed())
+
+		cfg, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Aliases).To(Equal(map[string]string{"code-font": "FiraCode@nerdfonts"}))
+	})
+
+	It("should reject aliases without a font spec", func() {
+		Expect(os.WriteFile(path, []byte("aliases:\n  code-font: \"\"\n"), 0644)).To(Succeed())
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring(`aliases: "code-font"`)))
+	})
+
+	It("should update the config without expanding environment variables", func() {
+		os.Setenv("FM_TEST_TOKEN", "s3cret")
+		defer os.Unsetenv("FM_TEST_TOKEN")
+		Expect(os.WriteFile(path, []byte("hosts:\n  - host: fonts.example.com\n    password: ${FM_TEST_TOKEN}\n"), 0644)).To(Succeed())
+
+		Expect(config.Update(path, func(cfg *config.Config) error {
+			cfg.Aliases = map[string]string{"code-font": "FiraCode@nerdfonts"}
+			return nil
+		})).To(Succeed())
+
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("${FM_TEST_TOKEN}"))
+		Expect(string(data)).To(ContainSubstring("code-font: FiraCode@nerdfonts"))
+
+		Expect(config.Update(path, func(cfg *config.Config) error {
+			cfg.Aliases["ui-font"] = " "
+			return nil
+		})).To(MatchError(ContainSubstring("needs both a name and a font spec")))
+	})
+
+	It("should reject unknown install scopes", func() {
+		Expect(os.WriteFile(path, []byte("scope: global\n"), 0644)).To(Succeed())
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring(`unknown scope "global"`)))
+	})
+
+	It("should load the confirmation policy", func() {
+		Expect(os.WriteFile(path, []byte("confirm: never\n"), 0644)).To(Succeed())
+
+		cfg, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Confirm).To(Equal(config.ConfirmNever))
+	})
+
+	It("should reject unknown confirmation policies", func() {
+		Expect(os.WriteFile(path, []byte("confirm: sometimes\n"), 0644)).To(Succeed())
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring(`unknown confirm policy "sometimes"`)))
+	})
+
+	It("should load hook commands", func() {
+		Expect(os.WriteFile(path, []byte("hooks:\n  post_install: [kitty @ load-config]\n  pre_uninstall: ['echo $FM_FONT']\n"), 0644)).To(Succeed())
+
+		cfg, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Hooks.Commands("install", true)).To(Equal([]string{"kitty @ load-config"}))
+		Expect(cfg.Hooks.Commands("uninstall", false)).To(Equal([]string{"echo $FM_FONT"}))
+		Expect(cfg.Hooks.Commands("upgrade", true)).To(BeEmpty())
+	})
+
+	It("should reject empty hook commands", func() {
+		Expect(os.WriteFile(path, []byte("hooks:\n  post_upgrade: ['']\n"), 0644)).To(Succeed())
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("hooks.post_upgrade: entry 1 is empty")))
+	})
+
+	It("should load scan limits", func() {
+		Expect(os.WriteFile(path, []byte("scan:\n  max_depth: 2\n  exclude: [truetype/dejavu, noto*]\n  skip_large_dirs: false\n"), 0644)).To(Succeed())
+
+		cfg, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Scan.MaxDepth).To(Equal(2))
+		Expect(cfg.Scan.Exclude).To(Equal([]string{"truetype/dejavu", "noto*"}))
+		Expect(cfg.Scan.SkipLargeDirs).NotTo(BeNil())
+		Expect(*cfg.Scan.SkipLargeDirs).To(BeFalse())
+	})
+
+	It("should reject invalid scan exclude globs", func() {
+		Expect(os.WriteFile(path, []byte("scan:\n  exclude: [\"noto[\"]\n"), 0644)).To(Succeed())
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("scan.exclude: entry 1")))
+	})
+
+	It("should reject duplicate source names", func() {
+		Expect(os.WriteFile(path, []byte(`
+sources:
+  - name: internal
+    url: https://a.example.com/{name}.zip
+  - name: internal
+    url: https://b.example.com/{name}.zip
+`), 0644)).To(Succeed())
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("more than once")))
+	})
+})
@@ -1,32 +1,37 @@
+//go:build darwin
+
 package platform
 
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"time"
 )
 
-type darwinManager struct{}
+type darwinManager struct {
+	user  *targetUser // Set when installing for another user
+	cache CacheSettings
+}
 
-func newDarwinManager() Manager {
-	return &darwinManager{}
+func newManager(target *targetUser) Manager {
+	return &darwinManager{user: target}
 }
 
 func (m *darwinManager) GetFontPaths() (FontPaths, error) {
-	homeDir, err := os.UserHomeDir()
+	homeDir, err := resolveHomeDir(m.user)
 	if err != nil {
-		return FontPaths{}, fmt.Errorf("getting user home directory: %w", err)
+		return FontPaths{}, err
 	}
 
 	paths := FontPaths{
 		SystemDir: "/Library/Fonts",
-		UserDir:   filepath.Join(homeDir, "Library/Fonts"),
+		UserDir:   userFontDir(m.user, filepath.Join(homeDir, "Library/Fonts")),
+		HomeDir:   homeDir,
 	}
 
 	// Ensure user fonts directory exists
-	if err := os.MkdirAll(paths.UserDir, 0755); err != nil {
+	if err := ensureUserDir(m.user, paths.UserDir); err != nil {
 		return FontPaths{}, fmt.Errorf("creating user fonts directory: %w", err)
 	}
 
@@ -36,9 +41,9 @@ func (m *darwinManager) GetFontPaths() (FontPaths, error) {
 func (m *darwinManager) UpdateFontCache() error {
 	// macOS automatically detects new fonts, but we can force a refresh
 	// by touching the fonts directory
-	homeDir, err := os.UserHomeDir()
+	homeDir, err := resolveHomeDir(m.user)
 	if err != nil {
-		return fmt.Errorf("getting user home directory: %w", err)
+		return err
 	}
 
 	fontsDir := filepath.Join(homeDir, "Library/Fonts")
@@ -47,12 +52,29 @@ func (m *darwinManager) UpdateFontCache() error {
 		return fmt.Errorf("updating directory timestamp: %w", err)
 	}
 
+	if command := m.cache.Command; len(command) > 0 {
+		return runCommandAs(m.user, command[0], command[1:]...)
+	}
+	if m.cache.SkipFontServerReset {
+		return nil
+	}
+
 	// For older macOS versions, we might need to restart the font server
-	if err := exec.Command("atsutil", "databases", "-remove").Run(); err == nil {
-		if err := exec.Command("atsutil", "server", "-shutdown").Run(); err != nil {
+	if err := userCommand(m.user, "atsutil", "databases", "-remove").Run(); err == nil {
+		if err := userCommand(m.user, "atsutil", "server", "-shutdown").Run(); err != nil {
 			return fmt.Errorf("restarting font server: %w", err)
 		}
 	}
 
 	return nil
 }
+
+// SetCacheSettings customizes how UpdateFontCache refreshes the cache
+func (m *darwinManager) SetCacheSettings(settings CacheSettings) {
+	m.cache = settings
+}
+
+// ChownToUser hands an installed font over to the target user
+func (m *darwinManager) ChownToUser(path string) error {
+	return chownToUser(m.user, path)
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package platform
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// IsRoot always reports false; privileges aren't checked on this platform
+func IsRoot() bool {
+	return false
+}
+
+// RunElevated is not supported on this platform
+func RunElevated(args []string, nonInteractive bool) error {
+	return fmt.Errorf("running fm with elevated privileges on %s: %w", runtime.GOOS, ErrUnsupported)
+}
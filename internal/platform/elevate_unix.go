@@ -0,0 +1,37 @@
+//go:build unix
+
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// IsRoot reports whether fm runs with root privileges
+func IsRoot() bool {
+	return os.Geteuid() == 0
+}
+
+// RunElevated runs fm again as root through sudo with args, attached to the
+// terminal so sudo can ask for a password. With nonInteractive, sudo fails
+// instead of asking.
+func RunElevated(args []string, nonInteractive bool) error {
+	if _, err := exec.LookPath("sudo"); err != nil {
+		return fmt.Errorf("root privileges are required and sudo was not found; run fm as root")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating fm: %w", err)
+	}
+
+	var sudoArgs []string
+	if nonInteractive {
+		sudoArgs = append(sudoArgs, "-n")
+	}
+	sudoArgs = append(append(sudoArgs, "--", exe), args...)
+
+	cmd := exec.Command("sudo", sudoArgs...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
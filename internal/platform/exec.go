@@ -0,0 +1,34 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func runCommand(name string, args ...string) error {
+	return runCommandAs(nil, name, args...)
+}
+
+// startCommand starts a command without waiting for it, e.g. to hand a file
+// to a desktop application
+func startCommand(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("%s not found; it comes with the desktop environment", name)
+		}
+		return fmt.Errorf("starting %s: %w", name, err)
+	}
+	return cmd.Process.Release()
+}
+
+func runCommandAs(target *targetUser, name string, args ...string) error {
+	cmd := userCommand(target, name, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed:\nCommand: %s %s\nOutput: %s\nError: %w",
+			name, name, strings.Join(args, " "), output, err)
+	}
+	return nil
+}
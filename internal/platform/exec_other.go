@@ -0,0 +1,18 @@
+//go:build !unix
+
+package platform
+
+import (
+	"os"
+	"os/exec"
+)
+
+// userCommand builds a command with the target user's home directory in the
+// environment; switching credentials is only supported on unix systems
+func userCommand(target *targetUser, name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(name, args...)
+	if target != nil {
+		cmd.Env = append(os.Environ(), "HOME="+target.homeDir, "USER="+target.name)
+	}
+	return cmd
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package platform
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// userCommand builds a command that runs as the target user, with their home
+// directory in the environment so per-user caches land in the right place
+func userCommand(target *targetUser, name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(name, args...)
+	if target != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			Credential: &syscall.Credential{Uid: uint32(target.uid), Gid: uint32(target.gid)},
+		}
+		cmd.Env = append(os.Environ(), "HOME="+target.homeDir, "USER="+target.name, "LOGNAME="+target.name)
+	}
+	return cmd
+}
@@ -0,0 +1,4 @@
+package platform
+
+// NewUnsupported exposes the stub manager to tests on every platform
+var NewUnsupported = newUnsupportedManager
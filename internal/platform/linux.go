@@ -1,32 +1,44 @@
+//go:build linux || freebsd || netbsd || openbsd || dragonfly
+
 package platform
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
 )
 
-type linuxManager struct{}
+// linuxManager handles fontconfig-based systems: Linux and the BSDs share the
+// freedesktop font directories and fc-cache
+type linuxManager struct {
+	user   *targetUser // Set when installing for another user
+	cache  CacheSettings
+	logger *slog.Logger
+}
 
-func newLinuxManager() Manager {
-	return &linuxManager{}
+func newManager(target *targetUser) Manager {
+	return &linuxManager{user: target}
 }
 
 func (m *linuxManager) GetFontPaths() (FontPaths, error) {
-	homeDir, err := os.UserHomeDir()
+	homeDir, err := resolveHomeDir(m.user)
 	if err != nil {
-		return FontPaths{}, fmt.Errorf("getting user home directory: %w", err)
+		return FontPaths{}, err
 	}
 
 	paths := FontPaths{
 		SystemDir: "/usr/local/share/fonts",
-		UserDir:   filepath.Join(homeDir, ".local/share/fonts"),
+		UserDir:   userFontDir(m.user, filepath.Join(homeDir, ".local/share/fonts")),
+		HomeDir:   homeDir,
+		LegacyDirs: []string{
+			filepath.Join(homeDir, ".fonts"),
+		},
 	}
 
 	// Ensure user fonts directory exists
-	if err := os.MkdirAll(paths.UserDir, 0755); err != nil {
+	if err := ensureUserDir(m.user, paths.UserDir); err != nil {
 		return FontPaths{}, fmt.Errorf("creating user fonts directory: %w", err)
 	}
 
@@ -39,6 +51,16 @@ func hasSudo() bool {
 }
 
 func (m *linuxManager) UpdateFontCache() error {
+	// A configured command replaces the defaults entirely
+	if command := m.cache.Command; len(command) > 0 {
+		return runCommandAs(m.user, command[0], command[1:]...)
+	}
+
+	// Rebuild the cache as the target user so it lands in their home
+	if m.user != nil {
+		return runCommandAs(m.user, "fc-cache", "-f")
+	}
+
 	// First try fc-cache
 	if err := runCommand("fc-cache", "-f"); err == nil {
 		return nil
@@ -50,9 +72,9 @@ func (m *linuxManager) UpdateFontCache() error {
 			return fmt.Errorf("font cache update failed. Please run 'fc-cache -f' manually with root privileges")
 		}
 
-		fmt.Printf("Unable to update font cache with current permissions.\n")
-		fmt.Printf("This can happen if system-wide fonts were installed or if the cache is locked.\n")
-		fmt.Printf("Attempting to update with elevated privileges. You may be prompted for your password.\n\n")
+		// This can happen if system-wide fonts were installed or if the cache
+		// is locked; say why sudo may prompt for a password
+		m.log().Warn("unable to update font cache with current permissions, retrying with elevated privileges")
 
 		if err := runCommand("sudo", "fc-cache", "-f"); err != nil {
 			return fmt.Errorf("updating font cache with elevated privileges: %w", err)
@@ -62,11 +84,24 @@ func (m *linuxManager) UpdateFontCache() error {
 	return nil
 }
 
-func runCommand(name string, args ...string) error {
-	cmd := exec.Command(name, args...)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("%s failed:\nCommand: %s %s\nOutput: %s\nError: %w",
-			name, name, strings.Join(args, " "), output, err)
+// SetCacheSettings customizes how UpdateFontCache refreshes the cache
+func (m *linuxManager) SetCacheSettings(settings CacheSettings) {
+	m.cache = settings
+}
+
+// SetLogger sets where the manager logs; nil logs to slog.Default
+func (m *linuxManager) SetLogger(logger *slog.Logger) {
+	m.logger = logger
+}
+
+func (m *linuxManager) log() *slog.Logger {
+	if m.logger == nil {
+		return slog.Default()
 	}
-	return nil
+	return m.logger
+}
+
+// ChownToUser hands an installed font over to the target user
+func (m *linuxManager) ChownToUser(path string) error {
+	return chownToUser(m.user, path)
 }
@@ -0,0 +1,39 @@
+//go:build linux
+
+package platform
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// AvailableMemory returns how many bytes of memory can be allocated without
+// swapping, the MemAvailable estimate from /proc/meminfo
+func AvailableMemory() (int64, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, fmt.Errorf("reading memory information: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "MemAvailable:")
+		if !ok {
+			continue
+		}
+		kb, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "kB")), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parsing MemAvailable: %w", err)
+		}
+		return kb << 10, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("reading memory information: %w", err)
+	}
+	// Kernels before 3.14 don't estimate it
+	return 0, fmt.Errorf("MemAvailable missing from /proc/meminfo: %w", ErrUnsupported)
+}
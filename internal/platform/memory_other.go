@@ -0,0 +1,13 @@
+//go:build !linux
+
+package platform
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// AvailableMemory is not supported on this platform
+func AvailableMemory() (int64, error) {
+	return 0, fmt.Errorf("reading available memory on %s: %w", runtime.GOOS, ErrUnsupported)
+}
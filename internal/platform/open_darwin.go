@@ -0,0 +1,9 @@
+//go:build darwin
+
+package platform
+
+// Open opens path with its default application: Finder for directories and
+// Font Book for font files
+func Open(path string) error {
+	return startCommand("open", path)
+}
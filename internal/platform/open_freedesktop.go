@@ -0,0 +1,9 @@
+//go:build linux || freebsd || netbsd || openbsd || dragonfly
+
+package platform
+
+// Open opens path with the desktop's default application: the file manager
+// for directories and the font viewer for font files
+func Open(path string) error {
+	return startCommand("xdg-open", path)
+}
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package platform
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Open is not supported on this platform
+func Open(path string) error {
+	return fmt.Errorf("opening %s on %s: %w", path, runtime.GOOS, ErrUnsupported)
+}
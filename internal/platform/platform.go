@@ -1,13 +1,30 @@
 package platform
 
 import (
-	"runtime"
+	"errors"
+	"log/slog"
 )
 
+// ErrUnsupported is returned by the managers of platforms fm has no font
+// handling for; embedders can supply their own Manager there instead
+var ErrUnsupported = errors.New("unsupported platform")
+
+// lowestNice is the nice value LowerPriority runs fm at
+const lowestNice = 19
+
+// FontDirEnv names the environment variable that replaces the user font
+// directory of the user running fm, e.g. in containers and CI
+const FontDirEnv = "FM_FONT_DIR"
+
 // FontPaths represents system and user font directories
 type FontPaths struct {
 	SystemDir string // System-wide font directory
 	UserDir   string // User-specific font directory
+	HomeDir   string // Home directory of the user UserDir belongs to
+
+	// LegacyDirs are older per-user font directories that are still scanned
+	// for fonts, e.g. ~/.fonts on Linux. fm never installs into them.
+	LegacyDirs []string
 }
 
 // Manager handles platform-specific operations
@@ -19,10 +36,37 @@ type Manager interface {
 	UpdateFontCache() error
 }
 
-// New returns a platform-specific manager
+// CacheSettings customizes how the font cache is refreshed
+type CacheSettings struct {
+	// Command replaces the platform's cache refresh, e.g.
+	// ["fc-cache", "-f", "-v"]
+	Command []string
+
+	// SkipFontServerReset stops macOS managers from resetting the font server
+	// with atsutil, leaving only the fonts directory timestamp update
+	SkipFontServerReset bool
+}
+
+// CacheConfigurer is implemented by managers whose font cache refresh can be
+// customized
+type CacheConfigurer interface {
+	Manager
+
+	// SetCacheSettings changes how UpdateFontCache refreshes the cache
+	SetCacheSettings(settings CacheSettings)
+}
+
+// LogConfigurer is implemented by managers that log, e.g. before asking for
+// elevated privileges to refresh the font cache
+type LogConfigurer interface {
+	Manager
+
+	// SetLogger sets where the manager logs
+	SetLogger(logger *slog.Logger)
+}
+
+// New returns the manager for the platform fm was built for. On platforms
+// without font handling every operation fails with ErrUnsupported.
 func New() Manager {
-	if runtime.GOOS == "darwin" {
-		return newDarwinManager()
-	}
-	return newLinuxManager()
+	return newManager(nil)
 }
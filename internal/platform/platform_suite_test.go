@@ -1,13 +1,38 @@
 package platform_test
 
 import (
+	"fmt"
+	"os"
 	"testing"
 
+	"github.com/logandonley/font-manager/internal/platform"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
+// lowerPriorityEnv makes the test binary lower its own priority and print
+// /proc/self/stat instead of running the specs, so the priority spec checks
+// a child process rather than slowing down the rest of the suite
+const lowerPriorityEnv = "FM_TEST_LOWER_PRIORITY"
+
 func TestPlatform(t *testing.T) {
+	if os.Getenv(lowerPriorityEnv) != "" {
+		lowerPriorityChild()
+	}
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Platform Suite")
 }
+
+func lowerPriorityChild() {
+	if err := platform.LowerPriority(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	stat, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(stat)
+	os.Exit(0)
+}
@@ -1,7 +1,12 @@
 package platform_test
 
 import (
+	"bytes"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 
 	"github.com/logandonley/font-manager/internal/platform"
 	. "github.com/onsi/ginkgo/v2"
@@ -29,7 +34,9 @@ var _ = Describe("Platform", func() {
 
 	Context("Linux Manager", func() {
 		BeforeEach(func() {
-			os.Setenv("GOOS", "linux")
+			if runtime.GOOS != "linux" {
+				Skip("Linux manager is only built on Linux")
+			}
 			manager = platform.New()
 		})
 
@@ -39,12 +46,61 @@ var _ = Describe("Platform", func() {
 
 			Expect(paths.SystemDir).To(Equal("/usr/local/share/fonts"))
 			Expect(paths.UserDir).To(ContainSubstring(".local/share/fonts"))
+			Expect(paths.LegacyDirs).To(ConsistOf(HaveSuffix("/.fonts")))
+		})
+
+		It("should use the font directory from the environment", func() {
+			dir := filepath.Join(tempDir, "fonts")
+			os.Setenv(platform.FontDirEnv, dir)
+			defer os.Unsetenv(platform.FontDirEnv)
+
+			paths, err := manager.GetFontPaths()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(paths.UserDir).To(Equal(dir))
+			Expect(dir).To(BeADirectory())
+		})
+
+		It("should lower the process priority", func() {
+			// Priority can't be raised again, so it's lowered in a child
+			cmd := exec.Command(os.Args[0], "-test.run=^TestPlatform$")
+			cmd.Env = append(os.Environ(), lowerPriorityEnv+"=1")
+			cmd.Stderr = GinkgoWriter
+			stat, err := cmd.Output()
+			Expect(err).NotTo(HaveOccurred())
+
+			// The nice value is the 19th field of /proc/self/stat, counting
+			// from the state after the parenthesized command name
+			fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+			Expect(fields[16]).To(Equal("19"))
+		})
+
+		It("should report the available memory", func() {
+			available, err := platform.AvailableMemory()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(available).To(BeNumerically(">", 0))
+		})
+
+		It("should run a configured cache command", func() {
+			marker := filepath.Join(tempDir, "refreshed")
+			configurer, ok := manager.(platform.CacheConfigurer)
+			Expect(ok).To(BeTrue())
+			configurer.SetCacheSettings(platform.CacheSettings{Command: []string{"touch", marker}})
+
+			Expect(manager.UpdateFontCache()).To(Succeed())
+			Expect(marker).To(BeAnExistingFile())
+		})
+
+		It("should report a failing cache command", func() {
+			manager.(platform.CacheConfigurer).SetCacheSettings(platform.CacheSettings{Command: []string{"false"}})
+			Expect(manager.UpdateFontCache()).To(MatchError(ContainSubstring("false failed")))
 		})
 	})
 
 	Context("Darwin Manager", func() {
 		BeforeEach(func() {
-			os.Setenv("GOOS", "darwin")
+			if runtime.GOOS != "darwin" {
+				Skip("Darwin manager is only built on macOS")
+			}
 			manager = platform.New()
 		})
 
@@ -56,4 +112,17 @@ var _ = Describe("Platform", func() {
 			Expect(paths.UserDir).To(ContainSubstring("Library/Fonts"))
 		})
 	})
+
+	Context("Unsupported platforms", func() {
+		BeforeEach(func() {
+			manager = platform.NewUnsupported("plan9")
+		})
+
+		It("should fail every operation explicitly", func() {
+			_, err := manager.GetFontPaths()
+			Expect(err).To(MatchError(platform.ErrUnsupported))
+			Expect(err).To(MatchError(ContainSubstring("plan9")))
+			Expect(manager.UpdateFontCache()).To(MatchError(platform.ErrUnsupported))
+		})
+	})
 })
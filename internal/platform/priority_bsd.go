@@ -0,0 +1,18 @@
+//go:build darwin || freebsd || netbsd || openbsd || dragonfly
+
+package platform
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// LowerPriority moves fm to the lowest CPU priority, like running it under
+// `nice -n 19`. Commands started afterwards, such as fc-cache, inherit it.
+// I/O priority can't be changed on these systems.
+func LowerPriority() error {
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, lowestNice); err != nil {
+		return fmt.Errorf("lowering CPU priority: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+//go:build linux
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// I/O priority constants from linux/ioprio.h
+const (
+	ioprioWhoProcess = 1
+	ioprioClassBE    = 2
+	ioprioClassShift = 13
+	ioprioLowest     = 7
+)
+
+// LowerPriority moves fm to the lowest CPU priority and the lowest
+// best-effort I/O priority, like running it under `nice -n 19 ionice -c2 -n7`.
+// Commands started afterwards, such as fc-cache, inherit it.
+func LowerPriority() error {
+	// Linux keeps priorities per thread and new threads inherit them from
+	// the thread that creates them, so lowering every existing thread covers
+	// the whole process
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return fmt.Errorf("listing threads: %w", err)
+	}
+
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		// Threads may exit while we go through the list
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, lowestNice); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return fmt.Errorf("lowering CPU priority: %w", err)
+		}
+		ioprio := uintptr(ioprioClassBE<<ioprioClassShift | ioprioLowest)
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprio); errno != 0 && errno != syscall.ESRCH {
+			return fmt.Errorf("lowering I/O priority: %w", errno)
+		}
+	}
+	return nil
+}
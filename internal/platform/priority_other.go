@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package platform
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// LowerPriority is not supported on this platform
+func LowerPriority() error {
+	return fmt.Errorf("lowering process priority on %s: %w", runtime.GOOS, ErrUnsupported)
+}
@@ -0,0 +1,11 @@
+//go:build !(linux || freebsd || netbsd || openbsd || dragonfly || darwin)
+
+package platform
+
+import (
+	"runtime"
+)
+
+func newManager(_ *targetUser) Manager {
+	return newUnsupportedManager(runtime.GOOS)
+}
@@ -0,0 +1,23 @@
+package platform
+
+import (
+	"fmt"
+)
+
+// unsupportedManager stands in on platforms fm has no font handling for, so
+// the library still builds there
+type unsupportedManager struct {
+	goos string
+}
+
+func newUnsupportedManager(goos string) Manager {
+	return &unsupportedManager{goos: goos}
+}
+
+func (m *unsupportedManager) GetFontPaths() (FontPaths, error) {
+	return FontPaths{}, fmt.Errorf("%w: %s has no known font directories", ErrUnsupported, m.goos)
+}
+
+func (m *unsupportedManager) UpdateFontCache() error {
+	return fmt.Errorf("%w: %s has no known font cache", ErrUnsupported, m.goos)
+}
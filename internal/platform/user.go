@@ -0,0 +1,120 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// UserManager is implemented by managers acting on behalf of a user other
+// than the one running fm
+type UserManager interface {
+	Manager
+
+	// ChownToUser recursively hands ownership of path to the target user
+	ChownToUser(path string) error
+}
+
+// targetUser identifies the account fonts are installed for
+type targetUser struct {
+	name    string
+	homeDir string
+	uid     int
+	gid     int
+}
+
+// NewForUser returns a platform-specific manager that installs into the font
+// directory of the named user. Acting for someone else requires root.
+func NewForUser(username string) (Manager, error) {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return nil, fmt.Errorf("looking up user %q: %w", username, err)
+	}
+
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return nil, fmt.Errorf("parsing uid of %q: %w", username, err)
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return nil, fmt.Errorf("parsing gid of %q: %w", username, err)
+	}
+
+	if uid != os.Geteuid() && os.Geteuid() != 0 {
+		return nil, fmt.Errorf("installing fonts for %q requires root privileges (try sudo)", username)
+	}
+
+	target := &targetUser{
+		name:    u.Username,
+		homeDir: u.HomeDir,
+		uid:     uid,
+		gid:     gid,
+	}
+
+	return newManager(target), nil
+}
+
+// resolveHomeDir returns the target user's home, or the current user's when the
+// manager acts for the current user
+func resolveHomeDir(target *targetUser) (string, error) {
+	if target != nil {
+		return target.homeDir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting user home directory: %w", err)
+	}
+	return homeDir, nil
+}
+
+// userFontDir returns the directory FontDirEnv names, or dir when it isn't
+// set. Installs for another user always use their own directory.
+func userFontDir(target *targetUser, dir string) string {
+	if env := os.Getenv(FontDirEnv); env != "" && target == nil {
+		return env
+	}
+	return dir
+}
+
+// ensureUserDir creates dir and any missing parents below the home
+// directory, owned by the target user when there is one
+func ensureUserDir(target *targetUser, dir string) error {
+	if target == nil {
+		return os.MkdirAll(dir, 0755)
+	}
+
+	rel, err := filepath.Rel(target.homeDir, dir)
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", dir, err)
+	}
+
+	path := target.homeDir
+	for _, component := range strings.Split(rel, string(filepath.Separator)) {
+		path = filepath.Join(path, component)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		if err := os.Mkdir(path, 0755); err != nil && !os.IsExist(err) {
+			return err
+		}
+		if err := os.Lchown(path, target.uid, target.gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func chownToUser(target *targetUser, path string) error {
+	if target == nil {
+		return nil
+	}
+	return filepath.Walk(path, func(p string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(p, target.uid, target.gid)
+	})
+}
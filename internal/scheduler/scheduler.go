@@ -0,0 +1,146 @@
+// Package scheduler orders the work fm does for the user ahead of the work
+// it does unattended, such as watching the font directories or refreshing
+// indexes, so background jobs never slow down an install someone is waiting
+// for
+package scheduler
+
+import (
+	"context"
+	"sync"
+)
+
+// Class is the priority class of a job
+type Class int
+
+const (
+	// Interactive jobs were started by someone waiting for them, e.g. an
+	// install from the command line
+	Interactive Class = iota
+
+	// Background jobs run unattended and wait while interactive jobs run or
+	// are waiting to
+	Background
+
+	classCount
+)
+
+// Limits caps how many jobs of each class run at once. Zero means no limit.
+type Limits struct {
+	Interactive int
+	Background  int
+}
+
+// DefaultLimits leaves interactive jobs to their own parallelism settings
+// and runs one background job at a time
+var DefaultLimits = Limits{Background: 1}
+
+// Scheduler hands out slots to run jobs in. Interactive jobs take precedence:
+// a background job only starts while no interactive job is running or
+// waiting. Jobs already running are not interrupted. The zero value has no
+// limits; a nil Scheduler runs every job right away.
+type Scheduler struct {
+	mu      sync.Mutex
+	limits  [classCount]int
+	running [classCount]int
+	waiting [classCount]int
+
+	// changed is closed and replaced whenever a job finishes or gives up
+	// waiting, waking the jobs waiting for a slot
+	changed chan struct{}
+}
+
+// New returns a scheduler with the given limits
+func New(limits Limits) *Scheduler {
+	s := &Scheduler{}
+	s.limits[Interactive] = limits.Interactive
+	s.limits[Background] = limits.Background
+	return s
+}
+
+// Acquire waits for a slot to run a job of class in and returns the function
+// that frees it again, which is safe to call more than once. It returns the
+// context's error if ctx is done first.
+func (s *Scheduler) Acquire(ctx context.Context, class Class) (release func(), err error) {
+	if s == nil {
+		return func() {}, nil
+	}
+
+	s.mu.Lock()
+	s.waiting[class]++
+	for !s.canStart(class) {
+		changed := s.wakeup()
+		s.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.waiting[class]--
+			s.notify()
+			s.mu.Unlock()
+			return nil, ctx.Err()
+		case <-changed:
+		}
+		s.mu.Lock()
+	}
+	s.waiting[class]--
+	s.running[class]++
+	s.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.running[class]--
+			s.notify()
+			s.mu.Unlock()
+		})
+	}, nil
+}
+
+// Run runs job in a slot of class once one is free
+func (s *Scheduler) Run(ctx context.Context, class Class, job func(context.Context) error) error {
+	release, err := s.Acquire(ctx, class)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return job(ctx)
+}
+
+// Busy reports whether interactive jobs are running or waiting to, which
+// long background jobs can check between steps to get out of the way
+func (s *Scheduler) Busy() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.busy()
+}
+
+func (s *Scheduler) busy() bool {
+	return s.running[Interactive] > 0 || s.waiting[Interactive] > 0
+}
+
+// canStart reports whether a job of class may start now
+func (s *Scheduler) canStart(class Class) bool {
+	if limit := s.limits[class]; limit > 0 && s.running[class] >= limit {
+		return false
+	}
+	return class != Background || !s.busy()
+}
+
+// wakeup returns the channel closed on the next change
+func (s *Scheduler) wakeup() chan struct{} {
+	if s.changed == nil {
+		s.changed = make(chan struct{})
+	}
+	return s.changed
+}
+
+// notify wakes every waiting job to check whether it may start
+func (s *Scheduler) notify() {
+	if s.changed != nil {
+		close(s.changed)
+		s.changed = nil
+	}
+}
@@ -0,0 +1,127 @@
+package scheduler_test
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/logandonley/font-manager/internal/scheduler"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scheduler", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	// started acquires a slot in the background and reports when it got one
+	started := func(s *scheduler.Scheduler, class scheduler.Class) (<-chan func(), context.CancelFunc) {
+		ctx, cancel := context.WithCancel(ctx)
+		acquired := make(chan func(), 1)
+		go func() {
+			if release, err := s.Acquire(ctx, class); err == nil {
+				acquired <- release
+			}
+		}()
+		return acquired, cancel
+	}
+
+	It("should hold background jobs back while interactive jobs run", func() {
+		s := scheduler.New(scheduler.DefaultLimits)
+		release, err := s.Acquire(ctx, scheduler.Interactive)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Busy()).To(BeTrue())
+
+		background, cancel := started(s, scheduler.Background)
+		defer cancel()
+		Consistently(background, 50*time.Millisecond).ShouldNot(Receive())
+
+		release()
+		release() // Releasing twice frees the slot once
+		Eventually(background).Should(Receive())
+		Expect(s.Busy()).To(BeFalse())
+	})
+
+	It("should let interactive jobs start while background jobs run", func() {
+		s := scheduler.New(scheduler.DefaultLimits)
+		release, err := s.Acquire(ctx, scheduler.Background)
+		Expect(err).NotTo(HaveOccurred())
+		defer release()
+
+		interactive, cancel := started(s, scheduler.Interactive)
+		defer cancel()
+		Eventually(interactive).Should(Receive())
+	})
+
+	It("should start waiting interactive jobs before waiting background jobs", func() {
+		s := scheduler.New(scheduler.Limits{Interactive: 1, Background: 1})
+		release, err := s.Acquire(ctx, scheduler.Interactive)
+		Expect(err).NotTo(HaveOccurred())
+
+		interactive, cancelInteractive := started(s, scheduler.Interactive)
+		defer cancelInteractive()
+		Consistently(interactive, 50*time.Millisecond).ShouldNot(Receive())
+		background, cancelBackground := started(s, scheduler.Background)
+		defer cancelBackground()
+		Consistently(background, 50*time.Millisecond).ShouldNot(Receive())
+
+		release()
+		var next func()
+		Eventually(interactive).Should(Receive(&next))
+		Consistently(background, 50*time.Millisecond).ShouldNot(Receive())
+		next()
+		Eventually(background).Should(Receive())
+	})
+
+	It("should cap the jobs of a class running at once", func() {
+		s := scheduler.New(scheduler.Limits{Background: 2})
+		var running, most atomic.Int32
+		done := make(chan error, 6)
+		for range 6 {
+			go func() {
+				done <- s.Run(ctx, scheduler.Background, func(context.Context) error {
+					n := running.Add(1)
+					for {
+						m := most.Load()
+						if n <= m || most.CompareAndSwap(m, n) {
+							break
+						}
+					}
+					time.Sleep(10 * time.Millisecond)
+					running.Add(-1)
+					return nil
+				})
+			}()
+		}
+		for range 6 {
+			Eventually(done).Should(Receive(BeNil()))
+		}
+		Expect(most.Load()).To(BeNumerically("==", 2))
+	})
+
+	It("should stop waiting when the context is done", func() {
+		s := scheduler.New(scheduler.DefaultLimits)
+		release, err := s.Acquire(ctx, scheduler.Interactive)
+		Expect(err).NotTo(HaveOccurred())
+		defer release()
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = s.Acquire(cancelled, scheduler.Background)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("should run every job right away without a scheduler", func() {
+		var s *scheduler.Scheduler
+		ran := false
+		Expect(s.Run(ctx, scheduler.Background, func(context.Context) error {
+			ran = true
+			return nil
+		})).To(Succeed())
+		Expect(ran).To(BeTrue())
+		Expect(s.Busy()).To(BeFalse())
+	})
+})
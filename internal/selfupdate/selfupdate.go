@@ -0,0 +1,196 @@
+// Package selfupdate replaces the running fm binary with the latest one
+// published on the project's GitHub releases
+package selfupdate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// DefaultAPIURL is the GitHub API endpoint of the latest fm release
+const DefaultAPIURL = "https://api.github.com/repos/logandonley/font-manager/releases/latest"
+
+// maxBinarySize bounds the download of a release binary
+const maxBinarySize = 200 << 20
+
+// Release is a published fm release
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	URL    string  `json:"html_url"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Latest fetches the latest release from the GitHub releases API at apiURL
+func Latest(ctx context.Context, client *http.Client, apiURL string) (*Release, error) {
+	body, err := get(ctx, client, apiURL, 1<<20)
+	if err != nil {
+		return nil, fmt.Errorf("checking for releases: %w", err)
+	}
+	var release Release
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, fmt.Errorf("decoding release: %w", err)
+	}
+	if release.Tag == "" {
+		return nil, fmt.Errorf("release has no tag")
+	}
+	return &release, nil
+}
+
+// AssetName returns the name of the release binary for a platform, as
+// published by goreleaser, e.g. font-manager_Linux_x86_64
+func AssetName(goos, goarch string) string {
+	if goarch == "amd64" {
+		goarch = "x86_64"
+	}
+	if goos != "" {
+		goos = strings.ToUpper(goos[:1]) + goos[1:]
+	}
+	return fmt.Sprintf("font-manager_%s_%s", goos, goarch)
+}
+
+// Binary returns the release binary for the platform fm runs on
+func (r *Release) Binary() (Asset, error) {
+	return r.asset(AssetName(runtime.GOOS, runtime.GOARCH))
+}
+
+func (r *Release) asset(name string) (Asset, error) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, nil
+		}
+	}
+	return Asset{}, fmt.Errorf("release %s has no binary for %s/%s", r.Tag, runtime.GOOS, runtime.GOARCH)
+}
+
+// checksums returns the checksum file of the release
+func (r *Release) checksums() (Asset, error) {
+	for _, asset := range r.Assets {
+		if strings.HasSuffix(asset.Name, "checksums.txt") {
+			return asset, nil
+		}
+	}
+	return Asset{}, fmt.Errorf("release %s has no checksums", r.Tag)
+}
+
+// Install downloads the release binary for this platform, checks it against
+// the release checksums and replaces the executable at exe with it. The
+// executable is only replaced once the new binary is complete and verified.
+func (r *Release) Install(ctx context.Context, client *http.Client, exe string) error {
+	binary, err := r.Binary()
+	if err != nil {
+		return err
+	}
+	checksums, err := r.checksums()
+	if err != nil {
+		return err
+	}
+
+	sums, err := get(ctx, client, checksums.URL, 1<<20)
+	if err != nil {
+		return fmt.Errorf("downloading checksums: %w", err)
+	}
+	want, err := findChecksum(sums, binary.Name)
+	if err != nil {
+		return err
+	}
+
+	data, err := get(ctx, client, binary.URL, maxBinarySize)
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", binary.Name, err)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", binary.Name, want, got)
+	}
+
+	return replace(exe, data)
+}
+
+// findChecksum looks up the SHA-256 of name in a sha256sum style file
+func findChecksum(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s", name)
+}
+
+// replace writes data next to exe and renames it over exe, keeping its
+// permissions, so the running executable is swapped in one step
+func replace(exe string, data []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".fm-update-*")
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return fmt.Errorf("%s isn't writable; rerun with sudo: %w", filepath.Dir(exe), err)
+		}
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing new binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing new binary: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0111); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		return fmt.Errorf("replacing %s: %w", exe, err)
+	}
+	return nil
+}
+
+// get fetches url, failing on unsuccessful responses and bodies over limit
+func get(ctx context.Context, client *http.Client, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "FontManager/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("response larger than %d bytes", limit)
+	}
+	return data, nil
+}
@@ -0,0 +1,90 @@
+package selfupdate_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/logandonley/font-manager/internal/selfupdate"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Self-update", func() {
+	var (
+		server   *httptest.Server
+		binary   []byte
+		checksum string
+		exe      string
+		ctx      context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		binary = []byte("#!/bin/sh\necho new fm\n")
+		sum := sha256.Sum256(binary)
+		checksum = hex.EncodeToString(sum[:])
+
+		name := selfupdate.AssetName(runtime.GOOS, runtime.GOARCH)
+		mux := http.NewServeMux()
+		server = httptest.NewServer(mux)
+		mux.HandleFunc("/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"tag_name": "v1.2.0", "html_url": "%[1]s/v1.2.0", "assets": [
+				{"name": "%[2]s", "browser_download_url": "%[1]s/download/%[2]s"},
+				{"name": "font-manager_1.2.0_checksums.txt", "browser_download_url": "%[1]s/download/checksums.txt"}
+			]}`, server.URL, name)
+		})
+		mux.HandleFunc("/download/"+name, func(w http.ResponseWriter, r *http.Request) {
+			w.Write(binary)
+		})
+		mux.HandleFunc("/download/checksums.txt", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s  font-manager_Other_arm64\n%s  %s\n", checksum, checksum, name)
+		})
+
+		exe = filepath.Join(GinkgoT().TempDir(), "fm")
+		Expect(os.WriteFile(exe, []byte("old fm"), 0755)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should name release binaries like goreleaser", func() {
+		Expect(selfupdate.AssetName("linux", "amd64")).To(Equal("font-manager_Linux_x86_64"))
+		Expect(selfupdate.AssetName("darwin", "arm64")).To(Equal("font-manager_Darwin_arm64"))
+	})
+
+	It("should replace the executable with the verified release binary", func() {
+		release, err := selfupdate.Latest(ctx, server.Client(), server.URL+"/releases/latest")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(release.Tag).To(Equal("v1.2.0"))
+
+		Expect(release.Install(ctx, server.Client(), exe)).To(Succeed())
+		Expect(os.ReadFile(exe)).To(Equal(binary))
+		info, err := os.Stat(exe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+		Expect(filepath.Glob(filepath.Join(filepath.Dir(exe), ".fm-update-*"))).To(BeEmpty())
+	})
+
+	It("should keep the executable when the checksum doesn't match", func() {
+		checksum = "0000"
+		release, err := selfupdate.Latest(ctx, server.Client(), server.URL+"/releases/latest")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(release.Install(ctx, server.Client(), exe)).To(MatchError(ContainSubstring("checksum mismatch")))
+		Expect(os.ReadFile(exe)).To(BeEquivalentTo("old fm"))
+	})
+
+	It("should fail for platforms without a release binary", func() {
+		release := &selfupdate.Release{Tag: "v1.2.0"}
+		_, err := release.Binary()
+		Expect(err).To(MatchError(ContainSubstring("no binary")))
+	})
+})
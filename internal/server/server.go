@@ -0,0 +1,334 @@
+// Package server exposes a font manager over HTTP with JSON bodies, so GUI
+// front ends and fleet management tools can drive the same engine as fm
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+)
+
+// streamType is the media type of streamed progress: one JSON event per
+// line, sent as each font is acted on
+const streamType = "application/x-ndjson"
+
+// maxBodySize bounds the JSON bodies of requests
+const maxBodySize = 1 << 20
+
+// Server serves the API of a font manager. Installs and uninstalls run one
+// request at a time.
+type Server struct {
+	manager   fm.Manager
+	version   string
+	token     string
+	localOnly bool
+	mux       *http.ServeMux
+
+	// mu serializes requests that change the installed fonts
+	mu sync.Mutex
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithVersion reports version as the fm release in status responses
+func WithVersion(version string) Option {
+	return func(s *Server) {
+		s.version = version
+	}
+}
+
+// WithToken requires every request to carry token as a bearer token
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithLocalOnly refuses requests naming a host other than this machine, so
+// a server listening on a loopback address can't be reached through DNS
+// rebinding
+func WithLocalOnly() Option {
+	return func(s *Server) {
+		s.localOnly = true
+	}
+}
+
+// New returns a server for manager
+func New(manager fm.Manager, opts ...Option) *Server {
+	s := &Server{manager: manager}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("GET /v1/status", s.status)
+	s.mux.HandleFunc("GET /v1/fonts", s.list)
+	s.mux.HandleFunc("GET /v1/search", s.search)
+	s.mux.HandleFunc("POST /v1/install", s.install)
+	s.mux.HandleFunc("POST /v1/uninstall", s.uninstall)
+	return s
+}
+
+// Font is an installed font in responses
+type Font struct {
+	Name        string `json:"name"`
+	Source      string `json:"source,omitempty"`
+	Version     string `json:"version,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Scope       string `json:"scope,omitempty"`
+	InstalledAt string `json:"installed_at,omitempty"`
+	Pinned      bool   `json:"pinned,omitempty"`
+	Note        string `json:"note,omitempty"`
+}
+
+func newFont(font fm.Font) Font {
+	return Font{
+		Name:        font.Name,
+		Source:      font.Source,
+		Version:     font.Meta["version"],
+		URL:         font.Meta["url"],
+		Path:        font.Meta["directory"],
+		Scope:       font.Meta["scope"],
+		InstalledAt: font.Meta["installed_at"],
+		Pinned:      fm.IsPinned(font),
+		Note:        fm.Note(font),
+	}
+}
+
+// SearchResult is a font found in a source
+type SearchResult struct {
+	Name   string            `json:"name"`
+	Source string            `json:"source"`
+	Match  fm.MatchKind      `json:"match"`
+	Score  float64           `json:"score"`
+	Meta   map[string]string `json:"meta,omitempty"`
+}
+
+// Status describes the server and what it manages
+type Status struct {
+	Version string `json:"version,omitempty"`
+	Fonts   int    `json:"fonts"` // Number of installed fonts
+}
+
+// FontsRequest is the body of install and uninstall requests
+type FontsRequest struct {
+	Fonts []string `json:"fonts"` // Font specs, as fm install takes them
+	Force bool     `json:"force,omitempty"`
+}
+
+// Result is the outcome of an install or uninstall request
+type Result struct {
+	Done    []string  `json:"done"`    // Fonts installed or uninstalled
+	Skipped []string  `json:"skipped"` // Fonts that were already installed
+	Failed  []Failure `json:"failed"`
+}
+
+// Failure is a font that couldn't be installed or uninstalled
+type Failure struct {
+	Font  string `json:"font"`
+	Error string `json:"error"`
+}
+
+// Event reports progress on one font of a streamed request. The last event
+// of a stream has the status "done" and carries the Result.
+type Event struct {
+	Font   string  `json:"font,omitempty"`
+	Status string  `json:"status"` // e.g. installing, installed, skipped, failed or done
+	Error  string  `json:"error,omitempty"`
+	Result *Result `json:"result,omitempty"`
+}
+
+// ServeHTTP routes requests to the API endpoints. Requests from web pages,
+// which carry an Origin header, are refused, so a page the user opens can't
+// drive the API.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Origin") != "" {
+		writeError(w, http.StatusForbidden, errors.New("cross-origin requests are not allowed"))
+		return
+	}
+	if s.localOnly && !isLocalHost(r.Host) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("host %q is not this machine", r.Host))
+		return
+	}
+	if s.token != "" && !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, errors.New("missing or wrong bearer token"))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// isLocalHost reports whether the Host of a request names this machine
+func isLocalHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	fonts, err := s.manager.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Status{Version: s.version, Fonts: len(fonts)})
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	fonts, err := s.manager.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	listed := make([]Font, 0, len(fonts))
+	for _, font := range fonts {
+		listed = append(listed, newFont(font))
+	}
+	writeJSON(w, http.StatusOK, listed)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing query parameter q"))
+		return
+	}
+	results, err := s.manager.Search(r.Context(), query)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	found := make([]SearchResult, 0, len(results))
+	for _, result := range results {
+		found = append(found, SearchResult{
+			Name:   result.Font.Name,
+			Source: result.Source,
+			Match:  result.Match,
+			Score:  result.Score,
+			Meta:   result.Font.Meta,
+		})
+	}
+	writeJSON(w, http.StatusOK, found)
+}
+
+func (s *Server) install(w http.ResponseWriter, r *http.Request) {
+	s.each(w, r, "install", func(req FontsRequest, font string) (string, error) {
+		var opts []fm.InstallOption
+		if req.Force {
+			opts = append(opts, fm.WithForce())
+		}
+		err := s.manager.Install(r.Context(), font, opts...)
+		switch {
+		case errors.Is(err, fm.ErrAlreadyInstalled):
+			return "skipped", nil
+		case err != nil:
+			return "failed", err
+		}
+		return "installed", nil
+	})
+}
+
+func (s *Server) uninstall(w http.ResponseWriter, r *http.Request) {
+	s.each(w, r, "uninstall", func(_ FontsRequest, font string) (string, error) {
+		if err := s.manager.Uninstall(r.Context(), font); err != nil {
+			return "failed", err
+		}
+		return "uninstalled", nil
+	})
+}
+
+// each acts on every font of a FontsRequest in turn, streaming an event per
+// font when the client accepts streamType and answering with the Result
+// otherwise
+func (s *Server) each(w http.ResponseWriter, r *http.Request, action string, act func(FontsRequest, string) (string, error)) {
+	// Browsers send forms and plain text cross-origin without asking first;
+	// JSON they don't
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, errors.New("request body must be application/json"))
+		return
+	}
+
+	var req FontsRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("decoding request: %w", err))
+		return
+	}
+	if len(req.Fonts) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("no fonts given"))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stream := strings.Contains(r.Header.Get("Accept"), streamType)
+	send := func(Event) {}
+	if stream {
+		w.Header().Set("Content-Type", streamType)
+		w.WriteHeader(http.StatusOK)
+		enc := json.NewEncoder(w)
+		flusher, _ := w.(http.Flusher)
+		send = func(event Event) {
+			enc.Encode(event)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+
+	result := &Result{Done: []string{}, Skipped: []string{}, Failed: []Failure{}}
+	for _, font := range req.Fonts {
+		if r.Context().Err() != nil {
+			break
+		}
+		send(Event{Font: font, Status: action + "ing"})
+		status, err := act(req, font)
+		switch {
+		case err != nil:
+			result.Failed = append(result.Failed, Failure{Font: font, Error: err.Error()})
+			send(Event{Font: font, Status: status, Error: err.Error()})
+			continue
+		case status == "skipped":
+			result.Skipped = append(result.Skipped, font)
+		default:
+			result.Done = append(result.Done, font)
+		}
+		send(Event{Font: font, Status: status})
+	}
+
+	if stream {
+		send(Event{Status: "done", Result: result})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,213 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/logandonley/font-manager/internal/server"
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeManager keeps installed fonts in memory. Methods the server doesn't
+// use panic through the embedded nil interface.
+type fakeManager struct {
+	fm.Manager
+	installed map[string]fm.Font
+	available []string
+}
+
+func (m *fakeManager) List(_ context.Context) ([]fm.Font, error) {
+	var fonts []fm.Font
+	for _, font := range m.installed {
+		fonts = append(fonts, font)
+	}
+	return fonts, nil
+}
+
+func (m *fakeManager) Search(_ context.Context, query string) ([]fm.SearchResult, error) {
+	var results []fm.SearchResult
+	for _, name := range m.available {
+		if strings.Contains(strings.ToLower(name), strings.ToLower(query)) {
+			results = append(results, fm.SearchResult{Font: fm.Font{Name: name}, Source: "fake", Match: fm.MatchPrefix, Score: 0.5})
+		}
+	}
+	return results, nil
+}
+
+func (m *fakeManager) Install(_ context.Context, name string, opts ...fm.InstallOption) error {
+	if _, ok := m.installed[name]; ok && len(opts) == 0 {
+		return fmt.Errorf("font %q is %w", name, fm.ErrAlreadyInstalled)
+	}
+	for _, available := range m.available {
+		if available == name {
+			m.installed[name] = fm.Font{Name: name, Source: "fake", Meta: map[string]string{"version": "v1.0"}}
+			return nil
+		}
+	}
+	return fmt.Errorf("font %q %w", name, fm.ErrNotFound)
+}
+
+func (m *fakeManager) Uninstall(_ context.Context, name string) error {
+	if _, ok := m.installed[name]; !ok {
+		return fmt.Errorf("font %q is %w", name, fm.ErrNotInstalled)
+	}
+	delete(m.installed, name)
+	return nil
+}
+
+var _ = Describe("Server", func() {
+	var (
+		manager *fakeManager
+		ts      *httptest.Server
+		opts    []server.Option
+	)
+
+	BeforeEach(func() {
+		manager = &fakeManager{
+			installed: map[string]fm.Font{"Inter": {Name: "Inter", Source: "fake", Meta: map[string]string{"version": "v4.0", "pinned": "true"}}},
+			available: []string{"Inter", "Fira Code", "Fira Sans"},
+		}
+		opts = []server.Option{server.WithVersion("v1.2.3")}
+	})
+
+	JustBeforeEach(func() {
+		ts = httptest.NewServer(server.New(manager, opts...))
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	do := func(method, path, body string, header ...string) *http.Response {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		Expect(err).NotTo(HaveOccurred())
+		if method == "POST" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(resp.Body.Close)
+		return resp
+	}
+
+	decode := func(resp *http.Response, v any) {
+		Expect(json.NewDecoder(resp.Body).Decode(v)).To(Succeed())
+	}
+
+	It("should report its status", func() {
+		resp := do("GET", "/v1/status", "")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var status server.Status
+		decode(resp, &status)
+		Expect(status).To(Equal(server.Status{Version: "v1.2.3", Fonts: 1}))
+	})
+
+	It("should list installed fonts", func() {
+		resp := do("GET", "/v1/fonts", "")
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+		var fonts []server.Font
+		decode(resp, &fonts)
+		Expect(fonts).To(ConsistOf(server.Font{Name: "Inter", Source: "fake", Version: "v4.0", Pinned: true}))
+	})
+
+	It("should search sources", func() {
+		var results []server.SearchResult
+		decode(do("GET", "/v1/search?q=fira", ""), &results)
+		Expect(results).To(ConsistOf(HaveField("Name", "Fira Code"), HaveField("Name", "Fira Sans")))
+
+		resp := do("GET", "/v1/search", "")
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should install fonts and report each outcome", func() {
+		resp := do("POST", "/v1/install", `{"fonts": ["Fira Code", "Inter", "Missing"]}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var result server.Result
+		decode(resp, &result)
+		Expect(result.Done).To(Equal([]string{"Fira Code"}))
+		Expect(result.Skipped).To(Equal([]string{"Inter"}))
+		Expect(result.Failed).To(ConsistOf(And(HaveField("Font", "Missing"), HaveField("Error", ContainSubstring("not found")))))
+		Expect(manager.installed).To(HaveKey("Fira Code"))
+	})
+
+	It("should stream progress when asked for NDJSON", func() {
+		resp := do("POST", "/v1/uninstall", `{"fonts": ["Inter", "Missing"]}`, "Accept", "application/x-ndjson")
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/x-ndjson"))
+
+		var events []server.Event
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var event server.Event
+			Expect(json.Unmarshal(scanner.Bytes(), &event)).To(Succeed())
+			events = append(events, event)
+		}
+		Expect(events).To(HaveLen(5))
+		Expect(events[:4]).To(Equal([]server.Event{
+			{Font: "Inter", Status: "uninstalling"},
+			{Font: "Inter", Status: "uninstalled"},
+			{Font: "Missing", Status: "uninstalling"},
+			{Font: "Missing", Status: "failed", Error: `font "Missing" is not installed`},
+		}))
+		Expect(events[4].Status).To(Equal("done"))
+		Expect(events[4].Result.Done).To(Equal([]string{"Inter"}))
+		Expect(manager.installed).To(BeEmpty())
+	})
+
+	It("should reject bad request bodies", func() {
+		Expect(do("POST", "/v1/install", `{"fonts": `).StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(do("POST", "/v1/install", `{"fonts": []}`).StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(do("GET", "/v1/install", "").StatusCode).To(Equal(http.StatusMethodNotAllowed))
+		Expect(do("POST", "/v1/install", `{"fonts": ["Inter"]}`, "Content-Type", "text/plain").StatusCode).To(Equal(http.StatusUnsupportedMediaType))
+		Expect(manager.installed).To(HaveLen(1))
+	})
+
+	It("should refuse requests from web pages", func() {
+		resp := do("POST", "/v1/uninstall", `{"fonts": ["Inter"]}`, "Origin", "https://example.com")
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+		Expect(manager.installed).To(HaveKey("Inter"))
+	})
+
+	Context("when local only", func() {
+		BeforeEach(func() {
+			opts = append(opts, server.WithLocalOnly())
+		})
+
+		It("should refuse requests naming another host", func() {
+			status := func(host string) int {
+				req, err := http.NewRequest("GET", ts.URL+"/v1/status", nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.Host = host
+				resp, err := http.DefaultClient.Do(req)
+				Expect(err).NotTo(HaveOccurred())
+				defer resp.Body.Close()
+				return resp.StatusCode
+			}
+			Expect(status("localhost:7070")).To(Equal(http.StatusOK))
+			Expect(status("[::1]:7070")).To(Equal(http.StatusOK))
+			Expect(status("evil.example.com")).To(Equal(http.StatusForbidden))
+			Expect(status("127.0.0.1.evil.example.com:7070")).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Context("with a token", func() {
+		BeforeEach(func() {
+			opts = append(opts, server.WithToken("secret"))
+		})
+
+		It("should refuse requests without it", func() {
+			Expect(do("GET", "/v1/status", "").StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(do("GET", "/v1/status", "", "Authorization", "Bearer wrong").StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(do("GET", "/v1/status", "", "Authorization", "Bearer secret").StatusCode).To(Equal(http.StatusOK))
+		})
+	})
+})
@@ -0,0 +1,137 @@
+package fm
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DefaultAliasIndexURL is where the curated alias index is refreshed from
+const DefaultAliasIndexURL = "https://raw.githubusercontent.com/logandonley/font-manager/main/pkg/fm/aliases.json"
+
+//go:embed aliases.json
+var embeddedAliases []byte
+
+// Alias maps a commonly used font name to the source and name that
+// installs it. In an index it is either an object of both or a font spec
+// naming them, such as "FiraCode@nerdfonts".
+type Alias struct {
+	Source string `json:"source"`
+	Name   string `json:"name"`
+}
+
+func (a *Alias) UnmarshalJSON(data []byte) error {
+	var line string
+	if err := json.Unmarshal(data, &line); err != nil {
+		type plain Alias
+		return json.Unmarshal(data, (*plain)(a))
+	}
+
+	font, err := ParseFontSpec(line)
+	if err != nil {
+		return err
+	}
+	if font == nil || font.Source == "" || font.URL != "" {
+		return fmt.Errorf("alias %q must name a font and its source, as name@source", line)
+	}
+	if font.Ref != "" || font.MinVersion != "" || font.Version != "" || len(font.Options) > 0 {
+		return fmt.Errorf("alias %q can only name a font and its source", line)
+	}
+	a.Source, a.Name = font.Source, font.Name
+	return nil
+}
+
+// AliasIndex resolves common font names to a canonical source and name. It
+// starts from the index shipped with fm and, when given a URL, merges in a
+// refreshed copy fetched through the source cache.
+type AliasIndex struct {
+	url    string
+	client *http.Client
+	cache  *Cache
+	logger *slog.Logger
+
+	once    sync.Once
+	aliases map[string]Alias
+}
+
+// NewAliasIndex creates an alias index refreshed from url; an empty url uses
+// only the shipped index
+func NewAliasIndex(url string, opts ...SourceOption) *AliasIndex {
+	o := newSourceOptions(opts)
+	return &AliasIndex{
+		url:    url,
+		client: o.client,
+		cache:  o.cache,
+		logger: o.logger,
+	}
+}
+
+// Lookup returns the alias registered for name, if any
+func (a *AliasIndex) Lookup(ctx context.Context, name string) (Alias, bool) {
+	a.once.Do(func() {
+		a.load(ctx)
+	})
+
+	alias, ok := a.aliases[aliasKey(name)]
+	return alias, ok
+}
+
+// CachedNames returns the aliased names from the shipped index and the
+// cached refreshed copy, without fetching it
+func (a *AliasIndex) CachedNames() []string {
+	var names []string
+	add := func(data []byte) {
+		var aliases map[string]Alias
+		if err := json.Unmarshal(data, &aliases); err != nil {
+			return
+		}
+		for name := range aliases {
+			names = append(names, name)
+		}
+	}
+
+	add(embeddedAliases)
+	if a.url != "" {
+		if data, ok := a.cache.GetStale(a.url); ok {
+			add(data)
+		}
+	}
+	return names
+}
+
+func (a *AliasIndex) load(ctx context.Context) {
+	a.aliases = make(map[string]Alias)
+
+	var shipped map[string]Alias
+	if err := json.Unmarshal(embeddedAliases, &shipped); err == nil {
+		a.merge(shipped)
+	}
+
+	// The refreshed index is optional; without it the shipped one still works
+	if a.url != "" {
+		var refreshed map[string]Alias
+		if err := getJSON(ctx, a.client, a.cache, a.logger, a.url, &refreshed); err != nil {
+			logger(a.logger).Debug("using the shipped alias index", "url", a.url, "err", err)
+		} else {
+			a.merge(refreshed)
+		}
+	}
+}
+
+func (a *AliasIndex) merge(aliases map[string]Alias) {
+	for name, alias := range aliases {
+		a.aliases[aliasKey(name)] = alias
+	}
+}
+
+// aliasKey normalizes case and separators but keeps word boundaries, so
+// "Fira Code" and "fira-code" match while "FiraCode" stays distinct
+func aliasKey(name string) string {
+	name = strings.NewReplacer("-", " ", "_", " ").Replace(normalizeName(name))
+	return strings.Join(strings.Fields(name), " ")
+}
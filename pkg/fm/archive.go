@@ -0,0 +1,317 @@
+package fm
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownArchive is returned when no registered extractor recognizes a
+// download
+var ErrUnknownArchive = errors.New("unrecognized archive format")
+
+// ArchiveEntry is a file inside an archive
+type ArchiveEntry struct {
+	Name string                        // Path of the file within the archive
+	Size int64                         // Uncompressed size in bytes
+	Open func() (io.ReadCloser, error) // Opens the file's contents
+}
+
+// ArchiveMatcher reports whether an extractor understands the given data,
+// typically by checking its magic bytes
+type ArchiveMatcher func(data []byte) bool
+
+// Extractor lists the regular files contained in an archive. Entries should
+// be opened lazily so limits can be checked before anything is decompressed.
+type Extractor func(data []byte) ([]ArchiveEntry, error)
+
+type registeredExtractor struct {
+	matcher   ArchiveMatcher
+	extractor Extractor
+	// readerAt lists the archive where it's stored, without reading it into
+	// memory. Only built-in extractors have one.
+	readerAt func(r io.ReaderAt, size int64) ([]ArchiveEntry, error)
+}
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   []registeredExtractor
+)
+
+// RegisterExtractor adds support for an archive format to the installer.
+// Extractors registered later take precedence, so a downstream registration
+// can replace a built-in one.
+func RegisterExtractor(matcher ArchiveMatcher, extractor Extractor) {
+	registerExtractor(registeredExtractor{matcher: matcher, extractor: extractor})
+}
+
+func registerExtractor(e registeredExtractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors = append(extractors, e)
+}
+
+func init() {
+	registerExtractor(registeredExtractor{matcher: isZip, extractor: extractZip, readerAt: extractZipAt})
+	registerExtractor(registeredExtractor{matcher: isGzip, extractor: extractTarGz, readerAt: extractTarGzAt})
+	registerExtractor(registeredExtractor{matcher: isTar, extractor: extractTar, readerAt: extractTarAt})
+}
+
+// extractEntries lists the files in data using the first matching extractor
+func extractEntries(data []byte) ([]ArchiveEntry, error) {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+
+	for i := len(extractors) - 1; i >= 0; i-- {
+		if extractors[i].matcher(data) {
+			return extractors[i].extractor(data)
+		}
+	}
+	return nil, ErrUnknownArchive
+}
+
+// archiveHeaderSize is how much of an archive extractEntriesAt matches
+// extractors against, a tar header block
+const archiveHeaderSize = 512
+
+// extractEntriesAt lists the files in the archive r of size bytes, e.g. a
+// download spooled to a file. Formats fm reads itself are listed and opened
+// in place; others are read into memory for their extractor.
+func extractEntriesAt(r io.ReaderAt, size int64) ([]ArchiveEntry, error) {
+	header, err := readArchiveHeader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	extractorsMu.RLock()
+	var readerAt func(io.ReaderAt, int64) ([]ArchiveEntry, error)
+	for i := len(extractors) - 1; i >= 0; i-- {
+		if extractors[i].matcher(header) {
+			readerAt = extractors[i].readerAt
+			break
+		}
+	}
+	extractorsMu.RUnlock()
+	if readerAt != nil {
+		return readerAt(r, size)
+	}
+
+	// Matchers may need more than the header, e.g. to find a cabinet in a
+	// self-extracting executable
+	data := make([]byte, size)
+	if _, err := io.ReadFull(io.NewSectionReader(r, 0, size), data); err != nil {
+		return nil, err
+	}
+	return extractEntries(data)
+}
+
+// readArchiveHeader reads the start of the archive r, enough to tell its
+// format
+func readArchiveHeader(r io.ReaderAt, size int64) ([]byte, error) {
+	header := make([]byte, min(size, archiveHeaderSize))
+	if _, err := r.ReadAt(header, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return header, nil
+}
+
+// expandNestedArchives replaces entries that are themselves archives with
+// their contents, descending at most depth levels. Entries that no extractor
+// recognizes are kept as they are. The expanded contents count towards the
+// same limits as the outer archive.
+func expandNestedArchives(entries []ArchiveEntry, depth int, limits Limits) ([]ArchiveEntry, error) {
+	if depth <= 0 {
+		return entries, nil
+	}
+
+	expanded := make([]ArchiveEntry, 0, len(entries))
+	nested := false
+	for _, entry := range entries {
+		// Fonts, licenses and hidden files are never archives worth opening
+		base := filepath.Base(entry.Name)
+		if isFontFile(entry.Name) || strings.EqualFold(base, "LICENSE") || strings.HasPrefix(base, ".") {
+			expanded = append(expanded, entry)
+			continue
+		}
+
+		data, err := readEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", entry.Name, err)
+		}
+
+		inner, err := extractEntries(data)
+		if err != nil {
+			// Not an archive (or not one we can read), e.g. a README
+			expanded = append(expanded, entry)
+			continue
+		}
+
+		if err := checkArchiveLimits(inner, limits); err != nil {
+			return nil, fmt.Errorf("nested archive %s: %w", entry.Name, err)
+		}
+
+		inner, err = expandNestedArchives(inner, depth-1, limits)
+		if err != nil {
+			return nil, err
+		}
+		for _, innerEntry := range inner {
+			innerEntry.Name = entry.Name + "/" + innerEntry.Name
+			expanded = append(expanded, innerEntry)
+		}
+		nested = true
+	}
+
+	if nested {
+		if err := checkArchiveLimits(expanded, limits); err != nil {
+			return nil, err
+		}
+	}
+	return expanded, nil
+}
+
+// readEntry reads an entry's contents, refusing more than its declared size
+func readEntry(entry ArchiveEntry) ([]byte, error) {
+	src, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, entry.Size+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > entry.Size {
+		return nil, fmt.Errorf("file is larger than its declared size of %d bytes", entry.Size)
+	}
+	return data, nil
+}
+
+func isZip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04")) || bytes.HasPrefix(data, []byte("PK\x05\x06"))
+}
+
+func extractZip(data []byte) ([]ArchiveEntry, error) {
+	return extractZipAt(bytes.NewReader(data), int64(len(data)))
+}
+
+func extractZipAt(r io.ReaderAt, size int64) ([]ArchiveEntry, error) {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("reading zip data: %w", err)
+	}
+
+	var entries []ArchiveEntry
+	for _, file := range zipReader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		entries = append(entries, ArchiveEntry{
+			Name: file.Name,
+			Size: int64(file.UncompressedSize64),
+			Open: file.Open,
+		})
+	}
+	return entries, nil
+}
+
+func isGzip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte{0x1f, 0x8b})
+}
+
+// isTar checks for the ustar magic that follows the first header's fields
+func isTar(data []byte) bool {
+	return len(data) > 262 && bytes.Equal(data[257:262], []byte("ustar"))
+}
+
+// tarOpener returns the uncompressed tar stream for the size bytes of r
+type tarOpener func(r io.ReaderAt, size int64) (io.ReadCloser, error)
+
+func openPlain(r io.ReaderAt, size int64) (io.ReadCloser, error) {
+	return io.NopCloser(io.NewSectionReader(r, 0, size)), nil
+}
+
+func openGzip(r io.ReaderAt, size int64) (io.ReadCloser, error) {
+	gz, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, fmt.Errorf("reading gzip data: %w", err)
+	}
+	return gz, nil
+}
+
+func extractTar(data []byte) ([]ArchiveEntry, error) {
+	return extractTarAt(bytes.NewReader(data), int64(len(data)))
+}
+
+func extractTarAt(r io.ReaderAt, size int64) ([]ArchiveEntry, error) {
+	return listTar(r, size, openPlain)
+}
+
+func extractTarGz(data []byte) ([]ArchiveEntry, error) {
+	return extractTarGzAt(bytes.NewReader(data), int64(len(data)))
+}
+
+func extractTarGzAt(r io.ReaderAt, size int64) ([]ArchiveEntry, error) {
+	return listTar(r, size, openGzip)
+}
+
+// listTar lists a tarball. Tar has no index, so each entry re-reads the
+// stream up to its own header when opened.
+func listTar(r io.ReaderAt, size int64, open tarOpener) ([]ArchiveEntry, error) {
+	stream, err := open(r, size)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var entries []ArchiveEntry
+	tarReader := tar.NewReader(stream)
+	for index := 0; ; index++ {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading tar data: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		entries = append(entries, ArchiveEntry{
+			Name: header.Name,
+			Size: header.Size,
+			Open: func() (io.ReadCloser, error) {
+				return openTarEntry(r, size, open, index)
+			},
+		})
+	}
+	return entries, nil
+}
+
+func openTarEntry(r io.ReaderAt, size int64, open tarOpener, index int) (io.ReadCloser, error) {
+	stream, err := open(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	tarReader := tar.NewReader(stream)
+	for i := 0; i <= index; i++ {
+		if _, err := tarReader.Next(); err != nil {
+			stream.Close()
+			return nil, fmt.Errorf("reading tar data: %w", err)
+		}
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{tarReader, stream}, nil
+}
@@ -0,0 +1,325 @@
+package fm_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// Helper function to create a gzipped tarball from name -> content
+func createTestTarGz(files map[string]string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	tarWriter := tar.NewWriter(gz)
+
+	for name, content := range files {
+		header := &tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tarWriter.Write([]byte(content)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Helper function to create a single-folder cabinet holding files, splitting the data into blocks of blockSize bytes compressed with MSZIP
+// when compress is set
+func createTestCab(files []testFont, blockSize int, compress bool) ([]byte, error) {
+	var names []string
+	var data []byte
+	for _, f := range files {
+		names = append(names, f.name+"."+f.format)
+		data = append(data, f.content...)
+	}
+
+	var blocks [][]byte
+	var sizes []int
+	for start := 0; start < len(data); start += blockSize {
+		end := min(start+blockSize, len(data))
+		block := data[start:end]
+		if compress {
+			buf := bytes.NewBufferString("CK")
+			w, err := flate.NewWriterDict(buf, flate.BestCompression, data[max(0, start-32*1024):start])
+			if err != nil {
+				return nil, err
+			}
+			w.Write(block)
+			w.Close()
+			block = buf.Bytes()
+		}
+		blocks = append(blocks, block)
+		sizes = append(sizes, end-start)
+	}
+
+	le := binary.LittleEndian
+	filesOffset := 36 + 8
+	dataOffset := filesOffset
+	for _, name := range names {
+		dataOffset += 16 + len(name) + 1
+	}
+
+	cab := make([]byte, 36)
+	copy(cab, "MSCF")
+	le.PutUint32(cab[16:], uint32(filesOffset))
+	cab[24], cab[25] = 3, 1
+	le.PutUint16(cab[26:], 1)
+	le.PutUint16(cab[28:], uint16(len(files)))
+
+	typeCompress := uint16(0)
+	if compress {
+		typeCompress = 1
+	}
+	cab = le.AppendUint32(cab, uint32(dataOffset))
+	cab = le.AppendUint16(cab, uint16(len(blocks)))
+	cab = le.AppendUint16(cab, typeCompress)
+
+	offset := 0
+	for i, name := range names {
+		cab = le.AppendUint32(cab, uint32(len(files[i].content)))
+		cab = le.AppendUint32(cab, uint32(offset))
+		cab = le.AppendUint16(cab, 0)
+		cab = append(cab, make([]byte, 6)...)
+		cab = append(append(cab, name...), 0)
+		offset += len(files[i].content)
+	}
+
+	for i, block := range blocks {
+		cab = le.AppendUint32(cab, 0)
+		cab = le.AppendUint16(cab, uint16(len(block)))
+		cab = le.AppendUint16(cab, uint16(sizes[i]))
+		cab = append(cab, block...)
+	}
+	le.PutUint32(cab[8:], uint32(len(cab)))
+	return cab, nil
+}
+
+var _ = Describe("Archive formats", func() {
+	var (
+		tempDir   string
+		installer *fm.FontInstaller
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "archive-test-*")
+		Expect(err).NotTo(HaveOccurred())
+		installer = fm.NewFontInstaller(tempDir)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	It("should install fonts from a gzipped tarball", func() {
+		data, err := createTestTarGz(map[string]string{
+			"TarFont/TarFont-Regular.ttf": "regular",
+			"TarFont/TarFont-Bold.otf":    "bold",
+			"TarFont/README.md":           "readme",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(installer.Install(fm.Font{Name: "TarFont"}, bytes.NewReader(data))).To(Succeed())
+
+		content, err := os.ReadFile(filepath.Join(tempDir, "TarFont", "TarFont-Bold.otf"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("bold"))
+		Expect(filepath.Join(tempDir, "TarFont", "TarFont-Regular.ttf")).To(BeAnExistingFile())
+		Expect(filepath.Join(tempDir, "TarFont", "README.md")).NotTo(BeAnExistingFile())
+	})
+
+	Context("with cabinets", func() {
+		files := []testFont{
+			{name: "arial", format: "ttf", content: strings.Repeat("arial regular ", 200)},
+			{name: "arialbd", format: "ttf", content: strings.Repeat("arial bold ", 300)},
+			{name: "fontinst", format: "exe", content: "installer"},
+		}
+
+		expectInstalled := func() {
+			content, err := os.ReadFile(filepath.Join(tempDir, "Arial", "arialbd.ttf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal(files[1].content))
+			Expect(filepath.Join(tempDir, "Arial", "arial.ttf")).To(BeAnExistingFile())
+			Expect(filepath.Join(tempDir, "Arial", "fontinst.exe")).NotTo(BeAnExistingFile())
+		}
+
+		It("should install fonts from an MSZIP cabinet spanning several blocks", func() {
+			data, err := createTestCab(files, 1000, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(installer.Install(fm.Font{Name: "Arial"}, bytes.NewReader(data))).To(Succeed())
+			expectInstalled()
+		})
+
+		It("should install fonts from an uncompressed cabinet", func() {
+			data, err := createTestCab(files, 1000, false)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(installer.Install(fm.Font{Name: "Arial"}, bytes.NewReader(data))).To(Succeed())
+			expectInstalled()
+		})
+
+		It("should find a cabinet embedded in a self-extracting executable", func() {
+			cab, err := createTestCab(files, 4096, true)
+			Expect(err).NotTo(HaveOccurred())
+			data := append([]byte("MZ stub mentioning MSCF before the real cabinet"), cab...)
+
+			Expect(installer.Install(fm.Font{Name: "Arial"}, bytes.NewReader(data))).To(Succeed())
+			expectInstalled()
+		})
+
+		It("should reject a truncated cabinet", func() {
+			data, err := createTestCab(files, 1000, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = installer.Install(fm.Font{Name: "Arial"}, bytes.NewReader(data[:len(data)-100]))
+			Expect(err).To(MatchError(ContainSubstring("invalid cabinet")))
+		})
+	})
+
+	It("should read archives from the spooled download in low-memory mode", func() {
+		tarGz, err := createTestTarGz(map[string]string{"TarFont/TarFont-Regular.ttf": "regular"})
+		Expect(err).NotTo(HaveOccurred())
+		cab, err := createTestCab([]testFont{{name: "arial", format: "ttf", content: "arial regular"}}, 1000, true)
+		Expect(err).NotTo(HaveOccurred())
+
+		source := newMockSource()
+		source.fonts["TarFont"] = tarGz
+		// Cabinets are found by their extractor reading the whole download
+		source.fonts["Arial"] = append([]byte("MZ stub"), cab...)
+		Expect(os.MkdirAll(filepath.Join(tempDir, "user"), 0755)).To(Succeed())
+		manager := fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithLowMemory())
+		Expect(manager.RegisterSource(source)).To(Succeed())
+
+		ctx := context.Background()
+		Expect(manager.Install(ctx, "TarFont@testsource")).To(Succeed())
+		Expect(manager.Install(ctx, "Arial@testsource")).To(Succeed())
+		Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+
+		content, err := os.ReadFile(filepath.Join(tempDir, "user", "TarFont", "TarFont-Regular.ttf"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("regular"))
+		Expect(filepath.Join(tempDir, "user", "Arial", "arial.ttf")).To(BeARegularFile())
+		Expect(filepath.Join(tempDir, "user", "TestFont1", "TestFont1.ttf")).To(BeARegularFile())
+	})
+
+	It("should reject data no extractor recognizes", func() {
+		err := installer.Install(fm.Font{Name: "Unknown"}, strings.NewReader("not an archive"))
+		Expect(err).To(MatchError(fm.ErrUnknownArchive))
+	})
+
+	It("should use registered extractors", func() {
+		// A trivial format: a magic header followed by a single font's bytes
+		magic := []byte("FMTESTFONT\n")
+		fm.RegisterExtractor(
+			func(data []byte) bool { return bytes.HasPrefix(data, magic) },
+			func(data []byte) ([]fm.ArchiveEntry, error) {
+				content := data[len(magic):]
+				return []fm.ArchiveEntry{{
+					Name: "Custom.ttf",
+					Size: int64(len(content)),
+					Open: func() (io.ReadCloser, error) {
+						return io.NopCloser(bytes.NewReader(content)), nil
+					},
+				}}, nil
+			},
+		)
+
+		data := append(append([]byte{}, magic...), "custom font"...)
+		Expect(installer.Install(fm.Font{Name: "Custom"}, bytes.NewReader(data))).To(Succeed())
+
+		content, err := os.ReadFile(filepath.Join(tempDir, "Custom", "Custom.ttf"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("custom font"))
+	})
+
+	It("should refuse entries larger than their declared size", func() {
+		magic := []byte("FMTESTLIAR\n")
+		fm.RegisterExtractor(
+			func(data []byte) bool { return bytes.HasPrefix(data, magic) },
+			func(data []byte) ([]fm.ArchiveEntry, error) {
+				return []fm.ArchiveEntry{{
+					Name: "Liar.ttf",
+					Size: 1,
+					Open: func() (io.ReadCloser, error) {
+						return io.NopCloser(strings.NewReader("much more than one byte")), nil
+					},
+				}}, nil
+			},
+		)
+
+		err := installer.Install(fm.Font{Name: "Liar"}, bytes.NewReader(magic))
+		Expect(err).To(MatchError(ContainSubstring("declared size")))
+	})
+})
+
+var _ = Describe("Nested archives", func() {
+	var (
+		tempDir   string
+		installer *fm.FontInstaller
+		data      []byte
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "nested-test-*")
+		Expect(err).NotTo(HaveOccurred())
+		installer = fm.NewFontInstaller(tempDir)
+
+		// A foundry-style download: one zip per family inside a tarball
+		inner, err := createTestZip(testFont{name: "Inner-Regular", format: "otf", content: "inner"})
+		Expect(err).NotTo(HaveOccurred())
+		data, err = createTestTarGz(map[string]string{
+			"Foundry/Inner.zip": string(inner),
+			"Foundry/README":    "readme",
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	It("should extract fonts from nested archives", func() {
+		Expect(installer.Install(fm.Font{Name: "Foundry"}, bytes.NewReader(data))).To(Succeed())
+
+		content, err := os.ReadFile(filepath.Join(tempDir, "Foundry", "Inner-Regular.otf"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("inner"))
+		Expect(filepath.Join(tempDir, "Foundry", "LICENSE")).To(BeAnExistingFile())
+	})
+
+	It("should not descend past the configured depth", func() {
+		err := installer.Install(fm.Font{Name: "Foundry"}, bytes.NewReader(data), fm.WithArchiveDepth(0))
+		Expect(err).To(MatchError(ContainSubstring("no valid font files")))
+	})
+
+	It("should apply limits to the nested contents", func() {
+		err := installer.Install(fm.Font{Name: "Foundry"}, bytes.NewReader(data), fm.WithLimits(fm.Limits{MaxFiles: 2}))
+		Expect(err).To(MatchError(fm.ErrLimitExceeded))
+	})
+})
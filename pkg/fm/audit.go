@@ -0,0 +1,198 @@
+package fm
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	auditMaxSize    = 10 << 20 // Rotate the log once it grows past 10 MiB
+	auditMaxBackups = 5        // Number of rotated logs kept alongside the active one
+)
+
+// AuditEntry records a single font operation
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user"`
+	SudoUser string    `json:"sudo_user,omitempty"`
+	Host     string    `json:"host"`
+	Action   string    `json:"action"`
+	Font     string    `json:"font"`
+	Source   string    `json:"source,omitempty"`
+	Version  string    `json:"version,omitempty"`
+	URL      string    `json:"url,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	SHA256   string    `json:"sha256,omitempty"`
+	License  string    `json:"license,omitempty"`
+
+	// LicenseOverride marks installs that bypassed the license policy
+	LicenseOverride bool `json:"license_override,omitempty"`
+
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// AuditLog is an append-only JSON lines log of font operations with
+// size-based rotation
+type AuditLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewAuditLog(path string) *AuditLog {
+	return &AuditLog{path: path}
+}
+
+// DefaultAuditLogPath returns the audit log location under the user's state
+// directory ($XDG_STATE_HOME or ~/.local/state)
+func DefaultAuditLogPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "audit.log"), nil
+}
+
+// stateDir returns fm's directory under the user's state directory
+// ($XDG_STATE_HOME or ~/.local/state)
+func stateDir() (string, error) {
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("getting user home directory: %w", err)
+		}
+		stateDir = filepath.Join(homeDir, ".local", "state")
+	}
+	return filepath.Join(stateDir, "fm"), nil
+}
+
+// Path returns the location of the active log file
+func (l *AuditLog) Path() string {
+	return l.path
+}
+
+// Record appends an entry, filling in the time, user and host when unset
+func (l *AuditLog) Record(entry AuditEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.User == "" {
+		entry.User = currentUsername()
+		entry.SudoUser = os.Getenv("SUDO_USER")
+	}
+	if entry.Host == "" {
+		entry.Host, _ = os.Hostname()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshaling audit entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("creating audit log directory: %w", err)
+	}
+	if err := l.rotate(); err != nil {
+		return fmt.Errorf("rotating audit log: %w", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	return nil
+}
+
+// rotate shifts audit.log to audit.log.1, audit.log.1 to audit.log.2 and so
+// on once the active log is too large, dropping the oldest backup
+func (l *AuditLog) rotate() error {
+	info, err := os.Stat(l.path)
+	if os.IsNotExist(err) || (err == nil && info.Size() < auditMaxSize) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := auditMaxBackups - 1; i > 0; i-- {
+		if err := os.Rename(l.backupPath(i), l.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(l.path, l.backupPath(1))
+}
+
+func (l *AuditLog) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", l.path, n)
+}
+
+// Entries returns all entries recorded at or after since, oldest first,
+// including those in rotated logs
+func (l *AuditLog) Entries(since time.Time) ([]AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []AuditEntry
+	for i := auditMaxBackups; i >= 0; i-- {
+		path := l.path
+		if i > 0 {
+			path = l.backupPath(i)
+		}
+
+		fileEntries, err := readAuditFile(path, since)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	return entries, nil
+}
+
+func readAuditFile(path string, since time.Time) ([]AuditEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if !entry.Time.Before(since) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+func currentUsername() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,103 @@
+package fm_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Audit log", func() {
+	var (
+		tempDir  string
+		auditLog *fm.AuditLog
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "audit-test-*")
+		Expect(err).NotTo(HaveOccurred())
+		auditLog = fm.NewAuditLog(filepath.Join(tempDir, "state", "audit.log"))
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	It("should fill in who and when", func() {
+		Expect(auditLog.Record(fm.AuditEntry{Action: "install", Font: "TestFont1", Result: "success"})).To(Succeed())
+
+		entries, err := auditLog.Entries(time.Time{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].User).NotTo(BeEmpty())
+		Expect(entries[0].Time).NotTo(BeZero())
+	})
+
+	It("should filter entries by time", func() {
+		now := time.Now()
+		Expect(auditLog.Record(fm.AuditEntry{Time: now.Add(-48 * time.Hour), Action: "install", Font: "Old"})).To(Succeed())
+		Expect(auditLog.Record(fm.AuditEntry{Time: now, Action: "install", Font: "New"})).To(Succeed())
+
+		entries, err := auditLog.Entries(now.Add(-24 * time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Font).To(Equal("New"))
+	})
+
+	It("should record installs and uninstalls made by the manager", func() {
+		Expect(os.MkdirAll(filepath.Join(tempDir, "user"), 0755)).To(Succeed())
+		manager := fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithAuditLog(auditLog))
+		Expect(manager.RegisterSource(newMockSource())).To(Succeed())
+
+		ctx := context.Background()
+		Expect(manager.Install(ctx, "TestFont1")).To(Succeed())
+		Expect(manager.Install(ctx, "FailingFont")).NotTo(Succeed())
+		Expect(manager.Uninstall(ctx, "TestFont1")).To(Succeed())
+
+		entries, err := auditLog.Entries(time.Time{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(3))
+
+		Expect(entries[0].Action).To(Equal("install"))
+		Expect(entries[0].Source).To(Equal("testsource"))
+		Expect(entries[0].SHA256).To(HaveLen(64))
+		Expect(entries[0].Result).To(Equal("success"))
+
+		Expect(entries[1].Result).To(Equal("failure"))
+		Expect(entries[1].Error).To(ContainSubstring("simulated failure"))
+
+		Expect(entries[2].Action).To(Equal("uninstall"))
+		Expect(entries[2].Font).To(Equal("TestFont1"))
+	})
+
+	It("should record upgrades and rollbacks with their versions", func() {
+		Expect(os.MkdirAll(filepath.Join(tempDir, "user"), 0755)).To(Succeed())
+		source := newMockSource()
+		source.meta["TestFont1"] = map[string]string{"version": "v1.0.0"}
+		manager := fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir},
+			fm.WithAuditLog(auditLog), fm.WithRollbackDir(filepath.Join(tempDir, "previous")))
+		Expect(manager.RegisterSource(source)).To(Succeed())
+
+		ctx := context.Background()
+		Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+		source.meta["TestFont1"] = map[string]string{"version": "v1.1.0"}
+		_, err := manager.Upgrade(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = manager.Rollback(ctx, "TestFont1")
+		Expect(err).NotTo(HaveOccurred())
+
+		entries, err := auditLog.Entries(time.Time{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveExactElements(
+			And(HaveField("Action", "install"), HaveField("Version", "v1.0.0")),
+			And(HaveField("Action", "uninstall"), HaveField("Version", "v1.0.0")),
+			And(HaveField("Action", "upgrade"), HaveField("Version", "v1.1.0")),
+			And(HaveField("Action", "rollback"), HaveField("Version", "v1.0.0")),
+		))
+	})
+})
@@ -0,0 +1,35 @@
+package fm
+
+import (
+	"net/http"
+	"strings"
+)
+
+// HostCredentials authenticate direct URL downloads from a host, e.g. a
+// private font server behind basic auth or an API token
+type HostCredentials struct {
+	Username string      // Basic auth user; basic auth is used when set
+	Password string      // Basic auth password
+	Headers  http.Header // Extra headers such as Authorization or X-Api-Key
+}
+
+// authenticate applies the credentials configured for the request's host and
+// then the explicit headers, which take precedence
+func (m *DefaultManager) authenticate(req *http.Request, headers http.Header) {
+	creds, ok := m.credentials[strings.ToLower(req.URL.Host)]
+	if !ok {
+		creds, ok = m.credentials[strings.ToLower(req.URL.Hostname())]
+	}
+	if ok {
+		if creds.Username != "" {
+			req.SetBasicAuth(creds.Username, creds.Password)
+		}
+		for name, values := range creds.Headers {
+			req.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	for name, values := range headers {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+}
@@ -0,0 +1,228 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/logandonley/font-manager/pkg/fontmeta"
+)
+
+// VariableSource is implemented by sources that can download the variable
+// build of a font, whose design space can then be limited to the axes and
+// ranges that are needed
+type VariableSource interface {
+	Source
+
+	// Axes returns the variation axes of font's variable build, or none if
+	// the font only has static instances
+	Axes(ctx context.Context, font Font) ([]AxisRange, error)
+}
+
+// axesMetaKey is the Font.Meta key holding the axis ranges to keep, as
+// formatted by FormatAxes. Sources download the variable build when it is set.
+const axesMetaKey = "axes"
+
+// DefaultInstancerCommand limits the axes of variable fonts, with fontTools'
+// instancer. It is run with the font file, a tag=min:max argument per axis and
+// -o with the output file.
+var DefaultInstancerCommand = []string{"fonttools", "varLib.instancer"}
+
+// AxisRange is a range of a variation axis, e.g. weights 400 to 700. A range
+// whose minimum and maximum are equal pins the axis to a single instance.
+type AxisRange struct {
+	Tag      string // Four-character axis tag, e.g. wght or wdth
+	Min, Max float64
+}
+
+func (a AxisRange) String() string {
+	if a.Min == a.Max {
+		return a.Tag + "=" + formatAxisValue(a.Min)
+	}
+	return a.Tag + "=" + formatAxisValue(a.Min) + ".." + formatAxisValue(a.Max)
+}
+
+// contains reports whether r lies within a
+func (a AxisRange) contains(r AxisRange) bool {
+	return a.Min <= r.Min && r.Max <= a.Max
+}
+
+func formatAxisValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// ParseAxes parses a comma-separated list of axis ranges, such as
+// "wght=400..700,wdth=100", where a single value pins the axis
+func ParseAxes(s string) ([]AxisRange, error) {
+	var axes []AxisRange
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		tag, value, ok := strings.Cut(part, "=")
+		if !ok || !validAxisTag(tag) {
+			return nil, fmt.Errorf("invalid axis %q (expected e.g. wght=400..700)", part)
+		}
+		if seen[tag] {
+			return nil, fmt.Errorf("axis %s is given more than once", tag)
+		}
+		seen[tag] = true
+
+		lo, hi, isRange := strings.Cut(value, "..")
+		if !isRange {
+			hi = lo
+		}
+		axis := AxisRange{Tag: tag}
+		var err error
+		if axis.Min, err = strconv.ParseFloat(lo, 64); err == nil {
+			axis.Max, err = strconv.ParseFloat(hi, 64)
+		}
+		if err != nil || axis.Min > axis.Max {
+			return nil, fmt.Errorf("invalid range %q for axis %s", value, tag)
+		}
+		axes = append(axes, axis)
+	}
+
+	if len(axes) == 0 {
+		return nil, fmt.Errorf("no axes given")
+	}
+	return axes, nil
+}
+
+// FormatAxes formats axis ranges the way ParseAxes accepts them
+func FormatAxes(axes []AxisRange) string {
+	parts := make([]string, len(axes))
+	for i, axis := range axes {
+		parts[i] = axis.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// validAxisTag reports whether tag is an OpenType axis tag: four letters or
+// digits, lowercase for registered axes and uppercase for custom ones
+func validAxisTag(tag string) bool {
+	if len(tag) != 4 {
+		return false
+	}
+	for _, r := range tag {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// selectAxes checks the requested ranges against the design space a source
+// offers
+func selectAxes(available, requested []AxisRange) error {
+	if len(available) == 0 {
+		return fmt.Errorf("font has no variable build")
+	}
+	for _, axis := range requested {
+		i := indexAxis(available, axis.Tag)
+		if i < 0 {
+			return fmt.Errorf("axis %s is not available (available: %s)", axis.Tag, FormatAxes(available))
+		}
+		if !available[i].contains(axis) {
+			return fmt.Errorf("%s is outside the design space %s", axis, available[i])
+		}
+	}
+	return nil
+}
+
+func indexAxis(axes []AxisRange, tag string) int {
+	for i, axis := range axes {
+		if axis.Tag == tag {
+			return i
+		}
+	}
+	return -1
+}
+
+// fontAxes reads the variation axes of a TrueType or OpenType font,
+// returning none for static fonts
+func fontAxes(data []byte) ([]AxisRange, error) {
+	axes, err := fontmeta.ReadAxes(data)
+	if err != nil {
+		return nil, err
+	}
+	ranges := make([]AxisRange, len(axes))
+	for i, axis := range axes {
+		ranges[i] = AxisRange{Tag: axis.Tag, Min: axis.Min, Max: axis.Max}
+	}
+	return ranges, nil
+}
+
+// instanceFonts limits the variable font files in dir to the given axis
+// ranges with the instancer, in place. Axes a file doesn't have are left out
+// for it, e.g. the slant of an upright file. It fails unless dir has at least
+// one variable font.
+func (fi *FontInstaller) instanceFonts(dir string, axes []AxisRange) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading font directory: %w", err)
+	}
+
+	instanced := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !isFontFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fileAxes, err := fontAxes(data)
+		if err != nil {
+			return fmt.Errorf("reading axes of %s: %w", entry.Name(), err)
+		}
+		if len(fileAxes) == 0 {
+			continue
+		}
+
+		var args []string
+		for _, axis := range axes {
+			if i := indexAxis(fileAxes, axis.Tag); i >= 0 {
+				args = append(args, strings.Replace(axis.String(), "..", ":", 1))
+			}
+		}
+		if len(args) > 0 {
+			if err := fi.runInstancer(path, args); err != nil {
+				return fmt.Errorf("limiting the axes of %s: %w", entry.Name(), err)
+			}
+		}
+		instanced++
+	}
+
+	if instanced == 0 {
+		return fmt.Errorf("no variable font files to limit to %s", FormatAxes(axes))
+	}
+	return nil
+}
+
+// runInstancer replaces the font file at path with an instance limited by
+// the axis arguments
+func (fi *FontInstaller) runInstancer(path string, axisArgs []string) error {
+	output := path + ".instance"
+	defer os.Remove(output)
+
+	args := slices.Concat(fi.instancerCmd[1:], []string{path}, axisArgs, []string{"-o", output})
+	cmd := exec.Command(fi.instancerCmd[0], args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("%s is needed to limit variable font axes; install fontTools (pip install fonttools): %w", fi.instancerCmd[0], err)
+		}
+		return fmt.Errorf("%s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return os.Rename(output, path)
+}
@@ -0,0 +1,27 @@
+package fm_test
+
+import (
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Variable axes", func() {
+	It("should parse ranges and pinned values", func() {
+		axes, err := fm.ParseAxes("wght=400..700, wdth=100,opsz=14..32.5")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(axes).To(Equal([]fm.AxisRange{
+			{Tag: "wght", Min: 400, Max: 700},
+			{Tag: "wdth", Min: 100, Max: 100},
+			{Tag: "opsz", Min: 14, Max: 32.5},
+		}))
+		Expect(fm.FormatAxes(axes)).To(Equal("wght=400..700,wdth=100,opsz=14..32.5"))
+	})
+
+	It("should reject malformed axes", func() {
+		for _, value := range []string{"", "weight=400", "wght", "wght=700..400", "wght=bold", "wght=400,wght=700"} {
+			_, err := fm.ParseAxes(value)
+			Expect(err).To(HaveOccurred(), value)
+		}
+	})
+})
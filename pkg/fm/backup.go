@@ -0,0 +1,483 @@
+package fm
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// backupManifestName is the first entry of every backup, describing the fonts
+// that follow under fonts/<dir>/
+const backupManifestName = "manifest.json"
+
+// backupVersion is bumped when the backup layout changes incompatibly
+const backupVersion = 1
+
+// BackupManifest describes the fonts stored in a backup
+type BackupManifest struct {
+	Version int          `json:"version"`
+	Created time.Time    `json:"created"`
+	Fonts   []BackupFont `json:"fonts"`
+}
+
+// BackupFont is a font directory stored in a backup, including fm's state
+// files such as .source and .metadata
+type BackupFont struct {
+	Name   string          `json:"name"`
+	Source string          `json:"source,omitempty"`
+	Dir    string          `json:"dir"` // Directory name within the font directory
+	Files  []InstalledFile `json:"files"`
+}
+
+// RestoreResult reports what RestoreBackup did with each font in a backup
+type RestoreResult struct {
+	Restored []string // Fonts written to the font directory
+	Skipped  []string // Fonts that were already installed
+}
+
+// CreateBackup writes every font fm installed in the user font directory,
+// along with its state, to an archive at path. The format follows the
+// extension: .tar.zst, .tar.gz, .tar or .zip.
+func (m *DefaultManager) CreateBackup(ctx context.Context, archive string) (*BackupManifest, error) {
+	fonts, err := m.ManagedFonts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &BackupManifest{Version: backupVersion, Created: time.Now().UTC()}
+	dirs := make(map[string]string)
+	for _, font := range fonts {
+		dir := font.Meta["directory"]
+		files, err := backupFiles(dir)
+		if err != nil {
+			return nil, fmt.Errorf("backing up %s: %w", font.Name, err)
+		}
+		entry := BackupFont{
+			Name:   font.Name,
+			Source: font.Source,
+			Dir:    filepath.Base(dir),
+			Files:  files,
+		}
+		manifest.Fonts = append(manifest.Fonts, entry)
+		dirs[entry.Dir] = dir
+	}
+
+	f, err := os.Create(archive)
+	if err != nil {
+		return nil, fmt.Errorf("creating backup: %w", err)
+	}
+	err = writeBackup(f, archive, manifest, dirs)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(archive)
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// ManagedFonts returns the fonts fm installed in the user font directory,
+// told apart by the metadata fm keeps with them. System fonts and fonts
+// copied in by hand are left out.
+func (m *DefaultManager) ManagedFonts(ctx context.Context) ([]Font, error) {
+	paths, err := m.platform.GetFontPaths()
+	if err != nil {
+		return nil, fmt.Errorf("getting font paths: %w", err)
+	}
+	fonts, err := m.listManaged(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var managed []Font
+	for _, font := range fonts {
+		dir := font.Meta["directory"]
+		if font.Meta["installed_at"] == "" || filepath.Dir(dir) != filepath.Clean(paths.UserDir) {
+			continue
+		}
+		managed = append(managed, font)
+	}
+	return managed, nil
+}
+
+// backupFiles hashes every regular file of a font directory, following the
+// symlinks of store-managed fonts
+func backupFiles(dir string) ([]InstalledFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading font directory: %w", err)
+	}
+
+	var files []InstalledFile
+	for _, entry := range entries {
+		p := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		file, err := hashFile(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// backupWriter adds files to a backup archive
+type backupWriter interface {
+	add(name string, size int64, r io.Reader) error
+	Close() error
+}
+
+func writeBackup(w io.Writer, archive string, manifest *BackupManifest, dirs map[string]string) error {
+	bw, err := newBackupWriter(w, archive)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding backup manifest: %w", err)
+	}
+	if err := bw.add(backupManifestName, int64(len(data)), bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("writing backup manifest: %w", err)
+	}
+
+	for _, font := range manifest.Fonts {
+		for _, file := range font.Files {
+			if err := addBackupFile(bw, path.Join("fonts", font.Dir, file.Name),
+				filepath.Join(dirs[font.Dir], file.Name), file.Size); err != nil {
+				return fmt.Errorf("backing up %s: %w", font.Name, err)
+			}
+		}
+	}
+	return bw.Close()
+}
+
+func addBackupFile(bw backupWriter, name, src string, size int64) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return bw.add(name, size, f)
+}
+
+func newBackupWriter(w io.Writer, archive string) (backupWriter, error) {
+	name := strings.ToLower(archive)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return &zipBackupWriter{zw: zip.NewWriter(w)}, nil
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		return &tarBackupWriter{tw: tar.NewWriter(zw), compressor: zw}, nil
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		gw := gzip.NewWriter(w)
+		return &tarBackupWriter{tw: tar.NewWriter(gw), compressor: gw}, nil
+	case strings.HasSuffix(name, ".tar"):
+		return &tarBackupWriter{tw: tar.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported backup format %q (expected .tar.zst, .tar.gz, .tar or .zip)", filepath.Base(archive))
+	}
+}
+
+type tarBackupWriter struct {
+	tw         *tar.Writer
+	compressor io.WriteCloser
+}
+
+func (b *tarBackupWriter) add(name string, size int64, r io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	if err := b.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.Copy(b.tw, r)
+	return err
+}
+
+func (b *tarBackupWriter) Close() error {
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	if b.compressor != nil {
+		return b.compressor.Close()
+	}
+	return nil
+}
+
+type zipBackupWriter struct {
+	zw *zip.Writer
+}
+
+func (b *zipBackupWriter) add(name string, _ int64, r io.Reader) error {
+	w, err := b.zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func (b *zipBackupWriter) Close() error {
+	return b.zw.Close()
+}
+
+// RestoreBackup reinstates the fonts of a backup created by CreateBackup
+// without downloading them again. Every file is checked against the checksum
+// recorded in the backup, and fonts with missing, corrupted or unlisted files
+// are left out. Fonts that are already installed are skipped.
+func (m *DefaultManager) RestoreBackup(ctx context.Context, archive string) (*RestoreResult, error) {
+	paths, err := m.platform.GetFontPaths()
+	if err != nil {
+		return nil, fmt.Errorf("getting font paths: %w", err)
+	}
+
+	// Unpack beside the font directory rather than in it, so the staged
+	// files aren't listed as fonts, yet verified fonts can be moved into
+	// place with a rename
+	staging, err := os.MkdirTemp(filepath.Dir(paths.UserDir), ".fm-restore-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, err := unpackBackup(archive, staging)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{}
+	var errs []error
+	for _, font := range manifest.Fonts {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		restored, err := m.restoreFont(ctx, font, staging, paths.UserDir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", font.Name, err))
+			continue
+		}
+		if restored {
+			result.Restored = append(result.Restored, font.Name)
+		} else {
+			result.Skipped = append(result.Skipped, font.Name)
+		}
+	}
+
+	if len(result.Restored) > 0 {
+		if err := m.UpdateCache(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// restoreFont verifies a staged font and moves it into the font directory,
+// reporting false when the font is already installed
+func (m *DefaultManager) restoreFont(ctx context.Context, font BackupFont, staging, userDir string) (bool, error) {
+	installed, err := m.findInstalled(ctx, font.Name)
+	if err != nil {
+		return false, err
+	}
+	if installed != nil {
+		return false, nil
+	}
+
+	if font.Dir == "" || font.Dir != filepath.Base(font.Dir) || strings.HasPrefix(font.Dir, ".") {
+		return false, fmt.Errorf("invalid directory %q in backup", font.Dir)
+	}
+	staged := filepath.Join(staging, font.Dir)
+	listed := make(map[string]bool, len(font.Files))
+	for _, file := range font.Files {
+		got, err := hashFile(filepath.Join(staged, file.Name))
+		if err != nil {
+			return false, fmt.Errorf("missing %s in backup", file.Name)
+		}
+		if got.SHA256 != file.SHA256 {
+			return false, fmt.Errorf("checksum mismatch for %s: backup is corrupted", file.Name)
+		}
+		listed[file.Name] = true
+	}
+
+	// The whole directory is moved into place, so files the manifest doesn't
+	// vouch for would be installed unverified
+	entries, err := os.ReadDir(staged)
+	if err != nil {
+		return false, fmt.Errorf("reading staged font: %w", err)
+	}
+	for _, entry := range entries {
+		if !listed[entry.Name()] {
+			return false, fmt.Errorf("%s in backup isn't listed in its manifest", entry.Name())
+		}
+	}
+
+	dir := filepath.Join(userDir, font.Dir)
+	if _, err := os.Lstat(dir); err == nil {
+		return false, fmt.Errorf("%s already exists", dir)
+	}
+	err = os.Rename(staged, dir)
+	if err == nil {
+		err = m.chownToUser(dir)
+	}
+	if err == nil && m.store != nil {
+		if err = m.store.Adopt(dir); err != nil {
+			err = fmt.Errorf("adding font to store: %w", err)
+		}
+	}
+	m.recordAudit(AuditEntry{
+		Action: "restore",
+		Font:   font.Name,
+		Source: font.Source,
+		Path:   dir,
+	}, err)
+	return err == nil, err
+}
+
+// unpackBackup extracts the fonts of a backup into dir and returns its
+// manifest. Entries outside fonts/<dir>/<file> are ignored.
+func unpackBackup(archive, dir string) (*BackupManifest, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, fmt.Errorf("opening backup: %w", err)
+	}
+	defer f.Close()
+
+	var manifest *BackupManifest
+	extract := func(name string, r io.Reader) error {
+		if name == backupManifestName {
+			manifest = &BackupManifest{}
+			if err := json.NewDecoder(r).Decode(manifest); err != nil {
+				return fmt.Errorf("reading backup manifest: %w", err)
+			}
+			if manifest.Version > backupVersion {
+				return fmt.Errorf("backup version %d is newer than this fm supports", manifest.Version)
+			}
+			return nil
+		}
+
+		parts := strings.Split(path.Clean(name), "/")
+		if len(parts) != 3 || parts[0] != "fonts" || parts[1] == ".." || parts[2] == ".." {
+			return nil
+		}
+		fontDir := filepath.Join(dir, parts[1])
+		if err := os.MkdirAll(fontDir, 0755); err != nil {
+			return err
+		}
+		out, err := os.Create(filepath.Join(fontDir, parts[2]))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, r)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+
+	if err := readBackup(f, extract); err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%s is not an fm backup: %s is missing", filepath.Base(archive), backupManifestName)
+	}
+	return manifest, nil
+}
+
+// readBackup hands every file in a backup archive to extract, detecting the
+// format from its contents
+func readBackup(f *os.File, extract func(name string, r io.Reader) error) error {
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(4)
+
+	var r io.Reader = br
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		return readZipBackup(f, extract)
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("reading zstd data: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("reading gzip data: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading backup: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := extract(header.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func readZipBackup(f *os.File, extract func(name string, r io.Reader) error) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return fmt.Errorf("reading zip backup: %w", err)
+	}
+
+	for _, file := range zr.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", file.Name, err)
+		}
+		err = extract(file.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package fm_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Backups", func() {
+	var (
+		tempDir string
+		source  *mockSource
+		ctx     context.Context
+	)
+
+	newManager := func(fontDir string) *fm.DefaultManager {
+		Expect(os.MkdirAll(filepath.Join(fontDir, "user"), 0755)).To(Succeed())
+		manager := fm.NewManagerWithPlatform(&mockPlatform{fontDir: fontDir})
+		Expect(manager.RegisterSource(source)).To(Succeed())
+		return manager
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "font-backup-*")
+		Expect(err).NotTo(HaveOccurred())
+		source = newMockSource()
+		source.meta["TestFont1"] = map[string]string{"version": "v1.0.0"}
+		ctx = context.Background()
+
+		original := newManager(filepath.Join(tempDir, "original"))
+		Expect(original.Install(ctx, "TestFont1@testsource")).To(Succeed())
+		Expect(original.Install(ctx, "TestOTF@testsource")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	for _, ext := range []string{".tar.zst", ".tar.gz", ".tar", ".zip"} {
+		It("should restore fonts and their state from a "+ext+" backup", func() {
+			original := newManager(filepath.Join(tempDir, "original"))
+			archive := filepath.Join(tempDir, "backup"+ext)
+			manifest, err := original.CreateBackup(ctx, archive)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifest.Fonts).To(HaveLen(2))
+
+			restored := newManager(filepath.Join(tempDir, "restored"))
+			result, err := restored.RestoreBackup(ctx, archive)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Restored).To(ConsistOf("TestFont1", "TestOTF"))
+
+			fonts, err := restored.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fonts).To(HaveLen(2))
+			for _, font := range fonts {
+				Expect(font.Source).To(Equal("testsource"))
+				if font.Name == "TestFont1" {
+					Expect(font.Meta["version"]).To(Equal("v1.0.0"))
+				}
+			}
+		})
+	}
+
+	It("should skip fonts that are already installed", func() {
+		original := newManager(filepath.Join(tempDir, "original"))
+		archive := filepath.Join(tempDir, "backup.tar.zst")
+		_, err := original.CreateBackup(ctx, archive)
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := original.RestoreBackup(ctx, archive)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Restored).To(BeEmpty())
+		Expect(result.Skipped).To(ConsistOf("TestFont1", "TestOTF"))
+	})
+
+	It("should refuse fonts whose files don't match their checksums", func() {
+		original := newManager(filepath.Join(tempDir, "original"))
+		archive := filepath.Join(tempDir, "backup.zip")
+		_, err := original.CreateBackup(ctx, archive)
+		Expect(err).NotTo(HaveOccurred())
+
+		// Rewrite the backup with one font file altered
+		reader, err := zip.OpenReader(archive)
+		Expect(err).NotTo(HaveOccurred())
+		buf := new(bytes.Buffer)
+		writer := zip.NewWriter(buf)
+		for _, file := range reader.File {
+			rc, err := file.Open()
+			Expect(err).NotTo(HaveOccurred())
+			data, err := io.ReadAll(rc)
+			Expect(err).NotTo(HaveOccurred())
+			rc.Close()
+			if strings.HasSuffix(file.Name, "TestFont1.ttf") {
+				data = []byte("tampered")
+			}
+			w, err := writer.Create(file.Name)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = w.Write(data)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		reader.Close()
+		Expect(writer.Close()).To(Succeed())
+		Expect(os.WriteFile(archive, buf.Bytes(), 0644)).To(Succeed())
+
+		restored := newManager(filepath.Join(tempDir, "restored"))
+		result, err := restored.RestoreBackup(ctx, archive)
+		Expect(err).To(MatchError(ContainSubstring("checksum mismatch for TestFont1.ttf")))
+		Expect(result.Restored).To(ConsistOf("TestOTF"))
+
+		installed, err := restored.IsInstalled(ctx, "TestFont1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installed).To(BeFalse())
+	})
+
+	It("should refuse fonts with files their manifest doesn't list", func() {
+		original := newManager(filepath.Join(tempDir, "original"))
+		archive := filepath.Join(tempDir, "backup.zip")
+		manifest, err := original.CreateBackup(ctx, archive)
+		Expect(err).NotTo(HaveOccurred())
+		var dir string
+		for _, font := range manifest.Fonts {
+			if font.Name == "TestFont1" {
+				dir = font.Dir
+			}
+		}
+		Expect(dir).NotTo(BeEmpty())
+
+		// Rewrite the backup with an extra file slipped into one font
+		reader, err := zip.OpenReader(archive)
+		Expect(err).NotTo(HaveOccurred())
+		buf := new(bytes.Buffer)
+		writer := zip.NewWriter(buf)
+		for _, file := range reader.File {
+			Expect(writer.Copy(file)).To(Succeed())
+		}
+		reader.Close()
+		w, err := writer.Create("fonts/" + dir + "/Unlisted.ttf")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = w.Write([]byte("unverified"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+		Expect(os.WriteFile(archive, buf.Bytes(), 0644)).To(Succeed())
+
+		restored := newManager(filepath.Join(tempDir, "restored"))
+		result, err := restored.RestoreBackup(ctx, archive)
+		Expect(err).To(MatchError(ContainSubstring("Unlisted.ttf in backup isn't listed in its manifest")))
+		Expect(result.Restored).To(ConsistOf("TestOTF"))
+
+		installed, err := restored.IsInstalled(ctx, "TestFont1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installed).To(BeFalse())
+		_, err = os.Stat(filepath.Join(tempDir, "restored", "user", dir))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("should reject archives that aren't backups", func() {
+		archive := filepath.Join(tempDir, "fonts.zip")
+		content, err := createTestZip(testFont{name: "Other", format: "ttf", content: "other"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(archive, content, 0644)).To(Succeed())
+
+		restored := newManager(filepath.Join(tempDir, "restored"))
+		_, err = restored.RestoreBackup(ctx, archive)
+		Expect(err).To(MatchError(ContainSubstring("not an fm backup")))
+	})
+
+	It("should reject unknown backup formats", func() {
+		original := newManager(filepath.Join(tempDir, "original"))
+		_, err := original.CreateBackup(ctx, filepath.Join(tempDir, "backup.rar"))
+		Expect(err).To(MatchError(ContainSubstring("unsupported backup format")))
+	})
+})
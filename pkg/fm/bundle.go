@@ -0,0 +1,374 @@
+package fm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// bundleManifestName is the first entry of every bundle, describing the
+// downloads that follow under archives/<sha256>
+const bundleManifestName = "bundle.json"
+
+// bundleVersion is bumped when the bundle layout changes incompatibly
+const bundleVersion = 1
+
+// BundleManifest describes the fonts of a config stored in a bundle
+type BundleManifest struct {
+	Version int           `json:"version"`
+	Created time.Time     `json:"created"`
+	Fonts   []BundledFont `json:"fonts"`
+}
+
+// BundledFont is the download of one font of a config, as its source
+// resolved it when the bundle was made
+type BundledFont struct {
+	Spec   string            `json:"spec"` // The config line, with its constraint and options
+	Name   string            `json:"name"`
+	Source string            `json:"source,omitempty"`
+	Ref    string            `json:"ref,omitempty"`
+	URL    string            `json:"url,omitempty"`  // The font's own URL, for fonts installed from one
+	Meta   map[string]string `json:"meta,omitempty"` // What the source recorded, e.g. the version
+
+	DownloadURL string `json:"download_url,omitempty"` // Where the archive was downloaded from
+	SHA256      string `json:"sha256"`                 // Checksum of the archive
+	Size        int64  `json:"size"`
+}
+
+// archive returns the name of the font's download within the bundle
+func (f BundledFont) archive() string {
+	return path.Join("archives", f.SHA256)
+}
+
+// CreateBundle resolves and downloads every font of a config and writes the
+// downloads to an archive at path, for InstallBundle to install them on
+// machines without network access. Nothing is installed. The format follows
+// the extension, as for backups: .tar.zst, .tar.gz, .tar or .zip.
+func (m *DefaultManager) CreateBundle(ctx context.Context, fonts []Font, archive string, opts ...InstallOption) (*BundleManifest, error) {
+	fonts, err := m.ExpandAliases(fonts)
+	if err != nil {
+		return nil, err
+	}
+	fonts = MergeConfigs(fonts)
+
+	staging, err := os.MkdirTemp("", "fm-bundle-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	manifest := &BundleManifest{Version: bundleVersion, Created: time.Now().UTC()}
+	o := m.newInstallOptions(opts)
+	var errs []error
+	for i := range fonts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		bundled, err := m.fetchBundled(ctx, &fonts[i], o, staging)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", fonts[i].Name, err))
+			continue
+		}
+		manifest.Fonts = append(manifest.Fonts, *bundled)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string, len(manifest.Fonts))
+	for _, font := range manifest.Fonts {
+		files[font.archive()] = filepath.Join(staging, font.SHA256)
+	}
+	f, err := os.Create(archive)
+	if err != nil {
+		return nil, fmt.Errorf("creating bundle: %w", err)
+	}
+	err = writeBundle(f, archive, manifest, files)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(archive)
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// fetchBundled resolves spec like an install would and downloads its archive
+// into dir, named by its checksum
+func (m *DefaultManager) fetchBundled(ctx context.Context, spec *Font, o *installOptions, dir string) (*BundledFont, error) {
+	fetch, err := o.withSpecOptions(spec)
+	if err != nil {
+		return nil, err
+	}
+	copied := *fetch
+	fetch = &copied
+
+	var bundled *BundledFont
+	fetch.fetch = func(font Font, data io.Reader) error {
+		var downloadURL string
+		if located, ok := data.(interface{ URL() string }); ok {
+			downloadURL = located.URL()
+		}
+		if limit := fetch.limits.MaxDownloadSize; limit > 0 {
+			data = io.LimitReader(data, limit+1)
+		}
+
+		tmp, err := os.CreateTemp(dir, "*.tmp")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		h := sha256.New()
+		size, err := io.Copy(io.MultiWriter(tmp, h), data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("reading font data: %w", err)
+		}
+		if limit := fetch.limits.MaxDownloadSize; limit > 0 && size > limit {
+			return fmt.Errorf("%w: download is larger than %s", ErrLimitExceeded, FormatSize(limit))
+		}
+		checksum := hex.EncodeToString(h.Sum(nil))
+		if fetch.sha256 != "" && !strings.EqualFold(fetch.sha256, checksum) {
+			return fmt.Errorf("%w: download has SHA-256 %s, expected %s", ErrChecksumMismatch, checksum, fetch.sha256)
+		}
+		if err := os.Rename(tmp.Name(), filepath.Join(dir, checksum)); err != nil {
+			return err
+		}
+
+		bundled = &BundledFont{
+			Spec:   FormatFontSpec(*spec),
+			Name:   font.Name,
+			Source: font.Source,
+			Ref:    font.Ref,
+			URL:    font.URL,
+			Meta:   font.Meta,
+
+			DownloadURL: downloadURL,
+			SHA256:      checksum,
+			Size:        size,
+		}
+		return nil
+	}
+
+	if _, err := m.install(ctx, spec, fetch); err != nil {
+		return nil, err
+	}
+	return bundled, nil
+}
+
+func writeBundle(w io.Writer, archive string, manifest *BundleManifest, files map[string]string) error {
+	bw, err := newBackupWriter(w, archive)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding bundle manifest: %w", err)
+	}
+	if err := bw.add(bundleManifestName, int64(len(data)), bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("writing bundle manifest: %w", err)
+	}
+
+	for _, font := range manifest.Fonts {
+		name := font.archive()
+		src, ok := files[name]
+		if !ok {
+			continue
+		}
+		delete(files, name) // Fonts sharing a download store it once
+		if err := addBackupFile(bw, name, src, font.Size); err != nil {
+			return fmt.Errorf("bundling %s: %w", font.Name, err)
+		}
+	}
+	return bw.Close()
+}
+
+// InstallBundle installs the fonts of a bundle written by CreateBundle from
+// the downloads it holds, without network access, applying the options each
+// font had in its config. Every archive must match its recorded checksum.
+// The outcome of each font is returned in manifest order, with fonts that
+// are already installed reporting ErrAlreadyInstalled; the error covers the
+// bundle as a whole.
+func (m *DefaultManager) InstallBundle(ctx context.Context, archive string, opts ...InstallOption) (*BundleManifest, []error, error) {
+	staging, err := os.MkdirTemp("", "fm-bundle-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, err := unpackBundle(archive, staging)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unpacked := &bundle{dir: staging, fonts: make(map[string]BundledFont, len(manifest.Fonts))}
+	fonts := make([]Font, len(manifest.Fonts))
+	for i, bundled := range manifest.Fonts {
+		font, err := ParseFontSpec(bundled.Spec)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bundle entry %q: %w", bundled.Spec, err)
+		}
+		if font == nil {
+			return nil, nil, fmt.Errorf("bundle entry %d names no font", i+1)
+		}
+		fonts[i] = *font
+		unpacked.fonts[FormatFontSpec(*font)] = bundled
+	}
+
+	o := m.newInstallOptions(opts)
+	o.bundle = unpacked
+	results := m.installEach(ctx, fonts, o)
+	for i, err := range results {
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, ErrChecksumMismatch) {
+			results[i] = fmt.Errorf("bundle is corrupted: %w", err)
+		}
+	}
+	if slices.Contains(results, nil) {
+		return manifest, results, m.UpdateCache()
+	}
+	return manifest, results, nil
+}
+
+// unpackBundle extracts the downloads of a bundle into dir and returns its
+// manifest. Entries other than archives/<sha256> are ignored.
+func unpackBundle(archive, dir string) (*BundleManifest, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, fmt.Errorf("opening bundle: %w", err)
+	}
+	defer f.Close()
+
+	var manifest *BundleManifest
+	extract := func(name string, r io.Reader) error {
+		if name == bundleManifestName {
+			manifest = &BundleManifest{}
+			if err := json.NewDecoder(r).Decode(manifest); err != nil {
+				return fmt.Errorf("reading bundle manifest: %w", err)
+			}
+			if manifest.Version > bundleVersion {
+				return fmt.Errorf("bundle version %d is newer than this fm supports", manifest.Version)
+			}
+			return nil
+		}
+
+		parts := strings.Split(path.Clean(name), "/")
+		if len(parts) != 2 || parts[0] != "archives" || !isHexDigest(parts[1]) {
+			return nil
+		}
+		out, err := os.Create(filepath.Join(dir, parts[1]))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, r)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+
+	if err := readBackup(f, extract); err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%s is not an fm bundle: %s is missing", filepath.Base(archive), bundleManifestName)
+	}
+	return manifest, nil
+}
+
+// isHexDigest reports whether name is a SHA-256 in hex
+func isHexDigest(name string) bool {
+	if len(name) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
+
+// bundle is the contents of a bundle unpacked into dir
+type bundle struct {
+	dir   string
+	fonts map[string]BundledFont // By the config line they were bundled for
+}
+
+// install installs spec from its bundled download, which must match the
+// checksum recorded for it
+func (b *bundle) install(ctx context.Context, m *DefaultManager, spec *Font, o *installOptions) (*installResult, error) {
+	bundled, ok := b.fonts[FormatFontSpec(*spec)]
+	if !ok {
+		return nil, fmt.Errorf("font %q %w in bundle", spec.Name, ErrNotFound)
+	}
+
+	// The subsets were chosen when the bundle was made and are recorded in
+	// its metadata, which picks the same download
+	verified := *o
+	verified.sha256 = bundled.SHA256
+	verified.subsets, verified.unicodeRanges = nil, ""
+
+	font := Font{
+		Name:   bundled.Name,
+		Source: bundled.Source,
+		Ref:    bundled.Ref,
+		URL:    bundled.URL,
+		Meta:   bundled.Meta,
+	}
+	source := &bundledSource{path: filepath.Join(b.dir, bundled.SHA256), font: bundled}
+	return m.installFont(ctx, font, source, &verified)
+}
+
+// bundledSource serves the download of one font from an unpacked bundle in
+// place of the source it came from
+type bundledSource struct {
+	path string
+	font BundledFont
+}
+
+// Name is the name of the source the font came from, so its configured
+// defaults still apply
+func (s *bundledSource) Name() string {
+	if s.font.Source == "" {
+		return "bundle"
+	}
+	return s.font.Source
+}
+
+func (s *bundledSource) Search(_ context.Context, _ string) ([]Font, error) {
+	return nil, fmt.Errorf("bundles can't be searched")
+}
+
+func (s *bundledSource) Download(_ context.Context, font Font) (io.ReadCloser, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("bundle has no download of %s", font.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &downloadBody{ReadCloser: f, size: s.font.Size, url: s.font.DownloadURL}, nil
+}
+
+// Axes returns the axis ranges the font was bundled for, which an install
+// limits the variable build to again
+func (s *bundledSource) Axes(_ context.Context, font Font) ([]AxisRange, error) {
+	if s.font.Meta[axesMetaKey] == "" {
+		return nil, nil
+	}
+	return ParseAxes(s.font.Meta[axesMetaKey])
+}
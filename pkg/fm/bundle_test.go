@@ -0,0 +1,147 @@
+package fm_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bundles", func() {
+	var (
+		tempDir string
+		source  *mockSource
+		ctx     context.Context
+		fonts   []fm.Font
+	)
+
+	// Machines installing from a bundle have no sources to download from
+	offlineManager := func() *fm.DefaultManager {
+		fontDir := filepath.Join(tempDir, "offline")
+		Expect(os.MkdirAll(filepath.Join(fontDir, "user"), 0755)).To(Succeed())
+		return fm.NewManagerWithPlatform(&mockPlatform{fontDir: fontDir})
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "font-bundle-*")
+		Expect(err).NotTo(HaveOccurred())
+		source = newMockSource()
+		source.meta["TestFont1"] = map[string]string{"version": "v1.0.0"}
+		ctx = context.Background()
+
+		fonts, err = fm.ParseConfig(strings.NewReader("TestFont1@testsource >=v1.0\nTestOTF@testsource\n"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	createBundle := func(archive string) *fm.BundleManifest {
+		Expect(os.MkdirAll(filepath.Join(tempDir, "online", "user"), 0755)).To(Succeed())
+		online := fm.NewManagerWithPlatform(&mockPlatform{fontDir: filepath.Join(tempDir, "online")})
+		Expect(online.RegisterSource(source)).To(Succeed())
+		manifest, err := online.CreateBundle(ctx, fonts, archive)
+		Expect(err).NotTo(HaveOccurred())
+		return manifest
+	}
+
+	It("should install the fonts of a config without downloading them", func() {
+		archive := filepath.Join(tempDir, "fonts-bundle.tar.zst")
+		manifest := createBundle(archive)
+		Expect(manifest.Fonts).To(ConsistOf(
+			HaveField("Spec", "TestFont1@testsource >=v1.0"),
+			HaveField("Spec", "TestOTF@testsource"),
+		))
+		Expect(source.downloads).To(ConsistOf("TestFont1", "TestOTF"))
+
+		// Nothing was installed while bundling
+		Expect(os.ReadDir(filepath.Join(tempDir, "online", "user"))).To(BeEmpty())
+
+		manager := offlineManager()
+		_, results, err := manager.InstallBundle(ctx, archive)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(Equal([]error{nil, nil}))
+
+		installed, err := manager.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installed).To(ConsistOf(
+			And(HaveField("Name", "TestFont1"), HaveField("Source", "testsource"), HaveField("Meta", HaveKeyWithValue("version", "v1.0.0"))),
+			And(HaveField("Name", "TestOTF"), HaveField("Source", "testsource")),
+		))
+		Expect(source.downloads).To(HaveLen(2))
+
+		_, results, err = manager.InstallBundle(ctx, archive)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(HaveEach(MatchError(fm.ErrAlreadyInstalled)))
+	})
+
+	It("should refuse downloads that don't match their checksums", func() {
+		archive := filepath.Join(tempDir, "fonts-bundle.zip")
+		manifest := createBundle(archive)
+
+		// Rewrite the bundle with the download of TestOTF altered
+		reader, err := zip.OpenReader(archive)
+		Expect(err).NotTo(HaveOccurred())
+		buf := new(bytes.Buffer)
+		writer := zip.NewWriter(buf)
+		for _, file := range reader.File {
+			rc, err := file.Open()
+			Expect(err).NotTo(HaveOccurred())
+			data, err := io.ReadAll(rc)
+			Expect(err).NotTo(HaveOccurred())
+			rc.Close()
+			if file.Name == "archives/"+manifest.Fonts[1].SHA256 {
+				data, err = createTestZip(testFont{name: "TestOTF", format: "otf", content: "tampered"})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			w, err := writer.Create(file.Name)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = w.Write(data)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		reader.Close()
+		Expect(writer.Close()).To(Succeed())
+		Expect(os.WriteFile(archive, buf.Bytes(), 0644)).To(Succeed())
+
+		manager := offlineManager()
+		_, results, err := manager.InstallBundle(ctx, archive)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results[0]).NotTo(HaveOccurred())
+		Expect(results[1]).To(MatchError(fm.ErrChecksumMismatch))
+		Expect(results[1]).To(MatchError(ContainSubstring("bundle is corrupted")))
+
+		installed, err := manager.IsInstalled(ctx, "TestOTF")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installed).To(BeFalse())
+	})
+
+	It("should not write a bundle when a font can't be downloaded", func() {
+		fonts = append(fonts, fm.Font{Name: "Missing", Source: "testsource"})
+		online := fm.NewManagerWithPlatform(&mockPlatform{fontDir: filepath.Join(tempDir, "online")})
+		Expect(online.RegisterSource(source)).To(Succeed())
+
+		archive := filepath.Join(tempDir, "fonts-bundle.tar.zst")
+		_, err := online.CreateBundle(ctx, fonts, archive)
+		Expect(err).To(MatchError(fm.ErrNotFound))
+		Expect(archive).NotTo(BeAnExistingFile())
+	})
+
+	It("should reject archives that aren't bundles", func() {
+		archive := filepath.Join(tempDir, "fonts.zip")
+		content, err := createTestZip(testFont{name: "Other", format: "ttf", content: "other"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(archive, content, 0644)).To(Succeed())
+
+		_, _, err = offlineManager().InstallBundle(ctx, archive)
+		Expect(err).To(MatchError(ContainSubstring("not an fm bundle")))
+	})
+})
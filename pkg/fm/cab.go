@@ -0,0 +1,260 @@
+package fm
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Microsoft Cabinet support, enough for the font packages Microsoft has
+// shipped: single-cabinet archives using no compression or MSZIP, either
+// standalone or embedded in a self-extracting executable.
+
+var cabSignature = []byte("MSCF")
+
+const (
+	cabHeaderSize = 36
+
+	cabFlagPrevCabinet    = 0x0001
+	cabFlagNextCabinet    = 0x0002
+	cabFlagReservePresent = 0x0004
+
+	cabCompressNone  = 0
+	cabCompressMSZIP = 1
+
+	// MSZIP blocks carry at most this much history between them
+	mszipWindow = 32 * 1024
+)
+
+var errInvalidCab = errors.New("invalid cabinet")
+
+func init() {
+	RegisterExtractor(isCab, extractCab)
+}
+
+// isCab matches a cabinet or an executable with one embedded
+func isCab(data []byte) bool {
+	if bytes.HasPrefix(data, cabSignature) {
+		return true
+	}
+	return bytes.HasPrefix(data, []byte("MZ")) && findCab(data) >= 0
+}
+
+// findCab returns the offset of the first plausible cabinet header in data
+func findCab(data []byte) int {
+	for offset := 0; ; {
+		i := bytes.Index(data[offset:], cabSignature)
+		if i < 0 {
+			return -1
+		}
+		offset += i
+
+		header := data[offset:]
+		if len(header) >= cabHeaderSize &&
+			binary.LittleEndian.Uint32(header[4:]) == 0 &&
+			int(binary.LittleEndian.Uint32(header[8:])) <= len(header) {
+			return offset
+		}
+		offset++
+	}
+}
+
+type cabFolder struct {
+	dataOffset uint32
+	blocks     int
+	compress   uint16
+
+	once sync.Once
+	data []byte
+	err  error
+}
+
+func extractCab(data []byte) ([]ArchiveEntry, error) {
+	start := findCab(data)
+	if start < 0 {
+		return nil, errInvalidCab
+	}
+	cab := data[start:]
+	r := &cabReader{data: cab}
+
+	r.seek(16)
+	filesOffset := r.u32()
+	r.seek(26)
+	folderCount := int(r.u16())
+	fileCount := int(r.u16())
+	flags := r.u16()
+	r.skip(4) // set ID and cabinet index
+
+	if flags&(cabFlagPrevCabinet|cabFlagNextCabinet) != 0 {
+		return nil, fmt.Errorf("%w: multi-part cabinets are not supported", errInvalidCab)
+	}
+
+	var folderReserve, dataReserve int
+	if flags&cabFlagReservePresent != 0 {
+		headerReserve := int(r.u16())
+		folderReserve = int(r.u8())
+		dataReserve = int(r.u8())
+		r.skip(headerReserve)
+	}
+
+	folders := make([]*cabFolder, folderCount)
+	for i := range folders {
+		folders[i] = &cabFolder{
+			dataOffset: r.u32(),
+			blocks:     int(r.u16()),
+			compress:   r.u16() & 0x000f,
+		}
+		r.skip(folderReserve)
+	}
+
+	r.seek(int(filesOffset))
+	var entries []ArchiveEntry
+	for i := 0; i < fileCount; i++ {
+		size := r.u32()
+		offset := r.u32()
+		folderIndex := int(r.u16())
+		r.skip(6) // date, time and attributes
+		name := r.cstring()
+
+		if r.err != nil {
+			return nil, r.err
+		}
+		if folderIndex >= len(folders) {
+			return nil, fmt.Errorf("%w: file %s refers to a missing folder", errInvalidCab, name)
+		}
+
+		folder := folders[folderIndex]
+		entries = append(entries, ArchiveEntry{
+			// Cabinets use Windows path separators
+			Name: string(bytes.ReplaceAll([]byte(name), []byte(`\`), []byte("/"))),
+			Size: int64(size),
+			Open: func() (io.ReadCloser, error) {
+				folder.once.Do(func() {
+					folder.data, folder.err = folder.decompress(cab, dataReserve)
+				})
+				if folder.err != nil {
+					return nil, folder.err
+				}
+				end := uint64(offset) + uint64(size)
+				if end > uint64(len(folder.data)) {
+					return nil, fmt.Errorf("%w: file %s extends past its folder", errInvalidCab, name)
+				}
+				return io.NopCloser(bytes.NewReader(folder.data[offset:end])), nil
+			},
+		})
+	}
+
+	return entries, r.err
+}
+
+// decompress returns the uncompressed contents of every data block in the
+// folder
+func (f *cabFolder) decompress(cab []byte, dataReserve int) ([]byte, error) {
+	if f.compress != cabCompressNone && f.compress != cabCompressMSZIP {
+		return nil, fmt.Errorf("%w: unsupported compression type %d", errInvalidCab, f.compress)
+	}
+
+	r := &cabReader{data: cab}
+	r.seek(int(f.dataOffset))
+
+	var out []byte
+	for i := 0; i < f.blocks; i++ {
+		r.skip(4) // checksum
+		compressedSize := int(r.u16())
+		uncompressedSize := int(r.u16())
+		r.skip(dataReserve)
+		block := r.bytes(compressedSize)
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		if f.compress == cabCompressNone {
+			out = append(out, block...)
+			continue
+		}
+
+		// Each MSZIP block is a deflate stream prefixed with "CK" that may
+		// refer back to the previous block's output
+		if !bytes.HasPrefix(block, []byte("CK")) {
+			return nil, fmt.Errorf("%w: bad MSZIP block", errInvalidCab)
+		}
+		dict := out
+		if len(dict) > mszipWindow {
+			dict = dict[len(dict)-mszipWindow:]
+		}
+		inflater := flate.NewReaderDict(bytes.NewReader(block[2:]), dict)
+		chunk := make([]byte, uncompressedSize)
+		if _, err := io.ReadFull(inflater, chunk); err != nil {
+			return nil, fmt.Errorf("%w: decompressing block: %v", errInvalidCab, err)
+		}
+		inflater.Close()
+		out = append(out, chunk...)
+	}
+	return out, nil
+}
+
+// cabReader reads little-endian fields, remembering the first overrun
+type cabReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *cabReader) seek(pos int) {
+	r.pos = pos
+}
+
+func (r *cabReader) skip(n int) {
+	r.pos += n
+}
+
+func (r *cabReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || r.pos+n > len(r.data) {
+		if r.err == nil {
+			r.err = fmt.Errorf("%w: unexpected end of data", errInvalidCab)
+		}
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *cabReader) u8() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *cabReader) u16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *cabReader) u32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *cabReader) cstring() string {
+	if r.err != nil || r.pos > len(r.data) {
+		return ""
+	}
+	end := bytes.IndexByte(r.data[r.pos:], 0)
+	if end < 0 {
+		r.err = fmt.Errorf("%w: unterminated file name", errInvalidCab)
+		return ""
+	}
+	s := string(r.data[r.pos : r.pos+end])
+	r.pos += end + 1
+	return s
+}
@@ -0,0 +1,384 @@
+package fm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCacheTTL is how long cached source responses are considered fresh
+const DefaultCacheTTL = 6 * time.Hour
+
+// Cache stores source catalog and search responses on disk so repeated
+// lookups don't hit remote APIs, and downloaded archives so reinstalls don't
+// download them again. A nil *Cache caches nothing.
+type Cache struct {
+	dir     string
+	ttl     time.Duration
+	refresh bool
+}
+
+func NewCache(dir string, ttl time.Duration) *Cache {
+	return &Cache{
+		dir: dir,
+		ttl: ttl,
+	}
+}
+
+// CacheDirEnv names the environment variable that replaces the default cache
+// directory
+const CacheDirEnv = "FM_CACHE_DIR"
+
+// DefaultCacheDir returns the per-user cache directory for fm, or the one
+// CacheDirEnv names
+func DefaultCacheDir() (string, error) {
+	if dir := os.Getenv(CacheDirEnv); dir != "" {
+		return dir, nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("getting user cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "fm"), nil
+}
+
+// Dir returns the root directory of the cache
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// SetRefresh makes Get ignore cached entries so fresh responses are fetched
+// and stored
+func (c *Cache) SetRefresh(refresh bool) {
+	c.refresh = refresh
+}
+
+// Get returns the cached data for key if it is younger than the TTL
+func (c *Cache) Get(key string) ([]byte, bool) {
+	if c == nil || c.refresh {
+		return nil, false
+	}
+	return c.load(key, c.ttl)
+}
+
+// GetStale returns the cached data for key regardless of its age, for use
+// when the remote service can't be reached
+func (c *Cache) GetStale(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	return c.load(key, 0)
+}
+
+func (c *Cache) load(key string, ttl time.Duration) ([]byte, bool) {
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if ttl > 0 && time.Since(info.ModTime()) > ttl {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Updated returns when the entry for key was last stored, and whether it is
+// still younger than the TTL
+func (c *Cache) Updated(key string) (updated time.Time, fresh bool, ok bool) {
+	if c == nil {
+		return time.Time{}, false, false
+	}
+	info, err := os.Stat(c.path(key))
+	if err != nil {
+		return time.Time{}, false, false
+	}
+	updated = info.ModTime()
+	return updated, c.ttl <= 0 || time.Since(updated) <= c.ttl, true
+}
+
+// Put stores data under key
+func (c *Cache) Put(key string, data []byte) error {
+	if c == nil {
+		return nil
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	// Write to a temporary file of its own first so readers never see a
+	// partial entry, even with other writers of the same entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), "*.tmp")
+	if err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if err := errors.Join(err, tmp.Close()); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	return nil
+}
+
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, cacheIndexDir, hex.EncodeToString(sum[:]))
+}
+
+// Cache sections, each a directory under the cache root
+const (
+	cacheIndexDir     = "index"     // Catalog and search responses
+	cacheDownloadsDir = "downloads" // Downloaded font archives
+)
+
+// downloadURLSuffix names the file next to a cached download that records
+// the URL it came from
+const downloadURLSuffix = ".url"
+
+// CacheUsage is the number and total size of entries in part of the cache
+type CacheUsage struct {
+	Entries int
+	Size    int64
+}
+
+// CacheStats describes what the cache holds
+type CacheStats struct {
+	Dir       string
+	Index     CacheUsage // Catalog and search responses
+	Downloads CacheUsage // Font archives kept for reinstalls
+}
+
+// Stats counts the entries in the cache
+func (c *Cache) Stats() (CacheStats, error) {
+	stats := CacheStats{Dir: c.dir}
+	var err error
+	if stats.Index, err = c.walk(cacheIndexDir, time.Time{}, false); err != nil {
+		return stats, err
+	}
+	if stats.Downloads, err = c.walk(cacheDownloadsDir, time.Time{}, false); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// Clean removes the entries last used before the given time, or every entry
+// when it is zero, and reports what was removed
+func (c *Cache) Clean(before time.Time) (CacheStats, error) {
+	removed := CacheStats{Dir: c.dir}
+	var err error
+	if removed.Index, err = c.walk(cacheIndexDir, before, true); err != nil {
+		return removed, err
+	}
+	if removed.Downloads, err = c.walk(cacheDownloadsDir, before, true); err != nil {
+		return removed, err
+	}
+	return removed, nil
+}
+
+// walk totals the entries of a cache section last used before the given
+// time, or all of them when it is zero, removing them if asked to
+func (c *Cache) walk(section string, before time.Time, remove bool) (CacheUsage, error) {
+	var usage CacheUsage
+	entries, err := os.ReadDir(filepath.Join(c.dir, section))
+	if errors.Is(err, os.ErrNotExist) {
+		return usage, nil
+	}
+	if err != nil {
+		return usage, fmt.Errorf("reading cache: %w", err)
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if !before.IsZero() && !info.ModTime().Before(before) {
+			continue
+		}
+		// Sidecar files go with their entry and aren't counted on their own
+		sidecar := strings.HasSuffix(entry.Name(), downloadURLSuffix) || strings.HasSuffix(entry.Name(), digestSuffix)
+		if !sidecar && !strings.HasSuffix(entry.Name(), ".tmp") {
+			usage.Entries++
+		}
+		usage.Size += info.Size()
+		if remove {
+			path := filepath.Join(c.dir, section, entry.Name())
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return usage, fmt.Errorf("removing cache entry: %w", err)
+			}
+			if !sidecar {
+				os.Remove(path + downloadURLSuffix)
+				os.Remove(path + digestSuffix)
+			}
+		}
+	}
+	return usage, nil
+}
+
+// openDownload returns the cached archive for key and marks it used. The
+// digest recorded when it was stored is passed on unless the file changed
+// since.
+func (c *Cache) openDownload(key string) (*downloadBody, bool) {
+	if c == nil || c.refresh {
+		return nil, false
+	}
+	path := c.downloadPath(key)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, false
+	}
+
+	// Cleaning by age goes by last use, not by first download. Touching the
+	// file leaves its contents alone, so the digest is recorded again for
+	// the new modification time.
+	digest, _ := recordedDigest(path)
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	if digest != "" {
+		_ = recordDigest(path, digest)
+	}
+
+	url, _ := os.ReadFile(path + downloadURLSuffix)
+	return &downloadBody{ReadCloser: f, size: info.Size(), url: string(url), sha256: digest}, true
+}
+
+// recordDownload copies body into the cache as it is read. The entry only
+// appears once commit is called on the returned body, so failed or
+// rejected downloads are never served from the cache.
+func (c *Cache) recordDownload(key string, body io.ReadCloser) *cachingBody {
+	cb := &cachingBody{body: body, cache: c, key: key, hash: sha256.New()}
+	if c == nil {
+		return cb
+	}
+	path := c.downloadPath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return cb
+	}
+	if tmp, err := os.CreateTemp(filepath.Dir(path), "*.tmp"); err == nil {
+		cb.tmp = tmp
+	}
+	return cb
+}
+
+func (c *Cache) downloadPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, cacheDownloadsDir, hex.EncodeToString(sum[:]))
+}
+
+// cachingBody is a download being copied into the cache
+type cachingBody struct {
+	body  io.ReadCloser
+	cache *Cache
+	key   string
+	tmp   *os.File
+	hash  hash.Hash // Digest of the copy, recorded with it
+	err   error     // First error copying into the cache
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 && b.tmp != nil && b.err == nil {
+		_, b.err = b.tmp.Write(p[:n])
+		b.hash.Write(p[:n])
+	}
+	return n, err
+}
+
+// Size and URL pass on what the download announced
+func (b *cachingBody) Size() int64 {
+	if sized, ok := b.body.(interface{ Size() int64 }); ok {
+		return sized.Size()
+	}
+	return -1
+}
+
+func (b *cachingBody) URL() string {
+	if located, ok := b.body.(interface{ URL() string }); ok {
+		return located.URL()
+	}
+	return ""
+}
+
+// commit keeps the copied download in the cache. It must be called after
+// the body has been read to the end.
+func (b *cachingBody) commit() error {
+	if b.tmp == nil {
+		return nil
+	}
+	tmp := b.tmp
+	b.tmp = nil
+	if err := errors.Join(b.err, tmp.Close()); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("caching download: %w", err)
+	}
+
+	path := b.cache.downloadPath(b.key)
+	if url := b.URL(); url != "" {
+		if err := os.WriteFile(path+downloadURLSuffix, []byte(url), 0644); err != nil {
+			os.Remove(tmp.Name())
+			return fmt.Errorf("caching download: %w", err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("caching download: %w", err)
+	}
+	// Without a digest the download is simply hashed again when reused
+	_ = recordDigest(path, hex.EncodeToString(b.hash.Sum(nil)))
+	return nil
+}
+
+// Close closes the download, discarding the copy unless it was committed
+func (b *cachingBody) Close() error {
+	if b.tmp != nil {
+		b.tmp.Close()
+		os.Remove(b.tmp.Name())
+		b.tmp = nil
+	}
+	return b.body.Close()
+}
+
+// downloadCacheKey identifies the archive a source downloads for font. Only
+// fonts with a recorded version are cached, since an unversioned download
+// may change between installs.
+func downloadCacheKey(source string, font Font) (string, bool) {
+	if font.Meta["version"] == "" {
+		return "", false
+	}
+
+	keys := make([]string, 0, len(font.Meta))
+	for key := range font.Meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "download\x00%s\x00%s\x00%s\x00%s", source, font.Name, font.Ref, font.URL)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "\x00%s=%s", key, font.Meta[key])
+	}
+	return b.String(), true
+}
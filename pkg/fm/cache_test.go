@@ -0,0 +1,151 @@
+package fm_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cache", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "cache-test-*")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	It("should return fresh entries", func() {
+		cache := fm.NewCache(tempDir, time.Hour)
+		Expect(cache.Put("https://example.com/fonts", []byte(`["a"]`))).To(Succeed())
+
+		data, ok := cache.Get("https://example.com/fonts")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal(`["a"]`))
+	})
+
+	It("should store the same entry from several writers at once", func() {
+		cache := fm.NewCache(tempDir, time.Hour)
+		value := bytes.Repeat([]byte("value"), 100_000)
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer GinkgoRecover()
+				for range 20 {
+					Expect(cache.Put("key", value)).To(Succeed())
+				}
+			}()
+		}
+		wg.Wait()
+
+		data, ok := cache.Get("key")
+		Expect(ok).To(BeTrue())
+		Expect(data).To(Equal(value))
+		Expect(filepath.Glob(filepath.Join(tempDir, "*", "*.tmp"))).To(BeEmpty())
+	})
+
+	It("should expire entries older than the TTL", func() {
+		cache := fm.NewCache(tempDir, time.Minute)
+		Expect(cache.Put("key", []byte("value"))).To(Succeed())
+
+		// Age every entry past the TTL
+		old := time.Now().Add(-time.Hour)
+		Expect(filepath.Walk(tempDir, func(path string, _ os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Chtimes(path, old, old)
+		})).To(Succeed())
+
+		_, ok := cache.Get("key")
+		Expect(ok).To(BeFalse())
+
+		data, ok := cache.GetStale("key")
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal("value"))
+	})
+
+	It("should report when entries were stored and whether they are fresh", func() {
+		cache := fm.NewCache(tempDir, time.Minute)
+		_, _, ok := cache.Updated("key")
+		Expect(ok).To(BeFalse())
+
+		Expect(cache.Put("key", []byte("value"))).To(Succeed())
+		updated, fresh, ok := cache.Updated("key")
+		Expect(ok).To(BeTrue())
+		Expect(fresh).To(BeTrue())
+		Expect(updated).To(BeTemporally("~", time.Now(), 5*time.Second))
+
+		old := time.Now().Add(-time.Hour)
+		Expect(filepath.Walk(tempDir, func(path string, _ os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Chtimes(path, old, old)
+		})).To(Succeed())
+		_, fresh, ok = cache.Updated("key")
+		Expect(ok).To(BeTrue())
+		Expect(fresh).To(BeFalse())
+	})
+
+	It("should bypass entries when refreshing", func() {
+		cache := fm.NewCache(tempDir, time.Hour)
+		Expect(cache.Put("key", []byte("value"))).To(Succeed())
+		cache.SetRefresh(true)
+
+		_, ok := cache.Get("key")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should count and clean entries by last use", func() {
+		cache := fm.NewCache(tempDir, time.Hour)
+		Expect(cache.Put("old", []byte("old value"))).To(Succeed())
+		old := time.Now().Add(-48 * time.Hour)
+		Expect(filepath.Walk(tempDir, func(path string, _ os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Chtimes(path, old, old)
+		})).To(Succeed())
+		Expect(cache.Put("new", []byte("new"))).To(Succeed())
+
+		stats, err := cache.Stats()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats.Index).To(Equal(fm.CacheUsage{Entries: 2, Size: 12}))
+		Expect(stats.Downloads).To(Equal(fm.CacheUsage{}))
+
+		removed, err := cache.Clean(time.Now().Add(-24 * time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(removed.Index).To(Equal(fm.CacheUsage{Entries: 1, Size: 9}))
+		_, ok := cache.GetStale("old")
+		Expect(ok).To(BeFalse())
+		_, ok = cache.Get("new")
+		Expect(ok).To(BeTrue())
+
+		removed, err = cache.Clean(time.Time{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(removed.Index.Entries).To(Equal(1))
+		stats, err = cache.Stats()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats.Index).To(Equal(fm.CacheUsage{}))
+	})
+
+	It("should treat a nil cache as always empty", func() {
+		var cache *fm.Cache
+		Expect(cache.Put("key", []byte("value"))).To(Succeed())
+		_, ok := cache.Get("key")
+		Expect(ok).To(BeFalse())
+	})
+})
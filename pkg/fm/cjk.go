@@ -0,0 +1,239 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// CJKRegions lists the regional subsets supported by the CJK source
+var CJKRegions = []string{"SC", "TC", "JP", "KR"}
+
+// cjkFamily describes a pan-CJK family published as region-specific subsets
+type cjkFamily struct {
+	name      string            // Display name of the family
+	repo      string            // GitHub repository publishing the releases
+	tagPrefix string            // Release tag prefix when a repo ships several families
+	asset     string            // Asset name stem, completed by the region suffix
+	regions   map[string]string // Region -> asset suffix
+}
+
+var cjkFamilies = []cjkFamily{
+	{
+		name:      "Noto Sans CJK",
+		repo:      "notofonts/noto-cjk",
+		tagPrefix: "Sans",
+		asset:     "NotoSans",
+		regions:   map[string]string{"SC": "SC", "TC": "TC", "JP": "JP", "KR": "KR"},
+	},
+	{
+		name:      "Noto Serif CJK",
+		repo:      "notofonts/noto-cjk",
+		tagPrefix: "Serif",
+		asset:     "NotoSerif",
+		regions:   map[string]string{"SC": "SC", "TC": "TC", "JP": "JP", "KR": "KR"},
+	},
+	{
+		name:    "Source Han Sans",
+		repo:    "adobe-fonts/source-han-sans",
+		asset:   "SourceHanSans",
+		regions: map[string]string{"SC": "SC", "TC": "TC", "JP": "J", "KR": "K"},
+	},
+	{
+		name:    "Source Han Serif",
+		repo:    "adobe-fonts/source-han-serif",
+		asset:   "SourceHanSerif",
+		regions: map[string]string{"SC": "SC", "TC": "TC", "JP": "J", "KR": "K"},
+	},
+}
+
+// CJKSource installs the region-specific subset OTFs of the Noto CJK and
+// Source Han families instead of the multi-gigabyte super OTC
+type CJKSource struct {
+	client *http.Client
+	cache  *Cache
+	logger *slog.Logger
+}
+
+func NewCJKSource(opts ...SourceOption) *CJKSource {
+	o := newSourceOptions(opts)
+	return &CJKSource{
+		client: o.client,
+		cache:  o.cache,
+		logger: o.logger,
+	}
+}
+
+func (s *CJKSource) Name() string {
+	return "cjk"
+}
+
+func (s *CJKSource) Search(ctx context.Context, name string) ([]Font, error) {
+	family := findCJKFamily(name)
+	if family == nil {
+		return nil, nil
+	}
+
+	meta := map[string]string{"region": defaultCJKRegion()}
+	if release, err := s.getLatestRelease(ctx, family); err == nil {
+		meta["version"] = release.TagName
+	}
+
+	return []Font{{
+		Name:   family.name,
+		Source: s.Name(),
+		Meta:   meta,
+	}}, nil
+}
+
+// ParseVersion parses Adobe-style release tags such as "Sans2.004R", whose
+// minor part is a decimal fraction: 2.1 is 2.100 and newer than 2.004
+func (s *CJKSource) ParseVersion(tag string) (Version, error) {
+	start := strings.IndexAny(tag, "0123456789")
+	if start < 0 {
+		return Version{}, fmt.Errorf("invalid version %q", tag)
+	}
+
+	major, minor, _ := strings.Cut(tag[start:], ".")
+	if end := strings.IndexFunc(minor, func(r rune) bool { return r < '0' || r > '9' }); end >= 0 {
+		minor = minor[:end]
+	}
+	for len(minor) < 3 {
+		minor += "0"
+	}
+
+	v, err := ParseVersion(major + "." + minor)
+	if err != nil {
+		return Version{}, fmt.Errorf("invalid version %q", tag)
+	}
+	v.raw = tag
+	return v, nil
+}
+
+type githubAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+type githubRelease struct {
+	TagName string        `json:"tag_name"`
+	Assets  []githubAsset `json:"assets"`
+}
+
+func (s *CJKSource) Download(ctx context.Context, font Font) (io.ReadCloser, error) {
+	downloadURL, err := s.DownloadURL(ctx, font)
+	if err != nil {
+		return nil, err
+	}
+	return openURL(ctx, s.client, downloadURL)
+}
+
+// DownloadURL returns the release asset of font's region from the family's
+// latest release
+func (s *CJKSource) DownloadURL(ctx context.Context, font Font) (string, error) {
+	family := findCJKFamily(font.Name)
+	if family == nil {
+		return "", fmt.Errorf("font %w: %s", ErrNotFound, font.Name)
+	}
+
+	region := strings.ToUpper(font.Meta["region"])
+	if region == "" {
+		region = defaultCJKRegion()
+	}
+	suffix, ok := family.regions[region]
+	if !ok {
+		return "", fmt.Errorf("unsupported region %q (expected one of %s)", region, strings.Join(CJKRegions, ", "))
+	}
+
+	release, err := s.getLatestRelease(ctx, family)
+	if err != nil {
+		return "", fmt.Errorf("getting latest release: %w", err)
+	}
+
+	assetName := family.asset + suffix + ".zip"
+	var downloadURL string
+	for _, asset := range release.Assets {
+		// Release assets carry a numeric ordering prefix, e.g. "11_SourceHanSansSC.zip"
+		if asset.Name == assetName || strings.HasSuffix(asset.Name, "_"+assetName) {
+			downloadURL = asset.BrowserDownloadURL
+			break
+		}
+	}
+	if downloadURL == "" {
+		return "", fmt.Errorf("no %s subset found in release %s", region, release.TagName)
+	}
+	return downloadURL, nil
+}
+
+func (s *CJKSource) getLatestRelease(ctx context.Context, family *cjkFamily) (*githubRelease, error) {
+	var releases []githubRelease
+	url := fmt.Sprintf("https://api.github.com/repos/%s/releases", family.repo)
+	if err := getJSON(ctx, s.client, s.cache, s.logger, url, &releases); err != nil {
+		return nil, fmt.Errorf("fetching releases: %w", err)
+	}
+
+	// Releases are returned newest first; noto-cjk publishes Sans and Serif
+	// from the same repository, so pick the newest one for this family
+	for i := range releases {
+		if strings.HasPrefix(releases[i].TagName, family.tagPrefix) {
+			return &releases[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no release found for %s", family.name)
+}
+
+// Describe reports the source; releases are cached per family
+func (s *CJKSource) Describe() SourceInfo {
+	return describeSource("cjk", s.cache, "")
+}
+
+// CheckHealth probes the GitHub releases API of the first family's repository
+func (s *CJKSource) CheckHealth(ctx context.Context) SourceHealth {
+	return checkEndpoint(ctx, s.client, fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=1", cjkFamilies[0].repo))
+}
+
+func findCJKFamily(name string) *cjkFamily {
+	key := normalizeCJKName(name)
+	for i := range cjkFamilies {
+		if normalizeCJKName(cjkFamilies[i].name) == key {
+			return &cjkFamilies[i]
+		}
+	}
+	return nil
+}
+
+func normalizeCJKName(name string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(normalizeName(name))
+}
+
+// defaultCJKRegion picks a region from the user's locale, falling back to JP
+func defaultCJKRegion() string {
+	locale := ""
+	for _, env := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale = os.Getenv(env); locale != "" {
+			break
+		}
+	}
+
+	// Strip the encoding and modifier, e.g. "zh_TW.UTF-8@latin" -> "zh_tw"
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	locale = strings.ToLower(locale)
+
+	switch {
+	case strings.HasPrefix(locale, "zh_tw"), strings.HasPrefix(locale, "zh_hk"), strings.HasPrefix(locale, "zh_mo"):
+		return "TC"
+	case strings.HasPrefix(locale, "zh"):
+		return "SC"
+	case strings.HasPrefix(locale, "ko"):
+		return "KR"
+	default:
+		return "JP"
+	}
+}
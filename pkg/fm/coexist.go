@@ -0,0 +1,100 @@
+package fm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrFamilyConflict is returned when a list of fonts asks for the same
+// family from more than one source without saying both are wanted
+var ErrFamilyConflict = errors.New("family requested from more than one source")
+
+// checkCoexistence reports fonts of a list that are the same family from
+// different sources, such as FiraCode from fontsource and from nerdfonts.
+// Both builds are only installed under distinct names, and only when one of
+// them chose its name with install_as or sets allow_coexist=true.
+func checkCoexistence(fonts []Font) error {
+	var errs []error
+	byFamily := make(map[string][]Font)
+	var families []string
+	for _, font := range fonts {
+		if value, ok := font.Options["allow_coexist"]; ok {
+			if _, err := strconv.ParseBool(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: allow_coexist must be true or false, not %q", font.Name, value))
+			}
+		}
+		if font.Source == "" || font.URL != "" {
+			continue
+		}
+
+		key := familyKey(font.Name)
+		if _, ok := byFamily[key]; !ok {
+			families = append(families, key)
+		}
+		byFamily[key] = append(byFamily[key], font)
+	}
+
+	for _, key := range families {
+		group := byFamily[key]
+		for i := 1; i < len(group); i++ {
+			for _, other := range group[:i] {
+				if err := coexistenceError(other, group[i]); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// coexistenceError explains why two fonts of the same family can't both be
+// installed, or returns nil if they can
+func coexistenceError(a, b Font) error {
+	if a.Source == b.Source {
+		return nil
+	}
+
+	both := fmt.Sprintf("%s is requested from both %s and %s", a.Name, a.Source, b.Source)
+	if installedKey(a.installName()) == installedKey(b.installName()) {
+		return fmt.Errorf("%w: %s, which would install into the same directory; give one of them a distinct install_as name",
+			ErrFamilyConflict, both)
+	}
+	if allowsCoexistence(a) || allowsCoexistence(b) || a.Options["install_as"] != "" || b.Options["install_as"] != "" {
+		return nil
+	}
+
+	difference := "the sources may ship different builds of the family"
+	if a.Source == "nerdfonts" || b.Source == "nerdfonts" {
+		difference = "the Nerd Fonts build is patched with icon glyphs and named differently from the upstream family"
+	}
+	return fmt.Errorf("%w: %s; %s, and both would be installed side by side. Set allow_coexist=true or a distinct install_as name on one of them to install both, or list only one",
+		ErrFamilyConflict, both, difference)
+}
+
+// allowsCoexistence reports whether font sets allow_coexist=true
+func allowsCoexistence(font Font) bool {
+	allowed, _ := strconv.ParseBool(font.Options["allow_coexist"])
+	return allowed
+}
+
+// familyKey maps a font name to its family for spotting the same family
+// under different spellings, e.g. "FiraCode", "Fira Code" and "fira-code",
+// including the Nerd Fonts names of a patched family such as "FiraCode NF"
+func familyKey(name string) string {
+	key := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, stripAccents(normalizeName(name)))
+
+	for _, suffix := range []string{"nerdfontmono", "nerdfontpropo", "nerdfont", "nfm", "nfp", "nf"} {
+		if trimmed, ok := strings.CutSuffix(key, suffix); ok && trimmed != "" {
+			return trimmed
+		}
+	}
+	return key
+}
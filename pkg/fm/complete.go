@@ -0,0 +1,68 @@
+package fm
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// CompleteInstallable returns the font names starting with prefix that can be
+// installed, from the alias index and the cached catalogs of the registered
+// sources. It makes no network requests, so sources whose catalog hasn't
+// been fetched yet add nothing. After "@" the registered sources are
+// completed instead.
+func (m *DefaultManager) CompleteInstallable(prefix string) []string {
+	if name, source, ok := strings.Cut(prefix, "@"); ok {
+		var specs []string
+		for _, s := range m.sources {
+			if strings.HasPrefix(s.Name(), source) {
+				specs = append(specs, name+"@"+s.Name())
+			}
+		}
+		return specs
+	}
+
+	var names []string
+	if m.aliases != nil {
+		names = append(names, m.aliases.CachedNames()...)
+	}
+	for _, source := range m.sources {
+		if catalog, ok := source.(CatalogSource); ok {
+			names = append(names, catalog.CachedNames()...)
+		}
+	}
+	return completions(names, prefix)
+}
+
+// CompleteInstalled returns the names starting with prefix of the installed
+// fonts that can be uninstalled
+func (m *DefaultManager) CompleteInstalled(ctx context.Context, prefix string) ([]string, error) {
+	fonts, err := m.removableFonts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, font := range fonts {
+		names = append(names, font.Name)
+	}
+	return completions(names, prefix), nil
+}
+
+// completions returns the names starting with prefix, ignoring case, sorted
+// and without duplicates
+func completions(names []string, prefix string) []string {
+	prefix = normalizeName(prefix)
+	seen := make(map[string]bool)
+	var matches []string
+	for _, name := range names {
+		key := normalizeName(name)
+		if seen[key] || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		seen[key] = true
+		matches = append(matches, name)
+	}
+	sort.Strings(matches)
+	return matches
+}
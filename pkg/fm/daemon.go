@@ -0,0 +1,393 @@
+package fm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDaemonRetryInterval is how long a daemon waits before syncing again
+// after a sync failed with nothing changed since, e.g. while offline
+const DefaultDaemonRetryInterval = 10 * time.Minute
+
+// Daemon sync triggers
+const (
+	TriggerStart  = "start"  // The daemon started
+	TriggerConfig = "config" // A config file changed
+	TriggerFonts  = "fonts"  // Font files changed outside the daemon
+	TriggerRetry  = "retry"  // The last sync failed
+)
+
+// DaemonOptions controls how a Daemon keeps the installed fonts in sync
+type DaemonOptions struct {
+	Interval      time.Duration // How long changes settle, or how often to check for them when they can't be watched
+	RetryInterval time.Duration // How soon to retry a failed sync
+	Sync          SyncOptions   // Passed to every sync
+	Install       []InstallOption
+}
+
+// DaemonSync is the outcome of one sync run by a Daemon
+type DaemonSync struct {
+	Time    time.Time    `json:"time"`
+	Trigger string       `json:"trigger"`          // One of the Trigger constants
+	Changes []SyncChange `json:"-"`                // What the sync did
+	Changed []string     `json:"changed"`          // Fonts installed, updated or removed
+	Failed  []string     `json:"failed,omitempty"` // Fonts that failed, with their error
+	Error   string       `json:"error,omitempty"`
+}
+
+// DaemonStatus describes a running Daemon
+type DaemonStatus struct {
+	Files    []string    `json:"files"`
+	Started  time.Time   `json:"started"`
+	Syncs    int         `json:"syncs"`
+	LastSync *DaemonSync `json:"last_sync,omitempty"`
+}
+
+// Daemon keeps the installed fonts in sync with config files, syncing again
+// whenever the files change or font files change outside it
+type Daemon struct {
+	manager *DefaultManager
+	files   []string
+	opts    DaemonOptions
+
+	mu     sync.Mutex
+	status DaemonStatus
+}
+
+// NewDaemon returns a daemon syncing the installed fonts with the merged
+// fonts of files
+func (m *DefaultManager) NewDaemon(files []string, opts DaemonOptions) *Daemon {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultWatchInterval
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = DefaultDaemonRetryInterval
+	}
+	return &Daemon{
+		manager: m,
+		files:   files,
+		opts:    opts,
+		status:  DaemonStatus{Files: files},
+	}
+}
+
+// Status returns what the daemon has done so far
+func (d *Daemon) Status() DaemonStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	status := d.status
+	status.Files = slices.Clone(status.Files)
+	return status
+}
+
+// Run syncs once, then syncs again whenever the config files or font
+// directories change, once the change has settled, so a file being written
+// or a font directory being synced by another tool triggers a single sync.
+// Changes are watched for, and a watched change has settled when nothing
+// changed for an interval. Where the files can't be watched they're checked
+// every interval instead, and a change has settled when two checks in a row
+// agree. Configs that fail to parse are reported and left alone until they
+// change again, so a typo never prunes fonts. onSync is called after every
+// sync. Run returns when ctx is done.
+func (d *Daemon) Run(ctx context.Context, onSync func(DaemonSync)) error {
+	d.mu.Lock()
+	d.status.Started = time.Now()
+	d.mu.Unlock()
+
+	synced := d.hashConfigs()
+	previousConfigs := synced
+	failed := d.sync(ctx, TriggerStart, onSync)
+
+	refreshed, err := d.manager.scanFontDirs()
+	if err != nil {
+		return err
+	}
+	previousFonts := refreshed
+
+	settle := time.NewTimer(d.opts.Interval)
+	settle.Stop()
+	var ticker *time.Ticker
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+	var poll <-chan time.Time
+	pollInstead := func(err error) {
+		d.manager.log().Info("can't watch for changes, checking every interval instead", "err", err)
+		ticker = time.NewTicker(d.opts.Interval)
+		poll = ticker.C
+	}
+
+	var events <-chan fsnotify.Event
+	var watchErrs <-chan error
+	watcher, err := d.watch()
+	if err != nil {
+		pollInstead(err)
+	} else {
+		defer watcher.Close()
+		events, watchErrs = watcher.Events, watcher.Errors
+	}
+
+	var retry <-chan time.Time
+	if failed {
+		retry = time.After(d.opts.RetryInterval)
+	}
+
+	// changed reports what changed since the last sync, once it settled
+	changed := func(watched bool) string {
+		configs := d.hashConfigs()
+		configsSettled := watched || bytes.Equal(configs, previousConfigs)
+		previousConfigs = configs
+
+		fonts, err := d.manager.scanFontDirs()
+		if err != nil {
+			// The directories may be mid-sync; try again later
+			if watched {
+				settle.Reset(d.opts.Interval)
+			}
+			return ""
+		}
+		fontsSettled := watched || diffFontDirs(previousFonts, fonts).Empty()
+		previousFonts = fonts
+
+		switch {
+		case configsSettled && !bytes.Equal(configs, synced):
+			return TriggerConfig
+		case fontsSettled && !diffFontDirs(refreshed, fonts).Empty():
+			return TriggerFonts
+		}
+		return ""
+	}
+
+	for {
+		var trigger string
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-events:
+			if d.relevant(event) {
+				settle.Reset(d.opts.Interval)
+			}
+		case <-watchErrs:
+			// Events were dropped, e.g. when too many came at once; a check
+			// finds what they were about
+			settle.Reset(d.opts.Interval)
+		case <-settle.C:
+			// Directories created since the last check are watched too
+			if events != nil {
+				if err := d.watchFontDirs(watcher); err != nil {
+					watcher.Close()
+					events, watchErrs = nil, nil
+					pollInstead(err)
+				}
+			}
+			trigger = changed(true)
+		case <-poll:
+			trigger = changed(false)
+		case <-retry:
+			trigger = TriggerRetry
+		}
+		if trigger == "" {
+			continue
+		}
+
+		synced = d.hashConfigs()
+		previousConfigs = synced
+		failed = d.sync(ctx, trigger, onSync)
+		if ctx.Err() != nil {
+			return nil
+		}
+		retry = nil
+		if failed {
+			retry = time.After(d.opts.RetryInterval)
+		}
+
+		// The sync's own changes don't trigger another one
+		if fonts, err := d.manager.scanFontDirs(); err == nil {
+			refreshed, previousFonts = fonts, fonts
+		}
+	}
+}
+
+// watch starts watching the config files and the font directories with
+// every directory below them
+func (d *Daemon) watch() (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// Dotfiles tools and editors often replace a file rather than write it,
+	// which a watch on the file itself would lose track of
+	for _, path := range d.files {
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	if err := d.watchFontDirs(watcher); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	return watcher, nil
+}
+
+// watchFontDirs watches the font directories and every directory below them
+// that isn't watched yet. Directories that don't exist or can't be read are
+// left out, as they are when scanning.
+func (d *Daemon) watchFontDirs(watcher *fsnotify.Watcher) error {
+	paths, err := d.manager.platform.GetFontPaths()
+	if err != nil {
+		return fmt.Errorf("getting font paths: %w", err)
+	}
+	watched := make(map[string]bool)
+	for _, path := range watcher.WatchList() {
+		watched[path] = true
+	}
+	for _, dir := range []string{paths.UserDir, paths.SystemDir} {
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if entry != nil && entry.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if !entry.IsDir() || watched[path] {
+				return nil
+			}
+			return watcher.Add(path)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relevant reports whether a watched event may change what the daemon syncs:
+// any change in the font directories, but only changes to the config files
+// themselves in their directories
+func (d *Daemon) relevant(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	if slices.Contains(d.files, event.Name) {
+		return true
+	}
+	dir := filepath.Dir(event.Name)
+	for _, path := range d.files {
+		if filepath.Dir(path) == dir {
+			return d.inFontDirs(dir)
+		}
+	}
+	return true
+}
+
+// inFontDirs reports whether dir is a font directory or below one
+func (d *Daemon) inFontDirs(dir string) bool {
+	paths, err := d.manager.platform.GetFontPaths()
+	if err != nil {
+		return true
+	}
+	for _, fontDir := range []string{paths.UserDir, paths.SystemDir} {
+		if dir == fontDir || isWithin(dir, fontDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// sync reconciles the installed fonts with the configs and records the
+// outcome, reporting whether installing or removing fonts failed. Configs
+// that don't parse aren't retried until they change.
+func (d *Daemon) sync(ctx context.Context, trigger string, onSync func(DaemonSync)) bool {
+	result := DaemonSync{Time: time.Now(), Trigger: trigger, Changed: []string{}}
+	retry := false
+	fonts, err := d.readConfigs()
+	if err == nil {
+		result.Changes, err = d.manager.Sync(ctx, fonts, d.opts.Sync, d.opts.Install...)
+		retry = err != nil
+	}
+	for _, change := range result.Changes {
+		switch {
+		case change.Err != nil:
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", change.Font.Name, change.Err))
+		case change.Action != SyncKeep:
+			result.Changed = append(result.Changed, change.Font.Name)
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	d.mu.Lock()
+	d.status.Syncs++
+	d.status.LastSync = &result
+	d.mu.Unlock()
+	if onSync != nil {
+		onSync(result)
+	}
+	return retry
+}
+
+// readConfigs parses and merges the config files, failing when any line of
+// them doesn't parse
+func (d *Daemon) readConfigs() ([]Font, error) {
+	var configs [][]Font
+	var errs []error
+	for _, path := range d.files {
+		fonts, err := ParseConfigFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		configs = append(configs, fonts)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return MergeConfigs(configs...), nil
+}
+
+// hashConfigs fingerprints the contents of the config files. Files that
+// can't be read count as empty, so they're picked up once they reappear.
+func (d *Daemon) hashConfigs() []byte {
+	h := sha256.New()
+	for _, path := range d.files {
+		data, _ := os.ReadFile(path)
+		sum := sha256.Sum256(data)
+		h.Write(sum[:])
+	}
+	return h.Sum(nil)
+}
+
+// DefaultDaemonSocket returns where fm daemon serves its status, under the
+// user's state directory
+func DefaultDaemonSocket() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "daemon.sock"), nil
+}
+
+// DefaultServeTokenPath returns where fm serve writes the token it generates
+// when none is given, under the user's state directory
+func DefaultServeTokenPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "serve.token"), nil
+}
@@ -0,0 +1,124 @@
+package fm_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Daemon", func() {
+	var (
+		tempDir string
+		config  string
+		manager *fm.DefaultManager
+		daemon  *fm.Daemon
+		cancel  context.CancelFunc
+		done    chan struct{}
+
+		mu    sync.Mutex
+		syncs []fm.DaemonSync
+	)
+
+	received := func() []fm.DaemonSync {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]fm.DaemonSync(nil), syncs...)
+	}
+
+	installed := func() []string {
+		fonts, err := manager.List(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, font := range fonts {
+			names = append(names, font.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "font-daemon-*")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(tempDir, "user"), 0755)).To(Succeed())
+		config = filepath.Join(tempDir, "fonts.txt")
+		Expect(os.WriteFile(config, []byte("TestFont1@testsource\n"), 0644)).To(Succeed())
+
+		manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir})
+		Expect(manager.RegisterSource(newMockSource())).To(Succeed())
+		daemon = manager.NewDaemon([]string{config}, fm.DaemonOptions{
+			Interval: 10 * time.Millisecond,
+			Sync:     fm.SyncOptions{Prune: true},
+		})
+		syncs = nil
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		done = make(chan struct{})
+		go func() {
+			defer close(done)
+			defer GinkgoRecover()
+			Expect(daemon.Run(ctx, func(result fm.DaemonSync) {
+				mu.Lock()
+				defer mu.Unlock()
+				syncs = append(syncs, result)
+			})).To(Succeed())
+		}()
+		Eventually(received).Should(HaveLen(1))
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(done).Should(BeClosed())
+		os.RemoveAll(tempDir)
+	})
+
+	It("should sync on start and whenever the config changes", func() {
+		Expect(received()[0]).To(And(HaveField("Trigger", fm.TriggerStart), HaveField("Changed", []string{"TestFont1"})))
+		Expect(installed()).To(ConsistOf("TestFont1"))
+
+		Expect(os.WriteFile(config, []byte("TestFont2@testsource\n"), 0644)).To(Succeed())
+		Eventually(received).Should(HaveLen(2))
+		Expect(received()[1]).To(And(HaveField("Trigger", fm.TriggerConfig), HaveField("Changed", ConsistOf("TestFont1", "TestFont2"))))
+		Expect(installed()).To(ConsistOf("TestFont2"))
+
+		status := daemon.Status()
+		Expect(status.Files).To(Equal([]string{config}))
+		Expect(status.Syncs).To(Equal(2))
+		Expect(status.LastSync.Trigger).To(Equal(fm.TriggerConfig))
+
+		// The sync's own installs don't trigger another one
+		Consistently(received, 100*time.Millisecond).Should(HaveLen(2))
+	})
+
+	It("should reinstall fonts removed outside the daemon", func() {
+		Expect(os.RemoveAll(filepath.Join(tempDir, "user", "TestFont1"))).To(Succeed())
+
+		Eventually(received).Should(HaveLen(2))
+		Expect(received()[1].Trigger).To(Equal(fm.TriggerFonts))
+		Expect(installed()).To(ConsistOf("TestFont1"))
+	})
+
+	It("should notice changes in font directories created after it started", func() {
+		later := filepath.Join(tempDir, "user", "Later")
+		Expect(os.Mkdir(later, 0755)).To(Succeed())
+		Consistently(received, 100*time.Millisecond).Should(HaveLen(1))
+
+		Expect(os.WriteFile(filepath.Join(later, "Later.ttf"), []byte("font"), 0644)).To(Succeed())
+		Eventually(received).Should(HaveLen(2))
+		Expect(received()[1].Trigger).To(Equal(fm.TriggerFonts))
+	})
+
+	It("should leave the fonts alone while the config doesn't parse", func() {
+		Expect(os.WriteFile(config, []byte("TestFont2@testsource >>v1\n"), 0644)).To(Succeed())
+
+		Eventually(received).Should(HaveLen(2))
+		Expect(received()[1].Error).NotTo(BeEmpty())
+		Expect(installed()).To(ConsistOf("TestFont1"))
+	})
+})
@@ -0,0 +1,448 @@
+package fm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/logandonley/font-manager/pkg/fontmeta"
+)
+
+// FontFace is a font file found while looking for duplicates
+type FontFace struct {
+	Path    string // Location of the file
+	Family  string // Family name from the font's name table
+	Style   string // Subfamily name, e.g. "Bold Italic"
+	Version string // Version string from the name table
+	SHA256  string // Hex-encoded checksum of the contents
+	Managed bool   // Whether the file belongs to a font fm installed
+}
+
+// DuplicateFace is a font face present in more than one font directory
+type DuplicateFace struct {
+	Keep      FontFace   // Copy the others are consolidated into
+	Copies    []FontFace // Byte-identical or same-version copies elsewhere
+	Identical bool       // Whether every copy is byte-identical to Keep
+}
+
+// FindDuplicates looks for font faces present more than once across the user
+// font directory and the legacy per-user directories, such as ~/.fonts next
+// to ~/.local/share/fonts. Copies count as duplicates when they are
+// byte-identical or carry the same family, style and version. The copy to
+// keep is the one fm installed, then one in the user font directory.
+// Duplicates within a single directory and between fonts fm installed are
+// left alone.
+func (m *DefaultManager) FindDuplicates(ctx context.Context) ([]DuplicateFace, error) {
+	paths, err := m.platform.GetFontPaths()
+	if err != nil {
+		return nil, fmt.Errorf("getting font paths: %w", err)
+	}
+
+	faces, err := scanFaces(paths.UserDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range paths.LegacyDirs {
+		// Some distributions link ~/.fonts to the user font directory
+		if sameFile(dir, paths.UserDir) {
+			continue
+		}
+		legacy, err := scanFaces(dir)
+		if err != nil {
+			return nil, err
+		}
+		faces = append(faces, legacy...)
+	}
+
+	// Group the faces by what they are; files whose names can't be read are
+	// only matched by content
+	groups := make(map[string][]FontFace)
+	var keys []string
+	for _, face := range faces {
+		key := "sha256:" + face.SHA256
+		if face.Family != "" {
+			key = normalizeName(face.Family) + "\x00" + normalizeName(face.Style)
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], face)
+	}
+	sort.Strings(keys)
+
+	var duplicates []DuplicateFace
+	for _, key := range keys {
+		if duplicate, ok := findDuplicate(groups[key], paths.UserDir); ok {
+			duplicates = append(duplicates, duplicate)
+		}
+	}
+	return duplicates, nil
+}
+
+// findDuplicate picks the copy of a face to keep and the copies that
+// duplicate it
+func findDuplicate(faces []FontFace, userDir string) (DuplicateFace, bool) {
+	sort.SliceStable(faces, func(i, j int) bool {
+		if faces[i].Managed != faces[j].Managed {
+			return faces[i].Managed
+		}
+		iUser, jUser := withinDir(faces[i].Path, userDir), withinDir(faces[j].Path, userDir)
+		if iUser != jUser {
+			return iUser
+		}
+		return faces[i].Path < faces[j].Path
+	})
+
+	duplicate := DuplicateFace{Keep: faces[0], Identical: true}
+	for _, face := range faces[1:] {
+		switch {
+		case face.Managed:
+			// Removing files from an installed font would break its manifest
+			continue
+		case filepath.Dir(face.Path) == filepath.Dir(duplicate.Keep.Path):
+			continue
+		case sameFile(face.Path, duplicate.Keep.Path):
+			// Already consolidated with a symlink
+			continue
+		case face.SHA256 == duplicate.Keep.SHA256:
+		case face.Version != "" && face.Version == duplicate.Keep.Version:
+			duplicate.Identical = false
+		default:
+			continue
+		}
+		duplicate.Copies = append(duplicate.Copies, face)
+	}
+	return duplicate, len(duplicate.Copies) > 0
+}
+
+// scanFaces reads every font file under dir, following the font directory
+// symlinks of the store layout
+func scanFaces(dir string) ([]FontFace, error) {
+	var faces []FontFace
+	var visit filepath.WalkFunc
+	visit = func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 && filepath.Dir(path) == filepath.Clean(dir) {
+			if target, err := os.Stat(path); err == nil && target.IsDir() {
+				return filepath.Walk(path+string(filepath.Separator), visit)
+			}
+		}
+		if info.IsDir() || !isFontFile(info.Name()) {
+			return nil
+		}
+
+		face, err := readFace(path)
+		if err != nil {
+			return err
+		}
+		faces = append(faces, face)
+		return nil
+	}
+
+	if err := filepath.Walk(dir, visit); err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", dir, err)
+	}
+	return faces, nil
+}
+
+// readFace hashes a font file and reads its names. Files that aren't valid
+// fonts are returned without names.
+func readFace(path string) (FontFace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FontFace{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	sum := sha256.Sum256(data)
+	face := FontFace{
+		Path:    filepath.Clean(path),
+		SHA256:  hex.EncodeToString(sum[:]),
+		Managed: isManagedDir(filepath.Dir(path)),
+	}
+
+	parsed, err := fontmeta.Parse(data)
+	if err != nil {
+		return face, nil
+	}
+	face.Family, face.Style, face.Version = parsed.Family, parsed.Style, parsed.Version
+	return face, nil
+}
+
+// isManagedDir reports whether dir is the directory of a font fm installed
+func isManagedDir(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".installed"))
+	return err == nil
+}
+
+// ConsolidateDuplicates removes the copies of each duplicate face, or replaces
+// them with symlinks to the kept copy when link is set. A kept copy outside
+// the user font directory is first moved into it. Emptied legacy directories
+// are removed and the font cache is refreshed once at the end.
+func (m *DefaultManager) ConsolidateDuplicates(ctx context.Context, duplicates []DuplicateFace, link bool) error {
+	paths, err := m.platform.GetFontPaths()
+	if err != nil {
+		return fmt.Errorf("getting font paths: %w", err)
+	}
+
+	var errs []error
+	changed := false
+	for _, duplicate := range duplicates {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		keep, err := m.adoptFace(duplicate.Keep, paths.UserDir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if keep != duplicate.Keep.Path {
+			changed = true
+			removeEmptyDirs(filepath.Dir(duplicate.Keep.Path), paths.LegacyDirs)
+		}
+
+		for _, dup := range duplicate.Copies {
+			if err := replaceCopy(dup.Path, keep, link); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			changed = true
+			if !link {
+				removeEmptyDirs(filepath.Dir(dup.Path), append([]string{paths.UserDir}, paths.LegacyDirs...))
+			}
+		}
+	}
+
+	if changed {
+		if err := m.UpdateCache(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DuplicateFont is a font fm installed whose every face is also part of
+// another installed font, such as one family installed from two sources
+type DuplicateFont struct {
+	Keep      Font // Font whose faces are kept
+	Duplicate Font // Font that can be uninstalled without losing a face
+	Identical bool // Whether every face is byte-identical to one in Keep
+}
+
+// FindDuplicateFonts looks for fonts fm installed whose faces are all part of
+// another installed font too, byte-identical or with the same family, style
+// and version. The font kept is the one with more faces, then the one
+// installed first. Pinned fonts are always kept, and fonts sharing only some
+// of their faces are left alone.
+func (m *DefaultManager) FindDuplicateFonts(ctx context.Context) ([]DuplicateFont, error) {
+	fonts, err := m.removableFonts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type fontFaces struct {
+		font     Font
+		faces    []FontFace
+		contents map[string]bool // Checksums of the faces
+		versions map[string]bool // Family, style and version of the faces
+	}
+	var candidates []fontFaces
+	for _, font := range fonts {
+		faces, err := scanFaces(font.Meta["directory"])
+		if err != nil {
+			return nil, err
+		}
+		if len(faces) == 0 {
+			continue
+		}
+		c := fontFaces{font: font, faces: faces, contents: make(map[string]bool), versions: make(map[string]bool)}
+		for _, face := range faces {
+			c.contents[face.SHA256] = true
+			if key, ok := faceVersion(face); ok {
+				c.versions[key] = true
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if IsPinned(a.font) != IsPinned(b.font) {
+			return IsPinned(a.font)
+		}
+		if len(a.faces) != len(b.faces) {
+			return len(a.faces) > len(b.faces)
+		}
+		if a.font.Meta["installed_at"] != b.font.Meta["installed_at"] {
+			return a.font.Meta["installed_at"] < b.font.Meta["installed_at"]
+		}
+		return a.font.Name < b.font.Name
+	})
+
+	var duplicates []DuplicateFont
+	var kept []fontFaces
+	for _, c := range candidates {
+		duplicate := false
+		for _, k := range kept {
+			if IsPinned(c.font) {
+				break
+			}
+			identical, covered := true, true
+			for _, face := range c.faces {
+				if k.contents[face.SHA256] {
+					continue
+				}
+				identical = false
+				if key, ok := faceVersion(face); !ok || !k.versions[key] {
+					covered = false
+					break
+				}
+			}
+			if covered {
+				duplicates = append(duplicates, DuplicateFont{Keep: k.font, Duplicate: c.font, Identical: identical})
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			kept = append(kept, c)
+		}
+	}
+	return duplicates, nil
+}
+
+// faceVersion identifies a face by its family, style and version, if its
+// names could be read
+func faceVersion(face FontFace) (string, bool) {
+	if face.Family == "" || face.Version == "" {
+		return "", false
+	}
+	return normalizeName(face.Family) + "\x00" + normalizeName(face.Style) + "\x00" + face.Version, true
+}
+
+// RemoveDuplicateFonts uninstalls the duplicate of each entry, carrying on
+// past failures, and refreshes the font cache once at the end
+func (m *DefaultManager) RemoveDuplicateFonts(ctx context.Context, duplicates []DuplicateFont) error {
+	fonts := make([]Font, len(duplicates))
+	for i, d := range duplicates {
+		fonts[i] = Font{Name: d.Duplicate.Name}
+	}
+
+	var errs []error
+	for i, err := range m.UninstallFonts(ctx, fonts) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("uninstalling %s: %w", fonts[i].Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// adoptFace moves a kept face that isn't in the user font directory into a
+// directory named after its family there, returning its new path
+func (m *DefaultManager) adoptFace(face FontFace, userDir string) (string, error) {
+	if withinDir(face.Path, userDir) {
+		return face.Path, nil
+	}
+
+	name := face.Family
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(face.Path), filepath.Ext(face.Path))
+	}
+	dir := filepath.Join(userDir, fontDirName(name))
+	dest := filepath.Join(dir, filepath.Base(face.Path))
+	if _, err := os.Lstat(dest); err == nil {
+		return "", fmt.Errorf("moving %s: %s already exists", face.Path, dest)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("creating font directory: %w", err)
+	}
+	if err := os.Rename(face.Path, dest); err != nil {
+		// The legacy directory may be on another file system
+		if err := copyFile(face.Path, dest); err != nil {
+			return "", fmt.Errorf("moving %s: %w", face.Path, err)
+		}
+		if err := os.Remove(face.Path); err != nil {
+			return "", fmt.Errorf("removing %s: %w", face.Path, err)
+		}
+	}
+	if err := m.chownToUser(dir); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+// replaceCopy removes a duplicate file or atomically swaps it for a symlink
+// to keep
+func replaceCopy(path, keep string, link bool) error {
+	if !link {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("removing %s: %w", path, err)
+		}
+		return nil
+	}
+
+	tmp := path + ".fm-link"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(keep, tmp); err != nil {
+		return fmt.Errorf("linking %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("linking %s: %w", path, err)
+	}
+	return nil
+}
+
+// removeEmptyDirs removes dir and its parents while they are empty, stopping
+// at any of roots
+func removeEmptyDirs(dir string, roots []string) {
+	for withinDir(dir, roots...) {
+		for _, root := range roots {
+			if filepath.Clean(dir) == filepath.Clean(root) {
+				return
+			}
+		}
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// withinDir reports whether path is inside any of dirs
+func withinDir(path string, dirs ...string) bool {
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameFile reports whether two paths resolve to the same existing file or
+// directory
+func sameFile(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
@@ -0,0 +1,222 @@
+package fm_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomedium"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+var _ = Describe("Deduplicating fonts across directories", func() {
+	var (
+		tempDir   string
+		userDir   string
+		legacyDir string
+		manager   *fm.DefaultManager
+		ctx       context.Context
+	)
+
+	writeFile := func(path string, data []byte) {
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, data, 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "font-dedupe-*")
+		Expect(err).NotTo(HaveOccurred())
+		userDir = filepath.Join(tempDir, "user")
+		legacyDir = filepath.Join(tempDir, "legacy")
+		Expect(os.MkdirAll(userDir, 0755)).To(Succeed())
+
+		buf := new(bytes.Buffer)
+		zipWriter := zip.NewWriter(buf)
+		for name, data := range map[string][]byte{
+			"GoMono-Regular.ttf": gomono.TTF,
+			"GoBold.ttf":         gobold.TTF,
+		} {
+			f, err := zipWriter.Create(name)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.Write(data)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(zipWriter.Close()).To(Succeed())
+
+		source := newMockSource()
+		source.fonts["GoMono"] = buf.Bytes()
+		manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir})
+		Expect(manager.RegisterSource(source)).To(Succeed())
+
+		ctx = context.Background()
+		Expect(manager.Install(ctx, "GoMono@testsource")).To(Succeed())
+
+		// A byte-identical copy, a copy of the same version with different
+		// bytes, an unrelated font and a family only found in legacy dirs
+		writeFile(filepath.Join(legacyDir, "GoMono-Regular.ttf"), gomono.TTF)
+		writeFile(filepath.Join(legacyDir, "old", "GoBold.ttf"), append(append([]byte{}, gobold.TTF...), 0, 0, 0, 0))
+		writeFile(filepath.Join(legacyDir, "Go-Regular.ttf"), goregular.TTF)
+		writeFile(filepath.Join(legacyDir, "a", "Go-Medium.ttf"), gomedium.TTF)
+		writeFile(filepath.Join(legacyDir, "b", "Go-Medium.ttf"), gomedium.TTF)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	It("should find identical and same-version copies and keep the installed one", func() {
+		duplicates, err := manager.FindDuplicates(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(duplicates).To(HaveLen(3))
+
+		byFile := make(map[string]fm.DuplicateFace)
+		for _, d := range duplicates {
+			byFile[filepath.Base(d.Keep.Path)] = d
+		}
+
+		mono := byFile["GoMono-Regular.ttf"]
+		Expect(mono.Keep.Managed).To(BeTrue())
+		Expect(mono.Keep.Family).To(Equal("Go Mono"))
+		Expect(mono.Identical).To(BeTrue())
+		Expect(mono.Copies).To(HaveLen(1))
+		Expect(mono.Copies[0].Path).To(Equal(filepath.Join(legacyDir, "GoMono-Regular.ttf")))
+
+		bold := byFile["GoBold.ttf"]
+		Expect(bold.Keep.Path).To(HavePrefix(userDir))
+		Expect(bold.Identical).To(BeFalse())
+		Expect(bold.Copies[0].Path).To(Equal(filepath.Join(legacyDir, "old", "GoBold.ttf")))
+
+		medium := byFile["Go-Medium.ttf"]
+		Expect(medium.Keep.Path).To(Equal(filepath.Join(legacyDir, "a", "Go-Medium.ttf")))
+		Expect(medium.Copies[0].Path).To(Equal(filepath.Join(legacyDir, "b", "Go-Medium.ttf")))
+	})
+
+	It("should remove copies and move legacy-only fonts into the user directory", func() {
+		duplicates, err := manager.FindDuplicates(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manager.ConsolidateDuplicates(ctx, duplicates, false)).To(Succeed())
+
+		Expect(filepath.Join(legacyDir, "GoMono-Regular.ttf")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(legacyDir, "old")).NotTo(BeADirectory())
+		Expect(filepath.Join(legacyDir, "a")).NotTo(BeADirectory())
+		Expect(filepath.Join(legacyDir, "b")).NotTo(BeADirectory())
+		Expect(filepath.Join(legacyDir, "Go-Regular.ttf")).To(BeAnExistingFile())
+
+		moved, err := os.ReadFile(filepath.Join(userDir, "Go-Medium", "Go-Medium.ttf"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(moved).To(Equal(gomedium.TTF))
+
+		// The installed font is untouched
+		fonts, err := manager.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fonts).To(ContainElement(HaveField("Name", "GoMono")))
+	})
+
+	It("should replace copies with symlinks when asked", func() {
+		duplicates, err := manager.FindDuplicates(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manager.ConsolidateDuplicates(ctx, duplicates, true)).To(Succeed())
+
+		target, err := os.Readlink(filepath.Join(legacyDir, "GoMono-Regular.ttf"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(target).To(Equal(filepath.Join(userDir, "GoMono", "GoMono-Regular.ttf")))
+
+		target, err = os.Readlink(filepath.Join(legacyDir, "b", "Go-Medium.ttf"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(target).To(Equal(filepath.Join(userDir, "Go-Medium", "Go-Medium.ttf")))
+
+		duplicates, err = manager.FindDuplicates(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(duplicates).To(BeEmpty())
+	})
+
+	It("should skip a legacy directory that links to the user directory", func() {
+		Expect(os.RemoveAll(legacyDir)).To(Succeed())
+		Expect(os.Symlink(userDir, legacyDir)).To(Succeed())
+
+		duplicates, err := manager.FindDuplicates(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(duplicates).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Deduplicating installed fonts", func() {
+	var (
+		tempDir string
+		manager *fm.DefaultManager
+		ctx     context.Context
+	)
+
+	archive := func(files map[string][]byte) []byte {
+		buf := new(bytes.Buffer)
+		zipWriter := zip.NewWriter(buf)
+		for name, data := range files {
+			f, err := zipWriter.Create(name)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.Write(data)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(zipWriter.Close()).To(Succeed())
+		return buf.Bytes()
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "font-dedupe-installed-*")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(tempDir, "user"), 0755)).To(Succeed())
+
+		// GoMono from two sources, one of them with only some of the faces
+		source := newMockSource()
+		source.fonts["GoMono"] = archive(map[string][]byte{"GoMono-Regular.ttf": gomono.TTF, "GoBold.ttf": gobold.TTF})
+		source.fonts["GoMonoRegular"] = archive(map[string][]byte{"GoMono.ttf": gomono.TTF})
+		source.fonts["GoMixed"] = archive(map[string][]byte{"GoBold.ttf": gobold.TTF, "Go-Regular.ttf": goregular.TTF})
+		manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir})
+		Expect(manager.RegisterSource(source)).To(Succeed())
+
+		ctx = context.Background()
+		Expect(manager.Install(ctx, "GoMono@testsource")).To(Succeed())
+		Expect(manager.Install(ctx, "GoMonoRegular@testsource")).To(Succeed())
+		Expect(manager.Install(ctx, "GoMixed@testsource")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	It("should find fonts whose every face is installed with another font", func() {
+		duplicates, err := manager.FindDuplicateFonts(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(duplicates).To(HaveExactElements(And(
+			HaveField("Keep.Name", "GoMono"),
+			HaveField("Duplicate.Name", "GoMonoRegular"),
+			HaveField("Identical", true),
+		)))
+	})
+
+	It("should keep pinned fonts", func() {
+		Expect(manager.Pin(ctx, "GoMonoRegular", true)).To(Succeed())
+
+		duplicates, err := manager.FindDuplicateFonts(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(duplicates).To(BeEmpty())
+	})
+
+	It("should uninstall the duplicates", func() {
+		duplicates, err := manager.FindDuplicateFonts(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manager.RemoveDuplicateFonts(ctx, duplicates)).To(Succeed())
+
+		fonts, err := manager.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fonts).To(ConsistOf(HaveField("Name", "GoMono"), HaveField("Name", "GoMixed")))
+	})
+})
@@ -0,0 +1,59 @@
+package fm
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Hashing a large file every time it is verified or reinstalled is slow, so
+// the SHA-256 fm computes for a file is recorded with it, along with the size
+// and modification time it had. The digest is trusted for as long as both
+// are unchanged, since rewriting the file updates its modification time.
+// Installed files record theirs in the font's manifest and cached downloads
+// in a sidecar file.
+
+// digestSuffix names the file next to a cached download that records its
+// SHA-256
+const digestSuffix = ".sha256"
+
+// unchanged reports whether the file described by info still has the size
+// and modification time f was recorded with
+func (f InstalledFile) unchanged(info os.FileInfo) bool {
+	return !f.ModTime.IsZero() && info.Size() == f.Size && info.ModTime().Equal(f.ModTime)
+}
+
+// recordDigest records sum as the digest of the file at path as it is now
+func recordDigest(path, sum string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(InstalledFile{
+		Name:    info.Name(),
+		Size:    info.Size(),
+		SHA256:  sum,
+		ModTime: info.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+digestSuffix, data, 0644)
+}
+
+// recordedDigest returns the digest recorded for the file at path, unless
+// the file changed since
+func recordedDigest(path string) (string, bool) {
+	data, err := os.ReadFile(path + digestSuffix)
+	if err != nil {
+		return "", false
+	}
+	var recorded InstalledFile
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return "", false
+	}
+	info, err := os.Stat(path)
+	if err != nil || !recorded.unchanged(info) {
+		return "", false
+	}
+	return recorded.SHA256, true
+}
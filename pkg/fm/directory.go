@@ -0,0 +1,206 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DirectorySource finds font archives in a plain HTTP(S) directory listing,
+// such as those generated by nginx autoindex or Apache mod_autoindex
+type DirectorySource struct {
+	name   string
+	url    string
+	client *http.Client
+	cache  *Cache
+	logger *slog.Logger
+}
+
+// NewDirectorySource creates a source named name that serves the archives
+// listed at dirURL
+func NewDirectorySource(name, dirURL string, opts ...SourceOption) (*DirectorySource, error) {
+	if name == "" {
+		return nil, fmt.Errorf("source name is required")
+	}
+
+	u, err := url.Parse(dirURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid directory url %q", dirURL)
+	}
+
+	// Relative links in the listing resolve against the directory itself
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	o := newSourceOptions(opts)
+	return &DirectorySource{
+		name:   name,
+		url:    u.String(),
+		client: o.client,
+		cache:  o.cache,
+		logger: o.logger,
+	}, nil
+}
+
+func (s *DirectorySource) Name() string {
+	return s.name
+}
+
+// archiveExtensions are the listing entries considered font archives
+var archiveExtensions = []string{".zip", ".tar.gz", ".tgz"}
+
+var hrefPattern = regexp.MustCompile(`(?i)href\s*=\s*["']([^"']+)["']`)
+
+// listingEntry is an archive found in the directory listing
+type listingEntry struct {
+	stem string // File name without the archive extension
+	url  string
+}
+
+func (s *DirectorySource) Search(ctx context.Context, name string) ([]Font, error) {
+	entries, err := s.listing(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading directory listing: %w", err)
+	}
+
+	// Prefer an exact match, then versioned archives such as FontName-2.1.zip,
+	// newest first
+	key := listingKey(name)
+	var exact, versioned []listingEntry
+	for _, entry := range entries {
+		stemKey := listingKey(entry.stem)
+		switch {
+		case stemKey == key:
+			exact = append(exact, entry)
+		case strings.HasPrefix(stemKey, key) && isVersionSuffix(stemKey[len(key):]):
+			versioned = append(versioned, entry)
+		}
+	}
+	sort.Slice(versioned, func(i, j int) bool {
+		return versioned[i].stem > versioned[j].stem
+	})
+
+	var results []Font
+	for _, entry := range append(exact, versioned...) {
+		results = append(results, Font{
+			Name:   name,
+			Source: s.Name(),
+			URL:    entry.url,
+			Meta:   map[string]string{"file": entry.stem},
+		})
+	}
+
+	return results, nil
+}
+
+// listing fetches the directory index and returns the archives it links to
+func (s *DirectorySource) listing(ctx context.Context) ([]listingEntry, error) {
+	base, err := url.Parse(s.url)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []listingEntry
+	err = getCached(ctx, s.client, s.cache, s.logger, s.url, func(data []byte) error {
+		entries = parseListing(base, data)
+		return nil
+	})
+	return entries, err
+}
+
+// parseListing returns the archives a directory index at base links to
+func parseListing(base *url.URL, data []byte) []listingEntry {
+	var entries []listingEntry
+	for _, match := range hrefPattern.FindAllSubmatch(data, -1) {
+		ref, err := url.Parse(string(match[1]))
+		if err != nil {
+			continue
+		}
+
+		target := base.ResolveReference(ref)
+		file, err := url.PathUnescape(path.Base(target.Path))
+		if err != nil {
+			continue
+		}
+		if stem, ok := trimArchiveExtension(file); ok {
+			entries = append(entries, listingEntry{stem: stem, url: target.String()})
+		}
+	}
+	return entries
+}
+
+// CachedNames lists the archives in the cached directory listing, however
+// old
+func (s *DirectorySource) CachedNames() []string {
+	base, err := url.Parse(s.url)
+	if err != nil {
+		return nil
+	}
+	data, ok := s.cache.GetStale(s.url)
+	if !ok {
+		return nil
+	}
+
+	var names []string
+	for _, entry := range parseListing(base, data) {
+		names = append(names, entry.stem)
+	}
+	return names
+}
+
+func trimArchiveExtension(file string) (string, bool) {
+	lower := strings.ToLower(file)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) && len(file) > len(ext) {
+			return file[:len(file)-len(ext)], true
+		}
+	}
+	return "", false
+}
+
+// listingKey drops separators so "Fira Code" matches FiraCode.zip and
+// fira-code.zip
+func listingKey(name string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "", ".", "").Replace(normalizeName(name))
+}
+
+// isVersionSuffix reports whether the remainder of an archive name after the
+// font name looks like a version, e.g. "2.1" or "v3"
+func isVersionSuffix(rest string) bool {
+	rest = strings.TrimPrefix(rest, "v")
+	return rest != "" && rest[0] >= '0' && rest[0] <= '9'
+}
+
+func (s *DirectorySource) Download(ctx context.Context, font Font) (io.ReadCloser, error) {
+	downloadURL := font.URL
+	if downloadURL == "" {
+		fonts, err := s.Search(ctx, font.Name)
+		if err != nil {
+			return nil, err
+		}
+		if len(fonts) == 0 {
+			return nil, fmt.Errorf("font %w: %s", ErrNotFound, font.Name)
+		}
+		downloadURL = fonts[0].URL
+	}
+
+	return openURL(ctx, s.client, downloadURL)
+}
+
+// Describe reports the source and the cache state of its listing
+func (s *DirectorySource) Describe() SourceInfo {
+	return describeSource("directory", s.cache, s.url)
+}
+
+// CheckHealth probes the directory listing
+func (s *DirectorySource) CheckHealth(ctx context.Context) SourceHealth {
+	return checkEndpoint(ctx, s.client, s.url)
+}
@@ -0,0 +1,137 @@
+package fm
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/logandonley/font-manager/pkg/fontmeta"
+)
+
+//go:embed equivalents.json
+var embeddedEquivalents []byte
+
+// Equivalent records that a font from one source is a modified build of a
+// font another source publishes unmodified, such as a Nerd Fonts patched
+// font and the upstream family it was patched from. The shipped mapping
+// lists them as "name@source" specs, patched build first.
+type Equivalent struct {
+	Patched  Alias
+	Upstream Alias
+}
+
+// UpstreamRelease compares a patched font with the latest unmodified
+// release of the font it was built from
+type UpstreamRelease struct {
+	Source  string // Source publishing the unmodified font
+	Name    string // Name of the font in that source
+	Bundled string // Upstream version the installed files were built from, from their version names
+	Latest  string // Latest version the upstream source publishes
+	Newer   bool   // Whether Latest is newer than Bundled
+}
+
+// shippedEquivalents parses the mapping shipped with fm
+func shippedEquivalents() []Equivalent {
+	var mapping map[string]string
+	if err := json.Unmarshal(embeddedEquivalents, &mapping); err != nil {
+		return nil
+	}
+
+	var equivalents []Equivalent
+	for patched, upstream := range mapping {
+		var eq Equivalent
+		if json.Unmarshal(strconv.AppendQuote(nil, patched), &eq.Patched) != nil ||
+			json.Unmarshal(strconv.AppendQuote(nil, upstream), &eq.Upstream) != nil {
+			continue
+		}
+		equivalents = append(equivalents, eq)
+	}
+	sort.Slice(equivalents, func(i, j int) bool {
+		return equivalents[i].Patched.Name < equivalents[j].Patched.Name
+	})
+	return equivalents
+}
+
+// equivalentOf returns the upstream font an installed font was patched from
+func (m *DefaultManager) equivalentOf(font Font) (Equivalent, bool) {
+	if m.equivalents == nil {
+		m.equivalents = shippedEquivalents()
+	}
+	for _, eq := range m.equivalents {
+		if eq.Patched.Source == font.Source && sameFontName(eq.Patched.Name, font.Name) {
+			return eq, true
+		}
+	}
+	return Equivalent{}, false
+}
+
+// checkUpstream compares the upstream version an installed patched font was
+// built from with the latest upstream release. Fonts without an equivalent,
+// or whose versions can't be read or compared, have no upstream release.
+func (m *DefaultManager) checkUpstream(ctx context.Context, font Font) *UpstreamRelease {
+	eq, ok := m.equivalentOf(font)
+	if !ok {
+		return nil
+	}
+	bundled := bundledVersion(font.Meta["directory"])
+	bundledNumber, ok := fontVersionNumber(bundled)
+	if !ok {
+		return nil
+	}
+	latest, err := m.latestVersion(ctx, Font{Name: eq.Upstream.Name, Source: eq.Upstream.Source})
+	if err != nil {
+		return nil
+	}
+	latestNumber, ok := fontVersionNumber(latest)
+	if !ok {
+		return nil
+	}
+	return &UpstreamRelease{
+		Source:  eq.Upstream.Source,
+		Name:    eq.Upstream.Name,
+		Bundled: bundled,
+		Latest:  latest,
+		Newer:   latestNumber > bundledNumber,
+	}
+}
+
+// bundledVersion reads the version name of the first font file in dir that
+// has one
+func bundledVersion(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !isFontFile(entry.Name()) {
+			continue
+		}
+		if font, err := fontmeta.ParseFile(filepath.Join(dir, entry.Name())); err == nil && font.Version != "" {
+			return font.Version
+		}
+	}
+	return ""
+}
+
+// fontVersionPattern finds the number in font version names such as
+// "Version 6.002;Nerd Fonts 3.2.1" or release tags such as "v6.2"
+var fontVersionPattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// fontVersionNumber reads a font version as the decimal number font
+// revisions are, so 6.002 is older than 6.2
+func fontVersionNumber(version string) (float64, bool) {
+	match := fontVersionPattern.FindString(version)
+	if match == "" {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(match, 64)
+	return number, err == nil
+}
@@ -0,0 +1,88 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ExportOptions controls how installed fonts are written as font specs
+type ExportOptions struct {
+	Versions bool // Constrain each font to at least its installed version
+	URLs     bool // Pin each font to the URL it was downloaded from
+}
+
+// Export returns a font spec for every font fm installed, suitable for
+// WriteConfig and `fm install -f`. Fonts installed from a direct URL are
+// always exported as that URL. Fonts whose origin wasn't recorded, such as
+// URL installs from older versions of fm, are reported in the returned error
+// and left out.
+func (m *DefaultManager) Export(ctx context.Context, opts ExportOptions) ([]Font, error) {
+	installed, err := m.ManagedFonts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var fonts []Font
+	var errs []error
+	for _, font := range installed {
+		spec, err := exportSpec(font, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", font.Name, err))
+			continue
+		}
+		fonts = append(fonts, spec)
+	}
+	return fonts, errors.Join(errs...)
+}
+
+// exportSpec turns an installed font back into the spec that installs it
+func exportSpec(font Font, opts ExportOptions) (Font, error) {
+	spec := Font{
+		Name:   font.Name,
+		Source: font.Source,
+	}
+	if note := Note(font); note != "" {
+		spec.Meta = map[string]string{noteMetaKey: note}
+	}
+	if installAs := font.Meta["install_as"]; installAs != "" {
+		spec.Options = withMeta(spec.Options, "install_as", installAs)
+	}
+	if axes := font.Meta[axesMetaKey]; axes != "" {
+		spec.Options = withMeta(spec.Options, "axes", axes)
+	}
+	if variants := font.Meta[variantsMetaKey]; variants != "" {
+		spec.Options = withMeta(spec.Options, "variants", variants)
+	}
+	if format := font.Meta[formatMetaKey]; format != "" {
+		spec.Options = withMeta(spec.Options, "format", format)
+	}
+	if name := font.Meta["name"]; name != "" {
+		spec.Name = name
+	}
+
+	url := font.Meta["url"]
+	switch {
+	case font.Source == "url" && url == "":
+		return Font{}, fmt.Errorf("the URL it was installed from wasn't recorded")
+	case font.Source == "url" || (opts.URLs && url != ""):
+		spec.Source = "url"
+		spec.URL = url
+	case font.Meta["cid"] != "":
+		// Content addressed fonts are installed by reference
+		spec.Ref = font.Meta["cid"]
+		if _, path, ok := strings.Cut(url, "/ipfs/"); ok {
+			spec.Ref = path
+		}
+	}
+
+	// Versions the config parser can't compare are left out rather than
+	// producing a config that doesn't load
+	if version := font.Meta["version"]; opts.Versions && version != "" {
+		if _, err := ParseVersion(version); err == nil {
+			spec.MinVersion = version
+		}
+	}
+	return spec, nil
+}
@@ -0,0 +1,117 @@
+package fm_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Exporting installed fonts", func() {
+	var (
+		tempDir string
+		server  *httptest.Server
+		manager *fm.DefaultManager
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "font-export-*")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(tempDir, "user"), 0755)).To(Succeed())
+
+		archive, err := createTestZip(testFont{name: "Direct-Regular", format: "ttf", content: "direct"})
+		Expect(err).NotTo(HaveOccurred())
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(archive)
+		}))
+
+		source := newMockSource()
+		source.meta["TestFont1"] = map[string]string{"version": "v1.2.0"}
+		manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir})
+		Expect(manager.RegisterSource(source)).To(Succeed())
+
+		ctx = context.Background()
+		Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+		Expect(manager.Install(ctx, server.URL+"/Direct.zip")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(tempDir)
+	})
+
+	It("should export fonts with their sources and URL installs as URLs", func() {
+		fonts, err := manager.Export(ctx, fm.ExportOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		var specs []string
+		for _, font := range fonts {
+			specs = append(specs, fm.FormatFontSpec(font))
+		}
+		Expect(specs).To(ConsistOf("TestFont1@testsource", server.URL+"/Direct.zip"))
+	})
+
+	It("should keep install notes as comments that install them again", func() {
+		Expect(manager.Install(ctx, "TestFont2@testsource", fm.WithNote("for client X\n  branding"))).To(Succeed())
+
+		fonts, err := manager.Export(ctx, fm.ExportOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		buf := new(bytes.Buffer)
+		Expect(fm.WriteConfig(buf, fonts)).To(Succeed())
+		Expect(buf.String()).To(ContainSubstring("TestFont2@testsource  # for client X branding\n"))
+
+		Expect(manager.Uninstall(ctx, "TestFont2")).To(Succeed())
+		Expect(manager.InstallFromConfig(ctx, buf)).To(Succeed()) // The other fonts are still installed and skipped
+		installed, err := manager.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installed).To(ContainElement(SatisfyAll(
+			HaveField("Name", "TestFont2"),
+			WithTransform(fm.Note, Equal("for client X branding")),
+		)))
+	})
+
+	It("should add minimum versions when asked", func() {
+		fonts, err := manager.Export(ctx, fm.ExportOptions{Versions: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fonts).To(ContainElement(HaveField("MinVersion", "v1.2.0")))
+	})
+
+	It("should write a config that installs the same fonts", func() {
+		fonts, err := manager.Export(ctx, fm.ExportOptions{Versions: true})
+		Expect(err).NotTo(HaveOccurred())
+
+		buf := new(bytes.Buffer)
+		Expect(fm.WriteConfig(buf, fonts, "exported")).To(Succeed())
+		Expect(buf.String()).To(HavePrefix("# exported\n"))
+
+		parsed, err := fm.ParseConfig(buf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed).To(Equal(fonts))
+
+		for _, font := range fonts {
+			Expect(manager.Uninstall(ctx, font.Name)).To(Succeed())
+		}
+		Expect(manager.InstallFonts(ctx, parsed)).To(Succeed())
+
+		installed, err := manager.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installed).To(HaveLen(2))
+	})
+
+	It("should report URL installs whose URL wasn't recorded", func() {
+		metadata := filepath.Join(tempDir, "user", "Direct", ".metadata")
+		Expect(os.WriteFile(metadata, []byte(`{}`), 0644)).To(Succeed())
+
+		fonts, err := manager.Export(ctx, fm.ExportOptions{})
+		Expect(err).To(MatchError(ContainSubstring("wasn't recorded")))
+		Expect(fonts).To(HaveLen(1))
+	})
+})
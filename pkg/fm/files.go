@@ -0,0 +1,148 @@
+package fm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// manifestFile is the per-font record of installed files, written at install
+// time so listing files doesn't require re-hashing them
+const manifestFile = ".files"
+
+// InstalledFile describes a file belonging to an installed font
+type InstalledFile struct {
+	Name   string `json:"name"`   // File name within the font directory
+	Size   int64  `json:"size"`   // Size in bytes
+	SHA256 string `json:"sha256"` // Hex-encoded checksum of the contents
+	Format string `json:"format"` // "ttf", "otf" or "license"
+
+	// ModTime is when the file was last modified as it was hashed; the
+	// checksum is trusted without re-hashing while it and the size are
+	// unchanged
+	ModTime time.Time `json:"mtime"`
+}
+
+// Files returns the files installed for font, as returned by List. The
+// manifest recorded at install time is used unless rehash is set or it is
+// missing, in which case the files are hashed and the manifest rewritten.
+func (m *DefaultManager) Files(ctx context.Context, font Font, rehash bool) ([]InstalledFile, error) {
+	dir, ok := font.Meta["directory"]
+	if !ok {
+		return nil, fmt.Errorf("font directory information missing")
+	}
+
+	if !rehash {
+		if files, err := readManifest(dir); err == nil {
+			return files, nil
+		}
+	}
+
+	files, err := hashFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fonts in directories we can't write to, e.g. system fonts, are simply
+	// hashed again next time
+	_ = writeManifest(dir, files)
+	return files, nil
+}
+
+func readManifest(dir string) ([]InstalledFile, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []InstalledFile
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("parsing file manifest: %w", err)
+	}
+	return files, nil
+}
+
+func writeManifest(dir string, files []InstalledFile) error {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+
+	data, err := json.MarshalIndent(files, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding file manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestFile), data, 0644); err != nil {
+		return fmt.Errorf("writing file manifest: %w", err)
+	}
+	return nil
+}
+
+// hashFiles hashes the font and license files in dir
+func hashFiles(dir string) ([]InstalledFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading font directory: %w", err)
+	}
+
+	var files []InstalledFile
+	for _, entry := range entries {
+		format := fileFormat(entry.Name())
+		if entry.IsDir() || format == "" {
+			continue
+		}
+
+		file, err := hashFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func hashFile(path string) (InstalledFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return InstalledFile{}, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return InstalledFile{}, fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return InstalledFile{}, fmt.Errorf("hashing %s: %w", path, err)
+	}
+
+	name := filepath.Base(path)
+	return InstalledFile{
+		Name:    name,
+		Size:    size,
+		SHA256:  hex.EncodeToString(h.Sum(nil)),
+		Format:  fileFormat(name),
+		ModTime: info.ModTime(),
+	}, nil
+}
+
+// fileFormat classifies the files fm installs, returning "" for anything else
+func fileFormat(name string) string {
+	switch {
+	case isFontFile(name):
+		return strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	case isLicenseFile(name):
+		return "license"
+	default:
+		return ""
+	}
+}
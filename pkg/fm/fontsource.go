@@ -1,22 +1,31 @@
 package fm
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 )
 
 // FontSourceAPI provides access to fontsource.org
 type FontSourceAPI struct {
 	client *http.Client
+	cache  *Cache
+	logger *slog.Logger
 }
 
-func NewFontSourceAPI() *FontSourceAPI {
+func NewFontSourceAPI(opts ...SourceOption) *FontSourceAPI {
+	o := newSourceOptions(opts)
 	return &FontSourceAPI{
-		client: defaultClient,
+		client: o.client,
+		cache:  o.cache,
+		logger: o.logger,
 	}
 }
 
@@ -24,82 +33,298 @@ func (s *FontSourceAPI) Name() string {
 	return "fontsource"
 }
 
+const fontSourceAPIURL = "https://api.fontsource.org/v1/fonts"
+
 type fontSourceFont struct {
-	ID     string `json:"id"`
-	Family string `json:"family"`
+	ID      string `json:"id"`
+	Family  string `json:"family"`
+	Version string `json:"version"`
 }
 
 func (s *FontSourceAPI) Search(ctx context.Context, name string) ([]Font, error) {
 	encodedName := url.QueryEscape(name)
-	reqURL := fmt.Sprintf("https://api.fontsource.org/v1/fonts?family=%s", encodedName)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating search request: %w", err)
-	}
-
-	// Add required headers
-	req.Header.Set("User-Agent", "FontManager/1.0")
+	reqURL := fmt.Sprintf("%s?family=%s", fontSourceAPIURL, encodedName)
 
-	resp, err := s.client.Do(req)
-	if err != nil {
+	var fonts []fontSourceFont
+	if err := getJSON(ctx, s.client, s.cache, s.logger, reqURL, &fonts); err != nil {
 		return nil, fmt.Errorf("searching fonts: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
 
-	var fonts []fontSourceFont
-	if err := json.NewDecoder(resp.Body).Decode(&fonts); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	// Fall back to looking the name up as a fontsource ID, e.g. "fira-code"
+	if len(fonts) == 0 && isFontSourceID(name) {
+		reqURL = fmt.Sprintf("%s?id=%s", fontSourceAPIURL, encodedName)
+		if err := getJSON(ctx, s.client, s.cache, s.logger, reqURL, &fonts); err != nil {
+			return nil, fmt.Errorf("searching fonts: %w", err)
+		}
 	}
 
 	var results []Font
 	for _, f := range fonts {
+		meta := map[string]string{"id": f.ID}
+		// Downloads always fetch the latest release, which is the one listed
+		if f.Version != "" {
+			meta["version"] = f.Version
+		}
 		results = append(results, Font{
 			Name:   f.Family,
 			Source: s.Name(),
-			Meta:   map[string]string{"id": f.ID},
+			Meta:   meta,
 		})
 	}
 
 	return results, nil
 }
 
-func (s *FontSourceAPI) Download(ctx context.Context, font Font) (io.ReadCloser, error) {
-	fontID, ok := font.Meta["id"]
-	if !ok {
-		// If we don't have the ID, try to search for it
-		fonts, err := s.Search(ctx, font.Name)
-		if err != nil {
-			return nil, fmt.Errorf("searching for font ID: %w", err)
+func isFontSourceID(name string) bool {
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return false
 		}
-		if len(fonts) == 0 {
-			return nil, fmt.Errorf("font not found: %s", font.Name)
+	}
+	return name != ""
+}
+
+// fontID returns the fontsource ID of font, searching for it if needed
+func (s *FontSourceAPI) fontID(ctx context.Context, font Font) (string, error) {
+	if fontID, ok := font.Meta["id"]; ok {
+		return fontID, nil
+	}
+
+	// If we don't have the ID, try to search for it
+	fonts, err := s.Search(ctx, font.Name)
+	if err != nil {
+		return "", fmt.Errorf("searching for font ID: %w", err)
+	}
+	if len(fonts) == 0 {
+		return "", fmt.Errorf("font %w: %s", ErrNotFound, font.Name)
+	}
+	return fonts[0].Meta["id"], nil
+}
+
+func (s *FontSourceAPI) Download(ctx context.Context, font Font) (io.ReadCloser, error) {
+	fontID, err := s.fontID(ctx, font)
+	if err != nil {
+		return nil, err
+	}
+
+	if subsets := font.Meta[subsetsMetaKey]; subsets != "" {
+		return s.downloadSubsets(ctx, fontID, strings.Split(subsets, ","))
+	}
+	if font.Meta[axesMetaKey] != "" {
+		return s.downloadVariable(ctx, fontID)
+	}
+
+	return openURL(ctx, s.client, fontSourceDownloadURL(fontID))
+}
+
+// DownloadURL returns the archive of font's latest release. Subset and
+// variable downloads fetch several files, so they have no single URL.
+func (s *FontSourceAPI) DownloadURL(ctx context.Context, font Font) (string, error) {
+	fontID, err := s.fontID(ctx, font)
+	if err != nil {
+		return "", err
+	}
+	if font.Meta[subsetsMetaKey] != "" || font.Meta[axesMetaKey] != "" {
+		return "", nil
+	}
+	return fontSourceDownloadURL(fontID), nil
+}
+
+// fontSourceDownloadURL is the archive of every file of a fontsource font
+func fontSourceDownloadURL(fontID string) string {
+	return fmt.Sprintf("https://r2.fontsource.org/fonts/%s@latest/download.zip", fontID)
+}
+
+// fontSourceDetails is the per-font response of the fontsource API
+type fontSourceDetails struct {
+	ID           string            `json:"id"`
+	UnicodeRange map[string]string `json:"unicodeRange"`
+	// Variants maps weight -> style -> subset -> file URLs by format
+	Variants map[string]map[string]map[string]struct {
+		URL map[string]string `json:"url"`
+	} `json:"variants"`
+}
+
+func (s *FontSourceAPI) details(ctx context.Context, fontID string) (*fontSourceDetails, error) {
+	var details fontSourceDetails
+	if err := getJSON(ctx, s.client, s.cache, s.logger, fontSourceAPIURL+"/"+url.PathEscape(fontID), &details); err != nil {
+		return nil, fmt.Errorf("fetching font details: %w", err)
+	}
+	return &details, nil
+}
+
+// Subsets returns the script subsets fontsource publishes for font
+func (s *FontSourceAPI) Subsets(ctx context.Context, font Font) (map[string]string, error) {
+	fontID, err := s.fontID(ctx, font)
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := s.details(ctx, fontID)
+	if err != nil {
+		return nil, err
+	}
+	return details.UnicodeRange, nil
+}
+
+// downloadSubsets fetches the TTF of every weight and style for the given
+// subsets and bundles them into a zip for the installer
+func (s *FontSourceAPI) downloadSubsets(ctx context.Context, fontID string, subsets []string) (io.ReadCloser, error) {
+	details, err := s.details(ctx, fontID)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+	for _, weight := range sortedKeys(details.Variants) {
+		styles := details.Variants[weight]
+		for _, style := range sortedKeys(styles) {
+			for _, subset := range subsets {
+				file, ok := styles[style][subset]
+				if !ok || file.URL["ttf"] == "" {
+					continue
+				}
+
+				data, err := fetch(ctx, s.client, file.URL["ttf"])
+				if err != nil {
+					return nil, fmt.Errorf("downloading %s %s %s: %w", subset, weight, style, err)
+				}
+
+				w, err := zipWriter.Create(fmt.Sprintf("%s-%s-%s-%s.ttf", fontID, subset, weight, style))
+				if err != nil {
+					return nil, err
+				}
+				if _, err := w.Write(data); err != nil {
+					return nil, err
+				}
+			}
 		}
-		fontID = fonts[0].Meta["id"]
+	}
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
 	}
 
-	downloadURL := fmt.Sprintf("https://r2.fontsource.org/fonts/%s@latest/download.zip", fontID)
+	return io.NopCloser(buf), nil
+}
+
+// fontSourceVariableURL lists the axes of fontsource's variable fonts
+const fontSourceVariableURL = "https://api.fontsource.org/v1/variable"
+
+// fontSourceAxes is the variable font response of the fontsource API
+type fontSourceAxes struct {
+	Axes map[string]struct {
+		Min axisValue `json:"min"`
+		Max axisValue `json:"max"`
+	} `json:"axes"`
+}
+
+// axisValue decodes axis bounds, which the API sends as strings or numbers
+type axisValue float64
 
-	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
+func (v *axisValue) UnmarshalJSON(data []byte) error {
+	f, err := strconv.ParseFloat(strings.Trim(string(data), `"`), 64)
 	if err != nil {
-		return nil, fmt.Errorf("creating download request: %w", err)
+		return fmt.Errorf("invalid axis value %s", data)
 	}
+	*v = axisValue(f)
+	return nil
+}
 
-	req.Header.Set("User-Agent", "FontManager/1.0")
+// Axes returns the design space of font's variable build, or none for fonts
+// only published as static weights
+func (s *FontSourceAPI) Axes(ctx context.Context, font Font) ([]AxisRange, error) {
+	fontID, err := s.fontID(ctx, font)
+	if err != nil {
+		return nil, err
+	}
+
+	var variable fontSourceAxes
+	if err := getJSON(ctx, s.client, s.cache, s.logger, fontSourceVariableURL+"/"+url.PathEscape(fontID), &variable); err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("fetching variable axes: %w", err)
+	}
 
-	resp, err := s.client.Do(req)
+	var axes []AxisRange
+	for _, tag := range sortedKeys(variable.Axes) {
+		axis := variable.Axes[tag]
+		axes = append(axes, AxisRange{Tag: tag, Min: float64(axis.Min), Max: float64(axis.Max)})
+	}
+	return axes, nil
+}
+
+// googleFontsContentsURL lists the directories of the Google Fonts
+// repository, which publishes the variable TTFs fontsource only serves as
+// web fonts
+const googleFontsContentsURL = "https://api.github.com/repos/google/fonts/contents"
+
+// googleFontsLicenseDirs are the top-level directories of the Google Fonts
+// repository, one per license
+var googleFontsLicenseDirs = []string{"ofl", "apache", "ufl"}
+
+type githubContent struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"download_url"`
+}
+
+// downloadVariable fetches the variable TTFs of a font from the Google Fonts
+// repository and bundles them into a zip for the installer, which limits
+// their axes
+func (s *FontSourceAPI) downloadVariable(ctx context.Context, fontID string) (io.ReadCloser, error) {
+	// The repository names directories after the family without separators
+	dir := strings.ReplaceAll(fontID, "-", "")
+
+	var contents []githubContent
+	var err error
+	for _, license := range googleFontsLicenseDirs {
+		err = getJSON(ctx, s.client, s.cache, s.logger, googleFontsContentsURL+"/"+license+"/"+dir, &contents)
+		if !isNotFound(err) {
+			break
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("downloading font: %w", err)
+		return nil, fmt.Errorf("finding the variable build of %s: %w", fontID, err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		resp.Body.Close()
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+	found := false
+	for _, file := range contents {
+		// Variable files name their axes, as in "Inter[opsz,wght].ttf"
+		if !strings.Contains(file.Name, "[") || !isFontFile(file.Name) {
+			continue
+		}
+		data, err := fetch(ctx, s.client, file.DownloadURL)
+		if err != nil {
+			return nil, fmt.Errorf("downloading %s: %w", file.Name, err)
+		}
+		w, err := zipWriter.Create(file.Name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		found = true
+	}
+	if !found {
+		return nil, fmt.Errorf("%s has no variable TTFs in the Google Fonts repository", fontID)
 	}
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(buf), nil
+}
+
+// Describe reports the source; responses are cached per search
+func (s *FontSourceAPI) Describe() SourceInfo {
+	return describeSource("fontsource", s.cache, "")
+}
 
-	return resp.Body, nil
+// CheckHealth probes the fontsource search API
+func (s *FontSourceAPI) CheckHealth(ctx context.Context) SourceHealth {
+	return checkEndpoint(ctx, s.client, fontSourceAPIURL+"?family=Roboto")
 }
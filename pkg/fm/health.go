@@ -0,0 +1,134 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HealthChecker is implemented by sources that can report on the state of
+// their remote service
+type HealthChecker interface {
+	// CheckHealth probes the source's API and reports what it found
+	CheckHealth(ctx context.Context) SourceHealth
+}
+
+// SourceHealth is the result of probing a source
+type SourceHealth struct {
+	Source    string        // Name of the source
+	Endpoint  string        // URL that was probed
+	Healthy   bool          // Whether the source is usable right now
+	Latency   time.Duration // Time until the response headers arrived
+	Auth      string        // How requests are authenticated, e.g. "anonymous"
+	RateLimit *RateLimit    // Rate limit state, when the API reports it
+	Err       error         // Why the source is unhealthy
+}
+
+// RateLimit describes an API's request quota
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// CheckSources probes every registered source concurrently and returns the
+// results in registration order
+func (m *DefaultManager) CheckSources(ctx context.Context) []SourceHealth {
+	results := make([]SourceHealth, len(m.sources))
+
+	var wg sync.WaitGroup
+	for i, source := range m.sources {
+		checker, ok := source.(HealthChecker)
+		if !ok {
+			results[i] = SourceHealth{
+				Source:  source.Name(),
+				Healthy: true,
+				Err:     fmt.Errorf("source does not support health checks"),
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, checker HealthChecker) {
+			defer wg.Done()
+			results[i] = checker.CheckHealth(ctx)
+			results[i].Source = source.Name()
+		}(i, checker)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Sources describes the registered sources in the order they are searched
+func (m *DefaultManager) Sources() []SourceInfo {
+	infos := make([]SourceInfo, len(m.sources))
+	for i, source := range m.sources {
+		info := SourceInfo{Type: "custom", Auth: "anonymous"}
+		if described, ok := source.(DescribedSource); ok {
+			info = described.Describe()
+		}
+		info.Name = source.Name()
+		info.Priority = i + 1
+		infos[i] = info
+	}
+	return infos
+}
+
+// checkEndpoint issues a GET request and reports reachability, latency and
+// any GitHub-style rate limit headers
+func checkEndpoint(ctx context.Context, client *http.Client, endpoint string) SourceHealth {
+	health := SourceHealth{
+		Endpoint: endpoint,
+		Auth:     "anonymous",
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		health.Err = fmt.Errorf("creating request: %w", err)
+		return health
+	}
+	req.Header.Set("User-Agent", "FontManager/1.0")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	health.Latency = time.Since(start)
+	if err != nil {
+		health.Err = fmt.Errorf("unreachable: %w", err)
+		return health
+	}
+	defer resp.Body.Close()
+
+	health.RateLimit = parseRateLimit(resp.Header)
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		health.Healthy = true
+	case health.RateLimit != nil && health.RateLimit.Remaining == 0:
+		health.Err = fmt.Errorf("rate limited until %s", health.RateLimit.Reset.Format(time.Kitchen))
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		health.Err = fmt.Errorf("access denied (status %d)", resp.StatusCode)
+	default:
+		health.Err = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return health
+}
+
+func parseRateLimit(header http.Header) *RateLimit {
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return nil
+	}
+	remaining, _ := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	reset, _ := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+
+	return &RateLimit{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(reset, 0),
+	}
+}
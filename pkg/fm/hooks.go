@@ -0,0 +1,78 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+)
+
+// HookAction is the change to a font that hooks run around
+type HookAction string
+
+const (
+	HookInstall   HookAction = "install"
+	HookUninstall HookAction = "uninstall"
+	HookUpgrade   HookAction = "upgrade"
+)
+
+// HookEvent describes the change to one font a hook is called for
+type HookEvent struct {
+	Action HookAction
+
+	// After is false before the font changes and true once it has
+	After bool
+
+	// Font is the name of the font: as asked for before an install or
+	// upgrade, as installed otherwise
+	Font    string
+	Source  string
+	Version string // Recorded version, once known
+
+	// Dir is the directory of the font; empty before an install or upgrade
+	Dir string
+}
+
+// Hook is called before and after a font is installed, uninstalled or
+// upgraded. An error before the change aborts it; after the change, which
+// only calls hooks when it succeeded, errors are logged as warnings. Fonts
+// installed at once call hooks from several goroutines.
+type Hook func(ctx context.Context, event HookEvent) error
+
+// WithHook calls hook around every install, uninstall and upgrade, after
+// the hooks added before it
+func WithHook(hook Hook) ManagerOption {
+	return func(m *DefaultManager) {
+		m.hooks = append(m.hooks, hook)
+	}
+}
+
+// runHooks calls the hooks for event in order, stopping at the first error
+func (m *DefaultManager) runHooks(ctx context.Context, event HookEvent) error {
+	for _, hook := range m.hooks {
+		if err := hook(ctx, event); err != nil {
+			when := "pre"
+			if event.After {
+				when = "post"
+			}
+			return fmt.Errorf("%s-%s hook: %w", when, event.Action, err)
+		}
+	}
+	return nil
+}
+
+// runAfterHooks calls the hooks once the font changed, when failing can only
+// be reported
+func (m *DefaultManager) runAfterHooks(ctx context.Context, event HookEvent) {
+	event.After = true
+	if err := m.runHooks(ctx, event); err != nil {
+		m.log().Warn("hook failed", "font", event.Font, "err", err)
+	}
+}
+
+// installHookEvent describes installing spec with o to hooks
+func installHookEvent(spec *Font, o *installOptions) HookEvent {
+	event := HookEvent{Action: HookInstall, Font: o.installedName(spec), Source: spec.Source, Version: spec.Version}
+	if o.upgrade {
+		event.Action = HookUpgrade
+	}
+	return event
+}
@@ -0,0 +1,82 @@
+package fm_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTTP settings", func() {
+	var (
+		server   *httptest.Server
+		requests atomic.Int32
+		failures int32
+	)
+
+	BeforeEach(func() {
+		requests.Store(0)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) <= failures {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should retry transient failures", func() {
+		failures = 2
+		client, err := fm.NewHTTPClient(fm.HTTPSettings{Retries: 2, RetryDelay: time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := client.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(requests.Load()).To(BeEquivalentTo(3))
+	})
+
+	It("should give up after the configured number of retries", func() {
+		failures = 5
+		client, err := fm.NewHTTPClient(fm.HTTPSettings{Retries: 1, RetryDelay: time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := client.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(requests.Load()).To(BeEquivalentTo(2))
+	})
+
+	It("should fail requests without sending them when offline", func() {
+		os.Setenv(fm.OfflineEnv, "1")
+		defer os.Unsetenv(fm.OfflineEnv)
+		Expect(fm.IsOffline()).To(BeTrue())
+
+		client, err := fm.NewHTTPClient(fm.HTTPSettings{Retries: 2, RetryDelay: time.Second})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.Get(server.URL)
+		Expect(err).To(MatchError(fm.ErrOffline))
+		Expect(requests.Load()).To(BeZero())
+	})
+
+	It("should reject an invalid proxy URL", func() {
+		_, err := fm.NewHTTPClient(fm.HTTPSettings{Proxy: "://bad"})
+		Expect(err).To(MatchError(ContainSubstring("proxy")))
+	})
+
+	It("should reject a missing CA bundle", func() {
+		_, err := fm.NewHTTPClient(fm.HTTPSettings{CACertFile: "/nonexistent/ca.pem"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,140 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// UninstallImpact describes what uninstalling a font would affect, without
+// changing anything
+type UninstallImpact struct {
+	Font     Font              // The installed font
+	Dir      string            // Font directory that would be removed
+	Files    []string          // Files that would be removed, including fm's records
+	Families []string          // Families the font's files provide
+	Used     []ConfigReference // Application configs naming those families
+	Shared   []SharedFile      // Files other installed fonts have identical copies of
+}
+
+// SharedFile is a font file with byte-identical copies in other installed
+// fonts. In the store layout the copies are the same object, which stays
+// until the last font using it is removed.
+type SharedFile struct {
+	Name  string   // File name within the font directory
+	Fonts []string // Other installed fonts with the same contents
+}
+
+// UninstallImpact reports what Uninstall would remove for name and what else
+// depends on it: application configs in the font directory owner's home that
+// name its families, and other installed fonts sharing its files.
+func (m *DefaultManager) UninstallImpact(ctx context.Context, name string) (*UninstallImpact, error) {
+	font, err := m.findInstalled(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if font == nil {
+		return nil, fmt.Errorf("font %q is %w", name, ErrNotInstalled)
+	}
+
+	paths, err := m.platform.GetFontPaths()
+	if err != nil {
+		return nil, fmt.Errorf("getting font paths: %w", err)
+	}
+	dir := font.Meta["directory"]
+	if !strings.HasPrefix(dir, paths.UserDir) {
+		return nil, fmt.Errorf("cannot uninstall system font %q", name)
+	}
+
+	impact := &UninstallImpact{Font: *font, Dir: dir}
+	if impact.Files, err = listDir(dir); err != nil {
+		return nil, err
+	}
+
+	files, err := m.Files(ctx, *font, false)
+	if err != nil {
+		return nil, err
+	}
+	families := make(map[string]bool)
+	hashes := make(map[string]string)
+	for _, file := range files {
+		if file.Format == "license" {
+			continue
+		}
+		hashes[file.SHA256] = file.Name
+		if face, err := readFace(filepath.Join(dir, file.Name)); err == nil && face.Family != "" {
+			families[face.Family] = true
+		}
+	}
+	for family := range families {
+		impact.Families = append(impact.Families, family)
+	}
+	sort.Strings(impact.Families)
+
+	if impact.Shared, err = m.sharedFiles(ctx, *font, paths.UserDir, hashes); err != nil {
+		return nil, err
+	}
+
+	if impact.Used, err = FindConfigReferences(paths.HomeDir, append(impact.Families, font.Name)); err != nil {
+		return nil, err
+	}
+	return impact, nil
+}
+
+// sharedFiles finds the files of font, given by checksum, that other fonts
+// in the user font directory have identical copies of
+func (m *DefaultManager) sharedFiles(ctx context.Context, font Font, userDir string, hashes map[string]string) ([]SharedFile, error) {
+	installed, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string][]string)
+	for _, other := range installed {
+		dir := other.Meta["directory"]
+		if dir == font.Meta["directory"] || !strings.HasPrefix(dir, userDir) {
+			continue
+		}
+		files, err := m.Files(ctx, other, false)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if name, ok := hashes[file.SHA256]; ok && file.Format != "license" {
+				byName[name] = append(byName[name], other.Name)
+			}
+		}
+	}
+
+	var shared []SharedFile
+	for name, fonts := range byName {
+		shared = append(shared, SharedFile{Name: name, Fonts: fonts})
+	}
+	sort.Slice(shared, func(i, j int) bool {
+		return shared[i].Name < shared[j].Name
+	})
+	return shared, nil
+}
+
+// listDir returns every file under dir, following the symlink of a font in
+// the store layout
+func listDir(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir+string(filepath.Separator), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, filepath.Clean(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", dir, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
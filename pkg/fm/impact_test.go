@@ -0,0 +1,97 @@
+package fm_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+var _ = Describe("Uninstall impact", func() {
+	var (
+		tempDir string
+		manager *fm.DefaultManager
+		ctx     context.Context
+	)
+
+	zipOf := func(files map[string][]byte) []byte {
+		buf := new(bytes.Buffer)
+		zipWriter := zip.NewWriter(buf)
+		for name, data := range files {
+			f, err := zipWriter.Create(name)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.Write(data)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(zipWriter.Close()).To(Succeed())
+		return buf.Bytes()
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "font-impact-*")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(tempDir, "user"), 0755)).To(Succeed())
+
+		source := newMockSource()
+		source.fonts["GoMono"] = zipOf(map[string][]byte{
+			"GoMono-Regular.ttf": gomono.TTF,
+			"GoBold.ttf":         gobold.TTF,
+		})
+		source.fonts["GoBold"] = zipOf(map[string][]byte{"GoBold.ttf": gobold.TTF})
+		manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir})
+		Expect(manager.RegisterSource(source)).To(Succeed())
+
+		ctx = context.Background()
+		Expect(manager.Install(ctx, "GoMono@testsource")).To(Succeed())
+		Expect(manager.Install(ctx, "GoBold@testsource")).To(Succeed())
+
+		kitty := filepath.Join(tempDir, "home", ".config", "kitty", "kitty.conf")
+		Expect(os.MkdirAll(filepath.Dir(kitty), 0755)).To(Succeed())
+		Expect(os.WriteFile(kitty, []byte("font_size 12\nfont_family  go-mono\n"), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	It("should report files, families, configs and shared files without removing anything", func() {
+		impact, err := manager.UninstallImpact(ctx, "GoMono")
+		Expect(err).NotTo(HaveOccurred())
+
+		dir := filepath.Join(tempDir, "user", "GoMono")
+		Expect(impact.Dir).To(Equal(dir))
+		Expect(impact.Files).To(ContainElements(
+			filepath.Join(dir, "GoMono-Regular.ttf"),
+			filepath.Join(dir, "GoBold.ttf"),
+			filepath.Join(dir, ".installed"),
+		))
+		Expect(impact.Families).To(ConsistOf("Go", "Go Mono"))
+
+		Expect(impact.Used).To(HaveLen(1))
+		Expect(impact.Used[0].Path).To(HaveSuffix("kitty.conf"))
+		Expect(impact.Used[0].Line).To(Equal(2))
+		Expect(impact.Used[0].Family).To(Equal("Go Mono"))
+		Expect(impact.Used[0].Text).To(Equal("font_family  go-mono"))
+
+		Expect(impact.Shared).To(HaveLen(1))
+		Expect(impact.Shared[0].Name).To(Equal("GoBold.ttf"))
+		Expect(impact.Shared[0].Fonts).To(ConsistOf("GoBold"))
+
+		installed, err := manager.IsInstalled(ctx, "GoMono")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installed).To(BeTrue())
+	})
+
+	It("should fail for fonts that aren't installed", func() {
+		_, err := manager.UninstallImpact(ctx, "Missing")
+		Expect(err).To(MatchError(ContainSubstring("not installed")))
+	})
+})
@@ -1,11 +1,15 @@
 package fm
 
 import (
-	"archive/zip"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -15,66 +19,315 @@ import (
 
 // FontInstaller handles the installation of fonts into the system
 type FontInstaller struct {
-	fontDir  string
-	cacheCmd string
+	fontDir      string
+	cacheCmd     []string
+	instancerCmd []string
+	logger       *slog.Logger
 }
 
 func NewFontInstaller(fontDir string) *FontInstaller {
 	return &FontInstaller{
-		fontDir:  fontDir,
-		cacheCmd: "fc-cache", // default to fc-cache, can be overridden
+		fontDir:      fontDir,
+		cacheCmd:     []string{"fc-cache"},
+		instancerCmd: DefaultInstancerCommand,
 	}
 }
 
-func (fi *FontInstaller) Install(font Font, data io.Reader) error {
-	// Read all data into memory to avoid multiple reads
-	buf := new(bytes.Buffer)
-	if _, err := io.Copy(buf, data); err != nil {
-		return fmt.Errorf("reading font data: %w", err)
+// SetCacheCommand replaces the command and arguments UpdateCache runs, e.g.
+// "fc-cache", "-f", "-v"
+func (fi *FontInstaller) SetCacheCommand(command ...string) {
+	if len(command) > 0 {
+		fi.cacheCmd = command
+	}
+}
+
+// SetInstancerCommand replaces the command that limits the axes of variable
+// fonts, e.g. "python3", "-m", "fontTools.varLib.instancer"
+func (fi *FontInstaller) SetInstancerCommand(command ...string) {
+	if len(command) > 0 {
+		fi.instancerCmd = command
+	}
+}
+
+// ErrLimitExceeded is returned when an archive exceeds the configured Limits
+var ErrLimitExceeded = errors.New("archive limit exceeded")
+
+// ErrChecksumMismatch is returned when a download doesn't match the checksum
+// it was expected to have, e.g. from a lockfile
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// installResult describes a completed installation
+type installResult struct {
+	font   Font   // The font as recorded in its metadata
+	dir    string // Directory the font was installed into
+	sha256 string // Checksum of the downloaded archive
+	url    string // Where a dry run would download the archive from
+
+	license           string // SPDX identifier of the detected license, if recognized
+	licenseOverridden bool   // Installed despite the license policy
+}
+
+func (fi *FontInstaller) Install(font Font, data io.Reader, opts ...InstallOption) error {
+	_, err := fi.install(font, data, newInstallOptions(opts))
+	return err
+}
+
+func (fi *FontInstaller) install(font Font, data io.Reader, o *installOptions) (result *installResult, err error) {
+	limits := o.limits
+
+	// Downloads of a single file say where they came from
+	var downloadURL string
+	if located, ok := data.(interface{ URL() string }); ok {
+		downloadURL = located.URL()
+	}
+
+	// Fail before downloading anything when the server announces the size
+	if sized, ok := data.(interface{ Size() int64 }); ok && limits.MaxDownloadSize > 0 && sized.Size() > limits.MaxDownloadSize {
+		return nil, fmt.Errorf("%w: download is %s, larger than %s",
+			ErrLimitExceeded, FormatSize(sized.Size()), FormatSize(limits.MaxDownloadSize))
+	}
+
+	// Cached downloads carry the digest recorded when they were stored
+	var digest string
+	if digested, ok := data.(interface{ SHA256() string }); ok {
+		digest = digested.SHA256()
+	}
+
+	// Read all data up front, into memory or a temporary file in low-memory
+	// mode, stopping as soon as the download grows past the limit
+	if limits.MaxDownloadSize > 0 {
+		data = io.LimitReader(data, limits.MaxDownloadSize+1)
+	}
+	dl, checksum, err := readDownload(data, o.lowMemory, digest)
+	if err != nil {
+		return nil, fmt.Errorf("reading font data: %w", err)
+	}
+	defer dl.Close()
+	if limits.MaxDownloadSize > 0 && dl.size > limits.MaxDownloadSize {
+		return nil, fmt.Errorf("%w: download is larger than %s", ErrLimitExceeded, FormatSize(limits.MaxDownloadSize))
+	}
+
+	// Refuse anything but the expected download before extracting it
+	if o.sha256 != "" && !strings.EqualFold(o.sha256, checksum) {
+		return nil, fmt.Errorf("%w: download has SHA-256 %s, expected %s", ErrChecksumMismatch, checksum, o.sha256)
+	}
+	o.reportProgress(Progress{Font: font.Name, Phase: PhaseExtract})
+
+	// Process the archive with whichever extractor recognizes it
+	entries, err := dl.entries()
+	if err != nil {
+		// Misconfigured URLs commonly serve an error or landing page
+		if strings.HasPrefix(http.DetectContentType(dl.head()), "text/html") {
+			return nil, fmt.Errorf("received an HTML page instead of a font archive")
+		}
+		return nil, err
+	}
+	fi.log().Debug("downloaded", "font", font.Name, "size", FormatSize(dl.size), "sha256", checksum, "entries", len(entries))
+
+	if err := checkArchiveLimits(entries, limits); err != nil {
+		return nil, err
+	}
+
+	// Foundry downloads often wrap each family in its own archive
+	entries, err = expandNestedArchives(entries, o.archiveDepth, limits)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create font directory if it doesn't exist
-	fontPath := filepath.Join(fi.fontDir, sanitizeFontName(font.Name))
-	if err := os.MkdirAll(fontPath, 0755); err != nil {
-		return fmt.Errorf("creating font directory: %w", err)
+	// Sources may know the license; otherwise look for license text
+	license := font.Meta["license"]
+	if license == "" {
+		license = detectLicense(entries)
+	}
+	overridden, err := o.checkLicense(font, license)
+	if err != nil {
+		return nil, err
 	}
 
-	// Process the zip file
-	zipReader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	// The font is written to a staging directory next to its own and only
+	// renamed into place once complete, so a failed install leaves nothing
+	// that looks installed
+	dirName := font.Name
+	if o.installAs != "" {
+		dirName = o.installAs
+	}
+	fontPath := filepath.Join(fi.fontDir, fontDirName(dirName))
+	staging, err := fi.stagingDir(fontPath)
 	if err != nil {
-		return fmt.Errorf("reading zip data: %w", err)
+		return nil, fmt.Errorf("creating font directory: %w", err)
 	}
+	defer os.RemoveAll(staging)
+
+	preferred := preferredFaces(entries, o.format)
 
 	installed := false
-	for _, file := range zipReader.File {
-		// Skip directories and hidden files
-		if file.FileInfo().IsDir() || strings.HasPrefix(filepath.Base(file.Name), ".") {
+	files := make(map[string]InstalledFile)
+	copyBuf := o.copyBuffer()
+	for _, entry := range entries {
+		// Skip hidden files
+		if strings.HasPrefix(filepath.Base(entry.Name), ".") {
 			continue
 		}
 
-		// Check if it's a font file
-		if isFontFile(file.Name) {
-			if err := fi.extractFontFile(file, fontPath); err != nil {
-				return fmt.Errorf("extracting font file %s: %w", file.Name, err)
+		// Check if it's a font file of a wanted style
+		if isFontFile(entry.Name) {
+			if !matchesVariants(entry.Name, o.variants) {
+				fi.log().Debug("skipped file, not one of the variants", "font", font.Name, "file", entry.Name)
+				continue
+			}
+			if preferred[faceName(entry.Name)] && fileFormat(entry.Name) != o.format {
+				fi.log().Debug("skipped file, also in "+strings.ToUpper(o.format), "font", font.Name, "file", entry.Name)
+				continue
 			}
+			file, err := fi.extractFontFile(entry, staging, copyBuf)
+			if err != nil {
+				return nil, fmt.Errorf("extracting font file %s: %w", entry.Name, err)
+			}
+			fi.log().Debug("extracted", "font", font.Name, "file", entry.Name, "size", FormatSize(file.Size))
+			files[file.Name] = file
 			installed = true
 		}
 
-		// Always extract LICENSE files
-		if strings.EqualFold(filepath.Base(file.Name), "LICENSE") {
-			if err := fi.extractFontFile(file, fontPath); err != nil {
-				return fmt.Errorf("extracting license file: %w", err)
+		// Always extract license files, e.g. LICENSE, OFL.txt or COPYING
+		if isLicenseFile(entry.Name) && entry.Size <= maxLicenseSize {
+			file, err := fi.extractFontFile(entry, staging, copyBuf)
+			if err != nil {
+				return nil, fmt.Errorf("extracting license file: %w", err)
 			}
+			files[file.Name] = file
 		}
 	}
 
+	if !installed && len(o.variants) > 0 {
+		return nil, fmt.Errorf("no font files of the variants %s found in archive", strings.Join(o.variants, ", "))
+	}
 	if !installed {
-		return fmt.Errorf("no valid font files found in archive")
+		return nil, fmt.Errorf("no valid font files found in archive")
+	}
+
+	// Variable fonts are cut down to the requested design space
+	if len(o.axes) > 0 {
+		if err := fi.instanceFonts(staging, o.axes); err != nil {
+			return nil, err
+		}
+		for name := range files {
+			if !isFontFile(name) {
+				continue
+			}
+			file, err := hashFile(filepath.Join(staging, name))
+			if err != nil {
+				return nil, err
+			}
+			files[name] = file
+		}
+	}
+
+	// Record the archive checksum alongside the other metadata
+	meta := make(map[string]string, len(font.Meta)+1)
+	for k, v := range font.Meta {
+		meta[k] = v
+	}
+	meta["sha256"] = checksum
+	if downloadURL != "" {
+		meta["download_url"] = downloadURL
+	}
+	if license != "" {
+		meta["license"] = license
 	}
 
+	// Record what the font was installed as so it can be exported again
+	meta["name"] = font.Name
+	if o.installAs != "" {
+		meta["install_as"] = o.installAs
+	}
+	if len(o.variants) > 0 {
+		meta[variantsMetaKey] = strings.Join(o.variants, ",")
+	}
+	if o.format != "" {
+		meta[formatMetaKey] = o.format
+	}
+	if font.URL != "" {
+		meta["url"] = font.URL
+	}
+	font.Meta = meta
+
 	// Store metadata about the font source
-	if err := fi.storeMetadata(fontPath, font); err != nil {
-		return fmt.Errorf("storing font metadata: %w", err)
+	if err := fi.storeMetadata(staging, font); err != nil {
+		return nil, fmt.Errorf("storing font metadata: %w", err)
+	}
+
+	// Record what was installed so it can be listed without re-hashing
+	manifest := make([]InstalledFile, 0, len(files))
+	for _, file := range files {
+		manifest = append(manifest, file)
+	}
+	if err := writeManifest(staging, manifest); err != nil {
+		return nil, err
+	}
+	if err := commitStaging(staging, fontPath); err != nil {
+		return nil, fmt.Errorf("completing install: %w", err)
+	}
+
+	return &installResult{
+		font:              font,
+		dir:               fontPath,
+		sha256:            meta["sha256"],
+		license:           license,
+		licenseOverridden: overridden,
+	}, nil
+}
+
+// variantsMetaKey is the Font.Meta key listing the variants a font was
+// installed with, comma separated
+const variantsMetaKey = "variants"
+
+// matchesVariants reports whether a font file is one of the wanted styles,
+// taken from the file name after the last hyphen as in "FiraCode-Bold.ttf".
+// Files without a style are Regular. Nerd Fonts files also match their
+// spacing, Mono or Propo, as in "FiraCodeNerdFontMono-Bold.ttf". No variants
+// matches every file.
+func matchesVariants(name string, variants []string) bool {
+	if len(variants) == 0 {
+		return true
+	}
+
+	base := filepath.Base(name)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	family, style := base, "regular"
+	if i := strings.LastIndex(base, "-"); i >= 0 {
+		family, style = base[:i], squashName(base[i+1:])
+	}
+	var spacing string
+	if i := strings.LastIndex(family, "NerdFont"); i >= 0 {
+		spacing = squashName(family[i+len("NerdFont"):])
+	}
+
+	for _, variant := range variants {
+		if variant := squashName(variant); variant == style || spacing != "" && variant == spacing {
+			return true
+		}
+	}
+	return false
+}
+
+// checkArchiveLimits validates the archive's declared contents before anything
+// is extracted. Extraction refuses entries that grow past their declared
+// size, so the declared sizes can be trusted here.
+func checkArchiveLimits(entries []ArchiveEntry, limits Limits) error {
+	if limits.MaxFiles > 0 && len(entries) > limits.MaxFiles {
+		return fmt.Errorf("%w: archive contains %d files, more than the maximum of %d",
+			ErrLimitExceeded, len(entries), limits.MaxFiles)
+	}
+
+	if limits.MaxUncompressedSize > 0 {
+		var total int64
+		for _, entry := range entries {
+			total += entry.Size
+		}
+		if total > limits.MaxUncompressedSize {
+			return fmt.Errorf("%w: archive expands to more than %s",
+				ErrLimitExceeded, FormatSize(limits.MaxUncompressedSize))
+		}
 	}
 
 	return nil
@@ -115,11 +368,11 @@ func (fi *FontInstaller) storeMetadata(fontPath string, font Font) error {
 
 // Uninstall removes a font from the system
 func (fi *FontInstaller) Uninstall(fontName string) error {
-	fontPath := filepath.Join(fi.fontDir, sanitizeFontName(fontName))
+	fontPath := filepath.Join(fi.fontDir, fontDirName(fontName))
 
 	// Check if font exists
 	if _, err := os.Stat(fontPath); os.IsNotExist(err) {
-		return fmt.Errorf("font %s is not installed", fontName)
+		return fmt.Errorf("font %s is %w", fontName, ErrNotInstalled)
 	}
 
 	// Remove the font directory
@@ -132,16 +385,62 @@ func (fi *FontInstaller) Uninstall(fontName string) error {
 
 // UpdateCache runs the font cache update command
 func (fi *FontInstaller) UpdateCache() error {
-	cmd := exec.Command(fi.cacheCmd)
+	cmd := exec.Command(fi.cacheCmd[0], fi.cacheCmd[1:]...)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("updating font cache: %s: %w", output, err)
 	}
 	return nil
 }
 
+// stagingDir creates the directory an install into fontPath is written to
+// until it's complete, in the same font directory so it can be renamed into
+// place
+func (fi *FontInstaller) stagingDir(fontPath string) (string, error) {
+	if err := os.MkdirAll(fi.fontDir, 0755); err != nil {
+		return "", err
+	}
+	staging, err := os.MkdirTemp(fi.fontDir, filepath.Base(fontPath)+".*"+stagingSuffix)
+	if err != nil {
+		return "", err
+	}
+	// Temporary directories are private; fonts must be readable by everyone
+	if err := os.Chmod(staging, 0755); err != nil {
+		os.Remove(staging)
+		return "", err
+	}
+	return staging, nil
+}
+
+// commitStaging moves a completed install into place. A directory already
+// there, such as one left by hand, is replaced, and put back if the install
+// can't take its place. It's set aside the way a forced reinstall does, so
+// if fm is cut short in between, doctor --fix puts it back.
+func commitStaging(staging, fontPath string) error {
+	err := os.Rename(staging, fontPath)
+	if err == nil || errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if _, statErr := os.Lstat(fontPath); statErr != nil {
+		return err
+	}
+
+	replaced := fontPath + setAsideSuffix
+	if err := os.RemoveAll(replaced); err != nil {
+		return err
+	}
+	if err := os.Rename(fontPath, replaced); err != nil {
+		return err
+	}
+	if err := os.Rename(staging, fontPath); err != nil {
+		os.Rename(replaced, fontPath)
+		return err
+	}
+	return os.RemoveAll(replaced)
+}
+
 // IsInstalled checks if a font is installed
 func (fi *FontInstaller) IsInstalled(fontName string) bool {
-	fontPath := filepath.Join(fi.fontDir, sanitizeFontName(fontName))
+	fontPath := filepath.Join(fi.fontDir, fontDirName(fontName))
 	if _, err := os.Stat(fontPath); os.IsNotExist(err) {
 		return false
 	}
@@ -159,7 +458,7 @@ func (fi *FontInstaller) IsInstalled(fontName string) bool {
 		return nil
 	})
 	if err != nil {
-		fmt.Printf("error walking path: %v", err)
+		fi.log().Warn("failed to check installed font", "font", fontName, "err", err)
 		return false
 	}
 
@@ -168,11 +467,41 @@ func (fi *FontInstaller) IsInstalled(fontName string) bool {
 
 // Helper functions
 
+// FontFormats are the formats an install can prefer when an archive has
+// both, for WithFormat
+var FontFormats = []string{"ttf", "otf"}
+
+// formatMetaKey is the Font.Meta key holding the format a font was installed
+// in, when one was preferred
+const formatMetaKey = "format"
+
 func isFontFile(name string) bool {
 	ext := strings.ToLower(filepath.Ext(name))
 	return ext == ".ttf" || ext == ".otf"
 }
 
+// preferredFaces returns the faces an archive has font files of format for,
+// by faceName, so their files in the other format can be skipped
+func preferredFaces(entries []ArchiveEntry, format string) map[string]bool {
+	if format == "" {
+		return nil
+	}
+	faces := make(map[string]bool)
+	for _, entry := range entries {
+		if isFontFile(entry.Name) && fileFormat(entry.Name) == format {
+			faces[faceName(entry.Name)] = true
+		}
+	}
+	return faces
+}
+
+// faceName identifies a face across formats by its file name without the
+// directory and extension, e.g. "firacode-bold" for "otf/FiraCode-Bold.otf"
+func faceName(name string) string {
+	base := filepath.Base(name)
+	return strings.ToLower(strings.TrimSuffix(base, filepath.Ext(base)))
+}
+
 func sanitizeFontName(name string) string {
 	// Remove any potentially problematic characters from font name
 	name = strings.Map(func(r rune) rune {
@@ -180,30 +509,133 @@ func sanitizeFontName(name string) string {
 			return r
 		}
 		return '-'
-	}, name)
+	}, stripAccents(name))
 	return strings.Trim(name, "-")
 }
 
-func (fi *FontInstaller) extractFontFile(file *zip.File, destPath string) error {
+// download is a downloaded font or archive, held in memory, or in
+// low-memory mode spooled to a temporary file
+type download struct {
+	data  []byte
+	spool *os.File
+	size  int64
+}
+
+// readDownload reads a download along with its SHA-256, which is only
+// computed when no digest is known already. In low-memory mode it is spooled
+// to a temporary file and hashed on the way, and archives are later read
+// from the file instead of being held in memory.
+func readDownload(data io.Reader, lowMemory bool, digest string) (*download, string, error) {
+	if !lowMemory {
+		buf := new(bytes.Buffer)
+		if _, err := io.Copy(buf, data); err != nil {
+			return nil, "", err
+		}
+		dl := &download{data: buf.Bytes(), size: int64(buf.Len())}
+		if digest != "" {
+			return dl, digest, nil
+		}
+		sum := sha256.Sum256(buf.Bytes())
+		return dl, hex.EncodeToString(sum[:]), nil
+	}
+
+	spool, err := os.CreateTemp("", "fm-download-*")
+	if err != nil {
+		return nil, "", fmt.Errorf("creating temporary file: %w", err)
+	}
+	dl := &download{spool: spool}
+
+	h := sha256.New()
+	var w io.Writer = spool
+	if digest == "" {
+		w = io.MultiWriter(spool, h)
+	}
+	dl.size, err = io.CopyBuffer(w, data, make([]byte, lowMemoryBufferSize))
+	if err != nil {
+		dl.Close()
+		return nil, "", err
+	}
+	if digest != "" {
+		return dl, digest, nil
+	}
+	return dl, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// entries lists the files in the download using the first matching
+// extractor
+func (d *download) entries() ([]ArchiveEntry, error) {
+	if d.spool == nil {
+		return extractEntries(d.data)
+	}
+	return extractEntriesAt(d.spool, d.size)
+}
+
+// head returns the start of the download, enough to tell what it is
+func (d *download) head() []byte {
+	if d.spool == nil {
+		return d.data
+	}
+	header, _ := readArchiveHeader(d.spool, d.size)
+	return header
+}
+
+// Close removes the spooled file, if any. Entries of the download can't be
+// opened afterwards.
+func (d *download) Close() error {
+	if d.spool == nil {
+		return nil
+	}
+	err := d.spool.Close()
+	os.Remove(d.spool.Name())
+	return err
+}
+
+// copyBuffer returns the buffer files are extracted with, nil for the
+// default
+func (o *installOptions) copyBuffer() []byte {
+	if !o.lowMemory {
+		return nil
+	}
+	return make([]byte, lowMemoryBufferSize)
+}
+
+// extractFontFile writes an archive entry into destPath, returning its size
+// and checksum. A nil buf copies with the default buffer.
+func (fi *FontInstaller) extractFontFile(entry ArchiveEntry, destPath string, buf []byte) (InstalledFile, error) {
 	// Open the file from the archive
-	src, err := file.Open()
+	src, err := entry.Open()
 	if err != nil {
-		return fmt.Errorf("opening file in archive: %w", err)
+		return InstalledFile{}, fmt.Errorf("opening file in archive: %w", err)
 	}
 	defer src.Close()
 
 	// Create the destination file
-	destFile := filepath.Join(destPath, filepath.Base(file.Name))
-	dest, err := os.Create(destFile)
+	name := installedFileName(entry.Name)
+	dest, err := os.Create(filepath.Join(destPath, name))
 	if err != nil {
-		return fmt.Errorf("creating destination file: %w", err)
+		return InstalledFile{}, fmt.Errorf("creating destination file: %w", err)
 	}
 	defer dest.Close()
 
-	// Copy the contents
-	if _, err := io.Copy(dest, src); err != nil {
-		return fmt.Errorf("copying file contents: %w", err)
+	// Copy the contents, refusing to write more than the archive declared
+	h := sha256.New()
+	n, err := io.CopyBuffer(io.MultiWriter(dest, h), io.LimitReader(src, entry.Size+1), buf)
+	if err != nil {
+		return InstalledFile{}, fmt.Errorf("copying file contents: %w", err)
+	}
+	if n > entry.Size {
+		return InstalledFile{}, fmt.Errorf("file is larger than its declared size of %d bytes", entry.Size)
+	}
+	info, err := dest.Stat()
+	if err != nil {
+		return InstalledFile{}, fmt.Errorf("copying file contents: %w", err)
 	}
 
-	return nil
+	return InstalledFile{
+		Name:    name,
+		Size:    n,
+		SHA256:  hex.EncodeToString(h.Sum(nil)),
+		Format:  fileFormat(name),
+		ModTime: info.ModTime(),
+	}, nil
 }
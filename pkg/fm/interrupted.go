@@ -0,0 +1,100 @@
+package fm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// stagingSuffix ends the name of the directory an install writes to until
+// it's complete and renamed into place
+const stagingSuffix = ".fm-staging"
+
+// InterruptedInstall is an install that was cut short, e.g. by quitting fm
+// while it ran, and left a partial font directory or a set-aside copy behind
+type InterruptedInstall struct {
+	Dir      string // Font directory, or staging directory, the install was writing
+	Partial  bool   // Dir holds a partially installed font
+	SetAside string // Copy a forced reinstall moved out of the way, if left behind
+	Restored bool   // CleanInterrupted put the set-aside copy back
+}
+
+// Interrupted finds the installs into the manager's font directory that were
+// cut short
+func (m *DefaultManager) Interrupted() ([]InterruptedInstall, error) {
+	entries, err := os.ReadDir(m.installer.fontDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading font directory: %w", err)
+	}
+
+	var found []InterruptedInstall
+	index := make(map[string]int)
+	install := func(dir string) *InterruptedInstall {
+		i, ok := index[dir]
+		if !ok {
+			i = len(found)
+			index[dir] = i
+			found = append(found, InterruptedInstall{Dir: dir})
+		}
+		return &found[i]
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(m.installer.fontDir, entry.Name())
+		if dir, ok := strings.CutSuffix(path, setAsideSuffix); ok {
+			install(dir).SetAside = path
+		} else if strings.HasSuffix(path, stagingSuffix) {
+			install(path).Partial = true
+		}
+	}
+	return found, nil
+}
+
+// CleanInterrupted removes what interrupted installs left behind. Partial
+// font directories are removed, and copies moved aside by a forced reinstall
+// are put back, unless the reinstall completed and only its cleanup was cut
+// short.
+func (m *DefaultManager) CleanInterrupted() ([]InterruptedInstall, error) {
+	interrupted, err := m.Interrupted()
+	if err != nil || len(interrupted) == 0 {
+		return nil, err
+	}
+
+	var errs []error
+	for i := range interrupted {
+		if err := interrupted[i].clean(); err != nil {
+			errs = append(errs, fmt.Errorf("cleaning up %s: %w", interrupted[i].Dir, err))
+		}
+	}
+	if err := m.UpdateCache(); err != nil {
+		errs = append(errs, err)
+	}
+	return interrupted, errors.Join(errs...)
+}
+
+func (i *InterruptedInstall) clean() error {
+	if i.Partial {
+		if err := os.RemoveAll(i.Dir); err != nil {
+			return err
+		}
+	}
+	if i.SetAside == "" {
+		return nil
+	}
+	if _, err := os.Stat(i.Dir); err == nil {
+		return os.RemoveAll(i.SetAside)
+	}
+	if err := os.Rename(i.SetAside, i.Dir); err != nil {
+		return err
+	}
+	i.Restored = true
+	return nil
+}
@@ -0,0 +1,84 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// DefaultIPFSGateway is the public gateway used when none is configured
+const DefaultIPFSGateway = "https://ipfs.io"
+
+// IPFSSource installs font bundles pinned on IPFS through an HTTP gateway.
+// Content is addressed by CID, so fonts are installed with
+// "MyFont@ipfs:<cid>" rather than found by searching.
+type IPFSSource struct {
+	gateway string
+	client  *http.Client
+}
+
+// NewIPFSSource creates an IPFS source that fetches through gateway, or
+// DefaultIPFSGateway when gateway is empty
+func NewIPFSSource(gateway string, opts ...SourceOption) *IPFSSource {
+	if gateway == "" {
+		gateway = DefaultIPFSGateway
+	}
+
+	o := newSourceOptions(opts)
+	return &IPFSSource{
+		gateway: strings.TrimSuffix(gateway, "/"),
+		client:  o.client,
+	}
+}
+
+func (s *IPFSSource) Name() string {
+	return "ipfs"
+}
+
+// Search never finds anything; IPFS content can only be installed by CID
+func (s *IPFSSource) Search(ctx context.Context, name string) ([]Font, error) {
+	return nil, nil
+}
+
+// cidPattern matches a CIDv0 or base32 CIDv1, optionally followed by a path
+// within it
+var cidPattern = regexp.MustCompile(`^(Qm[1-9A-HJ-NP-Za-km-z]{44}|b[a-z2-7]{50,})(/[^?#]*)?$`)
+
+// Resolve maps a CID, or a path inside one, to its gateway URL
+func (s *IPFSSource) Resolve(ctx context.Context, name, ref string) (Font, error) {
+	ref = strings.TrimPrefix(ref, "/ipfs/")
+	if !cidPattern.MatchString(ref) {
+		return Font{}, fmt.Errorf("invalid CID %q", ref)
+	}
+
+	cid, _, _ := strings.Cut(ref, "/")
+	return Font{
+		Name:   name,
+		Source: s.Name(),
+		URL:    fmt.Sprintf("%s/ipfs/%s", s.gateway, ref),
+		Meta:   map[string]string{"cid": cid},
+	}, nil
+}
+
+// Download fetches the content as a tarball, which works for a single
+// archive as well as a directory of font files
+func (s *IPFSSource) Download(ctx context.Context, font Font) (io.ReadCloser, error) {
+	if font.URL == "" {
+		return nil, fmt.Errorf("font %s has no CID; install it with %s@ipfs:<cid>", font.Name, font.Name)
+	}
+	return openURL(ctx, s.client, font.URL+"?format=tar")
+}
+
+// Describe reports the source; content addressed downloads aren't cached
+func (s *IPFSSource) Describe() SourceInfo {
+	return describeSource("ipfs", nil, "")
+}
+
+// CheckHealth requests the empty inline CID, which every gateway can serve
+// without touching the network
+func (s *IPFSSource) CheckHealth(ctx context.Context) SourceHealth {
+	return checkEndpoint(ctx, s.client, s.gateway+"/ipfs/bafkqaaa")
+}
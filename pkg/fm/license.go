@@ -0,0 +1,188 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrLicenseNotAllowed is returned when a font's license isn't on the
+// allowed list and the install doesn't override the policy
+var ErrLicenseNotAllowed = errors.New("license not allowed")
+
+// maxLicenseSize bounds how much of a license file is read for detection
+const maxLicenseSize = 1 << 20
+
+// licenseSignatures identify licenses by phrases from their text, checked in
+// order against the upper-cased text with whitespace collapsed. Every phrase
+// of an entry must be present.
+var licenseSignatures = []struct {
+	id      string
+	phrases []string
+}{
+	{"OFL-1.1", []string{"SIL OPEN FONT LICENSE", "VERSION 1.1"}},
+	{"OFL-1.0", []string{"SIL OPEN FONT LICENSE", "VERSION 1.0"}},
+	{"UFL-1.0", []string{"UBUNTU FONT LICENCE"}},
+	{"Apache-2.0", []string{"APACHE LICENSE", "VERSION 2.0"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "VERSION 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE", "VERSION 2"}},
+	{"Bitstream-Vera", []string{"BITSTREAM VERA"}},
+	{"CC0-1.0", []string{"CC0 1.0 UNIVERSAL"}},
+	{"MIT", []string{"PERMISSION IS HEREBY GRANTED, FREE OF CHARGE"}},
+}
+
+// identifyLicense returns the SPDX identifier of the license text, or ""
+func identifyLicense(text string) string {
+	text = strings.ToUpper(strings.Join(strings.Fields(text), " "))
+	for _, sig := range licenseSignatures {
+		matched := true
+		for _, phrase := range sig.phrases {
+			if !strings.Contains(text, phrase) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return sig.id
+		}
+	}
+	return ""
+}
+
+// isLicenseFile reports whether an archive entry looks like license text,
+// e.g. LICENSE, LICENSE.txt, OFL.txt or COPYING
+func isLicenseFile(name string) bool {
+	if isFontFile(name) {
+		return false
+	}
+	base := strings.ToUpper(filepath.Base(name))
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	return strings.HasPrefix(base, "LICENSE") || strings.HasPrefix(base, "LICENCE") ||
+		base == "OFL" || base == "COPYING"
+}
+
+// detectLicense identifies the license of an archive from the first license
+// file it recognizes
+func detectLicense(entries []ArchiveEntry) string {
+	for _, entry := range entries {
+		if !isLicenseFile(entry.Name) || entry.Size > maxLicenseSize {
+			continue
+		}
+		data, err := readEntry(entry)
+		if err != nil {
+			continue
+		}
+		if id := identifyLicense(string(data)); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// FontLicense is the license of an installed font
+type FontLicense struct {
+	Font  Font
+	ID    string   // SPDX identifier, "" when it isn't recognized
+	Files []string // License files installed with the font
+}
+
+// License returns the license of the installed font name: the license
+// recorded when fm installed it, or the one its license files are recognized
+// as. Fonts fm didn't install have no license files.
+func (m *DefaultManager) License(ctx context.Context, name string) (*FontLicense, error) {
+	font, err := m.findInstalled(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if font == nil {
+		return nil, fmt.Errorf("font %q is %w", name, ErrNotInstalled)
+	}
+	return fontLicense(*font)
+}
+
+// Licenses returns the license of every font fm installed, by name
+func (m *DefaultManager) Licenses(ctx context.Context) ([]FontLicense, error) {
+	fonts, err := m.listManaged(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var licenses []FontLicense
+	for _, font := range fonts {
+		if !isManaged(&font) {
+			continue
+		}
+		license, err := fontLicense(font)
+		if err != nil {
+			return nil, err
+		}
+		licenses = append(licenses, *license)
+	}
+	sort.Slice(licenses, func(i, j int) bool {
+		return licenses[i].Font.Name < licenses[j].Font.Name
+	})
+	return licenses, nil
+}
+
+// fontLicense finds the license files of an installed font and identifies
+// them if no license was recorded
+func fontLicense(font Font) (*FontLicense, error) {
+	license := &FontLicense{Font: font, ID: font.Meta["license"]}
+	if !isManaged(&font) {
+		return license, nil
+	}
+
+	// Walk store layout symlinks through the link, like List does
+	dir := font.Meta["directory"]
+	err := filepath.WalkDir(dir+string(filepath.Separator), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isLicenseFile(d.Name()) {
+			license.Files = append(license.Files, filepath.Clean(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing license files of %s: %w", font.Name, err)
+	}
+	sort.Strings(license.Files)
+
+	for _, path := range license.Files {
+		if license.ID != "" {
+			break
+		}
+		if data, err := os.ReadFile(path); err == nil && len(data) <= maxLicenseSize {
+			license.ID = identifyLicense(string(data))
+		}
+	}
+	return license, nil
+}
+
+// checkLicense enforces the allowed license list, reporting whether the
+// install only proceeds because the policy was overridden
+func (o *installOptions) checkLicense(font Font, license string) (overridden bool, err error) {
+	if len(o.allowedLicenses) == 0 {
+		return false, nil
+	}
+	for _, allowed := range o.allowedLicenses {
+		if license != "" && strings.EqualFold(allowed, license) {
+			return false, nil
+		}
+	}
+	if o.overrideLicense {
+		return true, nil
+	}
+
+	detected := license
+	if detected == "" {
+		detected = "an unrecognized license"
+	}
+	return false, fmt.Errorf("%w: %s is licensed under %s (allowed: %s); rerun with --override-license to install it anyway",
+		ErrLicenseNotAllowed, font.Name, detected, strings.Join(o.allowedLicenses, ", "))
+}
@@ -0,0 +1,226 @@
+package fm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// lockfileVersion is the format version written to lockfiles
+const lockfileVersion = 1
+
+// Lockfile pins the fonts of a config to the exact downloads they were
+// installed from, like go.sum does for modules
+type Lockfile struct {
+	Version int          `json:"version"`
+	Fonts   []LockedFont `json:"fonts"`
+}
+
+// LockedFont is the exact install of one font in a config
+type LockedFont struct {
+	Name    string `json:"name"`
+	Source  string `json:"source,omitempty"`
+	Ref     string `json:"ref,omitempty"`     // Source-specific reference, e.g. an IPFS CID
+	Version string `json:"version,omitempty"` // Version recorded at install time
+	URL     string `json:"url,omitempty"`     // Download URL, when the font came from a single file
+	SHA256  string `json:"sha256"`            // Checksum of the downloaded archive
+	Region  string `json:"region,omitempty"`
+	Subsets string `json:"subsets,omitempty"` // Comma separated web font subsets
+
+	// InstallAs is the name the font is installed under, when the config
+	// gives it one with install_as
+	InstallAs string `json:"install_as,omitempty"`
+}
+
+// installName returns the name the locked font is installed under
+func (f LockedFont) installName() string {
+	if f.InstallAs != "" {
+		return f.InstallAs
+	}
+	return f.Name
+}
+
+// ReadLockfile parses a lockfile written by WriteLockfile
+func ReadLockfile(r io.Reader) (*Lockfile, error) {
+	var lock Lockfile
+	if err := json.NewDecoder(r).Decode(&lock); err != nil {
+		return nil, fmt.Errorf("parsing lockfile: %w", err)
+	}
+	if lock.Version != lockfileVersion {
+		return nil, fmt.Errorf("unsupported lockfile version %d", lock.Version)
+	}
+	return &lock, nil
+}
+
+// WriteLockfile writes lock as indented JSON
+func WriteLockfile(w io.Writer, lock *Lockfile) error {
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding lockfile: %w", err)
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing lockfile: %w", err)
+	}
+	return nil
+}
+
+// Check reports fonts of a config that lock has no entry for, which means
+// the lockfile is out of date
+func (l *Lockfile) Check(fonts []Font) error {
+	locked := make(map[string]bool, len(l.Fonts))
+	for _, font := range l.Fonts {
+		locked[installedKey(font.installName())] = true
+	}
+
+	var missing []string
+	for _, font := range fonts {
+		if !locked[installedKey(font.installName())] {
+			missing = append(missing, font.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("lockfile is out of date, %s not locked", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Lock records the installed fonts of a config, with the versions, download
+// URLs and checksums they were installed with. Every font must be installed.
+func (m *DefaultManager) Lock(ctx context.Context, fonts []Font) (*Lockfile, error) {
+	fonts, err := m.ExpandAliases(fonts)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := m.listManaged(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]Font, len(installed))
+	for _, font := range installed {
+		byKey[installedKey(font.Name)] = font
+	}
+
+	lock := &Lockfile{Version: lockfileVersion}
+	var errs []error
+	for _, spec := range MergeConfigs(fonts) {
+		font, ok := byKey[installedKey(spec.installName())]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %w", spec.Name, ErrNotInstalled))
+			continue
+		}
+		if font.Meta["sha256"] == "" {
+			errs = append(errs, fmt.Errorf("%s: no checksum was recorded when it was installed", spec.Name))
+			continue
+		}
+
+		locked := LockedFont{
+			Name:    font.Name,
+			Source:  font.Source,
+			Ref:     spec.Ref,
+			Version: font.Meta["version"],
+			URL:     font.Meta["download_url"],
+			SHA256:  font.Meta["sha256"],
+			Region:  font.Meta["region"],
+			Subsets: font.Meta[subsetsMetaKey],
+
+			InstallAs: font.Meta["install_as"],
+		}
+		if name := font.Meta["name"]; name != "" {
+			locked.Name = name
+		}
+		if locked.URL == "" {
+			locked.URL = font.Meta["url"]
+		}
+		lock.Fonts = append(lock.Fonts, locked)
+	}
+	return lock, errors.Join(errs...)
+}
+
+// InstallLocked reproduces the fonts in lock. Fonts already installed from
+// the locked download are left alone and other copies are replaced. Fonts are
+// downloaded from their locked URL, or from their source when they came from
+// several files, and must match their locked checksum.
+func (m *DefaultManager) InstallLocked(ctx context.Context, lock *Lockfile, opts ...InstallOption) error {
+	var errs []error
+	changed := false
+	for _, locked := range lock.Fonts {
+		installed, err := m.installLocked(ctx, locked, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to install %s: %w", locked.Name, err))
+		}
+		changed = changed || installed
+	}
+
+	if changed {
+		if err := m.UpdateCache(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("encountered errors during installation: %w", err)
+	}
+	return nil
+}
+
+// installLocked installs one locked font, reporting whether anything changed
+func (m *DefaultManager) installLocked(ctx context.Context, locked LockedFont, opts []InstallOption) (bool, error) {
+	current, err := m.findInstalled(ctx, locked.installName())
+	if err != nil {
+		return false, err
+	}
+	if current != nil && strings.EqualFold(current.Meta["sha256"], locked.SHA256) {
+		return false, nil
+	}
+
+	lockedOpts := slices.Clone(opts)
+	lockedOpts = append(lockedOpts, WithSHA256(locked.SHA256))
+	if locked.Region != "" {
+		lockedOpts = append(lockedOpts, WithRegion(locked.Region))
+	}
+	if locked.Subsets != "" {
+		lockedOpts = append(lockedOpts, WithSubsets(strings.Split(locked.Subsets, ",")...))
+	}
+	if locked.InstallAs != "" {
+		lockedOpts = append(lockedOpts, WithInstallAs(locked.InstallAs))
+	}
+	o := m.newInstallOptions(lockedOpts)
+
+	// The locked URL bypasses the source, so ask for its agreement here
+	if source, ok := m.findSource(locked.Source).(EULASource); ok && !o.acceptEULA {
+		return false, fmt.Errorf("%w: %s is distributed under %s; rerun with --accept-eula to accept it",
+			ErrEULANotAccepted, locked.Name, source.EULA(Font{Name: locked.Name, Source: locked.Source}))
+	}
+
+	spec := &Font{
+		Name:   locked.Name,
+		Source: locked.Source,
+		Ref:    locked.Ref,
+		URL:    locked.URL,
+	}
+	if locked.URL != "" {
+		// Downloaded directly, so record what the source would have
+		spec.Meta = make(map[string]string)
+		for key, value := range map[string]string{"version": locked.Version, "region": locked.Region} {
+			if value != "" {
+				spec.Meta[key] = value
+			}
+		}
+	}
+
+	if current != nil {
+		if !isManaged(current) {
+			return false, fmt.Errorf("font %q is installed but not by fm", locked.Name)
+		}
+		if err := m.uninstall(ctx, current.Name); err != nil {
+			return false, fmt.Errorf("removing the installed copy: %w", err)
+		}
+	}
+	if err := m.installNew(ctx, spec, o); err != nil {
+		return current != nil, err
+	}
+	return true, nil
+}
@@ -0,0 +1,115 @@
+package fm_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lockfiles", func() {
+	var (
+		tempDir string
+		archive []byte
+		server  *httptest.Server
+		manager *fm.DefaultManager
+		fonts   []fm.Font
+		ctx     context.Context
+	)
+
+	installedSums := func() map[string]string {
+		installed, err := manager.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		sums := make(map[string]string)
+		for _, font := range installed {
+			sums[font.Name] = font.Meta["sha256"]
+		}
+		return sums
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "font-lock-*")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(tempDir, "user"), 0755)).To(Succeed())
+
+		archive, err = createTestZip(testFont{name: "Direct-Regular", format: "ttf", content: "direct"})
+		Expect(err).NotTo(HaveOccurred())
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(archive)
+		}))
+
+		source := newMockSource()
+		source.meta["TestFont1"] = map[string]string{"version": "v1.2.0"}
+		manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir})
+		Expect(manager.RegisterSource(source)).To(Succeed())
+
+		ctx = context.Background()
+		fonts, err = fm.ParseConfig(bytes.NewBufferString("TestFont1@testsource\n" + server.URL + "/Direct.zip\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manager.InstallFonts(ctx, fonts)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(tempDir)
+	})
+
+	It("should record versions, download URLs and checksums", func() {
+		lock, err := manager.Lock(ctx, fonts)
+		Expect(err).NotTo(HaveOccurred())
+
+		sums := installedSums()
+		Expect(lock.Fonts).To(ConsistOf(
+			fm.LockedFont{Name: "TestFont1", Source: "testsource", Version: "v1.2.0", SHA256: sums["TestFont1"]},
+			fm.LockedFont{Name: "Direct", Source: "url", URL: server.URL + "/Direct.zip", SHA256: sums["Direct"]},
+		))
+
+		buf := new(bytes.Buffer)
+		Expect(fm.WriteLockfile(buf, lock)).To(Succeed())
+		read, err := fm.ReadLockfile(buf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read).To(Equal(lock))
+	})
+
+	It("should refuse to lock fonts that aren't installed", func() {
+		_, err := manager.Lock(ctx, []fm.Font{{Name: "TestFont2", Source: "testsource"}})
+		Expect(err).To(MatchError(ContainSubstring("TestFont2: not installed")))
+	})
+
+	It("should report config fonts missing from the lockfile", func() {
+		lock, err := manager.Lock(ctx, fonts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Check(fonts)).To(Succeed())
+		Expect(lock.Check(append(fonts, fm.Font{Name: "TestFont2"}))).To(MatchError(ContainSubstring("TestFont2 not locked")))
+	})
+
+	It("should reproduce the locked fonts", func() {
+		lock, err := manager.Lock(ctx, fonts)
+		Expect(err).NotTo(HaveOccurred())
+		want := installedSums()
+
+		Expect(manager.Uninstall(ctx, "Direct")).To(Succeed())
+		Expect(manager.InstallLocked(ctx, lock)).To(Succeed())
+		Expect(installedSums()).To(Equal(want))
+	})
+
+	It("should fail when a download no longer matches its checksum", func() {
+		lock, err := manager.Lock(ctx, fonts)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(manager.Uninstall(ctx, "Direct")).To(Succeed())
+		archive, err = createTestZip(testFont{name: "Direct-Regular", format: "ttf", content: "changed"})
+		Expect(err).NotTo(HaveOccurred())
+
+		err = manager.InstallLocked(ctx, lock)
+		Expect(err).To(MatchError(fm.ErrChecksumMismatch))
+		Expect(installedSums()).NotTo(HaveKey("Direct"))
+	})
+})
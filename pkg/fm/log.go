@@ -0,0 +1,29 @@
+package fm
+
+import "log/slog"
+
+// logger returns l, or the default logger when none was set, so library
+// users control where fm logs with WithLogger or slog.SetDefault
+func logger(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return slog.Default()
+	}
+	return l
+}
+
+// log returns the logger of the manager. Details such as where fonts are
+// downloaded from are logged at debug level, problems fm works around, such
+// as a failed font cache refresh, as warnings.
+func (m *DefaultManager) log() *slog.Logger {
+	return logger(m.logger)
+}
+
+// SetLogger sets where the installer logs the files it extracts and skips;
+// nil logs to the default logger
+func (fi *FontInstaller) SetLogger(l *slog.Logger) {
+	fi.logger = l
+}
+
+func (fi *FontInstaller) log() *slog.Logger {
+	return logger(fi.logger)
+}
@@ -1,25 +1,30 @@
 package fm
 
 import (
-	"bufio"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/logandonley/font-manager/internal/platform"
+	"github.com/logandonley/font-manager/internal/scheduler"
+	"github.com/logandonley/font-manager/pkg/spec"
 )
 
 // Manager handles font operations
 type Manager interface {
 	// Install installs a font from any registered source
-	Install(ctx context.Context, name string) error
+	Install(ctx context.Context, name string, opts ...InstallOption) error
 
 	// InstallFromURL installs a font from a direct URL
 	InstallFromURL(ctx context.Context, url string) error
@@ -33,124 +38,535 @@ type Manager interface {
 	// List returns all installed fonts
 	List(ctx context.Context) ([]Font, error)
 
+	// Search finds fonts matching a query across every source, ranked by
+	// how well they match
+	Search(ctx context.Context, query string) ([]SearchResult, error)
+
+	// Upgrade reinstalls installed fonts whose source has a newer release
+	Upgrade(ctx context.Context, names []string, opts ...InstallOption) ([]FontUpgrade, error)
+
+	// Outdated reports installed fonts' versions against their sources'
+	// latest releases
+	Outdated(ctx context.Context, names []string) ([]FontUpgrade, error)
+
 	// RegisterSource adds a new source to search for fonts
 	RegisterSource(source Source) error
 
 	// InstallFromConfig installs fonts from a config file
-	InstallFromConfig(ctx context.Context, reader io.Reader) error
+	InstallFromConfig(ctx context.Context, reader io.Reader, opts ...InstallOption) error
+
+	// InstallFonts installs parsed font specs
+	InstallFonts(ctx context.Context, fonts []Font, opts ...InstallOption) error
 }
 
+var _ Manager = (*DefaultManager)(nil)
+
+// ErrAlreadyInstalled is returned when installing a font that is already
+// installed without WithForce, or whose installed version already satisfies
+// the constraint asked for
+var ErrAlreadyInstalled = errors.New("already installed")
+
+// ErrNotFound is returned when a font or source can't be found
+var ErrNotFound = errors.New("not found")
+
+// ErrNotInstalled is returned when a font asked about or acted on isn't
+// installed
+var ErrNotInstalled = errors.New("not installed")
+
 // DefaultManager provides the standard font management implementation
 type DefaultManager struct {
 	sources   []Source
 	installer *FontInstaller
-	platform  platform.Manager
+	platform  Platform
+	auditLog  *AuditLog
+	aliases   *AliasIndex
+	store     *Store
+
+	// credentials authenticate direct URL downloads, keyed by host
+	credentials map[string]HostCredentials
+
+	// cacheSettings customize the font cache refresh when set
+	cacheSettings *CacheSettings
+
+	// allowedLicenses restricts installs to these licenses when set
+	allowedLicenses []string
+
+	// sourcePriority names the sources searched first, in order
+	sourcePriority []string
+
+	// defaultVariants are the variants installed from a source, keyed by
+	// source name, when an install doesn't choose any
+	defaultVariants map[string][]string
+
+	// defaultFormat is the format installed when an archive has both, unless
+	// an install chooses one
+	defaultFormat string
+
+	// parallelism is how many fonts are installed at once when installing
+	// several, unless an install sets its own; zero is DefaultParallelism
+	parallelism int
+
+	// progress receives the progress of installs when set
+	progress ProgressFunc
+
+	// hooks are called around every install, uninstall and upgrade
+	hooks []Hook
+
+	// systemScope installs into the system font directory
+	systemScope bool
+
+	// scan limits how the system font directory is walked
+	scan ScanOptions
+
+	// downloads keeps downloaded archives for reinstalls when set
+	downloads *Cache
+
+	// lowMemory installs one font at a time and spools downloads to disk
+	lowMemory bool
+
+	// instancer replaces DefaultInstancerCommand when set
+	instancer []string
+
+	// rehash hashes cached downloads and installed files every time instead
+	// of trusting their recorded digests
+	rehash bool
+
+	// client downloads fonts given by a direct URL when set
+	client *http.Client
+
+	// logger receives details of downloads and extraction, and warnings;
+	// nil logs to slog.Default
+	logger *slog.Logger
+
+	// equivalents map patched fonts to their upstream fonts; nil uses the
+	// shipped mapping
+	equivalents []Equivalent
+
+	// rollbackDir keeps the previous install of upgraded fonts, outside the
+	// store layout
+	rollbackDir string
+
+	// userAliases map the aliasKey of names defined by the user to the font
+	// specs they stand for
+	userAliases map[string]string
+
+	// jobs runs installs ahead of background work such as WatchFontDirs
+	jobs      *scheduler.Scheduler
+	jobLimits scheduler.Limits
 }
 
 // NewManager creates a new font manager using platform-specific settings
-func NewManager() (*DefaultManager, error) {
-	platformMgr := platform.New()
+func NewManager(opts ...ManagerOption) (*DefaultManager, error) {
+	m := &DefaultManager{
+		platform:  NewPlatform(),
+		jobLimits: scheduler.DefaultLimits,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.jobs = scheduler.New(m.jobLimits)
 
-	paths, err := platformMgr.GetFontPaths()
+	paths, err := m.platform.GetFontPaths()
 	if err != nil {
 		return nil, fmt.Errorf("getting font paths: %w", err)
 	}
 
-	installer := NewFontInstaller(paths.UserDir)
-
-	return &DefaultManager{
-		installer: installer,
-		platform:  platformMgr,
-	}, nil
+	m.installer = NewFontInstaller(m.installDir(paths))
+	m.installer.SetInstancerCommand(m.instancer...)
+	m.installer.SetLogger(m.logger)
+	m.applyCacheSettings()
+	m.applyLogger()
+	return m, nil
 }
 
-func NewManagerWithPlatform(platform platform.Manager) *DefaultManager {
-	paths, err := platform.GetFontPaths()
+func NewManagerWithPlatform(platform Platform, opts ...ManagerOption) *DefaultManager {
+	m := &DefaultManager{
+		platform:  platform,
+		sources:   make([]Source, 0),
+		jobLimits: scheduler.DefaultLimits,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.jobs = scheduler.New(m.jobLimits)
+
+	// Options such as WithFontDir may have changed the paths
+	paths, err := m.platform.GetFontPaths()
 	if err != nil {
 		panic(fmt.Sprintf("failed to get font paths: %v", err))
 	}
+	m.installer = NewFontInstaller(m.installDir(paths))
+	m.installer.SetInstancerCommand(m.instancer...)
+	m.installer.SetLogger(m.logger)
+	m.applyCacheSettings()
+	m.applyLogger()
+	return m
+}
 
-	return &DefaultManager{
-		installer: NewFontInstaller(paths.UserDir),
-		platform:  platform,
-		sources:   make([]Source, 0),
+// installDir returns the font directory the manager installs into
+func (m *DefaultManager) installDir(paths FontPaths) string {
+	if m.systemScope {
+		return paths.SystemDir
+	}
+	return paths.UserDir
+}
+
+// applyCacheSettings hands the configured cache refresh to the platform and
+// installer once the options have chosen both
+func (m *DefaultManager) applyCacheSettings() {
+	if m.cacheSettings == nil {
+		return
+	}
+	if configurer, ok := m.platform.(CacheConfigurer); ok {
+		configurer.SetCacheSettings(*m.cacheSettings)
+	}
+	m.installer.SetCacheCommand(m.cacheSettings.Command...)
+}
+
+// applyLogger hands the logger to platforms that log, such as before asking
+// for elevated privileges to refresh the font cache
+func (m *DefaultManager) applyLogger() {
+	if configurer, ok := m.platform.(LogConfigurer); ok && m.logger != nil {
+		configurer.SetLogger(m.logger)
 	}
 }
 
 // UpdateCache updates the system font cache
 func (m *DefaultManager) UpdateCache() error {
+	if m.progress != nil {
+		m.progress(Progress{Phase: PhaseCache})
+	}
 	return m.platform.UpdateFontCache()
 }
 
-// ParseFontSpec parses a font specification line into a Font struct
+// specParser parses font specs with versions fm can compare
+var specParser = spec.Parser{
+	CheckVersion: func(version string) error {
+		_, err := ParseVersion(version)
+		return err
+	},
+}
+
+// ParseFontSpec parses a font specification line into a Font struct, using
+// the grammar documented in package spec: a font name, name@source,
+// name@source:ref or a URL, optionally followed by a version constraint such
+// as ">=v3.0.0" or "==v3.2.1" and options such as "variants=Regular,Bold".
+// Blank lines and comments parse to nil. Syntax errors are *spec.SyntaxError.
 func ParseFontSpec(line string) (*Font, error) {
-	// Skip empty lines and comments
-	line = strings.TrimSpace(line)
-	if line == "" || strings.HasPrefix(line, "#") {
-		return nil, nil
+	parsed, err := specParser.Parse(line)
+	if err != nil || parsed == nil {
+		return nil, err
 	}
+	return fontFromSpec(parsed), nil
+}
 
-	// Check if it's a URL
-	if strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
-		_, err := url.Parse(line)
-		if err != nil {
-			return nil, fmt.Errorf("invalid URL: %w", err)
-		}
-		return &Font{
-			Source: "url",
-			URL:    line,
-			Name:   getFontNameFromURL(line),
-		}, nil
+// fontFromSpec converts a parsed spec into the font it describes
+func fontFromSpec(s *spec.Spec) *Font {
+	font := &Font{
+		Name:       s.Name,
+		Source:     s.Source,
+		Ref:        s.Ref,
+		URL:        s.URL,
+		MinVersion: s.MinVersion,
+		Version:    s.Version,
+		Options:    s.Options,
 	}
-
-	// Check for source specification with @
-	parts := strings.Split(line, "@")
-	name := strings.TrimSpace(parts[0])
-	source := ""
-	if len(parts) > 1 {
-		source = strings.TrimSpace(parts[1])
+	if s.Comment != "" {
+		font.Meta = map[string]string{noteMetaKey: s.Comment}
 	}
+	if font.URL != "" {
+		font.Source = "url"
+		font.Name = getFontNameFromURL(font.URL)
+	}
+	return font
+}
 
-	return &Font{
-		Name:   name,
-		Source: source,
-	}, nil
+// specFromFont is the inverse of fontFromSpec
+func specFromFont(font Font) spec.Spec {
+	s := spec.Spec{
+		Name:       font.Name,
+		Source:     font.Source,
+		Ref:        font.Ref,
+		URL:        font.URL,
+		MinVersion: font.MinVersion,
+		Version:    font.Version,
+		Options:    font.Options,
+		Comment:    Note(font),
+	}
+	if s.URL != "" {
+		s.Name, s.Source = "", ""
+	}
+	return s
 }
 
 // InstallFromConfig implements bulk font installation from a config file
-func (m *DefaultManager) InstallFromConfig(ctx context.Context, reader io.Reader) error {
-	scanner := bufio.NewScanner(reader)
-	var errors []error
+func (m *DefaultManager) InstallFromConfig(ctx context.Context, reader io.Reader, opts ...InstallOption) error {
+	fonts, err := ParseConfig(reader)
+	errs := append([]error{err}, m.installFonts(ctx, fonts, m.newInstallOptions(opts))...)
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("encountered errors during installation: %w", err)
+	}
+	return nil
+}
 
-	for scanner.Scan() {
-		font, err := ParseFontSpec(scanner.Text())
-		if err != nil {
-			errors = append(errors, err)
+// InstallFonts installs parsed font specs, such as merged configs, honoring
+// their version constraints. Fonts that are already installed are skipped, so
+// installing the same list again succeeds without changing anything.
+func (m *DefaultManager) InstallFonts(ctx context.Context, fonts []Font, opts ...InstallOption) error {
+	if err := errors.Join(m.installFonts(ctx, fonts, m.newInstallOptions(opts))...); err != nil {
+		return fmt.Errorf("encountered errors during installation: %w", err)
+	}
+	return nil
+}
+
+// InstallEach installs parsed font specs like InstallFonts and returns the
+// outcome of each in list order, for callers reporting them one by one. The
+// error covers the list as a whole: conflicting specs or a failed font cache
+// refresh. Fonts that are already installed report ErrAlreadyInstalled.
+func (m *DefaultManager) InstallEach(ctx context.Context, fonts []Font, opts ...InstallOption) ([]error, error) {
+	fonts, err := m.ExpandAliases(fonts)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCoexistence(fonts); err != nil {
+		return nil, err
+	}
+	results := m.installEach(ctx, fonts, m.newInstallOptions(opts))
+	if slices.Contains(results, nil) {
+		return results, m.UpdateCache()
+	}
+	return results, nil
+}
+
+// installFonts attempts every font and refreshes the font cache once at the
+// end, returning the errors encountered
+func (m *DefaultManager) installFonts(ctx context.Context, fonts []Font, o *installOptions) []error {
+	fonts, err := m.ExpandAliases(fonts)
+	if err != nil {
+		return []error{err}
+	}
+	if err := checkCoexistence(fonts); err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	installed := 0
+	for i, err := range m.installEach(ctx, fonts, o) {
+		if errors.Is(err, ErrAlreadyInstalled) {
 			continue
 		}
-		if font == nil {
-			continue // Skip empty lines and comments
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to install %s: %w", fonts[i].Name, err))
+			continue
 		}
+		installed++
+	}
 
-		// For both URL and source-specific fonts, we can use the regular Install
-		// The Font struct already contains the necessary source and URL information
-		err = m.Install(ctx, font.Name)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("failed to install %s: %w", font.Name, err))
+	// Refresh the cache once for the whole manifest
+	if installed > 0 {
+		if err := m.UpdateCache(); err != nil {
+			errs = append(errs, err)
 		}
 	}
+	return errs
+}
+
+// installEach installs every spec, up to the configured parallelism at once,
+// and returns the outcome of each in list order. The font cache is left to
+// the caller.
+func (m *DefaultManager) installEach(ctx context.Context, fonts []Font, o *installOptions) []error {
+	workers := min(max(o.parallelism, 1), len(fonts))
+	if o.lowMemory {
+		// Every worker holds a whole archive in memory
+		workers = min(workers, 1)
+	}
+	results := make([]error, len(fonts))
+
+	// Workers take fonts in order; results keep the order of the list
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				// Fonts not started yet are skipped once cancelled
+				if err := ctx.Err(); err != nil {
+					results[i] = err
+					continue
+				}
+				results[i] = m.installSpec(ctx, &fonts[i], o)
+			}
+		}()
+	}
+	for i := range fonts {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
+
+// fontSpec formats a parsed font back into the form Install accepts
+func fontSpec(font *Font) string {
+	switch {
+	case font.URL != "":
+		return font.URL
+	case font.Ref != "":
+		return font.Name + "@" + font.Source + ":" + font.Ref
+	case font.Source != "":
+		return font.Name + "@" + font.Source
+	default:
+		return font.Name
+	}
+}
+
+// installSpec installs a parsed font spec, honoring its version constraint,
+// and leaves the font cache update to the caller
+func (m *DefaultManager) installSpec(ctx context.Context, spec *Font, o *installOptions) (err error) {
+	o = m.withProgress(o, spec)
+	o.reportProgress(Progress{Font: spec.Name, Phase: PhaseResolve})
+	defer func() {
+		o.reportProgress(Progress{Font: spec.Name, Phase: PhaseDone, Err: err})
+	}()
+
+	// Someone is waiting for installs; background work waits for them
+	release, err := m.jobs.Acquire(ctx, scheduler.Interactive)
+	if err != nil {
+		return err
+	}
+	defer release()
 
-	if err := scanner.Err(); err != nil {
-		errors = append(errors, fmt.Errorf("error reading config: %w", err))
+	// The deferred progress report still needs o when the options are wrong
+	specOpts, err := o.withSpecOptions(spec)
+	if err != nil {
+		return err
+	}
+	if spec.MinVersion != "" || spec.Version != "" {
+		return m.ensureVersion(ctx, spec, specOpts)
 	}
+	return m.installNew(ctx, spec, specOpts)
+}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("encountered errors during installation: %v", errors)
+// ensureVersion installs spec unless a version satisfying its constraint is
+// already installed, reinstalling other or unversioned copies
+func (m *DefaultManager) ensureVersion(ctx context.Context, spec *Font, o *installOptions) error {
+	installed, err := m.findInstalled(ctx, o.installedName(spec))
+	if err != nil {
+		return err
+	}
+
+	// Forced installs replace the installed copy whatever its version
+	restore := func() {}
+	if installed != nil && !o.force {
+		if ok, _ := m.satisfiesVersion(installed, spec); ok {
+			return fmt.Errorf("font %q is %w", installed.Name, ErrAlreadyInstalled)
+		}
+		upgrade := *o
+		upgrade.upgrade = true
+		o = &upgrade
+		if err := m.runHooks(ctx, installHookEvent(spec, o)); err != nil {
+			return err
+		}
+		if restore, err = m.keepPrevious(installed); err != nil {
+			return fmt.Errorf("removing outdated version: %w", err)
+		}
 	}
 
+	if err := m.installNew(ctx, spec, o); err != nil {
+		restore()
+		return err
+	}
+
+	// The source may not have a new enough release yet
+	if installed, err = m.findInstalled(ctx, o.installedName(spec)); err != nil || installed == nil {
+		return err
+	}
+	if ok, version := m.satisfiesVersion(installed, spec); !ok {
+		if version == "" {
+			return fmt.Errorf("installed %s but its version is unknown, so %s can't be verified", spec.Name, versionConstraint(spec))
+		}
+		if spec.Version != "" {
+			return fmt.Errorf("installed version %s is not the required %s", version, spec.Version)
+		}
+		return fmt.Errorf("latest available version %s is older than the required %s", version, spec.MinVersion)
+	}
 	return nil
 }
 
+// satisfiesVersion reports whether an installed font meets the version
+// constraint of spec, along with the version it has recorded
+func (m *DefaultManager) satisfiesVersion(font *Font, spec *Font) (bool, string) {
+	if spec.Version != "" {
+		return m.isVersion(font, spec.Version)
+	}
+	return m.satisfiesMinVersion(font, spec.MinVersion)
+}
+
+// isVersion reports whether an installed font is exactly version, along with
+// the version it has recorded
+func (m *DefaultManager) isVersion(font *Font, version string) (bool, string) {
+	recorded := font.Meta["version"]
+	if recorded == "" {
+		return false, ""
+	}
+	return m.sameVersion(font.Source, recorded, version), recorded
+}
+
+// sameVersion compares two versions of a source's fonts, falling back to
+// comparing them as text when they don't parse
+func (m *DefaultManager) sameVersion(source, a, b string) bool {
+	va, errA := m.parseSourceVersion(source, a)
+	vb, errB := m.parseSourceVersion(source, b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return va.Compare(vb) == 0
+}
+
+// versionConstraint formats the version constraint of spec
+func versionConstraint(spec *Font) string {
+	if spec.Version != "" {
+		return "==" + spec.Version
+	}
+	return ">=" + spec.MinVersion
+}
+
+// satisfiesMinVersion reports whether an installed font is at least
+// minVersion, along with the version it has recorded
+func (m *DefaultManager) satisfiesMinVersion(font *Font, minVersion string) (bool, string) {
+	recorded := font.Meta["version"]
+	if recorded == "" {
+		return false, ""
+	}
+
+	have, err := m.parseSourceVersion(font.Source, recorded)
+	if err != nil {
+		return false, recorded
+	}
+	want, err := m.parseSourceVersion(font.Source, minVersion)
+	if err != nil {
+		return false, recorded
+	}
+	return have.Compare(want) >= 0, recorded
+}
+
+// findInstalled returns the installed font matching name, or nil
+func (m *DefaultManager) findInstalled(ctx context.Context, name string) (*Font, error) {
+	fonts, err := m.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("checking installation status: %w", err)
+	}
+
+	key := installedKey(name)
+	for i := range fonts {
+		if installedKey(fonts[i].Name) == key {
+			return &fonts[i], nil
+		}
+	}
+	return nil, nil
+}
+
 func getFontNameFromURL(urlStr string) string {
 	// Extract filename from URL and clean it up
 	u, _ := url.Parse(urlStr)
@@ -165,106 +581,431 @@ func getFontNameFromURL(urlStr string) string {
 	return name
 }
 
-func (m *DefaultManager) Install(ctx context.Context, name string) error {
-	// First check if it's already installed
-	installed, err := m.IsInstalled(ctx, name)
+// Install installs the font described by spec (see ParseFontSpec) and
+// refreshes the font cache
+func (m *DefaultManager) Install(ctx context.Context, spec string, opts ...InstallOption) error {
+	font, err := ParseFontSpec(spec)
 	if err != nil {
-		return fmt.Errorf("checking if font is installed: %w", err)
+		return err
 	}
-	if installed {
-		return fmt.Errorf("font %q is already installed", name)
+	if font == nil {
+		return fmt.Errorf("no font specified")
+	}
+	if err := m.expandSpec(font); err != nil {
+		return err
 	}
 
-	// If it looks like a URL, treat it as a direct URL installation
-	if strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
-		font := Font{
-			Name:   getFontNameFromURL(name),
-			Source: "url",
-			URL:    name,
+	if err := m.installSpec(ctx, font, m.newInstallOptions(opts)); err != nil {
+		return err
+	}
+
+	// Update font cache
+	return m.UpdateCache()
+}
+
+// InstallFromURL installs a font from a direct URL
+//
+// Deprecated: Install accepts URLs and applies every install option to them.
+func (m *DefaultManager) InstallFromURL(ctx context.Context, url string) error {
+	return m.Install(ctx, url)
+}
+
+// newInstallOptions applies opts on top of the manager's policies
+func (m *DefaultManager) newInstallOptions(opts []InstallOption) *installOptions {
+	return newInstallOptions(append([]InstallOption{func(o *installOptions) {
+		o.allowedLicenses = m.allowedLicenses
+		o.lowMemory = m.lowMemory
+		o.parallelism = m.parallelism
+		if o.parallelism == 0 {
+			o.parallelism = DefaultParallelism
 		}
+	}}, opts...))
+}
 
-		// Create a simple HTTP client for direct URL downloads
-		client := &http.Client{Timeout: 30 * time.Second}
-		req, err := http.NewRequestWithContext(ctx, "GET", name, nil)
-		if err != nil {
-			return fmt.Errorf("creating request: %w", err)
+// installNew installs a font that isn't installed yet, hands it to its user
+// and the store and records the outcome in the audit log
+func (m *DefaultManager) installNew(ctx context.Context, spec *Font, o *installOptions) (err error) {
+	name := o.installedName(spec)
+	installed, err := m.findInstalled(ctx, name)
+	if err != nil {
+		return fmt.Errorf("checking if font is installed: %w", err)
+	}
+	if installed != nil {
+		if !o.force {
+			return fmt.Errorf("font %q is %w", name, ErrAlreadyInstalled)
 		}
+		restore, asideErr := m.setAside(installed)
+		if asideErr != nil {
+			return asideErr
+		}
+		defer func() { restore(err == nil) }()
+	}
 
-		resp, err := client.Do(req)
-		if err != nil {
-			return fmt.Errorf("downloading font: %w", err)
+	// Upgrades call their hooks before setting the installed copy aside
+	event := installHookEvent(spec, o)
+	if !o.upgrade {
+		if err := m.runHooks(ctx, event); err != nil {
+			return err
 		}
-		defer resp.Body.Close()
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	result, err := m.install(ctx, spec, o)
+	if err == nil {
+		err = m.chownToUser(result.dir)
+	}
+	if err == nil && m.store != nil {
+		if err = m.store.Adopt(result.dir); err != nil {
+			err = fmt.Errorf("adding font to store: %w", err)
 		}
+	}
+	m.recordInstall(fontSpec(spec), o, result, err)
+	if err == nil {
+		event.Font = result.font.Name
+		event.Source = result.font.Source
+		event.Version = result.font.Meta["version"]
+		event.Dir = result.dir
+		m.runAfterHooks(ctx, event)
+	}
+	return err
+}
+
+// setAsideSuffix marks the directory of a font being reinstalled
+const setAsideSuffix = ".fm-replaced"
 
-		// Install the font
-		if err := m.installer.Install(font, resp.Body); err != nil {
-			return fmt.Errorf("installing font: %w", err)
+// setAside moves the directory of an installed font out of the way of its
+// reinstall. The returned function removes it once the reinstall succeeded,
+// or puts it back in place of whatever the failed reinstall left behind.
+func (m *DefaultManager) setAside(font *Font) (func(replaced bool), error) {
+	dir, ok := font.Meta["directory"]
+	if !ok {
+		return nil, fmt.Errorf("font directory information missing")
+	}
+	if removable, err := m.removable(font); err != nil {
+		return nil, err
+	} else if !removable {
+		return nil, fmt.Errorf("cannot replace system font %q", font.Name)
+	}
+
+	aside := dir + setAsideSuffix
+	// A reinstall that was interrupted may have left its copy behind
+	if err := os.RemoveAll(aside); err != nil {
+		return nil, fmt.Errorf("removing %s: %w", aside, err)
+	}
+	if err := os.Rename(dir, aside); err != nil {
+		return nil, fmt.Errorf("moving the installed copy aside: %w", err)
+	}
+
+	return func(replaced bool) {
+		if replaced {
+			os.RemoveAll(aside)
+			return
 		}
+		os.RemoveAll(dir)
+		os.Rename(aside, dir)
+	}, nil
+}
+
+// removable reports whether fm may remove an installed font: one in the user
+// font directory, or in the system one under the system scope or when fm
+// installed it there
+func (m *DefaultManager) removable(font *Font) (bool, error) {
+	paths, err := m.platform.GetFontPaths()
+	if err != nil {
+		return false, fmt.Errorf("getting font paths: %w", err)
+	}
+	dir := font.Meta["directory"]
+	if strings.HasPrefix(dir, paths.UserDir) {
+		return true, nil
+	}
+	return strings.HasPrefix(dir, paths.SystemDir) && (m.systemScope || font.Meta[scopeMetaKey] == ScopeSystem), nil
+}
+
+// scope is the install scope of the manager, ScopeUser or ScopeSystem
+func (m *DefaultManager) scope() string {
+	if m.systemScope {
+		return ScopeSystem
+	}
+	return ScopeUser
+}
 
-		// Update font cache
-		return m.UpdateCache()
+// install resolves a parsed spec to a font and the source providing it and
+// installs it
+func (m *DefaultManager) install(ctx context.Context, spec *Font, o *installOptions) (*installResult, error) {
+	if o.bundle != nil {
+		return o.bundle.install(ctx, m, spec, o)
 	}
 
-	// Check if there's a source specification with @
-	sourceName := ""
-	fontName := name
-	if parts := strings.Split(name, "@"); len(parts) > 1 {
-		fontName = strings.TrimSpace(parts[0])
-		sourceName = strings.TrimSpace(parts[1])
+	// Direct URLs are downloaded like any other source
+	if spec.URL != "" {
+		return m.installFont(ctx, *spec, &urlSource{manager: m, headers: o.headers}, o)
 	}
 
 	// If a specific source is requested, use only that source
-	if sourceName != "" {
-		for _, source := range m.sources {
-			if source.Name() == sourceName {
-				return m.installFromSource(ctx, fontName, source)
+	if spec.Source != "" {
+		source := m.findSource(spec.Source)
+		if source == nil {
+			return nil, fmt.Errorf("source %q %w", spec.Source, ErrNotFound)
+		}
+		if spec.Ref != "" {
+			return m.installFromReference(ctx, spec.Name, spec.Ref, source, o)
+		}
+		return m.installFromSource(ctx, spec.Name, source, o)
+	}
+
+	// Consult the curated alias index before trying every source
+	if m.aliases != nil {
+		if alias, ok := m.aliases.Lookup(ctx, spec.Name); ok {
+			if source := m.findSource(alias.Source); source != nil {
+				// Only a source without the font falls back to the others;
+				// they could install a different font under the alias
+				result, err := m.installFromSource(ctx, alias.Name, source, o)
+				if !errors.Is(err, ErrNotFound) {
+					return result, err
+				}
 			}
 		}
-		return fmt.Errorf("source %q not found", sourceName)
 	}
 
-	// Try all sources in order
+	// Try all sources in order, reporting a source that failed over one that
+	// just doesn't have the font
 	var lastErr error
 	for _, source := range m.sources {
-		err := m.installFromSource(ctx, fontName, source)
+		result, err := m.installFromSource(ctx, spec.Name, source, o)
 		if err == nil {
-			return nil
+			return result, nil
+		}
+		if lastErr == nil || !errors.Is(err, ErrNotFound) || errors.Is(lastErr, ErrNotFound) {
+			lastErr = err
 		}
-		lastErr = err
 	}
 
 	if lastErr != nil {
-		return fmt.Errorf("font %q not found in any source: %v", name, lastErr)
+		return nil, fmt.Errorf("font %q %w in any source: %w", spec.Name, ErrNotFound, lastErr)
 	}
-	return nil
+	return nil, fmt.Errorf("no sources registered")
 }
 
 // Helper method to install from a specific source
-func (m *DefaultManager) installFromSource(ctx context.Context, name string, source Source) error {
+func (m *DefaultManager) installFromSource(ctx context.Context, name string, source Source, o *installOptions) (*installResult, error) {
 	fonts, err := source.Search(ctx, name)
 	if err != nil {
-		return fmt.Errorf("searching in %s: %w", source.Name(), err)
+		return nil, fmt.Errorf("searching in %s: %w", source.Name(), err)
 	}
 
 	if len(fonts) == 0 {
-		return fmt.Errorf("font not found in %s", source.Name())
+		return nil, fmt.Errorf("font %w in %s", ErrNotFound, source.Name())
+	}
+
+	return m.installFont(ctx, fonts[0], source, o)
+}
+
+// installFromReference installs the font a source identifies by ref
+func (m *DefaultManager) installFromReference(ctx context.Context, name, ref string, source Source, o *installOptions) (*installResult, error) {
+	refSource, ok := source.(ReferenceSource)
+	if !ok {
+		return nil, fmt.Errorf("source %q does not accept references", source.Name())
+	}
+
+	font, err := refSource.Resolve(ctx, name, ref)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s in %s: %w", ref, source.Name(), err)
+	}
+
+	return m.installFont(ctx, font, source, o)
+}
+
+// installFont downloads a resolved font from its source and installs it
+func (m *DefaultManager) installFont(ctx context.Context, font Font, source Source, o *installOptions) (*installResult, error) {
+	// Fonts under a license agreement need the user's explicit consent
+	if eulaSource, ok := source.(EULASource); ok && !o.acceptEULA {
+		return nil, fmt.Errorf("%w: %s is distributed under %s; rerun with --accept-eula to accept it",
+			ErrEULANotAccepted, font.Name, eulaSource.EULA(font))
+	}
+
+	// An exact version has to come from a source that can install it, or be
+	// what the source offers anyway
+	if o.version != "" {
+		if versioned, ok := source.(VersionedSource); ok {
+			var err error
+			if font, err = versioned.AtVersion(ctx, font, o.version); err != nil {
+				return nil, fmt.Errorf("resolving %s at %s in %s: %w", font.Name, o.version, source.Name(), err)
+			}
+		} else if current := font.Meta["version"]; !m.sameVersion(source.Name(), current, o.version) {
+			return nil, fmt.Errorf("%s only installs the latest release of %s (%s), not %s",
+				source.Name(), font.Name, orUnknown(current), o.version)
+		}
+	}
+
+	// Configured default variants apply unless the install chose its own
+	if variants := m.defaultVariants[source.Name()]; len(variants) > 0 && len(o.variants) == 0 {
+		copied := *o
+		copied.variants = variants
+		o = &copied
+	}
+	if m.defaultFormat != "" && o.format == "" {
+		copied := *o
+		copied.format = m.defaultFormat
+		o = &copied
+	}
+
+	// Sources that publish regional builds advertise a default region in the
+	// metadata; an explicit region overrides it
+	if _, ok := font.Meta["region"]; ok && o.region != "" {
+		font.Meta = withMeta(font.Meta, "region", strings.ToUpper(o.region))
+	}
+
+	if o.note != "" {
+		font.Meta = withMeta(font.Meta, noteMetaKey, o.note)
+	}
+
+	// Web-oriented sources can download only the subsets that are needed
+	if len(o.subsets) > 0 || o.unicodeRanges != "" {
+		subsetSource, ok := source.(SubsetSource)
+		if !ok {
+			return nil, fmt.Errorf("source %s does not support subset downloads", source.Name())
+		}
+		available, err := subsetSource.Subsets(ctx, font)
+		if err != nil {
+			return nil, fmt.Errorf("listing subsets in %s: %w", source.Name(), err)
+		}
+		selected, err := selectSubsets(available, o.subsets, o.unicodeRanges)
+		if err != nil {
+			return nil, err
+		}
+		font.Meta = withMeta(font.Meta, subsetsMetaKey, strings.Join(selected, ","))
+	}
+
+	// Variable builds are limited to the requested design space after
+	// extraction
+	if len(o.axes) > 0 {
+		if font.Meta[subsetsMetaKey] != "" {
+			return nil, fmt.Errorf("variable axes can't be combined with subsets")
+		}
+		variableSource, ok := source.(VariableSource)
+		if !ok {
+			return nil, fmt.Errorf("source %s does not publish variable fonts", source.Name())
+		}
+		available, err := variableSource.Axes(ctx, font)
+		if err != nil {
+			return nil, fmt.Errorf("listing axes in %s: %w", source.Name(), err)
+		}
+		if err := selectAxes(available, o.axes); err != nil {
+			return nil, fmt.Errorf("%s: %w", font.Name, err)
+		}
+		font.Meta = withMeta(font.Meta, axesMetaKey, FormatAxes(o.axes))
 	}
 
-	data, err := source.Download(ctx, fonts[0])
+	if o.dryRun {
+		downloadURL, err := downloadURL(ctx, source, font)
+		if err != nil {
+			return nil, fmt.Errorf("resolving download from %s: %w", source.Name(), err)
+		}
+		return &installResult{font: font, url: downloadURL}, nil
+	}
+
+	m.log().Debug("downloading", "font", font.Name, "version", orUnknown(font.Meta["version"]), "source", source.Name())
+	data, recording, err := m.download(ctx, source, font, o)
 	if err != nil {
-		return fmt.Errorf("downloading from %s: %w", source.Name(), err)
+		return nil, fmt.Errorf("downloading from %s: %w", source.Name(), err)
 	}
 	defer data.Close()
+	if located, ok := data.(interface{ URL() string }); ok && located.URL() != "" {
+		m.log().Debug("resolved download", "font", font.Name, "url", located.URL())
+	}
+	if o.progress != nil {
+		data = newProgressReader(data, font.Name, o.progress)
+	}
+	if o.fetch != nil {
+		if err := o.fetch(font, data); err != nil {
+			return nil, err
+		}
+		if recording != nil {
+			m.commitDownload(font, recording)
+		}
+		return &installResult{font: font}, nil
+	}
 
-	if err := m.installer.Install(fonts[0], data); err != nil {
-		return fmt.Errorf("installing font: %w", err)
+	// Recorded after the download, whose cache is shared by both scopes
+	font.Meta = withMeta(font.Meta, scopeMetaKey, m.scope())
+	result, err := m.installer.install(font, data, o)
+	if err != nil {
+		return nil, fmt.Errorf("installing font: %w", err)
 	}
+	if o.verifyRender {
+		var glyphs []rune
+		if glyphSource, ok := source.(GlyphSource); ok {
+			glyphs = glyphSource.ExpectedGlyphs(font)
+		}
+		if err := verifyRender(result.dir, glyphs); err != nil {
+			os.RemoveAll(result.dir)
+			return nil, err
+		}
+	}
+	if recording != nil {
+		m.commitDownload(font, recording)
+	}
+	return result, nil
+}
 
-	return m.UpdateCache()
+// commitDownload keeps a recorded download in the download cache. A download
+// that can't be cached is simply fetched again next time.
+func (m *DefaultManager) commitDownload(font Font, recording *cachingBody) {
+	if err := recording.commit(); err != nil {
+		m.log().Debug("caching download failed", "font", font.Name, "err", err)
+	}
+}
+
+// download opens the archive of font, from the download cache when it holds
+// this exact release. Fresh downloads of cacheable releases are recorded,
+// for the caller to commit once the archive has been installed.
+func (m *DefaultManager) download(ctx context.Context, source Source, font Font, o *installOptions) (io.ReadCloser, *cachingBody, error) {
+	key, cacheable := downloadCacheKey(source.Name(), font)
+	if cacheable {
+		if cached, ok := m.downloads.openDownload(key); ok {
+			if m.rehash {
+				cached.sha256 = ""
+			}
+			m.log().Debug("using cached download", "font", font.Name, "url", orUnknown(cached.URL()))
+			return cached, nil, nil
+		}
+	}
+
+	data, err := source.Download(ctx, font)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sized, ok := data.(interface{ Size() int64 }); ok && o.confirmDownload != nil && sized.Size() >= o.confirmDownloadSize {
+		if !o.confirmDownload(font, sized.Size()) {
+			data.Close()
+			return nil, nil, ErrDownloadDeclined
+		}
+	}
+	if !cacheable || m.downloads == nil {
+		return data, nil, nil
+	}
+	recording := m.downloads.recordDownload(key, data)
+	return recording, recording, nil
+}
+
+// withMeta returns a copy of meta with key set, leaving the source's map
+// untouched
+func withMeta(meta map[string]string, key, value string) map[string]string {
+	copied := make(map[string]string, len(meta)+1)
+	for k, v := range meta {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
+
+// findSource returns the registered source with the given name, or nil
+func (m *DefaultManager) findSource(name string) Source {
+	for _, source := range m.sources {
+		if source.Name() == name {
+			return source
+		}
+	}
+	return nil
 }
 
 // RegisterSource adds a new source to search for fonts
@@ -281,13 +1022,44 @@ func (m *DefaultManager) RegisterSource(source Source) error {
 		}
 	}
 
-	// Add the source to our list
-	m.sources = append(m.sources, source)
+	// Keep the sources ordered by priority, then by registration
+	rank := m.sourceRank(source.Name())
+	i := len(m.sources)
+	for i > 0 && m.sourceRank(m.sources[i-1].Name()) > rank {
+		i--
+	}
+	m.sources = slices.Insert(m.sources, i, source)
 	return nil
 }
 
+// sourceRank orders sources by their position in the priority list, with
+// unlisted sources last
+func (m *DefaultManager) sourceRank(name string) int {
+	if i := slices.Index(m.sourcePriority, name); i >= 0 {
+		return i
+	}
+	return len(m.sourcePriority)
+}
+
 // List returns all installed fonts
 func (m *DefaultManager) List(ctx context.Context) ([]Font, error) {
+	return m.listFonts(m.scan)
+}
+
+// listManaged returns the installed fonts for commands that only need the
+// fonts fm installed, skipping known huge system subtrees unless configured
+// otherwise. Fonts fm installed there are still found.
+func (m *DefaultManager) listManaged(ctx context.Context) ([]Font, error) {
+	scan := m.scan
+	if !scan.ScanLargeDirs {
+		scan.skipLarge = true
+	}
+	return m.listFonts(scan)
+}
+
+// listFonts lists the user font directory and the system one, walking the
+// system directory within the scan limits
+func (m *DefaultManager) listFonts(scan ScanOptions) ([]Font, error) {
 	paths, err := m.platform.GetFontPaths()
 	if err != nil {
 		return nil, fmt.Errorf("getting font paths: %w", err)
@@ -303,7 +1075,7 @@ func (m *DefaultManager) List(ctx context.Context) ([]Font, error) {
 	fonts = append(fonts, userFonts...)
 
 	// Optionally read from system directory if we have permission
-	systemFonts, err := m.listFontsInDir(paths.SystemDir)
+	systemFonts, err := m.scanFontsInDir(paths.SystemDir, scan)
 	if err == nil {
 		fonts = append(fonts, systemFonts...)
 	}
@@ -319,13 +1091,39 @@ type FontMetadata struct {
 }
 
 func (m *DefaultManager) listFontsInDir(dir string) ([]Font, error) {
+	return m.scanFontsInDir(dir, ScanOptions{})
+}
+
+// scanFontsInDir lists the fonts in dir, leaving out what scan excludes
+func (m *DefaultManager) scanFontsInDir(dir string, scan ScanOptions) ([]Font, error) {
 	var fonts []Font
+	seen := make(map[string]bool)
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	var visit filepath.WalkFunc
+	visit = func(path string, info os.FileInfo, err error) error {
 		if err != nil {
+			// Fonts can be replaced by a parallel install while walking
+			if os.IsNotExist(err) && path != dir {
+				return nil
+			}
 			return err
 		}
 
+		if scan.skips(dir, path, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Fonts in the store layout are symlinks to a generation directory;
+		// walk them through the link so paths stay inside the font directory
+		if info.Mode()&os.ModeSymlink != 0 && filepath.Dir(path) == filepath.Clean(dir) {
+			if target, err := os.Stat(path); err == nil && target.IsDir() {
+				return filepath.Walk(path+string(filepath.Separator), visit)
+			}
+		}
+
 		// Skip if it's not a font file
 		if info.IsDir() || !isFontFile(info.Name()) {
 			return nil
@@ -345,11 +1143,10 @@ func (m *DefaultManager) listFontsInDir(dir string) ([]Font, error) {
 		}
 
 		// Check if we already have this font in our list
-		for _, existing := range fonts {
-			if existing.Name == fontName {
-				return nil
-			}
+		if seen[fontName] {
+			return nil
 		}
+		seen[fontName] = true
 
 		// Build the font object with metadata
 		font := Font{
@@ -381,13 +1178,22 @@ func (m *DefaultManager) listFontsInDir(dir string) ([]Font, error) {
 			}
 		}
 
+		// Fonts whose directory name was shortened or hashed are listed
+		// under the name they were installed as
+		if name := cmp.Or(font.Meta["install_as"], font.Meta["name"]); name != "" &&
+			fontName == fontDirName(name) && fontName != sanitizeFontName(name) {
+			font.Name = name
+		}
+
 		// Add file path information
 		font.Meta["path"] = path
 		font.Meta["directory"] = fontDir
 
 		fonts = append(fonts, font)
 		return nil
-	})
+	}
+
+	err := filepath.Walk(dir, visit)
 
 	if err != nil {
 		return nil, fmt.Errorf("walking directory %s: %w", dir, err)
@@ -397,43 +1203,67 @@ func (m *DefaultManager) listFontsInDir(dir string) ([]Font, error) {
 }
 
 func (m *DefaultManager) IsInstalled(ctx context.Context, name string) (bool, error) {
-	fonts, err := m.List(ctx)
+	font, err := m.findInstalled(ctx, name)
 	if err != nil {
-		return false, fmt.Errorf("checking installation status: %w", err)
+		return false, err
 	}
+	return font != nil, nil
+}
 
-	// Normalize the name for comparison
-	normalizedName := sanitizeFontName(name)
+func (m *DefaultManager) Uninstall(ctx context.Context, name string) error {
+	if err := m.uninstall(ctx, name); err != nil {
+		return err
+	}
 
-	for _, font := range fonts {
-		if sanitizeFontName(font.Name) == normalizedName {
-			return true, nil
+	// Update the system's font cache
+	if err := m.UpdateCache(); err != nil {
+		// Log the error but don't fail - the font is already removed
+		m.log().Warn("failed to update font cache", "err", err)
+	}
+	return nil
+}
+
+// UninstallFonts uninstalls every font, such as the fonts of a config file,
+// carrying on past failures, and refreshes the font cache once at the end.
+// It returns the outcome of each in list order.
+func (m *DefaultManager) UninstallFonts(ctx context.Context, fonts []Font) []error {
+	results := make([]error, len(fonts))
+	uninstalled := 0
+	for i, font := range fonts {
+		if results[i] = m.uninstall(ctx, font.installName()); results[i] == nil {
+			uninstalled++
 		}
 	}
 
-	return false, nil
+	// Refresh the cache once for the whole list
+	if uninstalled > 0 {
+		if err := m.UpdateCache(); err != nil {
+			// Log the error but don't fail - the fonts are already removed
+			m.log().Warn("failed to update font cache", "err", err)
+		}
+	}
+	return results
 }
 
-func (m *DefaultManager) Uninstall(ctx context.Context, name string) error {
+// uninstall removes an installed font and leaves the font cache update to
+// the caller
+func (m *DefaultManager) uninstall(ctx context.Context, name string) error {
 	// First check if the font is installed and get its metadata
-	fonts, err := m.List(ctx)
+	targetFont, err := m.findInstalled(ctx, name)
 	if err != nil {
-		return fmt.Errorf("checking font installation: %w", err)
+		return err
 	}
 
-	// Normalize the name for comparison
-	normalizedName := sanitizeFontName(name)
-
-	var targetFont *Font
-	for _, font := range fonts {
-		if sanitizeFontName(font.Name) == normalizedName {
-			targetFont = &font
-			break
+	// Names of user aliases remove the font they install
+	if targetFont == nil {
+		if aliased, err := m.expandAlias(Font{Name: name}); err == nil && aliased.Name != name {
+			if targetFont, err = m.findInstalled(ctx, aliased.installName()); err != nil {
+				return err
+			}
 		}
 	}
-
 	if targetFont == nil {
-		return fmt.Errorf("font %q is not installed", name)
+		return fmt.Errorf("font %q is %w", name, ErrNotInstalled)
 	}
 
 	// Get the font directory from metadata
@@ -443,25 +1273,89 @@ func (m *DefaultManager) Uninstall(ctx context.Context, name string) error {
 	}
 
 	// Check if this is in the user directory (we shouldn't remove system fonts)
-	paths, err := m.platform.GetFontPaths()
-	if err != nil {
-		return fmt.Errorf("getting font paths: %w", err)
+	if removable, err := m.removable(targetFont); err != nil {
+		return err
+	} else if !removable {
+		return fmt.Errorf("cannot uninstall system font %q", name)
 	}
 
-	if !strings.HasPrefix(fontDir, paths.UserDir) {
-		return fmt.Errorf("cannot uninstall system font %q", name)
+	event := HookEvent{
+		Action:  HookUninstall,
+		Font:    targetFont.Name,
+		Source:  targetFont.Source,
+		Version: targetFont.Meta["version"],
+		Dir:     fontDir,
+	}
+	if err := m.runHooks(ctx, event); err != nil {
+		return err
 	}
+	if err := m.removeFontDir(targetFont, fontDir); err != nil {
+		return err
+	}
+	m.runAfterHooks(ctx, event)
+	return nil
+}
 
-	// Remove the entire font directory
-	if err := os.RemoveAll(fontDir); err != nil {
+// removeFontDir removes the entire directory of an installed font, recording
+// it in the audit log
+func (m *DefaultManager) removeFontDir(font *Font, dir string) error {
+	err := os.RemoveAll(dir)
+	m.recordAudit(AuditEntry{
+		Action:  "uninstall",
+		Font:    font.Name,
+		Source:  font.Source,
+		Version: font.Meta["version"],
+		Path:    dir,
+	}, err)
+	if err != nil {
 		return fmt.Errorf("removing font directory: %w", err)
 	}
+	return nil
+}
 
-	// Update the system's font cache
-	if err := m.UpdateCache(); err != nil {
-		// Log the error but don't fail - the font is already removed
-		fmt.Fprintf(os.Stderr, "Warning: failed to update font cache: %v\n", err)
+// chownToUser hands installed files to the user they were installed for when
+// the platform acts on behalf of another user
+func (m *DefaultManager) chownToUser(dir string) error {
+	if um, ok := m.platform.(UserPlatform); ok {
+		if err := um.ChownToUser(dir); err != nil {
+			return fmt.Errorf("changing ownership of %s: %w", dir, err)
+		}
 	}
-
 	return nil
 }
+
+// recordInstall writes the outcome of an install to the audit log
+func (m *DefaultManager) recordInstall(name string, o *installOptions, result *installResult, err error) {
+	entry := AuditEntry{Action: "install", Font: name}
+	if o.upgrade {
+		entry.Action = "upgrade"
+	}
+	if result != nil {
+		entry.Font = result.font.Name
+		entry.Source = result.font.Source
+		entry.Version = result.font.Meta["version"]
+		entry.URL = result.font.URL
+		entry.Path = result.dir
+		entry.SHA256 = result.sha256
+		entry.License = result.license
+		entry.LicenseOverride = result.licenseOverridden
+	}
+	m.recordAudit(entry, err)
+}
+
+func (m *DefaultManager) recordAudit(entry AuditEntry, err error) {
+	if m.auditLog == nil {
+		return
+	}
+
+	entry.Result = "success"
+	if err != nil {
+		entry.Result = "failure"
+		entry.Error = err.Error()
+	}
+
+	if err := m.auditLog.Record(entry); err != nil {
+		// Log the error but don't fail - the operation itself already happened
+		m.log().Warn("failed to write audit log", "err", err)
+	}
+}
@@ -4,39 +4,70 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/logandonley/font-manager/internal/platform"
 	"github.com/logandonley/font-manager/pkg/fm"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"golang.org/x/image/font/gofont/goregular"
 )
 
 // Mock platform implementation for testing
 type mockPlatform struct {
-	fontDir string
+	fontDir      string
+	cacheUpdates int
 }
 
-func (m *mockPlatform) GetFontPaths() (platform.FontPaths, error) {
-	return platform.FontPaths{
+func (m *mockPlatform) GetFontPaths() (fm.FontPaths, error) {
+	return fm.FontPaths{
 		SystemDir: filepath.Join(m.fontDir, "system"),
 		UserDir:   filepath.Join(m.fontDir, "user"),
+		HomeDir:   filepath.Join(m.fontDir, "home"),
+		LegacyDirs: []string{
+			filepath.Join(m.fontDir, "legacy"),
+		},
 	}, nil
 }
 
 func (m *mockPlatform) UpdateFontCache() error {
+	m.cacheUpdates++
+	return nil
+}
+
+// Mock platform acting on behalf of another user
+type mockUserPlatform struct {
+	mockPlatform
+	chowned []string
+}
+
+func (m *mockUserPlatform) ChownToUser(path string) error {
+	m.chowned = append(m.chowned, path)
 	return nil
 }
 
 // Mock font source for testing
 type mockSource struct {
 	name     string
-	fonts    map[string][]byte // name -> zip content
-	failures map[string]error  // name -> error
+	fonts    map[string][]byte            // name -> zip content
+	failures map[string]error             // name -> error
+	meta     map[string]map[string]string // name -> metadata returned by Search
+
+	mu        sync.Mutex
+	downloads []string // Names of the fonts downloaded, in order
 }
 
 type testFont struct {
@@ -85,6 +116,7 @@ func newMockSource() *mockSource {
 		name:     "testsource",
 		fonts:    make(map[string][]byte),
 		failures: make(map[string]error),
+		meta:     make(map[string]map[string]string),
 	}
 	testFont1 := testFont{
 		name:    "TestFont1",
@@ -144,6 +176,12 @@ func newMockSource() *mockSource {
 		ms.fonts["TestMulti"] = content
 	}
 
+	// Add a font published in regional subsets
+	if content, err := createTestZip(testFont{name: "TestCJK", format: "otf", content: "fake otf content"}); err == nil {
+		ms.fonts["TestCJK"] = content
+		ms.meta["TestCJK"] = map[string]string{"region": "JP"}
+	}
+
 	ms.failures["FailingFont"] = fmt.Errorf("simulated failure")
 
 	return ms
@@ -162,6 +200,7 @@ func (s *mockSource) Search(_ context.Context, name string) ([]fm.Font, error) {
 		return []fm.Font{{
 			Name:   name,
 			Source: s.name,
+			Meta:   s.meta[name],
 		}}, nil
 	}
 	return nil, nil
@@ -176,9 +215,117 @@ func (s *mockSource) Download(_ context.Context, font fm.Font) (io.ReadCloser, e
 	if !exists {
 		return nil, fmt.Errorf("font not found")
 	}
+	s.mu.Lock()
+	s.downloads = append(s.downloads, font.Name)
+	s.mu.Unlock()
 	return io.NopCloser(bytes.NewReader(content)), nil
 }
 
+// Mock source whose fonts must contain certain glyphs
+type mockGlyphSource struct {
+	*mockSource
+	glyphs []rune
+}
+
+func (s *mockGlyphSource) ExpectedGlyphs(fm.Font) []rune {
+	return s.glyphs
+}
+
+// Mock web font source publishing script subsets
+type mockSubsetSource struct {
+	*mockSource
+	downloaded string // subsets requested by the last download
+}
+
+func (s *mockSubsetSource) Subsets(_ context.Context, _ fm.Font) (map[string]string, error) {
+	return map[string]string{
+		"latin":    "U+0000-00FF, U+0131",
+		"cyrillic": "U+0400-045F",
+		"greek":    "U+0370-03FF",
+	}, nil
+}
+
+func (s *mockSubsetSource) Download(ctx context.Context, font fm.Font) (io.ReadCloser, error) {
+	s.downloaded = font.Meta["subsets"]
+	return s.mockSource.Download(ctx, font)
+}
+
+// Mock source publishing variable builds
+type mockVariableSource struct {
+	*mockSource
+	axes []fm.AxisRange
+}
+
+func (s *mockVariableSource) Axes(_ context.Context, _ fm.Font) ([]fm.AxisRange, error) {
+	return s.axes, nil
+}
+
+// variableFont builds a font file with nothing but an fvar table of axes
+func variableFont(axes ...fm.AxisRange) []byte {
+	fixed := func(v float64) uint32 { return uint32(int32(v * 65536)) }
+
+	fvar := binary.BigEndian.AppendUint16(nil, 1)
+	fvar = binary.BigEndian.AppendUint16(fvar, 0)
+	fvar = binary.BigEndian.AppendUint16(fvar, 16) // axesArrayOffset
+	fvar = binary.BigEndian.AppendUint16(fvar, 2)
+	fvar = binary.BigEndian.AppendUint16(fvar, uint16(len(axes)))
+	fvar = binary.BigEndian.AppendUint16(fvar, 20) // axisSize
+	fvar = binary.BigEndian.AppendUint32(fvar, 0)  // no named instances
+	for _, axis := range axes {
+		fvar = append(fvar, axis.Tag...)
+		fvar = binary.BigEndian.AppendUint32(fvar, fixed(axis.Min))
+		fvar = binary.BigEndian.AppendUint32(fvar, fixed(axis.Min))
+		fvar = binary.BigEndian.AppendUint32(fvar, fixed(axis.Max))
+		fvar = binary.BigEndian.AppendUint32(fvar, 0)
+	}
+
+	font := binary.BigEndian.AppendUint32(nil, 0x00010000)
+	font = binary.BigEndian.AppendUint16(font, 1) // numTables
+	font = append(font, make([]byte, 6)...)
+	font = append(font, "fvar"...)
+	font = binary.BigEndian.AppendUint32(font, 0)
+	font = binary.BigEndian.AppendUint32(font, 28)
+	font = binary.BigEndian.AppendUint32(font, uint32(len(fvar)))
+	return append(font, fvar...)
+}
+
+type mockEULASource struct {
+	*mockSource
+}
+
+func (s *mockEULASource) EULA(_ fm.Font) string {
+	return "the Test EULA"
+}
+
+// Mock source whose catalog can't be reached
+type mockBrokenSource struct {
+	*mockSource
+}
+
+func (s *mockBrokenSource) Search(_ context.Context, _ string) ([]fm.Font, error) {
+	return nil, fmt.Errorf("catalog unavailable")
+}
+
+// Mock source whose search returns a fixed list of names, like catalogs
+// matching loosely
+type mockCatalogSearchSource struct {
+	*mockSource
+	names   []string
+	pending bool // Return the names unverified, like Nerd Fonts
+}
+
+func (s *mockCatalogSearchSource) Search(_ context.Context, _ string) ([]fm.Font, error) {
+	var fonts []fm.Font
+	for _, name := range s.names {
+		font := fm.Font{Name: name, Source: s.name}
+		if s.pending {
+			font.Meta = map[string]string{"pending": "true"}
+		}
+		fonts = append(fonts, font)
+	}
+	return fonts, nil
+}
+
 var _ = Describe("Font Manager", func() {
 	var (
 		manager     *fm.DefaultManager
@@ -276,6 +423,110 @@ var _ = Describe("Font Manager", func() {
 				Expect(hasOTF).To(BeTrue(), "Should have OTF file")
 			})
 		})
+		Context("with regional subsets", func() {
+			findFont := func(name string) *fm.Font {
+				fonts, err := manager.List(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				for _, f := range fonts {
+					if f.Name == name {
+						return &f
+					}
+				}
+				return nil
+			}
+
+			It("should keep the source's default region", func() {
+				Expect(manager.Install(ctx, "TestCJK")).To(Succeed())
+
+				font := findFont("TestCJK")
+				Expect(font).NotTo(BeNil())
+				Expect(font.Meta["region"]).To(Equal("JP"))
+			})
+
+			It("should apply an explicit region", func() {
+				Expect(manager.Install(ctx, "TestCJK", fm.WithRegion("kr"))).To(Succeed())
+
+				font := findFont("TestCJK")
+				Expect(font).NotTo(BeNil())
+				Expect(font.Meta["region"]).To(Equal("KR"))
+			})
+
+			It("should not add a region to fonts without regional subsets", func() {
+				Expect(manager.Install(ctx, "TestFont1", fm.WithRegion("KR"))).To(Succeed())
+
+				font := findFont("TestFont1")
+				Expect(font).NotTo(BeNil())
+				Expect(font.Meta).NotTo(HaveKey("region"))
+			})
+		})
+
+		Context("with archive limits", func() {
+			It("should reject archives with too many files", func() {
+				err := manager.Install(ctx, "TestMulti", fm.WithLimits(fm.Limits{MaxFiles: 1}))
+				Expect(err).To(MatchError(fm.ErrLimitExceeded))
+
+				installed, err := manager.IsInstalled(ctx, "TestMulti")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(installed).To(BeFalse())
+			})
+
+			It("should reject downloads larger than the maximum size", func() {
+				err := manager.Install(ctx, "TestFont1", fm.WithLimits(fm.Limits{MaxDownloadSize: 16}))
+				Expect(err).To(MatchError(fm.ErrLimitExceeded))
+			})
+
+			It("should reject archives that expand past the maximum size", func() {
+				err := manager.Install(ctx, "TestFont1", fm.WithLimits(fm.Limits{MaxUncompressedSize: 8}))
+				Expect(err).To(MatchError(fm.ErrLimitExceeded))
+			})
+
+			It("should explain when an HTML page is received", func() {
+				installer := fm.NewFontInstaller(filepath.Join(tempDir, "user"))
+				err := installer.Install(fm.Font{Name: "Broken"}, strings.NewReader("<!DOCTYPE html><html><body>Not found</body></html>"))
+				Expect(err).To(MatchError(ContainSubstring("HTML page")))
+			})
+		})
+
+		Context("with confirmation of large downloads", func() {
+			var server *httptest.Server
+
+			BeforeEach(func() {
+				archive, err := createTestZip(testFont{name: "Large-Regular", format: "ttf", content: "large"})
+				Expect(err).NotTo(HaveOccurred())
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write(archive)
+				}))
+			})
+
+			AfterEach(func() {
+				server.Close()
+			})
+
+			It("should only download once confirmed", func() {
+				var asked []int64
+				declined := fm.WithConfirmDownload(10, func(font fm.Font, size int64) bool {
+					asked = append(asked, size)
+					return false
+				})
+				Expect(manager.Install(ctx, server.URL+"/Large.zip", declined)).To(MatchError(fm.ErrDownloadDeclined))
+				Expect(asked).To(ConsistOf(BeNumerically(">", 10)))
+				installed, err := manager.IsInstalled(ctx, "Large")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(installed).To(BeFalse())
+
+				accepted := fm.WithConfirmDownload(10, func(fm.Font, int64) bool { return true })
+				Expect(manager.Install(ctx, server.URL+"/Large.zip", accepted)).To(Succeed())
+			})
+
+			It("should not ask about downloads below the size", func() {
+				never := fm.WithConfirmDownload(1<<30, func(fm.Font, int64) bool {
+					Fail("asked to confirm a small download")
+					return false
+				})
+				Expect(manager.Install(ctx, server.URL+"/Large.zip", never)).To(Succeed())
+			})
+		})
+
 		It("should install a font successfully", func() {
 			Expect(manager.Install(ctx, "TestFont1")).To(Succeed())
 
@@ -297,49 +548,2076 @@ var _ = Describe("Font Manager", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("already installed"))
 		})
+
+		It("should replace installed fonts when forced", func() {
+			Expect(manager.Install(ctx, "TestFont1")).To(Succeed())
+			Expect(manager.Install(ctx, "TestFont1")).To(MatchError(fm.ErrAlreadyInstalled))
+
+			// A corrupted install: a truncated file and a stray one
+			dir := filepath.Join(tempDir, "user", "TestFont1")
+			Expect(os.WriteFile(filepath.Join(dir, "TestFont1.ttf"), nil, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "partial.tmp"), []byte("x"), 0644)).To(Succeed())
+
+			Expect(manager.Install(ctx, "TestFont1", fm.WithForce())).To(Succeed())
+			content, err := os.ReadFile(filepath.Join(dir, "TestFont1.ttf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(content).NotTo(BeEmpty())
+			Expect(filepath.Join(dir, "partial.tmp")).NotTo(BeAnExistingFile())
+			Expect(dir + ".fm-replaced").NotTo(BeAnExistingFile())
+
+			fonts, err := manager.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fonts).To(HaveLen(1))
+		})
+
+		It("should keep the installed font when a forced reinstall fails", func() {
+			Expect(manager.Install(ctx, "TestFont1")).To(Succeed())
+			mockSource1.failures["TestFont1"] = fmt.Errorf("simulated failure")
+
+			err := manager.Install(ctx, "TestFont1@testsource", fm.WithForce())
+			Expect(err).To(MatchError(ContainSubstring("simulated failure")))
+			Expect(filepath.Join(tempDir, "user", "TestFont1", "TestFont1.ttf")).To(BeAnExistingFile())
+			Expect(filepath.Join(tempDir, "user", "TestFont1.fm-replaced")).NotTo(BeAnExistingFile())
+
+			installed, err := manager.IsInstalled(ctx, "TestFont1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installed).To(BeTrue())
+		})
+
+		It("should recognize installed fonts whatever the spec names as source", func() {
+			Expect(manager.Install(ctx, "TestFont1")).To(Succeed())
+			err := manager.Install(ctx, "TestFont1@testsource")
+			Expect(err).To(MatchError(ContainSubstring("already installed")))
+		})
+
+		It("should honor version constraints given to Install", func() {
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v3.2.1"}
+			Expect(manager.Install(ctx, "TestFont1@testsource >=v3.0")).To(Succeed())
+			Expect(manager.Install(ctx, "TestFont1@testsource >=v3.0")).To(MatchError(fm.ErrAlreadyInstalled))
+		})
+
+		It("should apply install options to direct URLs", func() {
+			archive, err := createTestZip(testFont{name: "Direct-Regular", format: "ttf", content: "direct"})
+			Expect(err).NotTo(HaveOccurred())
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write(archive)
+			}))
+			defer server.Close()
+
+			// The announced size is rejected before the body is read
+			err = manager.Install(ctx, server.URL+"/Direct.zip", fm.WithLimits(fm.Limits{MaxDownloadSize: 16}))
+			Expect(err).To(MatchError(ContainSubstring("download is")))
+
+			Expect(manager.Install(ctx, server.URL+"/Direct.zip")).To(Succeed())
+			err = manager.Install(ctx, server.URL+"/Direct.zip")
+			Expect(err).To(MatchError(ContainSubstring("already installed")))
+		})
+
+		It("should report fonts and sources that can't be found", func() {
+			err := manager.Install(ctx, "Missing")
+			Expect(err).To(MatchError(fm.ErrNotFound))
+			Expect(err).To(MatchError(ContainSubstring(`font "Missing" not found in any source`)))
+
+			err = manager.Install(ctx, "TestFont1@nosuchsource")
+			Expect(err).To(MatchError(fm.ErrNotFound))
+			Expect(err).To(MatchError(`source "nosuchsource" not found`))
+		})
+
+		Context("with names too long or foreign for a directory name", func() {
+			longName := strings.Repeat("Extended Condensed ", 20) + "Sans"
+
+			BeforeEach(func() {
+				for _, name := range []string{longName, "思源黑体", "源ノ角ゴシック"} {
+					archive, err := createTestZip(testFont{name: strings.Repeat("Face", 80), format: "otf", content: name})
+					Expect(err).NotTo(HaveOccurred())
+					mockSource1.fonts[name] = archive
+				}
+			})
+
+			It("should install them into short, distinct directories", func() {
+				Expect(manager.Install(ctx, longName+"@testsource")).To(Succeed())
+				Expect(manager.Install(ctx, "思源黑体@testsource")).To(Succeed())
+				Expect(manager.Install(ctx, "源ノ角ゴシック@testsource")).To(Succeed())
+
+				entries, err := os.ReadDir(filepath.Join(tempDir, "user"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(3))
+				for _, entry := range entries {
+					Expect(len(entry.Name())).To(BeNumerically("<=", 64))
+					files, err := os.ReadDir(filepath.Join(tempDir, "user", entry.Name()))
+					Expect(err).NotTo(HaveOccurred())
+					for _, file := range files {
+						Expect(len(file.Name())).To(BeNumerically("<=", 128))
+					}
+				}
+			})
+
+			It("should list, find and uninstall them by their full names", func() {
+				Expect(manager.Install(ctx, longName+"@testsource")).To(Succeed())
+				Expect(manager.Install(ctx, "思源黑体@testsource")).To(Succeed())
+
+				fonts, err := manager.List(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fonts).To(ConsistOf(HaveField("Name", longName), HaveField("Name", "思源黑体")))
+				Expect(manager.Install(ctx, "思源黑体@testsource")).To(MatchError(fm.ErrAlreadyInstalled))
+
+				Expect(manager.Uninstall(ctx, "思源黑体")).To(Succeed())
+				Expect(manager.Uninstall(ctx, longName)).To(Succeed())
+				Expect(filepath.Join(tempDir, "user")).To(BeADirectory())
+				Expect(os.ReadDir(filepath.Join(tempDir, "user"))).To(BeEmpty())
+			})
+		})
 	})
 
-	Describe("Listing fonts", func() {
-		BeforeEach(func() {
+	Describe("Installing for another user", func() {
+		It("should hand installed fonts to the target user", func() {
+			userPlatform := &mockUserPlatform{mockPlatform: mockPlatform{fontDir: tempDir}}
+			userManager := fm.NewManagerWithPlatform(userPlatform)
+			Expect(userManager.RegisterSource(newMockSource())).To(Succeed())
+
+			Expect(userManager.Install(ctx, "TestFont1")).To(Succeed())
+			Expect(userPlatform.chowned).To(ConsistOf(filepath.Join(tempDir, "user", "TestFont1")))
+		})
+	})
+
+	Describe("Installing into another font directory", func() {
+		It("should install and list fonts in the configured directory", func() {
+			platform := &mockUserPlatform{mockPlatform: mockPlatform{fontDir: tempDir}}
+			dir := filepath.Join(tempDir, "elsewhere")
+			manager := fm.NewManagerWithPlatform(platform, fm.WithFontDir(dir))
+			Expect(manager.RegisterSource(newMockSource())).To(Succeed())
+
 			Expect(manager.Install(ctx, "TestFont1")).To(Succeed())
-			Expect(manager.Install(ctx, "TestFont2")).To(Succeed())
+			Expect(filepath.Join(dir, "TestFont1")).To(BeADirectory())
+			Expect(filepath.Join(tempDir, "user", "TestFont1")).NotTo(BeAnExistingFile())
+			Expect(platform.chowned).To(ConsistOf(filepath.Join(dir, "TestFont1")))
+
+			installed, err := manager.IsInstalled(ctx, "TestFont1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installed).To(BeTrue())
+			Expect(manager.Uninstall(ctx, "TestFont1")).To(Succeed())
+			Expect(platform.cacheUpdates).To(BeNumerically(">", 0))
 		})
+	})
 
-		It("should list all installed fonts", func() {
-			fonts, err := manager.List(ctx)
+	Describe("Resolving aliases", func() {
+		It("should install the font an alias points to", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"Friendly Font": {"source": "testsource", "name": "TestFont2"}}`)
+			}))
+			defer server.Close()
+
+			aliasManager := fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir},
+				fm.WithAliases(fm.NewAliasIndex(server.URL)))
+			Expect(aliasManager.RegisterSource(newMockSource())).To(Succeed())
+
+			Expect(aliasManager.Install(ctx, "friendly-font")).To(Succeed())
+
+			installed, err := aliasManager.IsInstalled(ctx, "TestFont2")
 			Expect(err).NotTo(HaveOccurred())
-			Expect(fonts).To(HaveLen(2))
+			Expect(installed).To(BeTrue())
+		})
 
-			fontNames := []string{fonts[0].Name, fonts[1].Name}
-			Expect(fontNames).To(ContainElements("TestFont1", "TestFont2"))
+		It("should accept aliases given as specs", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"Friendly Font": "TestFont2@testsource"}`)
+			}))
+			defer server.Close()
+
+			aliases := fm.NewAliasIndex(server.URL)
+			alias, ok := aliases.Lookup(ctx, "friendly font")
+			Expect(ok).To(BeTrue())
+			Expect(alias).To(Equal(fm.Alias{Source: "testsource", Name: "TestFont2"}))
 		})
 
-		It("should include source information in listed fonts", func() {
-			fonts, err := manager.List(ctx)
+		It("should report failures of the source an alias points to", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"TestFont1": {"source": "testsource", "name": "FailingFont"}}`)
+			}))
+			defer server.Close()
+
+			aliasManager := fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir},
+				fm.WithAliases(fm.NewAliasIndex(server.URL)))
+			Expect(aliasManager.RegisterSource(newMockSource())).To(Succeed())
+
+			Expect(aliasManager.Install(ctx, "TestFont1")).To(MatchError(ContainSubstring("simulated failure")))
+			Expect(aliasManager.IsInstalled(ctx, "TestFont1")).To(BeFalse())
+		})
+
+		It("should search every source when the aliased source lacks the font", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"TestFont1": {"source": "testsource", "name": "Missing"}}`)
+			}))
+			defer server.Close()
+
+			aliasManager := fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir},
+				fm.WithAliases(fm.NewAliasIndex(server.URL)))
+			Expect(aliasManager.RegisterSource(newMockSource())).To(Succeed())
+
+			Expect(aliasManager.Install(ctx, "TestFont1")).To(Succeed())
+		})
+
+		It("should fall back to searching every source", func() {
+			aliasManager := fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir},
+				fm.WithAliases(fm.NewAliasIndex("")))
+			Expect(aliasManager.RegisterSource(newMockSource())).To(Succeed())
+
+			Expect(aliasManager.Install(ctx, "TestFont1")).To(Succeed())
+		})
+
+		Context("defined by the user", func() {
+			var aliasManager *fm.DefaultManager
+
+			BeforeEach(func() {
+				aliasManager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir},
+					fm.WithUserAliases(map[string]string{"Code Font": "TestFont2@testsource"}))
+				Expect(aliasManager.RegisterSource(newMockSource())).To(Succeed())
+			})
+
+			It("should install and uninstall the font an alias names", func() {
+				Expect(aliasManager.Install(ctx, "code-font")).To(Succeed())
+				Expect(aliasManager.IsInstalled(ctx, "TestFont2")).To(BeTrue())
+
+				Expect(aliasManager.Uninstall(ctx, "code-font")).To(Succeed())
+				Expect(aliasManager.IsInstalled(ctx, "TestFont2")).To(BeFalse())
+			})
+
+			It("should resolve aliases in font lists", func() {
+				config := "code-font\nTestFont1@testsource\n"
+				Expect(aliasManager.InstallFromConfig(ctx, strings.NewReader(config))).To(Succeed())
+				Expect(aliasManager.IsInstalled(ctx, "TestFont2")).To(BeTrue())
+				Expect(aliasManager.IsInstalled(ctx, "TestFont1")).To(BeTrue())
+			})
+
+			It("should keep the version and options given with an alias", func() {
+				font, err := fm.ParseFontSpec("code-font >=v2.0.0 variants=Regular")
+				Expect(err).NotTo(HaveOccurred())
+
+				expanded, err := aliasManager.ExpandAliases([]fm.Font{*font, {Name: "Code Font", Source: "testsource"}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(expanded[0]).To(And(
+					HaveField("Name", "TestFont2"),
+					HaveField("Source", "testsource"),
+					HaveField("MinVersion", "v2.0.0"),
+					HaveField("Options", HaveKeyWithValue("variants", "Regular")),
+				))
+				Expect(expanded[1].Name).To(Equal("Code Font"), "fonts with a source are never aliases")
+			})
+
+			It("should reject aliases that aren't plain names or name themselves", func() {
+				_, err := fm.ParseUserAlias("code-font@nerdfonts", "FiraCode")
+				Expect(err).To(MatchError(ContainSubstring("plain font name")))
+				_, err = fm.ParseUserAlias("FiraCode", "firacode")
+				Expect(err).To(MatchError(ContainSubstring("refers to itself")))
+				_, err = fm.ParseUserAlias("code-font", "")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Verifying installed files", func() {
+		BeforeEach(func() {
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+			Expect(manager.Install(ctx, "TestFont2@testsource")).To(Succeed())
+		})
+
+		It("should pass untouched fonts", func() {
+			results, err := manager.Verify(ctx, nil, false)
 			Expect(err).NotTo(HaveOccurred())
-			for _, font := range fonts {
-				Expect(font.Source).To(Equal("testsource"))
+			Expect(results).To(HaveLen(2))
+			for _, result := range results {
+				Expect(result.OK()).To(BeTrue(), result.Font.Name)
 			}
 		})
+
+		It("should report missing, modified and extra files", func() {
+			dir := filepath.Join(tempDir, "user", "TestFont1")
+			Expect(os.WriteFile(filepath.Join(dir, "TestFont1.ttf"), []byte("tampered"), 0644)).To(Succeed())
+			Expect(os.Remove(filepath.Join(dir, "LICENSE"))).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "Stray.otf"), []byte("stray"), 0644)).To(Succeed())
+
+			results, err := manager.Verify(ctx, []string{"TestFont1"}, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].OK()).To(BeFalse())
+			Expect(results[0].Modified).To(Equal([]string{"TestFont1.ttf"}))
+			Expect(results[0].Missing).To(Equal([]string{"LICENSE"}))
+			Expect(results[0].Extra).To(Equal([]string{"Stray.otf"}))
+		})
+
+		It("should only rehash files whose size or modification time changed", func() {
+			path := filepath.Join(tempDir, "user", "TestFont1", "TestFont1.ttf")
+			info, err := os.Stat(path)
+			Expect(err).NotTo(HaveOccurred())
+			content, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range content {
+				content[i] = 'x'
+			}
+			Expect(os.WriteFile(path, content, 0644)).To(Succeed())
+			Expect(os.Chtimes(path, info.ModTime(), info.ModTime())).To(Succeed())
+
+			results, err := manager.Verify(ctx, []string{"TestFont1"}, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].OK()).To(BeTrue())
+
+			results, err = manager.Verify(ctx, []string{"TestFont1"}, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Modified).To(Equal([]string{"TestFont1.ttf"}))
+
+			later := info.ModTime().Add(time.Minute)
+			Expect(os.Chtimes(path, later, later)).To(Succeed())
+			results, err = manager.Verify(ctx, []string{"TestFont1"}, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Modified).To(Equal([]string{"TestFont1.ttf"}))
+		})
+
+		It("should flag fonts without recorded checksums", func() {
+			Expect(os.Remove(filepath.Join(tempDir, "user", "TestFont2", ".files"))).To(Succeed())
+
+			results, err := manager.Verify(ctx, []string{"TestFont2"}, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Unrecorded).To(BeTrue())
+			Expect(results[0].OK()).To(BeFalse())
+		})
+
+		It("should fail for fonts that aren't installed", func() {
+			_, err := manager.Verify(ctx, []string{"Missing"}, false)
+			Expect(err).To(MatchError(ContainSubstring(`font "Missing" is not installed`)))
+		})
 	})
 
-	Describe("Uninstalling fonts", func() {
+	Describe("Render verification", func() {
 		BeforeEach(func() {
-			Expect(manager.Install(ctx, "TestFont1")).To(Succeed())
+			archive, err := createTestZip(testFont{name: "GoRegular", format: "ttf", content: string(goregular.TTF)})
+			Expect(err).NotTo(HaveOccurred())
+			mockSource1.fonts["Go"] = archive
 		})
 
-		It("should uninstall fonts successfully", func() {
-			Expect(manager.Uninstall(ctx, "TestFont1")).To(Succeed())
+		It("should install fonts that render", func() {
+			Expect(manager.Install(ctx, "Go@testsource", fm.WithVerifyRender())).To(Succeed())
+		})
 
-			installed, err := manager.IsInstalled(ctx, "TestFont1")
+		It("should remove fonts whose files can't be loaded", func() {
+			err := manager.Install(ctx, "TestFont1@testsource", fm.WithVerifyRender())
+			Expect(err).To(MatchError(fm.ErrRenderFailed))
+			Expect(err).To(MatchError(ContainSubstring("TestFont1.ttf: parsing font")))
+			Expect(filepath.Join(tempDir, "user", "TestFont1")).NotTo(BeADirectory())
+		})
+
+		It("should require the glyphs the source promises", func() {
+			glyphSource := &mockGlyphSource{mockSource: newMockSource(), glyphs: []rune{'G', 0xE0B0}}
+			glyphSource.name = "glyphs"
+			glyphSource.fonts["Go"] = mockSource1.fonts["Go"]
+			Expect(manager.RegisterSource(glyphSource)).To(Succeed())
+
+			err := manager.Install(ctx, "Go@glyphs", fm.WithVerifyRender())
+			Expect(err).To(MatchError(ContainSubstring("GoRegular.ttf: missing glyphs U+E0B0")))
+
+			glyphSource.glyphs = []rune{'G'}
+			Expect(manager.Install(ctx, "Go@glyphs", fm.WithVerifyRender())).To(Succeed())
+		})
+	})
+
+	Describe("Locating installed fonts", func() {
+		It("should list the directory and files of a font", func() {
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+
+			loc, err := manager.Which(ctx, "testfont1")
 			Expect(err).NotTo(HaveOccurred())
-			Expect(installed).To(BeFalse())
+			Expect(loc.Font.Name).To(Equal("TestFont1"))
+			Expect(loc.Scope).To(Equal(fm.ScopeUser))
+			Expect(loc.Dir).To(Equal(filepath.Join(tempDir, "user", "TestFont1")))
+			Expect(loc.Files).To(Equal([]string{filepath.Join(tempDir, "user", "TestFont1", "TestFont1.ttf")}))
 		})
 
-		It("should fail when trying to uninstall non-existent fonts", func() {
-			err := manager.Uninstall(ctx, "NonExistentFont")
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("not installed"))
+		It("should find system fonts and files copied into a font directory", func() {
+			systemFont := filepath.Join(tempDir, "system", "truetype", "dejavu", "DejaVuSans.ttf")
+			looseFont := filepath.Join(tempDir, "user", "Loose.otf")
+			for _, path := range []string{systemFont, looseFont} {
+				Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+				Expect(os.WriteFile(path, []byte("font"), 0644)).To(Succeed())
+			}
+
+			loc, err := manager.Which(ctx, "truetype")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loc.Scope).To(Equal(fm.ScopeSystem))
+			Expect(loc.Dir).To(Equal(filepath.Join(tempDir, "system", "truetype")))
+			Expect(loc.Files).To(Equal([]string{systemFont}))
+
+			loc, err = manager.Which(ctx, "Loose")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loc.Scope).To(Equal(fm.ScopeUser))
+			Expect(loc.Dir).To(Equal(filepath.Join(tempDir, "user")))
+			Expect(loc.Files).To(Equal([]string{looseFont}))
+		})
+
+		It("should fail for fonts that aren't installed", func() {
+			_, err := manager.Which(ctx, "TestFont2")
+			Expect(err).To(MatchError(ContainSubstring(`font "TestFont2" is not installed`)))
 		})
 	})
-})
+
+	Describe("Configured defaults", func() {
+		It("should search prioritized sources first", func() {
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithSourcePriority("second", "third"))
+			for _, name := range []string{"first", "third", "second", "fourth"} {
+				source := newMockSource()
+				source.name = name
+				Expect(manager.RegisterSource(source)).To(Succeed())
+			}
+
+			var names []string
+			for _, info := range manager.Sources() {
+				names = append(names, info.Name)
+			}
+			Expect(names).To(Equal([]string{"second", "third", "first", "fourth"}))
+		})
+
+		It("should install a source's default variants unless others are asked for", func() {
+			archive, err := createTestZip(
+				testFont{name: "FiraCodeNerdFont-Regular", format: "ttf", content: "regular"},
+				testFont{name: "FiraCodeNerdFontMono-Regular", format: "ttf", content: "mono"},
+				testFont{name: "FiraCodeNerdFontMono-Bold", format: "ttf", content: "mono bold"},
+				testFont{name: "FiraCodeNerdFontPropo-Regular", format: "ttf", content: "propo"},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			mockSource1.fonts["FiraCode"] = archive
+
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithDefaultVariants("testsource", "Mono"))
+			Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+
+			Expect(manager.Install(ctx, "FiraCode@testsource")).To(Succeed())
+			files, err := filepath.Glob(filepath.Join(tempDir, "user", "FiraCode", "*.ttf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(ConsistOf(
+				HaveSuffix("FiraCodeNerdFontMono-Regular.ttf"),
+				HaveSuffix("FiraCodeNerdFontMono-Bold.ttf"),
+			))
+
+			Expect(manager.Uninstall(ctx, "FiraCode")).To(Succeed())
+			Expect(manager.Install(ctx, "FiraCode@testsource", fm.WithVariants("Propo"))).To(Succeed())
+			files, err = filepath.Glob(filepath.Join(tempDir, "user", "FiraCode", "*.ttf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(ConsistOf(HaveSuffix("FiraCodeNerdFontPropo-Regular.ttf")))
+		})
+
+		It("should install into the system font directory with the system scope", func() {
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithSystemScope())
+			Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+			Expect(filepath.Join(tempDir, "system", "TestFont1")).To(BeADirectory())
+			Expect(filepath.Join(tempDir, "user", "TestFont1")).NotTo(BeADirectory())
+
+			Expect(manager.Uninstall(ctx, "TestFont1")).To(Succeed())
+			Expect(filepath.Join(tempDir, "system", "TestFont1")).NotTo(BeADirectory())
+		})
+
+		It("should record the scope so system-wide installs can be uninstalled", func() {
+			platform := &mockPlatform{fontDir: tempDir}
+			system := fm.NewManagerWithPlatform(platform, fm.WithSystemScope())
+			Expect(system.RegisterSource(mockSource1)).To(Succeed())
+			Expect(system.Install(ctx, "TestFont1@testsource")).To(Succeed())
+
+			loc, err := manager.Which(ctx, "TestFont1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loc.Scope).To(Equal(fm.ScopeSystem))
+			Expect(loc.Font.Meta["scope"]).To(Equal(fm.ScopeSystem))
+
+			// A manager of the user scope removes it, but no other system fonts
+			systemFont := filepath.Join(tempDir, "system", "dejavu", "DejaVuSans.ttf")
+			Expect(os.MkdirAll(filepath.Dir(systemFont), 0755)).To(Succeed())
+			Expect(os.WriteFile(systemFont, []byte("font"), 0644)).To(Succeed())
+			Expect(manager.Uninstall(ctx, "TestFont1")).To(Succeed())
+			Expect(filepath.Join(tempDir, "system", "TestFont1")).NotTo(BeADirectory())
+			Expect(manager.Uninstall(ctx, "dejavu")).To(MatchError(ContainSubstring("cannot uninstall system font")))
+		})
+
+		It("should plan installs without downloading or writing anything", func() {
+			Expect(manager.Install(ctx, "TestFont1")).To(Succeed())
+			mockSource1.meta["TestFont2"] = map[string]string{"version": "v2.0.0"}
+
+			plans, err := manager.PlanInstall(ctx, []fm.Font{
+				{Name: "TestFont1"},
+				{Name: "TestFont2", Source: "testsource"},
+				{Name: "TestFont2", Source: "testsource", MinVersion: "v3.0.0", Options: map[string]string{"install_as": "TestFont2New"}},
+				{Name: "Missing", Source: "testsource"},
+				{Name: "Remote", URL: "https://fonts.example.com/remote.zip", Source: "url"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plans).To(HaveLen(5))
+
+			Expect(plans[0].Installed).To(BeTrue())
+			Expect(plans[1].Err).NotTo(HaveOccurred())
+			Expect(plans[1].Installed).To(BeFalse())
+			Expect(plans[1].Source).To(Equal("testsource"))
+			Expect(plans[1].Version).To(Equal("v2.0.0"))
+			Expect(plans[2].Err).To(MatchError(ContainSubstring("v2.0.0 does not satisfy >=v3.0.0")))
+			Expect(plans[3].Err).To(HaveOccurred())
+			Expect(plans[4].URL).To(Equal("https://fonts.example.com/remote.zip"))
+
+			Expect(filepath.Join(tempDir, "user", "TestFont2")).NotTo(BeADirectory())
+			Expect(mockSource1.downloads).To(Equal([]string{"TestFont1"}))
+		})
+
+		It("should install the same files and checksums in low-memory mode", func() {
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithLowMemory())
+			Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+
+			sum := sha256.Sum256(mockSource1.fonts["TestFont1"])
+			fonts := []fm.Font{
+				{Name: "TestFont1", Source: "testsource", Options: map[string]string{"sha256": hex.EncodeToString(sum[:])}},
+				{Name: "TestFont2", Source: "testsource"},
+			}
+			Expect(manager.InstallFonts(ctx, fonts, fm.WithParallelism(4))).To(Succeed())
+
+			installed, err := manager.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installed).To(HaveLen(2))
+			for _, font := range installed {
+				Expect(font.Meta).To(HaveKeyWithValue("sha256", Not(BeEmpty())))
+			}
+			results, err := manager.Verify(ctx, nil, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+			for _, result := range results {
+				Expect(result.OK()).To(BeTrue(), "%s: %+v", result.Font.Name, result)
+			}
+		})
+	})
+
+	Describe("Scanning the system directory", func() {
+		systemFont := func(path string) {
+			path = filepath.Join(tempDir, "system", path)
+			Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+			Expect(os.WriteFile(path, []byte("font"), 0644)).To(Succeed())
+		}
+
+		listNames := func() []string {
+			fonts, err := manager.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			var names []string
+			for _, font := range fonts {
+				names = append(names, font.Name)
+			}
+			return names
+		}
+
+		BeforeEach(func() {
+			systemFont("DejaVu/DejaVuSans.ttf")
+			systemFont("noto/NotoSans-Regular.ttf")
+			systemFont("truetype/msttcorefonts/Arial.ttf")
+		})
+
+		It("should walk the whole system directory by default", func() {
+			Expect(listNames()).To(ContainElements("DejaVu", "noto", "truetype"))
+		})
+
+		It("should leave out excluded paths and directories below the max depth", func() {
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithScanOptions(fm.ScanOptions{
+				MaxDepth: 1,
+				Exclude:  []string{"noto*"},
+			}))
+
+			names := listNames()
+			Expect(names).To(ContainElement("DejaVu"))
+			Expect(names).NotTo(ContainElements("noto", "truetype"))
+		})
+
+		It("should keep fonts fm installed when skipping large subtrees", func() {
+			archive, err := createTestZip(testFont{name: "NotoSans-Regular", format: "ttf", content: "noto"})
+			Expect(err).NotTo(HaveOccurred())
+			mockSource1.fonts["noto-sans"] = archive
+
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithSystemScope())
+			Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+			Expect(manager.Install(ctx, "noto-sans@testsource")).To(Succeed())
+
+			results, err := manager.Verify(ctx, nil, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Font.Name).To(Equal("noto-sans"))
+			Expect(results[0].OK()).To(BeTrue())
+		})
+	})
+
+	Describe("Same family from two sources", func() {
+		BeforeEach(func() {
+			archive, err := createTestZip(testFont{name: "FiraCode-Regular", format: "ttf", content: "upstream"})
+			Expect(err).NotTo(HaveOccurred())
+			mockSource1.fonts["fira-code"] = archive
+			mockSource1.fonts["FiraCode"] = archive
+
+			patched, err := createTestZip(testFont{name: "FiraCodeNerdFont-Regular", format: "ttf", content: "patched"})
+			Expect(err).NotTo(HaveOccurred())
+			nerdfonts := newMockSource()
+			nerdfonts.name = "nerdfonts"
+			nerdfonts.fonts["FiraCode"] = patched
+			Expect(manager.RegisterSource(nerdfonts)).To(Succeed())
+		})
+
+		parse := func(config string) []fm.Font {
+			fonts, err := fm.ParseConfig(strings.NewReader(config))
+			Expect(err).NotTo(HaveOccurred())
+			return fonts
+		}
+
+		It("should refuse to install both builds and explain the difference", func() {
+			err := manager.InstallFonts(ctx, parse("fira-code@testsource\nFiraCode@nerdfonts\n"))
+			Expect(err).To(MatchError(fm.ErrFamilyConflict))
+			Expect(err).To(MatchError(ContainSubstring("patched with icon glyphs")))
+			Expect(err).To(MatchError(ContainSubstring("allow_coexist=true")))
+			Expect(filepath.Join(tempDir, "user", "fira-code")).NotTo(BeADirectory())
+			Expect(filepath.Join(tempDir, "user", "FiraCode")).NotTo(BeADirectory())
+		})
+
+		It("should install both builds when allowed to coexist", func() {
+			Expect(manager.InstallFonts(ctx, parse("fira-code@testsource allow_coexist=true\nFiraCode@nerdfonts\n"))).To(Succeed())
+			Expect(filepath.Join(tempDir, "user", "fira-code")).To(BeADirectory())
+			Expect(filepath.Join(tempDir, "user", "FiraCode")).To(BeADirectory())
+		})
+
+		It("should keep both entries of a config and require distinct install names", func() {
+			fonts := fm.MergeConfigs(parse("FiraCode@testsource\nFiraCode@nerdfonts\n"))
+			Expect(fonts).To(HaveLen(2))
+			Expect(manager.InstallFonts(ctx, fonts)).To(MatchError(ContainSubstring("distinct install_as name")))
+
+			Expect(manager.InstallFonts(ctx, parse("FiraCode@testsource install_as=FiraCodeUpstream\nFiraCode@nerdfonts\n"))).To(Succeed())
+			Expect(filepath.Join(tempDir, "user", "FiraCodeUpstream", "FiraCode-Regular.ttf")).To(BeARegularFile())
+			Expect(filepath.Join(tempDir, "user", "FiraCode", "FiraCodeNerdFont-Regular.ttf")).To(BeARegularFile())
+
+			exported, err := manager.Export(ctx, fm.ExportOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			var specs []string
+			for _, font := range exported {
+				specs = append(specs, fm.FormatFontSpec(font))
+			}
+			Expect(specs).To(ContainElements("FiraCode@testsource install_as=FiraCodeUpstream", "FiraCode@nerdfonts"))
+			Expect(fm.MergeConfigs(exported)).To(HaveLen(2))
+		})
+
+		It("should let a later config replace a font from another source", func() {
+			fonts := fm.MergeConfigs(parse("FiraCode@testsource\n"), parse("FiraCode@nerdfonts\n"))
+			Expect(fonts).To(HaveLen(1))
+			Expect(fonts[0].Source).To(Equal("nerdfonts"))
+		})
+	})
+
+	Describe("Shell completion", func() {
+		It("should complete names from aliases and cached catalogs without fetching them", func() {
+			cache := fm.NewCache(filepath.Join(tempDir, "cache"), time.Hour)
+			index := "https://fonts.invalid/index.json"
+			Expect(cache.Put(index, []byte(`["Acme Sans", {"name": "Acme Mono", "url": "mono.zip"}, "Other"]`))).To(Succeed())
+
+			cataloged, err := fm.NewTemplateSource("internal", "https://fonts.invalid/{name}.zip", index, fm.WithCache(cache))
+			Expect(err).NotTo(HaveOccurred())
+			uncached, err := fm.NewTemplateSource("other", "https://other.invalid/{name}.zip", "https://other.invalid/index.json", fm.WithCache(cache))
+			Expect(err).NotTo(HaveOccurred())
+
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithAliases(fm.NewAliasIndex("")))
+			Expect(manager.RegisterSource(cataloged)).To(Succeed())
+			Expect(manager.RegisterSource(uncached)).To(Succeed())
+
+			Expect(manager.CompleteInstallable("acme")).To(Equal([]string{"Acme Mono", "Acme Sans"}))
+			Expect(manager.CompleteInstallable("JetBrainsMono N")).To(ContainElement("JetBrainsMono Nerd Font"))
+		})
+
+		It("should complete source names after @", func() {
+			Expect(manager.CompleteInstallable("Acme@test")).To(Equal([]string{"Acme@testsource"}))
+		})
+
+		It("should complete installed fonts that can be uninstalled", func() {
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+			Expect(manager.Install(ctx, "TestFont2@testsource")).To(Succeed())
+			systemFont := filepath.Join(tempDir, "system", "SystemFont", "SystemFont.ttf")
+			Expect(os.MkdirAll(filepath.Dir(systemFont), 0755)).To(Succeed())
+			Expect(os.WriteFile(systemFont, []byte("font"), 0644)).To(Succeed())
+
+			names, err := manager.CompleteInstalled(ctx, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names).To(Equal([]string{"TestFont1", "TestFont2"}))
+
+			names, err = manager.CompleteInstalled(ctx, "testfont2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names).To(Equal([]string{"TestFont2"}))
+		})
+	})
+
+	Describe("Download cache", func() {
+		var cache *fm.Cache
+
+		installedContent := func() string {
+			data, err := os.ReadFile(filepath.Join(tempDir, "user", "TestFont1", "TestFont1.ttf"))
+			Expect(err).NotTo(HaveOccurred())
+			return string(data)
+		}
+
+		replaceArchive := func() {
+			archive, err := createTestZip(testFont{name: "TestFont1", format: "ttf", content: "changed upstream"})
+			Expect(err).NotTo(HaveOccurred())
+			mockSource1.fonts["TestFont1"] = archive
+		}
+
+		BeforeEach(func() {
+			cache = fm.NewCache(filepath.Join(tempDir, "cache"), time.Hour)
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithDownloadCache(cache))
+			Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+		})
+
+		It("should reinstall a versioned release from the cache", func() {
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v1.0.0"}
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+			stats, err := cache.Stats()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stats.Downloads.Entries).To(Equal(1))
+
+			Expect(manager.Uninstall(ctx, "TestFont1")).To(Succeed())
+			replaceArchive()
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+			Expect(installedContent()).To(Equal("fake ttf content"))
+
+			// A new release is downloaded
+			Expect(manager.Uninstall(ctx, "TestFont1")).To(Succeed())
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v1.1.0"}
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+			Expect(installedContent()).To(Equal("changed upstream"))
+		})
+
+		It("should not cache unversioned or rejected downloads", func() {
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+
+			mockSource1.meta["TestFont2"] = map[string]string{"version": "v1.0.0"}
+			err := manager.Install(ctx, "TestFont2@testsource sha256=0000")
+			Expect(err).To(MatchError(fm.ErrChecksumMismatch))
+
+			stats, err := cache.Stats()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stats.Downloads).To(Equal(fm.CacheUsage{}))
+		})
+
+		It("should trust the digest recorded with a cached download until it changes", func() {
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v1.0.0"}
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+			sum := sha256.Sum256(mockSource1.fonts["TestFont1"])
+			digests, err := filepath.Glob(filepath.Join(tempDir, "cache", "downloads", "*.sha256"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(digests).To(HaveLen(1))
+
+			// A recorded digest that doesn't match the contents shows which
+			// one was used
+			recorded, err := os.ReadFile(digests[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(recorded)).To(ContainSubstring(hex.EncodeToString(sum[:])))
+			fake := strings.Replace(string(recorded), hex.EncodeToString(sum[:]), strings.Repeat("0", 64), 1)
+			Expect(os.WriteFile(digests[0], []byte(fake), 0644)).To(Succeed())
+			installedSum := func(opts ...fm.ManagerOption) string {
+				manager := fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, append(opts, fm.WithDownloadCache(cache))...)
+				Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+				Expect(manager.Install(ctx, "TestFont1@testsource", fm.WithForce())).To(Succeed())
+				loc, err := manager.Which(ctx, "TestFont1")
+				Expect(err).NotTo(HaveOccurred())
+				return loc.Font.Meta["sha256"]
+			}
+			Expect(installedSum()).To(Equal(strings.Repeat("0", 64)))
+			Expect(installedSum(fm.WithRehash())).To(Equal(hex.EncodeToString(sum[:])))
+
+			// Changing the archive invalidates the digest
+			archive := strings.TrimSuffix(digests[0], ".sha256")
+			later := time.Now().Add(time.Minute)
+			Expect(os.Chtimes(archive, later, later)).To(Succeed())
+			Expect(installedSum()).To(Equal(hex.EncodeToString(sum[:])))
+		})
+	})
+
+	Describe("Spec versions and options", func() {
+		installedFiles := func(name string) []string {
+			entries, err := os.ReadDir(filepath.Join(tempDir, "user", name))
+			Expect(err).NotTo(HaveOccurred())
+			var files []string
+			for _, entry := range entries {
+				if !strings.HasPrefix(entry.Name(), ".") {
+					files = append(files, entry.Name())
+				}
+			}
+			return files
+		}
+
+		BeforeEach(func() {
+			archive, err := createTestZip(
+				testFont{name: "Family-Regular", format: "ttf", content: "regular"},
+				testFont{name: "Family-Bold", format: "ttf", content: "bold"},
+				testFont{name: "Family-BoldItalic", format: "ttf", content: "bold italic"},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			mockSource1.fonts["Family"] = archive
+			mockSource1.meta["Family"] = map[string]string{"version": "v1.0.0"}
+		})
+
+		It("should only extract the requested variants", func() {
+			Expect(manager.Install(ctx, "Family@testsource variants=regular,BoldItalic")).To(Succeed())
+			Expect(installedFiles("Family")).To(ConsistOf("Family-Regular.ttf", "Family-BoldItalic.ttf", "LICENSE"))
+		})
+
+		It("should keep the variants on upgrade and export", func() {
+			Expect(manager.Install(ctx, "Family@testsource", fm.WithVariants("Bold"))).To(Succeed())
+			Expect(installedFiles("Family")).To(ConsistOf("Family-Bold.ttf", "LICENSE"))
+
+			mockSource1.meta["Family"] = map[string]string{"version": "v1.1.0"}
+			results, err := manager.Upgrade(ctx, []string{"Family"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Upgraded).To(BeTrue())
+			Expect(installedFiles("Family")).To(ConsistOf("Family-Bold.ttf", "LICENSE"))
+
+			fonts, err := manager.Export(ctx, fm.ExportOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fonts).To(ContainElement(And(HaveField("Name", "Family"), HaveField("Options", HaveKeyWithValue("variants", "Bold")))))
+		})
+
+		It("should fail when no file is one of the variants", func() {
+			err := manager.Install(ctx, "Family@testsource variants=Light")
+			Expect(err).To(MatchError(ContainSubstring("no font files of the variants Light")))
+		})
+
+		It("should only install the preferred format of faces the archive has in both", func() {
+			archive, err := createTestZip(
+				testFont{name: "Both-Regular", format: "ttf", content: "regular"},
+				testFont{name: "Both-Regular", format: "otf", content: "regular"},
+				testFont{name: "Both-Bold", format: "otf", content: "bold"},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			mockSource1.fonts["Both"] = archive
+
+			Expect(manager.Install(ctx, "Both@testsource", fm.WithFormat("TTF"))).To(Succeed())
+			Expect(installedFiles("Both")).To(ConsistOf("Both-Regular.ttf", "Both-Bold.otf", "LICENSE"))
+			Expect(manager.Uninstall(ctx, "Both")).To(Succeed())
+
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithDefaultFormat("otf"))
+			Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+			Expect(manager.Install(ctx, "Both@testsource")).To(Succeed())
+			Expect(installedFiles("Both")).To(ConsistOf("Both-Regular.otf", "Both-Bold.otf", "LICENSE"))
+		})
+
+		It("should take the format from a spec and keep it on upgrade and export", func() {
+			archive, err := createTestZip(
+				testFont{name: "Both-Regular", format: "ttf", content: "regular"},
+				testFont{name: "Both-Regular", format: "otf", content: "regular"},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			mockSource1.fonts["Both"] = archive
+			mockSource1.meta["Both"] = map[string]string{"version": "v1.0.0"}
+
+			Expect(manager.Install(ctx, "Both@testsource format=OTF")).To(Succeed())
+			Expect(installedFiles("Both")).To(ConsistOf("Both-Regular.otf", "LICENSE"))
+
+			mockSource1.meta["Both"] = map[string]string{"version": "v1.1.0"}
+			results, err := manager.Upgrade(ctx, []string{"Both"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Upgraded).To(BeTrue())
+			Expect(installedFiles("Both")).To(ConsistOf("Both-Regular.otf", "LICENSE"))
+
+			fonts, err := manager.Export(ctx, fm.ExportOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fonts).To(ContainElement(And(HaveField("Name", "Both"), HaveField("Options", HaveKeyWithValue("format", "otf")))))
+
+			err = manager.Install(ctx, "Family@testsource format=woff2")
+			Expect(err).To(MatchError(ContainSubstring(`invalid format "woff2"`)))
+		})
+
+		It("should install an exact version only if the source offers it", func() {
+			err := manager.Install(ctx, "Family@testsource ==v2.0.0")
+			Expect(err).To(MatchError(ContainSubstring("testsource only installs the latest release of Family (v1.0.0), not v2.0.0")))
+
+			Expect(manager.Install(ctx, "Family@testsource ==1.0")).To(Succeed())
+		})
+
+		It("should verify the archive checksum", func() {
+			err := manager.Install(ctx, "Family@testsource sha256=0000")
+			Expect(err).To(MatchError(fm.ErrChecksumMismatch))
+		})
+	})
+
+	Describe("URL template sources", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			archive, err := createTestZip(testFont{name: "Internal-Regular", format: "ttf", content: "internal"})
+			Expect(err).NotTo(HaveOccurred())
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/index.json":
+					fmt.Fprint(w, `["Internal Sans", {"name": "Legacy Serif", "url": "/legacy/serif.zip"}]`)
+				case "/fonts/Internal Sans.zip", "/legacy/serif.zip":
+					w.Write(archive)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should download from the expanded template", func() {
+			source, err := fm.NewTemplateSource("internal", server.URL+"/fonts/{name}.zip", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manager.RegisterSource(source)).To(Succeed())
+
+			Expect(manager.Install(ctx, "Internal Sans@internal")).To(Succeed())
+		})
+
+		It("should search the index when one is configured", func() {
+			source, err := fm.NewTemplateSource("internal", server.URL+"/fonts/{name}.zip", server.URL+"/index.json")
+			Expect(err).NotTo(HaveOccurred())
+
+			fonts, err := source.Search(ctx, "internal sans")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fonts).To(HaveLen(1))
+			Expect(fonts[0].Name).To(Equal("Internal Sans"))
+
+			fonts, err = source.Search(ctx, "Missing Font")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fonts).To(BeEmpty())
+		})
+
+		It("should use download URLs listed in the index", func() {
+			source, err := fm.NewTemplateSource("internal", server.URL+"/fonts/{name}.zip", server.URL+"/index.json")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manager.RegisterSource(source)).To(Succeed())
+
+			Expect(manager.Install(ctx, "Legacy Serif@internal")).To(Succeed())
+		})
+
+		It("should require a {name} placeholder", func() {
+			_, err := fm.NewTemplateSource("internal", server.URL+"/fonts/latest.zip", "")
+			Expect(err).To(MatchError(ContainSubstring("{name}")))
+		})
+	})
+
+	Describe("Installing from authenticated URLs", func() {
+		var (
+			server *httptest.Server
+			host   string
+		)
+
+		BeforeEach(func() {
+			archive, err := createTestZip(testFont{name: "Private-Regular", format: "ttf", content: "private"})
+			Expect(err).NotTo(HaveOccurred())
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user, password, _ := r.BasicAuth()
+				if user != "ci" || password != "secret" || r.Header.Get("X-Api-Key") != "key" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.Write(archive)
+			}))
+			host = strings.TrimPrefix(server.URL, "http://")
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should fail without credentials", func() {
+			err := manager.Install(ctx, server.URL+"/Private.zip")
+			Expect(err).To(MatchError(ContainSubstring("unexpected status code: 401")))
+		})
+
+		It("should use the credentials configured for the host", func() {
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir},
+				fm.WithHostCredentials(host, fm.HostCredentials{
+					Username: "ci",
+					Password: "secret",
+					Headers:  http.Header{"X-Api-Key": {"key"}},
+				}))
+
+			Expect(manager.Install(ctx, server.URL+"/Private.zip")).To(Succeed())
+		})
+
+		It("should let explicit headers override the host's", func() {
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir},
+				fm.WithHostCredentials(host, fm.HostCredentials{
+					Username: "ci",
+					Password: "secret",
+					Headers:  http.Header{"X-Api-Key": {"stale"}},
+				}))
+
+			Expect(manager.Install(ctx, server.URL+"/Private.zip",
+				fm.WithHeaders(http.Header{"X-Api-Key": {"key"}}))).To(Succeed())
+		})
+	})
+
+	Describe("Directory listing sources", func() {
+		var (
+			server   *httptest.Server
+			requests []string
+		)
+
+		BeforeEach(func() {
+			archive, err := createTestZip(testFont{name: "Listed-Regular", format: "ttf", content: "listed"})
+			Expect(err).NotTo(HaveOccurred())
+
+			requests = nil
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.URL.Path)
+				switch r.URL.Path {
+				case "/fonts/":
+					fmt.Fprint(w, `<html><body><h1>Index of /fonts/</h1><pre>
+<a href="../">../</a>
+<a href="ListedSans-1.0.zip">ListedSans-1.0.zip</a>
+<a href="ListedSans-2.0.zip">ListedSans-2.0.zip</a>
+<a href="Other%20Serif.tar.gz">Other Serif.tar.gz</a>
+<a href="notes.txt">notes.txt</a>
+</pre></body></html>`)
+				case "/fonts/ListedSans-2.0.zip":
+					w.Write(archive)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should match archives in the listing by name", func() {
+			source, err := fm.NewDirectorySource("listing", server.URL+"/fonts")
+			Expect(err).NotTo(HaveOccurred())
+
+			fonts, err := source.Search(ctx, "Other Serif")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fonts).To(HaveLen(1))
+			Expect(fonts[0].URL).To(Equal(server.URL + "/fonts/Other%20Serif.tar.gz"))
+
+			fonts, err = source.Search(ctx, "notes")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fonts).To(BeEmpty())
+		})
+
+		It("should install the newest versioned archive", func() {
+			source, err := fm.NewDirectorySource("listing", server.URL+"/fonts/")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manager.RegisterSource(source)).To(Succeed())
+
+			Expect(manager.Install(ctx, "Listed Sans@listing")).To(Succeed())
+			Expect(requests).To(ContainElement("/fonts/ListedSans-2.0.zip"))
+		})
+	})
+
+	Describe("IPFS sources", func() {
+		const cid = "bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi"
+
+		It("should install a bundle by CID through the gateway", func() {
+			bundle, err := createTestTarGz(map[string]string{"Pinned-Regular.ttf": "pinned"})
+			Expect(err).NotTo(HaveOccurred())
+
+			var requested string
+			gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requested = r.URL.RequestURI()
+				w.Write(bundle)
+			}))
+			defer gateway.Close()
+
+			Expect(manager.RegisterSource(fm.NewIPFSSource(gateway.URL))).To(Succeed())
+			Expect(manager.Install(ctx, "Pinned@ipfs:"+cid)).To(Succeed())
+			Expect(requested).To(Equal("/ipfs/" + cid + "?format=tar"))
+
+			installed, err := manager.IsInstalled(ctx, "Pinned")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installed).To(BeTrue())
+		})
+
+		It("should reject invalid CIDs", func() {
+			Expect(manager.RegisterSource(fm.NewIPFSSource(""))).To(Succeed())
+			Expect(manager.Install(ctx, "Pinned@ipfs:not-a-cid")).To(MatchError(ContainSubstring("invalid CID")))
+		})
+
+		It("should reject references for sources that don't accept them", func() {
+			Expect(manager.Install(ctx, "TestFont1@testsource:v1")).To(MatchError(ContainSubstring("does not accept references")))
+		})
+	})
+
+	Describe("Checking sources", func() {
+		It("should report sources that cannot be health checked", func() {
+			results := manager.CheckSources(ctx)
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Source).To(Equal("testsource"))
+			Expect(results[0].Err).To(MatchError(ContainSubstring("does not support health checks")))
+		})
+	})
+
+	Describe("Describing sources", func() {
+		It("should list sources in search order with their cache state", func() {
+			cache := fm.NewCache(filepath.Join(tempDir, "cache"), time.Hour)
+			Expect(cache.Put("https://fonts.example.com/index.json", []byte(`[]`))).To(Succeed())
+
+			indexed, err := fm.NewTemplateSource("indexed", "https://fonts.example.com/{name}.zip",
+				"https://fonts.example.com/index.json", fm.WithCache(cache))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manager.RegisterSource(indexed)).To(Succeed())
+			unindexed, err := fm.NewTemplateSource("unindexed", "https://fonts.example.com/{name}.zip", "", fm.WithCache(cache))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manager.RegisterSource(unindexed)).To(Succeed())
+
+			sources := manager.Sources()
+			Expect(sources).To(HaveLen(3))
+
+			Expect(sources[0].Name).To(Equal("testsource"))
+			Expect(sources[0].Type).To(Equal("custom"))
+			Expect(sources[0].Priority).To(Equal(1))
+
+			Expect(sources[1].Name).To(Equal("indexed"))
+			Expect(sources[1].Type).To(Equal("template"))
+			Expect(sources[1].Priority).To(Equal(2))
+			Expect(sources[1].Auth).To(Equal("anonymous"))
+			Expect(sources[1].Cached).To(BeTrue())
+			Expect(sources[1].Fresh).To(BeTrue())
+
+			Expect(sources[2].Index).To(BeEmpty())
+			Expect(sources[2].Cached).To(BeFalse())
+		})
+	})
+
+	Describe("Listing fonts", func() {
+		BeforeEach(func() {
+			Expect(manager.Install(ctx, "TestFont1")).To(Succeed())
+			Expect(manager.Install(ctx, "TestFont2")).To(Succeed())
+		})
+
+		It("should list all installed fonts", func() {
+			fonts, err := manager.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fonts).To(HaveLen(2))
+
+			fontNames := []string{fonts[0].Name, fonts[1].Name}
+			Expect(fontNames).To(ContainElements("TestFont1", "TestFont2"))
+		})
+
+		It("should include source information in listed fonts", func() {
+			fonts, err := manager.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			for _, font := range fonts {
+				Expect(font.Source).To(Equal("testsource"))
+			}
+		})
+	})
+
+	Describe("Installing from a manifest with version constraints", func() {
+		version := func() string {
+			fonts, err := manager.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fonts).To(HaveLen(1))
+			return fonts[0].Meta["version"]
+		}
+
+		BeforeEach(func() {
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v3.1.0"}
+		})
+
+		It("should leave fonts that satisfy the constraint alone", func() {
+			manifest := "TestFont1@testsource >=v3.0.0\n"
+			Expect(manager.InstallFromConfig(ctx, strings.NewReader(manifest))).To(Succeed())
+			Expect(manager.InstallFromConfig(ctx, strings.NewReader(manifest))).To(Succeed())
+			Expect(version()).To(Equal("v3.1.0"))
+		})
+
+		It("should reinstall fonts older than the constraint", func() {
+			Expect(manager.InstallFromConfig(ctx, strings.NewReader("TestFont1@testsource\n"))).To(Succeed())
+
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v3.2.0"}
+			Expect(manager.InstallFromConfig(ctx, strings.NewReader("TestFont1@testsource >=v3.2\n"))).To(Succeed())
+			Expect(version()).To(Equal("v3.2.0"))
+		})
+
+		It("should fail when the source has no new enough release", func() {
+			err := manager.InstallFromConfig(ctx, strings.NewReader("TestFont1@testsource >=v4.0.0\n"))
+			Expect(err).To(MatchError(ContainSubstring("older than the required v4.0.0")))
+		})
+	})
+
+	Describe("Installing several fonts in parallel", func() {
+		It("should install every font and report failures in list order", func() {
+			mockSource1.failures["Broken1"] = fmt.Errorf("first failure")
+			mockSource1.failures["Broken2"] = fmt.Errorf("second failure")
+			manifest := "TestFont1@testsource\nBroken1@testsource\nTestFont2@testsource\nBroken2@testsource\n"
+
+			err := manager.InstallFromConfig(ctx, strings.NewReader(manifest), fm.WithParallelism(4))
+			Expect(err).To(MatchError(MatchRegexp(`(?s)Broken1.*first failure.*Broken2.*second failure`)))
+
+			fonts, err := manager.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fonts).To(HaveLen(2))
+		})
+
+		It("should report the outcome of each font", func() {
+			mockSource1.failures["Broken1"] = fmt.Errorf("first failure")
+			Expect(manager.Install(ctx, "TestFont2@testsource")).To(Succeed())
+			fonts := []fm.Font{
+				{Name: "TestFont1", Source: "testsource"},
+				{Name: "Broken1", Source: "testsource"},
+				{Name: "TestFont2", Source: "testsource"},
+			}
+
+			results, err := manager.InstallEach(ctx, fonts, fm.WithParallelism(2))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(3))
+			Expect(results[0]).NotTo(HaveOccurred())
+			Expect(results[1]).To(MatchError(ContainSubstring("first failure")))
+			Expect(results[2]).To(MatchError(fm.ErrAlreadyInstalled))
+		})
+
+		It("should install several fonts at once unless told otherwise", func() {
+			fonts := []fm.Font{
+				{Name: "TestFont1", Source: "testsource"},
+				{Name: "TestFont2", Source: "testsource"},
+				{Name: "TestOTF", Source: "testsource"},
+			}
+			busiest := func(opts ...fm.ManagerOption) int32 {
+				dir, err := os.MkdirTemp(tempDir, "parallel-*")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.MkdirAll(filepath.Join(dir, "user"), 0755)).To(Succeed())
+				manager := fm.NewManagerWithPlatform(&mockPlatform{fontDir: dir}, opts...)
+				source := &concurrencySource{mockSource: newMockSource()}
+				Expect(manager.RegisterSource(source)).To(Succeed())
+				results, err := manager.InstallEach(ctx, fonts)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(results).To(HaveEach(BeNil()))
+				return source.busiest.Load()
+			}
+
+			Expect(busiest()).To(BeNumerically(">", 1))
+			Expect(busiest(fm.WithDefaultParallelism(1))).To(Equal(int32(1)))
+		})
+
+		It("should report the progress of installs", func() {
+			var mu sync.Mutex
+			var reported []fm.Progress
+			progressManager := fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithProgress(func(p fm.Progress) {
+				mu.Lock()
+				defer mu.Unlock()
+				reported = append(reported, p)
+			}))
+			Expect(progressManager.RegisterSource(newMockSource())).To(Succeed())
+			Expect(progressManager.Install(ctx, "TestFont1")).To(Succeed())
+
+			var phases []fm.Phase
+			for _, p := range reported {
+				if len(phases) == 0 || phases[len(phases)-1] != p.Phase {
+					phases = append(phases, p.Phase)
+				}
+				if p.Phase != fm.PhaseCache {
+					Expect(p.Spec).To(Equal("TestFont1"))
+				}
+			}
+			Expect(phases).To(Equal([]fm.Phase{fm.PhaseResolve, fm.PhaseDownload, fm.PhaseExtract, fm.PhaseDone, fm.PhaseCache}))
+
+			var downloaded int64
+			for _, p := range reported {
+				if p.Phase == fm.PhaseDownload {
+					downloaded = p.Bytes
+				}
+			}
+			Expect(downloaded).To(Equal(int64(len(newMockSource().fonts["TestFont1"]))))
+
+			reported = nil
+			Expect(progressManager.Install(ctx, "TestFont1")).To(MatchError(fm.ErrAlreadyInstalled))
+			Expect(reported).To(HaveLen(2))
+			Expect(reported[1].Phase).To(Equal(fm.PhaseDone))
+			Expect(reported[1].Err).To(MatchError(fm.ErrAlreadyInstalled))
+		})
+
+		It("should skip installed fonts when installing a list again", func() {
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v3.2.1"}
+			fonts := []fm.Font{
+				{Name: "TestFont1", Source: "testsource", MinVersion: "v3.0"},
+				{Name: "TestFont2", Source: "testsource"},
+			}
+			Expect(manager.InstallFonts(ctx, fonts)).To(Succeed())
+			Expect(manager.InstallFonts(ctx, fonts)).To(Succeed())
+
+			results, err := manager.InstallEach(ctx, fonts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0]).To(MatchError(fm.ErrAlreadyInstalled))
+			Expect(results[1]).To(MatchError(fm.ErrAlreadyInstalled))
+		})
+	})
+
+	Describe("Upgrading fonts", func() {
+		BeforeEach(func() {
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v3.1.0"}
+			mockSource1.meta["TestFont2"] = map[string]string{"version": "v2.0.0"}
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+			Expect(manager.Install(ctx, "TestFont2@testsource")).To(Succeed())
+		})
+
+		installedVersion := func(name string) string {
+			fonts, err := manager.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			for _, font := range fonts {
+				if font.Name == name {
+					return font.Meta["version"]
+				}
+			}
+			return ""
+		}
+
+		It("should reinstall only fonts with a newer release", func() {
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v3.2.0"}
+
+			results, err := manager.Upgrade(ctx, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(ConsistOf(
+				fm.FontUpgrade{Name: "TestFont1", Source: "testsource", Installed: "v3.1.0", Latest: "v3.2.0", Outdated: true, Upgraded: true},
+				fm.FontUpgrade{Name: "TestFont2", Source: "testsource", Installed: "v2.0.0", Latest: "v2.0.0"},
+			))
+			Expect(installedVersion("TestFont1")).To(Equal("v3.2.0"))
+		})
+
+		It("should only check the named fonts", func() {
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v3.2.0"}
+			mockSource1.meta["TestFont2"] = map[string]string{"version": "v2.1.0"}
+
+			results, err := manager.Upgrade(ctx, []string{"TestFont2"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Upgraded).To(BeTrue())
+			Expect(installedVersion("TestFont1")).To(Equal("v3.1.0"))
+			Expect(installedVersion("TestFont2")).To(Equal("v2.1.0"))
+		})
+
+		It("should keep the region a font was installed with", func() {
+			mockSource1.meta["TestCJK"] = map[string]string{"region": "JP", "version": "1.0"}
+			Expect(manager.Install(ctx, "TestCJK@testsource", fm.WithRegion("KR"))).To(Succeed())
+			mockSource1.meta["TestCJK"] = map[string]string{"region": "JP", "version": "1.1"}
+
+			_, err := manager.Upgrade(ctx, []string{"TestCJK"})
+			Expect(err).NotTo(HaveOccurred())
+
+			fonts, err := manager.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			for _, font := range fonts {
+				if font.Name == "TestCJK" {
+					Expect(font.Meta["region"]).To(Equal("KR"))
+					Expect(font.Meta["version"]).To(Equal("1.1"))
+				}
+			}
+		})
+
+		It("should leave pinned fonts alone", func() {
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v3.2.0"}
+			Expect(manager.Pin(ctx, "TestFont1", true)).To(Succeed())
+
+			results, err := manager.Upgrade(ctx, []string{"TestFont1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(ConsistOf(
+				fm.FontUpgrade{Name: "TestFont1", Source: "testsource", Installed: "v3.1.0", Latest: "v3.2.0", Outdated: true, Pinned: true},
+			))
+			Expect(installedVersion("TestFont1")).To(Equal("v3.1.0"))
+
+			Expect(manager.Pin(ctx, "TestFont1", false)).To(Succeed())
+			results, err = manager.Upgrade(ctx, []string{"TestFont1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Upgraded).To(BeTrue())
+			Expect(installedVersion("TestFont1")).To(Equal("v3.2.0"))
+		})
+
+		It("should keep the previous install to roll back to", func() {
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithRollbackDir(filepath.Join(tempDir, "previous")))
+			Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+
+			_, err := manager.Rollback(ctx, "TestFont1")
+			Expect(err).To(MatchError(ContainSubstring("no earlier install of TestFont1")))
+
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v3.2.0"}
+			_, err = manager.Upgrade(ctx, []string{"TestFont1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installedVersion("TestFont1")).To(Equal("v3.2.0"))
+
+			font, err := manager.Rollback(ctx, "TestFont1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(font.Meta["version"]).To(Equal("v3.1.0"))
+			Expect(installedVersion("TestFont1")).To(Equal("v3.1.0"))
+
+			// Rolling back again returns to the upgrade
+			font, err = manager.Rollback(ctx, "TestFont1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(font.Meta["version"]).To(Equal("v3.2.0"))
+		})
+
+		It("should put the previous install back when an upgrade fails", func() {
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithRollbackDir(filepath.Join(tempDir, "previous")))
+			Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v3.2.0"}
+			mockSource1.failures["TestFont1"] = fmt.Errorf("download failed")
+			_, err := manager.Upgrade(ctx, []string{"TestFont1"})
+			Expect(err).To(MatchError(ContainSubstring("download failed")))
+			Expect(installedVersion("TestFont1")).To(Equal("v3.1.0"))
+		})
+
+		It("should report fonts without a recorded version", func() {
+			Expect(manager.Install(ctx, "TestTTF@testsource")).To(Succeed())
+
+			results, err := manager.Upgrade(ctx, []string{"TestTTF"})
+			Expect(err).To(MatchError(ContainSubstring("no version was recorded")))
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Upgraded).To(BeFalse())
+		})
+
+		It("should reject fonts that aren't installed", func() {
+			_, err := manager.Upgrade(ctx, []string{"Missing"})
+			Expect(err).To(MatchError(ContainSubstring("not installed")))
+		})
+
+		It("should report outdated fonts without reinstalling them", func() {
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v3.2.0"}
+
+			results, err := manager.Outdated(ctx, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(ConsistOf(
+				fm.FontUpgrade{Name: "TestFont1", Source: "testsource", Installed: "v3.1.0", Latest: "v3.2.0", Outdated: true},
+				fm.FontUpgrade{Name: "TestFont2", Source: "testsource", Installed: "v2.0.0", Latest: "v2.0.0"},
+			))
+			Expect(installedVersion("TestFont1")).To(Equal("v3.1.0"))
+		})
+
+		It("should compare patched fonts with their upstream release", func() {
+			// The patched build bundles Go 2.010, while upstream is at 2.1
+			patched, err := createTestZip(testFont{name: "GoPatched", format: "ttf", content: string(goregular.TTF)})
+			Expect(err).NotTo(HaveOccurred())
+			mockSource1.fonts["GoPatched"] = patched
+			mockSource1.meta["GoPatched"] = map[string]string{"version": "v3.2.0"}
+			upstream := newMockSource()
+			upstream.name = "upstream"
+			upstream.fonts["Go"] = patched
+			upstream.meta["Go"] = map[string]string{"version": "2.1"}
+
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithEquivalents(fm.Equivalent{
+				Patched:  fm.Alias{Source: "testsource", Name: "GoPatched"},
+				Upstream: fm.Alias{Source: "upstream", Name: "Go"},
+			}))
+			Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+			Expect(manager.RegisterSource(upstream)).To(Succeed())
+			Expect(manager.Install(ctx, "GoPatched@testsource")).To(Succeed())
+
+			results, err := manager.Outdated(ctx, []string{"GoPatched", "TestFont1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Outdated).To(BeFalse())
+			Expect(results[0].Upstream).To(Equal(&fm.UpstreamRelease{
+				Source:  "upstream",
+				Name:    "Go",
+				Bundled: "Version 2.010; ttfautohint (v1.8.3)",
+				Latest:  "2.1",
+				Newer:   true,
+			}))
+			Expect(results[1].Upstream).To(BeNil())
+		})
+
+		It("should report fonts whose source can't be checked", func() {
+			mockSource1.failures["TestFont2"] = fmt.Errorf("simulated failure")
+
+			results, err := manager.Outdated(ctx, []string{"TestFont2"})
+			Expect(err).To(MatchError(ContainSubstring("simulated failure")))
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Outdated).To(BeFalse())
+		})
+	})
+
+	Describe("Installing variable axes", func() {
+		var (
+			variableSource *mockVariableSource
+			instancerLog   string
+		)
+
+		BeforeEach(func() {
+			variableSource = &mockVariableSource{
+				mockSource: newMockSource(),
+				axes:       []fm.AxisRange{{Tag: "wght", Min: 100, Max: 900}, {Tag: "slnt", Min: -10, Max: 0}},
+			}
+			variableSource.name = "varsource"
+			zipData, err := createTestZip(
+				testFont{name: "TestVar[wght]", format: "ttf", content: string(variableFont(fm.AxisRange{Tag: "wght", Min: 100, Max: 900}))},
+				testFont{name: "TestVar-Regular", format: "ttf", content: string(goregular.TTF)},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			variableSource.fonts["TestVar"] = zipData
+
+			// Stands in for fontTools: logs the axis arguments and writes a
+			// marker to the output file
+			instancerLog = filepath.Join(tempDir, "instancer.log")
+			instancer := filepath.Join(tempDir, "instancer.sh")
+			script := "#!/bin/sh\nshift\nwhile [ \"$1\" != -o ]; do echo \"$1\" >> " + instancerLog + "; shift; done\nprintf instanced > \"$2\"\n"
+			Expect(os.WriteFile(instancer, []byte(script), 0755)).To(Succeed())
+
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithInstancer("sh", instancer))
+			Expect(manager.RegisterSource(variableSource)).To(Succeed())
+		})
+
+		It("should limit the variable files to the requested ranges", func() {
+			Expect(manager.Install(ctx, "TestVar@varsource", fm.WithAxes(fm.AxisRange{Tag: "wght", Min: 400, Max: 700}, fm.AxisRange{Tag: "slnt", Min: 0, Max: 0}))).To(Succeed())
+
+			// Only the axes the file has are passed to the instancer
+			log, err := os.ReadFile(instancerLog)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(log)).To(Equal("wght=400:700\n"))
+
+			dir := filepath.Join(tempDir, "user", "TestVar")
+			Expect(os.ReadFile(filepath.Join(dir, "TestVar[wght].ttf"))).To(BeEquivalentTo("instanced"))
+			Expect(os.ReadFile(filepath.Join(dir, "TestVar-Regular.ttf"))).To(Equal(goregular.TTF))
+
+			fonts, err := manager.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fonts).To(HaveLen(1))
+			Expect(fonts[0].Meta["axes"]).To(Equal("wght=400..700,slnt=0"))
+
+			// The manifest describes the instanced file
+			results, err := manager.Verify(ctx, nil, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].OK()).To(BeTrue(), "%+v", results[0])
+		})
+
+		It("should reject ranges outside the design space", func() {
+			err := manager.Install(ctx, "TestVar@varsource", fm.WithAxes(fm.AxisRange{Tag: "wght", Min: 50, Max: 700}))
+			Expect(err).To(MatchError(ContainSubstring("outside the design space wght=100..900")))
+
+			err = manager.Install(ctx, "TestVar@varsource", fm.WithAxes(fm.AxisRange{Tag: "wdth", Min: 100, Max: 100}))
+			Expect(err).To(MatchError(ContainSubstring("axis wdth is not available")))
+		})
+
+		It("should reject sources without variable builds", func() {
+			Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+			err := manager.Install(ctx, "TestFont1@testsource", fm.WithAxes(fm.AxisRange{Tag: "wght", Min: 400, Max: 700}))
+			Expect(err).To(MatchError(ContainSubstring("does not publish variable fonts")))
+		})
+	})
+
+	Describe("Installing web font subsets", func() {
+		var webSource *mockSubsetSource
+
+		BeforeEach(func() {
+			webSource = &mockSubsetSource{mockSource: newMockSource()}
+			webSource.name = "websource"
+			Expect(manager.RegisterSource(webSource)).To(Succeed())
+		})
+
+		It("should download only the requested subsets", func() {
+			Expect(manager.Install(ctx, "TestFont1@websource", fm.WithSubsets("greek", "Latin"))).To(Succeed())
+			Expect(webSource.downloaded).To(Equal("greek,latin"))
+		})
+
+		It("should pick subsets covering the requested unicode ranges", func() {
+			Expect(manager.Install(ctx, "TestFont1@websource", fm.WithUnicodeRanges("U+0041-005A, U+04??"))).To(Succeed())
+			Expect(webSource.downloaded).To(Equal("cyrillic,latin"))
+		})
+
+		It("should reject unknown subsets", func() {
+			err := manager.Install(ctx, "TestFont1@websource", fm.WithSubsets("klingon"))
+			Expect(err).To(MatchError(ContainSubstring(`subset "klingon" is not available`)))
+		})
+
+		It("should reject sources that don't publish subsets", func() {
+			err := manager.Install(ctx, "TestFont1@testsource", fm.WithSubsets("latin"))
+			Expect(err).To(MatchError(ContainSubstring("does not support subset downloads")))
+		})
+	})
+
+	Describe("Logging", func() {
+		It("should log the download and each extracted file at debug level", func() {
+			logged := new(bytes.Buffer)
+			logger := slog.New(slog.NewTextHandler(logged, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithLogger(logger))
+			Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+			Expect(logged.String()).To(ContainSubstring(`level=DEBUG msg=downloading font=TestFont1 version="unknown version" source=testsource`))
+			Expect(logged.String()).To(MatchRegexp(`msg=downloaded font=TestFont1 size=.* sha256=[0-9a-f]{64} entries=2\n`))
+			Expect(logged.String()).To(ContainSubstring("msg=extracted font=TestFont1 file=TestFont1.ttf size="))
+		})
+
+		It("should log problems it works around as warnings", func() {
+			logged := new(bytes.Buffer)
+			logger := slog.New(slog.NewTextHandler(logged, nil))
+			platform := &failingCachePlatform{mockPlatform: mockPlatform{fontDir: tempDir}}
+			manager = fm.NewManagerWithPlatform(platform, fm.WithLogger(logger))
+			Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+
+			platform.failing = true
+			Expect(manager.Uninstall(ctx, "TestFont1")).To(Succeed())
+			Expect(logged.String()).To(ContainSubstring(`level=WARN msg="failed to update font cache" err="fc-cache failed"`))
+			Expect(logged.String()).NotTo(ContainSubstring("DEBUG"))
+		})
+	})
+
+	Describe("Hooks", func() {
+		var events []fm.HookEvent
+		var fail error
+
+		BeforeEach(func() {
+			events, fail = nil, nil
+			hook := func(_ context.Context, event fm.HookEvent) error {
+				events = append(events, event)
+				if !event.After {
+					return fail
+				}
+				return nil
+			}
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithHook(hook))
+			Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+		})
+
+		It("should be called before and after installs and uninstalls", func() {
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v3.1.0"}
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+			dir := filepath.Join(tempDir, "user", "TestFont1")
+			Expect(events).To(Equal([]fm.HookEvent{
+				{Action: fm.HookInstall, Font: "TestFont1", Source: "testsource"},
+				{Action: fm.HookInstall, After: true, Font: "TestFont1", Source: "testsource", Version: "v3.1.0", Dir: dir},
+			}))
+
+			events = nil
+			Expect(manager.Uninstall(ctx, "TestFont1")).To(Succeed())
+			Expect(events).To(Equal([]fm.HookEvent{
+				{Action: fm.HookUninstall, Font: "TestFont1", Source: "testsource", Version: "v3.1.0", Dir: dir},
+				{Action: fm.HookUninstall, After: true, Font: "TestFont1", Source: "testsource", Version: "v3.1.0", Dir: dir},
+			}))
+		})
+
+		It("should report upgrades as such", func() {
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v3.1.0"}
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+
+			events = nil
+			mockSource1.meta["TestFont1"] = map[string]string{"version": "v3.2.0"}
+			_, err := manager.Upgrade(ctx, []string{"TestFont1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Action).To(Equal(fm.HookUpgrade))
+			Expect(events[1].Version).To(Equal("v3.2.0"))
+		})
+
+		It("should abort the change when a hook fails before it", func() {
+			fail = errors.New("not now")
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(MatchError(ContainSubstring("pre-install hook: not now")))
+			Expect(filepath.Join(tempDir, "user", "TestFont1")).NotTo(BeADirectory())
+			Expect(events).To(HaveLen(1))
+
+			fail = nil
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+			fail = errors.New("still in use")
+			Expect(manager.Uninstall(ctx, "TestFont1")).To(MatchError(ContainSubstring("pre-uninstall hook: still in use")))
+			Expect(filepath.Join(tempDir, "user", "TestFont1")).To(BeADirectory())
+		})
+
+		It("should not be called for dry runs", func() {
+			_, err := manager.PlanInstall(ctx, []fm.Font{{Name: "TestFont1", Source: "testsource"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(BeEmpty())
+		})
+	})
+
+	Describe("Interrupted installs", func() {
+		It("should remove what a failed install wrote", func() {
+			err := manager.Install(ctx, "TestFont1@testsource variants=Light")
+			Expect(err).To(HaveOccurred())
+			Expect(filepath.Join(tempDir, "user", "TestFont1")).NotTo(BeADirectory())
+			Expect(filepath.Glob(filepath.Join(tempDir, "user", "*"))).To(BeEmpty())
+		})
+
+		It("should replace a directory in the way that isn't an installed font", func() {
+			stray := filepath.Join(tempDir, "user", "TestFont1")
+			Expect(os.MkdirAll(stray, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(stray, "notes.txt"), []byte("left by hand"), 0644)).To(Succeed())
+
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+			Expect(filepath.Join(stray, "TestFont1.ttf")).To(BeARegularFile())
+			Expect(filepath.Join(stray, "notes.txt")).NotTo(BeAnExistingFile())
+			Expect(filepath.Glob(filepath.Join(tempDir, "user", "*"))).To(ConsistOf(stray))
+		})
+
+		It("should not start installs once cancelled", func() {
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+			results, err := manager.InstallEach(cancelled, []fm.Font{
+				{Name: "TestFont1", Source: "testsource"},
+				{Name: "TestFont2", Source: "testsource"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveEach(MatchError(context.Canceled)))
+			Expect(mockSource1.downloads).To(BeEmpty())
+		})
+
+		It("should find and clean up installs cut short", func() {
+			user := filepath.Join(tempDir, "user")
+			Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+			Expect(manager.Install(ctx, "TestFont2@testsource")).To(Succeed())
+
+			// A staged install that never completed
+			staging := filepath.Join(user, "Staged.123.fm-staging")
+			Expect(os.MkdirAll(staging, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(staging, "Staged.ttf"), []byte("font"), 0644)).To(Succeed())
+			Expect(manager.IsInstalled(ctx, "Staged")).To(BeFalse())
+			// A reinstall quit before writing anything
+			Expect(os.Rename(filepath.Join(user, "TestFont1"), filepath.Join(user, "TestFont1.fm-replaced"))).To(Succeed())
+			// A reinstall that completed, but not its cleanup
+			Expect(os.MkdirAll(filepath.Join(user, "TestFont2.fm-replaced"), 0755)).To(Succeed())
+
+			interrupted, err := manager.Interrupted()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(interrupted).To(ConsistOf(
+				fm.InterruptedInstall{Dir: staging, Partial: true},
+				fm.InterruptedInstall{Dir: filepath.Join(user, "TestFont1"), SetAside: filepath.Join(user, "TestFont1.fm-replaced")},
+				fm.InterruptedInstall{Dir: filepath.Join(user, "TestFont2"), SetAside: filepath.Join(user, "TestFont2.fm-replaced")},
+			))
+
+			cleaned, err := manager.CleanInterrupted()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cleaned).To(HaveLen(3))
+			Expect(staging).NotTo(BeADirectory())
+			Expect(filepath.Join(user, "TestFont1", "TestFont1.ttf")).To(BeARegularFile())
+			Expect(filepath.Join(user, "TestFont2.fm-replaced")).NotTo(BeADirectory())
+			Expect(filepath.Join(user, "TestFont2", "TestFont2.ttf")).To(BeARegularFile())
+
+			interrupted, err = manager.Interrupted()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(interrupted).To(BeEmpty())
+		})
+	})
+
+	Describe("Installing from sources with a license agreement", func() {
+		BeforeEach(func() {
+			eulaSource := &mockEULASource{mockSource: newMockSource()}
+			eulaSource.name = "eulasource"
+			Expect(manager.RegisterSource(eulaSource)).To(Succeed())
+		})
+
+		It("should refuse to install until the agreement is accepted", func() {
+			err := manager.Install(ctx, "TestFont1@eulasource")
+			Expect(err).To(MatchError(fm.ErrEULANotAccepted))
+			Expect(err).To(MatchError(ContainSubstring("the Test EULA")))
+
+			installed, err := manager.IsInstalled(ctx, "TestFont1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installed).To(BeFalse())
+		})
+
+		It("should install once the agreement is accepted", func() {
+			Expect(manager.Install(ctx, "TestFont1@eulasource", fm.WithAcceptEULA())).To(Succeed())
+
+			installed, err := manager.IsInstalled(ctx, "TestFont1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installed).To(BeTrue())
+		})
+	})
+
+	Describe("Enforcing allowed licenses", func() {
+		var auditLog *fm.AuditLog
+
+		BeforeEach(func() {
+			// An archive whose license file identifies as OFL-1.1
+			buf := new(bytes.Buffer)
+			zipWriter := zip.NewWriter(buf)
+			f, err := zipWriter.Create("OpenFont-Regular.ttf")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.Write([]byte("open font"))
+			Expect(err).NotTo(HaveOccurred())
+			f, err = zipWriter.Create("OFL.txt")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.Write([]byte("This Font Software is licensed under the SIL Open Font License,\nVersion 1.1."))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(zipWriter.Close()).To(Succeed())
+			mockSource1.fonts["OpenFont"] = buf.Bytes()
+
+			auditLog = fm.NewAuditLog(filepath.Join(tempDir, "audit.log"))
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir},
+				fm.WithAuditLog(auditLog), fm.WithAllowedLicenses("OFL-1.1", "Apache-2.0"))
+			Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+		})
+
+		It("should install fonts under an allowed license", func() {
+			Expect(manager.Install(ctx, "OpenFont@testsource")).To(Succeed())
+
+			entries, err := auditLog.Entries(time.Time{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].License).To(Equal("OFL-1.1"))
+			Expect(entries[0].LicenseOverride).To(BeFalse())
+		})
+
+		It("should show the license files installed with a font", func() {
+			Expect(manager.Install(ctx, "OpenFont@testsource")).To(Succeed())
+			Expect(manager.Install(ctx, "TestFont1@testsource", fm.WithLicenseOverride())).To(Succeed())
+
+			license, err := manager.License(ctx, "OpenFont")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(license.ID).To(Equal("OFL-1.1"))
+			Expect(license.Files).To(ConsistOf(filepath.Join(tempDir, "user", "OpenFont", "OFL.txt")))
+
+			licenses, err := manager.Licenses(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(licenses).To(HaveExactElements(
+				And(HaveField("Font.Name", "OpenFont"), HaveField("ID", "OFL-1.1")),
+				And(HaveField("Font.Name", "TestFont1"), HaveField("ID", ""), HaveField("Files", HaveLen(1))),
+			))
+		})
+
+		It("should block fonts whose license isn't allowed", func() {
+			err := manager.Install(ctx, "TestFont1@testsource")
+			Expect(err).To(MatchError(fm.ErrLicenseNotAllowed))
+			Expect(err).To(MatchError(ContainSubstring("--override-license")))
+
+			installed, err := manager.IsInstalled(ctx, "TestFont1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installed).To(BeFalse())
+		})
+
+		It("should record overrides in the audit log", func() {
+			Expect(manager.Install(ctx, "TestFont1@testsource", fm.WithLicenseOverride())).To(Succeed())
+
+			entries, err := auditLog.Entries(time.Time{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Result).To(Equal("success"))
+			Expect(entries[0].LicenseOverride).To(BeTrue())
+		})
+	})
+
+	Describe("Searching sources", func() {
+		BeforeEach(func() {
+			other := newMockSource()
+			other.name = "othersource"
+			Expect(manager.RegisterSource(other)).To(Succeed())
+		})
+
+		It("should merge results from every source in registration order", func() {
+			results, err := manager.Search(ctx, "TestFont1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+			Expect(results[0].Source).To(Equal("testsource"))
+			Expect(results[1].Source).To(Equal("othersource"))
+			Expect(results[0].Font.Verified()).To(BeTrue())
+			Expect(results[0].Match).To(Equal(fm.MatchExact))
+			Expect(results[0].Score).To(Equal(1.0))
+		})
+
+		It("should rank exact matches before prefixes and fuzzy matches", func() {
+			catalog := &mockCatalogSearchSource{
+				mockSource: newMockSource(),
+				names:      []string{"Fira Sans", "Fira Code Retina", "FiraCode", "Fira Code"},
+			}
+			catalog.name = "catalog"
+			pending := &mockCatalogSearchSource{mockSource: newMockSource(), names: []string{"Fira Code"}, pending: true}
+			pending.name = "pending"
+			manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir})
+			Expect(manager.RegisterSource(pending)).To(Succeed())
+			Expect(manager.RegisterSource(catalog)).To(Succeed())
+
+			results, err := manager.Search(ctx, "fira code")
+			Expect(err).NotTo(HaveOccurred())
+			var ranked []string
+			for _, result := range results {
+				ranked = append(ranked, fmt.Sprintf("%s@%s %s", result.Font.Name, result.Source, result.Match))
+			}
+			Expect(ranked).To(Equal([]string{
+				"Fira Code@catalog exact",
+				"FiraCode@catalog exact",
+				"Fira Code@pending exact",
+				"Fira Code Retina@catalog prefix",
+				"Fira Sans@catalog fuzzy",
+			}))
+			for i := 1; i < len(results); i++ {
+				Expect(results[i].Score).To(BeNumerically("<", results[i-1].Score))
+			}
+		})
+
+		It("should return what other sources found when one fails", func() {
+			broken := &mockBrokenSource{mockSource: newMockSource()}
+			broken.name = "brokensource"
+			Expect(manager.RegisterSource(broken)).To(Succeed())
+
+			results, err := manager.Search(ctx, "TestFont1")
+			Expect(err).To(MatchError(ContainSubstring("searching in brokensource: catalog unavailable")))
+			Expect(results).To(HaveLen(2))
+		})
+
+		It("should find nothing for unknown fonts", func() {
+			results, err := manager.Search(ctx, "Missing Font")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(BeEmpty())
+		})
+	})
+
+	Describe("Listing installed files", func() {
+		var font fm.Font
+
+		BeforeEach(func() {
+			Expect(manager.Install(ctx, "TestMulti")).To(Succeed())
+
+			fonts, err := manager.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fonts).To(HaveLen(1))
+			font = fonts[0]
+		})
+
+		It("should report the files recorded at install time", func() {
+			files, err := manager.Files(ctx, font, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(HaveLen(3))
+
+			Expect(files[0].Name).To(Equal("LICENSE"))
+			Expect(files[0].Format).To(Equal("license"))
+			Expect(files[1].Name).To(Equal("TestMulti.otf"))
+			Expect(files[1].Size).To(Equal(int64(len("otf version"))))
+			Expect(files[1].SHA256).To(Equal("9ecea105d8f36860fe36868aea4f734924f0c1689e37d99de60117f1d7358a79"))
+			Expect(files[2].Format).To(Equal("ttf"))
+		})
+
+		It("should use the recorded hashes until asked to rehash", func() {
+			path := filepath.Join(font.Meta["directory"], "TestMulti.ttf")
+			Expect(os.WriteFile(path, []byte("modified"), 0644)).To(Succeed())
+
+			files, err := manager.Files(ctx, font, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files[2].Size).To(Equal(int64(len("ttf version"))))
+
+			files, err = manager.Files(ctx, font, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files[2].Size).To(Equal(int64(len("modified"))))
+		})
+	})
+
+	Describe("Matching font names", func() {
+		It("should ignore case and compatibility forms", func() {
+			Expect(manager.Install(ctx, "TestFont1")).To(Succeed())
+
+			for _, name := range []string{"TESTFONT1", "testfont1", "\uff34\uff45\uff53\uff54\uff26\uff4f\uff4e\uff54\uff11"} {
+				installed, err := manager.IsInstalled(ctx, name)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(installed).To(BeTrue(), name)
+			}
+		})
+
+		It("should match composed and decomposed accents", func() {
+			installer := fm.NewFontInstaller(filepath.Join(tempDir, "user"))
+			archive, err := createTestZip(testFont{name: "Cafe", format: "ttf", content: "cafe"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installer.Install(fm.Font{Name: "Caf\u00e9 Sans"}, bytes.NewReader(archive))).To(Succeed())
+
+			installed, err := manager.IsInstalled(ctx, "Cafe\u0301 Sans")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installed).To(BeTrue())
+
+			Expect(manager.Uninstall(ctx, "CAF\u00c9 SANS")).To(Succeed())
+		})
+	})
+
+	Describe("Uninstalling fonts", func() {
+		BeforeEach(func() {
+			Expect(manager.Install(ctx, "TestFont1")).To(Succeed())
+		})
+
+		It("should uninstall fonts successfully", func() {
+			Expect(manager.Uninstall(ctx, "TestFont1")).To(Succeed())
+
+			installed, err := manager.IsInstalled(ctx, "TestFont1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installed).To(BeFalse())
+		})
+
+		It("should fail when trying to uninstall non-existent fonts", func() {
+			err := manager.Uninstall(ctx, "NonExistentFont")
+			Expect(err).To(MatchError(fm.ErrNotInstalled))
+			Expect(err.Error()).To(ContainSubstring("not installed"))
+		})
+
+		It("should match installed fonts by pattern and source", func() {
+			Expect(manager.Install(ctx, "TestFont2")).To(Succeed())
+
+			names := func(fonts []fm.Font, err error) []string {
+				Expect(err).NotTo(HaveOccurred())
+				var names []string
+				for _, font := range fonts {
+					names = append(names, font.Name)
+				}
+				return names
+			}
+			Expect(names(manager.MatchInstalled(ctx, "testfont*", ""))).To(Equal([]string{"TestFont1", "TestFont2"}))
+			Expect(names(manager.MatchInstalled(ctx, "TestFont[2-9]", ""))).To(Equal([]string{"TestFont2"}))
+			Expect(names(manager.MatchInstalled(ctx, "", "TESTSOURCE"))).To(HaveLen(2))
+			Expect(names(manager.MatchInstalled(ctx, "Test*", "nerdfonts"))).To(BeEmpty())
+
+			_, err := manager.MatchInstalled(ctx, "Test[", "")
+			Expect(err).To(MatchError(ContainSubstring("invalid pattern")))
+			Expect(fm.IsPattern("Fira*")).To(BeTrue())
+			Expect(fm.IsPattern("Fira Code")).To(BeFalse())
+		})
+
+		It("should uninstall several fonts with one cache update", func() {
+			platform := &mockPlatform{fontDir: tempDir}
+			manager := fm.NewManagerWithPlatform(platform)
+			Expect(manager.RegisterSource(mockSource1)).To(Succeed())
+			Expect(manager.Install(ctx, "TestFont2")).To(Succeed())
+			platform.cacheUpdates = 0
+
+			results := manager.UninstallFonts(ctx, []fm.Font{{Name: "TestFont1"}, {Name: "NonExistentFont"}, {Name: "TestFont2"}})
+			Expect(results).To(HaveLen(3))
+			Expect(results[0]).NotTo(HaveOccurred())
+			Expect(results[1]).To(MatchError(ContainSubstring("not installed")))
+			Expect(results[2]).NotTo(HaveOccurred())
+			Expect(platform.cacheUpdates).To(Equal(1))
+
+			fonts, err := manager.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fonts).To(BeEmpty())
+		})
+
+		It("should only count fonts fm installed as managed", func() {
+			for _, path := range []string{"user/Manual/Manual.ttf", "system/DejaVu/DejaVuSans.ttf"} {
+				path = filepath.Join(tempDir, path)
+				Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+				Expect(os.WriteFile(path, []byte("font"), 0644)).To(Succeed())
+			}
+
+			managed, err := manager.ManagedFonts(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(managed).To(ConsistOf(HaveField("Name", "TestFont1")))
+		})
+	})
+})
+
+// concurrencySource records how many downloads it served at once
+type concurrencySource struct {
+	*mockSource
+	active, busiest atomic.Int32
+}
+
+func (s *concurrencySource) Download(ctx context.Context, font fm.Font) (io.ReadCloser, error) {
+	active := s.active.Add(1)
+	defer s.active.Add(-1)
+	for {
+		busiest := s.busiest.Load()
+		if active <= busiest || s.busiest.CompareAndSwap(busiest, active) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return s.mockSource.Download(ctx, font)
+}
+
+// failingCachePlatform fails to refresh the font cache once failing is set
+type failingCachePlatform struct {
+	mockPlatform
+	failing bool
+}
+
+func (p *failingCachePlatform) UpdateFontCache() error {
+	if p.failing {
+		return errors.New("fc-cache failed")
+	}
+	return p.mockPlatform.UpdateFontCache()
+}
@@ -0,0 +1,70 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IsPattern reports whether name is a glob pattern rather than a font name
+func IsPattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// MatchInstalled returns the installed fonts that can be uninstalled whose
+// name matches the glob pattern, ignoring case, and that came from source.
+// An empty pattern or source matches every font.
+func (m *DefaultManager) MatchInstalled(ctx context.Context, pattern, source string) ([]Font, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	fonts, err := m.removableFonts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Font
+	for _, font := range fonts {
+		if source != "" && !strings.EqualFold(font.Source, source) {
+			continue
+		}
+		if pattern != "" {
+			if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(font.Name)); !ok {
+				continue
+			}
+		}
+		matches = append(matches, font)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return strings.ToLower(matches[i].Name) < strings.ToLower(matches[j].Name)
+	})
+	return matches, nil
+}
+
+// removableFonts returns the installed fonts fm may uninstall: those in the
+// user font directory, and those in the system one under the system scope or
+// that fm installed there
+func (m *DefaultManager) removableFonts(ctx context.Context) ([]Font, error) {
+	paths, err := m.platform.GetFontPaths()
+	if err != nil {
+		return nil, err
+	}
+	fonts, err := m.listManaged(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var removable []Font
+	for _, font := range fonts {
+		dir := filepath.Dir(font.Meta["directory"])
+		systemWide := m.systemScope || font.Meta[scopeMetaKey] == ScopeSystem
+		if dir == filepath.Clean(paths.UserDir) || systemWide && dir == filepath.Clean(paths.SystemDir) {
+			removable = append(removable, font)
+		}
+	}
+	return removable, nil
+}
@@ -0,0 +1,86 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// msCoreFontsEULA is the agreement shipped in every Core Fonts package
+const msCoreFontsEULA = "the Microsoft Core Fonts EULA (https://corefonts.sourceforge.net/eula.htm)"
+
+// msCoreFontsURL is the SourceForge mirror of the original packages, which
+// Microsoft no longer hosts
+const msCoreFontsURL = "https://downloads.sourceforge.net/corefonts/%s.exe"
+
+// msCoreFontsPackages maps each family to the self-extracting package that
+// contains it
+var msCoreFontsPackages = map[string]string{
+	"Andale Mono":     "andale32",
+	"Arial":           "arial32",
+	"Arial Black":     "arialb32",
+	"Comic Sans MS":   "comic32",
+	"Courier New":     "courie32",
+	"Georgia":         "georgi32",
+	"Impact":          "impact32",
+	"Times New Roman": "times32",
+	"Trebuchet MS":    "trebuc32",
+	"Verdana":         "verdan32",
+	"Webdings":        "webdin32",
+}
+
+// MSCoreFontsSource installs the Microsoft Core Fonts for the Web. Each
+// family comes as a self-extracting cabinet, which the installer unpacks
+// like any other archive.
+type MSCoreFontsSource struct {
+	client *http.Client
+}
+
+func NewMSCoreFontsSource(opts ...SourceOption) *MSCoreFontsSource {
+	o := newSourceOptions(opts)
+	return &MSCoreFontsSource{
+		client: o.client,
+	}
+}
+
+func (s *MSCoreFontsSource) Name() string {
+	return "mscorefonts"
+}
+
+func (s *MSCoreFontsSource) Search(ctx context.Context, name string) ([]Font, error) {
+	for family, pkg := range msCoreFontsPackages {
+		if sameFontName(family, name) {
+			return []Font{{
+				Name:   family,
+				Source: s.Name(),
+				URL:    fmt.Sprintf(msCoreFontsURL, pkg),
+				Meta:   map[string]string{"package": pkg},
+			}}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *MSCoreFontsSource) Download(ctx context.Context, font Font) (io.ReadCloser, error) {
+	pkg, ok := font.Meta["package"]
+	if !ok {
+		return nil, fmt.Errorf("%s is not one of the core fonts", font.Name)
+	}
+	return openURL(ctx, s.client, fmt.Sprintf(msCoreFontsURL, pkg))
+}
+
+// EULA returns the agreement covering every core font
+func (s *MSCoreFontsSource) EULA(font Font) string {
+	return msCoreFontsEULA
+}
+
+// Describe reports the source; its package list is built in
+func (s *MSCoreFontsSource) Describe() SourceInfo {
+	return describeSource("mscorefonts", nil, "")
+}
+
+// CheckHealth probes the download mirror with the smallest package
+func (s *MSCoreFontsSource) CheckHealth(ctx context.Context) SourceHealth {
+	return checkEndpoint(ctx, s.client, fmt.Sprintf(msCoreFontsURL, msCoreFontsPackages["Webdings"]))
+}
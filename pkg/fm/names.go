@@ -0,0 +1,111 @@
+package fm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+var caseFolder = cases.Fold()
+
+// normalizeName folds a font name for comparison so that case, composed and
+// decomposed accents, compatibility forms (e.g. full-width letters) and
+// runs of whitespace don't affect matching
+func normalizeName(name string) string {
+	name = caseFolder.String(norm.NFKC.String(name))
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// sameFontName reports whether two font names refer to the same font
+func sameFontName(a, b string) bool {
+	return normalizeName(a) == normalizeName(b)
+}
+
+// installedKey maps a font name to the key installed fonts are matched by.
+// Installed fonts are only known by their directory name, so the key is the
+// directory name of the normalized name.
+func installedKey(name string) string {
+	return fontDirName(normalizeName(name))
+}
+
+// Generated names stay well inside the 255 bytes most filesystems allow for
+// a single name (NAME_MAX), leaving room for the rest of the path
+const (
+	maxDirNameLength  = 64
+	maxFileNameLength = 128
+)
+
+// fontDirName returns the name of the directory a font is installed in: the
+// sanitized name, with a short hash of the normalized name appended when the
+// sanitized form is too long or lost letters, as with CJK family names, so
+// such names neither fail to install nor share a directory
+func fontDirName(name string) string {
+	dir := sanitizeFontName(name)
+	if dir != "" && len(dir) <= maxDirNameLength && !dropsLetters(name) {
+		return dir
+	}
+	if dir == "" {
+		dir = "font"
+	}
+	return hashedName(dir, normalizeName(name), "", maxDirNameLength)
+}
+
+// dropsLetters reports whether sanitizing name replaces letters or digits,
+// which sanitizeFontName only keeps from the Latin alphabet
+func dropsLetters(name string) bool {
+	for _, r := range stripAccents(name) {
+		if r > unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return true
+		}
+	}
+	return false
+}
+
+// installedFileName returns the name an archive entry is extracted as: its
+// base name, shortened with a hash when it is longer than maxFileNameLength
+func installedFileName(entry string) string {
+	name := filepath.Base(entry)
+	if len(name) <= maxFileNameLength {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return hashedName(strings.TrimSuffix(name, ext), name, ext, maxFileNameLength)
+}
+
+// hashedName cuts prefix so that it, a hash of key and ext fit in limit bytes
+func hashedName(prefix, key, ext string, limit int) string {
+	sum := sha256.Sum256([]byte(key))
+	suffix := "-" + hex.EncodeToString(sum[:4]) + ext
+	for len(prefix) > limit-len(suffix) {
+		_, size := utf8.DecodeLastRuneInString(prefix)
+		prefix = prefix[:len(prefix)-size]
+	}
+	return strings.TrimRight(prefix, "-") + suffix
+}
+
+// installName returns the name a font spec installs as: its install_as
+// option, or its name
+func (f Font) installName() string {
+	if name := f.Options["install_as"]; name != "" {
+		return name
+	}
+	return f.Name
+}
+
+// stripAccents removes combining marks after decomposing, so "Café" and
+// "Cafe" share a directory regardless of how the accent was encoded
+func stripAccents(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(name) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
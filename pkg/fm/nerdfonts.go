@@ -2,9 +2,9 @@ package fm
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 )
@@ -12,11 +12,16 @@ import (
 // NerdFontsSource provides access to NerdFonts repository
 type NerdFontsSource struct {
 	client *http.Client
+	cache  *Cache
+	logger *slog.Logger
 }
 
-func NewNerdFontsSource() *NerdFontsSource {
+func NewNerdFontsSource(opts ...SourceOption) *NerdFontsSource {
+	o := newSourceOptions(opts)
 	return &NerdFontsSource{
-		client: defaultClient,
+		client: o.client,
+		cache:  o.cache,
+		logger: o.logger,
 	}
 }
 
@@ -24,32 +29,16 @@ func (s *NerdFontsSource) Name() string {
 	return "nerdfonts"
 }
 
+const nerdFontsLatestReleaseURL = "https://api.github.com/repos/ryanoasis/nerd-fonts/releases/latest"
+
 type nerdFontsRelease struct {
 	TagName string `json:"tag_name"`
 }
 
 func (s *NerdFontsSource) getLatestVersion(ctx context.Context) (string, error) {
-	req, err := http.NewRequestWithContext(ctx,
-		"GET",
-		"https://api.github.com/repos/ryanoasis/nerd-fonts/releases/latest",
-		nil)
-	if err != nil {
-		return "", fmt.Errorf("creating request: %w", err)
-	}
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("fetching latest release: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
 	var release nerdFontsRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return "", fmt.Errorf("decoding response: %w", err)
+	if err := getJSON(ctx, s.client, s.cache, s.logger, nerdFontsLatestReleaseURL, &release); err != nil {
+		return "", fmt.Errorf("fetching latest release: %w", err)
 	}
 
 	return release.TagName, nil
@@ -62,41 +51,75 @@ func (s *NerdFontsSource) Search(ctx context.Context, name string) ([]Font, erro
 	// Clean up the name to match NerdFonts naming convention
 	cleanName := strings.ReplaceAll(strings.TrimSpace(name), " ", "")
 
+	meta := map[string]string{"pending": "true"}
+
+	// Record the release being installed so version constraints can be
+	// checked later; Download falls back to the latest release without it
+	if version, err := s.getLatestVersion(ctx); err == nil {
+		meta["version"] = version
+	}
+
 	// You might want to maintain a list of known NerdFonts or fetch it dynamically
 	// For now, we'll just assume if it looks like a NerdFont name, it might be one
 	return []Font{{
 		Name:   cleanName,
 		Source: s.Name(),
-		Meta:   map[string]string{"pending": "true"},
+		Meta:   meta,
 	}}, nil
 }
 
 func (s *NerdFontsSource) Download(ctx context.Context, font Font) (io.ReadCloser, error) {
-	version, err := s.getLatestVersion(ctx)
+	downloadURL, err := s.DownloadURL(ctx, font)
 	if err != nil {
-		return nil, fmt.Errorf("getting latest version: %w", err)
+		return nil, err
+	}
+	return openURL(ctx, s.client, downloadURL)
+}
+
+// DownloadURL returns the release asset of font, from the latest release
+// unless the font records its version
+func (s *NerdFontsSource) DownloadURL(ctx context.Context, font Font) (string, error) {
+	version := font.Meta["version"]
+	if version == "" {
+		var err error
+		if version, err = s.getLatestVersion(ctx); err != nil {
+			return "", fmt.Errorf("getting latest version: %w", err)
+		}
 	}
 
-	downloadURL := fmt.Sprintf(
+	return fmt.Sprintf(
 		"https://github.com/ryanoasis/nerd-fonts/releases/download/%s/%s.zip",
 		version,
 		font.Name,
-	)
+	), nil
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating download request: %w", err)
+// AtVersion pins font to a release tag such as v3.2.1, which Download then
+// fetches instead of the latest release
+func (s *NerdFontsSource) AtVersion(_ context.Context, font Font, version string) (Font, error) {
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
 	}
+	font.Meta = withMeta(font.Meta, "version", version)
+	return font, nil
+}
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("downloading font: %w", err)
-	}
+// powerlineGlyphs are Powerline symbols every Nerd Font is patched with:
+// the branch, the padlock and the solid and thin right arrows
+var powerlineGlyphs = []rune{0xE0A0, 0xE0A2, 0xE0B0, 0xE0B1}
 
-	if resp.StatusCode != http.StatusOK {
-		resp.Body.Close()
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
+// ExpectedGlyphs returns the Powerline symbols, which are missing from
+// unpatched fonts
+func (s *NerdFontsSource) ExpectedGlyphs(Font) []rune {
+	return powerlineGlyphs
+}
+
+// Describe reports the source and the cache state of the latest release
+func (s *NerdFontsSource) Describe() SourceInfo {
+	return describeSource("nerdfonts", s.cache, nerdFontsLatestReleaseURL)
+}
 
-	return resp.Body, nil
+// CheckHealth probes the GitHub releases API used to resolve the latest version
+func (s *NerdFontsSource) CheckHealth(ctx context.Context) SourceHealth {
+	return checkEndpoint(ctx, s.client, nerdFontsLatestReleaseURL)
 }
@@ -0,0 +1,294 @@
+package fm
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NetworkError wraps a failed request with what the client saw of the
+// network, so failures on restricted networks can be told apart: a name that
+// doesn't resolve, an IPv6 address that can't be reached, a proxy in the way
+// or a TLS interception proxy.
+type NetworkError struct {
+	Host     string        // Host the request was for
+	Proxy    string        // Proxy the request went through; empty for a direct connection
+	Addrs    []string      // Addresses the host resolved to
+	Connects []ConnectInfo // Connection attempts, in order
+	TLS      *TLSInfo      // Negotiated TLS parameters, when the handshake completed
+	TLSErr   error         // Why the TLS handshake failed
+	Err      error
+}
+
+// ConnectInfo is a single attempt to connect to a resolved address
+type ConnectInfo struct {
+	Addr string
+	Err  error
+}
+
+// TLSInfo describes an established TLS connection
+type TLSInfo struct {
+	Version     string
+	CipherSuite string
+	ServerName  string
+	Subject     string    // Subject of the server certificate
+	Issuer      string    // Issuer of the server certificate
+	NotAfter    time.Time // Expiry of the server certificate
+}
+
+func (e *NetworkError) Error() string {
+	var notes []string
+	switch {
+	case e.Proxy != "":
+		notes = append(notes, "via proxy "+e.Proxy)
+	case len(e.Addrs) > 0:
+		notes = append(notes, fmt.Sprintf("%s resolved to %s", e.Host, strings.Join(e.Addrs, ", ")), "no proxy")
+	default:
+		notes = append(notes, e.Host+" did not resolve", "no proxy")
+	}
+	return fmt.Sprintf("%v (%s)", e.Err, strings.Join(notes, "; "))
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// Details describes every connection attempt and the TLS handshake, one item
+// per line
+func (e *NetworkError) Details() []string {
+	var lines []string
+	for _, c := range e.Connects {
+		if c.Err != nil {
+			lines = append(lines, fmt.Sprintf("connect %s (%s): %v", c.Addr, addrFamily(c.Addr), c.Err))
+		} else {
+			lines = append(lines, fmt.Sprintf("connect %s (%s): ok", c.Addr, addrFamily(c.Addr)))
+		}
+	}
+	switch {
+	case e.TLSErr != nil:
+		lines = append(lines, fmt.Sprintf("tls handshake: %v", e.TLSErr))
+	case e.TLS != nil:
+		lines = append(lines, "tls: "+e.TLS.String())
+	}
+	return lines
+}
+
+func (t *TLSInfo) String() string {
+	return fmt.Sprintf("%s %s, server %s, certificate %q issued by %q, expires %s",
+		t.Version, t.CipherSuite, t.ServerName, t.Subject, t.Issuer, t.NotAfter.Format(time.DateOnly))
+}
+
+func newTLSInfo(state tls.ConnectionState) *TLSInfo {
+	info := &TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  state.ServerName,
+	}
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		info.Subject = cert.Subject.String()
+		info.Issuer = cert.Issuer.String()
+		info.NotAfter = cert.NotAfter
+	}
+	return info
+}
+
+// addrFamily reports whether a host:port address is IPv4 or IPv6
+func addrFamily(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		return "IPv6"
+	}
+	return "IPv4"
+}
+
+// diagnosticTransport traces each request and turns failures into
+// NetworkErrors
+type diagnosticTransport struct {
+	next  http.RoundTripper
+	proxy func(*http.Request) (*url.URL, error)
+}
+
+func (t *diagnosticTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	netErr := &NetworkError{Host: req.URL.Hostname()}
+
+	// Trace callbacks can run on other goroutines while the transport races
+	// connections
+	var mu sync.Mutex
+	trace := &httptrace.ClientTrace{
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, addr := range info.Addrs {
+				netErr.Addrs = append(netErr.Addrs, addr.String())
+			}
+		},
+		ConnectDone: func(network, addr string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			netErr.Connects = append(netErr.Connects, ConnectInfo{Addr: addr, Err: err})
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				netErr.TLSErr = err
+				return
+			}
+			netErr.TLS = newTLSInfo(state)
+		},
+	}
+
+	resp, err := t.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err == nil || req.Context().Err() != nil {
+		return resp, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if t.proxy != nil {
+		if proxyURL, _ := t.proxy(req); proxyURL != nil {
+			netErr.Proxy = proxyURL.Redacted()
+		}
+	}
+	// Literal addresses skip resolution
+	if len(netErr.Addrs) == 0 && net.ParseIP(netErr.Host) != nil {
+		netErr.Addrs = []string{netErr.Host}
+	}
+	netErr.Err = err
+	return resp, netErr
+}
+
+// EndpointDiagnosis is the result of probing the network path to an endpoint
+// step by step
+type EndpointDiagnosis struct {
+	Endpoint   string
+	Host       string
+	Proxy      string        // Proxy requests would use; empty for a direct connection
+	ResolveErr error         // Why the host didn't resolve
+	Connects   []ConnectInfo // One attempt per resolved address
+	TLS        *TLSInfo      // Negotiated TLS parameters for https endpoints
+	TLSErr     error         // Why the TLS handshake failed
+}
+
+// OK reports whether the endpoint is reachable: the host resolved, at least
+// one address accepted a connection and any TLS handshake succeeded
+func (d EndpointDiagnosis) OK() bool {
+	if d.ResolveErr != nil || d.TLSErr != nil {
+		return false
+	}
+	for _, c := range d.Connects {
+		if c.Err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// DiagnoseEndpoint resolves the endpoint's host, tries to connect to every
+// address it resolves to, IPv4 and IPv6 alike, and performs a TLS handshake
+// for https endpoints. When the settings route the endpoint through a proxy,
+// the proxy is the host being checked.
+func DiagnoseEndpoint(ctx context.Context, endpoint string, settings HTTPSettings) EndpointDiagnosis {
+	d := EndpointDiagnosis{Endpoint: endpoint}
+
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		d.ResolveErr = fmt.Errorf("parsing endpoint: %w", err)
+		return d
+	}
+	d.Host = target.Hostname()
+
+	dialURL := target
+	proxyURL, err := settingsProxy(settings, target)
+	if err != nil {
+		d.ResolveErr = err
+		return d
+	}
+	if proxyURL != nil {
+		d.Proxy = proxyURL.Redacted()
+		dialURL = proxyURL
+	}
+
+	port := dialURL.Port()
+	if port == "" {
+		port = "80"
+		if dialURL.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, dialURL.Hostname())
+	if err != nil {
+		d.ResolveErr = err
+		return d
+	}
+
+	timeout := settings.ConnectTimeout
+	if timeout == 0 {
+		timeout = DefaultHTTPSettings.ConnectTimeout
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	for _, ip := range ips {
+		addr := net.JoinHostPort(ip.String(), port)
+		c, err := dialer.DialContext(ctx, "tcp", addr)
+		d.Connects = append(d.Connects, ConnectInfo{Addr: addr, Err: err})
+		if err != nil {
+			continue
+		}
+		if conn == nil {
+			conn = c
+		} else {
+			c.Close()
+		}
+	}
+	if conn == nil {
+		return d
+	}
+	defer conn.Close()
+
+	// Through a proxy the TLS session is tunnelled, which needs a full
+	// request; a direct connection can be checked here
+	if target.Scheme != "https" || proxyURL != nil {
+		return d
+	}
+
+	tlsConfig, err := newTLSConfig(settings)
+	if err != nil {
+		d.TLSErr = err
+		return d
+	}
+	tlsConfig.ServerName = d.Host
+	tlsConn := tls.Client(conn, tlsConfig)
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		d.TLSErr = err
+		return d
+	}
+	d.TLS = newTLSInfo(tlsConn.ConnectionState())
+	return d
+}
+
+// settingsProxy returns the proxy the settings would use for target
+func settingsProxy(settings HTTPSettings, target *url.URL) (*url.URL, error) {
+	if settings.Proxy != "" {
+		proxyURL, err := url.Parse(settings.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parsing proxy URL: %w", err)
+		}
+		return proxyURL, nil
+	}
+	return http.ProxyFromEnvironment(&http.Request{URL: target})
+}
@@ -0,0 +1,80 @@
+package fm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Network diagnostics", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should report the resolved addresses and TLS failure of a request", func() {
+		client, err := fm.NewHTTPClient(fm.HTTPSettings{})
+		Expect(err).NotTo(HaveOccurred())
+
+		// The test server's certificate isn't trusted by a default client
+		_, err = client.Get(strings.Replace(server.URL, "127.0.0.1", "localhost", 1))
+		Expect(err).To(HaveOccurred())
+
+		var netErr *fm.NetworkError
+		Expect(errors.As(err, &netErr)).To(BeTrue())
+		Expect(netErr.Error()).To(ContainSubstring("localhost resolved to"))
+		Expect(netErr.Error()).To(ContainSubstring("no proxy"))
+		Expect(netErr.TLSErr).To(HaveOccurred())
+		Expect(netErr.Details()).To(ContainElement(ContainSubstring("tls handshake")))
+	})
+
+	It("should report failed connections", func() {
+		url := server.URL
+		server.Close()
+
+		client, err := fm.NewHTTPClient(fm.HTTPSettings{})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.Get(url)
+		var netErr *fm.NetworkError
+		Expect(errors.As(err, &netErr)).To(BeTrue())
+		Expect(netErr.Addrs).To(Equal([]string{"127.0.0.1"}))
+		Expect(netErr.Details()).To(ContainElement(MatchRegexp(`connect 127\.0\.0\.1:\d+ \(IPv4\): .*refused`)))
+	})
+
+	It("should diagnose a reachable endpoint step by step", func() {
+		d := fm.DiagnoseEndpoint(context.Background(), server.URL, fm.HTTPSettings{InsecureSkipVerify: true})
+		Expect(d.OK()).To(BeTrue())
+		Expect(d.Proxy).To(BeEmpty())
+		Expect(d.Connects).To(HaveLen(1))
+		Expect(d.TLS).NotTo(BeNil())
+		Expect(d.TLS.Version).To(HavePrefix("TLS"))
+	})
+
+	It("should report an untrusted certificate", func() {
+		d := fm.DiagnoseEndpoint(context.Background(), server.URL, fm.HTTPSettings{})
+		Expect(d.OK()).To(BeFalse())
+		Expect(d.TLSErr).To(HaveOccurred())
+	})
+
+	It("should report the proxy an endpoint is reached through", func() {
+		d := fm.DiagnoseEndpoint(context.Background(), "https://fonts.example.com/api", fm.HTTPSettings{
+			Proxy: "http://user:secret@" + strings.TrimPrefix(server.URL, "https://"),
+		})
+		Expect(d.Proxy).To(ContainSubstring("user:xxxxx@"))
+		Expect(d.OK()).To(BeTrue())
+	})
+})
@@ -0,0 +1,22 @@
+package fm
+
+import "strings"
+
+// noteMetaKey is the Font.Meta key holding the note recorded with an
+// install, such as why the font was installed. The comment of a font spec is
+// read into it, and exported configs write it back as the comment.
+const noteMetaKey = "note"
+
+// Note returns the note recorded with an installed font, if any
+func Note(font Font) string {
+	return font.Meta[noteMetaKey]
+}
+
+// WithNote records a free-text note with the install, shown by fm info and
+// kept as a comment when the font is exported. Line breaks and runs of
+// whitespace are collapsed so the note fits on a config line.
+func WithNote(note string) InstallOption {
+	return func(o *installOptions) {
+		o.note = strings.Join(strings.Fields(note), " ")
+	}
+}
@@ -0,0 +1,555 @@
+package fm
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Limits bounds the font archives accepted by the installer. A zero field
+// disables that limit.
+type Limits struct {
+	MaxDownloadSize     int64 // Maximum size of the downloaded archive in bytes
+	MaxUncompressedSize int64 // Maximum total size of the archive contents in bytes
+	MaxFiles            int   // Maximum number of entries in the archive
+}
+
+// DefaultLimits comfortably fits the largest Nerd Fonts and CJK archives
+var DefaultLimits = Limits{
+	MaxDownloadSize:     1 << 30,
+	MaxUncompressedSize: 4 << 30,
+	MaxFiles:            10000,
+}
+
+// DefaultArchiveDepth is how many levels of archives nested inside the
+// download are unpacked when looking for fonts
+const DefaultArchiveDepth = 3
+
+// ManagerOption configures a DefaultManager
+type ManagerOption func(*DefaultManager)
+
+// WithAuditLog records every install and uninstall in the given audit log
+func WithAuditLog(log *AuditLog) ManagerOption {
+	return func(m *DefaultManager) {
+		m.auditLog = log
+	}
+}
+
+// WithAliases resolves common font names through the given alias index
+// before searching every source
+func WithAliases(index *AliasIndex) ManagerOption {
+	return func(m *DefaultManager) {
+		m.aliases = index
+	}
+}
+
+// WithStore installs fonts into the experimental content-addressed store
+// layout instead of plain directories
+func WithStore(store *Store) ManagerOption {
+	return func(m *DefaultManager) {
+		m.store = store
+	}
+}
+
+// WithRollbackDir keeps the previous install of every font Upgrade replaces
+// in dir, so Rollback can switch back to it. The store layout keeps every
+// generation of a font instead.
+func WithRollbackDir(dir string) ManagerOption {
+	return func(m *DefaultManager) {
+		m.rollbackDir = dir
+	}
+}
+
+// WithHostCredentials authenticates direct URL downloads from host, which
+// may include a port, with creds
+func WithHostCredentials(host string, creds HostCredentials) ManagerOption {
+	return func(m *DefaultManager) {
+		if m.credentials == nil {
+			m.credentials = make(map[string]HostCredentials)
+		}
+		m.credentials[strings.ToLower(host)] = creds
+	}
+}
+
+// WithCacheSettings customizes how the font cache is refreshed after
+// installs, e.g. to run "fc-cache -f -v" instead of the platform default
+func WithCacheSettings(settings CacheSettings) ManagerOption {
+	return func(m *DefaultManager) {
+		m.cacheSettings = &settings
+	}
+}
+
+// WithAllowedLicenses only lets fonts whose detected license is one of the
+// given SPDX identifiers (e.g. OFL-1.1, Apache-2.0) be installed
+func WithAllowedLicenses(licenses ...string) ManagerOption {
+	return func(m *DefaultManager) {
+		m.allowedLicenses = licenses
+	}
+}
+
+// WithPlatform replaces the platform-specific manager, e.g. with one from
+// NewPlatformForUser to install fonts for another user
+func WithPlatform(p Platform) ManagerOption {
+	return func(m *DefaultManager) {
+		m.platform = p
+	}
+}
+
+// WithSourcePriority searches the named sources before the others, in the
+// given order. Sources not named keep the order they were registered in.
+func WithSourcePriority(names ...string) ManagerOption {
+	return func(m *DefaultManager) {
+		m.sourcePriority = names
+	}
+}
+
+// WithDefaultVariants installs only the given variants of fonts from source
+// unless an install asks for variants itself, e.g. Mono for Nerd Fonts
+func WithDefaultVariants(source string, variants ...string) ManagerOption {
+	return func(m *DefaultManager) {
+		if m.defaultVariants == nil {
+			m.defaultVariants = make(map[string][]string)
+		}
+		m.defaultVariants[source] = variants
+	}
+}
+
+// WithDefaultParallelism installs up to n fonts at once when installing
+// several, unless an install sets its own parallelism. Zero keeps
+// DefaultParallelism.
+func WithDefaultParallelism(n int) ManagerOption {
+	return func(m *DefaultManager) {
+		m.parallelism = n
+	}
+}
+
+// WithDefaultFormat installs only the TTF or OTF files of faces an archive
+// has in both formats unless an install chooses a format itself
+func WithDefaultFormat(format string) ManagerOption {
+	return func(m *DefaultManager) {
+		m.defaultFormat = strings.ToLower(format)
+	}
+}
+
+// WithFontDir installs fonts into dir instead of the platform's user font
+// directory, which fm then lists and manages in its place. Give it before
+// WithPlatform and the platform replaces it.
+func WithFontDir(dir string) ManagerOption {
+	return func(m *DefaultManager) {
+		m.platform = fontDirPlatform{Platform: m.platform, dir: dir}
+	}
+}
+
+// WithSystemScope installs fonts into the system font directory for every
+// user instead of the user's own, which usually requires root
+func WithSystemScope() ManagerOption {
+	return func(m *DefaultManager) {
+		m.systemScope = true
+	}
+}
+
+// WithDownloadCache keeps downloaded archives of versioned releases in
+// cache, so reinstalling the same release doesn't download it again
+func WithDownloadCache(cache *Cache) ManagerOption {
+	return func(m *DefaultManager) {
+		m.downloads = cache
+	}
+}
+
+// WithScanOptions limits how the system font directory is walked when
+// listing fonts
+func WithScanOptions(scan ScanOptions) ManagerOption {
+	return func(m *DefaultManager) {
+		m.scan = scan
+	}
+}
+
+// WithInstancer replaces the command that limits the axes of variable fonts,
+// DefaultInstancerCommand
+func WithInstancer(command ...string) ManagerOption {
+	return func(m *DefaultManager) {
+		m.instancer = command
+	}
+}
+
+// WithLowMemory installs fonts one at a time, streams downloads through a
+// temporary file while hashing them and copies files with small buffers, so
+// large archives fit on devices with little memory
+func WithLowMemory() ManagerOption {
+	return func(m *DefaultManager) {
+		m.lowMemory = true
+	}
+}
+
+// WithRehash hashes cached downloads and installed files every time they are
+// reused or verified, instead of trusting the digests recorded with them
+// while their size and modification time are unchanged
+func WithRehash() ManagerOption {
+	return func(m *DefaultManager) {
+		m.rehash = true
+	}
+}
+
+// WithHTTPClient downloads fonts given by a direct URL with client instead of
+// the default one. Sources are given theirs WithClient.
+func WithHTTPClient(client *http.Client) ManagerOption {
+	return func(m *DefaultManager) {
+		m.client = client
+	}
+}
+
+// WithLogger logs to l instead of slog.Default: at debug level where fonts
+// are downloaded from and which files are extracted from their archives, as
+// warnings the problems fm works around, such as a failed font cache refresh
+func WithLogger(l *slog.Logger) ManagerOption {
+	return func(m *DefaultManager) {
+		m.logger = l
+	}
+}
+
+// WithEquivalents replaces the shipped mapping of patched fonts to the
+// upstream fonts they were built from
+func WithEquivalents(equivalents ...Equivalent) ManagerOption {
+	return func(m *DefaultManager) {
+		m.equivalents = equivalents
+	}
+}
+
+// WithUserAliases lets names stand for font specs wherever one is accepted,
+// including config files, e.g. "code-font" for "FiraCode@nerdfonts" so a
+// team can standardize on names that say what a font is for. User aliases
+// are expanded before the alias index is consulted.
+func WithUserAliases(aliases map[string]string) ManagerOption {
+	return func(m *DefaultManager) {
+		m.userAliases = make(map[string]string, len(aliases))
+		for name, spec := range aliases {
+			m.userAliases[aliasKey(name)] = spec
+		}
+	}
+}
+
+// WithBackgroundJobs caps how many background jobs, such as the refreshes of
+// WatchFontDirs and jobs given to RunBackground, run at once. They only start
+// while no install is running or waiting. Zero removes the cap.
+func WithBackgroundJobs(n int) ManagerOption {
+	return func(m *DefaultManager) {
+		m.jobLimits.Background = n
+	}
+}
+
+// lowMemoryBufferSize is the copy buffer used in low-memory mode, instead of
+// the 32 KiB io.Copy allocates
+const lowMemoryBufferSize = 4 << 10
+
+// InstallOption configures a single Install call
+type InstallOption func(*installOptions)
+
+type installOptions struct {
+	region        string
+	limits        Limits
+	archiveDepth  int
+	subsets       []string
+	unicodeRanges string
+	acceptEULA    bool
+	headers       http.Header
+	parallelism   int
+	sha256        string
+	variants      []string
+	format        string
+	verifyRender  bool
+	version       string
+	note          string
+	installAs     string
+	axes          []AxisRange
+
+	// allowedLicenses comes from the manager's license policy
+	allowedLicenses []string
+	overrideLicense bool
+
+	// lowMemory comes from the manager and trades speed for memory
+	lowMemory bool
+
+	// force replaces a font that is already installed
+	force bool
+
+	// upgrade records the install as an upgrade of the copy it replaces
+	upgrade bool
+
+	// dryRun resolves the font and where it would be downloaded from, but
+	// stops before downloading
+	dryRun bool
+
+	// fetch is handed the download of the resolved font in place of
+	// installing it, to bundle it
+	fetch func(font Font, data io.Reader) error
+
+	// progress reports the phases of the install when set, for the spec
+	// being installed
+	progress func(Progress)
+
+	// bundle installs fonts from the downloads of a bundle instead of their
+	// sources
+	bundle *bundle
+
+	// confirmDownload is asked before downloads of at least
+	// confirmDownloadSize bytes go ahead
+	confirmDownload     func(font Font, size int64) bool
+	confirmDownloadSize int64
+}
+
+// WithConfirmDownload asks confirm before downloading an archive of at least
+// size bytes, once the server has announced its size. Declined downloads
+// fail with ErrDownloadDeclined; cached downloads are never asked about.
+// With parallel installs confirm may be called from several goroutines.
+func WithConfirmDownload(size int64, confirm func(font Font, size int64) bool) InstallOption {
+	return func(o *installOptions) {
+		o.confirmDownload = confirm
+		o.confirmDownloadSize = size
+	}
+}
+
+// WithInstallAs installs the font under name instead of its own, so builds
+// of one family from different sources can be installed side by side
+func WithInstallAs(name string) InstallOption {
+	return func(o *installOptions) {
+		o.installAs = name
+	}
+}
+
+// WithForce reinstalls fonts that are already installed, replacing their
+// directory, e.g. after a corrupted or partial install. The installed copy
+// is kept if the reinstall fails.
+func WithForce() InstallOption {
+	return func(o *installOptions) {
+		o.force = true
+	}
+}
+
+// WithAxes installs the variable build of a font limited to the given axis
+// ranges, e.g. weights 400 to 700, from sources that publish variable fonts.
+// Axes that aren't listed keep their full range.
+func WithAxes(axes ...AxisRange) InstallOption {
+	return func(o *installOptions) {
+		o.axes = axes
+	}
+}
+
+// WithSHA256 makes the install fail unless the downloaded archive has the
+// given SHA-256 checksum, in hex
+func WithSHA256(sum string) InstallOption {
+	return func(o *installOptions) {
+		o.sha256 = sum
+	}
+}
+
+// WithRegion selects the regional subset to install for sources that
+// publish region-specific builds (e.g. SC, TC, JP or KR for CJK families)
+func WithRegion(region string) InstallOption {
+	return func(o *installOptions) {
+		o.region = region
+	}
+}
+
+// WithLimits overrides the archive size and file count limits
+func WithLimits(limits Limits) InstallOption {
+	return func(o *installOptions) {
+		o.limits = limits
+	}
+}
+
+// WithArchiveDepth sets how many levels of nested archives (e.g. a zip per
+// family inside the download) are unpacked. Zero only reads the download itself.
+func WithArchiveDepth(depth int) InstallOption {
+	return func(o *installOptions) {
+		o.archiveDepth = depth
+	}
+}
+
+// WithSubsets downloads only the named script subsets (e.g. latin,
+// latin-ext) from sources that publish subsetted web fonts
+func WithSubsets(subsets ...string) InstallOption {
+	return func(o *installOptions) {
+		o.subsets = subsets
+	}
+}
+
+// WithUnicodeRanges downloads only the subsets covering the given CSS
+// unicode-range list, e.g. "U+0000-00FF, U+0131"
+func WithUnicodeRanges(ranges string) InstallOption {
+	return func(o *installOptions) {
+		o.unicodeRanges = ranges
+	}
+}
+
+// WithAcceptEULA accepts the license agreement of sources that require one
+// before their fonts can be installed
+func WithAcceptEULA() InstallOption {
+	return func(o *installOptions) {
+		o.acceptEULA = true
+	}
+}
+
+// WithHeaders adds headers to direct URL downloads, overriding any set by
+// the host's credentials
+func WithHeaders(header http.Header) InstallOption {
+	return func(o *installOptions) {
+		o.headers = header
+	}
+}
+
+// WithLicenseOverride installs fonts even when their license isn't allowed by
+// the manager's license policy; the exception is recorded in the audit log
+func WithLicenseOverride() InstallOption {
+	return func(o *installOptions) {
+		o.overrideLicense = true
+	}
+}
+
+// DefaultParallelism is how many fonts are downloaded and extracted at once
+// when installing several, unless the manager or the install sets it
+const DefaultParallelism = 4
+
+// WithParallelism installs up to n fonts at once when installing several,
+// e.g. from a config file. Values below 2 install one font at a time.
+func WithParallelism(n int) InstallOption {
+	return func(o *installOptions) {
+		o.parallelism = n
+	}
+}
+
+// WithVerifyRender renders a sample with every installed font file and
+// fails the install, removing the font again, when a file can't be loaded
+// or lacks glyphs its source promises, such as the Nerd Fonts Powerline
+// symbols
+func WithVerifyRender() InstallOption {
+	return func(o *installOptions) {
+		o.verifyRender = true
+	}
+}
+
+// WithVariants extracts only the font files of the given styles, e.g.
+// Regular, Bold or BoldItalic, from archives bundling a whole family. Nerd
+// Fonts archives can also be narrowed to their Mono or Propo builds.
+func WithVariants(variants ...string) InstallOption {
+	return func(o *installOptions) {
+		o.variants = variants
+	}
+}
+
+// WithFormat installs only the files of format, ttf or otf, for faces an
+// archive has in both formats. Faces only available in the other format are
+// still installed.
+func WithFormat(format string) InstallOption {
+	return func(o *installOptions) {
+		o.format = strings.ToLower(format)
+	}
+}
+
+// withSpecOptions returns o with the exact version and per-font options of a
+// spec applied on top, leaving o itself untouched
+func (o *installOptions) withSpecOptions(font *Font) (*installOptions, error) {
+	if font.Version == "" && len(font.Options) == 0 && Note(*font) == "" {
+		return o, nil
+	}
+
+	copied := *o
+	copied.version = font.Version
+	if note := Note(*font); note != "" {
+		copied.note = note
+	}
+	for key, value := range font.Options {
+		switch key {
+		case "region":
+			copied.region = value
+		case "sha256":
+			copied.sha256 = value
+		case "subsets":
+			copied.subsets = strings.Split(value, ",")
+		case "variants":
+			copied.variants = strings.Split(value, ",")
+		case "format":
+			format := strings.ToLower(value)
+			if !slices.Contains(FontFormats, format) {
+				return nil, fmt.Errorf("invalid format %q (expected one of %s)", value, strings.Join(FontFormats, ", "))
+			}
+			copied.format = format
+		case "install_as":
+			copied.installAs = value
+		case "axes":
+			axes, err := ParseAxes(value)
+			if err != nil {
+				return nil, err
+			}
+			copied.axes = axes
+		case "allow_coexist":
+			// Checked for the whole list by checkCoexistence
+		default:
+			return nil, fmt.Errorf("unsupported option %q", key)
+		}
+	}
+	return &copied, nil
+}
+
+// installedName returns the name spec is installed under with o
+func (o *installOptions) installedName(spec *Font) string {
+	if o.installAs != "" {
+		return o.installAs
+	}
+	return spec.Name
+}
+
+func newInstallOptions(opts []InstallOption) *installOptions {
+	o := &installOptions{
+		limits:       DefaultLimits,
+		archiveDepth: DefaultArchiveDepth,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// SourceOption configures a font source
+type SourceOption func(*sourceOptions)
+
+type sourceOptions struct {
+	client *http.Client
+	cache  *Cache
+	logger *slog.Logger
+}
+
+// WithClient sets the HTTP client used by a source, typically one built with
+// NewHTTPClient to give the source its own timeouts, proxy, retries and TLS
+func WithClient(client *http.Client) SourceOption {
+	return func(o *sourceOptions) {
+		o.client = client
+	}
+}
+
+// WithCache lets a source cache its catalog and search responses
+func WithCache(cache *Cache) SourceOption {
+	return func(o *sourceOptions) {
+		o.cache = cache
+	}
+}
+
+// WithSourceLogger logs to l instead of slog.Default, e.g. when a source
+// falls back to a stale cached response because its server can't be reached
+func WithSourceLogger(l *slog.Logger) SourceOption {
+	return func(o *sourceOptions) {
+		o.logger = l
+	}
+}
+
+func newSourceOptions(opts []SourceOption) *sourceOptions {
+	o := &sourceOptions{
+		client: defaultClient,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
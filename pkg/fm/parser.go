@@ -1 +1,208 @@
 package fm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseConfig reads a font config file with one font spec per line, as
+// accepted by ParseFontSpec. Invalid lines are reported in the returned error
+// alongside the specs that did parse.
+func ParseConfig(reader io.Reader) ([]Font, error) {
+	scanner := bufio.NewScanner(reader)
+	var fonts []Font
+	var errs []error
+
+	for line := 1; scanner.Scan(); line++ {
+		font, err := ParseFontSpec(scanner.Text())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		if font == nil {
+			continue // Skip empty lines and comments
+		}
+		fonts = append(fonts, *font)
+	}
+
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("error reading config: %w", err))
+	}
+	return fonts, errors.Join(errs...)
+}
+
+// yamlConfig is a font config in YAML. Each entry of fonts is either a spec
+// string as accepted by ParseFontSpec or a mapping of its parts, with spec
+// options as further keys.
+type yamlConfig struct {
+	Fonts []yamlFont `yaml:"fonts"`
+}
+
+type yamlFont struct {
+	Font
+}
+
+func (f *yamlFont) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		font, err := ParseFontSpec(node.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		if font == nil {
+			return fmt.Errorf("line %d: empty font spec", node.Line)
+		}
+		f.Font = *font
+		return nil
+	}
+
+	var entry struct {
+		Name       string            `yaml:"name"`
+		Source     string            `yaml:"source"`
+		Ref        string            `yaml:"ref"`
+		URL        string            `yaml:"url"`
+		MinVersion string            `yaml:"min_version"`
+		Version    string            `yaml:"version"`
+		Note       string            `yaml:"note"`
+		Options    map[string]string `yaml:",inline"`
+	}
+	if err := node.Decode(&entry); err != nil {
+		return err
+	}
+	if entry.Name == "" && entry.URL == "" {
+		return fmt.Errorf("line %d: font needs a name or url", node.Line)
+	}
+	if entry.MinVersion != "" && entry.Version != "" {
+		return fmt.Errorf("line %d: only one of min_version and version is allowed", node.Line)
+	}
+	for key, version := range map[string]string{"min_version": entry.MinVersion, "version": entry.Version} {
+		if version == "" {
+			continue
+		}
+		if err := specParser.CheckVersion(version); err != nil {
+			return fmt.Errorf("line %d: invalid %s: %w", node.Line, key, err)
+		}
+	}
+	for key, value := range entry.Options {
+		if err := specParser.CheckOption(key, value); err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+	}
+
+	f.Font = Font{
+		Name:       entry.Name,
+		Source:     entry.Source,
+		Ref:        entry.Ref,
+		URL:        entry.URL,
+		MinVersion: entry.MinVersion,
+		Version:    entry.Version,
+	}
+	if len(entry.Options) > 0 {
+		f.Options = entry.Options
+	}
+	if entry.Note != "" {
+		f.Meta = map[string]string{noteMetaKey: entry.Note}
+	}
+	if entry.URL != "" {
+		f.Source = "url"
+		if f.Name == "" {
+			f.Name = getFontNameFromURL(entry.URL)
+		}
+	}
+	return nil
+}
+
+// ParseYAMLConfig reads a font config written in YAML, with a list of fonts
+// given as specs or as mappings of name, source, ref, url, min_version,
+// version, note and spec options:
+//
+//	fonts:
+//	  - JetBrainsMono@nerdfonts >=v3.0.0
+//	  - name: Inter
+//	    source: fontsource
+//	    subsets: latin,latin-ext
+//	    note: body text of the docs site
+func ParseYAMLConfig(reader io.Reader) ([]Font, error) {
+	var config yamlConfig
+	if err := yaml.NewDecoder(reader).Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+
+	fonts := make([]Font, 0, len(config.Fonts))
+	for _, font := range config.Fonts {
+		fonts = append(fonts, font.Font)
+	}
+	return fonts, nil
+}
+
+// ParseConfigFile reads a font config in the format its name suggests: YAML
+// for .yaml and .yml files, one spec per line otherwise
+func ParseConfigFile(path string) ([]Font, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening config file: %w", err)
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseYAMLConfig(file)
+	default:
+		return ParseConfig(file)
+	}
+}
+
+// MergeConfigs layers font configs on top of each other. A font listed again
+// in a later config replaces the earlier entry in place, so the merged list
+// keeps the order fonts were first listed in. A font listed twice in one
+// config from different sources is kept twice, for installs to report.
+func MergeConfigs(configs ...[]Font) []Font {
+	var merged []Font
+	index := make(map[string]int)
+	origin := make(map[string]int) // Config each key was last listed in
+	for c, fonts := range configs {
+		for _, font := range fonts {
+			key := installedKey(font.installName())
+			i, ok := index[key]
+			switch {
+			case ok && origin[key] == c && merged[i].Source != font.Source:
+				merged = append(merged, font)
+			case ok:
+				merged[i] = font
+				origin[key] = c
+			default:
+				index[key] = len(merged)
+				origin[key] = c
+				merged = append(merged, font)
+			}
+		}
+	}
+	return merged
+}
+
+// WriteConfig writes fonts as a config file ParseConfig reads, one spec per
+// line after the given comment lines
+func WriteConfig(w io.Writer, fonts []Font, comments ...string) error {
+	for _, comment := range comments {
+		if _, err := fmt.Fprintf(w, "# %s\n", comment); err != nil {
+			return err
+		}
+	}
+	for _, font := range fonts {
+		if _, err := fmt.Fprintln(w, FormatFontSpec(font)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FormatFontSpec formats a font back into the config line ParseFontSpec reads
+func FormatFontSpec(font Font) string {
+	return specFromFont(font).String()
+}
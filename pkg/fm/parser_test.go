@@ -0,0 +1,106 @@
+package fm_test
+
+import (
+	"strings"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Font configs", func() {
+	parse := func(config string) []fm.Font {
+		fonts, err := fm.ParseConfig(strings.NewReader(config))
+		Expect(err).NotTo(HaveOccurred())
+		return fonts
+	}
+
+	It("should skip comments and blank lines", func() {
+		fonts := parse("# base fonts\n\nInter\nFiraCode@nerdfonts\n")
+		Expect(fonts).To(HaveLen(2))
+		Expect(fonts[0].Name).To(Equal("Inter"))
+		Expect(fonts[1].Source).To(Equal("nerdfonts"))
+	})
+
+	It("should return the valid lines alongside errors", func() {
+		fonts, err := fm.ParseConfig(strings.NewReader("Inter\nFiraCode >=latest\n"))
+		Expect(err).To(MatchError(ContainSubstring("invalid version constraint")))
+		Expect(err).To(MatchError(ContainSubstring("line 2:")))
+		Expect(fonts).To(HaveLen(1))
+	})
+
+	It("should let later configs override earlier duplicates in place", func() {
+		base := parse("Inter\nJetBrainsMono@nerdfonts >=v3.0.0\nRoboto\n")
+		work := parse("inter@fontsource\nFiraCode\n")
+
+		var specs []string
+		for _, font := range fm.MergeConfigs(base, work) {
+			specs = append(specs, fm.FormatFontSpec(font))
+		}
+		Expect(specs).To(Equal([]string{
+			"inter@fontsource",
+			"JetBrainsMono@nerdfonts >=v3.0.0",
+			"Roboto",
+			"FiraCode",
+		}))
+	})
+
+	It("should format specs that parse back to the same font", func() {
+		for _, line := range []string{
+			"https://example.com/fonts/Acme.zip",
+			"MyFont@ipfs:bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi",
+			"JetBrainsMono@nerdfonts >=v3.0.0",
+			"FiraCode@nerdfonts ==v3.2.1 sha256=abc variants=Regular,Bold",
+		} {
+			font, err := fm.ParseFontSpec(line)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fm.FormatFontSpec(*font)).To(Equal(line))
+		}
+	})
+
+	It("should read YAML configs with specs and mappings", func() {
+		fonts, err := fm.ParseYAMLConfig(strings.NewReader(`
+fonts:
+  - JetBrainsMono@nerdfonts >=v3.0.0
+  - name: Inter
+    source: fontsource
+    min_version: v4.0.0
+    note: docs site body text
+  - url: https://fonts.example.com/Acme.zip
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		var specs []string
+		for _, font := range fonts {
+			specs = append(specs, fm.FormatFontSpec(font))
+		}
+		Expect(specs).To(Equal([]string{
+			"JetBrainsMono@nerdfonts >=v3.0.0",
+			"Inter@fontsource >=v4.0.0  # docs site body text",
+			"https://fonts.example.com/Acme.zip",
+		}))
+		Expect(fonts[2].Name).To(Equal("Acme"))
+	})
+
+	It("should report invalid YAML entries with their line", func() {
+		_, err := fm.ParseYAMLConfig(strings.NewReader("fonts:\n  - Inter\n  - source: fontsource\n"))
+		Expect(err).To(MatchError(ContainSubstring("line 3: font needs a name or url")))
+
+		_, err = fm.ParseYAMLConfig(strings.NewReader("fonts:\n  - name: Inter\n    weight: 700\n"))
+		Expect(err).To(MatchError(ContainSubstring(`line 2: unknown option "weight"`)))
+	})
+
+	It("should accept the same versions and options in YAML mappings as in specs", func() {
+		fonts, err := fm.ParseYAMLConfig(strings.NewReader(`
+fonts:
+  - FiraCode@nerdfonts ==v3.2.1 variants=Regular,Bold
+  - name: FiraCode
+    source: nerdfonts
+    version: v3.2.1
+    variants: Regular,Bold
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fonts).To(HaveLen(2))
+		Expect(fonts[1]).To(Equal(fonts[0]))
+	})
+})
@@ -0,0 +1,58 @@
+package fm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// pinnedMetaKey is the Font.Meta key marking a font as pinned, which keeps
+// Upgrade and Sync from reinstalling or removing it
+const pinnedMetaKey = "pinned"
+
+// IsPinned reports whether an installed font is pinned
+func IsPinned(font Font) bool {
+	return font.Meta[pinnedMetaKey] == "true"
+}
+
+// Pin pins or unpins the installed font name by recording it in the font's
+// metadata. Only fonts fm installed can be pinned.
+func (m *DefaultManager) Pin(ctx context.Context, name string, pinned bool) error {
+	font, err := m.findInstalled(ctx, name)
+	if err != nil {
+		return err
+	}
+	if font == nil {
+		return fmt.Errorf("font %q is %w", name, ErrNotInstalled)
+	}
+	if !isManaged(font) {
+		return fmt.Errorf("font %q was not installed by fm", name)
+	}
+
+	path := filepath.Join(font.Meta["directory"], ".metadata")
+	meta := make(map[string]string)
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return fmt.Errorf("parsing metadata of %s: %w", name, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("reading metadata of %s: %w", name, err)
+	}
+
+	if pinned {
+		meta[pinnedMetaKey] = "true"
+	} else {
+		delete(meta, pinnedMetaKey)
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("marshaling metadata: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("writing metadata of %s: %w", name, err)
+	}
+	return nil
+}
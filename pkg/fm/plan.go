@@ -0,0 +1,114 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+)
+
+// PlannedInstall is what installing a font would do, as worked out without
+// downloading or writing anything
+type PlannedInstall struct {
+	Font      Font   // The font as listed
+	Source    string // Source the font would be installed from
+	Version   string // Version that would be installed, if the source reports one
+	URL       string // Where the archive would be downloaded from, if the source can tell
+	Installed bool   // Already installed as asked, so nothing would be done
+	Reinstall string // Why the installed copy would be replaced, e.g. "v3.0.0 does not satisfy >=v3.1.0"
+	Err       error  // Why the install would fail
+}
+
+// PlanInstall resolves fonts the way InstallFonts installs them, finding the
+// source, version and download URL of each, without downloading or writing
+// anything. Failures are reported per font; the returned error is for the
+// list as a whole, such as a family requested from two sources.
+func (m *DefaultManager) PlanInstall(ctx context.Context, fonts []Font, opts ...InstallOption) ([]PlannedInstall, error) {
+	fonts, err := m.ExpandAliases(fonts)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCoexistence(fonts); err != nil {
+		return nil, err
+	}
+
+	o := m.newInstallOptions(opts)
+	plans := make([]PlannedInstall, len(fonts))
+	for i := range fonts {
+		plans[i] = m.planInstall(ctx, &fonts[i], o)
+	}
+	return plans, nil
+}
+
+// planInstall works out what installSpec would do for spec
+func (m *DefaultManager) planInstall(ctx context.Context, spec *Font, o *installOptions) PlannedInstall {
+	plan := PlannedInstall{Font: *spec}
+	specOpts, err := o.withSpecOptions(spec)
+	if err != nil {
+		plan.Err = err
+		return plan
+	}
+
+	installed, err := m.findInstalled(ctx, specOpts.installedName(spec))
+	if err != nil {
+		plan.Err = err
+		return plan
+	}
+	if installed != nil && o.force {
+		plan.Reinstall = "forced"
+	} else if installed != nil {
+		if spec.MinVersion == "" && spec.Version == "" {
+			plan.Installed = true
+			return plan
+		}
+		ok, version := m.satisfiesVersion(installed, spec)
+		if ok {
+			plan.Installed = true
+			return plan
+		}
+		plan.Reinstall = fmt.Sprintf("%s does not satisfy %s", orUnknown(version), versionConstraint(spec))
+	}
+
+	resolved := m.resolveInstall(ctx, spec, o)
+	resolved.Reinstall = plan.Reinstall
+	return resolved
+}
+
+// resolveInstall finds the source, version and download URL spec would be
+// installed from, stopping before the download
+func (m *DefaultManager) resolveInstall(ctx context.Context, spec *Font, o *installOptions) PlannedInstall {
+	plan := PlannedInstall{Font: *spec}
+	dryRun, err := o.withSpecOptions(spec)
+	if err != nil {
+		plan.Err = err
+		return plan
+	}
+	dryRun.dryRun = true
+
+	result, err := m.install(ctx, spec, dryRun)
+	if err != nil {
+		plan.Err = err
+		return plan
+	}
+	plan.Source = result.font.Source
+	plan.Version = result.font.Meta["version"]
+	plan.URL = result.url
+
+	// Like ensureVersion, the source may not have a new enough release yet
+	if (spec.MinVersion != "" || spec.Version != "") && plan.Version != "" {
+		if ok, _ := m.satisfiesVersion(&result.font, spec); !ok {
+			plan.Err = fmt.Errorf("available version %s does not satisfy %s", plan.Version, versionConstraint(spec))
+		}
+	}
+	return plan
+}
+
+// downloadURL returns where Download would fetch font from, if the source
+// can tell
+func downloadURL(ctx context.Context, source Source, font Font) (string, error) {
+	if font.URL != "" {
+		return font.URL, nil
+	}
+	if located, ok := source.(LocatedSource); ok {
+		return located.DownloadURL(ctx, font)
+	}
+	return "", nil
+}
@@ -0,0 +1,92 @@
+package fm
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/logandonley/font-manager/internal/platform"
+)
+
+// The platform layer lives in an internal package. These aliases let code
+// outside the module name its types, so custom platforms and test doubles
+// can be passed to NewManagerWithPlatform and WithPlatform.
+type (
+	// Platform locates the font directories and refreshes the font cache
+	Platform = platform.Manager
+
+	// FontPaths are the system, user and legacy font directories
+	FontPaths = platform.FontPaths
+
+	// CacheSettings customizes how the font cache is refreshed
+	CacheSettings = platform.CacheSettings
+
+	// CacheConfigurer is implemented by platforms whose font cache refresh
+	// can be customized with WithCacheSettings
+	CacheConfigurer = platform.CacheConfigurer
+
+	// LogConfigurer is implemented by platforms that log, which are given
+	// the logger of WithLogger
+	LogConfigurer = platform.LogConfigurer
+
+	// UserPlatform is implemented by platforms acting on behalf of a user
+	// other than the one running fm; installed files are handed to that user
+	UserPlatform = platform.UserManager
+)
+
+// FontDirEnv names the environment variable that replaces the user font
+// directory of the user running fm
+const FontDirEnv = platform.FontDirEnv
+
+// ErrUnsupportedPlatform is returned by the platforms fm has no font
+// handling for
+var ErrUnsupportedPlatform = platform.ErrUnsupported
+
+// NewPlatform returns the platform fm was built for
+func NewPlatform() Platform {
+	return platform.New()
+}
+
+// NewPlatformForUser returns the platform acting on behalf of the named
+// user, which requires root
+func NewPlatformForUser(username string) (Platform, error) {
+	return platform.NewForUser(username)
+}
+
+// fontDirPlatform replaces the user font directory of the platform it wraps,
+// passing cache settings and ownership changes through
+type fontDirPlatform struct {
+	Platform
+	dir string
+}
+
+func (p fontDirPlatform) GetFontPaths() (FontPaths, error) {
+	paths, err := p.Platform.GetFontPaths()
+	if err != nil {
+		return paths, err
+	}
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return paths, fmt.Errorf("creating font directory: %w", err)
+	}
+	paths.UserDir = p.dir
+	return paths, nil
+}
+
+func (p fontDirPlatform) SetCacheSettings(settings CacheSettings) {
+	if configurer, ok := p.Platform.(CacheConfigurer); ok {
+		configurer.SetCacheSettings(settings)
+	}
+}
+
+func (p fontDirPlatform) SetLogger(logger *slog.Logger) {
+	if configurer, ok := p.Platform.(LogConfigurer); ok {
+		configurer.SetLogger(logger)
+	}
+}
+
+func (p fontDirPlatform) ChownToUser(path string) error {
+	if um, ok := p.Platform.(UserPlatform); ok {
+		return um.ChownToUser(path)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Preview styles
+const (
+	PreviewBraille = "braille" // 2x4 pixels per character cell
+	PreviewBlock   = "block"   // 1x2 pixels per cell using half blocks
+)
+
+// DefaultPreviewSize is the pixel size samples are rendered at by default
+const DefaultPreviewSize = 24
+
+// PreviewOptions controls how a font sample is rendered for the terminal
+type PreviewOptions struct {
+	Size  float64 // Font size in pixels; defaults to DefaultPreviewSize
+	Style string  // PreviewBraille (the default) or PreviewBlock
+	Width int     // Maximum columns of output; 0 means unlimited
+	File  string  // Font file to use within the font's directory; defaults to the regular style
+}
+
+// Preview renders text with the installed font name as Unicode braille or
+// block art, one string per terminal line
+func (m *DefaultManager) Preview(ctx context.Context, name, text string, opts PreviewOptions) ([]string, error) {
+	installed, err := m.findInstalled(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if installed == nil {
+		return nil, fmt.Errorf("font %q is %w", name, ErrNotInstalled)
+	}
+
+	path, err := m.previewFile(ctx, *installed, opts.File)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading font file: %w", err)
+	}
+	return RenderPreview(data, text, opts)
+}
+
+// PrimaryFile returns the file standing for an installed font, the one
+// Preview renders by default: its regular style when it has several
+func (m *DefaultManager) PrimaryFile(ctx context.Context, name string) (string, error) {
+	installed, err := m.findInstalled(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if installed == nil {
+		return "", fmt.Errorf("font %q is %w", name, ErrNotInstalled)
+	}
+	return m.previewFile(ctx, *installed, "")
+}
+
+// previewFile picks the file of an installed font to render: the named one,
+// or the regular style when the font has several
+func (m *DefaultManager) previewFile(ctx context.Context, installed Font, name string) (string, error) {
+	dir := installed.Meta["directory"]
+	files, err := m.Files(ctx, installed, false)
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []string
+	for _, file := range files {
+		if file.Format != "ttf" && file.Format != "otf" {
+			continue
+		}
+		if name != "" && strings.EqualFold(file.Name, name) {
+			return filepath.Join(dir, file.Name), nil
+		}
+		candidates = append(candidates, file.Name)
+	}
+	if name != "" {
+		return "", fmt.Errorf("font %q has no file %q", installed.Name, name)
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("font %q has no font files", installed.Name)
+	}
+
+	sort.Strings(candidates)
+	for _, candidate := range candidates {
+		if strings.Contains(strings.ToLower(candidate), "regular") {
+			return filepath.Join(dir, candidate), nil
+		}
+	}
+	return filepath.Join(dir, candidates[0]), nil
+}
+
+// RenderPreview renders text with the TrueType or OpenType font in data as
+// Unicode braille or block art, one string per terminal line
+func RenderPreview(data []byte, text string, opts PreviewOptions) ([]string, error) {
+	if opts.Size <= 0 {
+		opts.Size = DefaultPreviewSize
+	}
+
+	parsed, err := opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing font: %w", err)
+	}
+	face, err := opentype.NewFace(parsed, &opentype.FaceOptions{
+		Size:    opts.Size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading font: %w", err)
+	}
+	defer face.Close()
+
+	metrics := face.Metrics()
+	width := font.MeasureString(face, text).Ceil()
+	height := (metrics.Ascent + metrics.Descent).Ceil()
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("nothing to render")
+	}
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	drawer := &font.Drawer{
+		Dst:  img,
+		Src:  image.White,
+		Face: face,
+		Dot:  fixed.Point26_6{Y: metrics.Ascent},
+	}
+	drawer.DrawString(text)
+
+	switch opts.Style {
+	case "", PreviewBraille:
+		return renderCells(img, 2, 4, opts.Width, brailleCell), nil
+	case PreviewBlock:
+		return renderCells(img, 1, 2, opts.Width, blockCell), nil
+	default:
+		return nil, fmt.Errorf("unknown preview style %q (expected %s or %s)", opts.Style, PreviewBraille, PreviewBlock)
+	}
+}
+
+// renderCells turns the image into lines of characters covering cellWidth by
+// cellHeight pixels each. cell maps the lit pixels of a cell, indexed by
+// column then row, to its character.
+func renderCells(img *image.Gray, cellWidth, cellHeight, maxColumns int, cell func(lit func(x, y int) bool) rune) []string {
+	bounds := img.Bounds()
+	columns := (bounds.Dx() + cellWidth - 1) / cellWidth
+	if maxColumns > 0 && columns > maxColumns {
+		columns = maxColumns
+	}
+
+	var lines []string
+	for top := 0; top < bounds.Dy(); top += cellHeight {
+		var line strings.Builder
+		for col := 0; col < columns; col++ {
+			left := col * cellWidth
+			line.WriteRune(cell(func(x, y int) bool {
+				px, py := left+x, top+y
+				return px < bounds.Dx() && py < bounds.Dy() && img.GrayAt(px, py).Y >= 0x80
+			}))
+		}
+		lines = append(lines, strings.TrimRight(line.String(), " ⠀"))
+	}
+
+	// Drop blank rows above and below the glyphs
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// brailleDots maps pixel positions within a 2x4 cell to braille dot bits
+var brailleDots = [2][4]rune{
+	{0x01, 0x02, 0x04, 0x40},
+	{0x08, 0x10, 0x20, 0x80},
+}
+
+func brailleCell(lit func(x, y int) bool) rune {
+	r := rune(0x2800)
+	for x := 0; x < 2; x++ {
+		for y := 0; y < 4; y++ {
+			if lit(x, y) {
+				r |= brailleDots[x][y]
+			}
+		}
+	}
+	return r
+}
+
+func blockCell(lit func(x, y int) bool) rune {
+	switch top, bottom := lit(0, 0), lit(0, 1); {
+	case top && bottom:
+		return '█'
+	case top:
+		return '▀'
+	case bottom:
+		return '▄'
+	default:
+		return ' '
+	}
+}
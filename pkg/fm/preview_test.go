@@ -0,0 +1,116 @@
+package fm_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+var _ = Describe("Previewing fonts", func() {
+	var (
+		tempDir string
+		manager *fm.DefaultManager
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "font-preview-*")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(tempDir, "user"), 0755)).To(Succeed())
+
+		// A family with a real regular and bold file
+		buf := new(bytes.Buffer)
+		zipWriter := zip.NewWriter(buf)
+		for name, data := range map[string][]byte{
+			"GoMono-Bold.ttf":    gobold.TTF,
+			"GoMono-Regular.ttf": gomono.TTF,
+		} {
+			f, err := zipWriter.Create(name)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.Write(data)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(zipWriter.Close()).To(Succeed())
+
+		source := newMockSource()
+		source.fonts["GoMono"] = buf.Bytes()
+		manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir})
+		Expect(manager.RegisterSource(source)).To(Succeed())
+
+		ctx = context.Background()
+		Expect(manager.Install(ctx, "GoMono@testsource")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	It("should render braille art with the regular style", func() {
+		lines, err := manager.Preview(ctx, "GoMono", "Hi", fm.PreviewOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lines).NotTo(BeEmpty())
+		for _, line := range lines {
+			for _, r := range line {
+				Expect(r).To(BeNumerically(">=", 0x2800))
+				Expect(r).To(BeNumerically("<=", 0x28FF))
+			}
+		}
+
+		regular, err := fm.RenderPreview(gomono.TTF, "Hi", fm.PreviewOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lines).To(Equal(regular))
+	})
+
+	It("should render another file of the font when asked", func() {
+		lines, err := manager.Preview(ctx, "GoMono", "Hi", fm.PreviewOptions{File: "GoMono-Bold.ttf"})
+		Expect(err).NotTo(HaveOccurred())
+
+		bold, err := fm.RenderPreview(gobold.TTF, "Hi", fm.PreviewOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lines).To(Equal(bold))
+	})
+
+	It("should pick the regular style as the primary file", func() {
+		path, err := manager.PrimaryFile(ctx, "GoMono")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(path).To(Equal(filepath.Join(tempDir, "user", "GoMono", "GoMono-Regular.ttf")))
+	})
+
+	It("should render half blocks taller than braille", func() {
+		braille, err := fm.RenderPreview(gomono.TTF, "Hi", fm.PreviewOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		blocks, err := fm.RenderPreview(gomono.TTF, "Hi", fm.PreviewOptions{Style: fm.PreviewBlock})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(len(blocks)).To(BeNumerically(">", len(braille)))
+		Expect(strings.Join(blocks, "")).To(ContainSubstring("█"))
+	})
+
+	It("should limit the width", func() {
+		lines, err := fm.RenderPreview(gomono.TTF, "A long sample line", fm.PreviewOptions{Width: 10})
+		Expect(err).NotTo(HaveOccurred())
+		for _, line := range lines {
+			Expect(len([]rune(line))).To(BeNumerically("<=", 10))
+		}
+	})
+
+	It("should reject fonts that aren't installed", func() {
+		_, err := manager.Preview(ctx, "Missing", "Hi", fm.PreviewOptions{})
+		Expect(err).To(MatchError(ContainSubstring("not installed")))
+	})
+
+	It("should reject data that isn't a font", func() {
+		_, err := fm.RenderPreview([]byte("not a font"), "Hi", fm.PreviewOptions{})
+		Expect(err).To(MatchError(ContainSubstring("parsing font")))
+	})
+})
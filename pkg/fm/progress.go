@@ -0,0 +1,115 @@
+package fm
+
+import "io"
+
+// Phase is a step of installing a font, reported to a ProgressFunc
+type Phase string
+
+const (
+	PhaseResolve  Phase = "resolve"  // Finding the font in its source
+	PhaseDownload Phase = "download" // Downloading its archive, reported as bytes arrive
+	PhaseExtract  Phase = "extract"  // Extracting its files into the font directory
+	PhaseDone     Phase = "done"     // Installed, or failed with Err
+	PhaseCache    Phase = "cache"    // Refreshing the font cache, once for every font installed
+)
+
+// Progress reports a font moving through the phases of its install
+type Progress struct {
+	// Spec is the font as it was asked for, e.g. a line of a config file,
+	// and identifies the install across its phases. Empty for PhaseCache.
+	Spec string
+
+	// Font is the name of the font the spec resolved to, once known
+	Font string
+
+	Phase Phase
+
+	// Bytes is how much of the download has arrived, and Total its size, or
+	// zero when the source doesn't tell. Set for PhaseDownload.
+	Bytes, Total int64
+
+	// Err is why the install failed, for PhaseDone. Fonts that are already
+	// installed report ErrAlreadyInstalled.
+	Err error
+}
+
+// ProgressFunc receives the progress of installs. Installs of several fonts
+// run in parallel, so it must be safe to call from several goroutines.
+type ProgressFunc func(Progress)
+
+// WithProgress reports the progress of every install to fn, so front ends
+// can show it without parsing output
+func WithProgress(fn ProgressFunc) ManagerOption {
+	return func(m *DefaultManager) {
+		m.progress = fn
+	}
+}
+
+// reportProgress reports p for the spec being installed with o, when
+// progress is reported at all
+func (o *installOptions) reportProgress(p Progress) {
+	if o.progress != nil {
+		o.progress(p)
+	}
+}
+
+// withProgress returns options reporting the progress of installing spec
+func (m *DefaultManager) withProgress(o *installOptions, spec *Font) *installOptions {
+	if m.progress == nil {
+		return o
+	}
+	copied := *o
+	name := FormatFontSpec(*spec)
+	copied.progress = func(p Progress) {
+		p.Spec = name
+		m.progress(p)
+	}
+	return &copied
+}
+
+// progressReader reports the bytes read from a download as they arrive
+type progressReader struct {
+	io.ReadCloser
+	font   string
+	read   int64
+	total  int64
+	report func(Progress)
+}
+
+func newProgressReader(data io.ReadCloser, font string, report func(Progress)) *progressReader {
+	r := &progressReader{ReadCloser: data, font: font, report: report}
+	if sized, ok := data.(interface{ Size() int64 }); ok && sized.Size() > 0 {
+		r.total = sized.Size()
+	}
+	r.report(Progress{Font: font, Phase: PhaseDownload, Total: r.total})
+	return r
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.read += int64(n)
+		r.report(Progress{Font: r.font, Phase: PhaseDownload, Bytes: r.read, Total: r.total})
+	}
+	return n, err
+}
+
+// URL, Size and SHA256 pass on what the download says about itself, which
+// installs check and record
+func (r *progressReader) URL() string {
+	if located, ok := r.ReadCloser.(interface{ URL() string }); ok {
+		return located.URL()
+	}
+	return ""
+}
+
+func (r *progressReader) Size() int64 {
+	return r.total
+}
+
+func (r *progressReader) SHA256() string {
+	if digested, ok := r.ReadCloser.(interface{ SHA256() string }); ok {
+		return digested.SHA256()
+	}
+	return ""
+}
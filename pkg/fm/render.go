@@ -0,0 +1,92 @@
+package fm
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/logandonley/font-manager/pkg/fontmeta"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// ErrRenderFailed is returned when an installed font file can't be loaded or
+// rendered, or lacks glyphs its source promises
+var ErrRenderFailed = errors.New("render check failed")
+
+// renderSample is the text every font file renders when checked
+const renderSample = "Hamburgefonstiv 0123456789"
+
+// verifyRender loads every font file in dir, renders a sample with it and
+// checks that it has the expected glyphs
+func verifyRender(dir string, glyphs []rune) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading font directory: %w", err)
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || !isFontFile(entry.Name()) {
+			continue
+		}
+		if err := verifyRenderFile(filepath.Join(dir, entry.Name()), glyphs); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s: %v", ErrRenderFailed, entry.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// verifyRenderFile renders the sample and the expected glyphs with one font
+// file, failing if any glyph is missing or nothing is drawn
+func verifyRenderFile(path string, glyphs []rune) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	parsed, err := fontmeta.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, r := range parsed.Missing(glyphs) {
+		missing = append(missing, fmt.Sprintf("U+%04X", r))
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing glyphs %s", strings.Join(missing, ", "))
+	}
+
+	face, err := opentype.NewFace(parsed.SFNT(), &opentype.FaceOptions{Size: DefaultPreviewSize, DPI: 72})
+	if err != nil {
+		return fmt.Errorf("loading font: %w", err)
+	}
+	defer face.Close()
+
+	text := renderSample + string(glyphs)
+	metrics := face.Metrics()
+	width := font.MeasureString(face, text).Ceil()
+	height := (metrics.Ascent + metrics.Descent).Ceil()
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("sample has no size")
+	}
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	drawer := &font.Drawer{
+		Dst:  img,
+		Src:  image.White,
+		Face: face,
+		Dot:  fixed.Point26_6{Y: metrics.Ascent},
+	}
+	drawer.DrawString(text)
+	for _, pixel := range img.Pix {
+		if pixel != 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("sample renders blank")
+}
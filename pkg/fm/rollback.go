@@ -0,0 +1,183 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DefaultRollbackDir returns the per-user location for the previous installs
+// of upgraded fonts
+func DefaultRollbackDir() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "previous"), nil
+}
+
+// Rollback switches a font back to the install an upgrade replaced and
+// returns the font now installed. Store-managed fonts switch to their previous
+// generation. Other fonts swap places with the previous install kept in the
+// rollback directory, so rolling back again returns to the newer one.
+// Uninstalled fonts get their latest kept install back.
+func (m *DefaultManager) Rollback(ctx context.Context, name string) (*Font, error) {
+	installed, err := m.findInstalled(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.store != nil && (installed == nil || m.store.Manages(installed.Meta["directory"])) {
+		_, err = m.RollbackFont(ctx, name)
+	} else {
+		err = m.swapPrevious(name, installed)
+	}
+	if err != nil {
+		m.recordAudit(AuditEntry{Action: "rollback", Font: name}, err)
+		return nil, err
+	}
+
+	rolledBack, err := m.findInstalled(ctx, name)
+	if err == nil && rolledBack == nil {
+		err = fmt.Errorf("font %q is not installed after rolling back", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.recordAudit(AuditEntry{
+		Action:  "rollback",
+		Font:    rolledBack.Name,
+		Source:  rolledBack.Source,
+		Version: rolledBack.Meta["version"],
+		Path:    rolledBack.Meta["directory"],
+	}, nil)
+	return rolledBack, nil
+}
+
+// keepPrevious moves the directory of an installed font into the rollback
+// directory before it is upgraded, replacing any install kept earlier. The
+// returned function moves it back if the upgrade failed. Without a rollback
+// directory, and in the store layout which keeps every generation anyway,
+// the font is simply removed. Upgrades call hooks of their own, so neither
+// calls uninstall hooks.
+func (m *DefaultManager) keepPrevious(font *Font) (func(), error) {
+	dir, ok := font.Meta["directory"]
+	if !ok {
+		return nil, fmt.Errorf("font directory information missing")
+	}
+	if removable, err := m.removable(font); err != nil {
+		return nil, err
+	} else if !removable {
+		return nil, fmt.Errorf("cannot replace system font %q", font.Name)
+	}
+	if m.rollbackDir == "" || m.store != nil {
+		return func() {}, m.removeFontDir(font, dir)
+	}
+
+	previous := filepath.Join(m.rollbackDir, filepath.Base(dir))
+	if err := os.RemoveAll(previous); err != nil {
+		return nil, fmt.Errorf("removing the install kept before: %w", err)
+	}
+	err := moveDir(dir, previous)
+	m.recordAudit(AuditEntry{
+		Action:  "uninstall",
+		Font:    font.Name,
+		Source:  font.Source,
+		Version: font.Meta["version"],
+		Path:    dir,
+	}, err)
+	if err != nil {
+		return nil, fmt.Errorf("keeping the installed copy: %w", err)
+	}
+
+	return func() {
+		os.RemoveAll(dir)
+		moveDir(previous, dir)
+	}, nil
+}
+
+// swapPrevious swaps an installed font with the install kept in the rollback
+// directory, or brings the kept install back if the font isn't installed
+func (m *DefaultManager) swapPrevious(name string, installed *Font) error {
+	paths, err := m.platform.GetFontPaths()
+	if err != nil {
+		return fmt.Errorf("getting font paths: %w", err)
+	}
+	dir := filepath.Join(m.installDir(paths), fontDirName(name))
+	if installed != nil {
+		if removable, err := m.removable(installed); err != nil {
+			return err
+		} else if !removable {
+			return fmt.Errorf("cannot roll back system font %q", installed.Name)
+		}
+		dir = installed.Meta["directory"]
+	}
+
+	noPrevious := fmt.Errorf("no earlier install of %s is kept", name)
+	if m.rollbackDir == "" {
+		return noPrevious
+	}
+	previous := filepath.Join(m.rollbackDir, filepath.Base(dir))
+	if _, err := os.Stat(previous); errors.Is(err, fs.ErrNotExist) {
+		return noPrevious
+	} else if err != nil {
+		return err
+	}
+
+	swap := previous + ".fm-swap"
+	if installed != nil {
+		if err := os.RemoveAll(swap); err != nil {
+			return fmt.Errorf("removing %s: %w", swap, err)
+		}
+		if err := moveDir(dir, swap); err != nil {
+			return fmt.Errorf("moving the installed copy aside: %w", err)
+		}
+	}
+	if err = moveDir(previous, dir); err != nil {
+		if installed != nil {
+			moveDir(swap, dir)
+		}
+		return fmt.Errorf("restoring the earlier install: %w", err)
+	}
+
+	// The newer install is kept in turn, to roll forward again
+	if installed != nil {
+		if err := moveDir(swap, previous); err != nil {
+			m.log().Warn("failed to keep the newer install", "font", name, "err", err)
+		}
+	}
+	return m.UpdateCache()
+}
+
+// moveDir moves a directory, copying it when it can't be renamed, such as
+// across file systems
+func moveDir(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dest); err == nil {
+		return nil
+	}
+
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dest, rel), 0755)
+		}
+		return copyFile(path, filepath.Join(dest, rel))
+	})
+	if err != nil {
+		os.RemoveAll(dest)
+		return err
+	}
+	return os.RemoveAll(src)
+}
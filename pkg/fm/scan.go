@@ -0,0 +1,82 @@
+package fm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ScanOptions limit how far the system font directory is walked when
+// listing fonts. Systems with thousands of CJK and Noto files otherwise make
+// every listing slow.
+type ScanOptions struct {
+	// MaxDepth is how many directory levels below the font directory are
+	// walked; zero means no limit
+	MaxDepth int
+
+	// Exclude lists globs of paths to skip, relative to the font directory.
+	// Globs without a slash match a file or directory name at any depth,
+	// e.g. "noto*" or "truetype/dejavu".
+	Exclude []string
+
+	// ScanLargeDirs also walks known huge system subtrees such as Noto and
+	// Source Han when only the fonts fm installed are needed
+	ScanLargeDirs bool
+
+	skipLarge bool // Skip largeSystemDirs in this walk
+}
+
+// largeSystemDirs are system font subtrees that hold thousands of files on
+// common distributions and never contain fonts fm installed
+var largeSystemDirs = []string{
+	"noto*",
+	"google-noto*",
+	"adobe-source-han*",
+	"source-han*",
+	"X11",
+}
+
+// skips reports whether the walk of root leaves out path
+func (s ScanOptions) skips(root, path string, info os.FileInfo) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return false
+	}
+
+	if info.IsDir() && s.MaxDepth > 0 && strings.Count(rel, string(filepath.Separator))+1 > s.MaxDepth {
+		return true
+	}
+	if matchesAny(s.Exclude, rel) {
+		return true
+	}
+	// Fonts set aside while being reinstalled, or still being written,
+	// aren't installed fonts
+	if info.IsDir() && (strings.HasSuffix(info.Name(), setAsideSuffix) || strings.HasSuffix(info.Name(), stagingSuffix)) {
+		return true
+	}
+	// Fonts fm installed are kept even if their name looks like a large
+	// system subtree, e.g. a system-wide install of Noto Sans
+	return s.skipLarge && info.IsDir() && matchesAny(largeSystemDirs, rel) && !hasInstallMarker(path)
+}
+
+// matchesAny reports whether rel matches one of the globs, which match the
+// base name at any depth when they have no slash
+func matchesAny(globs []string, rel string) bool {
+	rel = filepath.ToSlash(rel)
+	for _, glob := range globs {
+		subject := rel
+		if !strings.Contains(glob, "/") {
+			subject = rel[strings.LastIndex(rel, "/")+1:]
+		}
+		if ok, _ := filepath.Match(glob, subject); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hasInstallMarker reports whether dir is a font fm installed
+func hasInstallMarker(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".installed"))
+	return err == nil
+}
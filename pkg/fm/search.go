@@ -0,0 +1,168 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// MatchKind tells how a search result's name matches the query
+type MatchKind string
+
+const (
+	MatchExact  MatchKind = "exact"  // Same name, ignoring case, accents and spacing
+	MatchPrefix MatchKind = "prefix" // The name starts with the query
+	MatchFuzzy  MatchKind = "fuzzy"  // Anything else the source considered a match
+)
+
+// SearchResult is a font found by Search, annotated for ranking and display
+type SearchResult struct {
+	// Font is the font as the source described it; its Meta holds the
+	// source's metadata, such as the version or fontsource ID
+	Font Font
+
+	Source string    // Name of the source that produced the result
+	Match  MatchKind // How the name matches the query
+	Score  float64   // Relevance from 0 to 1, higher is better
+}
+
+// unverifiedPenalty scales the score of results a source couldn't confirm,
+// which echo the query and would otherwise always rank as exact matches
+const unverifiedPenalty = 0.9
+
+// Search asks every registered source for fonts matching query concurrently
+// and ranks the results by score, breaking ties by source registration
+// order. Sources that fail are skipped and reported in the returned error
+// alongside whatever the other sources found.
+func (m *DefaultManager) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	found := make([][]Font, len(m.sources))
+	errs := make([]error, len(m.sources))
+
+	var wg sync.WaitGroup
+	for i, source := range m.sources {
+		wg.Add(1)
+		go func(i int, source Source) {
+			defer wg.Done()
+			fonts, err := source.Search(ctx, query)
+			if err != nil {
+				errs[i] = fmt.Errorf("searching in %s: %w", source.Name(), err)
+				return
+			}
+			found[i] = fonts
+		}(i, source)
+	}
+	wg.Wait()
+
+	var results []SearchResult
+	seen := make(map[string]bool)
+	for i, sourceFonts := range found {
+		for _, font := range sourceFonts {
+			if font.Source == "" {
+				font.Source = m.sources[i].Name()
+			}
+			// Sources can list the same family more than once, e.g. under
+			// several IDs
+			key := font.Source + "\x00" + normalizeName(font.Name)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			match, score := matchName(query, font.Name)
+			if !font.Verified() {
+				score *= unverifiedPenalty
+			}
+			results = append(results, SearchResult{
+				Font:   font,
+				Source: font.Source,
+				Match:  match,
+				Score:  score,
+			})
+		}
+	}
+
+	// Results were collected in source order, which the stable sort keeps
+	// for equal scores
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results, errors.Join(errs...)
+}
+
+// matchName scores how well name matches query. Exact matches score 1;
+// names differing only in separators, as "FiraCode" and "Fira Code", score
+// just below; prefixes and substrings score by how much of the name the
+// query covers, and anything else by edit distance.
+func matchName(query, name string) (MatchKind, float64) {
+	q, n := normalizeName(query), normalizeName(name)
+	if q == n {
+		return MatchExact, 1
+	}
+
+	q, n = compactName(q), compactName(n)
+	switch {
+	case q == "" || n == "":
+		return MatchFuzzy, 0
+	case q == n:
+		return MatchExact, 0.95
+	case strings.HasPrefix(n, q):
+		return MatchPrefix, 0.6 + 0.3*coverage(q, n)
+	case strings.Contains(n, q):
+		return MatchFuzzy, 0.4 + 0.3*coverage(q, n)
+	}
+	return MatchFuzzy, 0.4 * similarity(q, n)
+}
+
+// compactName drops everything but letters and digits from a normalized name
+func compactName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, stripAccents(name))
+}
+
+// coverage is the share of n that its part q makes up
+func coverage(q, n string) float64 {
+	return float64(len([]rune(q))) / float64(len([]rune(n)))
+}
+
+// similarity turns the edit distance between a and b into a score from 0 to 1
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	return 1 - float64(editDistance(ra, rb))/float64(longest)
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// Verified reports whether the source confirmed the font exists. Sources
+// without a searchable catalog, like Nerd Fonts, return the requested name
+// unverified and only find out when downloading.
+func (f Font) Verified() bool {
+	return f.Meta["pending"] != "true"
+}
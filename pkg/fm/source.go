@@ -2,7 +2,11 @@ package fm
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -12,7 +16,20 @@ type Font struct {
 	Name   string            // Display name of the font
 	Source string            // Source identifier (e.g., "nerdfonts", "fontsource", "url")
 	URL    string            // Direct URL if provided
+	Ref    string            // Source-specific reference from name@source:ref, e.g. an IPFS CID
 	Meta   map[string]string // Additional metadata
+
+	// MinVersion is the oldest acceptable version when a manifest constrains
+	// the font with ">=", e.g. "JetBrainsMono@nerdfonts >=v3.0.0"
+	MinVersion string
+
+	// Version is the exact version a spec asks for with "==", which the
+	// source must be able to install
+	Version string
+
+	// Options are the spec's per-font install options, e.g. "variants" or
+	// "sha256"
+	Options map[string]string
 }
 
 // Source defines how to interact with a font source
@@ -27,12 +44,227 @@ type Source interface {
 	Download(ctx context.Context, font Font) (io.ReadCloser, error)
 }
 
-// Common HTTP client with reasonable defaults
-var defaultClient = &http.Client{
-	Timeout: 30 * time.Second,
-	Transport: &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 100,
-		IdleConnTimeout:     90 * time.Second,
-	},
+// ReferenceSource is implemented by sources that install from an explicit
+// reference given after the source name, as in "MyFont@ipfs:<cid>"
+type ReferenceSource interface {
+	Source
+
+	// Resolve returns the font identified by ref
+	Resolve(ctx context.Context, name, ref string) (Font, error)
+}
+
+// LocatedSource is implemented by sources that can tell where a font would
+// be downloaded from without downloading it, for dry runs. Fonts with a URL
+// of their own are downloaded from it.
+type LocatedSource interface {
+	Source
+
+	// DownloadURL returns the URL Download fetches font from, or an empty
+	// string when it is assembled from several downloads
+	DownloadURL(ctx context.Context, font Font) (string, error)
+}
+
+// EULASource is implemented by sources whose fonts may only be installed
+// after the user accepts a license agreement
+type EULASource interface {
+	Source
+
+	// EULA names the license agreement covering font and where to read it
+	EULA(font Font) string
+}
+
+// GlyphSource is implemented by sources whose fonts all contain certain
+// glyphs, which installs with WithVerifyRender check for
+type GlyphSource interface {
+	// ExpectedGlyphs returns the code points every file of font must have
+	ExpectedGlyphs(font Font) []rune
+}
+
+// CatalogSource is implemented by sources that can list their fonts from a
+// cached catalog, which shell completion offers without network requests
+type CatalogSource interface {
+	Source
+
+	// CachedNames returns the font names in the cached catalog, or nothing
+	// when the catalog hasn't been fetched yet
+	CachedNames() []string
+}
+
+// DescribedSource is implemented by sources that can tell `fm sources` more
+// than their name
+type DescribedSource interface {
+	Source
+
+	// Describe reports the source's type, authentication and cache state
+	Describe() SourceInfo
+}
+
+// SourceInfo describes a registered source
+type SourceInfo struct {
+	Name     string    // Identifier used in name@source specs
+	Type     string    // Kind of source, e.g. "nerdfonts" or "template"
+	Priority int       // Position in the search order, 1 being searched first
+	Auth     string    // How requests are authenticated, e.g. "anonymous"
+	Index    string    // URL of the catalog the source caches, if it has one
+	Cached   bool      // Whether the catalog is in the cache
+	Updated  time.Time // When the cached catalog was fetched
+	Fresh    bool      // Whether the cached catalog is younger than the cache TTL
+}
+
+// describeSource reports an anonymous source of the given type and the cache
+// state of its catalog at index, if it has one
+func describeSource(kind string, cache *Cache, index string) SourceInfo {
+	info := SourceInfo{Type: kind, Auth: "anonymous", Index: index}
+	if index != "" {
+		info.Updated, info.Fresh, info.Cached = cache.Updated(index)
+	}
+	return info
+}
+
+// VersionedSource is implemented by sources that can install releases other
+// than the latest, for specs asking for an exact version with "=="
+type VersionedSource interface {
+	Source
+
+	// AtVersion returns font, as found by Search, resolved to version
+	AtVersion(ctx context.Context, font Font, version string) (Font, error)
+}
+
+// ErrEULANotAccepted is returned when installing from an EULASource without
+// WithAcceptEULA
+var ErrEULANotAccepted = errors.New("license agreement not accepted")
+
+// ErrDownloadDeclined is returned when a download is declined through
+// WithConfirmDownload
+var ErrDownloadDeclined = errors.New("download declined")
+
+// Common HTTP client with reasonable defaults; the default settings cannot fail
+var defaultClient, _ = NewHTTPClient(DefaultHTTPSettings)
+
+// getJSON fetches url and decodes the JSON response into v. Fresh cached
+// responses are served without a request, and stale ones are used as a
+// fallback when the server can't be reached.
+func getJSON(ctx context.Context, client *http.Client, cache *Cache, l *slog.Logger, url string, v any) error {
+	return getCached(ctx, client, cache, l, url, func(data []byte) error {
+		return json.Unmarshal(data, v)
+	})
+}
+
+// getCached fetches url and hands the body to decode, going through the cache
+// like getJSON. Only responses that decode successfully are cached.
+func getCached(ctx context.Context, client *http.Client, cache *Cache, l *slog.Logger, url string, decode func([]byte) error) error {
+	if data, ok := cache.Get(url); ok && decode(data) == nil {
+		return nil
+	}
+
+	data, err := fetch(ctx, client, url)
+	if err != nil {
+		if stale, ok := cache.GetStale(url); ok && decode(stale) == nil {
+			logger(l).Warn("using stale cached response", "url", url, "err", err)
+			return nil
+		}
+		return err
+	}
+
+	if err := decode(data); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	// Caching is best effort; a failed write only costs a request next time
+	if err := cache.Put(url, data); err != nil {
+		logger(l).Debug("caching response failed", "url", url, "err", err)
+	}
+	return nil
+}
+
+func fetch(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "FontManager/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{code: resp.StatusCode}
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	return data, nil
+}
+
+// statusError is returned by fetch for unsuccessful responses
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.code)
+}
+
+// isNotFound reports whether err is a 404 response from fetch
+func isNotFound(err error) bool {
+	var status *statusError
+	return errors.As(err, &status) && status.code == http.StatusNotFound
+}
+
+// openURL starts downloading url, returning the body of a successful response
+func openURL(ctx context.Context, client *http.Client, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating download request: %w", err)
+	}
+	return openRequest(client, req)
+}
+
+// openRequest sends a prepared download request. The returned body reports
+// the announced size so the installer can enforce limits before reading, and
+// the URL requested so it can be locked.
+func openRequest(client *http.Client, req *http.Request) (io.ReadCloser, error) {
+	req.Header.Set("User-Agent", "FontManager/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading font: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return &downloadBody{ReadCloser: resp.Body, size: resp.ContentLength, url: req.URL.String()}, nil
+}
+
+// downloadBody is a response body of a known size, or -1 when the server
+// didn't announce one
+type downloadBody struct {
+	io.ReadCloser
+	size   int64
+	url    string
+	sha256 string // Digest recorded for a cached download
+}
+
+func (b *downloadBody) Size() int64 {
+	return b.size
+}
+
+// SHA256 returns the digest of a cached download recorded when it was
+// stored, or "" when it needs to be hashed
+func (b *downloadBody) SHA256() string {
+	return b.sha256
+}
+
+// URL returns the URL that was requested, before any redirects, which may be
+// signed and short-lived
+func (b *downloadBody) URL() string {
+	return b.url
 }
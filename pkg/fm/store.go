@@ -0,0 +1,388 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Store is an experimental content-addressed layout for installed fonts,
+// similar to nix profiles. Font files are kept once in objects/, named by
+// checksum, and every install of a font becomes an immutable generation
+// directory of symlinks to them. The font directory itself is a symlink to
+// the current generation, so switching or rolling back is a single atomic
+// rename and identical files are shared between fonts.
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// DefaultStoreDir returns the per-user store location
+func DefaultStoreDir() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "store"), nil
+}
+
+// dataDir returns the per-user directory fm keeps its data in
+func dataDir() (string, error) {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("getting user home directory: %w", err)
+		}
+		dataDir = filepath.Join(homeDir, ".local", "share")
+	}
+	return filepath.Join(dataDir, "fm"), nil
+}
+
+// Dir returns the root directory of the store
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Manages reports whether fontDir is a symlink into the store
+func (s *Store) Manages(fontDir string) bool {
+	target, err := os.Readlink(fontDir)
+	return err == nil && strings.HasPrefix(target, s.generationsDir()+string(filepath.Separator))
+}
+
+// Adopt moves the files of a classic font directory into the store as a new
+// generation and replaces the directory with a symlink to it
+func (s *Store) Adopt(fontDir string) error {
+	name := filepath.Base(fontDir)
+	generation, err := s.nextGeneration(name)
+	if err != nil {
+		return err
+	}
+
+	// Build the generation under a temporary name so it only ever appears
+	// complete
+	genDir := s.generationDir(name, generation)
+	tmpDir := genDir + ".tmp"
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return fmt.Errorf("removing stale generation: %w", err)
+	}
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return fmt.Errorf("creating generation: %w", err)
+	}
+
+	entries, err := os.ReadDir(fontDir)
+	if err != nil {
+		return fmt.Errorf("reading font directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		src := filepath.Join(fontDir, entry.Name())
+		dest := filepath.Join(tmpDir, entry.Name())
+
+		// Metadata stays a plain file; fonts and licenses become objects
+		if fileFormat(entry.Name()) == "" {
+			if err := copyFile(src, dest); err != nil {
+				return err
+			}
+			continue
+		}
+
+		object, err := s.addObject(src)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(object, dest); err != nil {
+			return fmt.Errorf("linking %s: %w", entry.Name(), err)
+		}
+	}
+
+	if err := os.Rename(tmpDir, genDir); err != nil {
+		return fmt.Errorf("creating generation: %w", err)
+	}
+
+	// Swap the directory for the link; a directory can't be atomically
+	// replaced, so move it aside first
+	oldDir := fontDir + ".fm-old"
+	if err := os.Rename(fontDir, oldDir); err != nil {
+		return fmt.Errorf("moving font directory aside: %w", err)
+	}
+	if err := os.Symlink(genDir, fontDir); err != nil {
+		// Put the original back so the font stays installed
+		_ = os.Rename(oldDir, fontDir)
+		return fmt.Errorf("linking font directory: %w", err)
+	}
+	return os.RemoveAll(oldDir)
+}
+
+// Materialize converts a store-managed font directory back into a classic
+// directory holding copies of its current generation
+func (s *Store) Materialize(fontDir string) error {
+	if !s.Manages(fontDir) {
+		return fmt.Errorf("%s is not managed by the store", fontDir)
+	}
+
+	genDir, err := os.Readlink(fontDir)
+	if err != nil {
+		return fmt.Errorf("reading font link: %w", err)
+	}
+
+	newDir := fontDir + ".fm-new"
+	if err := os.RemoveAll(newDir); err != nil {
+		return fmt.Errorf("removing stale directory: %w", err)
+	}
+	if err := os.MkdirAll(newDir, 0755); err != nil {
+		return fmt.Errorf("creating font directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(genDir)
+	if err != nil {
+		return fmt.Errorf("reading generation: %w", err)
+	}
+	for _, entry := range entries {
+		if err := copyFile(filepath.Join(genDir, entry.Name()), filepath.Join(newDir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(fontDir); err != nil {
+		return fmt.Errorf("removing font link: %w", err)
+	}
+	if err := os.Rename(newDir, fontDir); err != nil {
+		return fmt.Errorf("replacing font link: %w", err)
+	}
+	return nil
+}
+
+// Generations returns the generations recorded for a font directory, oldest
+// first, and the one currently active (0 when the font isn't linked)
+func (s *Store) Generations(fontDir string) ([]int, int, error) {
+	generations, err := s.generations(filepath.Base(fontDir))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	current := 0
+	if target, err := os.Readlink(fontDir); err == nil {
+		current, _ = strconv.Atoi(filepath.Base(target))
+	}
+	return generations, current, nil
+}
+
+// Switch atomically points a font directory at another generation
+func (s *Store) Switch(fontDir string, generation int) error {
+	genDir := s.generationDir(filepath.Base(fontDir), generation)
+	if _, err := os.Stat(genDir); err != nil {
+		return fmt.Errorf("generation %d %w", generation, ErrNotFound)
+	}
+
+	// Renaming a new link over the old one replaces it in a single step
+	tmp := fontDir + ".fm-link"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(genDir, tmp); err != nil {
+		return fmt.Errorf("linking generation: %w", err)
+	}
+	if err := os.Rename(tmp, fontDir); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("switching generation: %w", err)
+	}
+	return nil
+}
+
+// Rollback switches a font directory to the generation before the current
+// one and returns it
+func (s *Store) Rollback(fontDir string) (int, error) {
+	generations, current, err := s.Generations(fontDir)
+	if err != nil {
+		return 0, err
+	}
+
+	// Generations are sorted, so this finds the newest one before the
+	// current, or the newest overall for an uninstalled font
+	previous := 0
+	for _, n := range generations {
+		if current == 0 || n < current {
+			previous = n
+		}
+	}
+	if previous == 0 {
+		return 0, fmt.Errorf("no earlier generation of %s", filepath.Base(fontDir))
+	}
+	return previous, s.Switch(fontDir, previous)
+}
+
+func (s *Store) objectsDir() string {
+	return filepath.Join(s.dir, "objects")
+}
+
+func (s *Store) generationsDir() string {
+	return filepath.Join(s.dir, "generations")
+}
+
+func (s *Store) generationDir(name string, generation int) string {
+	return filepath.Join(s.generationsDir(), name, strconv.Itoa(generation))
+}
+
+// generations lists the generation numbers stored for a font, oldest first
+func (s *Store) generations(name string) ([]int, error) {
+	entries, err := os.ReadDir(filepath.Join(s.generationsDir(), name))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading generations: %w", err)
+	}
+
+	var generations []int
+	for _, entry := range entries {
+		if n, err := strconv.Atoi(entry.Name()); err == nil && entry.IsDir() {
+			generations = append(generations, n)
+		}
+	}
+	sort.Ints(generations)
+	return generations, nil
+}
+
+func (s *Store) nextGeneration(name string) (int, error) {
+	generations, err := s.generations(name)
+	if err != nil {
+		return 0, err
+	}
+	if len(generations) == 0 {
+		return 1, nil
+	}
+	return generations[len(generations)-1] + 1, nil
+}
+
+// addObject moves a file into the objects directory under its checksum,
+// dropping it if an identical object is already stored
+func (s *Store) addObject(path string) (string, error) {
+	file, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	object := filepath.Join(s.objectsDir(), file.SHA256+strings.ToLower(filepath.Ext(path)))
+	if _, err := os.Stat(object); err == nil {
+		return object, nil
+	}
+
+	if err := os.MkdirAll(s.objectsDir(), 0755); err != nil {
+		return "", fmt.Errorf("creating objects directory: %w", err)
+	}
+	if err := copyFile(path, object); err != nil {
+		return "", err
+	}
+	// Objects are shared between generations and must never change
+	if err := os.Chmod(object, 0444); err != nil {
+		return "", fmt.Errorf("protecting object: %w", err)
+	}
+	return object, nil
+}
+
+// copyFile copies src, following symlinks, to a new file at dest
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", dest, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("copying %s: %w", src, err)
+	}
+	return out.Close()
+}
+
+// MigrateToStore moves every classic user font into the store, returning how
+// many were migrated
+func (m *DefaultManager) MigrateToStore(ctx context.Context) (int, error) {
+	return m.migrate(ctx, func(dir string) (bool, error) {
+		if m.store.Manages(dir) {
+			return false, nil
+		}
+		return true, m.store.Adopt(dir)
+	})
+}
+
+// MigrateToClassic converts every store-managed user font back into a plain
+// directory, returning how many were migrated
+func (m *DefaultManager) MigrateToClassic(ctx context.Context) (int, error) {
+	return m.migrate(ctx, func(dir string) (bool, error) {
+		if !m.store.Manages(dir) {
+			return false, nil
+		}
+		return true, m.store.Materialize(dir)
+	})
+}
+
+func (m *DefaultManager) migrate(ctx context.Context, convert func(dir string) (bool, error)) (int, error) {
+	if m.store == nil {
+		return 0, fmt.Errorf("no font store configured")
+	}
+
+	paths, err := m.platform.GetFontPaths()
+	if err != nil {
+		return 0, fmt.Errorf("getting font paths: %w", err)
+	}
+	fonts, err := m.listFontsInDir(paths.UserDir)
+	if err != nil {
+		return 0, fmt.Errorf("listing user fonts: %w", err)
+	}
+
+	migrated := 0
+	for _, font := range fonts {
+		dir := font.Meta["directory"]
+		// Loose files directly in the font directory have no directory of
+		// their own to convert
+		if dir == paths.UserDir {
+			continue
+		}
+
+		converted, err := convert(dir)
+		if err != nil {
+			return migrated, fmt.Errorf("migrating %s: %w", font.Name, err)
+		}
+		if converted {
+			migrated++
+		}
+	}
+
+	if migrated > 0 {
+		if err := m.UpdateCache(); err != nil {
+			return migrated, err
+		}
+	}
+	return migrated, nil
+}
+
+// RollbackFont switches a store-managed font back to its previous
+// generation, or restores the latest generation of an uninstalled one
+func (m *DefaultManager) RollbackFont(ctx context.Context, name string) (int, error) {
+	if m.store == nil {
+		return 0, fmt.Errorf("no font store configured")
+	}
+
+	paths, err := m.platform.GetFontPaths()
+	if err != nil {
+		return 0, fmt.Errorf("getting font paths: %w", err)
+	}
+
+	generation, err := m.store.Rollback(filepath.Join(paths.UserDir, fontDirName(name)))
+	if err != nil {
+		return 0, err
+	}
+	return generation, m.UpdateCache()
+}
@@ -0,0 +1,123 @@
+package fm_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Store layout", func() {
+	var (
+		tempDir string
+		userDir string
+		store   *fm.Store
+		manager *fm.DefaultManager
+		source  *mockSource
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "store-test-*")
+		Expect(err).NotTo(HaveOccurred())
+		userDir = filepath.Join(tempDir, "user")
+		Expect(os.MkdirAll(userDir, 0755)).To(Succeed())
+
+		store = fm.NewStore(filepath.Join(tempDir, "store"))
+		manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir}, fm.WithStore(store))
+		source = newMockSource()
+		Expect(manager.RegisterSource(source)).To(Succeed())
+		ctx = context.Background()
+	})
+
+	AfterEach(func() {
+		// Objects are read-only
+		filepath.Walk(tempDir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && info.Mode().IsRegular() {
+				os.Chmod(path, 0644)
+			}
+			return nil
+		})
+		os.RemoveAll(tempDir)
+	})
+
+	It("should link installed fonts into the store", func() {
+		Expect(manager.Install(ctx, "TestFont1")).To(Succeed())
+
+		fontDir := filepath.Join(userDir, "TestFont1")
+		Expect(store.Manages(fontDir)).To(BeTrue())
+
+		info, err := os.Lstat(filepath.Join(fontDir, "TestFont1.ttf"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode() & os.ModeSymlink).NotTo(BeZero())
+
+		content, err := os.ReadFile(filepath.Join(fontDir, "TestFont1.ttf"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("fake ttf content"))
+
+		fonts, err := manager.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fonts).To(HaveLen(1))
+		Expect(fonts[0].Source).To(Equal("testsource"))
+	})
+
+	It("should store identical files once", func() {
+		Expect(manager.Install(ctx, "TestFont1")).To(Succeed())
+		Expect(manager.Install(ctx, "TestFont2")).To(Succeed())
+
+		// Both fonts share the same font content and license
+		objects, err := os.ReadDir(filepath.Join(store.Dir(), "objects"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objects).To(HaveLen(2))
+	})
+
+	It("should roll back to the previous generation", func() {
+		Expect(manager.Install(ctx, "TestFont1")).To(Succeed())
+		Expect(manager.Uninstall(ctx, "TestFont1")).To(Succeed())
+
+		archive, err := createTestZip(testFont{name: "TestFont1", format: "ttf", content: "version two"})
+		Expect(err).NotTo(HaveOccurred())
+		source.fonts["TestFont1"] = archive
+		Expect(manager.Install(ctx, "TestFont1")).To(Succeed())
+
+		fontFile := filepath.Join(userDir, "TestFont1", "TestFont1.ttf")
+		content, err := os.ReadFile(fontFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("version two"))
+
+		generation, err := manager.RollbackFont(ctx, "TestFont1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(generation).To(Equal(1))
+
+		content, err = os.ReadFile(fontFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("fake ttf content"))
+	})
+
+	It("should migrate between the classic and store layouts", func() {
+		classic := fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir})
+		Expect(classic.RegisterSource(newMockSource())).To(Succeed())
+		Expect(classic.Install(ctx, "TestFont1")).To(Succeed())
+
+		fontDir := filepath.Join(userDir, "TestFont1")
+		Expect(store.Manages(fontDir)).To(BeFalse())
+
+		migrated, err := manager.MigrateToStore(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(migrated).To(Equal(1))
+		Expect(store.Manages(fontDir)).To(BeTrue())
+
+		migrated, err = manager.MigrateToClassic(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(migrated).To(Equal(1))
+		Expect(store.Manages(fontDir)).To(BeFalse())
+
+		info, err := os.Lstat(filepath.Join(fontDir, "TestFont1.ttf"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().IsRegular()).To(BeTrue())
+	})
+})
@@ -0,0 +1,137 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SubsetSource is implemented by web-oriented sources that publish fonts
+// split into subsets by script (e.g. latin, cyrillic) and can download only
+// some of them
+type SubsetSource interface {
+	Source
+
+	// Subsets returns the subsets available for font, mapped to the unicode
+	// ranges they cover in CSS unicode-range syntax
+	Subsets(ctx context.Context, font Font) (map[string]string, error)
+}
+
+// subsetsMetaKey is the Font.Meta key listing the subsets to download, comma
+// separated
+const subsetsMetaKey = "subsets"
+
+// selectSubsets picks the subsets to download from those available: every
+// requested subset plus any subset covering part of the requested ranges
+func selectSubsets(available map[string]string, subsets []string, unicodeRanges string) ([]string, error) {
+	selected := make(map[string]bool)
+
+	for _, subset := range subsets {
+		subset = strings.ToLower(strings.TrimSpace(subset))
+		if _, ok := available[subset]; !ok {
+			return nil, fmt.Errorf("subset %q is not available (available: %s)", subset, strings.Join(sortedKeys(available), ", "))
+		}
+		selected[subset] = true
+	}
+
+	if unicodeRanges != "" {
+		wanted, err := parseUnicodeRanges(unicodeRanges)
+		if err != nil {
+			return nil, err
+		}
+		for subset, coverage := range available {
+			covered, err := parseUnicodeRanges(coverage)
+			if err != nil {
+				continue
+			}
+			if rangesOverlap(wanted, covered) {
+				selected[subset] = true
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no subsets cover the requested characters")
+	}
+	return sortedKeys(selected), nil
+}
+
+// unicodeRange is an inclusive range of code points
+type unicodeRange struct {
+	lo, hi rune
+}
+
+// parseUnicodeRanges parses a CSS unicode-range list such as
+// "U+0000-00FF, U+0131, U+4??"
+func parseUnicodeRanges(s string) ([]unicodeRange, error) {
+	var ranges []unicodeRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		spec := strings.ToUpper(part)
+		if !strings.HasPrefix(spec, "U+") {
+			return nil, fmt.Errorf("invalid unicode range %q", part)
+		}
+		spec = spec[2:]
+
+		var r unicodeRange
+		var err error
+		switch {
+		case strings.Contains(spec, "-"):
+			lo, hi, _ := strings.Cut(spec, "-")
+			if r.lo, err = parseCodePoint(lo); err == nil {
+				r.hi, err = parseCodePoint(hi)
+			}
+		case strings.Contains(spec, "?"):
+			// Wildcards cover every value of the trailing digits
+			if r.lo, err = parseCodePoint(strings.ReplaceAll(spec, "?", "0")); err == nil {
+				r.hi, err = parseCodePoint(strings.ReplaceAll(spec, "?", "F"))
+			}
+		default:
+			r.lo, err = parseCodePoint(spec)
+			r.hi = r.lo
+		}
+		if err != nil || r.lo > r.hi {
+			return nil, fmt.Errorf("invalid unicode range %q", part)
+		}
+		ranges = append(ranges, r)
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("empty unicode range")
+	}
+	return ranges, nil
+}
+
+func parseCodePoint(s string) (rune, error) {
+	n, err := strconv.ParseUint(s, 16, 32)
+	if err != nil || n > 0x10FFFF {
+		return 0, fmt.Errorf("invalid code point %q", s)
+	}
+	return rune(n), nil
+}
+
+func rangesOverlap(a, b []unicodeRange) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x.lo <= y.hi && y.lo <= x.hi {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,234 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Sync actions
+const (
+	SyncInstall = "install" // Listed but not installed
+	SyncUpdate  = "update"  // Installed, but older than required or from another source
+	SyncKeep    = "keep"    // Installed as listed
+	SyncRemove  = "remove"  // Installed by fm but not listed; only acted on when pruning
+)
+
+// SyncOptions controls how Sync reconciles the installed fonts with a list
+type SyncOptions struct {
+	Prune  bool // Remove fonts fm installed that aren't listed
+	DryRun bool // Only report what would change, resolving what would be installed
+
+	// ConfirmRemove is asked before pruning, with the fonts that would be
+	// removed. When it returns false they are kept and left out of the
+	// changes.
+	ConfirmRemove func(fonts []Font) bool
+}
+
+// SyncChange is what Sync did, or would do, for one font
+type SyncChange struct {
+	Action string // One of the Sync action constants
+	Font   Font   // The listed spec, or the installed font for removals
+	Reason string // Why an update is needed, e.g. "v3.0.0 is older than v3.1.0", or a pinned font is kept
+	Err    error  // Why the change failed
+
+	// Plan is where an install or update would come from, resolved on dry
+	// runs
+	Plan *PlannedInstall
+}
+
+// Sync makes the installed fonts match fonts: missing fonts are installed,
+// fonts below their minimum version or installed from a different source are
+// reinstalled, and with Prune, fonts fm installed that aren't listed are
+// removed. Fonts installed outside fm are never removed, and pinned fonts are
+// neither reinstalled nor removed. The result lists a change for every listed
+// font and every removal, in that order; failures are reported both there and
+// in the returned error. The font cache is refreshed once at the end.
+//
+// A dry run resolves the source, version and download URL of every install
+// and update without downloading or changing anything.
+func (m *DefaultManager) Sync(ctx context.Context, fonts []Font, opts SyncOptions, installOpts ...InstallOption) ([]SyncChange, error) {
+	fonts, err := m.ExpandAliases(fonts)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := m.planSync(ctx, fonts, opts.Prune)
+	if err != nil {
+		return changes, err
+	}
+	if opts.DryRun {
+		return changes, m.resolveSync(ctx, changes, m.newInstallOptions(installOpts))
+	}
+	if opts.Prune && opts.ConfirmRemove != nil {
+		changes = confirmRemovals(changes, opts.ConfirmRemove)
+	}
+
+	var errs []error
+	changed := false
+
+	// Fonts from another source make way for the listed one first
+	var pending []Font
+	var pendingChanges []*SyncChange
+	for i := range changes {
+		change := &changes[i]
+		if change.Action != SyncInstall && change.Action != SyncUpdate {
+			continue
+		}
+		if change.Action == SyncUpdate && change.Font.MinVersion == "" && change.Font.Version == "" {
+			if err := m.uninstall(ctx, change.Font.installName()); err != nil {
+				change.Err = fmt.Errorf("removing the installed copy: %w", err)
+				errs = append(errs, fmt.Errorf("%s: %w", change.Font.Name, change.Err))
+				continue
+			}
+			changed = true
+		}
+		pending = append(pending, change.Font)
+		pendingChanges = append(pendingChanges, change)
+	}
+
+	for i, err := range m.installEach(ctx, pending, m.newInstallOptions(installOpts)) {
+		if err != nil {
+			pendingChanges[i].Err = err
+			errs = append(errs, fmt.Errorf("%s: %w", pending[i].Name, err))
+			continue
+		}
+		changed = true
+	}
+
+	for i := range changes {
+		change := &changes[i]
+		if change.Action != SyncRemove || !opts.Prune {
+			continue
+		}
+		if err := m.uninstall(ctx, change.Font.Name); err != nil {
+			change.Err = err
+			errs = append(errs, fmt.Errorf("%s: %w", change.Font.Name, err))
+			continue
+		}
+		changed = true
+	}
+
+	if changed {
+		if err := m.UpdateCache(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return changes, errors.Join(errs...)
+}
+
+// confirmRemovals drops the removals from changes unless confirm accepts them
+func confirmRemovals(changes []SyncChange, confirm func([]Font) bool) []SyncChange {
+	var removals []Font
+	for _, change := range changes {
+		if change.Action == SyncRemove {
+			removals = append(removals, change.Font)
+		}
+	}
+	if len(removals) == 0 || confirm(removals) {
+		return changes
+	}
+	return slices.DeleteFunc(changes, func(change SyncChange) bool {
+		return change.Action == SyncRemove
+	})
+}
+
+// resolveSync resolves where the installs and updates of a dry run would
+// come from
+func (m *DefaultManager) resolveSync(ctx context.Context, changes []SyncChange, o *installOptions) error {
+	var errs []error
+	for i := range changes {
+		change := &changes[i]
+		if change.Action != SyncInstall && change.Action != SyncUpdate {
+			continue
+		}
+		plan := m.resolveInstall(ctx, &change.Font, o)
+		change.Plan = &plan
+		if plan.Err != nil {
+			change.Err = plan.Err
+			errs = append(errs, fmt.Errorf("%s: %w", change.Font.Name, plan.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// planSync compares the listed fonts with the installed ones. Removals are
+// only planned when pruning.
+func (m *DefaultManager) planSync(ctx context.Context, fonts []Font, prune bool) ([]SyncChange, error) {
+	fonts = MergeConfigs(fonts)
+	if err := checkCoexistence(fonts); err != nil {
+		return nil, err
+	}
+
+	installed, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*Font, len(installed))
+	for i := range installed {
+		byKey[installedKey(installed[i].Name)] = &installed[i]
+	}
+
+	listed := make(map[string]bool, len(fonts))
+	changes := make([]SyncChange, 0, len(fonts))
+	for _, font := range fonts {
+		key := installedKey(font.installName())
+		listed[key] = true
+
+		change := SyncChange{Action: SyncKeep, Font: font}
+		current, ok := byKey[key]
+		switch {
+		case !ok:
+			change.Action = SyncInstall
+		case font.Version != "":
+			if ok, version := m.isVersion(current, font.Version); !ok {
+				change.Action = SyncUpdate
+				change.Reason = fmt.Sprintf("%s is not %s", orUnknown(version), font.Version)
+			}
+		case font.MinVersion != "":
+			if ok, version := m.satisfiesMinVersion(current, font.MinVersion); !ok {
+				change.Action = SyncUpdate
+				change.Reason = fmt.Sprintf("%s is older than %s", orUnknown(version), font.MinVersion)
+			}
+		case font.Source != "" && current.Source != "" && font.Source != current.Source && isManaged(current):
+			change.Action = SyncUpdate
+			change.Reason = fmt.Sprintf("installed from %s", current.Source)
+		}
+		if change.Action == SyncUpdate && IsPinned(*current) {
+			change.Action = SyncKeep
+			change.Reason = "pinned, " + change.Reason
+		}
+		changes = append(changes, change)
+	}
+
+	if !prune {
+		return changes, nil
+	}
+	managed, err := m.ManagedFonts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, font := range managed {
+		if listed[installedKey(font.Name)] {
+			continue
+		}
+		if IsPinned(font) {
+			changes = append(changes, SyncChange{Action: SyncKeep, Font: font, Reason: "pinned, not listed"})
+			continue
+		}
+		changes = append(changes, SyncChange{Action: SyncRemove, Font: font})
+	}
+	return changes, nil
+}
+
+// isManaged reports whether fm installed a listed font
+func isManaged(font *Font) bool {
+	return font.Meta["installed_at"] != ""
+}
+
+func orUnknown(version string) string {
+	if version == "" {
+		return "unknown version"
+	}
+	return version
+}
@@ -0,0 +1,187 @@
+package fm_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Syncing fonts with a config", func() {
+	var (
+		tempDir string
+		source  *mockSource
+		manager *fm.DefaultManager
+		ctx     context.Context
+	)
+
+	actions := func(changes []fm.SyncChange) map[string]string {
+		result := make(map[string]string)
+		for _, change := range changes {
+			result[change.Font.Name] = change.Action
+		}
+		return result
+	}
+
+	installedNames := func() []string {
+		fonts, err := manager.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, font := range fonts {
+			names = append(names, font.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "font-sync-*")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(tempDir, "user"), 0755)).To(Succeed())
+
+		source = newMockSource()
+		source.meta["TestFont1"] = map[string]string{"version": "v1.0.0"}
+		manager = fm.NewManagerWithPlatform(&mockPlatform{fontDir: tempDir})
+		Expect(manager.RegisterSource(source)).To(Succeed())
+
+		ctx = context.Background()
+		Expect(manager.Install(ctx, "TestFont1@testsource")).To(Succeed())
+
+		// A font copied in by hand, which sync must never remove
+		manual := filepath.Join(tempDir, "user", "Manual")
+		Expect(os.MkdirAll(manual, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(manual, "Manual.ttf"), []byte("manual"), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	It("should only report changes on a dry run", func() {
+		fonts := []fm.Font{{Name: "TestFont2", Source: "testsource"}}
+		changes, err := manager.Sync(ctx, fonts, fm.SyncOptions{Prune: true, DryRun: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions(changes)).To(Equal(map[string]string{
+			"TestFont2": fm.SyncInstall,
+			"TestFont1": fm.SyncRemove,
+		}))
+		Expect(installedNames()).To(ConsistOf("TestFont1", "Manual"))
+
+		Expect(changes[0].Plan).NotTo(BeNil())
+		Expect(changes[0].Plan.Source).To(Equal("testsource"))
+		Expect(changes[1].Plan).To(BeNil())
+	})
+
+	It("should install missing fonts and keep listed ones", func() {
+		fonts := []fm.Font{{Name: "TestFont1", Source: "testsource"}, {Name: "TestFont2", Source: "testsource"}}
+		changes, err := manager.Sync(ctx, fonts, fm.SyncOptions{Prune: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions(changes)).To(Equal(map[string]string{
+			"TestFont1": fm.SyncKeep,
+			"TestFont2": fm.SyncInstall,
+		}))
+		Expect(installedNames()).To(ConsistOf("TestFont1", "TestFont2", "Manual"))
+	})
+
+	It("should prune unlisted fonts fm installed", func() {
+		fonts := []fm.Font{{Name: "TestFont2", Source: "testsource"}}
+		_, err := manager.Sync(ctx, fonts, fm.SyncOptions{Prune: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installedNames()).To(ConsistOf("TestFont2", "Manual"))
+	})
+
+	It("should only prune once the removals are confirmed", func() {
+		fonts := []fm.Font{{Name: "TestFont2", Source: "testsource"}}
+		var asked []string
+		decline := func(removals []fm.Font) bool {
+			for _, font := range removals {
+				asked = append(asked, font.Name)
+			}
+			return false
+		}
+
+		changes, err := manager.Sync(ctx, fonts, fm.SyncOptions{Prune: true, ConfirmRemove: decline})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(asked).To(Equal([]string{"TestFont1"}))
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Action).To(Equal(fm.SyncInstall))
+		Expect(installedNames()).To(ConsistOf("TestFont1", "TestFont2", "Manual"))
+
+		accept := func([]fm.Font) bool { return true }
+		_, err = manager.Sync(ctx, fonts, fm.SyncOptions{Prune: true, ConfirmRemove: accept})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installedNames()).To(ConsistOf("TestFont2", "Manual"))
+	})
+
+	It("should record pins in the font's metadata", func() {
+		Expect(manager.Pin(ctx, "TestFont1", true)).To(Succeed())
+
+		fonts, err := manager.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fonts).To(ContainElement(And(
+			HaveField("Name", "TestFont1"),
+			HaveField("Meta", And(HaveKeyWithValue("pinned", "true"), HaveKeyWithValue("version", "v1.0.0"))),
+		)))
+
+		Expect(manager.Pin(ctx, "Manual", true)).To(MatchError(ContainSubstring("not installed by fm")))
+		Expect(manager.Pin(ctx, "Missing", true)).To(MatchError(ContainSubstring("not installed")))
+	})
+
+	It("should neither prune nor reinstall pinned fonts", func() {
+		Expect(manager.Pin(ctx, "TestFont1", true)).To(Succeed())
+		source.meta["TestFont1"] = map[string]string{"version": "v1.1.0"}
+
+		changes, err := manager.Sync(ctx, nil, fm.SyncOptions{Prune: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Action).To(Equal(fm.SyncKeep))
+		Expect(changes[0].Reason).To(Equal("pinned, not listed"))
+
+		fonts := []fm.Font{{Name: "TestFont1", Source: "testsource", MinVersion: "v1.1.0"}}
+		changes, err = manager.Sync(ctx, fonts, fm.SyncOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes[0].Action).To(Equal(fm.SyncKeep))
+		Expect(changes[0].Reason).To(Equal("pinned, v1.0.0 is older than v1.1.0"))
+		Expect(installedNames()).To(ConsistOf("TestFont1", "Manual"))
+	})
+
+	It("should leave unlisted fonts alone without prune", func() {
+		changes, err := manager.Sync(ctx, nil, fm.SyncOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(BeEmpty())
+		Expect(installedNames()).To(ConsistOf("TestFont1", "Manual"))
+	})
+
+	It("should reinstall fonts older than their minimum version", func() {
+		source.meta["TestFont1"] = map[string]string{"version": "v1.1.0"}
+		fonts := []fm.Font{{Name: "TestFont1", Source: "testsource", MinVersion: "v1.1.0"}}
+
+		changes, err := manager.Sync(ctx, fonts, fm.SyncOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Action).To(Equal(fm.SyncUpdate))
+		Expect(changes[0].Reason).To(Equal("v1.0.0 is older than v1.1.0"))
+
+		fonts, err = manager.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fonts).To(ContainElement(HaveField("Meta", HaveKeyWithValue("version", "v1.1.0"))))
+	})
+
+	It("should reinstall fonts listed with a different source", func() {
+		other := newMockSource()
+		other.name = "other"
+		Expect(manager.RegisterSource(other)).To(Succeed())
+
+		changes, err := manager.Sync(ctx, []fm.Font{{Name: "TestFont1", Source: "other"}}, fm.SyncOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes[0].Action).To(Equal(fm.SyncUpdate))
+		Expect(changes[0].Reason).To(Equal("installed from testsource"))
+
+		fonts, err := manager.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fonts).To(ContainElement(And(HaveField("Name", "TestFont1"), HaveField("Source", "other"))))
+	})
+})
@@ -0,0 +1,166 @@
+package fm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TemplateSource downloads fonts from a URL built by substituting the font
+// name into a template, e.g. https://fonts.example.com/{name}.zip. It covers
+// simple font servers that don't warrant a dedicated source.
+type TemplateSource struct {
+	name     string
+	template string
+	index    string
+	client   *http.Client
+	cache    *Cache
+	logger   *slog.Logger
+}
+
+// NewTemplateSource creates a source named name that downloads from
+// urlTemplate. When index is set it must serve a JSON array of font names (or
+// objects with "name" and an optional, possibly relative, "url") and is used
+// for search; without an index every name is assumed to exist and the
+// download decides.
+func NewTemplateSource(name, urlTemplate, index string, opts ...SourceOption) (*TemplateSource, error) {
+	if name == "" {
+		return nil, fmt.Errorf("source name is required")
+	}
+	if !strings.Contains(urlTemplate, "{name}") {
+		return nil, fmt.Errorf("url template %q must contain {name}", urlTemplate)
+	}
+
+	o := newSourceOptions(opts)
+	return &TemplateSource{
+		name:     name,
+		template: urlTemplate,
+		index:    index,
+		client:   o.client,
+		cache:    o.cache,
+		logger:   o.logger,
+	}, nil
+}
+
+func (s *TemplateSource) Name() string {
+	return s.name
+}
+
+// templateIndexEntry is a font listed by a template source's index
+type templateIndexEntry struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// UnmarshalJSON accepts either a bare font name or an object
+func (e *templateIndexEntry) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &e.Name); err == nil {
+		return nil
+	}
+
+	type entry templateIndexEntry
+	return json.Unmarshal(data, (*entry)(e))
+}
+
+func (s *TemplateSource) Search(ctx context.Context, name string) ([]Font, error) {
+	if s.index == "" {
+		// Without an index the name can only be checked by downloading it
+		return []Font{{
+			Name:   name,
+			Source: s.Name(),
+			URL:    s.expand(name),
+			Meta:   map[string]string{"pending": "true"},
+		}}, nil
+	}
+
+	var entries []templateIndexEntry
+	if err := getJSON(ctx, s.client, s.cache, s.logger, s.index, &entries); err != nil {
+		return nil, fmt.Errorf("fetching index: %w", err)
+	}
+
+	var results []Font
+	for _, entry := range entries {
+		if !sameFontName(entry.Name, name) {
+			continue
+		}
+
+		downloadURL := s.expand(entry.Name)
+		if entry.URL != "" {
+			// Index URLs may be relative to the index itself
+			ref, err := url.Parse(entry.URL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid url for %s in index: %w", entry.Name, err)
+			}
+			base, err := url.Parse(s.index)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index url: %w", err)
+			}
+			downloadURL = base.ResolveReference(ref).String()
+		}
+		results = append(results, Font{
+			Name:   entry.Name,
+			Source: s.Name(),
+			URL:    downloadURL,
+		})
+	}
+
+	return results, nil
+}
+
+// CachedNames lists the fonts in the cached index, however old
+func (s *TemplateSource) CachedNames() []string {
+	if s.index == "" {
+		return nil
+	}
+	data, ok := s.cache.GetStale(s.index)
+	if !ok {
+		return nil
+	}
+	var entries []templateIndexEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	return names
+}
+
+// expand substitutes the font name into the URL template
+func (s *TemplateSource) expand(name string) string {
+	return strings.ReplaceAll(s.template, "{name}", url.PathEscape(name))
+}
+
+func (s *TemplateSource) Download(ctx context.Context, font Font) (io.ReadCloser, error) {
+	downloadURL := font.URL
+	if downloadURL == "" {
+		downloadURL = s.expand(font.Name)
+	}
+
+	return openURL(ctx, s.client, downloadURL)
+}
+
+// Describe reports the source and the cache state of its index, if any
+func (s *TemplateSource) Describe() SourceInfo {
+	return describeSource("template", s.cache, s.index)
+}
+
+// CheckHealth probes the index, or the server root when there is none
+func (s *TemplateSource) CheckHealth(ctx context.Context) SourceHealth {
+	if s.index != "" {
+		return checkEndpoint(ctx, s.client, s.index)
+	}
+
+	u, err := url.Parse(s.template)
+	if err != nil {
+		return SourceHealth{Endpoint: s.template, Err: fmt.Errorf("invalid url template: %w", err)}
+	}
+	return checkEndpoint(ctx, s.client, u.Scheme+"://"+u.Host+"/")
+}
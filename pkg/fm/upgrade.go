@@ -0,0 +1,230 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FontUpgrade is the outcome of checking an installed font against the
+// latest release of its source
+type FontUpgrade struct {
+	Name      string // Installed font name
+	Source    string // Source the font was installed from
+	Installed string // Version recorded at install time
+	Latest    string // Latest version the source publishes
+	Outdated  bool   // Whether Latest is newer than Installed
+	Upgraded  bool   // Whether the font was reinstalled at Latest
+	Pinned    bool   // Whether the font is pinned, so Upgrade leaves it alone
+	Err       error  // Why the font couldn't be checked or upgraded
+
+	// Upstream compares a patched font with the unmodified font it was
+	// built from, as checked by Outdated; nil for other fonts
+	Upstream *UpstreamRelease
+}
+
+// Upgrade reinstalls the named installed fonts, or every installed font with
+// a recorded version when no names are given, whose source publishes a newer
+// release than the one installed. Fonts keep the region, subsets, variants and
+// format they were installed with, and pinned fonts are never reinstalled. The result has an
+// entry per font checked; failures are reported both there and in the
+// returned error.
+func (m *DefaultManager) Upgrade(ctx context.Context, names []string, opts ...InstallOption) ([]FontUpgrade, error) {
+	fonts, err := m.upgradeCandidates(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []FontUpgrade
+	var errs []error
+	upgraded := 0
+	for _, font := range fonts {
+		result := m.upgrade(ctx, font, opts)
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Name, result.Err))
+		}
+		if result.Upgraded {
+			upgraded++
+		}
+		results = append(results, result)
+	}
+
+	if upgraded > 0 {
+		if err := m.UpdateCache(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// Outdated checks the named installed fonts, or every installed font with a
+// recorded version when no names are given, against the latest release of
+// their source without changing anything. Fonts that couldn't be checked are
+// reported both in the result and in the returned error. Patched fonts with a
+// known upstream, such as Nerd Fonts builds, are also compared with the
+// latest upstream release, which they can lag behind.
+func (m *DefaultManager) Outdated(ctx context.Context, names []string) ([]FontUpgrade, error) {
+	fonts, err := m.upgradeCandidates(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []FontUpgrade
+	var errs []error
+	for _, font := range fonts {
+		result := m.checkVersion(ctx, font)
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Name, result.Err))
+		}
+		result.Upstream = m.checkUpstream(ctx, font)
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
+
+// upgradeCandidates returns the installed fonts to check. Named fonts must be
+// installed; without names only fonts that recorded a version are checked.
+func (m *DefaultManager) upgradeCandidates(ctx context.Context, names []string) ([]Font, error) {
+	if len(names) == 0 {
+		installed, err := m.listManaged(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var fonts []Font
+		for _, font := range installed {
+			if font.Meta["version"] != "" {
+				fonts = append(fonts, font)
+			}
+		}
+		return fonts, nil
+	}
+
+	var fonts []Font
+	for _, name := range names {
+		font, err := m.findInstalled(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if font == nil {
+			return nil, fmt.Errorf("font %q is %w", name, ErrNotInstalled)
+		}
+		fonts = append(fonts, *font)
+	}
+	return fonts, nil
+}
+
+// upgrade reinstalls font if its source has a newer release
+func (m *DefaultManager) upgrade(ctx context.Context, font Font, opts []InstallOption) FontUpgrade {
+	result := m.checkVersion(ctx, font)
+	if result.Err != nil || !result.Outdated || result.Pinned {
+		return result
+	}
+
+	// Reinstall with the options the font was installed with, which explicit
+	// options override
+	var reinstallOpts []InstallOption
+	if region := font.Meta["region"]; region != "" {
+		reinstallOpts = append(reinstallOpts, WithRegion(region))
+	}
+	if subsets := font.Meta[subsetsMetaKey]; subsets != "" {
+		reinstallOpts = append(reinstallOpts, WithSubsets(strings.Split(subsets, ",")...))
+	}
+	if variants := font.Meta[variantsMetaKey]; variants != "" {
+		reinstallOpts = append(reinstallOpts, WithVariants(strings.Split(variants, ",")...))
+	}
+	if format := font.Meta[formatMetaKey]; format != "" {
+		reinstallOpts = append(reinstallOpts, WithFormat(format))
+	}
+	if axes, err := ParseAxes(font.Meta[axesMetaKey]); err == nil {
+		reinstallOpts = append(reinstallOpts, WithAxes(axes...))
+	}
+	if note := Note(font); note != "" {
+		reinstallOpts = append(reinstallOpts, WithNote(note))
+	}
+	name := font.Name
+	if installAs := font.Meta["install_as"]; installAs != "" {
+		// The source knows the font by its own name
+		name = font.Meta["name"]
+		reinstallOpts = append(reinstallOpts, WithInstallAs(installAs))
+	}
+	o := m.newInstallOptions(append(reinstallOpts, opts...))
+	o.upgrade = true
+
+	spec := &Font{Name: name, Source: font.Source}
+	if err := m.runHooks(ctx, installHookEvent(spec, o)); err != nil {
+		result.Err = err
+		return result
+	}
+	restore, err := m.keepPrevious(&font)
+	if err != nil {
+		result.Err = fmt.Errorf("removing outdated version: %w", err)
+		return result
+	}
+	if err := m.installNew(ctx, spec, o); err != nil {
+		restore()
+		result.Err = fmt.Errorf("installing %s: %w", result.Latest, err)
+		return result
+	}
+	result.Upgraded = true
+	return result
+}
+
+// checkVersion compares the version font was installed at with the latest
+// release of its source
+func (m *DefaultManager) checkVersion(ctx context.Context, font Font) FontUpgrade {
+	result := FontUpgrade{
+		Name:      font.Name,
+		Source:    font.Source,
+		Installed: font.Meta["version"],
+		Pinned:    IsPinned(font),
+	}
+	if result.Installed == "" {
+		result.Err = fmt.Errorf("no version was recorded when it was installed")
+		return result
+	}
+
+	result.Latest, result.Err = m.latestVersion(ctx, font)
+	if result.Err != nil {
+		return result
+	}
+
+	installed, err := m.parseSourceVersion(font.Source, result.Installed)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	latest, err := m.parseSourceVersion(font.Source, result.Latest)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Outdated = installed.Compare(latest) < 0
+	return result
+}
+
+// latestVersion asks the source font was installed from for the version it
+// would install now
+func (m *DefaultManager) latestVersion(ctx context.Context, font Font) (string, error) {
+	if font.Source == "" {
+		return "", fmt.Errorf("no source was recorded when it was installed")
+	}
+	source := m.findSource(font.Source)
+	if source == nil {
+		return "", fmt.Errorf("source %q %w", font.Source, ErrNotFound)
+	}
+
+	fonts, err := source.Search(ctx, font.Name)
+	if err != nil {
+		return "", fmt.Errorf("searching in %s: %w", source.Name(), err)
+	}
+	for _, candidate := range fonts {
+		if sameFontName(candidate.Name, font.Name) || len(fonts) == 1 {
+			if version := candidate.Meta["version"]; version != "" {
+				return version, nil
+			}
+			return "", fmt.Errorf("%s does not publish a version for %s", source.Name(), font.Name)
+		}
+	}
+	return "", fmt.Errorf("font %w in %s", ErrNotFound, source.Name())
+}
@@ -0,0 +1,37 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// urlSource downloads fonts given by a direct URL, authenticated with the
+// manager's host credentials and any explicit headers
+type urlSource struct {
+	manager *DefaultManager
+	headers http.Header
+}
+
+func (s *urlSource) Name() string {
+	return "url"
+}
+
+// Search never finds anything; URLs are installed directly
+func (s *urlSource) Search(ctx context.Context, name string) ([]Font, error) {
+	return nil, nil
+}
+
+func (s *urlSource) Download(ctx context.Context, font Font) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", font.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	s.manager.authenticate(req, s.headers)
+	client := defaultClient
+	if s.manager.client != nil {
+		client = s.manager.client
+	}
+	return openRequest(client, req)
+}
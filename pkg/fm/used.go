@@ -0,0 +1,132 @@
+package fm
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// appConfigFiles are application configs, relative to the home directory,
+// that commonly name a font family
+var appConfigFiles = []string{
+	".config/alacritty/alacritty.toml",
+	".config/alacritty/alacritty.yml",
+	".config/kitty/kitty.conf",
+	".config/wezterm/wezterm.lua",
+	".wezterm.lua",
+	".config/foot/foot.ini",
+	".config/ghostty/config",
+	".config/Code/User/settings.json",
+	"Library/Application Support/Code/User/settings.json",
+	".config/zed/settings.json",
+	".config/nvim/init.lua",
+	".config/nvim/init.vim",
+	".config/fontconfig/fonts.conf",
+	".config/gtk-3.0/settings.ini",
+	".config/gtk-4.0/settings.ini",
+	".config/sway/config",
+	".config/i3/config",
+	".config/hypr/hyprland.conf",
+	".config/waybar/style.css",
+	".Xresources",
+}
+
+// minFamilyLength keeps very short family names from matching unrelated
+// words in configs
+const minFamilyLength = 4
+
+// ConfigReference is a line of an application config naming a font family
+type ConfigReference struct {
+	Path   string // Config file
+	Line   int    // Line number, starting at 1
+	Family string // Family the line names
+	Text   string // The line itself, trimmed
+}
+
+// FindConfigReferences scans well-known application configs under home, such
+// as terminal emulator and editor settings, for lines naming any of families.
+// Names match regardless of case, spaces, hyphens and underscores, so
+// "JetBrainsMono Nerd Font" also finds "jetbrains-mono-nerd-font".
+func FindConfigReferences(home string, families []string) ([]ConfigReference, error) {
+	needles := make(map[string]string)
+	for _, family := range families {
+		// The first spelling of a name wins
+		needle := squashName(family)
+		if _, ok := needles[needle]; !ok && len(needle) >= minFamilyLength {
+			needles[needle] = family
+		}
+	}
+	if home == "" || len(needles) == 0 {
+		return nil, nil
+	}
+
+	// Longer names first, so a line is attributed to the most specific family
+	ordered := make([]string, 0, len(needles))
+	for needle := range needles {
+		ordered = append(ordered, needle)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if len(ordered[i]) != len(ordered[j]) {
+			return len(ordered[i]) > len(ordered[j])
+		}
+		return ordered[i] < ordered[j]
+	})
+
+	var refs []ConfigReference
+	for _, rel := range appConfigFiles {
+		path := filepath.Join(home, rel)
+		found, err := scanConfigFile(path, ordered, needles)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, found...)
+	}
+	return refs, nil
+}
+
+func scanConfigFile(path string, ordered []string, needles map[string]string) ([]ConfigReference, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var refs []ConfigReference
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		squashed := squashName(scanner.Text())
+		for _, needle := range ordered {
+			if strings.Contains(squashed, needle) {
+				refs = append(refs, ConfigReference{
+					Path:   path,
+					Line:   line,
+					Family: needles[needle],
+					Text:   strings.TrimSpace(scanner.Text()),
+				})
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return refs, nil
+}
+
+// squashName folds a font name for loose matching, dropping case, spaces,
+// hyphens and underscores
+func squashName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_', '\t':
+			return -1
+		}
+		return r
+	}, normalizeName(name))
+}
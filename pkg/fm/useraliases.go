@@ -0,0 +1,92 @@
+package fm
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// ParseUserAlias checks that a user alias names a plain font name and
+// returns the font its spec installs, e.g. "code-font" for
+// "FiraCode@nerdfonts"
+func ParseUserAlias(name, spec string) (*Font, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("alias name is empty")
+	}
+	if font, err := ParseFontSpec(name); err != nil || font == nil || font.Name != strings.TrimSpace(name) || font.URL != "" {
+		return nil, fmt.Errorf("alias name %q must be a plain font name", name)
+	}
+
+	font, err := ParseFontSpec(spec)
+	if err != nil {
+		return nil, fmt.Errorf("alias %s: %w", name, err)
+	}
+	if font == nil {
+		return nil, fmt.Errorf("alias %s names no font", name)
+	}
+	if font.Source == "" && font.URL == "" && aliasKey(font.Name) == aliasKey(name) {
+		return nil, fmt.Errorf("alias %s refers to itself", name)
+	}
+	return font, nil
+}
+
+// ExpandAliases replaces the fonts named by a user alias with the font the
+// alias names, keeping version constraints, options and notes given with the
+// alias. Fonts with a source or URL are never aliases.
+func (m *DefaultManager) ExpandAliases(fonts []Font) ([]Font, error) {
+	if len(m.userAliases) == 0 {
+		return fonts, nil
+	}
+
+	expanded := make([]Font, len(fonts))
+	for i, font := range fonts {
+		var err error
+		if expanded[i], err = m.expandAlias(font); err != nil {
+			return nil, err
+		}
+	}
+	return expanded, nil
+}
+
+// expandAlias returns the font a user alias named by font installs, or font
+// itself when it isn't one
+func (m *DefaultManager) expandAlias(font Font) (Font, error) {
+	if font.Source != "" || font.URL != "" {
+		return font, nil
+	}
+	spec, ok := m.userAliases[aliasKey(font.Name)]
+	if !ok {
+		return font, nil
+	}
+
+	target, err := ParseUserAlias(font.Name, spec)
+	if err != nil {
+		return font, err
+	}
+	if font.MinVersion != "" || font.Version != "" {
+		target.MinVersion, target.Version = font.MinVersion, font.Version
+	}
+	if len(font.Options) > 0 {
+		if target.Options == nil {
+			target.Options = make(map[string]string, len(font.Options))
+		}
+		maps.Copy(target.Options, font.Options)
+	}
+	if note := Note(font); note != "" {
+		if target.Meta == nil {
+			target.Meta = make(map[string]string, 1)
+		}
+		target.Meta[noteMetaKey] = note
+	}
+	return *target, nil
+}
+
+// expandSpec expands a single parsed spec in place
+func (m *DefaultManager) expandSpec(font *Font) error {
+	expanded, err := m.expandAlias(*font)
+	if err != nil {
+		return err
+	}
+	*font = expanded
+	return nil
+}
@@ -0,0 +1,123 @@
+package fm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FontVerification compares the files of an installed font with the
+// checksums recorded when it was installed
+type FontVerification struct {
+	Font       Font
+	Unrecorded bool     // No checksums were recorded, so nothing could be compared
+	Missing    []string // Recorded files that no longer exist
+	Modified   []string // Files whose contents changed
+	Extra      []string // Font files that weren't installed with the font
+	Err        error    // Why the font couldn't be checked
+}
+
+// OK reports whether the font's files are exactly the ones installed
+func (v FontVerification) OK() bool {
+	return v.Err == nil && !v.Unrecorded && len(v.Missing) == 0 && len(v.Modified) == 0 && len(v.Extra) == 0
+}
+
+// Verify re-hashes the files of installed fonts and compares them with the
+// checksums recorded at install time. Named fonts must be installed; without
+// names every font fm installed is checked. Files whose size and
+// modification time are unchanged since they were hashed aren't hashed
+// again, unless rehash is set or the manager was created WithRehash.
+func (m *DefaultManager) Verify(ctx context.Context, names []string, rehash bool) ([]FontVerification, error) {
+	var fonts []Font
+	if len(names) == 0 {
+		installed, err := m.listManaged(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, font := range installed {
+			if isManaged(&font) {
+				fonts = append(fonts, font)
+			}
+		}
+	}
+	for _, name := range names {
+		font, err := m.findInstalled(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if font == nil {
+			return nil, fmt.Errorf("font %q is %w", name, ErrNotInstalled)
+		}
+		fonts = append(fonts, *font)
+	}
+
+	var results []FontVerification
+	var errs []error
+	for _, font := range fonts {
+		result := verifyFont(font, rehash || m.rehash)
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", font.Name, result.Err))
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
+
+// verifyFont checks one font against its manifest, hashing only the files
+// that changed since unless rehash is set
+func verifyFont(font Font, rehash bool) FontVerification {
+	result := FontVerification{Font: font}
+	dir, ok := font.Meta["directory"]
+	if !ok {
+		result.Err = fmt.Errorf("font directory information missing")
+		return result
+	}
+
+	recorded, err := readManifest(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		result.Unrecorded = true
+		return result
+	}
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	seen := make(map[string]bool, len(recorded))
+	for _, file := range recorded {
+		seen[file.Name] = true
+		path := filepath.Join(dir, file.Name)
+		if info, err := os.Stat(path); err == nil && !rehash && file.unchanged(info) {
+			continue
+		}
+		current, err := hashFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			result.Missing = append(result.Missing, file.Name)
+		case err != nil:
+			result.Err = err
+			return result
+		case current.SHA256 != file.SHA256:
+			result.Modified = append(result.Modified, file.Name)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		result.Err = fmt.Errorf("reading font directory: %w", err)
+		return result
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && fileFormat(entry.Name()) != "" && !seen[entry.Name()] {
+			result.Extra = append(result.Extra, entry.Name())
+		}
+	}
+
+	sort.Strings(result.Missing)
+	sort.Strings(result.Modified)
+	sort.Strings(result.Extra)
+	return result
+}
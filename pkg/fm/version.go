@@ -0,0 +1,80 @@
+package fm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a comparable font release version such as v3.2.1
+type Version struct {
+	raw   string
+	parts []int
+}
+
+// VersionParser is implemented by sources whose release versions don't
+// compare correctly as plain dotted numbers
+type VersionParser interface {
+	// ParseVersion parses a version string as published by the source
+	ParseVersion(s string) (Version, error)
+}
+
+// ParseVersion parses a dotted numeric version, ignoring a leading non-numeric
+// prefix (e.g. "v" or a release name like "Sans") and any trailing suffix
+// such as "-beta"
+func ParseVersion(s string) (Version, error) {
+	start := strings.IndexAny(s, "0123456789")
+	if start < 0 {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+
+	numeric := s[start:]
+	if end := strings.IndexFunc(numeric, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	}); end >= 0 {
+		numeric = numeric[:end]
+	}
+
+	var parts []int
+	for _, field := range strings.Split(strings.Trim(numeric, "."), ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		parts = append(parts, n)
+	}
+	return Version{raw: s, parts: parts}, nil
+}
+
+// Compare returns -1, 0 or 1 as v is older than, equal to or newer than
+// other. Missing components count as zero, so 3.0 equals 3.0.0.
+func (v Version) Compare(other Version) int {
+	for i := 0; i < len(v.parts) || i < len(other.parts); i++ {
+		a, b := 0, 0
+		if i < len(v.parts) {
+			a = v.parts[i]
+		}
+		if i < len(other.parts) {
+			b = other.parts[i]
+		}
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v Version) String() string {
+	return v.raw
+}
+
+// parseSourceVersion parses s using the named source's rules when it has any
+func (m *DefaultManager) parseSourceVersion(sourceName, s string) (Version, error) {
+	if parser, ok := m.findSource(sourceName).(VersionParser); ok {
+		return parser.ParseVersion(s)
+	}
+	return ParseVersion(s)
+}
@@ -0,0 +1,53 @@
+package fm_test
+
+import (
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Versions", func() {
+	compare := func(parse func(string) (fm.Version, error), a, b string) int {
+		va, err := parse(a)
+		Expect(err).NotTo(HaveOccurred())
+		vb, err := parse(b)
+		Expect(err).NotTo(HaveOccurred())
+		return va.Compare(vb)
+	}
+
+	It("should compare dotted versions numerically", func() {
+		Expect(compare(fm.ParseVersion, "v3.2.1", "v3.10.0")).To(Equal(-1))
+		Expect(compare(fm.ParseVersion, "v3.0", "3.0.0")).To(Equal(0))
+		Expect(compare(fm.ParseVersion, "Sans2.004", "2.003-beta")).To(Equal(1))
+	})
+
+	It("should reject strings without a version", func() {
+		_, err := fm.ParseVersion("latest")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should compare CJK releases as decimals", func() {
+		cjk := fm.NewCJKSource()
+		Expect(compare(cjk.ParseVersion, "Sans2.1", "Sans2.004")).To(Equal(1))
+		Expect(compare(cjk.ParseVersion, "2.004R", "Sans2.004")).To(Equal(0))
+	})
+
+	It("should parse minimum version constraints in specs", func() {
+		font, err := fm.ParseFontSpec("JetBrainsMono@nerdfonts >=v3.0.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(font.Name).To(Equal("JetBrainsMono"))
+		Expect(font.Source).To(Equal("nerdfonts"))
+		Expect(font.MinVersion).To(Equal("v3.0.0"))
+
+		_, err = fm.ParseFontSpec("JetBrainsMono >=soon")
+		Expect(err).To(MatchError(ContainSubstring("invalid version constraint")))
+	})
+
+	It("should parse source references in specs", func() {
+		font, err := fm.ParseFontSpec("MyFont@ipfs:bafkqaaa")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(font.Name).To(Equal("MyFont"))
+		Expect(font.Source).To(Equal("ipfs"))
+		Expect(font.Ref).To(Equal("bafkqaaa"))
+	})
+})
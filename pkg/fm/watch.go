@@ -0,0 +1,178 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/logandonley/font-manager/internal/scheduler"
+)
+
+// DefaultWatchInterval is how often WatchFontDirs scans the font directories
+const DefaultWatchInterval = 5 * time.Second
+
+// FontDirChange lists the font files that appeared, disappeared or changed in
+// the font directories
+type FontDirChange struct {
+	Added    []string
+	Removed  []string
+	Modified []string
+}
+
+// Empty reports whether nothing changed
+func (c FontDirChange) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// fileState is what a scan records about a font file
+type fileState struct {
+	size    int64
+	modTime int64
+}
+
+// WatchFontDirs scans the user and system font directories every interval
+// and refreshes the font cache when font files are added, removed or changed
+// outside fm, e.g. copied by hand or synced by Syncthing. A change is only
+// acted on once a scan finds the directories settled, so a sync in progress
+// triggers a single refresh. onChange is called after every refresh with the
+// changes and the refresh error, if any. WatchFontDirs runs until ctx is
+// done.
+func (m *DefaultManager) WatchFontDirs(ctx context.Context, interval time.Duration, onChange func(FontDirChange, error)) error {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	// Changes are reported against the state at the last refresh once two
+	// scans in a row agree
+	refreshed, err := m.scanFontDirs()
+	if err != nil {
+		return err
+	}
+	previous := refreshed
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		// Installs change the directories as they go; scan once they are done
+		var change FontDirChange
+		var refreshErr error
+		err := m.RunBackground(ctx, func(context.Context) error {
+			current, err := m.scanFontDirs()
+			if err != nil {
+				// The directories may be mid-sync; try again next time
+				return nil
+			}
+
+			settled := diffFontDirs(previous, current).Empty()
+			previous = current
+			if !settled {
+				return nil
+			}
+
+			change = diffFontDirs(refreshed, current)
+			if !change.Empty() {
+				refreshed = current
+				refreshErr = m.UpdateCache()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil
+		}
+		if !change.Empty() {
+			onChange(change, refreshErr)
+		}
+	}
+}
+
+// RunBackground runs job as background work, such as a prefetch or index
+// refresh: it waits until no install is running or waiting, and for a slot
+// among the jobs WithBackgroundJobs allows at once. Long jobs can check
+// InstallsWaiting between steps to get out of the way sooner.
+func (m *DefaultManager) RunBackground(ctx context.Context, job func(context.Context) error) error {
+	return m.jobs.Run(ctx, scheduler.Background, job)
+}
+
+// InstallsWaiting reports whether installs are running or waiting to, which
+// background jobs yield to
+func (m *DefaultManager) InstallsWaiting() bool {
+	return m.jobs.Busy()
+}
+
+// scanFontDirs records every font file in the font directories. The system
+// directory is skipped when it can't be read.
+func (m *DefaultManager) scanFontDirs() (map[string]fileState, error) {
+	paths, err := m.platform.GetFontPaths()
+	if err != nil {
+		return nil, fmt.Errorf("getting font paths: %w", err)
+	}
+
+	files := make(map[string]fileState)
+	if err := scanFontDir(paths.UserDir, files); err != nil {
+		return nil, err
+	}
+	_ = scanFontDir(paths.SystemDir, files)
+	return files, nil
+}
+
+func scanFontDir(dir string, files map[string]fileState) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files can disappear while a sync is running
+			if os.IsNotExist(err) && path != dir {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !isFontFile(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files[path] = fileState{size: info.Size(), modTime: info.ModTime().UnixNano()}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("scanning %s: %w", dir, err)
+	}
+	return nil
+}
+
+// diffFontDirs compares two scans
+func diffFontDirs(before, after map[string]fileState) FontDirChange {
+	var change FontDirChange
+	for path, state := range after {
+		old, ok := before[path]
+		switch {
+		case !ok:
+			change.Added = append(change.Added, path)
+		case old != state:
+			change.Modified = append(change.Modified, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			change.Removed = append(change.Removed, path)
+		}
+	}
+	sort.Strings(change.Added)
+	sort.Strings(change.Removed)
+	sort.Strings(change.Modified)
+	return change
+}
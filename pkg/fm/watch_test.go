@@ -0,0 +1,121 @@
+package fm_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/logandonley/font-manager/pkg/fm"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// Mock platform counting font cache refreshes
+type countingPlatform struct {
+	mockPlatform
+	refreshes atomic.Int32
+}
+
+func (p *countingPlatform) UpdateFontCache() error {
+	p.refreshes.Add(1)
+	return nil
+}
+
+var _ = Describe("Watching font directories", func() {
+	var (
+		tempDir  string
+		userDir  string
+		platform *countingPlatform
+		manager  *fm.DefaultManager
+		cancel   context.CancelFunc
+		done     chan struct{}
+
+		mu      sync.Mutex
+		changes []fm.FontDirChange
+	)
+
+	received := func() []fm.FontDirChange {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]fm.FontDirChange(nil), changes...)
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "font-watch-*")
+		Expect(err).NotTo(HaveOccurred())
+		userDir = filepath.Join(tempDir, "user")
+		Expect(os.MkdirAll(userDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(userDir, "Existing.ttf"), []byte("existing"), 0644)).To(Succeed())
+
+		platform = &countingPlatform{mockPlatform: mockPlatform{fontDir: tempDir}}
+		manager = fm.NewManagerWithPlatform(platform)
+		changes = nil
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		done = make(chan struct{})
+		go func() {
+			defer close(done)
+			defer GinkgoRecover()
+			err := manager.WatchFontDirs(ctx, 10*time.Millisecond, func(change fm.FontDirChange, err error) {
+				Expect(err).NotTo(HaveOccurred())
+				mu.Lock()
+				defer mu.Unlock()
+				changes = append(changes, change)
+			})
+			Expect(err).NotTo(HaveOccurred())
+		}()
+		// Let the initial scan finish before changing anything
+		time.Sleep(20 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(done).Should(BeClosed())
+		os.RemoveAll(tempDir)
+	})
+
+	It("should refresh the cache when fonts are copied in", func() {
+		added := filepath.Join(userDir, "Synced", "Synced-Regular.otf")
+		Expect(os.MkdirAll(filepath.Dir(added), 0755)).To(Succeed())
+		Expect(os.WriteFile(added, []byte("synced"), 0644)).To(Succeed())
+
+		Eventually(received).Should(ConsistOf(fm.FontDirChange{Added: []string{added}}))
+		Expect(platform.refreshes.Load()).To(Equal(int32(1)))
+	})
+
+	It("should refresh the cache when fonts are removed", func() {
+		removed := filepath.Join(userDir, "Existing.ttf")
+		Expect(os.Remove(removed)).To(Succeed())
+
+		Eventually(received).Should(ConsistOf(fm.FontDirChange{Removed: []string{removed}}))
+	})
+
+	It("should wait while other background jobs run", func() {
+		started, finish := make(chan struct{}), make(chan struct{})
+		go manager.RunBackground(context.Background(), func(context.Context) error {
+			close(started)
+			<-finish
+			return nil
+		})
+		Eventually(started).Should(BeClosed())
+
+		added := filepath.Join(userDir, "Late-Regular.ttf")
+		Expect(os.WriteFile(added, []byte("late"), 0644)).To(Succeed())
+		Consistently(received, 100*time.Millisecond).Should(BeEmpty())
+
+		close(finish)
+		Eventually(received).Should(ConsistOf(fm.FontDirChange{Added: []string{added}}))
+	})
+
+	It("should ignore files that aren't fonts", func() {
+		Expect(os.WriteFile(filepath.Join(userDir, "notes.txt"), []byte("notes"), 0644)).To(Succeed())
+
+		Consistently(received, 100*time.Millisecond).Should(BeEmpty())
+		Expect(platform.refreshes.Load()).To(BeZero())
+	})
+})
@@ -0,0 +1,92 @@
+package fm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Install scopes, the font directories fonts are found in
+const (
+	ScopeUser   = "user"   // The user's own font directory
+	ScopeSystem = "system" // The system font directory, shared by every user
+)
+
+// scopeMetaKey is the Font.Meta key recording the scope fm installed a font
+// in, so fonts installed system-wide can be uninstalled again
+const scopeMetaKey = "scope"
+
+// FontLocation is where the files of an installed font are
+type FontLocation struct {
+	Font  Font
+	Scope string   // ScopeUser or ScopeSystem
+	Dir   string   // Directory holding the font, or the font directory itself for a loose file
+	Files []string // Absolute paths of the font files, sorted
+}
+
+// Which finds the directory and files backing an installed font
+func (m *DefaultManager) Which(ctx context.Context, name string) (*FontLocation, error) {
+	font, err := m.findInstalled(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if font == nil {
+		return nil, fmt.Errorf("font %q is %w", name, ErrNotInstalled)
+	}
+
+	paths, err := m.platform.GetFontPaths()
+	if err != nil {
+		return nil, fmt.Errorf("getting font paths: %w", err)
+	}
+
+	path := font.Meta["path"]
+	loc := &FontLocation{Font: *font}
+	var root string
+	switch {
+	case isWithin(path, paths.UserDir):
+		loc.Scope, root = ScopeUser, paths.UserDir
+	case isWithin(path, paths.SystemDir):
+		loc.Scope, root = ScopeSystem, paths.SystemDir
+	default:
+		return nil, fmt.Errorf("font %q is outside the font directories", name)
+	}
+
+	// Fonts are directories under the font directory, except for files
+	// copied there directly
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return nil, fmt.Errorf("getting relative path: %w", err)
+	}
+	top, _, nested := strings.Cut(rel, string(filepath.Separator))
+	if !nested {
+		loc.Dir = root
+		loc.Files = []string{path}
+		return loc, nil
+	}
+
+	loc.Dir = filepath.Join(root, top)
+	// Walk store layout symlinks through the link, like List does
+	err = filepath.Walk(loc.Dir+string(filepath.Separator), func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && isFontFile(info.Name()) {
+			loc.Files = append(loc.Files, filepath.Clean(file))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing files of %s: %w", font.Name, err)
+	}
+	sort.Strings(loc.Files)
+	return loc, nil
+}
+
+// isWithin reports whether path is inside dir
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
@@ -0,0 +1,292 @@
+// Package fontmeta reads what font files say about themselves: the family,
+// style and version names, the variation axes of variable fonts, the
+// OpenType layout features and which characters have glyphs.
+//
+// It reads TrueType and OpenType files and collections of them:
+//
+//	font, err := fontmeta.ParseFile("Inter-Regular.ttf")
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Println(font.Family, font.Style, font.Version)
+//	fmt.Println(font.Covers('€'), font.Features)
+//
+// Fonts are parsed once and keep their file's contents, so checking coverage
+// doesn't read the file again. A Font is safe for concurrent use.
+package fontmeta
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// ErrTruncated is returned for font files whose tables run past the end of
+// the file
+var ErrTruncated = errors.New("truncated font file")
+
+// Font is what a font file says about one of its faces
+type Font struct {
+	Family         string   `json:"family"`                    // Typographic family, e.g. "Inter", falling back to the legacy family
+	Style          string   `json:"style"`                     // Typographic subfamily, e.g. "Bold Italic", falling back to the legacy subfamily
+	FullName       string   `json:"full_name,omitempty"`       // Full name, e.g. "Inter Bold Italic"
+	PostScriptName string   `json:"postscript_name,omitempty"` // PostScript name, e.g. "Inter-BoldItalic"
+	Version        string   `json:"version,omitempty"`         // Version string, e.g. "Version 4.000"
+	Axes           []Axis   `json:"axes,omitempty"`            // Variation axes, none for static fonts
+	Features       []string `json:"features,omitempty"`        // OpenType layout feature tags, e.g. "liga" or "ss01", sorted
+
+	sfnt *sfnt.Font
+}
+
+// Axis is a variation axis of a variable font
+type Axis struct {
+	Tag     string  `json:"tag"` // Four-character axis tag, e.g. wght or wdth
+	Min     float64 `json:"min"`
+	Default float64 `json:"default"`
+	Max     float64 `json:"max"`
+}
+
+// Parse reads a TrueType or OpenType font file. Collections are read with
+// ParseCollection.
+func Parse(data []byte) (*Font, error) {
+	parsed, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing font: %w", err)
+	}
+	return newFont(parsed, data, 0)
+}
+
+// ParseFile reads the font file at path
+func ParseFile(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// ParseCollection reads every face of a TrueType or OpenType collection
+// (.ttc or .otc). A single font file is read as a collection of one.
+func ParseCollection(data []byte) ([]*Font, error) {
+	collection, err := sfnt.ParseCollection(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing font: %w", err)
+	}
+	offsets, err := directoryOffsets(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(offsets) != collection.NumFonts() {
+		return nil, fmt.Errorf("parsing font: collection header lists %d fonts, found %d", len(offsets), collection.NumFonts())
+	}
+
+	fonts := make([]*Font, len(offsets))
+	for i, offset := range offsets {
+		parsed, err := collection.Font(i)
+		if err != nil {
+			return nil, fmt.Errorf("parsing font %d of the collection: %w", i, err)
+		}
+		if fonts[i], err = newFont(parsed, data, offset); err != nil {
+			return nil, fmt.Errorf("font %d of the collection: %w", i, err)
+		}
+	}
+	return fonts, nil
+}
+
+func newFont(parsed *sfnt.Font, data []byte, offset int) (*Font, error) {
+	var buf sfnt.Buffer
+	font := &Font{
+		Family:         name(parsed, &buf, sfnt.NameIDTypographicFamily, sfnt.NameIDFamily),
+		Style:          name(parsed, &buf, sfnt.NameIDTypographicSubfamily, sfnt.NameIDSubfamily),
+		FullName:       name(parsed, &buf, sfnt.NameIDFull),
+		PostScriptName: name(parsed, &buf, sfnt.NameIDPostScript),
+		Version:        name(parsed, &buf, sfnt.NameIDVersion),
+		sfnt:           parsed,
+	}
+
+	tables, err := readTables(data, offset)
+	if err != nil {
+		return nil, err
+	}
+	if font.Axes, err = parseAxes(tables["fvar"]); err != nil {
+		return nil, err
+	}
+	if font.Features, err = parseFeatures(tables["GSUB"], tables["GPOS"]); err != nil {
+		return nil, err
+	}
+	return font, nil
+}
+
+// name returns the first of the name table entries ids that is set
+func name(f *sfnt.Font, buf *sfnt.Buffer, ids ...sfnt.NameID) string {
+	for _, id := range ids {
+		if value, err := f.Name(buf, id); err == nil && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// Variable reports whether the font has variation axes
+func (f *Font) Variable() bool {
+	return len(f.Axes) > 0
+}
+
+// HasFeature reports whether the font's layout tables define the feature
+func (f *Font) HasFeature(tag string) bool {
+	i := sort.SearchStrings(f.Features, tag)
+	return i < len(f.Features) && f.Features[i] == tag
+}
+
+// Covers reports whether the font has a glyph for r
+func (f *Font) Covers(r rune) bool {
+	var buf sfnt.Buffer
+	index, err := f.sfnt.GlyphIndex(&buf, r)
+	return err == nil && index != 0
+}
+
+// Missing returns the characters of runes the font has no glyph for, in the
+// order given
+func (f *Font) Missing(runes []rune) []rune {
+	var buf sfnt.Buffer
+	var missing []rune
+	for _, r := range runes {
+		if index, err := f.sfnt.GlyphIndex(&buf, r); err != nil || index == 0 {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
+
+// SFNT returns the parsed font, for drawing it with golang.org/x/image/font
+func (f *Font) SFNT() *sfnt.Font {
+	return f.sfnt
+}
+
+// ReadAxes reads only the variation axes of a single font file, without
+// parsing the rest of it. Static fonts have none.
+func ReadAxes(data []byte) ([]Axis, error) {
+	tables, err := readTables(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	return parseAxes(tables["fvar"])
+}
+
+// directoryOffsets returns where the table directory of each font in a file
+// starts: at the beginning of a single font, or as listed by a collection
+// header
+func directoryOffsets(data []byte) ([]int, error) {
+	if len(data) < 12 {
+		return nil, ErrTruncated
+	}
+	if string(data[:4]) != "ttcf" {
+		return []int{0}, nil
+	}
+	numFonts := int(binary.BigEndian.Uint32(data[8:]))
+	if len(data) < 12+4*numFonts {
+		return nil, ErrTruncated
+	}
+	offsets := make([]int, numFonts)
+	for i := range offsets {
+		offsets[i] = int(binary.BigEndian.Uint32(data[12+4*i:]))
+	}
+	return offsets, nil
+}
+
+// readTables returns the tables of the font whose table directory starts at
+// offset, keyed by tag
+func readTables(data []byte, offset int) (map[string][]byte, error) {
+	if offset < 0 || len(data) < offset+12 {
+		return nil, ErrTruncated
+	}
+	numTables := int(binary.BigEndian.Uint16(data[offset+4:]))
+	if len(data) < offset+12+16*numTables {
+		return nil, ErrTruncated
+	}
+
+	tables := make(map[string][]byte, numTables)
+	for i := 0; i < numTables; i++ {
+		record := data[offset+12+16*i:]
+		start := int64(binary.BigEndian.Uint32(record[8:]))
+		length := int64(binary.BigEndian.Uint32(record[12:]))
+		if start+length > int64(len(data)) {
+			return nil, fmt.Errorf("%w: %s table", ErrTruncated, record[:4])
+		}
+		tables[string(record[:4])] = data[start : start+length]
+	}
+	return tables, nil
+}
+
+// parseAxes reads the axis records of an fvar table
+func parseAxes(fvar []byte) ([]Axis, error) {
+	if fvar == nil {
+		return nil, nil
+	}
+	if len(fvar) < 16 {
+		return nil, fmt.Errorf("%w: fvar table", ErrTruncated)
+	}
+	axesOffset := int(binary.BigEndian.Uint16(fvar[4:]))
+	axisCount := int(binary.BigEndian.Uint16(fvar[8:]))
+	axisSize := int(binary.BigEndian.Uint16(fvar[10:]))
+	if axisSize < 20 || axesOffset+axisCount*axisSize > len(fvar) {
+		return nil, fmt.Errorf("%w: fvar table", ErrTruncated)
+	}
+
+	axes := make([]Axis, axisCount)
+	for i := range axes {
+		record := fvar[axesOffset+i*axisSize:]
+		axes[i] = Axis{
+			Tag:     string(record[:4]),
+			Min:     fixedToFloat(binary.BigEndian.Uint32(record[4:])),
+			Default: fixedToFloat(binary.BigEndian.Uint32(record[8:])),
+			Max:     fixedToFloat(binary.BigEndian.Uint32(record[12:])),
+		}
+	}
+	return axes, nil
+}
+
+// fixedToFloat converts a 16.16 fixed-point number
+func fixedToFloat(v uint32) float64 {
+	return float64(int32(v)) / 65536
+}
+
+// parseFeatures collects the feature tags of the feature lists of layout
+// tables such as GSUB and GPOS
+func parseFeatures(tables ...[]byte) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, table := range tables {
+		if table == nil {
+			continue
+		}
+		if len(table) < 10 {
+			return nil, fmt.Errorf("%w: layout table", ErrTruncated)
+		}
+		list := int(binary.BigEndian.Uint16(table[6:]))
+		if list == 0 {
+			continue
+		}
+		if len(table) < list+2 {
+			return nil, fmt.Errorf("%w: feature list", ErrTruncated)
+		}
+		count := int(binary.BigEndian.Uint16(table[list:]))
+		if len(table) < list+2+6*count {
+			return nil, fmt.Errorf("%w: feature list", ErrTruncated)
+		}
+		for i := 0; i < count; i++ {
+			seen[string(table[list+2+6*i:list+6+6*i])] = true
+		}
+	}
+
+	var features []string
+	for tag := range seen {
+		features = append(features, tag)
+	}
+	sort.Strings(features)
+	return features, nil
+}
@@ -0,0 +1,13 @@
+package fontmeta_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFontmeta(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fontmeta Suite")
+}
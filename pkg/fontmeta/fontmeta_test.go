@@ -0,0 +1,160 @@
+package fontmeta_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/logandonley/font-manager/pkg/fontmeta"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// withTable returns a copy of a font file with one more table, keeping the
+// table directory sorted by tag. The file must end on a four-byte boundary.
+func withTable(font []byte, tag string, table []byte) []byte {
+	numTables := int(binary.BigEndian.Uint16(font[4:]))
+	directoryEnd := 12 + 16*numTables
+
+	var records [][]byte
+	for i := 0; i < numTables; i++ {
+		record := append([]byte(nil), font[12+16*i:12+16*(i+1)]...)
+		binary.BigEndian.PutUint32(record[8:], binary.BigEndian.Uint32(record[8:])+16)
+		records = append(records, record)
+	}
+	record := []byte(tag)
+	record = binary.BigEndian.AppendUint32(record, 0)
+	record = binary.BigEndian.AppendUint32(record, uint32(len(font)+16))
+	record = binary.BigEndian.AppendUint32(record, uint32(len(table)))
+	i := 0
+	for i < len(records) && string(records[i][:4]) < tag {
+		i++
+	}
+	records = slices.Insert(records, i, record)
+
+	out := append([]byte(nil), font[:12]...)
+	binary.BigEndian.PutUint16(out[4:], uint16(numTables+1))
+	for _, record := range records {
+		out = append(out, record...)
+	}
+	out = append(out, font[directoryEnd:]...)
+	out = append(out, table...)
+	// Tables start on four-byte boundaries
+	for len(out)%4 != 0 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+// fvarTable builds an fvar table with the given axes
+func fvarTable(axes ...fontmeta.Axis) []byte {
+	fixed := func(v float64) uint32 { return uint32(int32(v * 65536)) }
+
+	fvar := binary.BigEndian.AppendUint16(nil, 1)
+	fvar = binary.BigEndian.AppendUint16(fvar, 0)
+	fvar = binary.BigEndian.AppendUint16(fvar, 16) // axesArrayOffset
+	fvar = binary.BigEndian.AppendUint16(fvar, 2)
+	fvar = binary.BigEndian.AppendUint16(fvar, uint16(len(axes)))
+	fvar = binary.BigEndian.AppendUint16(fvar, 20) // axisSize
+	fvar = binary.BigEndian.AppendUint32(fvar, 0)  // no named instances
+	for _, axis := range axes {
+		fvar = append(fvar, axis.Tag...)
+		fvar = binary.BigEndian.AppendUint32(fvar, fixed(axis.Min))
+		fvar = binary.BigEndian.AppendUint32(fvar, fixed(axis.Default))
+		fvar = binary.BigEndian.AppendUint32(fvar, fixed(axis.Max))
+		fvar = binary.BigEndian.AppendUint32(fvar, 0)
+	}
+	return fvar
+}
+
+// layoutTable builds a GSUB or GPOS table with features of the given tags
+func layoutTable(tags ...string) []byte {
+	table := binary.BigEndian.AppendUint16(nil, 1)
+	table = binary.BigEndian.AppendUint16(table, 0)
+	table = binary.BigEndian.AppendUint16(table, 0)  // no script list
+	table = binary.BigEndian.AppendUint16(table, 10) // featureListOffset
+	table = binary.BigEndian.AppendUint16(table, 0)  // no lookup list
+	table = binary.BigEndian.AppendUint16(table, uint16(len(tags)))
+	for _, tag := range tags {
+		table = append(table, tag...)
+		table = binary.BigEndian.AppendUint16(table, 0)
+	}
+	return table
+}
+
+var _ = Describe("Font metadata", func() {
+	It("should read the names of a font", func() {
+		font, err := fontmeta.Parse(goregular.TTF)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(font.Family).To(Equal("Go"))
+		Expect(font.Style).To(Equal("Regular"))
+		Expect(font.FullName).To(Equal("Go Regular"))
+		Expect(font.PostScriptName).To(Equal("GoRegular"))
+		Expect(font.Version).To(HavePrefix("Version 2.010"))
+		Expect(font.Variable()).To(BeFalse())
+	})
+
+	It("should read a font file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "Go-Regular.ttf")
+		Expect(os.WriteFile(path, goregular.TTF, 0644)).To(Succeed())
+
+		font, err := fontmeta.ParseFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(font.Family).To(Equal("Go"))
+	})
+
+	It("should report which characters have glyphs", func() {
+		font, err := fontmeta.Parse(goregular.TTF)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(font.Covers('A')).To(BeTrue())
+		Expect(font.Covers('中')).To(BeFalse())
+		Expect(font.Missing([]rune("A中b語"))).To(Equal([]rune("中語")))
+	})
+
+	It("should read the variation axes", func() {
+		axes := []fontmeta.Axis{
+			{Tag: "wght", Min: 100, Default: 400, Max: 900},
+			{Tag: "slnt", Min: -10, Default: 0, Max: 0},
+		}
+		data := withTable(goregular.TTF, "fvar", fvarTable(axes...))
+
+		font, err := fontmeta.Parse(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(font.Variable()).To(BeTrue())
+		Expect(font.Axes).To(Equal(axes))
+
+		read, err := fontmeta.ReadAxes(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read).To(Equal(axes))
+	})
+
+	It("should collect the layout features of GSUB and GPOS", func() {
+		data := withTable(goregular.TTF, "GSUB", layoutTable("liga", "ss01", "calt"))
+		data = withTable(data, "GPOS", layoutTable("kern", "liga"))
+
+		font, err := fontmeta.Parse(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(font.Features).To(Equal([]string{"calt", "kern", "liga", "ss01"}))
+		Expect(font.HasFeature("ss01")).To(BeTrue())
+		Expect(font.HasFeature("smcp")).To(BeFalse())
+	})
+
+	It("should read a single font as a collection of one", func() {
+		fonts, err := fontmeta.ParseCollection(goregular.TTF)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fonts).To(HaveLen(1))
+		Expect(fonts[0].Family).To(Equal("Go"))
+	})
+
+	It("should reject truncated tables", func() {
+		data := withTable(goregular.TTF, "fvar", fvarTable(fontmeta.Axis{Tag: "wght", Min: 100, Default: 400, Max: 900}))
+		_, err := fontmeta.ReadAxes(data[:len(data)-8])
+		Expect(errors.Is(err, fontmeta.ErrTruncated)).To(BeTrue(), "%v", err)
+
+		_, err = fontmeta.Parse([]byte("not a font"))
+		Expect(err).To(HaveOccurred())
+	})
+})
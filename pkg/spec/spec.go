@@ -0,0 +1,372 @@
+// Package spec parses font specs, the one-line font descriptions fm accepts
+// on the command line, in config files and in alias indexes.
+//
+// A spec names a font, optionally with its source and a source-specific
+// reference, or gives a URL to download it from, followed by an optional
+// version constraint and options:
+//
+//	spec       = target { constraint | option } [ comment ]
+//	target     = url | name [ "@" source [ ":" ref ] ]
+//	constraint = ( ">=" | "==" ) version
+//	option     = key "=" value
+//	comment    = "#" { any }
+//
+// Names may contain spaces; the name ends at "@" or at the first word that
+// starts a constraint or an option. Constraints, options and comments are
+// separated by whitespace, and a comment must follow whitespace so URL
+// fragments are kept. The text of a comment is kept as the spec's Comment.
+// For example:
+//
+//	JetBrainsMono@nerdfonts ==v3.2.1 variants=Regular,Bold
+//	Times New Roman@mscorefonts
+//	MyFont@ipfs:bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi
+//	https://fonts.example.com/acme.zip sha256=9f86d08...
+package spec
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultOptions are the option keys a zero Parser accepts
+var DefaultOptions = []string{"allow_coexist", "axes", "format", "install_as", "region", "sha256", "subsets", "variants"}
+
+// Spec is a parsed font spec
+type Spec struct {
+	Name       string            // Font name; empty for URLs
+	Source     string            // Source to install from, if given
+	Ref        string            // Source-specific reference after "source:"
+	URL        string            // Direct download URL
+	MinVersion string            // Oldest acceptable version, from ">="
+	Version    string            // Exact version, from "=="
+	Options    map[string]string // Options by key, e.g. "variants": "Regular,Bold"
+	Comment    string            // Trimmed text of a trailing comment
+}
+
+// String formats s back into a spec that parses to the same value, with
+// options sorted by key
+func (s Spec) String() string {
+	var b strings.Builder
+	switch {
+	case s.URL != "":
+		b.WriteString(s.URL)
+	case s.Source != "":
+		b.WriteString(s.Name + "@" + s.Source)
+		if s.Ref != "" {
+			b.WriteString(":" + s.Ref)
+		}
+	default:
+		b.WriteString(s.Name)
+	}
+	if s.MinVersion != "" {
+		b.WriteString(" >=" + s.MinVersion)
+	}
+	if s.Version != "" {
+		b.WriteString(" ==" + s.Version)
+	}
+
+	keys := make([]string, 0, len(s.Options))
+	for key := range s.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.WriteString(" " + key + "=" + s.Options[key])
+	}
+	if s.Comment != "" {
+		b.WriteString("  # " + s.Comment)
+	}
+	return b.String()
+}
+
+// SyntaxError describes where and why a spec failed to parse
+type SyntaxError struct {
+	Spec     string // The spec being parsed
+	Offset   int    // Byte offset of the problem in Spec
+	Expected string // What the grammar expected there
+	Found    string // What was there instead
+	Err      error  // Why a well-formed part was rejected, e.g. an invalid version
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%q column %d: %v", e.Spec, e.Column(), e.Err)
+	}
+	return fmt.Sprintf("%q column %d: expected %s, found %s", e.Spec, e.Column(), e.Expected, e.Found)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// Column returns the 1-based column of the problem
+func (e *SyntaxError) Column() int {
+	return utf8.RuneCountInString(e.Spec[:e.Offset]) + 1
+}
+
+// Parser parses specs. The zero value accepts DefaultOptions and any version.
+type Parser struct {
+	// Options are the accepted option keys; nil means DefaultOptions
+	Options []string
+
+	// CheckVersion validates the version of a constraint; nil accepts any
+	CheckVersion func(version string) error
+}
+
+// Parse parses a spec with the zero Parser
+func Parse(s string) (*Spec, error) {
+	return Parser{}.Parse(s)
+}
+
+// Parse parses a spec. Blank specs and comments parse to nil.
+func (p Parser) Parse(s string) (*Spec, error) {
+	sc := &scanner{input: s}
+	sc.skipSpace()
+	if sc.done() {
+		return nil, nil
+	}
+
+	spec := &Spec{}
+	if err := sc.target(spec); err != nil {
+		return nil, err
+	}
+
+	for {
+		sc.skipSpace()
+		if sc.done() {
+			if sc.atComment() {
+				spec.Comment = strings.TrimSpace(sc.input[sc.pos+1:])
+			}
+			return spec, nil
+		}
+		start := sc.pos
+		switch {
+		case sc.atConstraint():
+			if err := p.constraint(sc, spec); err != nil {
+				return nil, err
+			}
+		case optionKey(sc.word()) != "":
+			if err := p.option(sc, spec); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, sc.errorAt(start, "a version constraint (>=, ==) or option (key=value)", quote(sc.word()))
+		}
+	}
+}
+
+// CheckOption reports whether key=value is an option p accepts
+func (p Parser) CheckOption(key, value string) error {
+	options := p.Options
+	if options == nil {
+		options = DefaultOptions
+	}
+	if !slices.Contains(options, key) {
+		return fmt.Errorf("unknown option %q (expected one of %s)", key, strings.Join(options, ", "))
+	}
+	if value == "" {
+		return fmt.Errorf("option %q needs a value", key)
+	}
+	return nil
+}
+
+func (p Parser) constraint(sc *scanner, spec *Spec) error {
+	start := sc.pos
+	op := sc.input[sc.pos : sc.pos+2]
+	sc.pos += 2
+	sc.skipSpace()
+
+	versionStart := sc.pos
+	version := sc.word()
+	if version == "" {
+		return sc.errorAt(versionStart, "a version after "+op, sc.found())
+	}
+	if spec.MinVersion != "" || spec.Version != "" {
+		return sc.fail(start, fmt.Errorf("only one version constraint is allowed"))
+	}
+	if p.CheckVersion != nil {
+		if err := p.CheckVersion(version); err != nil {
+			return sc.fail(versionStart, fmt.Errorf("invalid version constraint %q: %w", version, err))
+		}
+	}
+	sc.pos += len(version)
+
+	if op == ">=" {
+		spec.MinVersion = version
+	} else {
+		spec.Version = version
+	}
+	return nil
+}
+
+func (p Parser) option(sc *scanner, spec *Spec) error {
+	start := sc.pos
+	word := sc.word()
+	key := optionKey(word)
+	value := word[len(key)+1:]
+	if err := p.CheckOption(key, value); err != nil {
+		return sc.fail(start, err)
+	}
+	if _, ok := spec.Options[key]; ok {
+		return sc.fail(start, fmt.Errorf("option %q is given twice", key))
+	}
+	if spec.Options == nil {
+		spec.Options = make(map[string]string)
+	}
+	spec.Options[key] = value
+	sc.pos += len(word)
+	return nil
+}
+
+// optionKey returns the key of a key=value word, or "" if word isn't one
+func optionKey(word string) string {
+	key, _, ok := strings.Cut(word, "=")
+	if !ok || key == "" {
+		return ""
+	}
+	for i, r := range key {
+		if !(r >= 'a' && r <= 'z' || r == '_' || r == '-' || i > 0 && r >= '0' && r <= '9') {
+			return ""
+		}
+	}
+	return key
+}
+
+// scanner walks a spec left to right
+type scanner struct {
+	input string
+	pos   int
+}
+
+func (sc *scanner) done() bool {
+	return sc.pos >= len(sc.input) || sc.atComment()
+}
+
+// atComment reports whether a comment starts at the current position
+func (sc *scanner) atComment() bool {
+	return sc.hasPrefix("#") && (sc.pos == 0 || isSpace(sc.input[sc.pos-1]))
+}
+
+func (sc *scanner) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(sc.input[sc.pos:], prefix)
+}
+
+func (sc *scanner) skipSpace() {
+	for sc.pos < len(sc.input) && isSpace(sc.input[sc.pos]) {
+		sc.pos++
+	}
+}
+
+// word returns the text up to the next whitespace without consuming it
+func (sc *scanner) word() string {
+	rest := sc.input[sc.pos:]
+	if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+		return rest[:i]
+	}
+	return rest
+}
+
+// found describes the input at the current position for error messages
+func (sc *scanner) found() string {
+	switch {
+	case sc.done():
+		return "end of spec"
+	case isSpace(sc.input[sc.pos]):
+		return "whitespace"
+	}
+	return quote(sc.word())
+}
+
+func (sc *scanner) target(spec *Spec) error {
+	if sc.hasPrefix("http://") || sc.hasPrefix("https://") {
+		start := sc.pos
+		raw := sc.word()
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return sc.errorAt(start, "a URL with a host", quote(raw))
+		}
+		sc.pos += len(raw)
+		spec.URL = raw
+		return nil
+	}
+
+	// The name runs to "@", a constraint or the first word starting an
+	// option
+	start := sc.pos
+	end := sc.pos
+	for sc.pos < len(sc.input) && sc.input[sc.pos] != '@' && !sc.atConstraint() {
+		if isSpace(sc.input[sc.pos]) {
+			sc.skipSpace()
+			if sc.done() || optionKey(sc.word()) != "" {
+				break
+			}
+			continue
+		}
+		sc.pos++
+		end = sc.pos
+	}
+	spec.Name = sc.input[start:end]
+	if spec.Name == "" {
+		return sc.errorAt(start, "a font name or URL", sc.found())
+	}
+	if !sc.hasPrefix("@") {
+		return nil
+	}
+
+	// A source directly follows "@", and a reference ":"
+	sc.pos++
+	sourceStart := sc.pos
+	for sc.pos < len(sc.input) && isSourceByte(sc.input[sc.pos]) {
+		sc.pos++
+	}
+	spec.Source = sc.input[sourceStart:sc.pos]
+	if spec.Source == "" {
+		return sc.errorAt(sourceStart, `a source name after "@"`, sc.found())
+	}
+	if sc.hasPrefix(":") {
+		sc.pos++
+		refStart := sc.pos
+		for sc.pos < len(sc.input) && !isSpace(sc.input[sc.pos]) && !sc.atConstraint() {
+			sc.pos++
+		}
+		spec.Ref = sc.input[refStart:sc.pos]
+		if spec.Ref == "" {
+			return sc.errorAt(refStart, `a reference after ":"`, sc.found())
+		}
+	}
+	if sc.pos < len(sc.input) && !isSpace(sc.input[sc.pos]) && !sc.atConstraint() {
+		return sc.errorAt(sc.pos, "whitespace after the source", quote(sc.word()))
+	}
+	return nil
+}
+
+// atConstraint reports whether a version constraint starts at the current
+// position
+func (sc *scanner) atConstraint() bool {
+	return sc.hasPrefix(">=") || sc.hasPrefix("==")
+}
+
+func (sc *scanner) errorAt(offset int, expected, found string) error {
+	return &SyntaxError{Spec: sc.input, Offset: offset, Expected: expected, Found: found}
+}
+
+func (sc *scanner) fail(offset int, err error) error {
+	return &SyntaxError{Spec: sc.input, Offset: offset, Err: err}
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+func isSourceByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '-' || b == '_' || b == '.'
+}
+
+func quote(s string) string {
+	return fmt.Sprintf("%q", s)
+}
@@ -0,0 +1,122 @@
+package spec_test
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/logandonley/font-manager/pkg/spec"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Font specs", func() {
+	parse := func(s string) *spec.Spec {
+		parsed, err := spec.Parse(s)
+		Expect(err).NotTo(HaveOccurred())
+		return parsed
+	}
+
+	syntaxError := func(s string) *spec.SyntaxError {
+		_, err := spec.Parse(s)
+		var syntaxErr *spec.SyntaxError
+		Expect(errors.As(err, &syntaxErr)).To(BeTrue(), "parsing %q: %v", s, err)
+		return syntaxErr
+	}
+
+	It("should parse every part of a spec", func() {
+		Expect(parse("JetBrainsMono@nerdfonts ==v3.2.1 variants=Regular,Bold sha256=abc")).To(Equal(&spec.Spec{
+			Name:    "JetBrainsMono",
+			Source:  "nerdfonts",
+			Version: "v3.2.1",
+			Options: map[string]string{"variants": "Regular,Bold", "sha256": "abc"},
+		}))
+		Expect(parse("MyFont@ipfs:bafy >=v1.0")).To(Equal(&spec.Spec{
+			Name: "MyFont", Source: "ipfs", Ref: "bafy", MinVersion: "v1.0",
+		}))
+		Expect(parse("https://fonts.example.com/acme.zip#v2 region=JP")).To(Equal(&spec.Spec{
+			URL:     "https://fonts.example.com/acme.zip#v2",
+			Options: map[string]string{"region": "JP"},
+		}))
+	})
+
+	It("should keep spaces in names", func() {
+		Expect(parse("  Times New Roman@mscorefonts  ").Name).To(Equal("Times New Roman"))
+		Expect(parse("Noto Sans CJK region=TC").Name).To(Equal("Noto Sans CJK"))
+		Expect(parse("Fira Code >= v6.2").MinVersion).To(Equal("v6.2"))
+		Expect(parse("Inter>=v4.0").Name).To(Equal("Inter"))
+	})
+
+	It("should treat blank specs and comments as nothing", func() {
+		Expect(parse("")).To(BeNil())
+		Expect(parse("   # a comment")).To(BeNil())
+		Expect(parse("Inter # body text").Name).To(Equal("Inter"))
+	})
+
+	It("should keep the text of a trailing comment", func() {
+		Expect(parse("Inter # body text ").Comment).To(Equal("body text"))
+		Expect(parse("Fira Code@fontsource variants=Regular  #  for the terminal").Comment).To(Equal("for the terminal"))
+		Expect(parse("https://fonts.example.com/acme.zip#v2").Comment).To(BeEmpty())
+	})
+
+	It("should format specs that parse back to the same value", func() {
+		for _, s := range []string{
+			"Inter",
+			"FiraCode@nerdfonts ==v3.2.1 sha256=abc variants=Regular",
+			"MyFont@ipfs:bafy >=v1.0",
+			"https://fonts.example.com/acme.zip",
+			"Inter@fontsource  # body text",
+		} {
+			Expect(parse(s).String()).To(Equal(s))
+		}
+	})
+
+	It("should report where a spec went wrong and what was expected", func() {
+		err := syntaxError("Inter@ >=v1")
+		Expect(err.Column()).To(Equal(7))
+		Expect(err.Expected).To(Equal(`a source name after "@"`))
+		Expect(err.Error()).To(Equal(`"Inter@ >=v1" column 7: expected a source name after "@", found whitespace`))
+
+		err = syntaxError("Inter >=")
+		Expect(err.Expected).To(Equal("a version after >="))
+		Expect(err.Found).To(Equal("end of spec"))
+
+		err = syntaxError("Inter@fontsource latin")
+		Expect(err.Column()).To(Equal(18))
+		Expect(err.Expected).To(Equal("a version constraint (>=, ==) or option (key=value)"))
+
+		Expect(syntaxError("MyFont@ipfs:").Expected).To(Equal(`a reference after ":"`))
+		Expect(syntaxError("@nerdfonts").Expected).To(Equal("a font name or URL"))
+		Expect(syntaxError("https:///acme.zip").Expected).To(Equal("a URL with a host"))
+	})
+
+	It("should reject unknown, empty and repeated options and constraints", func() {
+		Expect(syntaxError("Inter weight=700").Error()).To(ContainSubstring(`unknown option "weight"`))
+		Expect(syntaxError("Inter variants=").Error()).To(ContainSubstring(`option "variants" needs a value`))
+		Expect(syntaxError("Inter region=JP region=KR").Error()).To(ContainSubstring("given twice"))
+		Expect(syntaxError("Inter >=v1 ==v2").Error()).To(ContainSubstring("only one version constraint"))
+	})
+
+	It("should accept the options and versions a parser is configured with", func() {
+		invalid := fmt.Errorf("not a version")
+		parser := spec.Parser{
+			Options: []string{"weight"},
+			CheckVersion: func(version string) error {
+				if version == "latest" {
+					return invalid
+				}
+				return nil
+			},
+		}
+
+		parsed, err := parser.Parse("Inter weight=700")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed.Options).To(HaveKeyWithValue("weight", "700"))
+
+		_, err = parser.Parse("Inter variants=Bold")
+		Expect(err).To(MatchError(ContainSubstring(`unknown option "variants"`)))
+
+		_, err = parser.Parse("Inter ==latest")
+		Expect(err).To(MatchError(invalid))
+		Expect(err).To(MatchError(ContainSubstring(`column 9: invalid version constraint "latest"`)))
+	})
+})